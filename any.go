@@ -75,7 +75,7 @@ func (x *Composer) WriteAnyType(v bsttype.Type) error {
 
 	// 7. If the modules are required decode it.
 	if m != nil {
-		n, err = m.Write(x.w)
+		n, err = m.WriteWithOptions(x.w, x.typeWriteOptions())
 		if err != nil {
 			return err
 		}
@@ -83,7 +83,7 @@ func (x *Composer) WriteAnyType(v bsttype.Type) error {
 	}
 
 	// 5. Write the type.
-	n, err = bsttype.WriteType(x.w, v)
+	n, err = bsttype.WriteTypeWithOptions(x.w, v, x.typeWriteOptions())
 	if err != nil {
 		return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write type")
 	}
@@ -118,6 +118,7 @@ func (x *Extractor) ReadAnyType() (bsttype.Type, error) {
 	if err != nil {
 		return nil, err
 	}
+	x.bytesRead++
 
 	// 4. Check if the type defined in the Any value requires modules.
 	var (
@@ -171,5 +172,8 @@ func (x *Extractor) ReadAnyType() (bsttype.Type, error) {
 		return nil, x.err
 	}
 
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpHeader, t)
+	}
 	return t, nil
 }
@@ -5,6 +5,7 @@ import (
 	"io"
 	"math"
 
+	"github.com/devmodules/bst/bstcol"
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
 	"github.com/devmodules/bst/bstskip"
@@ -21,25 +22,36 @@ func (x *Composer) WriteArray(fn func(c *Composer) error, optLength int) error {
 	}
 
 	// 2. If the base is a struct, check if the field header needs to be written.
+	w, bufWrites := x.w, x.bufWrites
 	if x.needWriteFieldHeader() {
 		x.setFieldBuffer()
 	}
 
+	// 2.1. The length prefixed array element is buffered, so that its binary size could be written first.
+	prefixed := x.lengthPrefixedElem()
+	if prefixed {
+		x.w = iopool.GetBuffer(x.w)
+	}
+
 	// 3. Create a savepoint and resetWithRoot given composer.
 	sp := *x
 
 	// 4. Verify if current element matches expected type.
 	at, ok := x.elemType.(*bsttype.Array)
 	if !ok {
-		return bsterr.Err(bsterr.CodeInvalidType, "invalid type to write").
+		return x.abortComposite(&sp, w, bufWrites, bsterr.Err(bsterr.CodeInvalidType, "invalid type to write").
 			WithDetails(
 				bsterr.D("expected", bsttype.KindArray),
 				bsterr.D("actual", x.elemType.Kind()),
-			)
+			))
 	}
 
+	// 4.1. The elements of the delta encoded field are written as the differences from the previous ones.
+	delta := x.fieldEncoding() == bsttype.FieldEncodingDelta
+
 	// 5. resetWithRoot current composer state.
 	x.reset()
+	x.delta = delta
 
 	// 6. Set up the length of the array.
 	if optLength > 0 {
@@ -49,32 +61,40 @@ func (x *Composer) WriteArray(fn func(c *Composer) error, optLength int) error {
 
 	// 7. Initialize array composer.
 	if err := x.initializeArrayComposer(at, false); err != nil {
-		return err
+		return x.abortComposite(&sp, w, bufWrites, err)
 	}
 
 	// 8. Call input function.
 	if err := fn(x); err != nil {
-		return err
+		return x.abortComposite(&sp, w, bufWrites, err)
 	}
 
-	// 9. Verify if writing was completed
-	if x.index <= x.maxIndex && !x.definedLength {
-		return bsterr.Err(bsterr.CodeWritingFailed, "sub-composer didn't write all elements")
+	// 9. Verify if writing was completed. The arrays of undefined length are completed by any number of elements.
+	if x.index <= x.maxIndex && x.maxIndex != math.MaxInt {
+		return x.abortComposite(&sp, w, bufWrites, bsterr.Err(bsterr.CodeWritingFailed, "sub-composer didn't write all elements"))
 	}
 
 	// 10. Close the array composer.
 	if err := x.closeArray(at); err != nil {
-		return err
+		return x.abortComposite(&sp, w, bufWrites, err)
 	}
 
-	// 11. Store the number of bytes written to array composer.
-	bw := x.bytesWritten
+	// 11. Store the number of bytes written to array composer, along with its write stats.
+	bw, stats := x.bytesWritten, x.stats
 
 	// 12. Restore the savepoint.
 	*x = sp
 
-	// 13. Increase the number of bytes written by the array composer.
+	// 13. Increase the number of bytes written and the write stats by the array composer.
 	x.bytesWritten += bw
+	x.stats.add(stats)
+
+	// 13.1. Write the buffered length prefixed element.
+	if prefixed {
+		if err := x.writeLengthPrefixed(); err != nil {
+			return err
+		}
+	}
 
 	// 14. Finish the element.
 	if err := x.finishElem(); err != nil {
@@ -83,6 +103,31 @@ func (x *Composer) WriteArray(fn func(c *Composer) error, optLength int) error {
 	return nil
 }
 
+// WriteArrayWithLength writes an array value of the length known upfront to the composer, i.e. computed
+// by the bstvalue.ComputeBinarySize. Unlike the array of undefined length, its elements are written straight
+// to the composer writer rather than buffered and copied on close, unless the array encoding or the comparable
+// format requires it. The input function needs to write exactly n elements.
+func (x *Composer) WriteArrayWithLength(n int, fn func(c *Composer) error) error {
+	if n < 0 {
+		return bsterr.Err(bsterr.CodeInvalidValue, "negative array length").WithDetail("length", n)
+	}
+	if n > 0 {
+		return x.WriteArray(fn, n)
+	}
+
+	// The zero length denotes the undefined one to the WriteArray, thus no element could be written.
+	return x.WriteArray(func(c *Composer) error {
+		if err := fn(c); err != nil {
+			return err
+		}
+		if c.index > 0 {
+			return bsterr.Err(bsterr.CodeWritingFailed, "sub-composer wrote more elements than the array length").
+				WithDetails(bsterr.D("written", c.index), bsterr.D("length", n))
+		}
+		return nil
+	}, 0)
+}
+
 // ReadArray reads an array value from the extractor. It creates a sub-extractor which
 // should be used for the element array type.
 func (x *Extractor) ReadArray(fn func(x *Extractor) error) error {
@@ -96,8 +141,10 @@ func (x *Extractor) ReadArray(fn func(x *Extractor) error) error {
 		return bsterr.Err(bsterr.CodeAlreadyRead, "element already read")
 	}
 
-	// 3. Create a snapshot of the current state.
-	sp := *x
+	// 2.1. Read the binary size prefix of the length prefixed array element.
+	if err := x.skipLengthPrefix(); err != nil {
+		return err
+	}
 
 	// 3. Ensure that the element is an array.
 	if x.elemType.Kind() != bsttype.KindArray {
@@ -108,40 +155,54 @@ func (x *Extractor) ReadArray(fn func(x *Extractor) error) error {
 			)
 	}
 
+	// 3.1. Record the array in the trace, preceding the events of its elements.
+	var traceIndex int
+	if x.opts.Trace != nil {
+		traceIndex = x.traceEvent(TraceOpComposite, nil)
+	}
+
 	// 4. Keep embedded and expected array types.
 	xt := x.elemType
 	et := x.embed.elemType
+	delta := x.fieldEncoding() == bsttype.FieldEncodingDelta
 
-	// 5. Reset the extractor
-	x.reset()
+	// 5. Push the current frame and reset the extractor.
+	x.enterNested()
+	x.delta = delta
 
 	// 6. Set up base type for the new extractor composer.
 	x.opts.ExpectedType = xt
 	x.embedType = et
 
-	// 7. Initialize the extractor for the array.
+	// 7. Initialize the extractor for the array, whose length needs to fit within the decode budget.
 	if err := x.initializeArray(); err != nil {
-		return err
+		return x.abortNested(err)
+	}
+	if err := x.checkBudgetLength(); err != nil {
+		return x.abortNested(err)
 	}
 
 	// 8. Execute the extraction function.
 	if err := fn(x); err != nil {
-		return err
+		return x.abortNested(err)
 	}
 
 	// 9. Check if the array was fully extracted.
 	if err := x.finishArray(); err != nil {
-		return err
+		return x.abortNested(err)
 	}
 
 	// 10. Keep the number of bytes read from the array.
 	br := x.bytesRead
 
-	// 11. Restore an extractor from the snapshot.
-	*x = sp
+	// 11. Restore the frame of the parent composite.
+	x.leaveNested()
 
 	// 12. Update the number of bytes read.
 	x.bytesRead += br
+	if x.opts.Trace != nil {
+		x.traceComposite(traceIndex)
+	}
 
 	// 13. Finish this element.
 	x.finishElem()
@@ -161,44 +222,51 @@ func (x *Extractor) initializeArray() error {
 
 	// 1. Set up common array fields.
 	x.index = -1
-	x.embed.elemType = tt.Type
-	x.elemType = tt.Type
-	if et, ok := x.opts.ExpectedType.(*bsttype.Array); ok {
-		x.elemType = et.Type
-	}
-	x.elemType, x.err = x.derefType(x.elemType)
-	if x.err != nil {
-		return x.err
-	}
-	x.embed.elemType, x.err = x.derefType(x.embed.elemType)
-	if x.err != nil {
-		return x.err
+	if err := x.setArrayElemTypes(tt); err != nil {
+		return err
 	}
 
 	x.elemDesc = x.opts.Descending
 
-	// 2.If the array is of fixed size we already know the length and directly start the extraction.
+	// 2. The run length encoded arrays have their elements expanded upfront,
+	//    and the null bitmap arrays have their null flags read upfront.
+	if !x.opts.Comparable {
+		if err := tt.VerifyEncoding(); err != nil {
+			return err
+		}
+	}
+	if x.runLengthArray() {
+		return x.initializeRunLengthArray(tt)
+	}
+	if x.nullBitmapArray() {
+		return x.initializeNullBitmapArray(tt)
+	}
+	if x.floatColumnArray() {
+		return x.initializeFloatColumnArray(tt)
+	}
+
+	// 3. If the array is of fixed size we already know the length and directly start the extraction.
 	if tt.FixedSize != 0 {
 		x.maxIndex = int(tt.FixedSize) - 1
 		return nil
 	}
 
-	// 3. If the array is of variable size, and the extractor is not in comparable format,
+	// 4. If the array is of variable size, and the extractor is not in comparable format,
 	//    we need to read the length of the array.
 	if !x.opts.Comparable {
-		// 3.1. Read the length of the array.
-		ln, n, err := bstio.ReadUint(x.r, x.opts.Descending)
+		// 4.1. Read the length of the array.
+		ln, n, err := bstio.ReadLength(x.r, x.opts.Descending, bsttype.MinEncodedSize(x.embed.elemType))
 		if err != nil {
 			return err
 		}
 		x.bytesRead += n
 
-		// 3.2. Set the maximum index of the array.
+		// 4.2. Set the maximum index of the array.
 		x.maxIndex = int(ln - 1)
 		return nil
 	}
 
-	// 4. In the comparable format the length of the array is not known upfront.
+	// 5. In the comparable format the length of the array is not known upfront.
 	//    Array binary is terminated by a sequence of 0x02 and 0x01 bytes.
 	//    At the beginning we need to read the raw bytes, and unescape all consecutive 0x00 0x03 bytes.
 	//    Then we need to read elements of the array until we reach io.EOF.
@@ -207,23 +275,26 @@ func (x *Extractor) initializeArray() error {
 		escape = bstio.ArrayEscapeDescending
 	}
 
-	// 5. Read the raw bytes of the array.
-	data, n, err := bstio.ReadComparableBytesReader(x.r, x.opts.Descending, escape)
+	// 6. Read the raw bytes of the array.
+	//    The unescaped descending elements are kept inverted, as they are read in the descending order.
+	//    The unescaped elements are counted once again while they are read, thus their size is subtracted upfront.
+	data, n, err := bstio.ReadComparableBytesReader(x.r, false, escape)
 	if err != nil {
 		return err
 	}
-	x.bytesRead += n
+	x.bytesRead += n - len(data)
 
-	// 6. Wrap the array bytes with a new reader.
+	// 7. Wrap the array bytes with a new reader.
 	ar := iopool.GetReadSeeker(data)
 
-	// 7. Find a number of elements in the array.
+	// 8. Find a number of elements in the array.
 	//    NOTE: we don't know the length of the array, so we need to read the elements until we reach io.EOF.
 	var ln int
 	opts := bstio.ValueOptions{
 		Descending:        x.opts.Descending,
 		Comparable:        x.opts.Comparable,
 		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    x.opts.LengthPrefixedCollections,
 	}
 	for {
 		_, err = bstskip.SkipFuncOf(tt.Type)(ar, opts)
@@ -236,21 +307,191 @@ func (x *Extractor) initializeArray() error {
 		ln++
 	}
 
-	// 8. Reset array reader to the beginning.
+	// 9. Reset array reader to the beginning.
 	_, err = ar.Seek(0, io.SeekStart)
 	if err != nil {
 		return err
 	}
 
-	// 9. Create a wrapped reader, which could be unwrapped at the end of the array extraction.
+	// 10. Create a wrapped reader, which could be unwrapped at the end of the array extraction.
 	//    NOTE: it is important to notice that comparable arrays need to be unwrapped.
 	wr := iopool.WrapReader(ar)
 	x.r = wr
-	x.maxIndex = math.MaxInt
+	x.maxIndex = ln - 1
+
+	return nil
+}
+
+func (x *Extractor) initializeRunLengthArray(tt *bsttype.Array) error {
+	// 1. Find the binary size of the run values.
+	size, err := tt.RunLengthValueSize()
+	if err != nil {
+		return err
+	}
+
+	// 2. Read the length of the variable size array.
+	//    The runs might take fewer bytes than the elements, thus the length is not checked against the input.
+	ln := tt.FixedSize
+	if !tt.HasFixedSize() {
+		var n int
+		ln, n, err = bstio.ReadLength(x.r, x.opts.Descending, 0)
+		if err != nil {
+			return err
+		}
+		x.bytesRead += n
+	}
+
+	// 3. Read and expand the runs.
+	data, n, err := bstio.ReadRunLength(x.r, ln, size, x.opts.Descending)
+	if err != nil {
+		return err
+	}
+
+	// 4. The expanded elements are counted once again while they are read, thus their size is subtracted upfront.
+	x.bytesRead += n - len(data)
 
+	// 5. Create a wrapped reader over the expanded elements, which is unwrapped at the end of the array extraction.
+	x.r = iopool.WrapReader(iopool.GetReadSeeker(data))
+	x.maxIndex = int(ln) - 1
 	return nil
 }
 
+func (x *Extractor) initializeFloatColumnArray(tt *bsttype.Array) error {
+	// 1. Read the column binary, which holds the number of the array elements.
+	data, n, err := bstcol.ReadColumn(x.r)
+	if err != nil {
+		return err
+	}
+	x.bytesRead += n
+
+	// 2. Decode the column values, and encode them back as the array elements, so that they are read as usual.
+	var elems []byte
+	switch k := x.embed.elemType.Kind(); k {
+	case bsttype.KindFloat64:
+		values, err := bstcol.DecodeFloat64Array(nil, data)
+		if err != nil {
+			return err
+		}
+		appendFloat := bstio.AppendFloat64
+		if x.opts.OrderedFloats {
+			appendFloat = bstio.AppendOrderedFloat64
+		}
+		elems = make([]byte, 0, len(values)*8)
+		for _, v := range values {
+			elems = appendFloat(elems, v, x.opts.Descending)
+		}
+	case bsttype.KindFloat32:
+		values, err := bstcol.DecodeFloat32Array(nil, data)
+		if err != nil {
+			return err
+		}
+		appendFloat := bstio.AppendFloat32
+		if x.opts.OrderedFloats {
+			appendFloat = bstio.AppendOrderedFloat32
+		}
+		elems = make([]byte, 0, len(values)*4)
+		for _, v := range values {
+			elems = appendFloat(elems, v, x.opts.Descending)
+		}
+	default:
+		return bsterr.Err(bsterr.CodeInvalidType, "float column array elements need to be floats").
+			WithDetails(bsterr.D("elemKind", k))
+	}
+	ln := len(elems) / bstcol.Width(data)
+
+	// 3. The fixed size array needs to have all its elements.
+	if tt.HasFixedSize() && uint(ln) != tt.FixedSize {
+		return bsterr.Err(bsterr.CodeMalformedBinary, "float column array length doesn't match its fixed size").
+			WithDetails(bsterr.D("length", ln), bsterr.D("fixedSize", tt.FixedSize))
+	}
+
+	// 4. The encoded elements are counted once again while they are read, thus their size is subtracted upfront.
+	x.bytesRead -= len(elems)
+
+	// 5. Create a wrapped reader over the encoded elements, which is unwrapped at the end of the array extraction.
+	x.r = iopool.WrapReader(iopool.GetReadSeeker(elems))
+	x.maxIndex = ln - 1
+	return nil
+}
+
+func (x *Extractor) initializeNullBitmapArray(tt *bsttype.Array) error {
+	// 1. Read the length of the variable size array.
+	//    The null elements take no bytes, thus the length is checked against the bitmap size only.
+	ln := tt.FixedSize
+	if !tt.HasFixedSize() {
+		var (
+			n   int
+			err error
+		)
+		ln, n, err = bstio.ReadLength(x.r, x.opts.Descending, 0)
+		if err != nil {
+			return err
+		}
+		x.bytesRead += n
+	}
+
+	// 2. Read the null bitmap.
+	size := (ln + 7) >> 3
+	if err := bstio.CheckLength(x.r, size, 1); err != nil {
+		return err
+	}
+	bm, n, err := bstio.ReadFixedSizeBytesAppend(x.nullBitmap[:0], x.r, int(size), false)
+	if err != nil {
+		return err
+	}
+	x.bytesRead += n
+	x.nullBitmap = bm
+	x.maxIndex = int(ln) - 1
+	return nil
+}
+
+// nullBitmapArray returns true if the null flags of the extracted array elements are encoded as a bitmap.
+func (x *Extractor) nullBitmapArray() bool {
+	at, ok := x.embedType.(*bsttype.Array)
+	return ok && at.Encoding == bsttype.ArrayEncodingNullBitmap && !x.opts.Comparable
+}
+
+// nullBitmapIsNull returns true if the array element at given index is marked as null in the null bitmap.
+func (x *Extractor) nullBitmapIsNull(index int) bool {
+	return x.nullBitmap[index>>3]&(1<<(index&7)) != 0
+}
+
+// setArrayElemTypes sets up the embedded and expected element types of the array.
+func (x *Extractor) setArrayElemTypes(tt *bsttype.Array) error {
+	x.embed.elemType = tt.Type
+	x.elemType = tt.Type
+	if et, ok := x.opts.ExpectedType.(*bsttype.Array); ok {
+		x.elemType = et.Type
+	}
+	x.elemType, x.err = x.derefType(x.elemType)
+	if x.err != nil {
+		return x.err
+	}
+	x.embed.elemType, x.err = x.derefType(x.embed.elemType)
+	if x.err != nil {
+		return x.err
+	}
+	return nil
+}
+
+// runLengthArray returns true if the elements of the extracted array are run length encoded.
+func (x *Extractor) runLengthArray() bool {
+	at, ok := x.embedType.(*bsttype.Array)
+	return ok && at.Encoding == bsttype.ArrayEncodingRunLength && !x.opts.Comparable
+}
+
+// floatColumnArray returns true if the elements of the extracted array are encoded as the float array column.
+func (x *Extractor) floatColumnArray() bool {
+	at, ok := x.embedType.(*bsttype.Array)
+	return ok && at.Encoding == bsttype.ArrayEncodingFloatColumn && !x.opts.Comparable
+}
+
+// wrappedArrayReader returns true if the array elements are read from a wrapped reader.
+func (x *Extractor) wrappedArrayReader() bool {
+	at := x.embedType.(*bsttype.Array)
+	return (x.opts.Comparable && !at.HasFixedSize()) || x.runLengthArray() || x.floatColumnArray()
+}
+
 func (x *Extractor) nextArrayElem() bool {
 	// 1. If the previous elem was not done, then an error occurred.
 	if !x.elemDone && x.index >= 0 {
@@ -266,8 +507,8 @@ func (x *Extractor) nextArrayElem() bool {
 
 	// 3. Check if all elements of the array were already extracted.
 	if x.index > x.maxIndex {
-		// 3.1. For comparable binaries, a reader was wrapped, thus we need to unwrap and set it back to extractor.
-		if x.opts.Comparable {
+		// 3.1. For comparable and run length encoded binaries, a reader was wrapped, thus we need to unwrap and set it back to extractor.
+		if x.wrappedArrayReader() {
 			wr := x.r.(*iopool.SharedReadSeeker)
 			x.r = wr.Root().(io.ReadSeeker)
 			iopool.ReleaseReadSeeker(wr)
@@ -276,7 +517,14 @@ func (x *Extractor) nextArrayElem() bool {
 		return false
 	}
 
-	// 4. Reset the done flag.
+	// 4. Reset the element types, which might have been dereferenced by the previous element - i.e. Nullable.
+	if x.index > 0 {
+		if x.err = x.setArrayElemTypes(x.embedType.(*bsttype.Array)); x.err != nil {
+			return false
+		}
+	}
+
+	// 5. Reset the done flag.
 	x.elemDone = false
 	return true
 }
@@ -287,20 +535,34 @@ func (x *Extractor) finishArray() error {
 		return nil
 	}
 
-	// 2. Otherwise, try to get the valid type to skip the array elements.
-	elem := x.opts.ExpectedType
-	if !bsttype.TypesEqual(x.embed.elemType, elem) {
-		elem = x.embedType
+	// 2. Otherwise, skip the remaining elements of the embedded type, which might have been dereferenced by the last element.
+	//    The null bitmap array elements are skipped by their not null values.
+	if err := x.setArrayElemTypes(x.embedType.(*bsttype.Array)); err != nil {
+		return err
+	}
+	elem := x.embed.elemType
+	if x.nullBitmapArray() {
+		elem = elem.(*bsttype.Nullable).Type
 	}
-	skipFn := bstskip.SkipFuncOf(elem)
 	opts := bstio.ValueOptions{
 		Descending:        x.opts.Descending,
 		Comparable:        x.opts.Comparable,
 		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    x.opts.LengthPrefixedCollections,
+	}
+	skipFn := bstskip.ElemSkipFuncOf(elem, opts)
+
+	// 3. The current element, if it was not extracted, is skipped along with the remaining ones.
+	if x.index >= 0 && !x.elemDone {
+		x.index--
 	}
 
 	for x.index < x.maxIndex {
-		// 3. Skip the array elements.
+		// 4. Skip the array elements.
+		if x.nullBitmapArray() && x.nullBitmapIsNull(x.index+1) {
+			x.index++
+			continue
+		}
 		n, err := skipFn(x.r, opts)
 		if err != nil {
 			return err
@@ -309,8 +571,8 @@ func (x *Extractor) finishArray() error {
 		x.index++
 	}
 
-	// 3.1. For comparable binaries, a reader was wrapped, thus we need to unwrap and set it back to extractor.
-	if x.opts.Comparable {
+	// 5. For comparable and run length encoded binaries, a reader was wrapped, thus we need to unwrap and set it back to extractor.
+	if x.wrappedArrayReader() {
 		wr := x.r.(*iopool.SharedReadSeeker)
 		x.r = wr.Root().(io.ReadSeeker)
 		iopool.ReleaseReadSeeker(wr)
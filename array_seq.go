@@ -0,0 +1,24 @@
+//go:build go1.23
+// +build go1.23
+
+package bst
+
+import (
+	"iter"
+)
+
+// WriteArraySeq writes an array value of the elements yielded by the iterator, each written by the write function.
+// The length of the iterator is not known upfront, thus the array is written with the undefined length,
+// and buffered until all of its elements are written - see WriteArray. The fixed size array needs the iterator
+// to yield exactly its size elements. It is a function rather than a Composer method, as the methods
+// can't have type parameters.
+func WriteArraySeq[T any](c *Composer, seq iter.Seq[T], write func(c *Composer, v T) error) error {
+	return c.WriteArray(func(c *Composer) error {
+		for v := range seq {
+			if err := write(c, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, 0)
+}
@@ -0,0 +1,97 @@
+//go:build go1.23
+// +build go1.23
+
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestComposerWriteArraySeq(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Tags", Type: bsttype.ArrayOf(bsttype.String())},
+		{Index: 2, Name: "Count", Type: bsttype.Uint32()},
+	}}
+	writeString := func(c *Composer, v string) error { return c.WriteString(v) }
+
+	for _, tags := range [][]string{nil, {"a", "b", "c"}} {
+		for _, opts := range []ComposerOptions{{}, {Descending: true}, {Comparable: true}} {
+			// 1. The iterator binary is the same as the one of the array of undefined length.
+			var seqBuf, arrBuf bytes.Buffer
+			c, err := NewComposer(&seqBuf, st, opts)
+			if err != nil {
+				t.Fatalf("creating composer failed: %v", err)
+			}
+			if err = WriteArraySeq(c, slices.Values(tags), writeString); err != nil {
+				t.Fatalf("writing array seq failed: %v", err)
+			}
+			if err = c.WriteUint32(uint32(len(tags))); err != nil {
+				t.Fatalf("writing count failed: %v", err)
+			}
+			if err = c.Close(); err != nil {
+				t.Fatalf("closing composer failed: %v", err)
+			}
+
+			c, err = NewComposer(&arrBuf, st, opts)
+			if err != nil {
+				t.Fatalf("creating composer failed: %v", err)
+			}
+			if err = c.WriteArray(func(ac *Composer) error {
+				for _, tag := range tags {
+					if err := ac.WriteString(tag); err != nil {
+						return err
+					}
+				}
+				return nil
+			}, 0); err != nil {
+				t.Fatalf("writing array failed: %v", err)
+			}
+			if err = c.WriteUint32(uint32(len(tags))); err != nil {
+				t.Fatalf("writing count failed: %v", err)
+			}
+			if err = c.Close(); err != nil {
+				t.Fatalf("closing composer failed: %v", err)
+			}
+			if !bytes.Equal(seqBuf.Bytes(), arrBuf.Bytes()) {
+				t.Fatalf("binaries of %d elements differ: %x != %x", len(tags), seqBuf.Bytes(), arrBuf.Bytes())
+			}
+
+			// 2. Read back the elements.
+			x, err := NewExtractor(bytes.NewReader(seqBuf.Bytes()), ExtractorOptions{
+				ExpectedType: st,
+				Descending:   opts.Descending,
+				Comparable:   opts.Comparable,
+			})
+			if err != nil {
+				t.Fatalf("creating extractor failed: %v", err)
+			}
+			got, err := x.dynamicStruct(st)
+			if err != nil {
+				t.Fatalf("reading value failed: %v", err)
+			}
+			want := map[string]any{"Tags": []any{}, "Count": uint64(len(tags))}
+			for _, tag := range tags {
+				want["Tags"] = append(want["Tags"].([]any), tag)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("unexpected value: %v, wanted: %v", got, want)
+			}
+		}
+	}
+
+	// The error of the write function aborts the array.
+	c, err := NewComposer(&bytes.Buffer{}, st, ComposerOptions{})
+	if err != nil {
+		t.Fatalf("creating composer failed: %v", err)
+	}
+	errWrite := errors.New("write failed")
+	if err = WriteArraySeq(c, slices.Values([]string{"a"}), func(*Composer, string) error { return errWrite }); err == nil {
+		t.Fatal("expected write error")
+	}
+}
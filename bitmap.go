@@ -0,0 +1,92 @@
+package bst
+
+import (
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// WriteBitmap writes the bitmap value to the composer.
+func (x *Composer) WriteBitmap(v bstio.Bitmap) error {
+	// 1. Check if the element was already written.
+	if x.done {
+		return bsterr.Err(bsterr.CodeAlreadyWritten, "element already written")
+	}
+
+	// 2. Verify if current element matches expected type.
+	if x.elemType.Kind() != bsttype.KindBitmap {
+		return bsterr.Err(bsterr.CodeInvalidType, "invalid type to write").
+			WithDetails(
+				bsterr.D("expected", bsttype.KindBitmap),
+				bsterr.D("actual", x.elemType.Kind()),
+			)
+	}
+
+	// 3. Verify the bitmap, before anything is written.
+	if err := v.Validate(); err != nil {
+		return err
+	}
+
+	// 4. If the base is a struct, check if the field header needs to be written.
+	if x.needWriteFieldHeader() {
+		n, err := x.writeFieldHeader(x.w, x.fieldIndex(), bstio.BitmapBinarySize(v, x.opts.Comparable))
+		if err != nil {
+			return err
+		}
+
+		x.bytesWritten += n
+	}
+
+	// 5. Write the value.
+	n, err := bstio.WriteBitmap(x.w, v, x.elemDesc, x.opts.Comparable)
+	if err != nil {
+		return err
+	}
+
+	x.bytesWritten += n
+	x.countValueOverhead(n, len(v.Bits))
+
+	// 6. Mark the element as written.
+	if err = x.finishElem(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadBitmap reads the bitmap value from the extractor.
+func (x *Extractor) ReadBitmap() (bstio.Bitmap, error) {
+	if x.err != nil {
+		return bstio.Bitmap{}, x.err
+	}
+	// 1. Check if reading element value is already finished.
+	if x.elemDone {
+		return bstio.Bitmap{}, bsterr.Err(bsterr.CodeAlreadyRead, "elem already done")
+	}
+
+	// 2. Check if current element is still in range.
+	if x.index > x.maxIndex {
+		return bstio.Bitmap{}, bsterr.Err(bsterr.CodeOutOfBounds, "buffIndex out of bounds")
+	}
+
+	// 3. Verify if current element matches the expected type.
+	if x.elemType.Kind() != bsttype.KindBitmap {
+		return bstio.Bitmap{}, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
+			WithDetails(
+				bsterr.D("expected", bsttype.KindBitmap),
+				bsterr.D("actual", x.elemType.Kind()),
+			)
+	}
+
+	// 4. Read the bitmap.
+	v, n, err := bstio.ReadBitmap(x.r, x.elemDesc, x.opts.Comparable)
+	x.bytesRead += n
+	if err != nil {
+		return bstio.Bitmap{}, err
+	}
+
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
+	x.finishElem()
+	return v, nil
+}
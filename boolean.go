@@ -58,7 +58,7 @@ func (x *Composer) WriteBoolean(v bool) error {
 
 	// 3. Write the binary value to the boolean buffer.
 	//    For booleans the positive value is defined as '1'
-	if v || x.elemDesc {
+	if v != x.elemDesc {
 		x.boolBuf |= 1 << x.boolBufPos
 	}
 
@@ -68,8 +68,9 @@ func (x *Composer) WriteBoolean(v bool) error {
 	// 5. Check if the boolean buffer is full or if the next element is not a boolean.
 	//    If so, write the boolean buffer to the writer and resetWithRoot it.
 	//	  Otherwise, increment the boolean buffer position.
+	//    The booleans of the run length encoded arrays are not packed.
 	e, ok := x.previewNextElem()
-	if x.boolBufPos == 7 || !ok || (ok && e.Kind() != bsttype.KindBoolean) {
+	if x.boolBufPos == 8 || !ok || (ok && e.Kind() != bsttype.KindBoolean) || x.runLengthArray() {
 		if err := bstio.WriteByte(x.w, x.boolBuf); err != nil {
 			return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write bool")
 		}
@@ -105,15 +106,17 @@ func (x *Extractor) ReadBoolean() (bool, error) {
 			)
 	}
 
-	// 3. Read the bool value.
+	// 3. Read the bool value. The booleans of the run length encoded arrays are not packed.
 	prev, ok := x.previewPrevElem()
-	if !ok || x.boolBufPosition == 0 || (ok && prev.Kind() != bsttype.KindBoolean) {
+	if !ok || x.boolBufPosition == 0 || (ok && prev.Kind() != bsttype.KindBoolean) || x.runLengthArray() {
 		buf, err := bstio.ReadByte(x.r)
 		if err != nil {
 			return false, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read bool value")
 		}
+		x.bytesRead++
 
 		x.boolBuf = buf
+		x.boolBufPosition = 0
 	}
 
 	// 4. Extract the bool value.
@@ -132,6 +135,9 @@ func (x *Extractor) ReadBoolean() (bool, error) {
 	if x.elemDesc {
 		v = !v
 	}
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
@@ -0,0 +1,267 @@
+// Package bstblock packs sorted key-value pairs into blocks, similar to the LSM tree table blocks.
+// The keys are expected to be BST comparable encodings - thus their byte-wise order is the order of the values.
+//
+// Block binary:
+//   - Entries, each composed of: shared key prefix length (uint), unshared key length (uint),
+//     value length (uint), unshared key suffix and value.
+//   - Restart points offsets (uint32 each). The entry at a restart point stores its full key.
+//   - Number of restart points (uint32).
+package bstblock
+
+import (
+	"bytes"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+)
+
+// DefaultRestartInterval is the default number of entries between the restart points.
+const DefaultRestartInterval = 16
+
+// Builder builds a single block from the sorted key-value pairs.
+type Builder struct {
+	restartInterval int
+	buf             bytes.Buffer
+	restarts        []uint32
+	counter         int
+	entries         int
+	lastKey         []byte
+}
+
+// NewBuilder creates a new block builder with given restart interval.
+// If the restartInterval is not positive, the DefaultRestartInterval is used.
+func NewBuilder(restartInterval int) *Builder {
+	if restartInterval <= 0 {
+		restartInterval = DefaultRestartInterval
+	}
+	return &Builder{restartInterval: restartInterval}
+}
+
+// Add adds the key-value pair to the block.
+// The keys need to be added in strictly increasing order.
+func (x *Builder) Add(key, value []byte) error {
+	// 1. Verify the order of the keys.
+	if x.entries > 0 && bytes.Compare(key, x.lastKey) <= 0 {
+		return bsterr.Err(bsterr.CodeInvalidValue, "block keys must be added in strictly increasing order")
+	}
+
+	// 2. Compute the shared prefix, or start a new restart point.
+	var shared int
+	if x.counter < x.restartInterval && x.entries > 0 {
+		shared = sharedPrefixLen(x.lastKey, key)
+	} else {
+		x.restarts = append(x.restarts, uint32(x.buf.Len()))
+		x.counter = 0
+	}
+
+	// 3. Write the entry.
+	_, _ = bstio.WriteUint(&x.buf, uint(shared), false)
+	_, _ = bstio.WriteUint(&x.buf, uint(len(key)-shared), false)
+	_, _ = bstio.WriteUint(&x.buf, uint(len(value)), false)
+	x.buf.Write(key[shared:])
+	x.buf.Write(value)
+
+	// 4. Keep the last key.
+	x.lastKey = append(x.lastKey[:0], key...)
+	x.counter++
+	x.entries++
+	return nil
+}
+
+// Len returns the number of entries added to the block.
+func (x *Builder) Len() int {
+	return x.entries
+}
+
+// EstimatedSize returns the size of the block if it would be finished now.
+func (x *Builder) EstimatedSize() int {
+	return x.buf.Len() + 4*len(x.restarts) + 4
+}
+
+// Finish writes the restart points and returns the block binary.
+// The builder needs to be Reset before building the next block.
+func (x *Builder) Finish() []byte {
+	if len(x.restarts) == 0 {
+		x.restarts = append(x.restarts, 0)
+	}
+	for _, r := range x.restarts {
+		_, _ = bstio.WriteUint32(&x.buf, r, false)
+	}
+	_, _ = bstio.WriteUint32(&x.buf, uint32(len(x.restarts)), false)
+	return x.buf.Bytes()
+}
+
+// Reset clears the builder so that it could be reused for the next block.
+func (x *Builder) Reset() {
+	x.buf.Reset()
+	x.restarts = x.restarts[:0]
+	x.lastKey = x.lastKey[:0]
+	x.counter = 0
+	x.entries = 0
+}
+
+// Block is a read-only view over the block binary.
+type Block struct {
+	data     []byte // entries binary.
+	restarts []uint32
+}
+
+// Open parses the block binary built by the Builder.
+// The block references the input data, which must not be modified.
+func Open(data []byte) (*Block, error) {
+	// 1. Read the number of restart points.
+	if len(data) < 4 {
+		return nil, bsterr.Err(bsterr.CodeMalformedBinary, "block binary is too short")
+	}
+	n, err := bstio.ParseUint32(data[len(data)-4:], false)
+	if err != nil {
+		return nil, err
+	}
+	end := len(data) - 4 - 4*int(n)
+	if n == 0 || end < 0 {
+		return nil, bsterr.Err(bsterr.CodeMalformedBinary, "invalid block restart points").
+			WithDetail("count", n)
+	}
+
+	// 2. Read the restart points offsets.
+	restarts := make([]uint32, n)
+	for i := range restarts {
+		off := end + 4*i
+		restarts[i], _ = bstio.ParseUint32(data[off:off+4], false)
+		if int(restarts[i]) > end {
+			return nil, bsterr.Err(bsterr.CodeMalformedBinary, "block restart point out of range").
+				WithDetail("offset", restarts[i])
+		}
+	}
+	return &Block{data: data[:end], restarts: restarts}, nil
+}
+
+// Iterator creates a new iterator over the block entries.
+func (x *Block) Iterator() *Iterator {
+	return &Iterator{b: x}
+}
+
+// Iterator iterates over the block entries in the key order.
+// A new iterator is positioned before the first entry.
+type Iterator struct {
+	b          *Block
+	key, value []byte
+	next       int // offset of the next entry.
+	valid      bool
+	err        error
+}
+
+// First positions the iterator at the first entry.
+func (x *Iterator) First() bool {
+	x.seekRestart(0)
+	return x.Next()
+}
+
+// Next moves the iterator to the next entry.
+func (x *Iterator) Next() bool {
+	if x.err != nil || x.next >= len(x.b.data) {
+		x.valid = false
+		return false
+	}
+
+	// 1. Read the entry header.
+	r := bytes.NewReader(x.b.data[x.next:])
+	shared, _, err := bstio.ReadUint(r, false)
+	if err != nil {
+		return x.fail(err)
+	}
+	unshared, _, err := bstio.ReadUint(r, false)
+	if err != nil {
+		return x.fail(err)
+	}
+	valueLen, _, err := bstio.ReadUint(r, false)
+	if err != nil {
+		return x.fail(err)
+	}
+	//    Each length is checked on its own, as their sum could overflow.
+	rem := uint(r.Len())
+	if shared > uint(len(x.key)) || unshared > rem || valueLen > rem-unshared {
+		return x.fail(bsterr.Err(bsterr.CodeMalformedBinary, "block entry out of range"))
+	}
+
+	// 2. Reconstruct the key and reference the value.
+	start := len(x.b.data) - r.Len()
+	x.key = append(x.key[:shared], x.b.data[start:start+int(unshared)]...)
+	x.value = x.b.data[start+int(unshared) : start+int(unshared+valueLen)]
+	x.next = start + int(unshared+valueLen)
+	x.valid = true
+	return true
+}
+
+// Seek positions the iterator at the first entry with the key greater or equal to given key.
+// It returns false if there is no such entry.
+func (x *Iterator) Seek(key []byte) bool {
+	// 1. Binary search for the last restart point with the key less than given one.
+	lo, hi := 0, len(x.b.restarts)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		x.seekRestart(mid)
+		if !x.Next() {
+			return false
+		}
+		if bytes.Compare(x.key, key) < 0 {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	// 2. Scan linearly from the restart point.
+	x.seekRestart(lo)
+	for x.Next() {
+		if bytes.Compare(x.key, key) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid returns true if the iterator is positioned at an entry.
+func (x *Iterator) Valid() bool {
+	return x.valid
+}
+
+// Key returns the key of current entry. The key is valid until the next iterator move.
+func (x *Iterator) Key() []byte {
+	return x.key
+}
+
+// Value returns the value of current entry. The value references the block binary.
+func (x *Iterator) Value() []byte {
+	return x.value
+}
+
+// Err returns the error that occurred during the iteration.
+func (x *Iterator) Err() error {
+	return x.err
+}
+
+func (x *Iterator) seekRestart(i int) {
+	x.key = x.key[:0]
+	x.next = int(x.b.restarts[i])
+	x.valid = false
+}
+
+func (x *Iterator) fail(err error) bool {
+	x.err = bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read block entry")
+	x.valid = false
+	return false
+}
+
+func sharedPrefixLen(a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
@@ -0,0 +1,110 @@
+package bstblock
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/devmodules/bst/bstio"
+)
+
+func comparableKey(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	if _, err := bstio.WriteString(&buf, s, false, true); err != nil {
+		t.Fatalf("writing key failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestBlock(t *testing.T) {
+	b := NewBuilder(4)
+	var keys [][]byte
+	for i := 0; i < 50; i++ {
+		k := comparableKey(t, fmt.Sprintf("user/%04d", i*2))
+		keys = append(keys, k)
+		if err := b.Add(k, []byte{byte(i)}); err != nil {
+			t.Fatalf("adding entry failed: %v", err)
+		}
+	}
+
+	if err := b.Add(keys[0], nil); err == nil {
+		t.Fatal("expected error for not increasing key")
+	}
+
+	estimated := b.EstimatedSize()
+	data := b.Finish()
+	if len(data) != estimated {
+		t.Fatalf("unexpected block size: %d, estimated: %d", len(data), estimated)
+	}
+
+	blk, err := Open(data)
+	if err != nil {
+		t.Fatalf("opening block failed: %v", err)
+	}
+
+	t.Run("Iterate", func(t *testing.T) {
+		it := blk.Iterator()
+		var i int
+		for ok := it.First(); ok; ok = it.Next() {
+			if !bytes.Equal(it.Key(), keys[i]) || it.Value()[0] != byte(i) {
+				t.Fatalf("unexpected entry %d: %v %v", i, it.Key(), it.Value())
+			}
+			i++
+		}
+		if it.Err() != nil {
+			t.Fatalf("unexpected error: %v", it.Err())
+		}
+		if i != len(keys) {
+			t.Fatalf("unexpected number of entries: %d", i)
+		}
+	})
+
+	t.Run("Seek", func(t *testing.T) {
+		it := blk.Iterator()
+		for i := 0; i < 100; i++ {
+			ok := it.Seek(comparableKey(t, fmt.Sprintf("user/%04d", i)))
+			expected := (i + 1) / 2
+			if expected >= len(keys) {
+				if ok {
+					t.Fatalf("unexpected entry for %d", i)
+				}
+				continue
+			}
+			if !ok || !bytes.Equal(it.Key(), keys[expected]) {
+				t.Fatalf("unexpected seek result for %d: %v", i, it.Key())
+			}
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		// The entry of the overflowing unshared key and value lengths, followed by a single restart point.
+		var buf bytes.Buffer
+		for _, v := range []uint{0, 1, ^uint(0)} {
+			if _, err := bstio.WriteUint(&buf, v, false); err != nil {
+				t.Fatalf("writing entry header failed: %v", err)
+			}
+		}
+		buf.WriteString("key")
+		buf.Write([]byte{0, 0, 0, 0, 0, 0, 0, 1})
+
+		mb, err := Open(buf.Bytes())
+		if err != nil {
+			t.Fatalf("opening block failed: %v", err)
+		}
+		it := mb.Iterator()
+		if it.First() || it.Err() == nil {
+			t.Fatal("expected malformed entry error")
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		b.Reset()
+		blk, err = Open(b.Finish())
+		if err != nil {
+			t.Fatalf("opening block failed: %v", err)
+		}
+		if blk.Iterator().First() {
+			t.Fatal("unexpected entry in empty block")
+		}
+	})
+}
@@ -0,0 +1,129 @@
+// Package bstbloom builds and queries bloom filters over the BST comparable key encodings.
+// As the comparable encoding of a value is canonical, the filters built from the keys of
+// one storage layer could be used by any other one that uses the same key type.
+//
+// Filter binary:
+//   - Number of hash functions (1 byte).
+//   - Bit array.
+package bstbloom
+
+import (
+	"hash/fnv"
+	"math"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// DefaultBitsPerKey is the default number of filter bits per key, which gives roughly 1% false positive rate.
+const DefaultBitsPerKey = 10
+
+// maxHashes is the maximum number of hash functions used by the filter.
+const maxHashes = 30
+
+// Builder builds a bloom filter from the stream of keys.
+type Builder struct {
+	bitsPerKey int
+	hashes     []uint64
+}
+
+// NewBuilder creates a new filter builder with given number of bits per key.
+// If the bitsPerKey is not positive, the DefaultBitsPerKey is used.
+func NewBuilder(bitsPerKey int) *Builder {
+	if bitsPerKey <= 0 {
+		bitsPerKey = DefaultBitsPerKey
+	}
+	return &Builder{bitsPerKey: bitsPerKey}
+}
+
+// Add adds the comparable encoded key to the filter.
+// Only the key hash is kept, thus the key could be reused after this call.
+func (x *Builder) Add(key []byte) {
+	x.hashes = append(x.hashes, hashKey(key))
+}
+
+// Len returns the number of keys added to the builder.
+func (x *Builder) Len() int {
+	return len(x.hashes)
+}
+
+// Build creates the filter binary from all the added keys.
+func (x *Builder) Build() Filter {
+	// 1. Compute the optimal number of hash functions: k = ln(2) * m/n.
+	k := int(math.Round(float64(x.bitsPerKey) * math.Ln2))
+	if k < 1 {
+		k = 1
+	} else if k > maxHashes {
+		k = maxHashes
+	}
+
+	// 2. Compute the size of the bit array, with at least 64 bits for the small sets.
+	bits := len(x.hashes) * x.bitsPerKey
+	if bits < 64 {
+		bits = 64
+	}
+	nBytes := (bits + 7) / 8
+	bits = nBytes * 8
+
+	// 3. Set the bits of each key.
+	f := make(Filter, 1+nBytes)
+	f[0] = byte(k)
+	for _, h := range x.hashes {
+		f.set(h, k, uint64(bits))
+	}
+	return f
+}
+
+// Reset clears the builder, so that it could be used to build another filter.
+func (x *Builder) Reset() {
+	x.hashes = x.hashes[:0]
+}
+
+// Filter is the bloom filter binary.
+type Filter []byte
+
+// Validate checks if the filter binary is well-formed.
+func (f Filter) Validate() error {
+	if len(f) < 2 {
+		return bsterr.Err(bsterr.CodeMalformedBinary, "bloom filter binary is too short")
+	}
+	if f[0] == 0 || f[0] > maxHashes {
+		return bsterr.Err(bsterr.CodeMalformedBinary, "invalid bloom filter hash functions number").
+			WithDetail("hashes", f[0])
+	}
+	return nil
+}
+
+// MayContain returns false if the key is definitely not in the set, and true if it may be in it.
+// A malformed filter always returns true, so that it never excludes a key by mistake.
+func (f Filter) MayContain(key []byte) bool {
+	if f.Validate() != nil {
+		return true
+	}
+	k := int(f[0])
+	bits := uint64(len(f)-1) * 8
+
+	// Double hashing: the i-th hash is h1 + i*h2.
+	h := hashKey(key)
+	h1, h2 := h&math.MaxUint32, h>>32
+	for i := 0; i < k; i++ {
+		pos := (h1 + uint64(i)*h2) % bits
+		if f[1+pos/8]&(1<<(pos%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f Filter) set(h uint64, k int, bits uint64) {
+	h1, h2 := h&math.MaxUint32, h>>32
+	for i := 0; i < k; i++ {
+		pos := (h1 + uint64(i)*h2) % bits
+		f[1+pos/8] |= 1 << (pos % 8)
+	}
+}
+
+func hashKey(key []byte) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(key)
+	return h.Sum64()
+}
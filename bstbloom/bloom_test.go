@@ -0,0 +1,50 @@
+package bstbloom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/devmodules/bst/bstio"
+)
+
+func TestFilter(t *testing.T) {
+	key := func(i uint64) []byte {
+		var buf bytes.Buffer
+		if _, err := bstio.WriteUint64(&buf, i, false); err != nil {
+			t.Fatalf("writing key failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	b := NewBuilder(DefaultBitsPerKey)
+	const n = 1000
+	for i := uint64(0); i < n; i++ {
+		b.Add(key(i))
+	}
+	f := b.Build()
+	if err := f.Validate(); err != nil {
+		t.Fatalf("invalid filter: %v", err)
+	}
+
+	for i := uint64(0); i < n; i++ {
+		if !f.MayContain(key(i)) {
+			t.Fatalf("false negative for key %d", i)
+		}
+	}
+
+	var falsePositives int
+	for i := uint64(n); i < 2*n; i++ {
+		if f.MayContain(key(i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > n/20 {
+		t.Fatalf("too many false positives: %d", falsePositives)
+	}
+
+	t.Run("Malformed", func(t *testing.T) {
+		if !Filter(nil).MayContain(key(1)) {
+			t.Fatal("malformed filter must not exclude keys")
+		}
+	})
+}
@@ -0,0 +1,262 @@
+// Package bstcdc provides a standard change data capture (CDC) event envelope,
+// so that the pipelines which stream changes of the BST encoded rows share a single wire format.
+package bstcdc
+
+import (
+	"bytes"
+	"hash/fnv"
+	"time"
+
+	"github.com/devmodules/bst"
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+// Op is the operation of the change event.
+type Op uint8
+
+// Enumerated change event operations.
+const (
+	OpInsert Op = iota
+	OpUpdate
+	OpDelete
+	OpSnapshot
+)
+
+// String returns a human-readable name of the operation.
+func (o Op) String() string {
+	switch o {
+	case OpInsert:
+		return "insert"
+	case OpUpdate:
+		return "update"
+	case OpDelete:
+		return "delete"
+	case OpSnapshot:
+		return "snapshot"
+	default:
+		return "unknown"
+	}
+}
+
+// Change event struct field indices.
+const (
+	FieldOp uint = iota + 1
+	FieldKey
+	FieldBefore
+	FieldAfter
+	FieldSchemaFingerprint
+	FieldTimestamp
+)
+
+var _changeEventType = &bsttype.Struct{
+	Fields: []bsttype.StructField{
+		{
+			Index: FieldOp,
+			Name:  "Op",
+			Type: &bsttype.Enum{
+				ValueBytes: 1,
+				Elements: []bsttype.EnumElement{
+					{String: OpInsert.String(), Index: uint(OpInsert)},
+					{String: OpUpdate.String(), Index: uint(OpUpdate)},
+					{String: OpDelete.String(), Index: uint(OpDelete)},
+					{String: OpSnapshot.String(), Index: uint(OpSnapshot)},
+				},
+			},
+		},
+		{Index: FieldKey, Name: "Key", Type: &bsttype.Bytes{}},
+		{Index: FieldBefore, Name: "Before", Type: bsttype.NullableOf(&bsttype.Bytes{})},
+		{Index: FieldAfter, Name: "After", Type: bsttype.NullableOf(&bsttype.Bytes{})},
+		{Index: FieldSchemaFingerprint, Name: "SchemaFingerprint", Type: bsttype.Uint64()},
+		{Index: FieldTimestamp, Name: "Timestamp", Type: bsttype.Timestamp()},
+	},
+}
+
+// ChangeEventType returns the struct type of the change event envelope.
+// The returned type must not be modified.
+func ChangeEventType() *bsttype.Struct {
+	return _changeEventType
+}
+
+// ChangeEvent is the envelope of a single change of the row.
+// The Key, Before and After are the BST encoded binaries of the row key and the row values.
+// A nil Before or After is stored as a null value - i.e. the Before of the insert event.
+type ChangeEvent struct {
+	Op                Op
+	Key               []byte
+	Before            []byte
+	After             []byte
+	SchemaFingerprint uint64
+	Timestamp         time.Time
+}
+
+// Validate checks if the change event contains the row images required by its operation.
+func (e *ChangeEvent) Validate() error {
+	switch e.Op {
+	case OpInsert, OpSnapshot:
+		if e.After == nil {
+			return bsterr.Errf(bsterr.CodeInvalidValue, "%s change event requires after value", e.Op)
+		}
+	case OpUpdate:
+		if e.Before == nil || e.After == nil {
+			return bsterr.Err(bsterr.CodeInvalidValue, "update change event requires before and after values")
+		}
+	case OpDelete:
+		if e.Before == nil {
+			return bsterr.Err(bsterr.CodeInvalidValue, "delete change event requires before value")
+		}
+	default:
+		return bsterr.Err(bsterr.CodeInvalidValue, "unknown change event operation").
+			WithDetail("op", uint8(e.Op))
+	}
+	return nil
+}
+
+// Fingerprint computes the schema fingerprint of the given type.
+// The fingerprint is the FNV-1a hash of the type binary, thus it changes with any change of the type,
+// apart from its doc strings, which are omitted.
+func Fingerprint(t bsttype.Type) (uint64, error) {
+	buf := iopool.GetBuffer(nil)
+	defer iopool.ReleaseBuffer(buf)
+
+	if _, err := bsttype.WriteTypeWithOptions(buf, t, bsttype.WriteTypeOptions{OmitDocs: true}); err != nil {
+		return 0, err
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(buf.Bytes)
+	return h.Sum64(), nil
+}
+
+// Write writes the change event as the next value of the composer.
+// The composer current element needs to be of the ChangeEventType.
+func Write(c *bst.Composer, e *ChangeEvent) error {
+	return c.WriteStruct(func(sc *bst.Composer) error {
+		return writeFields(sc, e)
+	})
+}
+
+// Read reads the change event as the next value of the extractor.
+// The extractor current element needs to be of the ChangeEventType.
+func Read(x *bst.Extractor) (*ChangeEvent, error) {
+	var e ChangeEvent
+	err := x.ReadStruct(func(sx *bst.Extractor) error {
+		return readFields(sx, &e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Marshal encodes the change event with given options.
+// The event type is never embedded - the ChangeEventType is well-known.
+func Marshal(e *ChangeEvent, options bst.ComposerOptions) ([]byte, error) {
+	// 1. Validate the event.
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	// 2. Compose the event struct.
+	var buf bytes.Buffer
+	options.EmbedType = false
+	c, err := bst.NewComposer(&buf, _changeEventType, options)
+	if err != nil {
+		return nil, err
+	}
+	if err = writeFields(c, e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the change event encoded by the Marshal function.
+func Unmarshal(data []byte) (*ChangeEvent, error) {
+	r := iopool.GetReadSeeker(data)
+	defer iopool.ReleaseReadSeeker(r)
+
+	x, err := bst.NewExtractor(r, bst.ExtractorOptions{ExpectedType: _changeEventType})
+	if err != nil {
+		return nil, err
+	}
+	defer x.Close()
+
+	var e ChangeEvent
+	if err = readFields(x, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func writeFields(c *bst.Composer, e *ChangeEvent) error {
+	// 1. Op.
+	if err := c.WriteEnumIndex(int(e.Op)); err != nil {
+		return err
+	}
+
+	// 2. Key.
+	if err := c.WriteBytes(e.Key); err != nil {
+		return err
+	}
+
+	// 3. Before and After row images.
+	for _, v := range [][]byte{e.Before, e.After} {
+		if v == nil {
+			if err := c.WriteNull(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := c.WriteNotNull(); err != nil {
+			return err
+		}
+		if err := c.WriteBytes(v); err != nil {
+			return err
+		}
+	}
+
+	// 4. Schema fingerprint.
+	if err := c.WriteUint64(e.SchemaFingerprint); err != nil {
+		return err
+	}
+
+	// 5. Timestamp.
+	return c.WriteTimestamp(e.Timestamp)
+}
+
+func readFields(x *bst.Extractor, e *ChangeEvent) error {
+	for x.Next() {
+		var err error
+		switch x.Index() {
+		case 0:
+			var op uint
+			op, err = x.ReadEnumIndex()
+			e.Op = Op(op)
+		case 1:
+			e.Key, err = x.ReadBytes()
+		case 2:
+			e.Before, err = readNullableBytes(x)
+		case 3:
+			e.After, err = readNullableBytes(x)
+		case 4:
+			e.SchemaFingerprint, err = x.ReadUint64()
+		case 5:
+			e.Timestamp, err = x.ReadTimestamp()
+		default:
+			_, err = x.Skip()
+		}
+		if err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read change event field").
+				WithDetail("field", x.FieldName())
+		}
+	}
+	return x.Err()
+}
+
+func readNullableBytes(x *bst.Extractor) ([]byte, error) {
+	isNull, err := x.IsNull()
+	if err != nil || isNull {
+		return nil, err
+	}
+	return x.ReadBytes()
+}
@@ -0,0 +1,89 @@
+package bstcdc
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/devmodules/bst"
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestMarshal(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fp, err := Fingerprint(bsttype.String())
+	if err != nil {
+		t.Fatalf("computing fingerprint failed: %v", err)
+	}
+
+	testCases := []struct {
+		Name  string
+		Event ChangeEvent
+	}{
+		{
+			Name:  "Insert",
+			Event: ChangeEvent{Op: OpInsert, Key: []byte{0x01}, After: []byte("after"), SchemaFingerprint: fp, Timestamp: ts},
+		},
+		{
+			Name:  "Update",
+			Event: ChangeEvent{Op: OpUpdate, Key: []byte{0x02}, Before: []byte("before"), After: []byte("after"), SchemaFingerprint: fp, Timestamp: ts},
+		},
+		{
+			Name:  "Delete",
+			Event: ChangeEvent{Op: OpDelete, Key: []byte{0x03}, Before: []byte("before"), SchemaFingerprint: fp, Timestamp: ts},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			data, err := Marshal(&tc.Event, bst.ComposerOptions{})
+			if err != nil {
+				t.Fatalf("marshaling event failed: %v", err)
+			}
+
+			got, err := Unmarshal(data)
+			if err != nil {
+				t.Fatalf("unmarshaling event failed: %v", err)
+			}
+
+			if got.Op != tc.Event.Op {
+				t.Errorf("unexpected op: %v, expected: %v", got.Op, tc.Event.Op)
+			}
+			if !bytes.Equal(got.Key, tc.Event.Key) {
+				t.Errorf("unexpected key: %v, expected: %v", got.Key, tc.Event.Key)
+			}
+			if (got.Before == nil) != (tc.Event.Before == nil) || !bytes.Equal(got.Before, tc.Event.Before) {
+				t.Errorf("unexpected before: %v, expected: %v", got.Before, tc.Event.Before)
+			}
+			if (got.After == nil) != (tc.Event.After == nil) || !bytes.Equal(got.After, tc.Event.After) {
+				t.Errorf("unexpected after: %v, expected: %v", got.After, tc.Event.After)
+			}
+			if got.SchemaFingerprint != fp {
+				t.Errorf("unexpected fingerprint: %v, expected: %v", got.SchemaFingerprint, fp)
+			}
+			if !got.Timestamp.Equal(ts) {
+				t.Errorf("unexpected timestamp: %v, expected: %v", got.Timestamp, ts)
+			}
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := Marshal(&ChangeEvent{Op: OpUpdate, After: []byte("after")}, bst.ComposerOptions{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestFingerprint(t *testing.T) {
+	a, err := Fingerprint(bsttype.String())
+	if err != nil {
+		t.Fatalf("computing fingerprint failed: %v", err)
+	}
+	b, err := Fingerprint(bsttype.Int64())
+	if err != nil {
+		t.Fatalf("computing fingerprint failed: %v", err)
+	}
+	if a == b {
+		t.Fatal("fingerprints of different types are equal")
+	}
+}
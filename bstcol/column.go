@@ -0,0 +1,315 @@
+// Package bstcol provides the bulk column codecs of the float arrays, which prepare the values for the
+// downstream general purpose compression (i.e. zstd).
+//
+// Column binary:
+//   - Header byte: the element width flag (0x80 for float64) and the transform flags (ArrayTransform).
+//   - Number of values (bstio uint).
+//   - Values data - the IEEE-754 bits of each value in little endian order, after the transforms are applied.
+//
+// The transforms are lossless:
+//   - Delta replaces the bits of each value with the XOR of its bits and the bits of the previous value.
+//     Consecutive sensor readings share their sign, exponent and high mantissa bits, thus the result is mostly zero bytes.
+//   - Shuffle transposes the data so that the i-th byte of all the values is stored in the i-th plane.
+//     Similar bytes are stored next to each other, which makes the data far more compressible.
+package bstcol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+)
+
+// ArrayTransform is a set of the transforms applied on the float array values before they are written.
+type ArrayTransform uint8
+
+// Enumerated array transforms. The values are a part of the binary format and never change.
+const (
+	TransformShuffle ArrayTransform = 1 << iota
+	TransformDelta
+)
+
+const (
+	_transformMask = TransformShuffle | TransformDelta
+	_float64Flag   = 0x80
+)
+
+// ArrayEncodingOptions are the options of the float array column encoding.
+type ArrayEncodingOptions struct {
+	// Shuffle enables the byte shuffle (transpose) of the values.
+	Shuffle bool
+	// Delta enables the XOR delta of the consecutive values.
+	Delta bool
+}
+
+// ForCompression returns the options which make the float arrays the most compressible - both transforms are enabled.
+func ForCompression() ArrayEncodingOptions {
+	return ArrayEncodingOptions{Shuffle: true, Delta: true}
+}
+
+// Transform returns the transforms enabled by the options.
+func (x ArrayEncodingOptions) Transform() ArrayTransform {
+	var t ArrayTransform
+	if x.Shuffle {
+		t |= TransformShuffle
+	}
+	if x.Delta {
+		t |= TransformDelta
+	}
+	return t
+}
+
+// AppendFloat64Array appends the column encoding of the values to the dst and returns the extended slice.
+func AppendFloat64Array(dst []byte, values []float64, opts ArrayEncodingOptions) []byte {
+	return appendArray(dst, len(values), 8, opts, func(i int) uint64 {
+		return math.Float64bits(values[i])
+	})
+}
+
+// AppendFloat32Array appends the column encoding of the values to the dst and returns the extended slice.
+func AppendFloat32Array(dst []byte, values []float32, opts ArrayEncodingOptions) []byte {
+	return appendArray(dst, len(values), 4, opts, func(i int) uint64 {
+		return uint64(math.Float32bits(values[i]))
+	})
+}
+
+// DecodeFloat64Array decodes the float64 column binary, appends its values to the dst and returns the extended slice.
+// On error the dst is returned unchanged.
+func DecodeFloat64Array(dst []float64, data []byte) ([]float64, error) {
+	n := len(dst)
+	err := decodeArray(data, 8, func(count int) {
+		dst = growFloats(dst, count)
+	}, func(i int, bits uint64) {
+		dst[n+i] = math.Float64frombits(bits)
+	})
+	if err != nil {
+		return dst[:n], err
+	}
+	return dst, nil
+}
+
+// DecodeFloat32Array decodes the float32 column binary, appends its values to the dst and returns the extended slice.
+// On error the dst is returned unchanged.
+func DecodeFloat32Array(dst []float32, data []byte) ([]float32, error) {
+	n := len(dst)
+	err := decodeArray(data, 4, func(count int) {
+		dst = growFloats(dst, count)
+	}, func(i int, bits uint64) {
+		dst[n+i] = math.Float32frombits(uint32(bits))
+	})
+	if err != nil {
+		return dst[:n], err
+	}
+	return dst, nil
+}
+
+// ReadColumn reads the float array column binary out of the reader, i.e. the one composed as the array element binaries.
+// Returns the column binary, which could be decoded by the DecodeFloat64Array or DecodeFloat32Array depending on its
+// Width, along with the number of bytes read. The values data is read as it comes, thus a malformed number of values
+// fails on the end of input rather than on the allocation.
+func ReadColumn(r io.Reader) ([]byte, int, error) {
+	// 1. Read and verify the header.
+	header, err := bstio.ReadByte(r)
+	if err != nil {
+		return nil, 0, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read float array column header")
+	}
+	width, err := headerWidth(header)
+	if err != nil {
+		return nil, 1, err
+	}
+
+	// 2. Read the number of values, and verify it against the remaining input.
+	count, n, err := bstio.ReadUint(r, false)
+	if err != nil {
+		return nil, 1 + n, err
+	}
+	size, err := dataSize(count, width)
+	if err != nil {
+		return nil, 1 + n, err
+	}
+	if err = bstio.CheckLength(r, count, width); err != nil {
+		return nil, 1 + n, err
+	}
+
+	// 3. Read the values data.
+	buf := bytes.NewBuffer(make([]byte, 0, 1+n+int(min(size, 1<<16))))
+	buf.WriteByte(header)
+	buf.Write(bstio.MarshalUint(count, false))
+	read, err := io.CopyN(buf, r, size)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 1 + n + int(read), bsterr.ErrWrap(err, bsterr.CodeTruncatedBinary, "failed to read float array column values").
+			WithDetails(bsterr.D("count", count), bsterr.D("width", width))
+	}
+	return buf.Bytes(), buf.Len(), nil
+}
+
+// SkipColumn skips the float array column binary of the read seeker. Returns the number of bytes skipped.
+func SkipColumn(rs io.ReadSeeker) (int64, error) {
+	// 1. Read and verify the header.
+	header, err := bstio.ReadByte(rs)
+	if err != nil {
+		return 0, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read float array column header")
+	}
+	width, err := headerWidth(header)
+	if err != nil {
+		return 1, err
+	}
+
+	// 2. Read the number of values, and seek over their data.
+	count, n, err := bstio.ReadUint(rs, false)
+	if err != nil {
+		return int64(1 + n), err
+	}
+	size, err := dataSize(count, width)
+	if err != nil {
+		return int64(1 + n), err
+	}
+	if err = bstio.CheckLength(rs, count, width); err != nil {
+		return int64(1 + n), err
+	}
+	if _, err = rs.Seek(size, io.SeekCurrent); err != nil {
+		return int64(1 + n), bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to skip float array column values")
+	}
+	return int64(1+n) + size, nil
+}
+
+// Width returns the binary width of the values of the column binary, i.e. 4 for the float32 and 8 for the float64.
+// It returns 0 for the empty binary.
+func Width(data []byte) int {
+	if len(data) == 0 {
+		return 0
+	}
+	if data[0]&_float64Flag != 0 {
+		return 8
+	}
+	return 4
+}
+
+// headerWidth verifies the column header, and returns the width of its values.
+func headerWidth(header byte) (int, error) {
+	if ArrayTransform(header&^_float64Flag)&^_transformMask != 0 {
+		return 0, bsterr.Err(bsterr.CodeDecodingBinaryValue, "unknown float array column transform").
+			WithDetail("header", header)
+	}
+	if header&_float64Flag != 0 {
+		return 8, nil
+	}
+	return 4, nil
+}
+
+// dataSize returns the size of the data of count values of given width, unless it overflows.
+func dataSize(count uint, width int) (int64, error) {
+	if count > uint(math.MaxInt64/width) {
+		return 0, bsterr.Err(bsterr.CodeMalformedBinary, "float array column number of values is too large").
+			WithDetail("count", count)
+	}
+	return int64(count) * int64(width), nil
+}
+
+func appendArray(dst []byte, count, width int, opts ArrayEncodingOptions, bitsAt func(i int) uint64) []byte {
+	// 1. Write the header and the number of values.
+	t := opts.Transform()
+	header := byte(t)
+	if width == 8 {
+		header |= _float64Flag
+	}
+	dst = append(dst, header)
+	dst = append(dst, bstio.MarshalUint(uint(count), false)...)
+
+	// 2. Reserve the values data.
+	start := len(dst)
+	dst = append(dst, make([]byte, count*width)...)
+	data := dst[start:]
+
+	// 3. Write the values, applying the delta transform and the shuffle on the fly.
+	var prev uint64
+	var buf [8]byte
+	for i := 0; i < count; i++ {
+		bits := bitsAt(i)
+		v := bits
+		if t&TransformDelta != 0 {
+			v ^= prev
+			prev = bits
+		}
+		binary.LittleEndian.PutUint64(buf[:], v)
+		if t&TransformShuffle != 0 {
+			for j := 0; j < width; j++ {
+				data[j*count+i] = buf[j]
+			}
+			continue
+		}
+		copy(data[i*width:], buf[:width])
+	}
+	return dst
+}
+
+func decodeArray(data []byte, width int, grow func(count int), set func(i int, bits uint64)) error {
+	// 1. Read and verify the header.
+	if len(data) == 0 {
+		return bsterr.Err(bsterr.CodeTruncatedBinary, "float array column header is missing")
+	}
+	header := data[0]
+	if (header&_float64Flag != 0) != (width == 8) {
+		return bsterr.Err(bsterr.CodeMismatchingValueType, "float array column width doesn't match").
+			WithDetails(bsterr.D("header", header), bsterr.D("expectedWidth", width))
+	}
+	t := ArrayTransform(header &^ _float64Flag)
+	if t&^_transformMask != 0 {
+		return bsterr.Err(bsterr.CodeDecodingBinaryValue, "unknown float array column transform").
+			WithDetail("header", header)
+	}
+
+	// 2. Read the number of values and check it against the remaining data.
+	br := bytes.NewReader(data[1:])
+	count, _, err := bstio.ReadUint(br, false)
+	if err != nil {
+		return err
+	}
+	values := data[len(data)-br.Len():]
+	if count > uint(len(values)/width) || int(count)*width != len(values) {
+		return bsterr.Err(bsterr.CodeMalformedBinary, "float array column data size doesn't match the number of values").
+			WithDetails(
+				bsterr.D("count", count),
+				bsterr.D("width", width),
+				bsterr.D("dataSize", len(values)),
+			)
+	}
+	n := int(count)
+	grow(n)
+
+	// 3. Read the values, reverting the shuffle and the delta transform.
+	var prev uint64
+	var buf [8]byte
+	for i := 0; i < n; i++ {
+		if t&TransformShuffle != 0 {
+			for j := 0; j < width; j++ {
+				buf[j] = values[j*n+i]
+			}
+		} else {
+			copy(buf[:width], values[i*width:])
+		}
+		bits := binary.LittleEndian.Uint64(buf[:])
+		if t&TransformDelta != 0 {
+			bits ^= prev
+			prev = bits
+		}
+		set(i, bits)
+	}
+	return nil
+}
+
+func growFloats[T float32 | float64](dst []T, n int) []T {
+	if cap(dst)-len(dst) < n {
+		nd := make([]T, len(dst), len(dst)+n)
+		copy(nd, dst)
+		dst = nd
+	}
+	return dst[:len(dst)+n]
+}
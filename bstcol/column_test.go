@@ -0,0 +1,93 @@
+package bstcol
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloat64Array(t *testing.T) {
+	values := []float64{20.5, 20.51, 20.53, -0.0, math.Inf(1), math.NaN(), 1e-300, 20.49}
+	for _, opts := range []ArrayEncodingOptions{{}, {Shuffle: true}, {Delta: true}, ForCompression()} {
+		data := AppendFloat64Array([]byte{0xAA}, values, opts)
+		if data[0] != 0xAA {
+			t.Fatalf("dst prefix overwritten: %v", opts)
+		}
+
+		got, err := DecodeFloat64Array([]float64{1}, data[1:])
+		if err != nil {
+			t.Fatalf("decoding failed (%+v): %v", opts, err)
+		}
+		if len(got) != len(values)+1 || got[0] != 1 {
+			t.Fatalf("unexpected decoded values (%+v): %v", opts, got)
+		}
+		for i, v := range values {
+			if math.Float64bits(got[i+1]) != math.Float64bits(v) {
+				t.Fatalf("value %d mismatch (%+v): %v != %v", i, opts, got[i+1], v)
+			}
+		}
+	}
+}
+
+func TestFloat32Array(t *testing.T) {
+	values := []float32{1.5, 1.25, -3, 0, float32(math.Inf(-1))}
+	for _, opts := range []ArrayEncodingOptions{{}, {Shuffle: true}, {Delta: true}, ForCompression()} {
+		data := AppendFloat32Array(nil, values, opts)
+		got, err := DecodeFloat32Array(nil, data)
+		if err != nil {
+			t.Fatalf("decoding failed (%+v): %v", opts, err)
+		}
+		if len(got) != len(values) {
+			t.Fatalf("unexpected decoded values (%+v): %v", opts, got)
+		}
+		for i, v := range values {
+			if got[i] != v {
+				t.Fatalf("value %d mismatch (%+v): %v != %v", i, opts, got[i], v)
+			}
+		}
+	}
+}
+
+func TestArrayShuffleZeroBytes(t *testing.T) {
+	// Slowly changing readings should produce long runs of zero bytes after the transforms.
+	values := make([]float64, 256)
+	for i := range values {
+		values[i] = 100 + float64(i)*0.25
+	}
+	zeros := func(b []byte) (n int) {
+		for _, c := range b {
+			if c == 0 {
+				n++
+			}
+		}
+		return n
+	}
+	plain := AppendFloat64Array(nil, values, ArrayEncodingOptions{})
+	transformed := AppendFloat64Array(nil, values, ForCompression())
+	if zeros(transformed) <= zeros(plain) {
+		t.Fatalf("expected more zero bytes after transforms: %d <= %d", zeros(transformed), zeros(plain))
+	}
+}
+
+func TestArrayInvalid(t *testing.T) {
+	data := AppendFloat64Array(nil, []float64{1, 2, 3}, ForCompression())
+
+	if _, err := DecodeFloat32Array(nil, data); err == nil {
+		t.Fatal("expected error decoding float64 column as float32")
+	}
+	dst := []float64{7}
+	got, err := DecodeFloat64Array(dst, data[:len(data)-1])
+	if err == nil {
+		t.Fatal("expected error decoding truncated column")
+	}
+	if len(got) != 1 || got[0] != 7 {
+		t.Fatalf("dst should be returned unchanged on error: %v", got)
+	}
+	if _, err = DecodeFloat64Array(nil, nil); err == nil {
+		t.Fatal("expected error decoding empty column")
+	}
+	bad := append([]byte{}, data...)
+	bad[0] |= 0x10
+	if _, err = DecodeFloat64Array(nil, bad); err == nil {
+		t.Fatal("expected error decoding unknown transform")
+	}
+}
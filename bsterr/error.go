@@ -40,6 +40,10 @@ const (
 	CodeConvertingIntoBinaryValue ErrCode = 2005
 	// CodeValueFieldMissing is the error code for missing value field.
 	CodeValueFieldMissing ErrCode = 2006
+	// CodeTruncatedBinary is the error code for the binary input that ends in the middle of a value.
+	CodeTruncatedBinary ErrCode = 2007
+	// CodeEndOfInput is the error code for the binary input that ends cleanly, before the start of a value.
+	CodeEndOfInput ErrCode = 2008
 
 	// CodeEncodingTypeUndefined is the error code for undefined value type for encoding.
 	CodeEncodingTypeUndefined ErrCode = 3001
@@ -76,6 +80,18 @@ const (
 	CodeCyclicDependency ErrCode = 6008
 	// CodeModulesUndefined is an error code for situation where the modules are undefined.
 	CodeModulesUndefined ErrCode = 6009
+	// CodeConcurrentUse is an error code for situation where the composer or extractor is used by multiple goroutines.
+	CodeConcurrentUse ErrCode = 6010
+	// CodeValueTooLarge is an error code for situation where the composed value exceeds its maximum binary size.
+	CodeValueTooLarge ErrCode = 6011
+	// CodeBudgetExceeded is an error code for situation where the extracted values exceed their decode budget.
+	CodeBudgetExceeded ErrCode = 6012
+	// CodeFrameOverrun is an error code for situation where the extracted value overruns its frame.
+	CodeFrameOverrun ErrCode = 6013
+	// CodeInvalidSignature is an error code for situation where the signature of the value is missing or invalid.
+	CodeInvalidSignature ErrCode = 6014
+	// CodeChecksumMismatch is an error code for situation where the checksum trailer of the value is missing or invalid.
+	CodeChecksumMismatch ErrCode = 6015
 )
 
 var _ error = (*Error)(nil)
@@ -89,12 +105,9 @@ type Error struct {
 }
 
 // Error implements the error interface.
+// The details are redacted by the DefaultRedaction.
 func (e *Error) Error() string {
-	str := fmt.Sprintf("%d: %s - %s", e.Code, e.Msg, Ds(e.Details))
-	if e.Wrapped != nil {
-		str += fmt.Sprintf("\n%s", e.Wrapped)
-	}
-	return str
+	return e.Redacted(DefaultRedaction)
 }
 
 // WithDetail sets the details of the error.
@@ -150,10 +163,13 @@ func D(key string, value interface{}) ErrorDetail {
 type ErrorDetail struct {
 	Key   string
 	Value interface{}
+	// PII annotates the value as personally identifiable, or otherwise sensitive, thus it is never formatted.
+	PII bool
 }
 
+// String returns the detail with its value redacted by the DefaultRedaction.
 func (d ErrorDetail) String() string {
-	return fmt.Sprintf("%s: %v", d.Key, d.Value)
+	return DefaultRedaction.String(d)
 }
 
 // WithDetails sets the details of the error.
@@ -0,0 +1,128 @@
+package bsterr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Redaction defines how the values of the error details are redacted when the error is formatted,
+// so that the logged errors do not leak the contents of the sensitive payloads, while still being diagnosable.
+// The values of the PII details are never formatted. The strings and bytes longer than the MaxLength
+// are truncated, or replaced with their hash. Other values are formatted as is.
+type Redaction struct {
+	// MaxLength is the number of bytes of the string and bytes values formatted as is.
+	// The longer values are truncated to this length. The zero value means no limit.
+	MaxLength int
+	// Hash replaces the long values with the prefix of their SHA-256 hash, instead of truncating them,
+	// and adds the hash to the redacted PII values. It lets the equal values be correlated across the logs.
+	Hash bool
+}
+
+// DefaultRedaction is the redaction applied by the Error and MarshalText methods of the Error.
+var DefaultRedaction = Redaction{MaxLength: 256}
+
+// redactedHashSize is the number of the hex encoded bytes of the value hash.
+const redactedHashSize = 8
+
+// PII creates a detail with the given key and the value which is personally identifiable, or otherwise sensitive.
+// Its value is redacted whenever the error is formatted.
+func PII(key string, value interface{}) ErrorDetail {
+	return ErrorDetail{Key: key, Value: value, PII: true}
+}
+
+// WithPII sets the PII detail of the error - see the PII.
+func (e *Error) WithPII(key string, value interface{}) *Error {
+	e.Details = append(e.Details, PII(key, value))
+	return e
+}
+
+// MarshalText returns the error message with the details redacted by the DefaultRedaction.
+// Implements the encoding.TextMarshaler interface, so that the structured loggers format the error the same way.
+func (e *Error) MarshalText() ([]byte, error) {
+	return []byte(e.Redacted(DefaultRedaction)), nil
+}
+
+// Redacted returns the error message with the details redacted by given redaction.
+// The details of the wrapped errors of this package are redacted as well.
+func (e *Error) Redacted(r Redaction) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d: %s - ", e.Code, e.Msg)
+	for i, d := range e.Details {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(d.Key)
+		sb.WriteString(": ")
+		sb.WriteString(r.value(d))
+	}
+	if e.Wrapped != nil {
+		sb.WriteByte('\n')
+		if we, ok := e.Wrapped.(*Error); ok {
+			sb.WriteString(we.Redacted(r))
+		} else {
+			sb.WriteString(e.Wrapped.Error())
+		}
+	}
+	return sb.String()
+}
+
+// String returns the detail with its value redacted by given redaction.
+func (r Redaction) String(d ErrorDetail) string {
+	return d.Key + ": " + r.value(d)
+}
+
+// value returns the redacted value of the detail.
+func (r Redaction) value(d ErrorDetail) string {
+	// 1. Get the binary of the string and bytes values, the other ones are formatted as is unless these are PII.
+	var (
+		b     []byte
+		isStr bool
+	)
+	switch v := d.Value.(type) {
+	case string:
+		b, isStr = []byte(v), true
+	case []byte:
+		b = v
+	default:
+		if !d.PII {
+			return fmt.Sprint(d.Value)
+		}
+		b = []byte(fmt.Sprint(d.Value))
+	}
+
+	// 2. The PII values are replaced with their length, and the hash if enabled.
+	if d.PII {
+		if r.Hash {
+			return fmt.Sprintf("<redacted len=%d sha256=%s>", len(b), hashPrefix(b))
+		}
+		return fmt.Sprintf("<redacted len=%d>", len(b))
+	}
+
+	// 3. The values within the limit are formatted as is.
+	if r.MaxLength <= 0 || len(b) <= r.MaxLength {
+		return fmt.Sprint(d.Value)
+	}
+
+	// 4. The long values are either hashed or truncated.
+	if r.Hash {
+		return fmt.Sprintf("<len=%d sha256=%s>", len(b), hashPrefix(b))
+	}
+	if isStr {
+		// The string is truncated at the rune boundary.
+		n := r.MaxLength
+		for n > 0 && !utf8.RuneStart(b[n]) {
+			n--
+		}
+		return fmt.Sprintf("%s...<len=%d>", b[:n], len(b))
+	}
+	return fmt.Sprintf("%v...<len=%d>", b[:r.MaxLength], len(b))
+}
+
+// hashPrefix returns the hex encoded prefix of the SHA-256 hash of b.
+func hashPrefix(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:redactedHashSize/2])
+}
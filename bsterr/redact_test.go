@@ -0,0 +1,55 @@
+package bsterr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	long := strings.Repeat("ab", 10)
+	testCases := []struct {
+		name     string
+		r        Redaction
+		detail   ErrorDetail
+		expected string
+	}{
+		{name: "Short", r: Redaction{MaxLength: 8}, detail: D("v", "abc"), expected: "v: abc"},
+		{name: "NoLimit", r: Redaction{}, detail: D("v", long), expected: "v: " + long},
+		{name: "Truncated", r: Redaction{MaxLength: 4}, detail: D("v", long), expected: "v: abab...<len=20>"},
+		{name: "TruncatedRune", r: Redaction{MaxLength: 4}, detail: D("v", "aąćę"), expected: "v: aą...<len=7>"},
+		{name: "TruncatedBytes", r: Redaction{MaxLength: 2}, detail: D("v", []byte{1, 2, 3}), expected: "v: [1 2]...<len=3>"},
+		{name: "Hashed", r: Redaction{MaxLength: 2, Hash: true}, detail: D("v", "abc"), expected: "v: <len=3 sha256=ba7816bf>"},
+		{name: "Number", r: Redaction{MaxLength: 2}, detail: D("v", 123456), expected: "v: 123456"},
+		{name: "PII", r: Redaction{}, detail: PII("v", "abc"), expected: "v: <redacted len=3>"},
+		{name: "PIIHashed", r: Redaction{Hash: true}, detail: PII("v", []byte("abc")), expected: "v: <redacted len=3 sha256=ba7816bf>"},
+		{name: "PIINumber", r: Redaction{}, detail: PII("v", 12), expected: "v: <redacted len=2>"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if s := tc.r.String(tc.detail); s != tc.expected {
+				t.Fatalf("unexpected redacted detail: %q != %q", s, tc.expected)
+			}
+		})
+	}
+
+	t.Run("Error", func(t *testing.T) {
+		inner := Err(CodeInvalidValue, "inner").WithPII("email", "john@example.com")
+		err := ErrWrap(inner, CodeReadingFailed, "outer").WithDetails(D("value", strings.Repeat("x", 300)), D("size", 300))
+		expected := "5001: outer - value: " + strings.Repeat("x", 256) + "...<len=300>, size: 300\n" +
+			"1005: inner - email: <redacted len=16>"
+		if s := err.Error(); s != expected {
+			t.Fatalf("unexpected error: %q", s)
+		}
+		text, _ := err.MarshalText()
+		if string(text) != expected {
+			t.Fatalf("unexpected error text: %q", text)
+		}
+		if s := err.Redacted(Redaction{Hash: true}); !strings.Contains(s, "email: <redacted len=16 sha256=") || strings.Contains(s, "john") {
+			t.Fatalf("unexpected redacted error: %q", s)
+		}
+		if !errors.Is(err, inner) {
+			t.Fatalf("expected wrapped error")
+		}
+	})
+}
@@ -0,0 +1,498 @@
+package bstgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// Import paths of the generated source.
+const (
+	importBsterr = "github.com/devmodules/bst/bsterr"
+	importBstio  = "github.com/devmodules/bst/bstio"
+)
+
+// codeGen writes the Go source of the generator structs. The source is formatted at the end,
+// thus the statements are written without the indentation.
+type codeGen struct {
+	g       *Generator
+	imports map[string]struct{}
+
+	// The state of the currently generated function.
+	body       bytes.Buffer
+	vars       int
+	usedN      bool
+	usedErr    bool
+	fieldError string
+}
+
+// newCodeGen creates the code generator of given generator structs.
+func newCodeGen(g *Generator) *codeGen {
+	return &codeGen{g: g, imports: map[string]struct{}{"io": {}}}
+}
+
+// generate returns the formatted Go source.
+func (x *codeGen) generate() ([]byte, error) {
+	// 1. Generate the type declarations and the methods of each struct.
+	var decls bytes.Buffer
+	for _, gs := range x.g.structs {
+		if gs.doc != "" {
+			x.writeTypeDecl(&decls, gs)
+		}
+		x.writeEncoder(&decls, gs)
+		x.writeDecoder(&decls, gs)
+	}
+
+	// 2. Write the header with the used imports.
+	var out bytes.Buffer
+	out.WriteString("// Code generated by bstgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", x.g.pkg)
+	imports := make([]string, 0, len(x.imports))
+	for imp := range x.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		if !strings.Contains(imp, ".") {
+			fmt.Fprintf(&out, "%q\n", imp)
+		}
+	}
+	out.WriteString("\n")
+	for _, imp := range imports {
+		if strings.Contains(imp, ".") {
+			fmt.Fprintf(&out, "%q\n", imp)
+		}
+	}
+	out.WriteString(")\n")
+	out.Write(decls.Bytes())
+
+	// 3. Format the source.
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeInvalidValue, "failed to format generated source")
+	}
+	return src, nil
+}
+
+// writeTypeDecl writes the Go type declaration of the struct, with the fields tagged as for the bst.Marshal,
+// and documented with their doc strings.
+func (x *codeGen) writeTypeDecl(w *bytes.Buffer, gs *genStruct) {
+	fmt.Fprintf(w, "\n// %s is %s.\ntype %s struct {\n", gs.name, gs.doc, gs.name)
+	for i, f := range gs.fields {
+		tag := fmt.Sprintf("%s,index=%d", f.field.Name, f.field.Index)
+		if f.field.Descending {
+			tag += ",desc"
+		}
+		// The doc string of the field is its doc comment, separated from the preceding field.
+		if f.field.Doc != "" {
+			if i > 0 {
+				w.WriteString("\n")
+			}
+			for _, line := range strings.Split(f.field.Doc, "\n") {
+				fmt.Fprintf(w, "// %s\n", strings.TrimRight(line, " \t\r"))
+			}
+		}
+		fmt.Fprintf(w, "%s %s `bst:%s`\n", f.name, f.t.name, strconv.Quote(tag))
+		x.useType(f.t)
+	}
+	w.WriteString("}\n")
+}
+
+// useType adds the imports of the Go type declaration.
+func (x *codeGen) useType(gt *goType) {
+	if strings.Contains(gt.name, "time.") {
+		x.imports["time"] = struct{}{}
+	}
+}
+
+// writeEncoder writes the EncodeBST method of the struct.
+func (x *codeGen) writeEncoder(w *bytes.Buffer, gs *genStruct) {
+	// 1. Generate the body of the function.
+	x.reset()
+	for i := 0; i < len(gs.fields); i++ {
+		f := gs.fields[i]
+		x.fieldError = fmt.Sprintf("bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, \"failed to write struct field\").WithDetail(\"field\", %q)", f.field.Name)
+		if f.t.kind == bsttype.KindBoolean {
+			i += x.encodeBools(gs.fields[i:]) - 1
+			continue
+		}
+		x.encodeValue("x."+f.name, f.t, f.field.Descending)
+	}
+
+	// 2. Write the function with the used variables.
+	fmt.Fprintf(w, "\n// EncodeBST writes the BST binary of the value to w. Returns the number of bytes written.\n")
+	fmt.Fprintf(w, "func (x *%s) EncodeBST(w io.Writer) (int, error) {\n", gs.name)
+	x.writeVars(w, "bytesWritten")
+	w.Write(x.body.Bytes())
+	fmt.Fprintf(w, "return %s, nil\n}\n", x.counter("bytesWritten"))
+}
+
+// writeDecoder writes the DecodeBST method of the struct.
+func (x *codeGen) writeDecoder(w *bytes.Buffer, gs *genStruct) {
+	// 1. Generate the body of the function.
+	x.reset()
+	for i := 0; i < len(gs.fields); i++ {
+		f := gs.fields[i]
+		x.fieldError = fmt.Sprintf("bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, \"failed to read struct field\").WithDetail(\"field\", %q)", f.field.Name)
+		if f.t.kind == bsttype.KindBoolean {
+			i += x.decodeBools(gs.fields[i:]) - 1
+			continue
+		}
+		x.decodeValue("x."+f.name, f.t, f.field.Descending)
+	}
+
+	// 2. Write the function with the used variables.
+	fmt.Fprintf(w, "\n// DecodeBST reads the BST binary of the value from r. Returns the number of bytes read.\n")
+	fmt.Fprintf(w, "func (x *%s) DecodeBST(r io.Reader) (int, error) {\n", gs.name)
+	x.writeVars(w, "bytesRead")
+	w.Write(x.body.Bytes())
+	fmt.Fprintf(w, "return %s, nil\n}\n", x.counter("bytesRead"))
+}
+
+// reset resets the state of the generated function.
+func (x *codeGen) reset() {
+	x.body.Reset()
+	x.vars = 0
+	x.usedN, x.usedErr = false, false
+}
+
+// writeVars writes the declaration of the variables used by the function body.
+func (x *codeGen) writeVars(w *bytes.Buffer, counter string) {
+	switch {
+	case x.usedN:
+		fmt.Fprintf(w, "var (\n%s, n int\nerr error\n)\n", counter)
+	case x.usedErr:
+		fmt.Fprintf(w, "var (\n%s int\nerr error\n)\n", counter)
+	}
+}
+
+// counter returns the returned bytes counter, which is declared only if any value is written or read.
+func (x *codeGen) counter(name string) string {
+	if x.usedErr {
+		return name
+	}
+	return "0"
+}
+
+// line writes a formatted line of the function body.
+func (x *codeGen) line(format string, args ...any) {
+	fmt.Fprintf(&x.body, format, args...)
+	x.body.WriteByte('\n')
+}
+
+// tmp returns the unique name of the temporary variable.
+func (x *codeGen) tmp(prefix string) string {
+	x.vars++
+	return prefix + strconv.Itoa(x.vars)
+}
+
+// encodeCall writes the call of the bstio function, which returns the number of bytes written and an error.
+func (x *codeGen) encodeCall(format string, args ...any) {
+	x.usedN, x.usedErr = true, true
+	x.imports[importBstio] = struct{}{}
+	x.line("n, err = "+format, args...)
+	x.line("bytesWritten += n")
+	x.line("if err != nil {\nreturn bytesWritten, %s\n}", x.errorExpr())
+}
+
+// encodeByte writes a single byte expression.
+func (x *codeGen) encodeByte(expr string) {
+	x.usedErr = true
+	x.imports[importBstio] = struct{}{}
+	x.line("if err = bstio.WriteByte(w, %s); err != nil {\nreturn bytesWritten, %s\n}", expr, x.errorExpr())
+	x.line("bytesWritten++")
+}
+
+// errorExpr returns the error expression of the current field.
+func (x *codeGen) errorExpr() string {
+	x.imports[importBsterr] = struct{}{}
+	return x.fieldError
+}
+
+// encodeValue writes the encoding of the value expression v of given Go type.
+func (x *codeGen) encodeValue(v string, gt *goType, desc bool) {
+	switch gt.kind {
+	case bsttype.KindInt:
+		x.encodeCall("bstio.WriteInt(w, %s, %t, false)", convert(v, "int", gt), desc)
+	case bsttype.KindInt8, bsttype.KindInt16, bsttype.KindInt32, bsttype.KindInt64,
+		bsttype.KindUint8, bsttype.KindUint16, bsttype.KindUint32, bsttype.KindUint64,
+		bsttype.KindFloat32, bsttype.KindFloat64:
+		name := _basicGoTypes[gt.kind]
+		x.encodeCall("bstio.Write%s(w, %s, %t)", exportName(name), convert(v, name, gt), desc)
+	case bsttype.KindUint:
+		x.encodeCall("bstio.WriteUint(w, %s, %t)", convert(v, "uint", gt), desc)
+	case bsttype.KindString:
+		x.encodeCall("bstio.WriteString(w, %s, %t, false)", convert(v, "string", gt), desc)
+	case bsttype.KindBytes:
+		x.encodeCall("bstio.WriteBytes(w, %d, %s, %t, false)", gt.t.(*bsttype.Bytes).FixedSize, v, desc)
+	case bsttype.KindDuration:
+		x.encodeCall("bstio.WriteInt64(w, int64(%s), %t)", v, desc)
+	case bsttype.KindTimestamp:
+		x.encodeCall("bstio.WriteInt64(w, %s.UnixNano(), %t)", paren(v), desc)
+	case bsttype.KindStruct:
+		x.encodeCall("%s.EncodeBST(w)", paren(v))
+	case bsttype.KindNullable:
+		x.line("if %s == nil {", v)
+		x.encodeByte(nullFlag("NullableIsNull", desc))
+		x.line("} else {")
+		x.encodeByte(nullFlag("NullableIsNotNull", desc))
+		x.encodeValue("*"+v, gt.elem, desc)
+		x.line("}")
+	case bsttype.KindArray:
+		x.encodeArray(v, gt)
+	case bsttype.KindMap:
+		x.encodeMap(v, gt)
+	}
+}
+
+// encodeArray writes the encoding of the array, where the elements are encoded in the order of the composer.
+func (x *codeGen) encodeArray(v string, gt *goType) {
+	// 1. The variable length arrays are prefixed with their length.
+	if !gt.t.(*bsttype.Array).HasFixedSize() {
+		x.encodeCall("bstio.WriteUint(w, uint(len(%s)), false)", v)
+	}
+
+	// 2. The booleans are packed by 8 into a byte.
+	i := x.tmp("i")
+	if gt.elem.kind == bsttype.KindBoolean {
+		b := x.tmp("b")
+		x.line("var %s byte", b)
+		x.line("for %s := range %s {", i, v)
+		x.line("if %s[%s] {\n%s |= 1 << (%s & 7)\n}", paren(v), i, b, i)
+		x.line("if %s&7 == 7 || %s == len(%s)-1 {", i, i, v)
+		x.encodeByte(b)
+		x.line("%s = 0\n}\n}", b)
+		return
+	}
+
+	// 3. The elements are encoded in the order of the composer, neither of which is descending.
+	x.line("for %s := range %s {", i, v)
+	x.encodeValue(fmt.Sprintf("%s[%s]", paren(v), i), gt.elem, false)
+	x.line("}")
+}
+
+// encodeMap writes the encoding of the map, where the entries are sorted by the keys, just as by the bst.Marshal.
+func (x *codeGen) encodeMap(v string, gt *goType) {
+	mt := gt.t.(*bsttype.Map)
+	x.encodeCall("bstio.WriteUint(w, uint(len(%s)), false)", v)
+
+	// 1. Collect and sort the keys.
+	keys, k := x.tmp("keys"), x.tmp("k")
+	x.line("%s := make([]%s, 0, len(%s))", keys, gt.key.name, v)
+	x.line("for %s := range %s {\n%s = append(%s, %s)\n}", k, v, keys, keys, k)
+	x.imports["slices"] = struct{}{}
+	if gt.key.kind == bsttype.KindTimestamp {
+		x.imports["time"] = struct{}{}
+		x.line("slices.SortFunc(%s, time.Time.Compare)", keys)
+	} else {
+		x.line("slices.Sort(%s)", keys)
+	}
+
+	// 2. Encode the entries.
+	e := x.tmp("e")
+	x.line("for _, %s := range %s {", k, keys)
+	x.encodeValue(k, gt.key, mt.Key.Descending)
+	x.line("%s := %s[%s]", e, v, k)
+	x.encodeValue(e, gt.elem, mt.Value.Descending)
+	x.line("}")
+}
+
+// encodeBools writes the encoding of the consecutive boolean fields, packed by 8 into a byte.
+// Returns the number of encoded fields.
+func (x *codeGen) encodeBools(fields []genField) int {
+	b := x.tmp("b")
+	x.line("var %s byte", b)
+	var count int
+	for ; count < len(fields) && count < 8 && fields[count].t.kind == bsttype.KindBoolean; count++ {
+		f := fields[count]
+		not := ""
+		if f.field.Descending {
+			not = "!"
+		}
+		x.line("if %sx.%s {\n%s |= 1 << %d\n}", not, f.name, b, count)
+	}
+	x.encodeByte(b)
+	return count
+}
+
+// decodeCall writes the call of the bstio function, which returns the value, the number of bytes read and an error.
+// The value is assigned to v, converted into the Go type if needed.
+func (x *codeGen) decodeCall(v string, gt *goType, format string, args ...any) {
+	if !gt.conv {
+		x.decodeCallAs(v, "", "", format, args...)
+		return
+	}
+	x.decodeCallAs(v, _basicGoTypes[gt.kind], gt.name+"(%s)", format, args...)
+}
+
+// decodeCallAs writes the call of the bstio function, whose value is read into the temporary variable
+// of given type, and then assigned to v with the conversion format. The value is read directly into v
+// if the temporary type is empty.
+func (x *codeGen) decodeCallAs(v, tmpType, conv string, format string, args ...any) {
+	x.usedN, x.usedErr = true, true
+	x.imports[importBstio] = struct{}{}
+	target := v
+	if tmpType != "" {
+		target = x.tmp("v")
+		x.line("var %s %s", target, tmpType)
+	}
+	x.line("%s, n, err = "+format, append([]any{target}, args...)...)
+	x.line("bytesRead += n")
+	x.line("if err != nil {\nreturn bytesRead, %s\n}", x.errorExpr())
+	if tmpType != "" {
+		x.line("%s = "+conv, v, target)
+	}
+}
+
+// decodeByte writes the read of a single byte into the variable b.
+func (x *codeGen) decodeByte(b, format string) {
+	x.usedErr = true
+	x.imports[importBstio] = struct{}{}
+	x.line("if %s, err = "+format+"; err != nil {\nreturn bytesRead, %s\n}", b, x.errorExpr())
+	x.line("bytesRead++")
+}
+
+// decodeValue writes the decoding of the value into the assignable expression v of given Go type.
+func (x *codeGen) decodeValue(v string, gt *goType, desc bool) {
+	switch gt.kind {
+	case bsttype.KindInt:
+		x.decodeCall(v, gt, "bstio.ReadInt(r, %t, false)", desc)
+	case bsttype.KindInt8, bsttype.KindInt16, bsttype.KindInt32, bsttype.KindInt64,
+		bsttype.KindUint8, bsttype.KindUint16, bsttype.KindUint32, bsttype.KindUint64,
+		bsttype.KindFloat32, bsttype.KindFloat64:
+		x.decodeCall(v, gt, "bstio.Read%s(r, %t)", exportName(_basicGoTypes[gt.kind]), desc)
+	case bsttype.KindUint:
+		x.decodeCall(v, gt, "bstio.ReadUint(r, %t)", desc)
+	case bsttype.KindString:
+		x.decodeCall(v, gt, "bstio.ReadString(r, %t, false)", desc)
+	case bsttype.KindBytes:
+		// The bytes are read into the value buffer, which is reused.
+		x.decodeCallAs(v, "", "", "bstio.ReadBytesAppend(%s[:0], r, %d, %t, false)", paren(v), gt.t.(*bsttype.Bytes).FixedSize, desc)
+	case bsttype.KindDuration:
+		x.decodeCallAs(v, "int64", gt.name+"(%s)", "bstio.ReadInt64(r, %t)", desc)
+	case bsttype.KindTimestamp:
+		x.imports["time"] = struct{}{}
+		x.decodeCallAs(v, "int64", "time.Unix(0, %s).UTC()", "bstio.ReadInt64(r, %t)", desc)
+	case bsttype.KindStruct:
+		x.usedN, x.usedErr = true, true
+		x.line("n, err = %s.DecodeBST(r)", paren(v))
+		x.line("bytesRead += n")
+		x.line("if err != nil {\nreturn bytesRead, %s\n}", x.errorExpr())
+	case bsttype.KindNullable:
+		f := x.tmp("f")
+		x.line("var %s byte", f)
+		x.decodeByte(f, fmt.Sprintf("bstio.ReadNullableFlag(r, %t)", desc))
+		x.line("if %s == bstio.NullableIsNull {\n%s = nil\n} else {", f, v)
+		x.line("if %s == nil {\n%s = new(%s)\n}", v, v, gt.elem.name)
+		x.decodeValue("*"+v, gt.elem, desc)
+		x.line("}")
+	case bsttype.KindArray:
+		x.decodeArray(v, gt)
+	case bsttype.KindMap:
+		x.decodeMap(v, gt)
+	}
+}
+
+// decodeArray writes the decoding of the array. The slice is reused if its capacity suffices.
+func (x *codeGen) decodeArray(v string, gt *goType) {
+	// 1. Read the length of the variable length array, verified against the remaining input.
+	boolElem := gt.elem.kind == bsttype.KindBoolean
+	if !gt.t.(*bsttype.Array).HasFixedSize() {
+		l := x.tmp("l")
+		x.line("var %s uint", l)
+		minSize := bsttype.MinEncodedSize(gt.elem.t)
+		x.decodeCallAs(l, "", "", "bstio.ReadLength(r, false, %d)", minSize)
+		if boolElem {
+			x.line("if err = bstio.CheckLength(r, (%s+7)>>3, 1); err != nil {\nreturn bytesRead, %s\n}", l, x.errorExpr())
+		}
+		x.line("if uint(cap(%s)) >= %s {\n%s = %s[:%s]\n} else {\n%s = make(%s, %s)\n}", v, l, v, paren(v), l, v, gt.name, l)
+	}
+
+	// 2. The booleans are packed by 8 into a byte.
+	i := x.tmp("i")
+	if boolElem {
+		b := x.tmp("b")
+		x.line("var %s byte", b)
+		x.line("for %s := range %s {", i, v)
+		x.line("if %s&7 == 0 {", i)
+		x.decodeByte(b, "bstio.ReadByte(r)")
+		x.line("}")
+		x.line("%s[%s] = %s", paren(v), i, convert(fmt.Sprintf("%s&(1<<(%s&7)) != 0", b, i), gt.elem.name, gt.elem))
+		x.line("}")
+		return
+	}
+
+	// 3. Read the elements.
+	x.line("for %s := range %s {", i, v)
+	x.decodeValue(fmt.Sprintf("%s[%s]", paren(v), i), gt.elem, false)
+	x.line("}")
+}
+
+// decodeMap writes the decoding of the map. The map is reused after being cleared.
+func (x *codeGen) decodeMap(v string, gt *goType) {
+	mt := gt.t.(*bsttype.Map)
+
+	// 1. Read the length of the map, verified against the remaining input.
+	l := x.tmp("l")
+	x.line("var %s uint", l)
+	minSize := bsttype.MinEncodedSize(mt.Key.Type) + bsttype.MinEncodedSize(mt.Value.Type)
+	x.decodeCallAs(l, "", "", "bstio.ReadLength(r, false, %d)", minSize)
+	x.line("if %s == nil {\n%s = make(%s, %s)\n} else {\nclear(%s)\n}", v, v, gt.name, l, v)
+
+	// 2. Read the entries.
+	i, k, e := x.tmp("i"), x.tmp("k"), x.tmp("e")
+	x.line("for %s := uint(0); %s < %s; %s++ {", i, i, l, i)
+	x.line("var %s %s", k, gt.key.name)
+	x.decodeValue(k, gt.key, mt.Key.Descending)
+	x.line("var %s %s", e, gt.elem.name)
+	x.decodeValue(e, gt.elem, mt.Value.Descending)
+	x.line("%s[%s] = %s\n}", paren(v), k, e)
+}
+
+// decodeBools writes the decoding of the consecutive boolean fields, packed by 8 into a byte.
+// Returns the number of decoded fields.
+func (x *codeGen) decodeBools(fields []genField) int {
+	b := x.tmp("b")
+	x.line("var %s byte", b)
+	x.decodeByte(b, "bstio.ReadByte(r)")
+	var count int
+	for ; count < len(fields) && count < 8 && fields[count].t.kind == bsttype.KindBoolean; count++ {
+		f := fields[count]
+		op := "!="
+		if f.field.Descending {
+			op = "=="
+		}
+		x.line("x.%s = %s", f.name, convert(fmt.Sprintf("%s&(1<<%d) %s 0", b, count, op), f.t.name, f.t))
+	}
+	return count
+}
+
+// convert returns the expression v converted into given type, if the Go type needs a conversion.
+func convert(v, name string, gt *goType) string {
+	if !gt.conv {
+		return v
+	}
+	return name + "(" + v + ")"
+}
+
+// paren returns the expression in parentheses if it is dereferenced, so that it could be indexed or called.
+func paren(v string) string {
+	if strings.HasPrefix(v, "*") {
+		return "(" + v + ")"
+	}
+	return v
+}
+
+// nullFlag returns the expression of the nullable flag constant.
+func nullFlag(name string, desc bool) string {
+	if desc {
+		name += "Desc"
+	}
+	return "bstio." + name
+}
@@ -0,0 +1,138 @@
+// Package bstgen generates the Go source of the static BST encoders and decoders.
+//
+// The generated EncodeBST and DecodeBST methods write and read the struct value binaries with the bstio primitives
+// directly, without the reflection, nor the Composer and Extractor state checks. The binary is the one composed
+// with the default options, i.e. neither comparable, descending, nor in the compatibility mode, without
+// the composer header byte. Thus, it equals the bst.Marshal binary without its first byte.
+//
+// The structs are defined either by the bsttype definitions, for which the Go types are generated as well,
+// or by the Go structs tagged as for the bst.Marshal, for which only the methods are generated.
+// The generator is meant to be run by a small program, invoked with the go:generate directive, i.e.:
+//
+//	g := bstgen.New("model")
+//	if err := g.AddGoStruct(model.User{}); err != nil {
+//		log.Fatal(err)
+//	}
+//	if err := g.WriteFile("user_bst.go"); err != nil {
+//		log.Fatal(err)
+//	}
+package bstgen
+
+import (
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// Encoder is implemented by the types with the generated encoder.
+type Encoder interface {
+	// EncodeBST writes the BST binary of the value to w. Returns the number of bytes written.
+	EncodeBST(w io.Writer) (int, error)
+}
+
+// Decoder is implemented by the types with the generated decoder.
+type Decoder interface {
+	// DecodeBST reads the BST binary of the value from r. Returns the number of bytes read.
+	DecodeBST(r io.Reader) (int, error)
+}
+
+// Generator generates the Go source of the static encoders and decoders of the added structs.
+// The structs these depend on are added as well. All the Go struct types need to be defined in the generated package.
+type Generator struct {
+	pkg, pkgPath string
+
+	structs  []*genStruct
+	byName   map[string]*genStruct
+	byType   map[*bsttype.Struct]*genStruct
+	byGoType map[reflect.Type]*genStruct
+}
+
+// New creates a new generator of the Go source in the package of given name.
+func New(pkg string) *Generator {
+	return &Generator{
+		pkg:      pkg,
+		byName:   make(map[string]*genStruct),
+		byType:   make(map[*bsttype.Struct]*genStruct),
+		byGoType: make(map[reflect.Type]*genStruct),
+	}
+}
+
+// AddStruct adds the struct definition, whose Go type is declared with given name.
+// The anonymous structs of its fields are declared with the name followed by the field name.
+func (x *Generator) AddStruct(name string, st *bsttype.Struct) error {
+	_, err := x.addStruct(name, "the Go type of the "+name+" struct", st)
+	return err
+}
+
+// AddNamed adds the resolved named struct definition, whose Go type is declared with the name of the type.
+func (x *Generator) AddNamed(nt *bsttype.Named) error {
+	gt, err := x.typeOf(exportName(nt.Name), nt)
+	if err != nil {
+		return err
+	}
+	if gt.st == nil {
+		return bsterr.Err(bsterr.CodeInvalidType, "named type is not a struct").WithDetail("type", nt.String())
+	}
+	return nil
+}
+
+// AddModules adds all the struct definitions of the modules, resolving them first if needed.
+// The definitions of other types are used only where referenced by the structs.
+func (x *Generator) AddModules(m *bsttype.Modules) error {
+	if !m.IsResolved() {
+		if err := m.Resolve(); err != nil {
+			return err
+		}
+	}
+	for _, mod := range m.List {
+		for _, def := range mod.Definitions {
+			if _, ok := def.Type.(*bsttype.Struct); !ok {
+				continue
+			}
+			if err := x.AddNamed(&bsttype.Named{Module: mod.Name, Name: def.Name, Type: def.Type}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AddGoStruct adds the Go struct type of the value, which is mapped just as by the bst.Marshal.
+// Only the methods are generated for the Go structs, thus these need to be defined in the generated package.
+func (x *Generator) AddGoStruct(v any) error {
+	// 1. Dereference the Go type.
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return bsterr.Err(bsterr.CodeInvalidType, "generated Go type needs to be a struct").WithDetail("type", rt)
+	}
+
+	// 2. The first Go struct determines the package of all the Go types.
+	if x.pkgPath == "" {
+		x.pkgPath = rt.PkgPath()
+	}
+	_, err := x.addGoStruct(rt)
+	return err
+}
+
+// Source returns the formatted Go source of the added structs.
+func (x *Generator) Source() ([]byte, error) {
+	return newCodeGen(x).generate()
+}
+
+// WriteFile writes the Go source of the added structs to the named file.
+func (x *Generator) WriteFile(name string) error {
+	src, err := x.Source()
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(name, src, 0o644); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write generated file").WithDetail("file", name)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package bstgen
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/devmodules/bst/bstgen/internal/example"
+	"github.com/devmodules/bst/bsttype"
+)
+
+var _update = flag.Bool("update", false, "update the generated files of the example package")
+
+func TestGenerator(t *testing.T) {
+	// The generated files of the example package are compiled and tested by the example package tests.
+	generated := []struct {
+		file string
+		add  func(g *Generator) error
+	}{
+		{file: "user_bst.go", add: func(g *Generator) error { return g.AddGoStruct(&example.User{}) }},
+		{file: "shop_bst.go", add: func(g *Generator) error { return g.AddModules(example.ShopModules()) }},
+	}
+	for _, gen := range generated {
+		g := New("example")
+		if err := gen.add(g); err != nil {
+			t.Fatalf("adding %s structs failed: %v", gen.file, err)
+		}
+		src, err := g.Source()
+		if err != nil {
+			t.Fatalf("generating %s failed: %v", gen.file, err)
+		}
+
+		name := filepath.Join("internal", "example", gen.file)
+		if *_update {
+			if err = g.WriteFile(name); err != nil {
+				t.Fatalf("writing %s failed: %v", name, err)
+			}
+			continue
+		}
+		current, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("reading %s failed: %v", name, err)
+		}
+		if !bytes.Equal(src, current) {
+			t.Fatalf("%s is not up-to-date, regenerate it with: go test ./bstgen -run TestGenerator -update", name)
+		}
+	}
+
+	t.Run("Names", func(t *testing.T) {
+		g := New("example")
+		if err := g.AddModules(example.ShopModules()); err != nil {
+			t.Fatalf("adding modules failed: %v", err)
+		}
+		src, err := g.Source()
+		if err != nil {
+			t.Fatalf("generating failed: %v", err)
+		}
+		for _, decl := range []string{"type Order struct", "type Item struct", "type OrderDelivery struct", "OrderId "} {
+			if !strings.Contains(string(src), decl) {
+				t.Fatalf("generated source doesn't declare %q", decl)
+			}
+		}
+
+		for name, expected := range map[string]string{"user_id": "UserId", "name": "Name", "1st": "X1st", "_": "X"} {
+			if actual := exportName(name); actual != expected {
+				t.Fatalf("unexpected exported name of %q: %q", name, actual)
+			}
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		type chanField struct {
+			C chan int
+		}
+		type foreign struct {
+			B bytes.Buffer
+		}
+		structs := map[string]*bsttype.Struct{
+			"enum":         {Fields: []bsttype.StructField{{Index: 1, Name: "e", Type: &bsttype.Enum{}}}},
+			"encoding":     {Fields: []bsttype.StructField{{Index: 1, Name: "d", Type: bsttype.String(), Encoding: bsttype.FieldEncodingDeflate}}},
+			"nullableBool": {Fields: []bsttype.StructField{{Index: 1, Name: "b", Type: bsttype.NullableOf(bsttype.Boolean())}}},
+			"mapBool":      {Fields: []bsttype.StructField{{Index: 1, Name: "m", Type: bsttype.MapTypeOf(bsttype.String(), bsttype.Boolean(), false, false)}}},
+			"runLength":    {Fields: []bsttype.StructField{{Index: 1, Name: "a", Type: &bsttype.Array{Type: bsttype.Int8(), Encoding: bsttype.ArrayEncodingRunLength}}}},
+			"sameName":     {Fields: []bsttype.StructField{{Index: 1, Name: "a_b", Type: bsttype.Int8()}, {Index: 2, Name: "a-b", Type: bsttype.Int8()}}},
+			"unresolved":   {Fields: []bsttype.StructField{{Index: 1, Name: "n", Type: &bsttype.Named{Module: "m", Name: "n"}}}},
+		}
+		for name, st := range structs {
+			if err := New("example").AddStruct("T", st); err == nil {
+				t.Fatalf("expected %s struct to fail", name)
+			}
+		}
+
+		g := New("example")
+		if err := g.AddStruct("User", &bsttype.Struct{}); err != nil {
+			t.Fatalf("adding struct failed: %v", err)
+		}
+		if err := g.AddGoStruct(example.User{}); err == nil {
+			t.Fatal("expected duplicated Go type name to fail")
+		}
+		for _, v := range []any{1, chanField{}, foreign{}} {
+			if err := New("bstgen").AddGoStruct(v); err == nil {
+				t.Fatalf("expected Go struct %T to fail", v)
+			}
+		}
+	})
+}
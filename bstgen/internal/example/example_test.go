@@ -0,0 +1,125 @@
+package example
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/devmodules/bst"
+)
+
+// codec is the value with the generated encoder and decoder.
+type codec interface {
+	EncodeBST(w io.Writer) (int, error)
+	DecodeBST(r io.Reader) (int, error)
+}
+
+func TestGenerated(t *testing.T) {
+	note := "leave at the door"
+	placed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	values := []struct {
+		name     string
+		in       codec
+		expected codec
+		newValue func() codec
+	}{
+		{
+			name: "User",
+			in: &User{
+				ID:       42,
+				Name:     "john",
+				Score:    -7,
+				Active:   true,
+				Flags:    [9]bool{true, false, true, true, false, false, true, false, true},
+				Level:    -3,
+				Tags:     []string{"a", "b"},
+				Data:     []byte{1, 2, 3},
+				Address:  Address{City: "Warsaw", Zip: 12345},
+				Previous: &Address{City: "Cracow", Zip: 30001},
+				History:  []Address{{City: "Gdansk", Zip: 80001}, {City: "Poznan", Zip: 60001}},
+				Labels:   map[string]int64{"x": 1, "y": -2, "z": 3},
+				Created:  placed,
+				TTL:      time.Minute,
+				Ratio:    0.25,
+				Nested:   map[uint8][]uint16{2: {3, 4}, 1: {5}},
+				Admin:    true,
+			},
+			newValue: func() codec { return &User{} },
+		},
+		{
+			name: "Order",
+			in: &Order{
+				OrderId:   -1,
+				Items:     []Item{{Sku: []byte("ab12"), Quantity: 3, Price: 9.99}, {Sku: []byte("cd"), Price: -1}},
+				Paid:      true,
+				Note:      &note,
+				Placed:    &placed,
+				Delivery:  OrderDelivery{Street: "Main", Express: true},
+				Discounts: map[time.Time]int16{placed: -5},
+				Gift:      &Item{Sku: []byte("gift"), Quantity: 1},
+			},
+			newValue: func() codec { return &Order{} },
+		},
+		{
+			name:     "Empty",
+			in:       &Order{},
+			expected: &Order{Discounts: map[time.Time]int16{}},
+			newValue: func() codec { return &Order{} },
+		},
+	}
+
+	for _, tc := range values {
+		t.Run(tc.name, func(t *testing.T) {
+			// 1. The generated binary is the marshaled one, without the header byte.
+			data, err := bst.Marshal(tc.in)
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+			var buf bytes.Buffer
+			n, err := tc.in.EncodeBST(&buf)
+			if err != nil {
+				t.Fatalf("encoding failed: %v", err)
+			}
+			if data[0] != 0x00 || !bytes.Equal(buf.Bytes(), data[1:]) || n != buf.Len() {
+				t.Fatalf("unexpected encoded binary (%d bytes):\n%v\nexpected:\n%v", n, buf.Bytes(), data)
+			}
+
+			// 2. The decoded value is the encoded one, also if decoded into the same value again.
+			expected := tc.expected
+			if expected == nil {
+				expected = tc.in
+			}
+			out := tc.newValue()
+			for i := 0; i < 2; i++ {
+				n, err = out.DecodeBST(bytes.NewReader(data[1:]))
+				if err != nil {
+					t.Fatalf("decoding failed: %v", err)
+				}
+				if n != len(data)-1 || !reflect.DeepEqual(out, expected) {
+					t.Fatalf("unexpected decoded value (%d bytes):\n%+v\nexpected:\n%+v", n, out, expected)
+				}
+			}
+
+			// 3. The truncated binary fails to decode.
+			if _, err = tc.newValue().DecodeBST(bytes.NewReader(data[1 : len(data)-1])); err == nil {
+				t.Fatal("expected decoding truncated binary to fail")
+			}
+		})
+	}
+
+	t.Run("Allocations", func(t *testing.T) {
+		item := &Item{Sku: []byte("ab12"), Quantity: 3, Price: 9.99}
+		var buf bytes.Buffer
+		allocs := testing.AllocsPerRun(100, func() {
+			buf.Reset()
+			if _, err := item.EncodeBST(&buf); err != nil {
+				t.Fatalf("encoding failed: %v", err)
+			}
+		})
+		if allocs != 0 {
+			t.Fatalf("unexpected number of allocations: %v", allocs)
+		}
+	})
+}
@@ -0,0 +1,366 @@
+// Code generated by bstgen. DO NOT EDIT.
+
+package example
+
+import (
+	"io"
+	"slices"
+	"time"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+)
+
+// Order is the Go type of the shop.order struct.
+type Order struct {
+	OrderId int    `bst:"order_id,index=1"`
+	Items   []Item `bst:"items,index=2"`
+	Paid    bool   `bst:"paid,index=3"`
+
+	// Note is the message of the customer,
+	// if any.
+	Note      *string             `bst:"note,index=4"`
+	Placed    *time.Time          `bst:"placed,index=5"`
+	Delivery  OrderDelivery       `bst:"delivery,index=6"`
+	Discounts map[time.Time]int16 `bst:"discounts,index=7"`
+	Gift      *Item               `bst:"gift,index=8"`
+}
+
+// EncodeBST writes the BST binary of the value to w. Returns the number of bytes written.
+func (x *Order) EncodeBST(w io.Writer) (int, error) {
+	var (
+		bytesWritten, n int
+		err             error
+	)
+	n, err = bstio.WriteInt(w, x.OrderId, false, false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "order_id")
+	}
+	n, err = bstio.WriteUint(w, uint(len(x.Items)), false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "items")
+	}
+	for i1 := range x.Items {
+		n, err = x.Items[i1].EncodeBST(w)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "items")
+		}
+	}
+	var b2 byte
+	if x.Paid {
+		b2 |= 1 << 0
+	}
+	if err = bstio.WriteByte(w, b2); err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "paid")
+	}
+	bytesWritten++
+	if x.Note == nil {
+		if err = bstio.WriteByte(w, bstio.NullableIsNull); err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "note")
+		}
+		bytesWritten++
+	} else {
+		if err = bstio.WriteByte(w, bstio.NullableIsNotNull); err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "note")
+		}
+		bytesWritten++
+		n, err = bstio.WriteString(w, *x.Note, false, false)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "note")
+		}
+	}
+	if x.Placed == nil {
+		if err = bstio.WriteByte(w, bstio.NullableIsNull); err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "placed")
+		}
+		bytesWritten++
+	} else {
+		if err = bstio.WriteByte(w, bstio.NullableIsNotNull); err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "placed")
+		}
+		bytesWritten++
+		n, err = bstio.WriteInt64(w, (*x.Placed).UnixNano(), false)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "placed")
+		}
+	}
+	n, err = x.Delivery.EncodeBST(w)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "delivery")
+	}
+	n, err = bstio.WriteUint(w, uint(len(x.Discounts)), false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "discounts")
+	}
+	keys3 := make([]time.Time, 0, len(x.Discounts))
+	for k4 := range x.Discounts {
+		keys3 = append(keys3, k4)
+	}
+	slices.SortFunc(keys3, time.Time.Compare)
+	for _, k4 := range keys3 {
+		n, err = bstio.WriteInt64(w, k4.UnixNano(), false)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "discounts")
+		}
+		e5 := x.Discounts[k4]
+		n, err = bstio.WriteInt16(w, e5, false)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "discounts")
+		}
+	}
+	if x.Gift == nil {
+		if err = bstio.WriteByte(w, bstio.NullableIsNull); err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "gift")
+		}
+		bytesWritten++
+	} else {
+		if err = bstio.WriteByte(w, bstio.NullableIsNotNull); err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "gift")
+		}
+		bytesWritten++
+		n, err = (*x.Gift).EncodeBST(w)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "gift")
+		}
+	}
+	return bytesWritten, nil
+}
+
+// DecodeBST reads the BST binary of the value from r. Returns the number of bytes read.
+func (x *Order) DecodeBST(r io.Reader) (int, error) {
+	var (
+		bytesRead, n int
+		err          error
+	)
+	x.OrderId, n, err = bstio.ReadInt(r, false, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "order_id")
+	}
+	var l1 uint
+	l1, n, err = bstio.ReadLength(r, false, 0)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "items")
+	}
+	if uint(cap(x.Items)) >= l1 {
+		x.Items = x.Items[:l1]
+	} else {
+		x.Items = make([]Item, l1)
+	}
+	for i2 := range x.Items {
+		n, err = x.Items[i2].DecodeBST(r)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "items")
+		}
+	}
+	var b3 byte
+	if b3, err = bstio.ReadByte(r); err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "paid")
+	}
+	bytesRead++
+	x.Paid = b3&(1<<0) != 0
+	var f4 byte
+	if f4, err = bstio.ReadNullableFlag(r, false); err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "note")
+	}
+	bytesRead++
+	if f4 == bstio.NullableIsNull {
+		x.Note = nil
+	} else {
+		if x.Note == nil {
+			x.Note = new(string)
+		}
+		*x.Note, n, err = bstio.ReadString(r, false, false)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "note")
+		}
+	}
+	var f5 byte
+	if f5, err = bstio.ReadNullableFlag(r, false); err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "placed")
+	}
+	bytesRead++
+	if f5 == bstio.NullableIsNull {
+		x.Placed = nil
+	} else {
+		if x.Placed == nil {
+			x.Placed = new(time.Time)
+		}
+		var v6 int64
+		v6, n, err = bstio.ReadInt64(r, false)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "placed")
+		}
+		*x.Placed = time.Unix(0, v6).UTC()
+	}
+	n, err = x.Delivery.DecodeBST(r)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "delivery")
+	}
+	var l7 uint
+	l7, n, err = bstio.ReadLength(r, false, 10)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "discounts")
+	}
+	if x.Discounts == nil {
+		x.Discounts = make(map[time.Time]int16, l7)
+	} else {
+		clear(x.Discounts)
+	}
+	for i8 := uint(0); i8 < l7; i8++ {
+		var k9 time.Time
+		var v11 int64
+		v11, n, err = bstio.ReadInt64(r, false)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "discounts")
+		}
+		k9 = time.Unix(0, v11).UTC()
+		var e10 int16
+		e10, n, err = bstio.ReadInt16(r, false)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "discounts")
+		}
+		x.Discounts[k9] = e10
+	}
+	var f12 byte
+	if f12, err = bstio.ReadNullableFlag(r, false); err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "gift")
+	}
+	bytesRead++
+	if f12 == bstio.NullableIsNull {
+		x.Gift = nil
+	} else {
+		if x.Gift == nil {
+			x.Gift = new(Item)
+		}
+		n, err = (*x.Gift).DecodeBST(r)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "gift")
+		}
+	}
+	return bytesRead, nil
+}
+
+// Item is the Go type of the shop.item struct.
+type Item struct {
+	Sku []byte `bst:"sku,index=1"`
+
+	// Quantity is the number of the ordered units.
+	Quantity uint    `bst:"quantity,index=2"`
+	Price    float32 `bst:"price,index=3,desc"`
+}
+
+// EncodeBST writes the BST binary of the value to w. Returns the number of bytes written.
+func (x *Item) EncodeBST(w io.Writer) (int, error) {
+	var (
+		bytesWritten, n int
+		err             error
+	)
+	n, err = bstio.WriteBytes(w, 0, x.Sku, false, false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "sku")
+	}
+	n, err = bstio.WriteUint(w, x.Quantity, false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "quantity")
+	}
+	n, err = bstio.WriteFloat32(w, x.Price, true)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "price")
+	}
+	return bytesWritten, nil
+}
+
+// DecodeBST reads the BST binary of the value from r. Returns the number of bytes read.
+func (x *Item) DecodeBST(r io.Reader) (int, error) {
+	var (
+		bytesRead, n int
+		err          error
+	)
+	x.Sku, n, err = bstio.ReadBytesAppend(x.Sku[:0], r, 0, false, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "sku")
+	}
+	x.Quantity, n, err = bstio.ReadUint(r, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "quantity")
+	}
+	x.Price, n, err = bstio.ReadFloat32(r, true)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "price")
+	}
+	return bytesRead, nil
+}
+
+// OrderDelivery is the Go type of the anonymous struct.
+type OrderDelivery struct {
+	Street  string `bst:"street,index=1"`
+	Express bool   `bst:"express,index=2"`
+}
+
+// EncodeBST writes the BST binary of the value to w. Returns the number of bytes written.
+func (x *OrderDelivery) EncodeBST(w io.Writer) (int, error) {
+	var (
+		bytesWritten, n int
+		err             error
+	)
+	n, err = bstio.WriteString(w, x.Street, false, false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "street")
+	}
+	var b1 byte
+	if x.Express {
+		b1 |= 1 << 0
+	}
+	if err = bstio.WriteByte(w, b1); err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "express")
+	}
+	bytesWritten++
+	return bytesWritten, nil
+}
+
+// DecodeBST reads the BST binary of the value from r. Returns the number of bytes read.
+func (x *OrderDelivery) DecodeBST(r io.Reader) (int, error) {
+	var (
+		bytesRead, n int
+		err          error
+	)
+	x.Street, n, err = bstio.ReadString(r, false, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "street")
+	}
+	var b1 byte
+	if b1, err = bstio.ReadByte(r); err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "express")
+	}
+	bytesRead++
+	x.Express = b1&(1<<0) != 0
+	return bytesRead, nil
+}
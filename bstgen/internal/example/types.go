@@ -0,0 +1,71 @@
+// Package example contains the structs with the encoders and decoders generated by the bstgen,
+// both from the tagged Go structs (user_bst.go) and from the module definitions (shop_bst.go).
+// The generated files are verified to be up-to-date by the bstgen tests, which regenerate them with the -update flag.
+package example
+
+import (
+	"time"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+// Level is the named integer, converted by the generated code.
+type Level int8
+
+// Address is the Go struct nested in the User.
+type Address struct {
+	City string `bst:"city"`
+	Zip  uint32 `bst:"zip,index=5"`
+}
+
+// User is the tagged Go struct, mapped just as by the bst.Marshal.
+type User struct {
+	ID       uint64             `bst:"id,index=1"`
+	Name     string             `bst:"name,index=2"`
+	Score    int32              `bst:",index=4,desc"`
+	Active   bool               `bst:"active,index=3"`
+	Flags    [9]bool            `bst:"flags"`
+	Level    Level              `bst:"level"`
+	Tags     []string           `bst:"tags"`
+	Data     []byte             `bst:"data"`
+	Address  Address            `bst:"address"`
+	Previous *Address           `bst:"previous"`
+	History  []Address          `bst:"history"`
+	Labels   map[string]int64   `bst:"labels"`
+	Created  time.Time          `bst:"created"`
+	TTL      time.Duration      `bst:"ttl"`
+	Ratio    float64            `bst:"ratio"`
+	Nested   map[uint8][]uint16 `bst:"nested"`
+	Admin    bool               `bst:"admin,desc"`
+	Verified bool               `bst:"verified"`
+	Ignored  string             `bst:"-"`
+}
+
+// ShopModules returns the module definitions of the shop, whose Go types are generated.
+func ShopModules() *bsttype.Modules {
+	item := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "sku", Type: &bsttype.Bytes{}},
+		{Index: 2, Name: "quantity", Type: bsttype.Uint(), Doc: "Quantity is the number of the ordered units."},
+		{Index: 3, Name: "price", Type: bsttype.Float32(), Descending: true},
+	}}
+	order := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "order_id", Type: bsttype.Int()},
+		{Index: 2, Name: "items", Type: bsttype.ArrayOf(&bsttype.Named{Module: "shop", Name: "item"})},
+		{Index: 3, Name: "paid", Type: bsttype.Boolean()},
+		{Index: 4, Name: "note", Type: bsttype.NullableOf(bsttype.String()), Doc: "Note is the message of the customer,\nif any."},
+		{Index: 5, Name: "placed", Type: bsttype.NullableOf(bsttype.Timestamp())},
+		{Index: 6, Name: "delivery", Type: &bsttype.Struct{Fields: []bsttype.StructField{
+			{Index: 1, Name: "street", Type: bsttype.String()},
+			{Index: 2, Name: "express", Type: bsttype.Boolean()},
+		}}},
+		{Index: 7, Name: "discounts", Type: bsttype.MapTypeOf(bsttype.Timestamp(), bsttype.Int16(), false, false)},
+		{Index: 8, Name: "gift", Type: bsttype.NullableOf(&bsttype.Named{Module: "shop", Name: "item"})},
+	}}
+	return &bsttype.Modules{List: []*bsttype.Module{{
+		Name: "shop",
+		Definitions: []bsttype.ModuleDefinition{
+			{Name: "order", Type: order},
+			{Name: "item", Type: item},
+		},
+	}}}
+}
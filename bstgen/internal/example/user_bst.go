@@ -0,0 +1,430 @@
+// Code generated by bstgen. DO NOT EDIT.
+
+package example
+
+import (
+	"io"
+	"slices"
+	"time"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+)
+
+// EncodeBST writes the BST binary of the value to w. Returns the number of bytes written.
+func (x *User) EncodeBST(w io.Writer) (int, error) {
+	var (
+		bytesWritten, n int
+		err             error
+	)
+	n, err = bstio.WriteUint64(w, x.ID, false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "id")
+	}
+	n, err = bstio.WriteString(w, x.Name, false, false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "name")
+	}
+	var b1 byte
+	if x.Active {
+		b1 |= 1 << 0
+	}
+	if err = bstio.WriteByte(w, b1); err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "active")
+	}
+	bytesWritten++
+	n, err = bstio.WriteInt32(w, x.Score, true)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "Score")
+	}
+	var b3 byte
+	for i2 := range x.Flags {
+		if x.Flags[i2] {
+			b3 |= 1 << (i2 & 7)
+		}
+		if i2&7 == 7 || i2 == len(x.Flags)-1 {
+			if err = bstio.WriteByte(w, b3); err != nil {
+				return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "flags")
+			}
+			bytesWritten++
+			b3 = 0
+		}
+	}
+	n, err = bstio.WriteInt8(w, int8(x.Level), false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "level")
+	}
+	n, err = bstio.WriteUint(w, uint(len(x.Tags)), false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "tags")
+	}
+	for i4 := range x.Tags {
+		n, err = bstio.WriteString(w, x.Tags[i4], false, false)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "tags")
+		}
+	}
+	n, err = bstio.WriteBytes(w, 0, x.Data, false, false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "data")
+	}
+	n, err = x.Address.EncodeBST(w)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "address")
+	}
+	if x.Previous == nil {
+		if err = bstio.WriteByte(w, bstio.NullableIsNull); err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "previous")
+		}
+		bytesWritten++
+	} else {
+		if err = bstio.WriteByte(w, bstio.NullableIsNotNull); err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "previous")
+		}
+		bytesWritten++
+		n, err = (*x.Previous).EncodeBST(w)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "previous")
+		}
+	}
+	n, err = bstio.WriteUint(w, uint(len(x.History)), false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "history")
+	}
+	for i5 := range x.History {
+		n, err = x.History[i5].EncodeBST(w)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "history")
+		}
+	}
+	n, err = bstio.WriteUint(w, uint(len(x.Labels)), false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "labels")
+	}
+	keys6 := make([]string, 0, len(x.Labels))
+	for k7 := range x.Labels {
+		keys6 = append(keys6, k7)
+	}
+	slices.Sort(keys6)
+	for _, k7 := range keys6 {
+		n, err = bstio.WriteString(w, k7, false, false)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "labels")
+		}
+		e8 := x.Labels[k7]
+		n, err = bstio.WriteInt64(w, e8, false)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "labels")
+		}
+	}
+	n, err = bstio.WriteInt64(w, x.Created.UnixNano(), false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "created")
+	}
+	n, err = bstio.WriteInt64(w, int64(x.TTL), false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "ttl")
+	}
+	n, err = bstio.WriteFloat64(w, x.Ratio, false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "ratio")
+	}
+	n, err = bstio.WriteUint(w, uint(len(x.Nested)), false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "nested")
+	}
+	keys9 := make([]uint8, 0, len(x.Nested))
+	for k10 := range x.Nested {
+		keys9 = append(keys9, k10)
+	}
+	slices.Sort(keys9)
+	for _, k10 := range keys9 {
+		n, err = bstio.WriteUint8(w, k10, false)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "nested")
+		}
+		e11 := x.Nested[k10]
+		n, err = bstio.WriteUint(w, uint(len(e11)), false)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "nested")
+		}
+		for i12 := range e11 {
+			n, err = bstio.WriteUint16(w, e11[i12], false)
+			bytesWritten += n
+			if err != nil {
+				return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "nested")
+			}
+		}
+	}
+	var b13 byte
+	if !x.Admin {
+		b13 |= 1 << 0
+	}
+	if x.Verified {
+		b13 |= 1 << 1
+	}
+	if err = bstio.WriteByte(w, b13); err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "admin")
+	}
+	bytesWritten++
+	return bytesWritten, nil
+}
+
+// DecodeBST reads the BST binary of the value from r. Returns the number of bytes read.
+func (x *User) DecodeBST(r io.Reader) (int, error) {
+	var (
+		bytesRead, n int
+		err          error
+	)
+	x.ID, n, err = bstio.ReadUint64(r, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "id")
+	}
+	x.Name, n, err = bstio.ReadString(r, false, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "name")
+	}
+	var b1 byte
+	if b1, err = bstio.ReadByte(r); err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "active")
+	}
+	bytesRead++
+	x.Active = b1&(1<<0) != 0
+	x.Score, n, err = bstio.ReadInt32(r, true)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "Score")
+	}
+	var b3 byte
+	for i2 := range x.Flags {
+		if i2&7 == 0 {
+			if b3, err = bstio.ReadByte(r); err != nil {
+				return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "flags")
+			}
+			bytesRead++
+		}
+		x.Flags[i2] = b3&(1<<(i2&7)) != 0
+	}
+	var v4 int8
+	v4, n, err = bstio.ReadInt8(r, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "level")
+	}
+	x.Level = Level(v4)
+	var l5 uint
+	l5, n, err = bstio.ReadLength(r, false, 1)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "tags")
+	}
+	if uint(cap(x.Tags)) >= l5 {
+		x.Tags = x.Tags[:l5]
+	} else {
+		x.Tags = make([]string, l5)
+	}
+	for i6 := range x.Tags {
+		x.Tags[i6], n, err = bstio.ReadString(r, false, false)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "tags")
+		}
+	}
+	x.Data, n, err = bstio.ReadBytesAppend(x.Data[:0], r, 0, false, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "data")
+	}
+	n, err = x.Address.DecodeBST(r)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "address")
+	}
+	var f7 byte
+	if f7, err = bstio.ReadNullableFlag(r, false); err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "previous")
+	}
+	bytesRead++
+	if f7 == bstio.NullableIsNull {
+		x.Previous = nil
+	} else {
+		if x.Previous == nil {
+			x.Previous = new(Address)
+		}
+		n, err = (*x.Previous).DecodeBST(r)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "previous")
+		}
+	}
+	var l8 uint
+	l8, n, err = bstio.ReadLength(r, false, 0)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "history")
+	}
+	if uint(cap(x.History)) >= l8 {
+		x.History = x.History[:l8]
+	} else {
+		x.History = make([]Address, l8)
+	}
+	for i9 := range x.History {
+		n, err = x.History[i9].DecodeBST(r)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "history")
+		}
+	}
+	var l10 uint
+	l10, n, err = bstio.ReadLength(r, false, 9)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "labels")
+	}
+	if x.Labels == nil {
+		x.Labels = make(map[string]int64, l10)
+	} else {
+		clear(x.Labels)
+	}
+	for i11 := uint(0); i11 < l10; i11++ {
+		var k12 string
+		k12, n, err = bstio.ReadString(r, false, false)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "labels")
+		}
+		var e13 int64
+		e13, n, err = bstio.ReadInt64(r, false)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "labels")
+		}
+		x.Labels[k12] = e13
+	}
+	var v14 int64
+	v14, n, err = bstio.ReadInt64(r, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "created")
+	}
+	x.Created = time.Unix(0, v14).UTC()
+	var v15 int64
+	v15, n, err = bstio.ReadInt64(r, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "ttl")
+	}
+	x.TTL = time.Duration(v15)
+	x.Ratio, n, err = bstio.ReadFloat64(r, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "ratio")
+	}
+	var l16 uint
+	l16, n, err = bstio.ReadLength(r, false, 2)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "nested")
+	}
+	if x.Nested == nil {
+		x.Nested = make(map[uint8][]uint16, l16)
+	} else {
+		clear(x.Nested)
+	}
+	for i17 := uint(0); i17 < l16; i17++ {
+		var k18 uint8
+		k18, n, err = bstio.ReadUint8(r, false)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "nested")
+		}
+		var e19 []uint16
+		var l20 uint
+		l20, n, err = bstio.ReadLength(r, false, 2)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "nested")
+		}
+		if uint(cap(e19)) >= l20 {
+			e19 = e19[:l20]
+		} else {
+			e19 = make([]uint16, l20)
+		}
+		for i21 := range e19 {
+			e19[i21], n, err = bstio.ReadUint16(r, false)
+			bytesRead += n
+			if err != nil {
+				return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "nested")
+			}
+		}
+		x.Nested[k18] = e19
+	}
+	var b22 byte
+	if b22, err = bstio.ReadByte(r); err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "admin")
+	}
+	bytesRead++
+	x.Admin = b22&(1<<0) == 0
+	x.Verified = b22&(1<<1) != 0
+	return bytesRead, nil
+}
+
+// EncodeBST writes the BST binary of the value to w. Returns the number of bytes written.
+func (x *Address) EncodeBST(w io.Writer) (int, error) {
+	var (
+		bytesWritten, n int
+		err             error
+	)
+	n, err = bstio.WriteString(w, x.City, false, false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "city")
+	}
+	n, err = bstio.WriteUint32(w, x.Zip, false)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").WithDetail("field", "zip")
+	}
+	return bytesWritten, nil
+}
+
+// DecodeBST reads the BST binary of the value from r. Returns the number of bytes read.
+func (x *Address) DecodeBST(r io.Reader) (int, error) {
+	var (
+		bytesRead, n int
+		err          error
+	)
+	x.City, n, err = bstio.ReadString(r, false, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "city")
+	}
+	x.Zip, n, err = bstio.ReadUint32(r, false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").WithDetail("field", "zip")
+	}
+	return bytesRead, nil
+}
@@ -0,0 +1,381 @@
+package bstgen
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/devmodules/bst"
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+var (
+	_timeType     = reflect.TypeOf(time.Time{})
+	_durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// genStruct is the struct whose encoder and decoder are generated.
+type genStruct struct {
+	// name is the name of the Go type.
+	name string
+	// doc is the doc comment of the Go type declaration, which is generated only if not empty.
+	doc    string
+	t      *bsttype.Struct
+	fields []genField
+}
+
+// genField is the struct field, mapped to the Go struct field of given name.
+type genField struct {
+	name  string
+	field bsttype.StructField
+	t     *goType
+}
+
+// goType is the Go representation of the bst type.
+type goType struct {
+	// kind is the kind of the dereferenced bst type.
+	kind bsttype.Kind
+	// t is the dereferenced bst type.
+	t bsttype.Type
+	// name is the Go type expression.
+	name string
+	// conv determines that the Go type is a named type, which needs to be converted to and from
+	// the values of the bstio functions.
+	conv bool
+	// elem is the element of the Array and Nullable, or the value of the Map.
+	elem *goType
+	// key is the key of the Map.
+	key *goType
+	// st is the generated struct of the Struct.
+	st *genStruct
+}
+
+// _basicGoTypes are the Go types of the basic bst kinds.
+var _basicGoTypes = map[bsttype.Kind]string{
+	bsttype.KindBoolean:   "bool",
+	bsttype.KindInt:       "int",
+	bsttype.KindInt8:      "int8",
+	bsttype.KindInt16:     "int16",
+	bsttype.KindInt32:     "int32",
+	bsttype.KindInt64:     "int64",
+	bsttype.KindUint:      "uint",
+	bsttype.KindUint8:     "uint8",
+	bsttype.KindUint16:    "uint16",
+	bsttype.KindUint32:    "uint32",
+	bsttype.KindUint64:    "uint64",
+	bsttype.KindFloat32:   "float32",
+	bsttype.KindFloat64:   "float64",
+	bsttype.KindString:    "string",
+	bsttype.KindBytes:     "[]byte",
+	bsttype.KindDuration:  "time.Duration",
+	bsttype.KindTimestamp: "time.Time",
+}
+
+// addStruct adds the struct definition of given Go type name, along with the structs it depends on.
+// The Go type declaration is generated as well.
+func (x *Generator) addStruct(name, doc string, st *bsttype.Struct) (*genStruct, error) {
+	// 1. The struct might be already added, i.e. as a dependency of another struct.
+	if gs, ok := x.byType[st]; ok {
+		return gs, nil
+	}
+	gs := &genStruct{name: name, doc: doc, t: st}
+	if err := x.register(gs); err != nil {
+		return nil, err
+	}
+	x.byType[st] = gs
+
+	// 2. Map each field into the exported Go struct field.
+	names := make(map[string]struct{}, len(st.Fields))
+	for _, f := range st.Fields {
+		goName := exportName(f.Name)
+		if _, ok := names[goName]; ok {
+			return nil, bsterr.Err(bsterr.CodeInvalidType, "struct fields map to the same Go field name").
+				WithDetails(bsterr.D("struct", name), bsterr.D("field", goName))
+		}
+		names[goName] = struct{}{}
+		if f.Name == "-" || strings.ContainsAny(f.Name, ",`") {
+			return nil, bsterr.Err(bsterr.CodeInvalidType, "struct field name could not be written in the Go struct tag").
+				WithDetails(bsterr.D("struct", name), bsterr.D("field", f.Name))
+		}
+
+		gt, err := x.typeOf(name+goName, f.Type)
+		if err != nil {
+			return nil, wrapFieldError(err, name, f)
+		}
+		if err = checkField(f, gt); err != nil {
+			return nil, wrapFieldError(err, name, f)
+		}
+		gs.fields = append(gs.fields, genField{name: goName, field: f, t: gt})
+	}
+	return gs, nil
+}
+
+// typeOf returns the Go type of the bst type. The anonymous structs are declared with given name.
+func (x *Generator) typeOf(name string, t bsttype.Type) (*goType, error) {
+	switch tt := t.(type) {
+	case *bsttype.Named:
+		// 1. The named types need to be resolved, where the named structs are declared with the name of the type.
+		if tt.Type == nil {
+			return nil, bsterr.Err(bsterr.CodeUndefinedType, "named type is not resolved").WithDetail("type", tt.String())
+		}
+		if st, ok := tt.Type.(*bsttype.Struct); ok {
+			gs, err := x.addStruct(exportName(tt.Name), fmt.Sprintf("the Go type of the %s struct", tt), st)
+			if err != nil {
+				return nil, err
+			}
+			return &goType{kind: bsttype.KindStruct, t: st, name: gs.name, st: gs}, nil
+		}
+		return x.typeOf(name, tt.Type)
+	case *bsttype.Struct:
+		gs, err := x.addStruct(name, "the Go type of the anonymous struct", tt)
+		if err != nil {
+			return nil, err
+		}
+		return &goType{kind: bsttype.KindStruct, t: tt, name: gs.name, st: gs}, nil
+	case *bsttype.Nullable:
+		elem, err := x.typeOf(name, tt.Type)
+		if err != nil {
+			return nil, err
+		}
+		return &goType{kind: bsttype.KindNullable, t: tt, name: "*" + elem.name, elem: elem}, nil
+	case *bsttype.Array:
+		elem, err := x.typeOf(name+"Elem", tt.Type)
+		if err != nil {
+			return nil, err
+		}
+		gt := &goType{kind: bsttype.KindArray, t: tt, name: "[]" + elem.name, elem: elem}
+		if tt.HasFixedSize() {
+			gt.name = fmt.Sprintf("[%d]%s", tt.FixedSize, elem.name)
+		}
+		return gt, nil
+	case *bsttype.Map:
+		key, err := x.typeOf(name+"Key", tt.Key.Type)
+		if err != nil {
+			return nil, err
+		}
+		value, err := x.typeOf(name+"Value", tt.Value.Type)
+		if err != nil {
+			return nil, err
+		}
+		return &goType{kind: bsttype.KindMap, t: tt, name: "map[" + key.name + "]" + value.name, key: key, elem: value}, nil
+	}
+
+	gn, ok := _basicGoTypes[t.Kind()]
+	if !ok {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "type is not supported by the generator").WithDetail("kind", t.Kind())
+	}
+	return &goType{kind: t.Kind(), t: t, name: gn}, nil
+}
+
+// addGoStruct adds the Go struct type, along with the Go structs it depends on.
+// The struct type is derived with the bst.StructTypeOf, thus the Go struct fields are tagged as for the bst.Marshal.
+func (x *Generator) addGoStruct(rt reflect.Type) (*genStruct, error) {
+	// 1. The struct might be already added, i.e. as a dependency of another struct.
+	if gs, ok := x.byGoType[rt]; ok {
+		return gs, nil
+	}
+	if rt.Name() == "" || rt.PkgPath() != x.pkgPath {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "Go struct needs to be a named type of the generated package").
+			WithDetails(bsterr.D("type", rt), bsterr.D("package", x.pkgPath))
+	}
+	st, err := bst.StructTypeOf(reflect.New(rt).Interface())
+	if err != nil {
+		return nil, err
+	}
+	gs := &genStruct{name: rt.Name(), t: st}
+	if err = x.register(gs); err != nil {
+		return nil, err
+	}
+	x.byGoType[rt] = gs
+
+	// 2. Find the Go struct fields by the names of the struct type fields.
+	goFields := make(map[string]reflect.StructField, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := sf.Tag.Get("bst")
+		if !sf.IsExported() || tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		if _, ok := goFields[name]; ok {
+			return nil, bsterr.Err(bsterr.CodeInvalidType, "Go struct fields map to the same struct field name").
+				WithDetails(bsterr.D("type", rt), bsterr.D("field", name))
+		}
+		goFields[name] = sf
+	}
+
+	// 3. Map each field.
+	for _, f := range st.Fields {
+		sf := goFields[f.Name]
+		gt, err := x.goTypeOf(sf.Type, f.Type)
+		if err != nil {
+			return nil, wrapFieldError(err, gs.name, f)
+		}
+		if err = checkField(f, gt); err != nil {
+			return nil, wrapFieldError(err, gs.name, f)
+		}
+		gs.fields = append(gs.fields, genField{name: sf.Name, field: f, t: gt})
+	}
+	return gs, nil
+}
+
+// goTypeOf returns the Go type of the Go reflect type, mapped to given bst type.
+func (x *Generator) goTypeOf(rt reflect.Type, t bsttype.Type) (*goType, error) {
+	name, err := x.goTypeName(rt)
+	if err != nil {
+		return nil, err
+	}
+	gt := &goType{kind: t.Kind(), t: t, name: name}
+	switch tt := t.(type) {
+	case *bsttype.Struct:
+		if gt.st, err = x.addGoStruct(rt); err != nil {
+			return nil, err
+		}
+	case *bsttype.Nullable:
+		gt.elem, err = x.goTypeOf(rt.Elem(), tt.Type)
+	case *bsttype.Array:
+		gt.elem, err = x.goTypeOf(rt.Elem(), tt.Type)
+	case *bsttype.Map:
+		if gt.key, err = x.goTypeOf(rt.Key(), tt.Key.Type); err != nil {
+			return nil, err
+		}
+		gt.elem, err = x.goTypeOf(rt.Elem(), tt.Value.Type)
+	default:
+		// The named Go types of the basic kinds are converted, except the well-known time types.
+		gt.conv = rt.PkgPath() != "" && rt != _timeType && rt != _durationType
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gt, nil
+}
+
+// goTypeName returns the Go type expression of the reflect type, within the generated package.
+func (x *Generator) goTypeName(rt reflect.Type) (string, error) {
+	switch {
+	case rt == _timeType:
+		return "time.Time", nil
+	case rt == _durationType:
+		return "time.Duration", nil
+	case rt.Name() != "":
+		if rt.PkgPath() != "" && rt.PkgPath() != x.pkgPath {
+			return "", bsterr.Err(bsterr.CodeInvalidType, "Go type of another package is not supported by the generator").
+				WithDetail("type", rt)
+		}
+		return rt.Name(), nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		elem, err := x.goTypeName(rt.Elem())
+		if err != nil {
+			return "", err
+		}
+		switch rt.Kind() {
+		case reflect.Pointer:
+			return "*" + elem, nil
+		case reflect.Slice:
+			return "[]" + elem, nil
+		}
+		return fmt.Sprintf("[%d]%s", rt.Len(), elem), nil
+	case reflect.Map:
+		key, err := x.goTypeName(rt.Key())
+		if err != nil {
+			return "", err
+		}
+		value, err := x.goTypeName(rt.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "map[" + key + "]" + value, nil
+	}
+	return "", bsterr.Err(bsterr.CodeInvalidType, "Go type is not supported by the generator").WithDetail("type", rt)
+}
+
+// register adds the generated struct, whose Go type name needs to be unique.
+func (x *Generator) register(gs *genStruct) error {
+	if _, ok := x.byName[gs.name]; ok {
+		return bsterr.Err(bsterr.CodeTypeAlreadyMapped, "Go type name is already used by another struct").
+			WithDetail("name", gs.name)
+	}
+	x.byName[gs.name] = gs
+	x.structs = append(x.structs, gs)
+	return nil
+}
+
+// checkField verifies that the generator supports the encoding of the struct field.
+func checkField(f bsttype.StructField, gt *goType) error {
+	if f.Encoding != bsttype.FieldEncodingPlain {
+		return bsterr.Err(bsterr.CodeInvalidType, "field encoding is not supported by the generator").
+			WithDetail("encoding", f.Encoding)
+	}
+	return checkType(gt)
+}
+
+// checkType verifies that the generator supports the encoding of the Go type. The booleans are packed into bytes
+// only as the struct fields and the array elements, thus the nullable booleans and the booleans of the maps
+// are not supported, just like the array encodings other than the plain one.
+func checkType(gt *goType) error {
+	switch gt.kind {
+	case bsttype.KindNullable:
+		if k := gt.elem.kind; k == bsttype.KindBoolean || k == bsttype.KindNullable {
+			return bsterr.Err(bsterr.CodeInvalidType, "nullable type is not supported by the generator").
+				WithDetail("elem", k)
+		}
+		return checkType(gt.elem)
+	case bsttype.KindArray:
+		if enc := gt.t.(*bsttype.Array).Encoding; enc != bsttype.ArrayEncodingPlain {
+			return bsterr.Err(bsterr.CodeInvalidType, "array encoding is not supported by the generator").
+				WithDetail("encoding", enc)
+		}
+		return checkType(gt.elem)
+	case bsttype.KindMap:
+		switch gt.key.kind {
+		case bsttype.KindInt, bsttype.KindInt8, bsttype.KindInt16, bsttype.KindInt32, bsttype.KindInt64,
+			bsttype.KindUint, bsttype.KindUint8, bsttype.KindUint16, bsttype.KindUint32, bsttype.KindUint64,
+			bsttype.KindString, bsttype.KindDuration, bsttype.KindTimestamp:
+		default:
+			return bsterr.Err(bsterr.CodeInvalidType, "map key type is not supported by the generator").
+				WithDetail("key", gt.key.kind)
+		}
+		if gt.elem.kind == bsttype.KindBoolean {
+			return bsterr.Err(bsterr.CodeInvalidType, "boolean map values are not supported by the generator")
+		}
+		return checkType(gt.elem)
+	}
+	return nil
+}
+
+// wrapFieldError wraps the error of mapping the struct field.
+func wrapFieldError(err error, name string, f bsttype.StructField) error {
+	return bsterr.ErrWrap(err, bsterr.CodeTypeNotMapped, "failed to map struct field").
+		WithDetails(bsterr.D("struct", name), bsterr.D("field", f.Name))
+}
+
+// exportName converts the name of the bst type or field into the exported Go identifier,
+// i.e. the 'user_id' into the 'UserId'.
+func exportName(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	if s := sb.String(); s != "" && unicode.IsUpper([]rune(s)[0]) {
+		return s
+	}
+	return "X" + sb.String()
+}
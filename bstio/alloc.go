@@ -0,0 +1,201 @@
+package bstio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync/atomic"
+)
+
+// Allocator provides the scratch byte slices used while encoding the values, i.e. the escaped comparable bytes
+// or the inverted descending strings. It lets the high-throughput users reuse the scratch slices, instead of
+// allocating new ones for each value. The nil Allocator allocates on the heap.
+type Allocator interface {
+	// Alloc returns the byte slice of zero length and at least n capacity.
+	Alloc(n int) []byte
+	// Free releases the byte slice returned by the Alloc, which is no longer used.
+	// The slice could have been grown by appending to it.
+	Free(b []byte)
+}
+
+// Compile-time check to ensure that ScratchAllocator implements the Allocator interface.
+var _ Allocator = (*ScratchAllocator)(nil)
+
+// ScratchAllocator is the Allocator which keeps the largest freed slice, and reuses it for the next allocation.
+// It is not safe for concurrent use, thus it should be owned by a single composer.
+type ScratchAllocator struct {
+	buf []byte
+}
+
+// Alloc returns the kept slice if its capacity is sufficient, or a new one otherwise.
+// Implements the Allocator interface.
+func (x *ScratchAllocator) Alloc(n int) []byte {
+	if cap(x.buf) < n {
+		return make([]byte, 0, n)
+	}
+	b := x.buf[:0]
+	x.buf = nil
+	return b
+}
+
+// Free keeps the slice for the next allocation, if it is larger than the kept one.
+// Implements the Allocator interface.
+func (x *ScratchAllocator) Free(b []byte) {
+	if cap(b) > cap(x.buf) {
+		x.buf = b[:0]
+	}
+}
+
+// allocBytes returns the scratch byte slice of the allocator, or a new one if the allocator is nil.
+func allocBytes(a Allocator, n int) []byte {
+	if a == nil {
+		return make([]byte, 0, n)
+	}
+	return a.Alloc(n)
+}
+
+// freeBytes releases the scratch byte slice to the allocator, if both are defined.
+func freeBytes(a Allocator, b []byte) {
+	if a != nil && b != nil {
+		a.Free(b)
+	}
+}
+
+// AppendUint8 appends the binary of the unsigned 8-bit integer to the dst.
+func AppendUint8(dst []byte, v uint8, desc bool) []byte {
+	if desc {
+		v = ^v
+	}
+	return append(dst, v)
+}
+
+// AppendUint16 appends the binary of the unsigned 16-bit integer to the dst.
+func AppendUint16(dst []byte, v uint16, desc bool) []byte {
+	return appendOrdered(dst, binary.BigEndian.AppendUint16(dst, v), desc)
+}
+
+// AppendUint32 appends the binary of the unsigned 32-bit integer to the dst.
+func AppendUint32(dst []byte, v uint32, desc bool) []byte {
+	return appendOrdered(dst, binary.BigEndian.AppendUint32(dst, v), desc)
+}
+
+// AppendUint64 appends the binary of the unsigned 64-bit integer to the dst.
+func AppendUint64(dst []byte, v uint64, desc bool) []byte {
+	return appendOrdered(dst, binary.BigEndian.AppendUint64(dst, v), desc)
+}
+
+// AppendUint appends the binary of the varying size unsigned integer to the dst.
+// The binary is the same as written by the WriteUint: the size header followed by the big-endian value bytes.
+func AppendUint(dst []byte, uv uint, desc bool) []byte {
+	bytesNo := findUintBytes(uv)
+	n := len(dst)
+	dst = append(dst, byte(bytesNo))
+	for i := bytesNo; i >= 1; i-- {
+		dst = append(dst, byte(uv>>uint(8*(i-1))))
+	}
+	return appendOrdered(dst[:n], dst, desc)
+}
+
+// AppendInt16 appends the binary of the 16-bit integer to the dst.
+// The binary is the same as of the MarshalInt16.
+func AppendInt16(dst []byte, v int16, desc bool) []byte {
+	n := len(dst)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(v))
+	dst[n] = signedFirstByte(dst[n], v < 0)
+	return appendOrdered(dst[:n], dst, desc)
+}
+
+// AppendInt32 appends the binary of the 32-bit integer to the dst.
+// The binary is the same as of the MarshalInt32.
+func AppendInt32(dst []byte, v int32, desc bool) []byte {
+	n := len(dst)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(v))
+	dst[n] = signedFirstByte(dst[n], v < 0)
+	return appendOrdered(dst[:n], dst, desc)
+}
+
+// AppendInt64 appends the binary of the 64-bit integer to the dst.
+// The binary is the same as of the MarshalInt64.
+func AppendInt64(dst []byte, v int64, desc bool) []byte {
+	n := len(dst)
+	dst = binary.BigEndian.AppendUint64(dst, uint64(v))
+	dst[n] = signedFirstByte(dst[n], v < 0)
+	return appendOrdered(dst[:n], dst, desc)
+}
+
+// AppendFloat32 appends the binary of the float32 value to the dst.
+// The binary is the same as of the MarshalFloat32.
+func AppendFloat32(dst []byte, v float32, desc bool) []byte {
+	return binary.BigEndian.AppendUint32(dst, float32Bits(v, false, desc))
+}
+
+// AppendOrderedFloat32 appends the binary of the float32 value in the ordered format to the dst.
+// The binary is the same as written by the WriteOrderedFloat32.
+func AppendOrderedFloat32(dst []byte, v float32, desc bool) []byte {
+	return binary.BigEndian.AppendUint32(dst, float32Bits(v, true, desc))
+}
+
+// AppendFloat64 appends the binary of the float64 value to the dst.
+// The binary is the same as of the MarshalFloat64.
+func AppendFloat64(dst []byte, v float64, desc bool) []byte {
+	return binary.BigEndian.AppendUint64(dst, float64Bits(v, false, desc))
+}
+
+// AppendOrderedFloat64 appends the binary of the float64 value in the ordered format to the dst.
+// The binary is the same as written by the WriteOrderedFloat64.
+func AppendOrderedFloat64(dst []byte, v float64, desc bool) []byte {
+	return binary.BigEndian.AppendUint64(dst, float64Bits(v, true, desc))
+}
+
+// AppendComparableBytes appends the comparable binary of the bytes to the dst, escaped with the BytesEscape
+// and finished with the terminator. The binary is the same as written by the WriteBytes in the comparable mode.
+func AppendComparableBytes(dst, v []byte, desc bool) []byte {
+	return appendBytesInternalComparable(dst, v, BytesEscape, desc)
+}
+
+// appendBytesInternalComparable appends the data with each eb byte escaped, followed by the escape terminator.
+func appendBytesInternalComparable(dst, data []byte, eb byte, desc bool) []byte {
+	n := len(dst)
+
+	// 1. Iterate over the byte slice and escape each escape byte.
+	for {
+		i := bytes.IndexByte(data, eb)
+		if i == -1 {
+			break
+		}
+		dst = append(dst, data[:i]...)
+		dst = append(dst, eb, 0xff)
+		data = data[i+1:]
+	}
+
+	// 2. Append the rest of the data along with the escape terminator.
+	dst = append(dst, data...)
+	dst = append(dst, eb, 0x01)
+	return appendOrdered(dst[:n], dst, desc)
+}
+
+// signedFirstByte returns the first byte of the signed value binary, where the highest bit is set for the positive values.
+func signedFirstByte(fb byte, negative bool) byte {
+	if negative {
+		return fb & NegativeBit8Mask
+	}
+	return fb | PositiveBit8Mask
+}
+
+// appendOrdered inverts the bytes appended to the prefix for the descending order, and returns the whole slice.
+func appendOrdered(prefix, dst []byte, desc bool) []byte {
+	if desc {
+		ReverseBytes(dst[len(prefix):])
+	}
+	return dst
+}
+
+// SetDecimalScratchLimit sets the maximum number of the decimal digits, for which the scratch space of the decimal
+// encoding is pooled for reuse, and returns the previous limit. The scratch space grown by the larger decimals
+// is left to the garbage collector, so that the pool doesn't retain the memory of the occasional huge values.
+// The zero limit disables the pooling. The default limit is 1024 digits.
+func SetDecimalScratchLimit(digits int) int {
+	if digits < 0 {
+		digits = 0
+	}
+	return int(atomic.SwapInt64(&_decimalScratchLimit, int64(digits)))
+}
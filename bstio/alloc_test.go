@@ -0,0 +1,84 @@
+package bstio
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	prefix := []byte{0xAA, 0xBB}
+	for _, desc := range []bool{false, true} {
+		tests := []struct {
+			name     string
+			appended []byte
+			expected []byte
+		}{
+			{name: "Uint8", appended: AppendUint8(prefix, 0x12, desc), expected: MarshalUint8(0x12, desc)},
+			{name: "Uint16", appended: AppendUint16(prefix, 0x1234, desc), expected: MarshalUint16(0x1234, desc)},
+			{name: "Uint32", appended: AppendUint32(prefix, 0x12345678, desc), expected: MarshalUint32(0x12345678, desc)},
+			{name: "Uint64", appended: AppendUint64(prefix, math.MaxUint64-5, desc), expected: MarshalUint64(math.MaxUint64-5, desc)},
+			{name: "UintZero", appended: AppendUint(prefix, 0, desc), expected: MarshalUint(0, desc)},
+			{name: "Uint", appended: AppendUint(prefix, 0x123456, desc), expected: MarshalUint(0x123456, desc)},
+			{name: "Int16", appended: AppendInt16(prefix, -1234, desc), expected: MarshalInt16(-1234, desc)},
+			{name: "Int32", appended: AppendInt32(prefix, 123456, desc), expected: MarshalInt32(123456, desc)},
+			{name: "Int64", appended: AppendInt64(prefix, math.MinInt64, desc), expected: MarshalInt64(math.MinInt64, desc)},
+			{name: "Float32", appended: AppendFloat32(prefix, -1.5, desc), expected: MarshalFloat32(-1.5, desc)},
+			{name: "Float64", appended: AppendFloat64(prefix, 2.25, desc), expected: MarshalFloat64(2.25, desc)},
+		}
+		for _, tc := range tests {
+			if !bytes.Equal(tc.appended[:len(prefix)], prefix) {
+				t.Errorf("%s (desc: %v): prefix was modified: %v", tc.name, desc, tc.appended)
+			}
+			if !bytes.Equal(tc.appended[len(prefix):], tc.expected) {
+				t.Errorf("%s (desc: %v): unexpected binary: %v, expected: %v", tc.name, desc, tc.appended[len(prefix):], tc.expected)
+			}
+		}
+
+		for _, v := range [][]byte{nil, {0x01, 0x02}, {0x00, 0xFF, 0x00}} {
+			var buf bytes.Buffer
+			if _, err := WriteBytes(&buf, 0, v, desc, true); err != nil {
+				t.Fatal(err)
+			}
+			if got := AppendComparableBytes(nil, v, desc); !bytes.Equal(got, buf.Bytes()) {
+				t.Errorf("unexpected comparable bytes %v (desc: %v): %v, expected: %v", v, desc, got, buf.Bytes())
+			}
+		}
+	}
+}
+
+func TestScratchAllocator(t *testing.T) {
+	var a ScratchAllocator
+	data := []byte{0x00, 0x01, 0x02}
+	write := func(buf *bytes.Buffer) {
+		buf.Reset()
+		for _, comparable := range []bool{false, true} {
+			if _, err := WriteStringAlloc(buf, "some\x00string", true, comparable, &a); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := WriteBytesAlloc(buf, 0, data, true, comparable, &a); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// 1. The allocator doesn't change the binary.
+	var buf, expected bytes.Buffer
+	write(&buf)
+	for _, comparable := range []bool{false, true} {
+		if _, err := WriteString(&expected, "some\x00string", true, comparable); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := WriteBytes(&expected, 0, data, true, comparable); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(buf.Bytes(), expected.Bytes()) {
+		t.Fatalf("unexpected binary: %v, expected: %v", buf.Bytes(), expected.Bytes())
+	}
+
+	// 2. Once the scratch slice is large enough, no more allocations are made.
+	if allocs := testing.AllocsPerRun(10, func() { write(&buf) }); allocs != 0 {
+		t.Fatalf("unexpected number of allocations: %v", allocs)
+	}
+}
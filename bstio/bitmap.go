@@ -0,0 +1,310 @@
+package bstio
+
+import (
+	"bytes"
+	"io"
+	"math/bits"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// Bitmap is the packed vector of bits. The bit i is stored in the byte i/8, starting from its most significant bit,
+// so that the packed bytes are ordered just as the bits are.
+//
+// Binary representation of the non-comparable Bitmap is:
+//
+//	Size(bits)   | Name     | Description
+//	-------------+----------+------------
+//	   8-72      | Length   | The number of the bits as the varying size unsigned integer.
+//	   8*N       | Bits     | The packed bits, N = (Length + 7) / 8. The unused bits of the last byte are zero.
+//
+// The comparable Bitmap is written as the comparable bytes of the packed bits (escaped with the BytesEscape),
+// followed by a single byte with the number of the bits used in the last byte (zero if the bitmap is empty).
+// This way the shorter bitmap is ordered before the longer one it prefixes.
+type Bitmap struct {
+	// Bits are the packed bits.
+	Bits []byte
+	// Len is the number of the bits.
+	Len int
+}
+
+// NewBitmap creates a new bitmap of n unset bits.
+func NewBitmap(n int) Bitmap {
+	return Bitmap{Bits: make([]byte, bitmapSize(n)), Len: n}
+}
+
+// BitmapOf creates a new bitmap of given boolean values.
+func BitmapOf(values ...bool) Bitmap {
+	b := NewBitmap(len(values))
+	for i, v := range values {
+		if v {
+			b.Bits[i>>3] |= 0x80 >> (i & 7)
+		}
+	}
+	return b
+}
+
+// bitmapSize returns the number of the bytes of n packed bits.
+func bitmapSize(n int) int {
+	return (n + 7) >> 3
+}
+
+// Get returns the bit at index i. It panics if the index is out of range.
+func (x Bitmap) Get(i int) bool {
+	x.checkIndex(i)
+	return x.Bits[i>>3]&(0x80>>(i&7)) != 0
+}
+
+// Set sets the bit at index i. It panics if the index is out of range.
+func (x Bitmap) Set(i int, v bool) {
+	x.checkIndex(i)
+	if v {
+		x.Bits[i>>3] |= 0x80 >> (i & 7)
+	} else {
+		x.Bits[i>>3] &^= 0x80 >> (i & 7)
+	}
+}
+
+func (x Bitmap) checkIndex(i int) {
+	if i < 0 || i >= x.Len {
+		panic(bsterr.Err(bsterr.CodeOutOfBounds, "bitmap index out of range").
+			WithDetails(bsterr.D("index", i), bsterr.D("length", x.Len)))
+	}
+}
+
+// Count returns the number of the set bits.
+func (x Bitmap) Count() int {
+	return x.Rank(x.Len)
+}
+
+// Rank returns the number of the set bits before the index i, i.e. in the range [0, i).
+// The index is clamped to the bitmap length.
+func (x Bitmap) Rank(i int) int {
+	i = min(max(i, 0), x.Len)
+	var n int
+	full := i >> 3
+	for _, b := range x.Bits[:full] {
+		n += bits.OnesCount8(b)
+	}
+	if rem := i & 7; rem > 0 {
+		n += bits.OnesCount8(x.Bits[full] & ^(0xFF >> rem))
+	}
+	return n
+}
+
+// Select returns the index of the k-th set bit, counting from zero. The false is returned if there are not enough set bits.
+func (x Bitmap) Select(k int) (int, bool) {
+	if k < 0 {
+		return 0, false
+	}
+	// 1. Skip the whole bytes with fewer set bits than needed.
+	for bi, b := range x.Bits[:bitmapSize(x.Len)] {
+		c := bits.OnesCount8(b)
+		if k >= c {
+			k -= c
+			continue
+		}
+
+		// 2. Find the set bit within the byte.
+		for ; ; k-- {
+			lz := bits.LeadingZeros8(b)
+			if k == 0 {
+				i := bi<<3 + lz
+				return i, i < x.Len
+			}
+			b &^= 0x80 >> lz
+		}
+	}
+	return 0, false
+}
+
+// NextSet returns the index of the first set bit at or after the index i. The false is returned if there is none.
+func (x Bitmap) NextSet(i int) (int, bool) {
+	i = max(i, 0)
+	for bi := i >> 3; bi < bitmapSize(x.Len); bi++ {
+		b := x.Bits[bi]
+		if bi == i>>3 {
+			b &= 0xFF >> (i & 7)
+		}
+		if b == 0 {
+			continue
+		}
+		next := bi<<3 + bits.LeadingZeros8(b)
+		return next, next < x.Len
+	}
+	return 0, false
+}
+
+// ForEachSet calls the fn with the index of each set bit, in ascending order. The iteration stops once the fn returns false.
+func (x Bitmap) ForEachSet(fn func(i int) bool) {
+	for i, ok := x.NextSet(0); ok; i, ok = x.NextSet(i + 1) {
+		if !fn(i) {
+			return
+		}
+	}
+}
+
+// Bools returns the bits as the boolean values.
+func (x Bitmap) Bools() []bool {
+	values := make([]bool, x.Len)
+	x.ForEachSet(func(i int) bool {
+		values[i] = true
+		return true
+	})
+	return values
+}
+
+// Validate checks if the number of the packed bytes matches the bitmap length.
+func (x Bitmap) Validate() error {
+	if x.Len < 0 || len(x.Bits) != bitmapSize(x.Len) {
+		return bsterr.Err(bsterr.CodeInvalidValue, "bitmap bits don't match its length").
+			WithDetails(bsterr.D("length", x.Len), bsterr.D("bytes", len(x.Bits)))
+	}
+	return nil
+}
+
+// BitmapBinarySize returns the size of the bitmap binary.
+func BitmapBinarySize(b Bitmap, comparable bool) uint {
+	if comparable {
+		// The bits are escaped before the binary is inverted for the descending order.
+		return BytesComparableBinarySize(b.Bits, BytesEscapeAscending) + 1
+	}
+	return uint(UintBinarySize(uint(b.Len)) + len(b.Bits))
+}
+
+// WriteBitmap writes the bitmap binary. The desc flag inverts the binary for the descending order.
+func WriteBitmap(w io.Writer, b Bitmap, desc, comparable bool) (int, error) {
+	// 1. Verify the bitmap. The unused bits of its last byte are cleared on a copy, so that its binary is canonical.
+	if err := b.Validate(); err != nil {
+		return 0, err
+	}
+	if rem := b.Len & 7; rem > 0 && b.Bits[len(b.Bits)-1]&(0xFF>>rem) != 0 {
+		b.Bits = append([]byte(nil), b.Bits...)
+		b.Bits[len(b.Bits)-1] &= ^(0xFF >> rem)
+	}
+
+	// 2. The comparable bitmap is written as the comparable bytes followed by the number of the bits of the last byte.
+	if comparable {
+		n, err := writeBytesInternalComparable(w, b.Bits, BytesEscape, desc, nil)
+		if err != nil {
+			return n, err
+		}
+		tail := byte(b.Len - (len(b.Bits)-1)<<3)
+		if b.Len == 0 {
+			tail = 0
+		}
+		if desc {
+			tail = ^tail
+		}
+		if err = WriteByte(w, tail); err != nil {
+			return n, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write bitmap tail")
+		}
+		return n + 1, nil
+	}
+
+	// 3. Otherwise, the length is followed by the packed bits.
+	n, err := WriteUint(w, uint(b.Len), desc)
+	if err != nil {
+		return n, err
+	}
+	if len(b.Bits) == 0 {
+		return n, nil
+	}
+	m, err := writeBytesNonComparable(w, len(b.Bits), b.Bits, desc, nil)
+	return n + m, err
+}
+
+// ReadBitmap reads the bitmap binary. The desc flag determines if the binary was written in descending order.
+func ReadBitmap(r io.Reader, desc, comparable bool) (Bitmap, int, error) {
+	// 1. Read the comparable bits, followed by the number of the bits of the last byte.
+	if comparable {
+		data, n, err := ReadComparableBytesAppend(nil, r, desc)
+		if err != nil {
+			return Bitmap{}, n, err
+		}
+		tail, err := ReadByte(r)
+		if err != nil {
+			return Bitmap{}, n, readError(err, n, n+1, "failed to read bitmap tail")
+		}
+		n++
+		if desc {
+			tail = ^tail
+		}
+		if (len(data) == 0 && tail != 0) || (len(data) > 0 && (tail == 0 || tail > 8)) {
+			return Bitmap{}, n, bsterr.Err(bsterr.CodeMalformedBinary, "invalid bitmap tail").WithDetail("tail", tail)
+		}
+		return Bitmap{Bits: data, Len: max(len(data)-1, 0)<<3 + int(tail)}, n, nil
+	}
+
+	// 2. Read the length, and the packed bits.
+	length, n, err := ReadUint(r, desc)
+	if err != nil {
+		return Bitmap{}, n, err
+	}
+	if err = CheckLength(r, uint(bitmapSize(int(length))), 1); err != nil {
+		return Bitmap{}, n, err
+	}
+	b := NewBitmap(int(length))
+	m, err := readFull(r, b.Bits, n, "failed to read bitmap bits")
+	if err != nil {
+		return Bitmap{}, n + m, err
+	}
+	if desc {
+		ReverseBytes(b.Bits)
+	}
+	return b, n + m, nil
+}
+
+// ViewBitmap returns the bitmap of the non-comparable bitmap binary, along with its binary size.
+// The bits of the ascending binary are not copied, thus the bitmap could be queried directly within its encoded form.
+// The bits of the descending binary are copied and inverted.
+func ViewBitmap(data []byte, desc bool) (Bitmap, int, error) {
+	// 1. Read the length.
+	length, n, err := ReadUint(bytes.NewReader(data), desc)
+	if err != nil {
+		return Bitmap{}, n, err
+	}
+
+	// 2. Slice the packed bits.
+	size := bitmapSize(int(length))
+	if len(data)-n < size {
+		return Bitmap{}, n, bsterr.ErrWrap(&TruncatedError{Expected: n + size, Read: len(data)}, bsterr.CodeTruncatedBinary,
+			"failed to view bitmap bits")
+	}
+	b := Bitmap{Bits: data[n : n+size : n+size], Len: int(length)}
+	if desc {
+		b.Bits = append([]byte(nil), b.Bits...)
+		ReverseBytes(b.Bits)
+	}
+	return b, n + size, nil
+}
+
+// SkipBitmap skips the bitmap binary.
+func SkipBitmap(rs io.ReadSeeker, desc, comparable bool) (int64, error) {
+	// 1. Skip the comparable bits and the tail byte.
+	if comparable {
+		escape := BytesEscapeAscending
+		if desc {
+			escape = BytesEscapeDescending
+		}
+		n, err := SkipComparableBytes(rs, 64, escape)
+		if err != nil {
+			return n, err
+		}
+		if _, err = rs.Seek(1, io.SeekCurrent); err != nil {
+			return n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip bitmap tail")
+		}
+		return n + 1, nil
+	}
+
+	// 2. Read the length and skip the packed bits.
+	length, n, err := ReadUint(rs, desc)
+	if err != nil {
+		return int64(n), err
+	}
+	size := int64(bitmapSize(int(length)))
+	if _, err = rs.Seek(size, io.SeekCurrent); err != nil {
+		return int64(n), bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip bitmap bits")
+	}
+	return int64(n) + size, nil
+}
@@ -0,0 +1,125 @@
+package bstio
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestBitmap(t *testing.T) {
+	values := []bool{true, false, false, true, true, false, false, false, false, true, false, true}
+	b := BitmapOf(values...)
+
+	if !slices.Equal(b.Bools(), values) {
+		t.Fatalf("unexpected bits: %v, expected: %v", b.Bools(), values)
+	}
+	if b.Count() != 5 {
+		t.Errorf("unexpected count: %d, expected: 5", b.Count())
+	}
+	for i, expected := range []int{0, 1, 1, 1, 2, 3, 3, 3, 3, 3, 4, 4, 5} {
+		if r := b.Rank(i); r != expected {
+			t.Errorf("unexpected rank of %d: %d, expected: %d", i, r, expected)
+		}
+	}
+	for k, expected := range []int{0, 3, 4, 9, 11} {
+		if i, ok := b.Select(k); !ok || i != expected {
+			t.Errorf("unexpected select of %d: %d, expected: %d", k, i, expected)
+		}
+	}
+	if _, ok := b.Select(5); ok {
+		t.Error("unexpected select past the set bits")
+	}
+
+	var set []int
+	b.ForEachSet(func(i int) bool {
+		set = append(set, i)
+		return true
+	})
+	if !slices.Equal(set, []int{0, 3, 4, 9, 11}) {
+		t.Errorf("unexpected set bits: %v", set)
+	}
+
+	b.Set(1, true)
+	b.Set(0, false)
+	if b.Get(0) || !b.Get(1) || b.Count() != 5 {
+		t.Errorf("unexpected bits after set: %v", b.Bools())
+	}
+}
+
+func TestBitmapBinary(t *testing.T) {
+	bitmaps := []Bitmap{
+		BitmapOf(),
+		BitmapOf(false, false, false, false, false, false, false, false, true),
+		BitmapOf(true),
+		BitmapOf(true, false),
+		BitmapOf(true, true, true, true, true, true, true, true),
+	}
+
+	for _, comparable := range []bool{false, true} {
+		for _, desc := range []bool{false, true} {
+			var binaries [][]byte
+			for _, b := range bitmaps {
+				var buf bytes.Buffer
+				n, err := WriteBitmap(&buf, b, desc, comparable)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != buf.Len() || uint(n) != BitmapBinarySize(b, comparable) {
+					t.Fatalf("unexpected bitmap binary size: %d, expected: %d", n, buf.Len())
+				}
+
+				got, n, err := ReadBitmap(bytes.NewReader(buf.Bytes()), desc, comparable)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != buf.Len() || got.Len != b.Len || !bytes.Equal(got.Bits, b.Bits) {
+					t.Fatalf("unexpected bitmap: %v, expected: %v", got.Bools(), b.Bools())
+				}
+
+				skipped, err := SkipBitmap(bytes.NewReader(buf.Bytes()), desc, comparable)
+				if err != nil || skipped != int64(buf.Len()) {
+					t.Fatalf("unexpected skipped size: %d, err: %v", skipped, err)
+				}
+				binaries = append(binaries, buf.Bytes())
+			}
+
+			// The comparable binaries are ordered just as the bits are.
+			if comparable {
+				for i := 1; i < len(binaries); i++ {
+					c := bytes.Compare(binaries[i-1], binaries[i])
+					if (!desc && c >= 0) || (desc && c <= 0) {
+						t.Errorf("unexpected order of %v and %v (desc: %v)", bitmaps[i-1].Bools(), bitmaps[i].Bools(), desc)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestViewBitmap(t *testing.T) {
+	b := BitmapOf(false, true, true, false, true, false, true, false, true, true)
+	for _, desc := range []bool{false, true} {
+		var buf bytes.Buffer
+		if _, err := WriteBitmap(&buf, b, desc, false); err != nil {
+			t.Fatal(err)
+		}
+		buf.WriteString("next")
+
+		v, n, err := ViewBitmap(buf.Bytes(), desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != buf.Len()-4 || v.Count() != 6 || !v.Get(8) || v.Get(0) {
+			t.Fatalf("unexpected bitmap view: %v, size: %d", v.Bools(), n)
+		}
+
+		// The ascending view shares the binary.
+		if !desc && &v.Bits[0] != &buf.Bytes()[n-len(v.Bits)] {
+			t.Error("expected bitmap view to share the binary")
+		}
+
+		if _, _, err = ViewBitmap(buf.Bytes()[:n-1], desc); err == nil {
+			t.Error("expected truncated bitmap view error")
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package bstio
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"strconv"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// BlobRefSize is the binary size of the BlobRef - the digest followed by the 64-bit size.
+const BlobRefSize = sha256.Size + 8
+
+// Tags of the external field values. The values are a part of the binary format and never change.
+const (
+	// ExternalInlineTag precedes the inlined value of the external field.
+	ExternalInlineTag byte = 0x00
+	// ExternalRefTag precedes the blob reference of the offloaded value of the external field.
+	ExternalRefTag byte = 0x01
+)
+
+// BlobRef is the locator of the blob stored out of line: the SHA-256 digest of its content along with its size.
+// The blob content determines its reference, thus the same blobs are stored only once.
+type BlobRef struct {
+	Digest [sha256.Size]byte
+	Size   uint64
+}
+
+// NewBlobRef computes the reference of the blob content.
+func NewBlobRef(data []byte) BlobRef {
+	return BlobRef{Digest: sha256.Sum256(data), Size: uint64(len(data))}
+}
+
+// Matches checks if the blob content matches the reference.
+func (x BlobRef) Matches(data []byte) bool {
+	return uint64(len(data)) == x.Size && sha256.Sum256(data) == x.Digest
+}
+
+// String returns a human-readable representation of the reference, i.e. 'sha256:<hex digest>/<size>'.
+func (x BlobRef) String() string {
+	return "sha256:" + hex.EncodeToString(x.Digest[:]) + "/" + strconv.FormatUint(x.Size, 10)
+}
+
+// WriteBlobRef writes the blob reference binary. The desc flag inverts the binary for the descending order.
+func WriteBlobRef(w io.Writer, ref BlobRef, desc bool) (int, error) {
+	buf := MarshalBlobRef(ref, desc)
+	n, err := w.Write(buf)
+	if err != nil {
+		return n, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write blob reference")
+	}
+	return n, nil
+}
+
+// MarshalBlobRef encodes the blob reference into its binary representation.
+func MarshalBlobRef(ref BlobRef, desc bool) []byte {
+	buf := make([]byte, BlobRefSize)
+	copy(buf, ref.Digest[:])
+	binary.BigEndian.PutUint64(buf[sha256.Size:], ref.Size)
+	if desc {
+		for i := range buf {
+			buf[i] = ^buf[i]
+		}
+	}
+	return buf
+}
+
+// ReadBlobRef reads the blob reference binary. The desc flag determines if the binary was written in descending order.
+func ReadBlobRef(r io.Reader, desc bool) (BlobRef, int, error) {
+	var buf [BlobRefSize]byte
+	n, err := readFull(r, buf[:], 0, "failed to read blob reference")
+	if err != nil {
+		return BlobRef{}, n, err
+	}
+	if desc {
+		for i := range buf {
+			buf[i] = ^buf[i]
+		}
+	}
+	var ref BlobRef
+	copy(ref.Digest[:], buf[:sha256.Size])
+	ref.Size = binary.BigEndian.Uint64(buf[sha256.Size:])
+	return ref, n, nil
+}
+
+// SkipBlobRef skips the blob reference binary.
+func SkipBlobRef(s io.ReadSeeker) (int64, error) {
+	_, err := s.Seek(BlobRefSize, io.SeekCurrent)
+	if err != nil {
+		return 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip blob reference")
+	}
+	return BlobRefSize, nil
+}
@@ -0,0 +1,39 @@
+package bstio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBlobRef(t *testing.T) {
+	data := []byte("large blob content")
+	ref := NewBlobRef(data)
+	if !ref.Matches(data) || ref.Matches(data[1:]) {
+		t.Fatalf("unexpected blob reference match: %s", ref)
+	}
+
+	for _, desc := range []bool{false, true} {
+		var buf bytes.Buffer
+		n, err := WriteBlobRef(&buf, ref, desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != BlobRefSize || buf.Len() != BlobRefSize {
+			t.Fatalf("unexpected blob reference binary size: %d", n)
+		}
+
+		got, n, err := ReadBlobRef(bytes.NewReader(buf.Bytes()), desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != BlobRefSize || got != ref {
+			t.Fatalf("unexpected blob reference: %s, expected: %s", got, ref)
+		}
+	}
+
+	// The truncated binary is reported as such.
+	_, _, err := ReadBlobRef(bytes.NewReader(MarshalBlobRef(ref, false)[:10]), false)
+	if err == nil {
+		t.Fatal("expected truncated blob reference error")
+	}
+}
@@ -11,7 +11,7 @@ import (
 func ReadBool(r io.Reader, desc bool) (bool, int, error) {
 	b, err := ReadByte(r)
 	if err != nil {
-		return false, 0, bsterr.Err(bsterr.CodeReadingFailed, "failed to read bool value")
+		return false, 0, readError(err, 0, 1, "failed to read bool value")
 	}
 	bv, err := ParseBool(b, desc)
 	return bv, 1, err
@@ -41,7 +41,7 @@ func ParseBool(b byte, desc bool) (bool, error) {
 func SkipBool(s io.ReadSeeker) (int64, error) {
 	n, err := s.Seek(1, io.SeekCurrent)
 	if err != nil {
-		return n, bsterr.Err(bsterr.CodeDecodingBinaryValue, "failed to skip bool value")
+		return n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip bool value")
 	}
 	return 1, nil
 }
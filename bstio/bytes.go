@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"slices"
 
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/internal/iopool"
@@ -24,13 +25,19 @@ const (
 )
 
 // Binary escapes for comparable types.
+// The escape byte within the value is written as the escape followed by the 0xFF, which is read back as the last
+// byte of the escapes, and the value is terminated by the escape followed by the 0x01.
+//
+// Format note: the array and map escape pairs used to be read back as 0x00 rather than the escape itself,
+// and the empty comparable values used to be written as 0x00 0xFF rather than terminated by their escape.
+// The comparable binaries holding any of these, written before, are not read back the same.
 var (
 	BytesEscapeAscending  = escapes{BytesEscape, 0x01, 0xFF, 0x00}
 	BytesEscapeDescending = escapes{^BytesEscape, 0xFE, 0x00, 0xFF}
-	ArrayEscapeAscending  = escapes{ArrayEscape, 0x01, 0xFF, 0x00}
-	ArrayEscapeDescending = escapes{^ArrayEscape, 0xFE, 0x00, 0xFF}
-	MapEscapeAscending    = escapes{MapEscape, 0x01, 0xFF, 0x00}
-	MapEscapeDescending   = escapes{^MapEscape, 0xFE, 0x00, 0xFF}
+	ArrayEscapeAscending  = escapes{ArrayEscape, 0x01, 0xFF, ArrayEscape}
+	ArrayEscapeDescending = escapes{^ArrayEscape, 0xFE, 0x00, ^ArrayEscape}
+	MapEscapeAscending    = escapes{MapEscape, 0x01, 0xFF, MapEscape}
+	MapEscapeDescending   = escapes{^MapEscape, 0xFE, 0x00, ^MapEscape}
 )
 
 // ReadBytes reads a slice of bytes encoded in the binary format.
@@ -40,137 +47,166 @@ var (
 // The comparable binary is escaped with the BytesEscape byte and uses BytesEscapeAscending or BytesEscapeDescending
 // depending on the desc flag.
 func ReadBytes(r io.Reader, fixedSize int, desc, comparable bool) ([]byte, int, error) {
+	return newBytes(ReadBytesAppend(nil, r, fixedSize, desc, comparable))
+}
+
+// ReadBytesAppend reads a slice of bytes encoded in the binary format, just like the ReadBytes,
+// but appends the decoded value to the dst and returns the extended slice.
+// Reusing the dst across the calls avoids the allocation of the value. On error, the dst is returned unchanged.
+func ReadBytesAppend(dst []byte, r io.Reader, fixedSize int, desc, comparable bool) ([]byte, int, error) {
 	// 1. For fixed size bytes, the amount of bytes to read is directly determined by fixed size.
 	//    No matter if the value is comparable or not it is always stored in the same way.
 	if fixedSize > 0 {
-		return ReadFixedSizeBytes(r, fixedSize, desc)
+		return ReadFixedSizeBytesAppend(dst, r, fixedSize, desc)
 	}
 
 	// 2. For varying size bytes, in non-comparable format we need to read the length
 	//    and then defined amount of bytes to read.
 	if !comparable {
-		return ReadBytesNonComparable(r, fixedSize, desc)
+		return ReadBytesNonComparableAppend(dst, r, fixedSize, desc)
 	}
 
 	// 3. For varying size bytes, in comparable format we need to provide value escapes and stop on terminator.
+	return ReadComparableBytesAppend(dst, r, desc)
+}
+
+// ReadComparableBytesAppend reads the comparable bytes escaped with the BytesEscape and appends the decoded value to the dst.
+// The desc flag indicates if the bytes are encoded in descending order. On error, the dst is returned unchanged.
+func ReadComparableBytesAppend(dst []byte, r io.Reader, desc bool) ([]byte, int, error) {
+	return readComparableBytesAppend(dst, r, desc, 64)
+}
+
+func readComparableBytesAppend(dst []byte, r io.Reader, desc bool, minSize int) ([]byte, int, error) {
 	escape := BytesEscapeAscending
 	if desc {
 		escape = BytesEscapeDescending
 	}
 	if rs, ok := r.(io.ReadSeeker); ok {
-		// 3.1. If the reader is a read seeker, it could be much faster to decode the value.
-		return ReadComparableBytesSeeker(rs, desc, 64, escape)
+		// If the reader is a read seeker, it could be much faster to decode the value.
+		return readComparableBytesSeekerAppend(dst, rs, desc, minSize, escape)
 	}
-	return ReadComparableBytesReader(r, desc, escape)
+	return readComparableBytesReaderAppend(dst, r, desc, escape)
 }
 
-// ReadComparableBytesReader reads binary data from the reader and
+// ReadComparableBytesReader reads the comparable binary data from the reader, until the escape terminator.
+// Returns the decoded value along with the number of bytes read.
 func ReadComparableBytesReader(r io.Reader, desc bool, escape escapes) ([]byte, int, error) {
-	// 1. Obtain shared buffer.
-	buf := iopool.GetBuffer(nil)
-	defer iopool.ReleaseBuffer(buf)
+	return newBytes(readComparableBytesReaderAppend(nil, r, desc, escape))
+}
 
+func readComparableBytesReaderAppend(dst []byte, r io.Reader, desc bool, escape escapes) ([]byte, int, error) {
 	var bytesRead int
-	// 2. Iterate byte by byte over the reader until we reach the escape terminator.
+	start := len(dst)
+	// 1. Iterate byte by byte over the reader until we reach the escape terminator.
 	for {
-		// 2.1. Read the next byte.
+		// 1.1. Read the next byte.
 		b, err := ReadByte(r)
 		if err != nil {
-			return nil, bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read string value")
+			return dst[:start], bytesRead, readError(err, bytesRead, bytesRead+2, "failed to read bytes value")
 		}
 		bytesRead++
 
-		if err = buf.WriteByte(b); err != nil {
-			return nil, 0, err
-		}
-
-		// 2.2. If the byte is not the escape, continue.
+		// 1.2. If the byte is not the escape, append it and continue.
 		if b != escape.escape {
+			dst = append(dst, b)
 			continue
 		}
 
-		// 2.3. Read the next byte.
+		// 1.3. Read the next byte.
 		b, err = ReadByte(r)
 		if err != nil {
-			return nil, bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "malformed string binary value")
+			return dst[:start], bytesRead, readError(err, bytesRead, bytesRead+1, "malformed bytes binary value")
 		}
 		bytesRead++
 
-		// 2.4. Check if the next byte is a terminator byte, so that we can stop iterating.
+		// 1.4. Check if the next byte is a terminator byte, so that we can stop iterating.
 		if b == escape.escapedTerm {
 			break
 		}
 
-		// 2.5. If the next byte is not the escape, check consistency.
+		// 1.5. If the next byte is not the escape, check consistency.
 		if b != escape.escaped00 {
-			return nil, bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "malformed string binary value")
+			return dst[:start], bytesRead, bsterr.Err(bsterr.CodeDecodingBinaryValue, "malformed bytes binary value")
 		}
 
-		// 2.6. Write escaped byte and continue iteration.
-		if err = buf.WriteByte(escape.escapedFF); err != nil {
-			return nil, bytesRead, err
-		}
+		// 1.6. Append escaped byte and continue iteration.
+		dst = append(dst, escape.escapedFF)
 	}
 
+	// 2. If the value is encoded in descending order, ReverseBytes the appended bytes.
 	if desc {
-		ReverseBytes(buf.Bytes)
+		ReverseBytes(dst[start:])
 	}
-	return buf.Bytes, len(buf.Bytes), nil
+	return dst, bytesRead, nil
 }
 
 // ReadFixedSizeBytes reads a fixed size slice of bytes encoded in the binary format.
 // The desc flag indicates if the bytes are encoded in descending order.
 func ReadFixedSizeBytes(r io.Reader, fixedSize int, desc bool) ([]byte, int, error) {
-	// 1. Allocate bytes data.
-	bl := make([]byte, fixedSize)
+	return newBytes(ReadFixedSizeBytesAppend(nil, r, fixedSize, desc))
+}
+
+// ReadFixedSizeBytesAppend reads a fixed size slice of bytes and appends it to the dst.
+// The desc flag indicates if the bytes are encoded in descending order. On error, the dst is returned unchanged.
+func ReadFixedSizeBytesAppend(dst []byte, r io.Reader, fixedSize int, desc bool) ([]byte, int, error) {
+	// 1. Extend the dst by the fixed size.
+	start := len(dst)
+	dst = growBytes(dst, fixedSize)
 
 	// 2. Read the content from the reader.
-	n, err := r.Read(bl)
+	n, err := readFull(r, dst[start:], 0, "failed to read fixed size bytes value")
 	if err != nil {
-		return nil, 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read fixed size bytes value")
+		return dst[:start], n, err
 	}
 
 	// 3. For descending order, ReverseBytes the bytes.
 	if desc {
-		ReverseBytes(bl)
+		ReverseBytes(dst[start:])
 	}
-	return bl, n, nil
+	return dst, n, nil
 }
 
 // ReadBytesNonComparable reads a slice of bytes encoded in the binary format.
 // If the fixed size is different from 0, the binary data has a fixed number of bytes.
 // The desc flag indicates if the bytes are encoded in descending order.
 func ReadBytesNonComparable(r io.Reader, fixedSize int, desc bool) ([]byte, int, error) {
+	return newBytes(ReadBytesNonComparableAppend(nil, r, fixedSize, desc))
+}
+
+// ReadBytesNonComparableAppend reads a non-comparable slice of bytes and appends it to the dst.
+// If the fixed size is different from 0, the binary data has a fixed number of bytes.
+// The desc flag indicates if the bytes are encoded in descending order. On error, the dst is returned unchanged.
+func ReadBytesNonComparableAppend(dst []byte, r io.Reader, fixedSize int, desc bool) ([]byte, int, error) {
 	// 1. Read the length of the byte slice.
-	var total int64
+	var total int
 	length := fixedSize
 	if length == 0 {
-		luv, n, err := ReadUint(r, desc)
+		luv, n, err := ReadLength(r, desc, 1)
 		if err != nil {
-			return []byte{}, int(n), err
+			return dst, n, err
 		}
-		total += int64(n)
+		total += n
 		length = int(luv)
 	}
 	if length == 0 {
-		return []byte{}, int(total), nil
+		return dst, total, nil
 	}
 
 	// 2. Read the byte slice.
-	bl := make([]byte, length)
-	n, err := r.Read(bl)
+	start := len(dst)
+	dst = growBytes(dst, length)
+	n, err := readFull(r, dst[start:], total, "malformed bytes value binary input")
 	if err != nil {
-		return nil, int(total), bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "malformed bytes value binary input")
+		return dst[:start], total + n, err
 	}
-	n += int(total)
 
 	// 3. If the value is encoded in descending order, ReverseBytes the bytes.
 	if desc {
-		ReverseBytes(bl)
+		ReverseBytes(dst[start:])
 	}
 
-	// 4. Return the byte slice.
-	return bl, n, nil
+	// 4. Return the extended slice.
+	return dst, total + n, nil
 }
 
 // ReadComparableBytesSeeker reads binary data from the seeker and returns the decoded value.
@@ -178,105 +214,38 @@ func ReadBytesNonComparable(r io.Reader, fixedSize int, desc bool) ([]byte, int,
 // The minSize is the minimum size of the value.
 // Escapes are used to escape the value.
 func ReadComparableBytesSeeker(rs io.ReadSeeker, desc bool, minSize int, escape escapes) ([]byte, int, error) {
-	// 1. Save current position of the read seeker so that we may know where we need to stop.
-	curPos, err := rs.Seek(0, io.SeekCurrent)
+	return newBytes(readComparableBytesSeekerAppend(nil, rs, desc, minSize, escape))
+}
+
+func readComparableBytesSeekerAppend(dst []byte, rs io.ReadSeeker, desc bool, minSize int, escape escapes) ([]byte, int, error) {
+	start := len(dst)
+	dst, n, err := scanComparableBytes(dst, rs, minSize, escape, true)
 	if err != nil {
-		return nil, 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "seeking through read seeker failed")
+		return dst, n, err
 	}
 
-	// 2. Prepare a buffer to read the string into.
-	var (
-		n, escapePosition int64
-		nn                int
-	)
-	// 3. Start with the mi	nimum size allocation slice.
-	nMax := int64(minSize)
-	buf := make([]byte, nMax)
-
-	var isEOF, foundTerminator bool
-	var r []byte
-
-	// 4. Fill the buffer until we reach the escape bytes.
-	for !isEOF {
-		// 4.1. If the buffer is full, double the size.
-		if n == nMax {
-			nMax *= 2
-			bNew := make([]byte, nMax)
-			copy(bNew, buf)
-			buf = bNew
-		}
-
-		// 4.2. Read the next bytes in the buffer.
-		nn, err = rs.Read(buf[n:])
-		if err != nil {
-			if !errors.Is(err, io.EOF) {
-				return nil, int(n), bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "malformed binary value")
-			}
-			// 4.2.1. If we reached the end of the file, mark it so that no more bytes are read.
-			isEOF = true
-		}
-
-		eIdx := int(n)
-		// 5. Search for the escape byte in current buffer.
-		for eIdx <= int(n)+nn {
-			idx := bytes.IndexByte(buf[eIdx:], escape.escape)
-			if idx == -1 {
-				break
-			}
-			idx += eIdx
-
-			// 6. If the escape character was found in the last byte of current index, we need to read more bytes to the buffer.
-			if idx == len(buf)-1 {
-				break
-			}
-
-			// 7. Check if the next byte in the buffer is the escaped term.
-			if buf[idx+1] == escape.escapedTerm {
-				if r == nil {
-					r = buf[:idx]
-				} else {
-					r = append(r, buf[eIdx:idx]...)
-				}
-				foundTerminator = true
-				break
-			}
-
-			// 8. If the next byte in the buffer is not the escaped term, the next byte should be a 0x00 byte.
-			if buf[idx+1] != escape.escaped00 {
-				return nil, int(n), bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "malformed bytes value")
-			}
-
-			// 9. If the escape00 was found, replace it with the escapeFF byte.
-			r = append(r, buf[eIdx:idx]...)
-			r = append(r, escape.escapedFF)
-			eIdx = idx + 2
-		}
-		n += int64(nn)
-		escapePosition += int64(nn)
-
-		if foundTerminator {
-			break
-		}
+	// 1. If the value is encoded in descending order, ReverseBytes the appended bytes.
+	if desc {
+		ReverseBytes(dst[start:])
 	}
+	return dst, n, nil
+}
 
-	// 10. Check if the escape term was found.
-	if !foundTerminator {
-		return nil, int(n), bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "malformed bytes value")
+// newBytes returns the result of the append read function with a newly allocated value.
+// The empty value is returned as non-nil slice, whereas the value is nil on error.
+func newBytes(v []byte, n int, err error) ([]byte, int, error) {
+	if err != nil {
+		return nil, n, err
 	}
-
-	// 11. Set the position of the read seeker to the position of the escape term.
-	nextPos := curPos + escapePosition + 1
-	if _, err = rs.Seek(nextPos, io.SeekStart); err != nil {
-		if !errors.Is(err, io.EOF) {
-			return nil, int(n), bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read string value")
-		}
+	if v == nil {
+		v = []byte{}
 	}
+	return v, n, nil
+}
 
-	// 12. If the value is encoded in descending order, ReverseBytes the bytes.
-	if desc {
-		ReverseBytes(r)
-	}
-	return r, int(n), nil
+// growBytes extends the length of the dst by n bytes, reallocating it only if its capacity is not sufficient.
+func growBytes(dst []byte, n int) []byte {
+	return slices.Grow(dst, n)[:len(dst)+n]
 }
 
 // BytesBinarySize returns the size of the bytes in binary format.
@@ -305,7 +274,7 @@ func BytesComparableBinarySize(bin []byte, es escapes) uint {
 		if i == -1 {
 			break
 		}
-		lastIndex = i + 1
+		lastIndex += i + 1
 		escapeCount++
 	}
 	return uint(len(bin) + escapeCount + 2)
@@ -317,42 +286,25 @@ func BytesComparableBinarySize(bin []byte, es escapes) uint {
 // Comparable flag is used to determine if the bytes are encoded in comparable mode.
 // NOTE: Input data could be malformed during encoding.
 func WriteBytes(w io.Writer, fixedSize int, v []byte, desc, comparable bool) (int, error) {
-	if fixedSize > 0 || !comparable {
-		return writeBytesNonComparable(w, fixedSize, v, desc)
-	}
-	return writeBytesInternalComparable(w, v, BytesEscape, desc)
+	return WriteBytesAlloc(w, fixedSize, v, desc, comparable, nil)
 }
 
-func writeBytesInternalComparable(w io.Writer, data []byte, eb byte, desc bool) (int, error) {
-	// 1. Check if the value is empty.
-	if len(data) == 0 {
-		return WriteEmptyComparableBytes(w, desc)
-	}
-
-	var b []byte
-
-	// 2. Iterate over the byte slice and check if there is anything to escape.
-	for {
-		i := bytes.IndexByte(data, eb)
-		if i == -1 {
-			break
-		}
-
-		b = append(b, data[:i]...)
-		b = append(b, eb, 0xff)
-		data = data[i+1:]
+// WriteBytesAlloc encodes and writes input bytes just as the WriteBytes, taking its scratch byte slices
+// out of the allocator. The nil allocator allocates them on the heap.
+func WriteBytesAlloc(w io.Writer, fixedSize int, v []byte, desc, comparable bool, a Allocator) (int, error) {
+	if fixedSize > 0 || !comparable {
+		return writeBytesNonComparable(w, fixedSize, v, desc, a)
 	}
+	return writeBytesInternalComparable(w, v, BytesEscape, desc, a)
+}
 
-	data = append(b, data...)
-	data = append(data, eb, 0x01)
+func writeBytesInternalComparable(w io.Writer, data []byte, eb byte, desc bool, a Allocator) (int, error) {
+	// 1. Escape the data within the scratch slice, which fits the data with its terminator if there is nothing to escape.
+	b := appendBytesInternalComparable(allocBytes(a, len(data)+2), data, eb, desc)
+	defer freeBytes(a, b)
 
-	// 4. If the value is encoded in descending order, ReverseBytes the bytes.
-	if desc {
-		ReverseBytes(data)
-	}
-
-	// 5. Write the binary data.
-	n, err := w.Write(data)
+	// 2. Write the binary data.
+	n, err := w.Write(b)
 	if err != nil {
 		return n, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write bytes value")
 	}
@@ -363,44 +315,16 @@ func writeBytesInternalComparable(w io.Writer, data []byte, eb byte, desc bool)
 // WriteBufferedBytesInternalComparable writes the bytes in a binary format to the input writer.
 // The bytes are encoded in comparable mode, taken out of the shared buffer.
 func WriteBufferedBytesInternalComparable(w io.Writer, sb *iopool.SharedBuffer, eb byte, desc bool) (int, error) {
-	// 1. Check if the value is empty.
-	if len(sb.Bytes) == 0 {
-		return WriteEmptyComparableBytes(w, desc)
-	}
-
-	var b []byte
-	data := sb.Bytes
-
-	// 2. Iterate over the byte slice and check if there is anything to escape.
-	for {
-		i := bytes.IndexByte(data, eb)
-		if i == -1 {
-			break
-		}
-
-		b = append(b, data[:i]...)
-		b = append(b, eb, 0xff)
-		data = data[i+1:]
-	}
-
-	data = append(b, data...)
-	data = append(data, eb, 0x01)
-
-	// 4. If the value is encoded in descending order, ReverseBytes the bytes.
-	if desc {
-		ReverseBytes(data)
-	}
-
-	// 5. Write the binary data.
-	n, err := w.Write(data)
-	if err != nil {
-		return n, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write bytes value")
-	}
+	return writeBytesInternalComparable(w, sb.Bytes, eb, desc, nil)
+}
 
-	return n, nil
+// WriteBufferedBytesInternalComparableAlloc writes the bytes just as the WriteBufferedBytesInternalComparable,
+// taking its scratch byte slice out of the allocator.
+func WriteBufferedBytesInternalComparableAlloc(w io.Writer, sb *iopool.SharedBuffer, eb byte, desc bool, a Allocator) (int, error) {
+	return writeBytesInternalComparable(w, sb.Bytes, eb, desc, a)
 }
 
-func writeBytesNonComparable(w io.Writer, fixedSize int, v []byte, desc bool) (int, error) {
+func writeBytesNonComparable(w io.Writer, fixedSize int, v []byte, desc bool, a Allocator) (int, error) {
 	var bytesWritten int
 
 	// 1. Non-fixed size bytes require to store the length of the data.
@@ -414,11 +338,10 @@ func writeBytesNonComparable(w io.Writer, fixedSize int, v []byte, desc bool) (i
 
 	// 2. Write the binary data.
 	if v != nil {
-		// 3. For descending order, ReverseBytes the bytes.
+		// 3. For descending order, ReverseBytes the copy of the bytes.
 		if desc {
-			cp := make([]byte, len(v))
-			copy(cp, v)
-			v = cp
+			v = append(allocBytes(a, len(v)), v...)
+			defer freeBytes(a, v)
 			ReverseBytes(v)
 		}
 
@@ -472,89 +395,92 @@ func SkipBytes(rs io.ReadSeeker, fixedSize int, descending, comparable bool) (in
 // SkipComparableBytes skips the binary encoded bytes from the input read seeker in comparable mode.
 // The min size determines a size of a buffer to read the data, and the escape byte is used to determine if the data is escaped.
 func SkipComparableBytes(rs io.ReadSeeker, minSize int, escape escapes) (int64, error) {
+	_, n, err := scanComparableBytes(nil, rs, minSize, escape, false)
+	return int64(n), err
+}
+
+// scanComparableBytes scans the comparable binary of the read seeker chunk by chunk, until the escape terminator.
+// The read seeker is positioned just after the terminator, and the number of the value bytes (including the terminator)
+// is returned. If the collect flag is set, the unescaped value bytes are appended to the dst.
+// The chunks are read into the spare capacity of the dst and unescaped in place, thus no buffer is allocated
+// if the dst has enough capacity. On error, the dst is returned unchanged.
+func scanComparableBytes(dst []byte, rs io.ReadSeeker, minSize int, escape escapes, collect bool) ([]byte, int, error) {
 	// 1. Save current position of the read seeker so that we may know where we need to stop.
 	curPos, err := rs.Seek(0, io.SeekCurrent)
 	if err != nil {
-		return 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "seeking through read seeker failed")
+		return dst, 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "seeking through read seeker failed")
 	}
 
-	// 2. Prepare a buffer to read the string into.
 	var (
-		n, escapePosition int64
-		nn                int
+		n       int
+		escaped bool
 	)
-	// 3. Start with the minimum size allocation slice.
-	nMax := int64(minSize)
-	buf := make([]byte, nMax)
-	var isEOF, foundTerminator bool
-
-	// 4. Fill the buffer until we reach the escape bytes.
-	for !isEOF {
-		// 4.1. If the buffer is full, double the size.
-		if n == nMax {
-			nMax *= 2
-			bNew := make([]byte, nMax)
-			copy(bNew, buf)
-			buf = bNew
-		}
-
-		// 4.2. Read the next bytes in the buffer.
-		nn, err = rs.Read(buf[n:])
-		if err != nil {
-			if !errors.Is(err, io.EOF) {
-				return n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "malformed binary value")
+	start := len(dst)
+	chunkSize := max(minSize, 2)
+	for {
+		// 2. Read the next chunk of the binary just after the unescaped bytes.
+		//    The unescaped bytes are never longer than the escaped ones, thus appending them overwrites
+		//    only the already scanned part of the chunk.
+		dst = slices.Grow(dst, chunkSize)
+		nn, rErr := rs.Read(dst[len(dst) : len(dst)+chunkSize])
+		chunk := dst[len(dst) : len(dst)+nn]
+
+		// 3. Scan the chunk for the escapes.
+		for len(chunk) > 0 {
+			// 3.1. The byte following the escape is either the terminator or the escaped one.
+			if escaped {
+				escaped = false
+				n++
+				switch chunk[0] {
+				case escape.escapedTerm:
+					// 3.1.1. Set the position of the read seeker just after the terminator.
+					if _, err = rs.Seek(curPos+int64(n), io.SeekStart); err != nil {
+						return dst[:start], n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "seeking through read seeker failed")
+					}
+					return dst, n, nil
+				case escape.escaped00:
+					if collect {
+						dst = append(dst, escape.escapedFF)
+					}
+					chunk = chunk[1:]
+					continue
+				default:
+					return dst[:start], n, bsterr.Err(bsterr.CodeDecodingBinaryValue, "malformed bytes value")
+				}
 			}
-			// 4.2.1. If we reached the end of the file, mark it so that no more bytes are read.
-			isEOF = true
-		}
 
-		eIdx := int(n)
-		// 5. Search for the escape byte in current buffer.
-		for eIdx <= int(n)+nn {
-			idx := bytes.IndexByte(buf[eIdx:], escape.escape)
+			// 3.2. Find the next escape within the chunk.
+			idx := bytes.IndexByte(chunk, escape.escape)
 			if idx == -1 {
+				if collect {
+					dst = append(dst, chunk...)
+				}
+				n += len(chunk)
 				break
 			}
-			idx += eIdx
-
-			// 6. If the escape character was found in the last byte of current index, we need to read more bytes to the buffer.
-			if idx == len(buf)-1 {
-				break
+			if collect {
+				dst = append(dst, chunk[:idx]...)
 			}
+			n += idx + 1
+			escaped = true
+			chunk = chunk[idx+1:]
+		}
 
-			// 7. Check if the next byte in the buffer is the escaped term.
-			if buf[idx+1] == escape.escapedTerm {
-				foundTerminator = true
-				break
+		// 4. The input ended before the terminator.
+		if rErr != nil {
+			if !errors.Is(rErr, io.EOF) {
+				return dst[:start], n, bsterr.ErrWrap(rErr, bsterr.CodeDecodingBinaryValue, "malformed binary value")
 			}
-
-			// 8. If the next byte in the buffer is not the escaped term, the next byte should be a 0x00 byte.
-			if buf[idx+1] != escape.escaped00 {
-				return n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "malformed bytes value")
+			expected := n + 2
+			if escaped {
+				expected = n + 1
 			}
-
-			// 9. If the escape00 was found, replace it with the escapeFF byte.
-			eIdx = idx + 2
+			return dst[:start], n, readError(io.EOF, n, expected, "malformed bytes value")
 		}
-		n += int64(nn)
-		escapePosition += int64(nn)
 
-		if foundTerminator {
-			break
+		// 5. Read larger chunks for the longer values.
+		if nn == chunkSize && chunkSize < 4096 {
+			chunkSize *= 2
 		}
 	}
-
-	// 10. Check if the escape term was found.
-	if !foundTerminator {
-		return n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "malformed string value")
-	}
-
-	// 11. Set the position of the read seeker to the position of the escape term.
-	nextPos := curPos + escapePosition + 1
-	if _, err = rs.Seek(nextPos, io.SeekStart); err != nil {
-		if !errors.Is(err, io.EOF) {
-			return n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read string value")
-		}
-	}
-	return n, nil
 }
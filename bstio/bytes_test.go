@@ -0,0 +1,53 @@
+package bstio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadBytesAppend(t *testing.T) {
+	testCases := []struct {
+		Name                   string
+		FixedSize              int
+		Descending, Comparable bool
+		Value                  []byte
+	}{
+		{Name: "Fixed", FixedSize: 3, Value: []byte{0x00, 0x01, 0xFF}},
+		{Name: "Fixed/Desc", FixedSize: 3, Descending: true, Value: []byte{0x00, 0x01, 0xFF}},
+		{Name: "NonComparable", Value: []byte{0x00, 0x01, 0xFF}},
+		{Name: "NonComparable/Empty", Value: []byte{}},
+		{Name: "Comparable", Comparable: true, Value: []byte{0x00, 0x01, 0xFF}},
+		{Name: "Comparable/Desc", Comparable: true, Descending: true, Value: []byte{0xFF, 0x00, 0x00}},
+		{Name: "Comparable/Empty", Comparable: true, Value: []byte{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if _, err := WriteBytes(&buf, tc.FixedSize, tc.Value, tc.Descending, tc.Comparable); err != nil {
+				t.Fatal(err)
+			}
+
+			// 1. The value is appended after the dst content.
+			prefix := []byte{0xAA, 0xBB}
+			dst, n, err := ReadBytesAppend(prefix, bytes.NewReader(buf.Bytes()), tc.FixedSize, tc.Descending, tc.Comparable)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if n != buf.Len() {
+				t.Fatalf("unexpected number of bytes read: %d, expected: %d", n, buf.Len())
+			}
+			if !bytes.Equal(dst, append([]byte{0xAA, 0xBB}, tc.Value...)) {
+				t.Fatalf("unexpected value: %x", dst)
+			}
+
+			// 2. The allocating variant returns the same value.
+			v, _, err := ReadBytes(bytes.NewReader(buf.Bytes()), tc.FixedSize, tc.Descending, tc.Comparable)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v == nil || !bytes.Equal(v, tc.Value) {
+				t.Fatalf("unexpected value: %x", v)
+			}
+		})
+	}
+}
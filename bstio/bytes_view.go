@@ -0,0 +1,118 @@
+package bstio
+
+import (
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+var (
+	_ io.ReadSeeker  = (*BytesReader)(nil)
+	_ io.ByteReader  = (*BytesReader)(nil)
+	_ RemainingSizer = (*BytesReader)(nil)
+)
+
+// BytesReader is the in-memory read seeker of the byte slice, which exposes its input. Unlike the bytes.Reader,
+// the values read with the ReadBytesView out of it are the views of the slice rather than its copies.
+type BytesReader struct {
+	b   []byte
+	pos int64
+}
+
+// NewBytesReader creates the reader of the byte slice, which is referenced rather than copied.
+func NewBytesReader(b []byte) *BytesReader {
+	return &BytesReader{b: b}
+}
+
+// Read implements the io.Reader interface.
+func (x *BytesReader) Read(p []byte) (int, error) {
+	if x.pos >= int64(len(x.b)) {
+		return 0, io.EOF
+	}
+	n := copy(p, x.b[x.pos:])
+	x.pos += int64(n)
+	return n, nil
+}
+
+// ReadByte implements the io.ByteReader interface.
+func (x *BytesReader) ReadByte() (byte, error) {
+	if x.pos >= int64(len(x.b)) {
+		return 0, io.EOF
+	}
+	b := x.b[x.pos]
+	x.pos++
+	return b, nil
+}
+
+// Seek implements the io.Seeker interface. Seeking beyond the end of the slice fails with the io.EOF,
+// just as the extractor readers do.
+func (x *BytesReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = x.pos + offset
+	case io.SeekEnd:
+		pos = int64(len(x.b)) + offset
+	default:
+		return 0, bsterr.Err(bsterr.CodeReadingFailed, "invalid bytes reader seek whence").WithDetail("whence", whence)
+	}
+	if pos < 0 {
+		return 0, bsterr.Err(bsterr.CodeReadingFailed, "negative bytes reader position").WithDetail("offset", pos)
+	}
+	x.pos = pos
+	if pos > int64(len(x.b)) {
+		return pos, io.EOF
+	}
+	return pos, nil
+}
+
+// RemainingSize returns the number of the unread bytes of the slice.
+// Implements RemainingSizer interface.
+func (x *BytesReader) RemainingSize() (int64, bool) {
+	return max(int64(len(x.b))-x.pos, 0), true
+}
+
+// Next returns the view of the next n bytes of the slice, and advances the reader past them.
+// If less than n bytes remain, it returns false and the reader is not advanced.
+func (x *BytesReader) Next(n int) ([]byte, bool) {
+	if n < 0 || x.pos+int64(n) > int64(len(x.b)) {
+		return nil, false
+	}
+	b := x.b[x.pos : x.pos+int64(n) : x.pos+int64(n)]
+	x.pos += int64(n)
+	return b, true
+}
+
+// ReadBytesView reads the non-comparable bytes value encoded in ascending order, just like the ReadBytes.
+// If the reader exposes its input, i.e. the BytesReader, the value is the view of the input rather than its copy,
+// thus it must not be modified, and it is valid as long as the input is. The view is capped at the value end,
+// thus appending to it never overwrites the following input.
+func ReadBytesView(r io.Reader, fixedSize int) ([]byte, int, error) {
+	// 1. Read the length of the value.
+	var total int
+	length := fixedSize
+	if length == 0 {
+		luv, n, err := ReadLength(r, false, 1)
+		if err != nil {
+			return nil, n, err
+		}
+		total += n
+		length = int(luv)
+	}
+	if length == 0 {
+		return []byte{}, total, nil
+	}
+
+	// 2. Return the view of the input, or read the copy of the value if the input is not exposed.
+	if b, ok := fixedBytes(r, length); ok {
+		return b, total + length, nil
+	}
+	v := make([]byte, length)
+	n, err := readFull(r, v, total, "malformed bytes value binary input")
+	if err != nil {
+		return nil, total + n, err
+	}
+	return v, total + n, nil
+}
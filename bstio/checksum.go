@@ -0,0 +1,192 @@
+package bstio
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/crc32"
+	"math/bits"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// ChecksumKind is the algorithm of the value checksum.
+type ChecksumKind uint8
+
+// Enumerated checksum kinds. The values are a part of the value header and never change.
+const (
+	// ChecksumNone means no checksum.
+	ChecksumNone ChecksumKind = 0
+	// ChecksumCRC32C is the CRC-32 with the Castagnoli polynomial, of 4 bytes.
+	ChecksumCRC32C ChecksumKind = 1
+	// ChecksumXXHash64 is the xxHash64 with the zero seed, of 8 bytes.
+	ChecksumXXHash64 ChecksumKind = 2
+)
+
+// String returns the name of the checksum kind.
+func (k ChecksumKind) String() string {
+	switch k {
+	case ChecksumNone:
+		return "none"
+	case ChecksumCRC32C:
+		return "crc32c"
+	case ChecksumXXHash64:
+		return "xxhash64"
+	default:
+		return "unknown"
+	}
+}
+
+// Size returns the binary size of the checksum of given kind.
+func (k ChecksumKind) Size() int {
+	switch k {
+	case ChecksumCRC32C:
+		return crc32.Size
+	case ChecksumXXHash64:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// New creates the hash computing the checksum of given kind. The hash sum is big-endian.
+func (k ChecksumKind) New() (hash.Hash, error) {
+	switch k {
+	case ChecksumCRC32C:
+		return crc32.New(_castagnoli), nil
+	case ChecksumXXHash64:
+		return NewXXHash64(), nil
+	default:
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "unknown checksum kind").WithDetail("kind", uint8(k))
+	}
+}
+
+var _castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// xxHash64 primes.
+const (
+	_xxPrime1 uint64 = 11400714785074694791
+	_xxPrime2 uint64 = 14029467366897019727
+	_xxPrime3 uint64 = 1609587929392839161
+	_xxPrime4 uint64 = 9650029242287828579
+	_xxPrime5 uint64 = 2870177450012600261
+)
+
+// xxHash64 is the streaming xxHash64 of the zero seed.
+type xxHash64 struct {
+	v1, v2, v3, v4 uint64
+	total          uint64
+	mem            [32]byte
+	n              int
+}
+
+// NewXXHash64 creates the hash computing the xxHash64 with the zero seed.
+func NewXXHash64() hash.Hash64 {
+	h := &xxHash64{}
+	h.Reset()
+	return h
+}
+
+// Reset resets the hash to its initial state.
+func (x *xxHash64) Reset() {
+	// The initial accumulators wrap around, thus the prime is not a constant.
+	p1 := _xxPrime1
+	*x = xxHash64{v1: p1 + _xxPrime2, v2: _xxPrime2, v4: -p1}
+}
+
+// Size returns the number of bytes of the hash sum.
+func (x *xxHash64) Size() int {
+	return 8
+}
+
+// BlockSize returns the block size of the hash.
+func (x *xxHash64) BlockSize() int {
+	return 32
+}
+
+// Write adds the bytes to the hash. It never fails.
+func (x *xxHash64) Write(p []byte) (int, error) {
+	n := len(p)
+	x.total += uint64(n)
+
+	// 1. Fill up the buffered block first.
+	if x.n > 0 {
+		c := copy(x.mem[x.n:], p)
+		x.n += c
+		p = p[c:]
+		if x.n < len(x.mem) {
+			return n, nil
+		}
+		x.block(x.mem[:])
+		x.n = 0
+	}
+
+	// 2. Consume the full blocks, and buffer the rest.
+	for ; len(p) >= len(x.mem); p = p[len(x.mem):] {
+		x.block(p)
+	}
+	x.n = copy(x.mem[:], p)
+	return n, nil
+}
+
+// block consumes the 32 byte block.
+func (x *xxHash64) block(b []byte) {
+	x.v1 = xxRound(x.v1, binary.LittleEndian.Uint64(b[0:8]))
+	x.v2 = xxRound(x.v2, binary.LittleEndian.Uint64(b[8:16]))
+	x.v3 = xxRound(x.v3, binary.LittleEndian.Uint64(b[16:24]))
+	x.v4 = xxRound(x.v4, binary.LittleEndian.Uint64(b[24:32]))
+}
+
+// Sum64 returns the hash sum.
+func (x *xxHash64) Sum64() uint64 {
+	// 1. Merge the accumulators, if any block was consumed.
+	var h uint64
+	if x.total >= uint64(len(x.mem)) {
+		h = bits.RotateLeft64(x.v1, 1) + bits.RotateLeft64(x.v2, 7) + bits.RotateLeft64(x.v3, 12) + bits.RotateLeft64(x.v4, 18)
+		h = xxMergeRound(h, x.v1)
+		h = xxMergeRound(h, x.v2)
+		h = xxMergeRound(h, x.v3)
+		h = xxMergeRound(h, x.v4)
+	} else {
+		h = _xxPrime5
+	}
+	h += x.total
+
+	// 2. Consume the buffered bytes.
+	p := x.mem[:x.n]
+	for ; len(p) >= 8; p = p[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(p))
+		h = bits.RotateLeft64(h, 27)*_xxPrime1 + _xxPrime4
+	}
+	if len(p) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(p)) * _xxPrime1
+		h = bits.RotateLeft64(h, 23)*_xxPrime2 + _xxPrime3
+		p = p[4:]
+	}
+	for _, b := range p {
+		h ^= uint64(b) * _xxPrime5
+		h = bits.RotateLeft64(h, 11) * _xxPrime1
+	}
+
+	// 3. Avalanche the bits.
+	h ^= h >> 33
+	h *= _xxPrime2
+	h ^= h >> 29
+	h *= _xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+// Sum appends the big-endian hash sum to b.
+func (x *xxHash64) Sum(b []byte) []byte {
+	return binary.BigEndian.AppendUint64(b, x.Sum64())
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * _xxPrime2
+	return bits.RotateLeft64(acc, 31) * _xxPrime1
+}
+
+func xxMergeRound(acc, v uint64) uint64 {
+	acc ^= xxRound(0, v)
+	return acc*_xxPrime1 + _xxPrime4
+}
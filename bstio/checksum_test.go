@@ -0,0 +1,55 @@
+package bstio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+func TestChecksumKind(t *testing.T) {
+	// 1. The xxHash64 matches the reference sums, also when written in parts.
+	long := []byte("Nobody inspects the spammish repetition")
+	for _, tc := range []struct {
+		in  []byte
+		sum uint64
+	}{
+		{in: nil, sum: 0xef46db3751d8e999},
+		{in: []byte("a"), sum: 0xd24ec4f1a98c6e5b},
+		{in: []byte("abc"), sum: 0x44bc2cf5ad770999},
+		{in: long, sum: 0xfbcea83c8a378bf1},
+	} {
+		h := NewXXHash64()
+		_, _ = h.Write(tc.in)
+		if h.Sum64() != tc.sum {
+			t.Fatalf("unexpected xxhash64 of %q: %x, expected: %x", tc.in, h.Sum64(), tc.sum)
+		}
+		h.Reset()
+		for i := range tc.in {
+			_, _ = h.Write(tc.in[i : i+1])
+		}
+		if h.Sum64() != tc.sum {
+			t.Fatalf("unexpected xxhash64 of %q written bytewise: %x", tc.in, h.Sum64())
+		}
+	}
+
+	// 2. The checksums are big-endian sums of their sizes.
+	data := bytes.Repeat(long, 10)
+	for _, k := range []ChecksumKind{ChecksumCRC32C, ChecksumXXHash64} {
+		h, err := k.New()
+		if err != nil {
+			t.Fatalf("creating %s hash failed: %v", k, err)
+		}
+		_, _ = h.Write(data)
+		sum := h.Sum(nil)
+		if len(sum) != k.Size() {
+			t.Fatalf("unexpected %s size: %d", k, len(sum))
+		}
+		if k == ChecksumCRC32C && binary.BigEndian.Uint32(sum) != crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)) {
+			t.Fatalf("unexpected crc32c: %x", sum)
+		}
+	}
+	if _, err := ChecksumKind(7).New(); err == nil {
+		t.Fatal("expected unknown checksum kind error")
+	}
+}
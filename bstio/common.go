@@ -4,7 +4,11 @@ import (
 	"io"
 )
 
-// Constant binary values.
+// Binary size constants. The values are a part of the binary format and never change.
+//
+// The BinarySize* values are the number of bytes of the fixed width values, i.e. the enum and oneof indexes.
+// The same values are also valid headers of the varying size unsigned integers, which however
+// might use any number of bytes up to the MaxSizeHeader - see the EncodeSizeHeader.
 const (
 	BinarySizeZero   uint8 = 0x00
 	BinarySizeUint8  uint8 = 0x01
@@ -12,9 +16,21 @@ const (
 	BinarySizeUint32 uint8 = 0x04
 	BinarySizeUint64 uint8 = 0x08
 
+	// MaxSizeHeader is the maximum (ascending) header of the varying size unsigned integer.
+	MaxSizeHeader = BinarySizeUint64
+)
+
+// Sign masks of the first byte of the comparable signed values. The values are a part of the binary format and never change.
+//
+// The comparable signed integers and floats have the sign bit of their first byte flipped,
+// so that the negative values are ordered before the positive ones.
+const (
+	// BinaryPositiveZero is the first byte of the comparable signed zero.
 	BinaryPositiveZero = 0x00 | PositiveBit8Mask
 
+	// NegativeBit8Mask (0x7F) clears the sign bit of the first byte of a negative value.
 	NegativeBit8Mask = uint8(2<<6 - 1)
+	// PositiveBit8Mask (0x80) sets the sign bit of the first byte of a positive value.
 	PositiveBit8Mask = uint8(1 << 7)
 )
 
@@ -33,6 +49,13 @@ type ValueOptions struct {
 	// CompatibilityMode determines that the value binary is compatible with the old
 	// encoding format.
 	CompatibilityMode bool
+	// LengthPrefixed determines that the variable size array and map elements of the arrays and maps
+	// are prefixed with their binary size, so that they could be skipped without parsing.
+	// It applies only in the compatibility mode, for non-comparable binaries.
+	LengthPrefixed bool
+	// OrderedFloats determines that the float values are encoded in the ordered format, whose binaries
+	// of the negative values compare on the bytes level just like the values. See the WriteOrderedFloat64.
+	OrderedFloats bool
 }
 
 // ReadByte reads a single byte from the reader.
@@ -41,7 +64,7 @@ func ReadByte(r io.Reader) (byte, error) {
 		return br.ReadByte()
 	}
 	b := make([]byte, 1)
-	_, err := r.Read(b)
+	_, err := io.ReadFull(r, b)
 	if err != nil {
 		return 0, err
 	}
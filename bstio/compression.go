@@ -0,0 +1,99 @@
+package bstio
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// CompressionKind is the codec compressing the value body.
+type CompressionKind uint8
+
+// Enumerated compression kinds. The values are a part of the value header and never change.
+// Up to 15 kinds could be defined, as the kind takes 4 bits of the header.
+const (
+	// CompressionNone means no compression.
+	CompressionNone CompressionKind = 0
+	// CompressionDeflate is the raw DEFLATE stream, of the lowest overhead.
+	CompressionDeflate CompressionKind = 1
+	// CompressionGzip is the gzip stream, readable by the common tools.
+	CompressionGzip CompressionKind = 2
+)
+
+// String returns the name of the compression kind.
+func (k CompressionKind) String() string {
+	switch k {
+	case CompressionNone:
+		return "none"
+	case CompressionDeflate:
+		return "deflate"
+	case CompressionGzip:
+		return "gzip"
+	default:
+		return "unknown"
+	}
+}
+
+// Known returns true if the compression kind is a known codec, or none.
+func (k CompressionKind) Known() bool {
+	return k <= CompressionGzip
+}
+
+// Compress compresses the data with the codec of given kind.
+func (k CompressionKind) Compress(data []byte) ([]byte, error) {
+	switch k {
+	case CompressionDeflate:
+		return Deflate(data)
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to compress the value")
+		}
+		if err := w.Close(); err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to compress the value")
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "unknown compression kind").WithDetail("kind", uint8(k))
+	}
+}
+
+// Decompress decompresses the data compressed with the codec of given kind.
+// The data which decompresses to more than maxSize bytes fails, unless the maxSize is zero,
+// so that the small inputs could not expand into arbitrarily large values.
+func (k CompressionKind) Decompress(data []byte, maxSize int64) ([]byte, error) {
+	// 1. Create the decompressing reader of the codec.
+	var r io.ReadCloser
+	switch k {
+	case CompressionDeflate:
+		r = flate.NewReader(bytes.NewReader(data))
+	case CompressionGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to decompress the value")
+		}
+		r = gr
+	default:
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "unknown compression kind").WithDetail("kind", uint8(k))
+	}
+	defer r.Close()
+
+	// 2. Read the decompressed data, up to the maximum size.
+	var lr io.Reader = r
+	if maxSize > 0 {
+		lr = io.LimitReader(r, maxSize+1)
+	}
+	v, err := io.ReadAll(lr)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to decompress the value")
+	}
+	if maxSize > 0 && int64(len(v)) > maxSize {
+		return nil, bsterr.Err(bsterr.CodeValueTooLarge, "decompressed value exceeds its maximum size").
+			WithDetail("max", maxSize)
+	}
+	return v, nil
+}
@@ -14,7 +14,7 @@ func ReadDateTime(r io.Reader, desc bool, loc *time.Location) (time.Time, int, e
 	// 1. Read the Version Byte.
 	ver, err := ReadByte(r)
 	if err != nil {
-		return time.Time{}, 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read DateTimeValue version")
+		return time.Time{}, 0, readError(err, 0, 1, "failed to read DateTimeValue version")
 	}
 	bytesRead := 1
 
@@ -31,9 +31,9 @@ func ReadDateTime(r io.Reader, desc bool, loc *time.Location) (time.Time, int, e
 	bin[0] = ver
 
 	// 3. Read the binary value.
-	n, err := r.Read(bin[1:])
+	n, err := readFull(r, bin[1:], bytesRead, "failed to read DateTimeValue")
 	if err != nil {
-		return time.Time{}, bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read DateTimeValue")
+		return time.Time{}, bytesRead + n, err
 	}
 	bytesRead += n
 
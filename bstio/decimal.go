@@ -0,0 +1,401 @@
+package bstio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// Decimal is the arbitrary precision decimal number, of the value Unscaled * 10^-Scale.
+// The nil Unscaled is the zero value.
+//
+// The binary of the decimal is order preserving, so that the decimals could be used as the index keys.
+// The value is normalized to the digits d1 d2 ... dn, without the trailing zeros, and the exponent E,
+// so that its absolute value is 0.d1d2...dn * 10^E. The binary representation is:
+//
+//	Size(bits)   | Name     | Description
+//	-------------+----------+------------
+//	   8         | Sign     | The sign class: 0x01 for negative, 0x02 for zero and 0x03 for positive values.
+//	   32        | Exponent | The exponent E as the comparable int32. Absent for zero.
+//	   8*N       | Digits   | The digit pairs, each as a byte of 10*d1+d2+1, N = (n + 1) / 2. Absent for zero.
+//	   8         | End      | The 0x00 terminator of the digits. Absent for zero.
+//
+// The exponent, digits and terminator of the negative values are inverted, so that their order is reversed.
+// The desc flag inverts the whole binary afterwards. The decimals of the same value, but different scale,
+// i.e. 1.5 and 1.50, have the same binary, thus the scale is not preserved.
+type Decimal struct {
+	// Unscaled is the unscaled integer value.
+	Unscaled *big.Int
+	// Scale is the number of the decimal digits of the Unscaled, which are after the decimal point.
+	Scale int32
+}
+
+const (
+	decimalNegative = 0x01
+	decimalZero     = 0x02
+	decimalPositive = 0x03
+
+	// maxUint64Digits is the number of the decimal digits, which always fit within the uint64.
+	maxUint64Digits = 19
+)
+
+// NewDecimal creates a new decimal of given unscaled value and scale, i.e. NewDecimal(1234, 2) is 12.34.
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	return Decimal{Unscaled: big.NewInt(unscaled), Scale: scale}
+}
+
+// ParseDecimal parses the decimal text, i.e. '-12.34' or '1.5e-3'.
+func ParseDecimal(s string) (Decimal, error) {
+	invalid := func() (Decimal, error) {
+		return Decimal{}, bsterr.Err(bsterr.CodeInvalidValue, "invalid decimal text").WithDetail("text", s)
+	}
+
+	// 1. Split the exponent.
+	mantissa, exp := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return invalid()
+		}
+		mantissa, exp = s[:i], e
+	}
+
+	// 2. Remove the decimal point, which determines the scale.
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	digits := intPart + fracPart
+	if strings.TrimLeft(digits, "+-") == "" || strings.ContainsAny(fracPart, "+-") {
+		return invalid()
+	}
+	unscaled, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return invalid()
+	}
+	scale := int64(len(fracPart)) - exp
+	if scale < math.MinInt32 || scale > math.MaxInt32 {
+		return invalid()
+	}
+	return Decimal{Unscaled: unscaled, Scale: int32(scale)}, nil
+}
+
+// Sign returns -1, 0 or 1, if the decimal is negative, zero or positive.
+func (x Decimal) Sign() int {
+	if x.Unscaled == nil {
+		return 0
+	}
+	return x.Unscaled.Sign()
+}
+
+// Rat returns the decimal as the rational number.
+func (x Decimal) Rat() *big.Rat {
+	r := new(big.Rat)
+	if x.Unscaled == nil {
+		return r
+	}
+	r.SetInt(x.Unscaled)
+	pow := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs32(x.Scale))), nil))
+	if x.Scale > 0 {
+		return r.Quo(r, pow)
+	}
+	return r.Mul(r, pow)
+}
+
+// Cmp compares the decimals by their values, and returns -1, 0 or 1 if the x is lower, equal or greater than y.
+func (x Decimal) Cmp(y Decimal) int {
+	return x.Rat().Cmp(y.Rat())
+}
+
+// String returns the decimal text, i.e. '-12.34'. The decimal of the negative scale is written with the exponent,
+// i.e. '12e3'.
+func (x Decimal) String() string {
+	if x.Unscaled == nil {
+		return "0"
+	}
+	digits := new(big.Int).Abs(x.Unscaled).String()
+	var sb strings.Builder
+	if x.Unscaled.Sign() < 0 {
+		sb.WriteByte('-')
+	}
+	switch {
+	case x.Scale <= 0:
+		sb.WriteString(digits)
+		if x.Scale < 0 && x.Unscaled.Sign() != 0 {
+			sb.WriteByte('e')
+			sb.WriteString(strconv.FormatInt(-int64(x.Scale), 10))
+		}
+	case int(x.Scale) < len(digits):
+		sb.WriteString(digits[:len(digits)-int(x.Scale)])
+		sb.WriteByte('.')
+		sb.WriteString(digits[len(digits)-int(x.Scale):])
+	default:
+		sb.WriteString("0.")
+		sb.WriteString(strings.Repeat("0", int(x.Scale)-len(digits)))
+		sb.WriteString(digits)
+	}
+	return sb.String()
+}
+
+// Validate checks if the normalized exponent of the decimal fits within the int32.
+func (x Decimal) Validate() error {
+	if x.Sign() == 0 {
+		return nil
+	}
+	s := getDecimalScratch()
+	defer putDecimalScratch(s)
+	_, exp := x.normalize(s)
+	return x.validateExponent(exp)
+}
+
+func (x Decimal) validateExponent(exp int64) error {
+	if exp < math.MinInt32 || exp > math.MaxInt32 {
+		return bsterr.Err(bsterr.CodeInvalidValue, "decimal exponent out of range").
+			WithDetails(bsterr.D("exponent", exp), bsterr.D("scale", x.Scale))
+	}
+	return nil
+}
+
+// normalize returns the digits of the absolute value without the trailing zeros, along with the exponent E,
+// so that the absolute value is 0.d1d2...dn * 10^E. The digits are written to the scratch space, thus they are
+// valid until it is released. The unscaled values within the int64 are formatted without allocations.
+func (x Decimal) normalize(s *decimalScratch) ([]byte, int64) {
+	d := s.digits[:0]
+	if x.Unscaled.IsInt64() {
+		u := x.Unscaled.Int64()
+		if u < 0 {
+			// The negation of the MinInt64 overflows to itself, which is still its magnitude as the uint64.
+			u = -u
+		}
+		d = strconv.AppendUint(d, uint64(u), 10)
+	} else {
+		d = x.Unscaled.Append(d, 10)
+		if d[0] == '-' {
+			d = d[:copy(d, d[1:])]
+		}
+	}
+	s.digits = d
+	exp := int64(len(d)) - int64(x.Scale)
+	return bytes.TrimRight(d, "0"), exp
+}
+
+// decimalScratch is the temporary space of the decimal encoding, pooled for reuse.
+type decimalScratch struct {
+	digits []byte
+}
+
+var (
+	_decimalScratchPool  = sync.Pool{New: func() any { return new(decimalScratch) }}
+	_decimalScratchLimit = int64(defaultDecimalScratchLimit)
+)
+
+// defaultDecimalScratchLimit is the default maximum number of the decimal digits of the pooled scratch space.
+const defaultDecimalScratchLimit = 1024
+
+func getDecimalScratch() *decimalScratch {
+	return _decimalScratchPool.Get().(*decimalScratch)
+}
+
+// putDecimalScratch releases the scratch space to the pool, unless it grew beyond the decimal scratch limit.
+func putDecimalScratch(s *decimalScratch) {
+	if int64(cap(s.digits)) > atomic.LoadInt64(&_decimalScratchLimit) {
+		return
+	}
+	s.digits = s.digits[:0]
+	_decimalScratchPool.Put(s)
+}
+
+func abs32(v int32) int64 {
+	if v < 0 {
+		return -int64(v)
+	}
+	return int64(v)
+}
+
+// DecimalBinarySize returns the size of the decimal binary.
+func DecimalBinarySize(v Decimal) uint {
+	if v.Sign() == 0 {
+		return 1
+	}
+	s := getDecimalScratch()
+	defer putDecimalScratch(s)
+	digits, _ := v.normalize(s)
+	return uint(1 + 4 + (len(digits)+1)/2 + 1)
+}
+
+// AppendDecimal appends the binary of the decimal to the dst. The desc flag inverts the binary for the descending order.
+// The decimal digits are formatted within the pooled scratch space, thus the decimals of the unscaled value
+// within the int64 are appended without allocations, if the dst has the capacity for them.
+func AppendDecimal(dst []byte, v Decimal, desc bool) ([]byte, error) {
+	// 1. The zero is written as its sign class only.
+	n := len(dst)
+	if v.Sign() == 0 {
+		return appendOrdered(dst[:n], append(dst, decimalZero), desc), nil
+	}
+
+	// 2. Verify the exponent of the normalized decimal.
+	s := getDecimalScratch()
+	defer putDecimalScratch(s)
+	digits, exp := v.normalize(s)
+	if err := v.validateExponent(exp); err != nil {
+		return dst, err
+	}
+
+	// 3. Write the sign class, the exponent and the digit pairs along with the terminator.
+	sign := byte(decimalPositive)
+	if v.Sign() < 0 {
+		sign = decimalNegative
+	}
+	dst = append(dst, sign)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(int32(exp))^1<<31)
+	for i := 0; i < len(digits); i += 2 {
+		pair := (digits[i] - '0') * 10
+		if i+1 < len(digits) {
+			pair += digits[i+1] - '0'
+		}
+		dst = append(dst, pair+1)
+	}
+	dst = append(dst, 0x00)
+
+	// 4. The negative values have their magnitude inverted, so that their order is reversed.
+	if sign == decimalNegative {
+		ReverseBytes(dst[n+1:])
+	}
+	return appendOrdered(dst[:n], dst, desc), nil
+}
+
+// WriteDecimal writes the decimal binary. The desc flag inverts the binary for the descending order.
+func WriteDecimal(w io.Writer, v Decimal, desc bool) (int, error) {
+	return WriteDecimalAlloc(w, v, desc, nil)
+}
+
+// WriteDecimalAlloc writes the decimal binary just as the WriteDecimal, taking its scratch byte slice
+// out of the allocator. The nil allocator allocates it on the heap.
+func WriteDecimalAlloc(w io.Writer, v Decimal, desc bool, a Allocator) (int, error) {
+	b, err := AppendDecimal(allocBytes(a, int(DecimalBinarySize(v))), v, desc)
+	defer freeBytes(a, b)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	if err != nil {
+		return n, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write decimal value")
+	}
+	return n, nil
+}
+
+// ReadDecimal reads the decimal binary. The desc flag determines if the binary was written in descending order.
+// The returned decimal is normalized, so that its Unscaled value has no trailing zeros.
+func ReadDecimal(r io.Reader, desc bool) (Decimal, int, error) {
+	// 1. Read the sign class.
+	sign, err := ReadByte(r)
+	if err != nil {
+		return Decimal{}, 0, readError(err, 0, 1, "failed to read decimal sign")
+	}
+	if desc {
+		sign = ^sign
+	}
+	switch sign {
+	case decimalZero:
+		return Decimal{Unscaled: new(big.Int)}, 1, nil
+	case decimalNegative, decimalPositive:
+	default:
+		return Decimal{}, 1, bsterr.Err(bsterr.CodeMalformedBinary, "invalid decimal sign").WithDetail("sign", sign)
+	}
+	invert := desc != (sign == decimalNegative)
+
+	// 2. Read the exponent.
+	var eb [4]byte
+	n, err := readFull(r, eb[:], 1, "failed to read decimal exponent")
+	n++
+	if err != nil {
+		return Decimal{}, n, err
+	}
+	if invert {
+		ReverseBytes(eb[:])
+	}
+	exp := int64(int32(binary.BigEndian.Uint32(eb[:]) ^ 1<<31))
+
+	// 3. Read the digit pairs, up to the terminator, into the pooled scratch space.
+	s := getDecimalScratch()
+	defer putDecimalScratch(s)
+	digits := s.digits[:0]
+	for {
+		b, err := ReadByte(r)
+		if err != nil {
+			return Decimal{}, n, readError(err, n, n+1, "failed to read decimal digits")
+		}
+		n++
+		if invert {
+			b = ^b
+		}
+		if b == 0x00 {
+			break
+		}
+		if b > 100 {
+			return Decimal{}, n, bsterr.Err(bsterr.CodeMalformedBinary, "invalid decimal digits").WithDetail("digits", b)
+		}
+		digits = append(digits, '0'+(b-1)/10, '0'+(b-1)%10)
+	}
+	s.digits = digits
+	digits = bytes.TrimRight(digits, "0")
+	if len(digits) == 0 || digits[0] == '0' {
+		return Decimal{}, n, bsterr.Err(bsterr.CodeMalformedBinary, "decimal digits not normalized")
+	}
+
+	// 4. Compose the decimal. The unscaled values within the uint64 are composed without the digits text.
+	unscaled := new(big.Int)
+	if len(digits) <= maxUint64Digits {
+		var u uint64
+		for _, d := range digits {
+			u = u*10 + uint64(d-'0')
+		}
+		unscaled.SetUint64(u)
+	} else {
+		unscaled.SetString(string(digits), 10)
+	}
+	if sign == decimalNegative {
+		unscaled.Neg(unscaled)
+	}
+	return Decimal{Unscaled: unscaled, Scale: int32(int64(len(digits)) - exp)}, n, nil
+}
+
+// SkipDecimal skips the decimal binary.
+func SkipDecimal(rs io.ReadSeeker, desc bool) (int64, error) {
+	// 1. Read the sign class, the zero has nothing more to skip.
+	sign, err := ReadByte(rs)
+	if err != nil {
+		return 0, readError(err, 0, 1, "failed to skip decimal value")
+	}
+	if desc {
+		sign = ^sign
+	}
+	if sign == decimalZero {
+		return 1, nil
+	}
+	end := byte(0x00)
+	if desc != (sign == decimalNegative) {
+		end = 0xFF
+	}
+
+	// 2. Skip the exponent and the digits, up to the terminator.
+	if _, err = rs.Seek(4, io.SeekCurrent); err != nil {
+		return 1, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip decimal exponent")
+	}
+	n := int64(5)
+	for {
+		b, err := ReadByte(rs)
+		if err != nil {
+			return n, readError(err, int(n), int(n)+1, "failed to skip decimal digits")
+		}
+		n++
+		if b == end {
+			return n, nil
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package bstio
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecimalOrder(t *testing.T) {
+	// The decimals are sorted by their values.
+	texts := []string{"-9223372036854775808", "-1e10", "-123.45", "-123.4", "-1", "-0.5", "-0.05", "0", "0.0001", "0.05",
+		"0.5", "0.51", "1", "1.000001", "9.99", "10", "123.4", "123.45", "1e10", "9999999999999999999",
+		"12345678901234567890123456789"}
+	values := make([]Decimal, len(texts))
+	for i, s := range texts {
+		d, err := ParseDecimal(s)
+		if err != nil {
+			t.Fatalf("parsing %s failed: %v", s, err)
+		}
+		values[i] = d
+	}
+
+	for _, desc := range []bool{false, true} {
+		for i, v := range values {
+			b, err := AppendDecimal(nil, v, desc)
+			if err != nil {
+				t.Fatalf("appending %s failed: %v", v, err)
+			}
+			if len(b) != int(DecimalBinarySize(v)) {
+				t.Fatalf("unexpected binary size of %s: %d, expected: %d", v, len(b), DecimalBinarySize(v))
+			}
+
+			// 1. The binary is read back and skipped.
+			var buf bytes.Buffer
+			if _, err = WriteDecimal(&buf, v, desc); err != nil {
+				t.Fatalf("writing %s failed: %v", v, err)
+			}
+			if !bytes.Equal(buf.Bytes(), b) {
+				t.Fatalf("unexpected binary of %s: %x, expected: %x", v, buf.Bytes(), b)
+			}
+			rv, n, err := ReadDecimal(bytes.NewReader(b), desc)
+			if err != nil || n != len(b) || rv.Cmp(v) != 0 {
+				t.Fatalf("unexpected read decimal: %s, expected: %s, read: %d, err: %v", rv, v, n, err)
+			}
+			if sn, err := SkipDecimal(bytes.NewReader(b), desc); err != nil || sn != int64(len(b)) {
+				t.Fatalf("unexpected skipped bytes of %s: %d, err: %v", v, sn, err)
+			}
+
+			// 2. The binaries compare just like the values.
+			if i == 0 {
+				continue
+			}
+			prev, _ := AppendDecimal(nil, values[i-1], desc)
+			cmp := bytes.Compare(prev, b)
+			if (!desc && cmp >= 0) || (desc && cmp <= 0) {
+				t.Fatalf("unexpected order of %s and %s, desc: %v", values[i-1], v, desc)
+			}
+		}
+	}
+}
+
+func TestDecimalText(t *testing.T) {
+	for in, expected := range map[string]string{
+		"12.34":   "12.34",
+		"-0.0012": "-0.0012",
+		"+5":      "5",
+		"1.5e3":   "15e2",
+		"-.5":     "-0.5",
+		"2.50":    "2.50",
+		"0.000":   "0.000",
+	} {
+		d, err := ParseDecimal(in)
+		if err != nil {
+			t.Fatalf("parsing %s failed: %v", in, err)
+		}
+		if d.String() != expected {
+			t.Fatalf("unexpected text of %s: %s, expected: %s", in, d, expected)
+		}
+	}
+	for _, in := range []string{"", "-", "1.2.3", "1e", "abc", "1.-2", "1e99999999999"} {
+		if _, err := ParseDecimal(in); err == nil {
+			t.Fatalf("expected %q to fail", in)
+		}
+	}
+
+	// The equal values of different scales have the same binary, and compare as equal.
+	a, b := NewDecimal(150, 2), NewDecimal(15, 1)
+	ab, _ := AppendDecimal(nil, a, false)
+	bb, _ := AppendDecimal(nil, b, false)
+	if a.Cmp(b) != 0 || !bytes.Equal(ab, bb) {
+		t.Fatalf("expected %s and %s to be equal", a, b)
+	}
+}
+
+func TestDecimalMalformed(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"sign":       {0x07},
+		"truncated":  {decimalPositive, 0x80, 0x00},
+		"digits":     {decimalPositive, 0x80, 0x00, 0x00, 0x01, 0xF0, 0x00},
+		"normalized": {decimalPositive, 0x80, 0x00, 0x00, 0x01, 0x01, 0x00},
+		"empty":      {decimalPositive, 0x80, 0x00, 0x00, 0x01, 0x00},
+	} {
+		if _, _, err := ReadDecimal(bytes.NewReader(data), false); err == nil {
+			t.Fatalf("expected %s binary to fail", name)
+		}
+	}
+}
+
+func TestDecimalAllocs(t *testing.T) {
+	v := NewDecimal(-123456789, 4)
+	dst := make([]byte, 0, DecimalBinarySize(v))
+	a := &ScratchAllocator{}
+
+	// 1. The decimals of the int64 unscaled value are encoded within the pooled scratch space.
+	for name, fn := range map[string]func(){
+		"AppendDecimal": func() { _, _ = AppendDecimal(dst, v, true) },
+		"WriteDecimalAlloc": func() {
+			_, _ = WriteDecimalAlloc(io.Discard, v, false, a)
+		},
+		"DecimalBinarySize": func() { DecimalBinarySize(v) },
+	} {
+		if allocs := testing.AllocsPerRun(100, fn); allocs > 0 {
+			t.Fatalf("unexpected allocations of %s: %v", name, allocs)
+		}
+	}
+
+	// 2. The scratch space is not pooled above the limit, which doesn't affect the binary.
+	huge, err := ParseDecimal("-" + strings.Repeat("12345", 50))
+	if err != nil {
+		t.Fatalf("parsing decimal failed: %v", err)
+	}
+	expected, err := AppendDecimal(nil, huge, false)
+	if err != nil {
+		t.Fatalf("appending decimal failed: %v", err)
+	}
+	prev := SetDecimalScratchLimit(0)
+	defer SetDecimalScratchLimit(prev)
+	if prev != defaultDecimalScratchLimit {
+		t.Fatalf("unexpected default decimal scratch limit: %d", prev)
+	}
+	b, err := AppendDecimal(nil, huge, false)
+	if err != nil || !bytes.Equal(b, expected) {
+		t.Fatalf("unexpected binary without the pooled scratch: %x, err: %v", b, err)
+	}
+	rv, _, err := ReadDecimal(bytes.NewReader(b), false)
+	if err != nil || rv.Cmp(huge) != 0 {
+		t.Fatalf("unexpected read decimal: %s, err: %v", rv, err)
+	}
+}
@@ -0,0 +1,60 @@
+package bstio
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"sync"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+var deflateWriters = sync.Pool{
+	New: func() interface{} {
+		// The level is valid, thus the error is never returned.
+		w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return w
+	},
+}
+
+// Deflate compresses the data with the DEFLATE algorithm.
+func Deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := deflateWriters.Get().(*flate.Writer)
+	defer deflateWriters.Put(w)
+
+	// 1. Reset the pooled writer on the output buffer.
+	w.Reset(&buf)
+
+	// 2. Compress the data and flush the final block.
+	if _, err := w.Write(data); err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to deflate the value")
+	}
+	if err := w.Close(); err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to deflate the value")
+	}
+	return buf.Bytes(), nil
+}
+
+// Inflate decompresses the data compressed with the Deflate function.
+// The data which inflates to more than maxSize bytes fails with the bsterr.CodeValueTooLarge error,
+// unless the maxSize is zero, so that the small inputs could not expand into arbitrarily large values.
+func Inflate(data []byte, maxSize int64) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+
+	// 1. Read the inflated data, up to the maximum size.
+	var lr io.Reader = r
+	if maxSize > 0 {
+		lr = io.LimitReader(r, maxSize+1)
+	}
+	v, err := io.ReadAll(lr)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to inflate the value")
+	}
+	if maxSize > 0 && int64(len(v)) > maxSize {
+		return nil, bsterr.Err(bsterr.CodeValueTooLarge, "inflated value exceeds its maximum size").
+			WithDetail("max", maxSize)
+	}
+	return v, nil
+}
@@ -0,0 +1,53 @@
+package bstio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// TruncatedError is the error returned when the binary input ends in the middle of a value.
+// It unwraps to the io.ErrUnexpectedEOF.
+type TruncatedError struct {
+	// Expected is the number of bytes the value requires. For the values without known length
+	// (i.e. comparable strings) it is the minimum number of bytes the value could have.
+	Expected int
+	// Read is the number of bytes of the value read before the input ended.
+	Read int
+}
+
+// Error implements the error interface.
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("binary value truncated: read %d of %d expected bytes", e.Read, e.Expected)
+}
+
+// Unwrap returns the io.ErrUnexpectedEOF.
+func (e *TruncatedError) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+// readError wraps the error that occurred while reading a value, which already has read bytes out of expected.
+// An EOF at the value boundary (no bytes read) is wrapped as the bsterr.CodeEndOfInput, still matching the io.EOF,
+// whereas an EOF in the middle of the value is wrapped as the bsterr.CodeTruncatedBinary with the TruncatedError.
+func readError(err error, read, expected int, msg string) error {
+	switch {
+	case read == 0 && errors.Is(err, io.EOF):
+		return bsterr.ErrWrap(io.EOF, bsterr.CodeEndOfInput, msg)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return bsterr.ErrWrap(&TruncatedError{Expected: expected, Read: read}, bsterr.CodeTruncatedBinary, msg)
+	default:
+		return bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, msg)
+	}
+}
+
+// readFull reads exactly len(buf) bytes into the buffer, where the read number of bytes of the value
+// were already read. The number of bytes read into the buffer is returned.
+func readFull(r io.Reader, buf []byte, read int, msg string) (int, error) {
+	n, err := io.ReadFull(r, buf)
+	if err != nil {
+		return n, readError(err, read+n, read+len(buf), msg)
+	}
+	return n, nil
+}
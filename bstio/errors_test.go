@@ -0,0 +1,115 @@
+package bstio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+func TestReadErrors(t *testing.T) {
+	testCases := []struct {
+		Name  string
+		Write func(w io.Writer) error
+		Read  func(r io.Reader) error
+	}{
+		{
+			Name:  "Uint64",
+			Write: func(w io.Writer) error { _, err := WriteUint64(w, 1<<40, false); return err },
+			Read:  func(r io.Reader) error { _, _, err := ReadUint64(r, false); return err },
+		},
+		{
+			Name:  "Int32",
+			Write: func(w io.Writer) error { _, err := WriteInt32(w, -42, true); return err },
+			Read:  func(r io.Reader) error { _, _, err := ReadInt32(r, true); return err },
+		},
+		{
+			Name:  "Float64",
+			Write: func(w io.Writer) error { _, err := WriteFloat64(w, 3.14, false); return err },
+			Read:  func(r io.Reader) error { _, _, err := ReadFloat64(r, false); return err },
+		},
+		{
+			Name:  "Uint",
+			Write: func(w io.Writer) error { _, err := WriteUint(w, 1<<20, false); return err },
+			Read:  func(r io.Reader) error { _, _, err := ReadUint(r, false); return err },
+		},
+		{
+			Name:  "String",
+			Write: func(w io.Writer) error { _, err := WriteString(w, "hello", false, false); return err },
+			Read:  func(r io.Reader) error { _, _, err := ReadString(r, false, false); return err },
+		},
+		{
+			Name:  "String/Comparable",
+			Write: func(w io.Writer) error { _, err := WriteString(w, "hello", false, true); return err },
+			Read:  func(r io.Reader) error { _, _, err := ReadString(r, false, true); return err },
+		},
+		{
+			Name:  "Bytes/Fixed",
+			Write: func(w io.Writer) error { _, err := WriteBytes(w, 3, []byte{1, 2, 3}, false, false); return err },
+			Read:  func(r io.Reader) error { _, _, err := ReadBytes(r, 3, false, false); return err },
+		},
+	}
+
+	readers := []struct {
+		Name string
+		New  func(data []byte) io.Reader
+	}{
+		{Name: "Seeker", New: func(data []byte) io.Reader { return bytes.NewReader(data) }},
+		{Name: "Reader", New: func(data []byte) io.Reader { return struct{ io.Reader }{bytes.NewReader(data)} }},
+	}
+
+	for _, tc := range testCases {
+		for _, rc := range readers {
+			t.Run(tc.Name+"/"+rc.Name, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := tc.Write(&buf); err != nil {
+					t.Fatalf("write failed: %v", err)
+				}
+				data := buf.Bytes()
+
+				// 1. Check the complete value.
+				if err := tc.Read(rc.New(data)); err != nil {
+					t.Fatalf("read failed: %v", err)
+				}
+
+				// 2. Check the end of input at the value boundary.
+				err := tc.Read(rc.New(nil))
+				if !errors.Is(err, io.EOF) {
+					t.Fatalf("expected io.EOF, got: %v", err)
+				}
+				if !hasErrCode(err, bsterr.CodeEndOfInput) {
+					t.Fatalf("expected end of input error code, got: %v", err)
+				}
+
+				// 3. Check the value truncated in the middle.
+				err = tc.Read(rc.New(data[:len(data)-1]))
+				if errors.Is(err, io.EOF) {
+					t.Fatalf("truncated value reported as io.EOF: %v", err)
+				}
+				if !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Fatalf("expected io.ErrUnexpectedEOF, got: %v", err)
+				}
+				var te *TruncatedError
+				if !errors.As(err, &te) {
+					t.Fatalf("expected truncated error, got: %v", err)
+				}
+				if te.Read != len(data)-1 {
+					t.Fatalf("unexpected truncated read bytes: %d, expected: %d", te.Read, len(data)-1)
+				}
+				if te.Expected < len(data) {
+					t.Fatalf("unexpected truncated expected bytes: %d, at least: %d", te.Expected, len(data))
+				}
+				if !hasErrCode(err, bsterr.CodeTruncatedBinary) {
+					t.Fatalf("expected truncated binary error code, got: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func hasErrCode(err error, code bsterr.ErrCode) bool {
+	var be *bsterr.Error
+	return errors.As(err, &be) && be.Code == code
+}
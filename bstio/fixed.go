@@ -0,0 +1,53 @@
+package bstio
+
+import (
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// fixedWriter is implemented by the writers exposing their unused buffer capacity, i.e. the bytes.Buffer,
+// bufio.Writer or the composer buffers. The fixed size binaries are stored directly in it and written at once,
+// instead of byte by byte.
+type fixedWriter interface {
+	io.Writer
+	AvailableBuffer() []byte
+}
+
+// fixedReader is implemented by the readers exposing their buffered input, i.e. the extractor readers.
+type fixedReader interface {
+	// Next returns the next n buffered bytes and advances the reader past them.
+	// If less than n bytes are buffered, it returns false and the reader is not advanced.
+	Next(n int) ([]byte, bool)
+}
+
+// fixedBuffer returns the n bytes of the writer available buffer, if the writer exposes it and it is large enough.
+func fixedBuffer(w io.Writer, n int) ([]byte, bool) {
+	fw, ok := w.(fixedWriter)
+	if !ok {
+		return nil, false
+	}
+	b := fw.AvailableBuffer()
+	if cap(b) < n {
+		return nil, false
+	}
+	return b[:n], true
+}
+
+// writeFixed writes the fixed size binary stored in the writer available buffer.
+func writeFixed(w io.Writer, b []byte, msg string) (int, error) {
+	n, err := w.Write(b)
+	if err != nil {
+		return n, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, msg)
+	}
+	return n, nil
+}
+
+// fixedBytes returns the next n bytes of the reader, if the reader exposes its buffered input and they are buffered.
+func fixedBytes(r io.Reader, n int) ([]byte, bool) {
+	fr, ok := r.(fixedReader)
+	if !ok {
+		return nil, false
+	}
+	return fr.Next(n)
+}
@@ -0,0 +1,185 @@
+package bstio
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+)
+
+// sliceReader is the fixedReader over the byte slice.
+type sliceReader struct {
+	b   []byte
+	pos int
+}
+
+func newSliceReader(b []byte) *sliceReader {
+	return &sliceReader{b: b}
+}
+
+func (x *sliceReader) Read(p []byte) (int, error) {
+	if x.pos >= len(x.b) {
+		return 0, io.EOF
+	}
+	n := copy(p, x.b[x.pos:])
+	x.pos += n
+	return n, nil
+}
+
+func (x *sliceReader) Next(n int) ([]byte, bool) {
+	if x.pos+n > len(x.b) {
+		return nil, false
+	}
+	x.pos += n
+	return x.b[x.pos-n : x.pos], true
+}
+
+func TestFixedFastPath(t *testing.T) {
+	type codec struct {
+		name  string
+		write func(w io.Writer, desc bool) (int, error)
+		read  func(r io.Reader, desc bool) (any, int, error)
+		bin   func(desc bool) []byte
+		value any
+	}
+	var codecs []codec
+	for _, v := range []int64{0, 1, -1, 255, -256, math.MaxInt64, math.MinInt64} {
+		codecs = append(codecs,
+			codec{
+				name:  "Int16",
+				write: func(w io.Writer, desc bool) (int, error) { return WriteInt16(w, int16(v), desc) },
+				read:  func(r io.Reader, desc bool) (any, int, error) { return wrap(ReadInt16(r, desc)) },
+				bin:   func(desc bool) []byte { return MarshalInt16(int16(v), desc) },
+				value: int16(v),
+			},
+			codec{
+				name:  "Int32",
+				write: func(w io.Writer, desc bool) (int, error) { return WriteInt32(w, int32(v), desc) },
+				read:  func(r io.Reader, desc bool) (any, int, error) { return wrap(ReadInt32(r, desc)) },
+				bin:   func(desc bool) []byte { return MarshalInt32(int32(v), desc) },
+				value: int32(v),
+			},
+			codec{
+				name:  "Int64",
+				write: func(w io.Writer, desc bool) (int, error) { return WriteInt64(w, v, desc) },
+				read:  func(r io.Reader, desc bool) (any, int, error) { return wrap(ReadInt64(r, desc)) },
+				bin:   func(desc bool) []byte { return MarshalInt64(v, desc) },
+				value: v,
+			},
+			codec{
+				name:  "Uint16",
+				write: func(w io.Writer, desc bool) (int, error) { return WriteUint16(w, uint16(v), desc) },
+				read:  func(r io.Reader, desc bool) (any, int, error) { return wrap(ReadUint16(r, desc)) },
+				bin:   func(desc bool) []byte { return MarshalUint16(uint16(v), desc) },
+				value: uint16(v),
+			},
+			codec{
+				name:  "Uint32",
+				write: func(w io.Writer, desc bool) (int, error) { return WriteUint32(w, uint32(v), desc) },
+				read:  func(r io.Reader, desc bool) (any, int, error) { return wrap(ReadUint32(r, desc)) },
+				bin:   func(desc bool) []byte { return MarshalUint32(uint32(v), desc) },
+				value: uint32(v),
+			},
+			codec{
+				name:  "Uint64",
+				write: func(w io.Writer, desc bool) (int, error) { return WriteUint64(w, uint64(v), desc) },
+				read:  func(r io.Reader, desc bool) (any, int, error) { return wrap(ReadUint64(r, desc)) },
+				bin:   func(desc bool) []byte { return MarshalUint64(uint64(v), desc) },
+				value: uint64(v),
+			},
+		)
+	}
+	for _, v := range []float64{0, math.Copysign(0, -1), 1.5, -1.5, math.MaxFloat64, math.Inf(-1)} {
+		codecs = append(codecs,
+			codec{
+				name:  "Float32",
+				write: func(w io.Writer, desc bool) (int, error) { return WriteFloat32(w, float32(v), desc) },
+				read:  func(r io.Reader, desc bool) (any, int, error) { return wrap(ReadFloat32(r, desc)) },
+				bin:   func(desc bool) []byte { return MarshalFloat32(float32(v), desc) },
+				value: float32(v),
+			},
+			codec{
+				name:  "Float64",
+				write: func(w io.Writer, desc bool) (int, error) { return WriteFloat64(w, v, desc) },
+				read:  func(r io.Reader, desc bool) (any, int, error) { return wrap(ReadFloat64(r, desc)) },
+				bin:   func(desc bool) []byte { return MarshalFloat64(v, desc) },
+				value: v,
+			},
+		)
+	}
+
+	for _, c := range codecs {
+		for _, desc := range []bool{false, true} {
+			// 1. The binary written to the buffered writer is the same as the marshaled one.
+			expected := c.bin(desc)
+			buf := bytes.NewBuffer(make([]byte, 0, 16))
+			n, err := c.write(buf, desc)
+			if err != nil {
+				t.Fatalf("%s: write failed: %v", c.name, err)
+			}
+			if n != len(expected) || !bytes.Equal(buf.Bytes(), expected) {
+				t.Fatalf("%s(%v, desc: %v): unexpected binary: %v, expected: %v", c.name, c.value, desc, buf.Bytes(), expected)
+			}
+
+			// 2. The value read from the buffered reader is the same as read byte by byte.
+			got, n, err := c.read(newSliceReader(expected), desc)
+			if err != nil {
+				t.Fatalf("%s: read failed: %v", c.name, err)
+			}
+			want, _, err := c.read(bytes.NewReader(expected), desc)
+			if err != nil {
+				t.Fatalf("%s: read failed: %v", c.name, err)
+			}
+			if n != len(expected) || got != want {
+				t.Fatalf("%s(%v, desc: %v): unexpected value: %v, expected: %v", c.name, c.value, desc, got, want)
+			}
+		}
+	}
+
+	t.Run("Truncated", func(t *testing.T) {
+		// The truncated input falls back to the regular reading, which reports the truncation.
+		if _, _, err := ReadInt64(newSliceReader([]byte{0x80, 0x00}), false); err == nil {
+			t.Fatal("expected truncated binary error")
+		}
+	})
+}
+
+func wrap[T any](v T, n int, err error) (any, int, error) {
+	return v, n, err
+}
+
+func BenchmarkFixedFastPath(b *testing.B) {
+	b.Run("WriteUint64", func(b *testing.B) {
+		b.Run("ByteWriter", func(b *testing.B) {
+			w := io.Writer(discard{})
+			for i := 0; i < b.N; i++ {
+				WriteUint64(w, 72057594037927936, false)
+			}
+		})
+		b.Run("Buffered", func(b *testing.B) {
+			buf := bytes.NewBuffer(make([]byte, 0, 8))
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				WriteUint64(buf, 72057594037927936, false)
+			}
+		})
+	})
+
+	b.Run("ReadInt64", func(b *testing.B) {
+		bin := MarshalInt64(-12345, false)
+		b.Run("ByteReader", func(b *testing.B) {
+			r := bytes.NewReader(bin)
+			for i := 0; i < b.N; i++ {
+				_, _, _ = ReadInt64(r, false)
+				r.Seek(0, io.SeekStart)
+			}
+		})
+		b.Run("Buffered", func(b *testing.B) {
+			r := newSliceReader(bin)
+			for i := 0; i < b.N; i++ {
+				_, _, _ = ReadInt64(r, false)
+				r.pos = 0
+			}
+		})
+	})
+}
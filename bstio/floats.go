@@ -8,138 +8,219 @@ import (
 	"github.com/devmodules/bst/bsterr"
 )
 
+// The floating point values are encoded in one of two formats, both with the descending values having all their bytes
+// inverted afterwards, just like the integers:
+//   - the default one, i.e. the MarshalFloat64, has the sign bit of the IEEE-754 bits set for the positive values,
+//     and cleared for the negative ones. The positive values compare on the bytes level just like the values,
+//     and the negative ones are lower than the positive, but their order among themselves is reversed.
+//   - the ordered one, i.e. the MarshalOrderedFloat64, has the bits of the negative values inverted as a whole,
+//     which reverses the order of their magnitudes, thus all the values compare on the bytes level just like the values.
+//
+// The formats have the same binaries of the positive values, but different of the negative ones, thus the values
+// need to be read in the format they were written with. The ordered format is enabled by the OrderedFloats
+// of the ValueOptions.
+
+// OrderedFloat32 maps the float32 value to the uint32 of the same order.
+// The negative values are inverted as a whole, so that their order is reversed.
+func OrderedFloat32(v float32) uint32 {
+	b := math.Float32bits(v)
+	if b&(1<<31) != 0 {
+		return ^b
+	}
+	return b | 1<<31
+}
+
+// OrderedFloat64 maps the float64 value to the uint64 of the same order.
+// The negative values are inverted as a whole, so that their order is reversed.
+func OrderedFloat64(v float64) uint64 {
+	b := math.Float64bits(v)
+	if b&(1<<63) != 0 {
+		return ^b
+	}
+	return b | 1<<63
+}
+
+// float32FromOrdered reverts the OrderedFloat32.
+func float32FromOrdered(u uint32) float32 {
+	if u&(1<<31) != 0 {
+		return math.Float32frombits(u &^ (1 << 31))
+	}
+	return math.Float32frombits(^u)
+}
+
+// float64FromOrdered reverts the OrderedFloat64.
+func float64FromOrdered(u uint64) float64 {
+	if u&(1<<63) != 0 {
+		return math.Float64frombits(u &^ (1 << 63))
+	}
+	return math.Float64frombits(^u)
+}
+
+// float32Bits returns the bits of the float32 binary, in the ordered or the default format.
+func float32Bits(v float32, ordered, desc bool) uint32 {
+	var ui uint32
+	switch {
+	case ordered:
+		ui = OrderedFloat32(v)
+	case v < 0:
+		ui = math.Float32bits(v) &^ (1 << 31)
+	default:
+		ui = math.Float32bits(v) | 1<<31
+	}
+	if desc {
+		ui = ^ui
+	}
+	return ui
+}
+
+// float32FromBits reverts the float32Bits.
+func float32FromBits(ui uint32, ordered, desc bool) float32 {
+	if desc {
+		ui = ^ui
+	}
+	if ordered {
+		return float32FromOrdered(ui)
+	}
+	return math.Float32frombits(ui ^ (1 << 31))
+}
+
+// float64Bits returns the bits of the float64 binary, in the ordered or the default format.
+func float64Bits(v float64, ordered, desc bool) uint64 {
+	var ui uint64
+	switch {
+	case ordered:
+		ui = OrderedFloat64(v)
+	case v < 0:
+		ui = math.Float64bits(v) &^ (1 << 63)
+	default:
+		ui = math.Float64bits(v) | 1<<63
+	}
+	if desc {
+		ui = ^ui
+	}
+	return ui
+}
+
+// float64FromBits reverts the float64Bits.
+func float64FromBits(ui uint64, ordered, desc bool) float64 {
+	if desc {
+		ui = ^ui
+	}
+	if ordered {
+		return float64FromOrdered(ui)
+	}
+	return math.Float64frombits(ui ^ (1 << 63))
+}
+
 // MarshalFloat32 returns the binary representation of the float32 value.
 // The first bit of the first byte is set to 1 for positive values, whereas for negative it takes a value of 0.
-// This ensures comparability of the binary representation on the bytes level.
 // The desc flag determines the order of the bytes.
 func MarshalFloat32(value float32, desc bool) []byte {
-	ui := math.Float32bits(value)
-	fb := byte(ui >> 24)
-	if value < 0 {
-		// Positive values by standard IEEE-754 are encoded with the first bit set to 0.
-		// In this encoding the first bit of the first byte is set to 0 for positive values.
-		fb &= NegativeBit8Mask
-	} else {
-		// Negative values by standard IEEE-754 are encoded with the first bit set to 1.
-		// In this encoding the first bit of the first byte is set to 1 for negative values.
-		fb |= 1 << 7
-	}
-	res := []byte{
-		fb,
-		byte(ui >> 16),
-		byte(ui >> 8),
-		byte(ui),
-	}
-	if desc {
-		ReverseBytes(res)
-	}
-	return res
+	return AppendFloat32(make([]byte, 0, 4), value, desc)
 }
 
 // WriteFloat32 writes the float32 value to the writer.
 // The desc flag determines the order of the bytes.
 func WriteFloat32(w io.Writer, v float32, desc bool) (int, error) {
+	return writeFloat32(w, float32Bits(v, false, desc))
+}
+
+// WriteOrderedFloat32 writes the float32 value to the writer, in the ordered format.
+// The desc flag determines the order of the bytes.
+func WriteOrderedFloat32(w io.Writer, v float32, desc bool) (int, error) {
+	return writeFloat32(w, float32Bits(v, true, desc))
+}
+
+// writeFloat32 writes the bits of the float32 binary.
+func writeFloat32(w io.Writer, ui uint32) (int, error) {
+	if b, ok := fixedBuffer(w, 4); ok {
+		binary.BigEndian.PutUint32(b, ui)
+		return writeFixed(w, b, "failed to write float value")
+	}
 	if bw, ok := w.(io.ByteWriter); ok {
-		return writeFloat32ByteWriter(bw, v, desc)
+		return writeFloat32ByteWriter(bw, ui)
 	}
 
-	n, err := w.Write(MarshalFloat32(v, desc))
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], ui)
+	n, err := w.Write(b[:])
 	if err != nil {
 		return n, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write float value")
 	}
-
 	return n, nil
 }
 
-func writeFloat32ByteWriter(bw io.ByteWriter, v float32, desc bool) (n int, err error) {
-	ui := math.Float32bits(v)
-	fb := byte(ui >> 24)
-	if v < 0 {
-		// Positive values by standard IEEE-754 are encoded with the first bit set to 0.
-		// In this encoding the first bit of the first byte is set to 0 for positive values.
-		fb &= NegativeBit8Mask
-	}
-
-	writeByteFn := func(b byte) {
-		if err != nil {
-			return
-		}
-		if desc {
-			b = ^b
-		}
-		err = bw.WriteByte(b)
-		if err != nil {
-			err = bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write float value")
-			return
+// writeFloat32ByteWriter writes the bits of the float32 binary, byte by byte.
+func writeFloat32ByteWriter(bw io.ByteWriter, ui uint32) (int, error) {
+	for i := 0; i < 4; i++ {
+		if err := bw.WriteByte(byte(ui >> (24 - 8*i))); err != nil {
+			return i, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write float value")
 		}
-		n++
 	}
-
-	writeByteFn(fb)
-	writeByteFn(byte(ui >> 16))
-	writeByteFn(byte(ui >> 8))
-	writeByteFn(byte(ui))
-
-	return n, err
+	return 4, nil
 }
 
 // ReadFloat32 reads a float32 value from the reader.
 // The desc flag determines the order of the bytes.
 // Returns the float32 value and the number of read bytes.
 func ReadFloat32(r io.Reader, desc bool) (float32, int, error) {
-	if br, ok := r.(io.ByteReader); ok {
-		return readFloat32ByteReader(br, desc)
+	ui, n, err := readFloat32(r)
+	return float32FromBits(ui, false, desc), n, err
+}
+
+// ReadOrderedFloat32 reads a float32 value in the ordered format from the reader.
+// The desc flag determines the order of the bytes.
+// Returns the float32 value and the number of read bytes.
+func ReadOrderedFloat32(r io.Reader, desc bool) (float32, int, error) {
+	ui, n, err := readFloat32(r)
+	return float32FromBits(ui, true, desc), n, err
+}
+
+// readFloat32 reads the bits of the float32 binary.
+func readFloat32(r io.Reader) (uint32, int, error) {
+	if b, ok := fixedBytes(r, 4); ok {
+		return binary.BigEndian.Uint32(b), 4, nil
 	}
-	bl := make([]byte, 4)
-	n, err := r.Read(bl)
-	if err != nil {
-		return 0, n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read float value")
+	if br, ok := r.(io.ByteReader); ok {
+		return readFloat32ByteReader(br)
 	}
-
-	fv, err := ParseFloat32(bl, desc)
+	var bl [4]byte
+	n, err := readFull(r, bl[:], 0, "failed to read float value")
 	if err != nil {
 		return 0, n, err
 	}
-	return fv, n, nil
+	return binary.BigEndian.Uint32(bl[:]), n, nil
 }
 
-func readFloat32ByteReader(br io.ByteReader, desc bool) (float32, int, error) {
-	var (
-		n   int
-		err error
-	)
-	readByteFn := func(msb bool) uint32 {
+// readFloat32ByteReader reads the bits of the float32 binary, byte by byte.
+func readFloat32ByteReader(br io.ByteReader) (uint32, int, error) {
+	var u32 uint32
+	for n := 0; n < 4; n++ {
+		bt, err := br.ReadByte()
 		if err != nil {
-			return 0
+			return 0, n, readError(err, n, 4, "failed to read float value")
 		}
-		bt, er := br.ReadByte()
-		if er != nil {
-			err = er
-			return 0
-		}
-		if desc {
-			bt = ^bt
-		}
-		if msb {
-			bt = ParseSignedValueMSB(bt)
-		}
-		n++
-		return uint32(bt)
+		u32 = u32<<8 | uint32(bt)
 	}
-
-	u32 := readByteFn(true)<<24 | readByteFn(false)<<16 | readByteFn(false)<<8 | readByteFn(false)
-	return math.Float32frombits(u32), n, err
+	return u32, 4, nil
 }
 
 // ParseFloat32 parses the binary representation of a float32 value.
+// The desc flag determines the order of the bytes.
 func ParseFloat32(bl []byte, desc bool) (float32, error) {
-	// 1. If the value is encoded in ascending order flip the first bit of the first byte.
-	//    This ensures that the binary representation is comparable on the bytes level.
-	if desc {
-		ReverseBytes(bl)
+	if len(bl) != 4 {
+		return 0, bsterr.Err(bsterr.CodeDecodingBinaryValue, "invalid float32 binary length").WithDetail("length", len(bl))
+	}
+	return float32FromBits(binary.BigEndian.Uint32(bl), false, desc), nil
+}
+
+// ParseOrderedFloat32 parses the binary representation of a float32 value in the ordered format.
+// The desc flag determines the order of the bytes.
+func ParseOrderedFloat32(bl []byte, desc bool) (float32, error) {
+	if len(bl) != 4 {
+		return 0, bsterr.Err(bsterr.CodeDecodingBinaryValue, "invalid float32 binary length").WithDetail("length", len(bl))
 	}
-	bl[0] = ParseSignedValueMSB(bl[0])
-	return math.Float32frombits(binary.BigEndian.Uint32(bl)), nil
+	return float32FromBits(binary.BigEndian.Uint32(bl), true, desc), nil
 }
 
 // SkipFloat32 skips the bytes of a float32 value.
@@ -154,150 +235,111 @@ func SkipFloat32(s io.ReadSeeker) (int64, error) {
 
 // MarshalFloat64 returns the binary representation of the float64 value.
 // The first bit of the first byte is set to 1 for positive values, whereas for negative it takes a value of 0.
-// This ensures comparability of the binary representation on the bytes level.
 // The desc flag determines the order of the bytes.
 func MarshalFloat64(v float64, desc bool) []byte {
-	ui := math.Float64bits(v)
-	fb := byte(ui >> 56)
-	if v < 0 {
-		fb &= NegativeBit8Mask
-	} else {
-		fb |= PositiveBit8Mask
-	}
-	res := []byte{
-		fb,
-		byte(ui >> 48),
-		byte(ui >> 40),
-		byte(ui >> 32),
-		byte(ui >> 24),
-		byte(ui >> 16),
-		byte(ui >> 8),
-		byte(ui),
-	}
-	if desc {
-		ReverseBytes(res)
-	}
-	return res
+	return AppendFloat64(make([]byte, 0, 8), v, desc)
 }
 
 // WriteFloat64 writes the float64 value to the writer.
 // The desc flag determines the order of the bytes.
 func WriteFloat64(w io.Writer, v float64, desc bool) (int, error) {
+	return writeFloat64(w, float64Bits(v, false, desc))
+}
+
+// WriteOrderedFloat64 writes the float64 value to the writer, in the ordered format.
+// The desc flag determines the order of the bytes.
+func WriteOrderedFloat64(w io.Writer, v float64, desc bool) (int, error) {
+	return writeFloat64(w, float64Bits(v, true, desc))
+}
+
+// writeFloat64 writes the bits of the float64 binary.
+func writeFloat64(w io.Writer, ui uint64) (int, error) {
+	if b, ok := fixedBuffer(w, 8); ok {
+		binary.BigEndian.PutUint64(b, ui)
+		return writeFixed(w, b, "failed to write float value")
+	}
 	if bw, ok := w.(io.ByteWriter); ok {
-		return writeFloat64ByteWriter(bw, v, desc)
+		return writeFloat64ByteWriter(bw, ui)
 	}
-	return writeFloat64Writer(w, v, desc)
-}
 
-func writeFloat64Writer(w io.Writer, v float64, desc bool) (int, error) {
-	n, err := w.Write(MarshalFloat64(v, desc))
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], ui)
+	n, err := w.Write(b[:])
 	if err != nil {
 		return n, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write float value")
 	}
 	return n, nil
 }
 
-func writeFloat64ByteWriter(bw io.ByteWriter, v float64, desc bool) (int, error) {
-	ui := math.Float64bits(v)
-	fb := byte(ui >> 56)
-	if v < 0 {
-		fb &= NegativeBit8Mask
-	} else {
-		fb |= PositiveBit8Mask
-	}
-	var (
-		n   int
-		err error
-	)
-	writeByteFn := func(b byte) {
-		if err != nil {
-			return
-		}
-		if desc {
-			b = ^b
+// writeFloat64ByteWriter writes the bits of the float64 binary, byte by byte.
+func writeFloat64ByteWriter(bw io.ByteWriter, ui uint64) (int, error) {
+	for i := 0; i < 8; i++ {
+		if err := bw.WriteByte(byte(ui >> (56 - 8*i))); err != nil {
+			return i, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write float value")
 		}
-		if err = bw.WriteByte(b); err != nil {
-			err = bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write float value")
-			return
-		}
-		n++
 	}
-
-	writeByteFn(fb)
-	writeByteFn(byte(ui >> 48))
-	writeByteFn(byte(ui >> 40))
-	writeByteFn(byte(ui >> 32))
-	writeByteFn(byte(ui >> 24))
-	writeByteFn(byte(ui >> 16))
-	writeByteFn(byte(ui >> 8))
-	writeByteFn(byte(ui))
-	return n, err
+	return 8, nil
 }
 
 // ReadFloat64 reads a float64 value from the reader.
 // The desc flag determines the order of the bytes.
 func ReadFloat64(r io.Reader, desc bool) (float64, int, error) {
-	if br, ok := r.(io.ByteReader); ok {
-		return readFloat64ByteReader(br, desc)
+	ui, n, err := readFloat64(r)
+	return float64FromBits(ui, false, desc), n, err
+}
+
+// ReadOrderedFloat64 reads a float64 value in the ordered format from the reader.
+// The desc flag determines the order of the bytes.
+func ReadOrderedFloat64(r io.Reader, desc bool) (float64, int, error) {
+	ui, n, err := readFloat64(r)
+	return float64FromBits(ui, true, desc), n, err
+}
+
+// readFloat64 reads the bits of the float64 binary.
+func readFloat64(r io.Reader) (uint64, int, error) {
+	if b, ok := fixedBytes(r, 8); ok {
+		return binary.BigEndian.Uint64(b), 8, nil
 	}
-	bl := make([]byte, 8)
-	n, err := r.Read(bl)
-	if err != nil {
-		return 0, 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read float value")
+	if br, ok := r.(io.ByteReader); ok {
+		return readFloat64ByteReader(br)
 	}
-	fv, err := ParseFloat64(bl, desc)
+	var bl [8]byte
+	n, err := readFull(r, bl[:], 0, "failed to read float value")
 	if err != nil {
-		return 0, 0, err
+		return 0, n, err
 	}
-	return fv, n, nil
+	return binary.BigEndian.Uint64(bl[:]), n, nil
 }
 
-func readFloat64ByteReader(br io.ByteReader, desc bool) (float64, int, error) {
-	var (
-		n   int
-		err error
-	)
-	readByteFn := func(msb bool) uint64 {
+// readFloat64ByteReader reads the bits of the float64 binary, byte by byte.
+func readFloat64ByteReader(br io.ByteReader) (uint64, int, error) {
+	var u64 uint64
+	for n := 0; n < 8; n++ {
+		bt, err := br.ReadByte()
 		if err != nil {
-			return 0
+			return 0, n, readError(err, n, 8, "failed to read float value")
 		}
-		bt, er := br.ReadByte()
-		if er != nil {
-			err = er
-			return 0
-		}
-		if desc {
-			bt = ^bt
-		}
-		if msb {
-			bt = ParseSignedValueMSB(bt)
-		}
-		n++
-		return uint64(bt)
-	}
-
-	u64 := readByteFn(true)<<56 | readByteFn(false)<<48 | readByteFn(false)<<40 | readByteFn(false)<<32 |
-		readByteFn(false)<<24 | readByteFn(false)<<16 | readByteFn(false)<<8 | readByteFn(false)
-	if err != nil {
-		return 0, n, err
+		u64 = u64<<8 | uint64(bt)
 	}
-	return math.Float64frombits(u64), n, nil
+	return u64, 8, nil
 }
 
 // ParseFloat64 parses the binary representation of a float64 value.
-// The first bit of the first byte is set to 1 for positive values, whereas for negative it takes a value of 0.
-// This ensures comparability of the binary representation on the bytes level.
 // The desc flag determines the order of the bytes.
 func ParseFloat64(bl []byte, desc bool) (float64, error) {
-	// 1. If the value is encoded in descending order, ReverseBytes the bytes.
-	if desc {
-		ReverseBytes(bl)
+	if len(bl) != 8 {
+		return 0, bsterr.Err(bsterr.CodeDecodingBinaryValue, "invalid float64 binary length").WithDetail("length", len(bl))
 	}
+	return float64FromBits(binary.BigEndian.Uint64(bl), false, desc), nil
+}
 
-	// 2. Flip the sign bit.
-	bl[0] = ParseSignedValueMSB(bl[0])
-
-	return math.Float64frombits(binary.BigEndian.Uint64(bl)), nil
+// ParseOrderedFloat64 parses the binary representation of a float64 value in the ordered format.
+// The desc flag determines the order of the bytes.
+func ParseOrderedFloat64(bl []byte, desc bool) (float64, error) {
+	if len(bl) != 8 {
+		return 0, bsterr.Err(bsterr.CodeDecodingBinaryValue, "invalid float64 binary length").WithDetail("length", len(bl))
+	}
+	return float64FromBits(binary.BigEndian.Uint64(bl), true, desc), nil
 }
 
 // SkipFloat64 skips a float64 value from the reader.
@@ -0,0 +1,128 @@
+package bstio
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+)
+
+// plainWriter is the writer, which is neither a byte writer nor a buffered one.
+type plainWriter struct{ w io.Writer }
+
+func (x plainWriter) Write(p []byte) (int, error) { return x.w.Write(p) }
+
+// plainReader is the reader, which is neither a byte reader nor a buffered one.
+type plainReader struct{ r io.Reader }
+
+func (x plainReader) Read(p []byte) (int, error) { return x.r.Read(p) }
+
+// floatFormat is the set of the float64 functions of a single format.
+type floatFormat struct {
+	name   string
+	append func(dst []byte, v float64, desc bool) []byte
+	write  func(w io.Writer, v float64, desc bool) (int, error)
+	read   func(r io.Reader, desc bool) (float64, int, error)
+	parse  func(bl []byte, desc bool) (float64, error)
+}
+
+var floatFormats = []floatFormat{
+	{name: "Default", append: AppendFloat64, write: WriteFloat64, read: ReadFloat64, parse: ParseFloat64},
+	{name: "Ordered", append: AppendOrderedFloat64, write: WriteOrderedFloat64, read: ReadOrderedFloat64, parse: ParseOrderedFloat64},
+}
+
+func TestFloat64Formats(t *testing.T) {
+	values := []float64{math.Inf(-1), -math.MaxFloat64, -1e10, -1.5, -1, -math.SmallestNonzeroFloat64, 0,
+		math.SmallestNonzeroFloat64, 0.5, 1, 1e10, math.MaxFloat64, math.Inf(1)}
+	for _, f := range floatFormats {
+		t.Run(f.name, func(t *testing.T) {
+			for _, desc := range []bool{false, true} {
+				for i, v := range values {
+					b := f.append(nil, v, desc)
+
+					// 1. The binaries of all the write paths are equal, and read back as the value.
+					var buf bytes.Buffer
+					if _, err := f.write(&buf, v, desc); err != nil {
+						t.Fatalf("writing %v failed: %v", v, err)
+					}
+					if _, err := f.write(plainWriter{&buf}, v, desc); err != nil {
+						t.Fatalf("writing %v failed: %v", v, err)
+					}
+					if !bytes.Equal(buf.Bytes(), append(append([]byte{}, b...), b...)) {
+						t.Fatalf("unexpected binary of %v: %x, expected: %x", v, buf.Bytes(), b)
+					}
+					for _, r := range []io.Reader{&buf, plainReader{&buf}} {
+						if rv, _, err := f.read(r, desc); err != nil || rv != v {
+							t.Fatalf("unexpected read value: %v, expected: %v, err: %v", rv, v, err)
+						}
+					}
+					if pv, err := f.parse(b, desc); err != nil || pv != v {
+						t.Fatalf("unexpected parsed value: %v, expected: %v, err: %v", pv, v, err)
+					}
+
+					// 2. The binaries compare just like the values - in the default format only the non-negative ones.
+					if i == 0 || (f.name == "Default" && v <= 0) {
+						continue
+					}
+					cmp := bytes.Compare(f.append(nil, values[i-1], desc), b)
+					if (!desc && cmp >= 0) || (desc && cmp <= 0) {
+						t.Fatalf("unexpected order of %v and %v, desc: %v", values[i-1], v, desc)
+					}
+				}
+			}
+		})
+	}
+
+	// The default format keeps the binary of the positive values, whereas the negative ones differ.
+	if !bytes.Equal(MarshalFloat64(1.5, false), AppendOrderedFloat64(nil, 1.5, false)) {
+		t.Fatal("expected equal binaries of positive values")
+	}
+	if bytes.Equal(MarshalFloat64(-1.5, false), AppendOrderedFloat64(nil, -1.5, false)) {
+		t.Fatal("expected different binaries of negative values")
+	}
+	if !bytes.Equal(MarshalFloat64(-1.5, false), []byte{0x3F, 0xF8, 0, 0, 0, 0, 0, 0}) {
+		t.Fatalf("unexpected default binary of negative value: %x", MarshalFloat64(-1.5, false))
+	}
+}
+
+func TestFloat32Formats(t *testing.T) {
+	values := []float32{float32(math.Inf(-1)), -math.MaxFloat32, -2.5, -1, 0, 1, 2.5, math.MaxFloat32,
+		float32(math.Inf(1))}
+	for _, desc := range []bool{false, true} {
+		for i, v := range values {
+			// 1. The default format reads back the value.
+			var buf bytes.Buffer
+			if _, err := WriteFloat32(plainWriter{&buf}, v, desc); err != nil {
+				t.Fatalf("writing %v failed: %v", v, err)
+			}
+			if b := MarshalFloat32(v, desc); !bytes.Equal(buf.Bytes(), b) || !bytes.Equal(AppendFloat32(nil, v, desc), b) {
+				t.Fatalf("unexpected binary of %v: %x, expected: %x", v, buf.Bytes(), b)
+			}
+			if rv, _, err := ReadFloat32(&buf, desc); err != nil || rv != v {
+				t.Fatalf("unexpected read value: %v, expected: %v, err: %v", rv, v, err)
+			}
+
+			// 2. The ordered format reads back the value, and its binaries compare just like the values.
+			b := AppendOrderedFloat32(nil, v, desc)
+			if _, err := WriteOrderedFloat32(plainWriter{&buf}, v, desc); err != nil {
+				t.Fatalf("writing %v failed: %v", v, err)
+			}
+			if !bytes.Equal(buf.Bytes(), b) {
+				t.Fatalf("unexpected ordered binary of %v: %x, expected: %x", v, buf.Bytes(), b)
+			}
+			if rv, _, err := ReadOrderedFloat32(&buf, desc); err != nil || rv != v {
+				t.Fatalf("unexpected read value: %v, expected: %v, err: %v", rv, v, err)
+			}
+			if pv, err := ParseOrderedFloat32(b, desc); err != nil || pv != v {
+				t.Fatalf("unexpected parsed value: %v, expected: %v, err: %v", pv, v, err)
+			}
+			if i == 0 {
+				continue
+			}
+			cmp := bytes.Compare(AppendOrderedFloat32(nil, values[i-1], desc), b)
+			if (!desc && cmp >= 0) || (desc && cmp <= 0) {
+				t.Fatalf("unexpected order of %v and %v, desc: %v", values[i-1], v, desc)
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package bstio
+
+import (
+	"github.com/devmodules/bst/bsterr"
+)
+
+// ValidateSizeHeader checks if the ascending header byte of the varying size unsigned integer is valid.
+// A valid header is the number of the value bytes, not greater than the MaxSizeHeader.
+func ValidateSizeHeader(b byte) error {
+	if b > MaxSizeHeader {
+		return bsterr.Errf(bsterr.CodeDecodingBinaryValue, "invalid uint binary size").
+			WithDetails(
+				bsterr.D("size", b),
+				bsterr.D("expectedMax", MaxSizeHeader),
+			)
+	}
+	return nil
+}
+
+// ValidateBinarySize checks if the size is one of the fixed width BinarySize* values.
+func ValidateBinarySize(size uint8) error {
+	switch size {
+	case BinarySizeZero, BinarySizeUint8, BinarySizeUint16, BinarySizeUint32, BinarySizeUint64:
+		return nil
+	default:
+		return bsterr.Errf(bsterr.CodeInvalidValue, "invalid binary size").WithDetail("size", size)
+	}
+}
+
+// EncodeSizeHeader encodes the number of bytes of the varying size unsigned integer into its header byte.
+// If desc is true, the header is encoded in descending order.
+func EncodeSizeHeader(size int, desc bool) (byte, error) {
+	if size < 0 || size > int(MaxSizeHeader) {
+		return 0, bsterr.Errf(bsterr.CodeEncodingBinaryValue, "invalid uint binary size").
+			WithDetails(
+				bsterr.D("size", size),
+				bsterr.D("expectedMax", MaxSizeHeader),
+			)
+	}
+	header := byte(size)
+	if desc {
+		header = ^header
+	}
+	return header, nil
+}
+
+// DecodeSizeHeader decodes the header byte of the varying size unsigned integer into the number of bytes that follow.
+// If desc is true, the header is expected to be encoded in descending order.
+func DecodeSizeHeader(b byte, desc bool) (int, error) {
+	if desc {
+		b = ^b
+	}
+	if err := ValidateSizeHeader(b); err != nil {
+		return 0, err
+	}
+	return int(b), nil
+}
@@ -0,0 +1,63 @@
+package bstio
+
+import (
+	"testing"
+)
+
+func TestSizeHeader(t *testing.T) {
+	for _, desc := range []bool{false, true} {
+		for size := 0; size <= int(MaxSizeHeader); size++ {
+			h, err := EncodeSizeHeader(size, desc)
+			if err != nil {
+				t.Fatalf("encoding size %d (desc: %v) failed: %v", size, desc, err)
+			}
+
+			// The header needs to be compatible with the unsigned integer encoding.
+			if size > 0 {
+				v := uint(1) << (8 * (size - 1))
+				if uh := UintSizeHeader(v, desc); uh != h {
+					t.Fatalf("size header %x doesn't match uint header %x (desc: %v)", h, uh, desc)
+				}
+			}
+
+			got, err := DecodeSizeHeader(h, desc)
+			if err != nil {
+				t.Fatalf("decoding header %x (desc: %v) failed: %v", h, desc, err)
+			}
+			if got != size {
+				t.Fatalf("expected size %d, got %d (desc: %v)", size, got, desc)
+			}
+		}
+
+		if _, err := EncodeSizeHeader(int(MaxSizeHeader)+1, desc); err == nil {
+			t.Fatalf("expected error encoding too large size (desc: %v)", desc)
+		}
+		if _, err := EncodeSizeHeader(-1, desc); err == nil {
+			t.Fatalf("expected error encoding negative size (desc: %v)", desc)
+		}
+
+		h := MaxSizeHeader + 1
+		if desc {
+			h = ^h
+		}
+		if _, err := DecodeSizeHeader(h, desc); err == nil {
+			t.Fatalf("expected error decoding invalid header %x (desc: %v)", h, desc)
+		}
+	}
+}
+
+func TestValidateBinarySize(t *testing.T) {
+	for _, size := range []uint8{BinarySizeZero, BinarySizeUint8, BinarySizeUint16, BinarySizeUint32, BinarySizeUint64} {
+		if err := ValidateBinarySize(size); err != nil {
+			t.Fatalf("binary size %d should be valid: %v", size, err)
+		}
+	}
+	for _, size := range []uint8{3, 5, 7, 9, 0xFF} {
+		if err := ValidateBinarySize(size); err == nil {
+			t.Fatalf("binary size %d should be invalid", size)
+		}
+	}
+	if err := ValidateSizeHeader(MaxSizeHeader + 1); err == nil {
+		t.Fatal("size header greater than the MaxSizeHeader should be invalid")
+	}
+}
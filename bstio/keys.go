@@ -0,0 +1,62 @@
+package bstio
+
+// isEscapeLeader checks if the byte could start an escape sequence of any comparable
+// bytes, array or map binary - either in ascending or descending order.
+func isEscapeLeader(b byte) bool {
+	switch b {
+	case BytesEscape, ArrayEscape, MapEscape, ^BytesEscape, ^ArrayEscape, ^MapEscape:
+		return true
+	default:
+		return false
+	}
+}
+
+// SharedPrefixLen returns the length of the common prefix of two comparable keys.
+// The prefix never ends with a byte that could start an escape sequence, so that splitting
+// the keys at the prefix never separates the escape byte from the escaped one.
+// As the keys are not decoded, the escape detection is conservative, and the prefix might be shorter
+// than the raw common prefix.
+func SharedPrefixLen(a, b []byte) int {
+	// 1. Find the raw common prefix.
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			n = i
+			break
+		}
+	}
+
+	// 2. Step back if the prefix would end within an escape sequence.
+	if n > 0 && isEscapeLeader(a[n-1]) {
+		n--
+	}
+	return n
+}
+
+// TruncateSeparator returns the shortest key k, such that a <= k < b in a byte-wise comparison.
+// It is used to find the separator keys of the index blocks, which are shorter than the keys themselves.
+// The key a needs to be less than b, otherwise a copy of a is returned.
+// The separator never ends with a byte that could start an escape sequence of the comparable binary,
+// which applies to both ascending and descending segments.
+func TruncateSeparator(a, b []byte) []byte {
+	// 1. Find the escape safe common prefix.
+	n := SharedPrefixLen(a, b)
+
+	// 2. If a is a prefix of b (or they are equal), no shorter separator exists.
+	if n < len(a) && n < len(b) {
+		// 3. Try to find a byte greater than a[n] and less than b[n], that is not an escape leader.
+		for c := int(a[n]) + 1; c < int(b[n]); c++ {
+			if isEscapeLeader(byte(c)) {
+				continue
+			}
+			k := make([]byte, n+1)
+			copy(k, a[:n])
+			k[n] = byte(c)
+			return k
+		}
+	}
+	return append([]byte(nil), a...)
+}
@@ -0,0 +1,64 @@
+package bstio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSharedPrefixLen(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		A, B     []byte
+		Expected int
+	}{
+		{Name: "Equal", A: []byte("abc"), B: []byte("abc"), Expected: 3},
+		{Name: "Prefix", A: []byte("ab"), B: []byte("abc"), Expected: 2},
+		{Name: "Different", A: []byte("abc"), B: []byte("abd"), Expected: 2},
+		{Name: "None", A: []byte("abc"), B: []byte("xyz"), Expected: 0},
+		{Name: "AscendingEscape", A: []byte{'a', 0x00, 0x01}, B: []byte{'a', 0x00, 0xFF}, Expected: 1},
+		{Name: "DescendingEscape", A: []byte{'a', 0xFF, 0xFE}, B: []byte{'a', 0xFF, 0x00}, Expected: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if n := SharedPrefixLen(tc.A, tc.B); n != tc.Expected {
+				t.Fatalf("unexpected shared prefix length: %d, expected: %d", n, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestTruncateSeparator(t *testing.T) {
+	comparable := func(s string, desc bool) []byte {
+		var buf bytes.Buffer
+		if _, err := WriteString(&buf, s, desc, true); err != nil {
+			t.Fatalf("writing string failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	testCases := []struct {
+		Name     string
+		A, B     []byte
+		Expected []byte
+	}{
+		{Name: "Short", A: comparable("apple", false), B: comparable("cherry", false), Expected: []byte{'b'}},
+		{Name: "CommonPrefix", A: comparable("user/1000", false), B: comparable("user/3000", false), Expected: []byte("user/2")},
+		{Name: "Adjacent", A: comparable("abc", false), B: comparable("abd", false), Expected: comparable("abc", false)},
+		{Name: "Prefix", A: []byte("ab"), B: []byte("abc"), Expected: []byte("ab")},
+		{Name: "SkipsEscapeLeader", A: []byte{0x01}, B: []byte{0x04}, Expected: []byte{0x01}},
+		{Name: "Descending", A: comparable("zebra", true), B: comparable("apple", true), Expected: []byte{^byte('y')}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			k := TruncateSeparator(tc.A, tc.B)
+			if !bytes.Equal(k, tc.Expected) {
+				t.Fatalf("unexpected separator: %v, expected: %v", k, tc.Expected)
+			}
+			if bytes.Compare(tc.A, k) > 0 || bytes.Compare(k, tc.B) >= 0 {
+				t.Fatalf("separator out of range: %v", k)
+			}
+		})
+	}
+}
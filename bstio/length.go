@@ -0,0 +1,77 @@
+package bstio
+
+import (
+	"io"
+	"math"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// RemainingSizer is the interface of the readers that might know the number of the remaining input bytes.
+type RemainingSizer interface {
+	// RemainingSize returns the number of the remaining input bytes, if it is known.
+	RemainingSize() (int64, bool)
+}
+
+// RemainingSize returns the number of the remaining bytes of the reader, if the reader exposes it.
+// It is known for the readers implementing the RemainingSizer or Len() int (i.e. bytes.Reader).
+// The other io.Seeker readers, i.e. the os.File, are not asked for their size, as it would cost the seeks on every length read.
+func RemainingSize(r io.Reader) (int64, bool) {
+	switch rt := r.(type) {
+	case RemainingSizer:
+		return rt.RemainingSize()
+	case interface{ Len() int }:
+		return int64(rt.Len()), true
+	default:
+		return 0, false
+	}
+}
+
+// CheckLength verifies that the decoded length of a collection fits into the remaining input of the reader,
+// where each of its elements takes at least minElemSize bytes.
+// This allows failing early on malformed lengths, instead of attempting huge allocations.
+// A length exceeding the input is reported as the bsterr.CodeTruncatedBinary error, wrapping the TruncatedError.
+// If the reader does not expose its remaining size, or the minElemSize is not positive, the check is skipped.
+func CheckLength(r io.Reader, length uint, minElemSize int) error {
+	return checkLength(r, length, minElemSize, 0)
+}
+
+// ReadLength reads the varying size length header of a collection and verifies it with the CheckLength.
+// Returns the length along with the number of bytes read.
+func ReadLength(r io.Reader, desc bool, minElemSize int) (uint, int, error) {
+	length, n, err := ReadUint(r, desc)
+	if err != nil {
+		return 0, n, err
+	}
+	if err = checkLength(r, length, minElemSize, n); err != nil {
+		return 0, n, err
+	}
+	return length, n, nil
+}
+
+// checkLength is the CheckLength, where the read number of bytes of the value were already read.
+func checkLength(r io.Reader, length uint, minElemSize, read int) error {
+	if minElemSize <= 0 || length == 0 {
+		return nil
+	}
+	remaining, ok := RemainingSize(r)
+	if !ok || remaining < 0 {
+		return nil
+	}
+	if length <= uint(remaining)/uint(minElemSize) {
+		return nil
+	}
+
+	// 1. Compute the number of expected bytes, capped on overflow.
+	expected := math.MaxInt
+	if length <= uint(math.MaxInt-read)/uint(minElemSize) {
+		expected = read + int(length)*minElemSize
+	}
+	return bsterr.ErrWrap(&TruncatedError{Expected: expected, Read: read + int(remaining)}, bsterr.CodeTruncatedBinary,
+		"decoded length exceeds the remaining input").
+		WithDetails(
+			bsterr.D("length", length),
+			bsterr.D("minElemSize", minElemSize),
+			bsterr.D("remaining", remaining),
+		)
+}
@@ -0,0 +1,74 @@
+package bstio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestReadLength(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := WriteUint(&buf, math.MaxUint32, false); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	buf.Write([]byte{1, 2, 3})
+	data := buf.Bytes()
+
+	t.Run("Exceeding", func(t *testing.T) {
+		_, _, err := ReadLength(bytes.NewReader(data), false, 1)
+		var te *TruncatedError
+		if !errors.As(err, &te) {
+			t.Fatalf("expected truncated error, got: %v", err)
+		}
+		if te.Read != len(data) {
+			t.Fatalf("unexpected truncated read bytes: %d, expected: %d", te.Read, len(data))
+		}
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("expected io.ErrUnexpectedEOF, got: %v", err)
+		}
+	})
+
+	t.Run("Fitting", func(t *testing.T) {
+		ln, _, err := ReadLength(bytes.NewReader(data), false, 0)
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if ln != math.MaxUint32 {
+			t.Fatalf("unexpected length: %d", ln)
+		}
+	})
+
+	t.Run("UnknownSize", func(t *testing.T) {
+		if _, _, err := ReadLength(struct{ io.Reader }{bytes.NewReader(data)}, false, 1); err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+	})
+
+	t.Run("String", func(t *testing.T) {
+		_, _, err := ReadString(bytes.NewReader(data), false, false)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("expected io.ErrUnexpectedEOF, got: %v", err)
+		}
+	})
+}
+
+func TestRemainingSize(t *testing.T) {
+	r := bytes.NewReader([]byte{1, 2, 3, 4})
+	if _, err := r.Seek(1, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	n, ok := RemainingSize(r)
+	if !ok || n != 3 {
+		t.Fatalf("unexpected remaining size: %d, %v", n, ok)
+	}
+
+	// The plain seekers are not asked for their size.
+	if _, ok = RemainingSize(struct{ io.ReadSeeker }{r}); ok {
+		t.Fatal("remaining size of the seeker should not be known")
+	}
+	if _, ok = RemainingSize(struct{ io.Reader }{r}); ok {
+		t.Fatal("remaining size should not be known")
+	}
+}
@@ -0,0 +1,126 @@
+package bstio
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// MapDirectoryEntrySize is the binary size of a single entry of the map directory.
+const MapDirectoryEntrySize = 8
+
+// MapDirectoryEntry is the entry of the directory of the indexed map, locating the map entry by the hash of its key.
+// The binary of the entry is the key hash followed by the entry offset, both as big endian uint32.
+type MapDirectoryEntry struct {
+	// Hash is the MapKeyHash of the entry key binary.
+	Hash uint32
+	// Offset is the offset of the entry key, counted from the start of the map entries.
+	Offset uint32
+}
+
+// MapKeyHash returns the hash of the map key binary, used by the map directory.
+func MapKeyHash(key []byte) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write(key)
+	return h.Sum32()
+}
+
+// NewMapDirectoryEntry creates the directory entry of the map key binary, at given offset of the map entries.
+func NewMapDirectoryEntry(key []byte, offset int) (MapDirectoryEntry, error) {
+	if offset > math.MaxUint32 {
+		return MapDirectoryEntry{}, bsterr.Err(bsterr.CodeEncodingBinaryValue, "indexed map entries exceed the directory offset range").
+			WithDetail("offset", offset)
+	}
+	return MapDirectoryEntry{Hash: MapKeyHash(key), Offset: uint32(offset)}, nil
+}
+
+// AppendMapDirectory appends the binary of the map directory to the dst. The entries are sorted by their hashes,
+// so that the directory could be binary searched.
+func AppendMapDirectory(dst []byte, entries []MapDirectoryEntry) []byte {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Hash != entries[j].Hash {
+			return entries[i].Hash < entries[j].Hash
+		}
+		return entries[i].Offset < entries[j].Offset
+	})
+	for _, e := range entries {
+		dst = binary.BigEndian.AppendUint32(dst, e.Hash)
+		dst = binary.BigEndian.AppendUint32(dst, e.Offset)
+	}
+	return dst
+}
+
+// SearchMapDirectory finds the offsets of the entries whose keys have the hash, in the directory of length entries
+// starting at the current position of the rs. The position of the rs is undefined afterward.
+func SearchMapDirectory(rs io.ReadSeeker, length uint, hash uint32) ([]uint32, error) {
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to seek map directory")
+	}
+	var buf [MapDirectoryEntrySize]byte
+	readEntry := func(i int) (MapDirectoryEntry, error) {
+		if _, err := rs.Seek(start+int64(i)*MapDirectoryEntrySize, io.SeekStart); err != nil {
+			return MapDirectoryEntry{}, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to seek map directory entry")
+		}
+		if _, err := io.ReadFull(rs, buf[:]); err != nil {
+			return MapDirectoryEntry{}, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read map directory entry")
+		}
+		return MapDirectoryEntry{Hash: binary.BigEndian.Uint32(buf[:4]), Offset: binary.BigEndian.Uint32(buf[4:])}, nil
+	}
+
+	// 1. Binary search the first entry of the hash.
+	lo, hi := 0, int(length)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		e, err := readEntry(mid)
+		if err != nil {
+			return nil, err
+		}
+		if e.Hash < hash {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	// 2. Collect the offsets of all the entries of the hash, as the keys could collide.
+	var offsets []uint32
+	for i := lo; i < int(length); i++ {
+		e, err := readEntry(i)
+		if err != nil {
+			return nil, err
+		}
+		if e.Hash != hash {
+			break
+		}
+		offsets = append(offsets, e.Offset)
+	}
+	return offsets, nil
+}
+
+// ReadMapEntriesSize reads the binary size of the entries of the indexed map of given length, which precedes them.
+// The entries followed by their directory must fit the remaining input, otherwise the bsterr.CodeTruncatedBinary
+// error is returned. Returns the size of the entries along with the number of bytes read.
+func ReadMapEntriesSize(r io.Reader, length uint, desc bool) (uint, int, error) {
+	size, n, err := ReadUint(r, desc)
+	if err != nil {
+		return 0, n, err
+	}
+
+	// 1. The entries along with the directory could not exceed the max offset of the input.
+	if size > math.MaxInt || length > (uint(math.MaxInt)-size)/MapDirectoryEntrySize {
+		return 0, n, bsterr.Err(bsterr.CodeMalformedBinary, "indexed map entries size exceeds the max offset").
+			WithDetails(
+				bsterr.D("size", size),
+				bsterr.D("length", length),
+			)
+	}
+	if err = checkLength(r, size+length*MapDirectoryEntrySize, 1, n); err != nil {
+		return 0, n, err
+	}
+	return size, n, nil
+}
@@ -0,0 +1,168 @@
+package bstio
+
+// The dimension masks of the Z-order codes, where the bits of the first dimension are the most significant.
+const (
+	mortonMask2X = 0xAAAAAAAAAAAAAAAA
+	mortonMask2Y = 0x5555555555555555
+	mortonMask3X = 0x4924924924924924
+	mortonMask3Y = 0x2492492492492492
+	mortonMask3Z = 0x1249249249249249
+)
+
+// Morton2 interleaves the bits of two values into the Z-order (Morton) code, with the bits of x being more significant.
+// Written as the comparable Uint64 key segment, it keeps the points close in both dimensions close in the key order,
+// which gives the range scans spatial locality without a separate spatial index. See the MortonBox.
+// The signed and floating point values needs to be mapped with the OrderedInt32 or OrderedFloat32 first.
+func Morton2(x, y uint32) uint64 {
+	return spreadBits2(x)<<1 | spreadBits2(y)
+}
+
+// Unmorton2 returns the values interleaved by the Morton2.
+func Unmorton2(code uint64) (x, y uint32) {
+	return compactBits2(code >> 1), compactBits2(code)
+}
+
+// Morton3 interleaves the bits of three values into the Z-order (Morton) code, with the bits of x being the most significant.
+// Only the highest 21 bits of each value fit in the code, thus the values are truncated, which keeps their order,
+// but the values differing only in the lowest 11 bits have the same code.
+func Morton3(x, y, z uint32) uint64 {
+	return spreadBits3(x>>11)<<2 | spreadBits3(y>>11)<<1 | spreadBits3(z>>11)
+}
+
+// Unmorton3 returns the values interleaved by the Morton3, with their truncated lowest 11 bits set to zero.
+func Unmorton3(code uint64) (x, y, z uint32) {
+	return compactBits3(code>>2) << 11, compactBits3(code>>1) << 11, compactBits3(code) << 11
+}
+
+// OrderedInt32 maps the int32 value to the uint32 of the same order.
+func OrderedInt32(v int32) uint32 {
+	return uint32(v) ^ 1<<31
+}
+
+// spreadBits2 spreads the bits of v to the even bits of the result.
+func spreadBits2(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000FFFF0000FFFF
+	x = (x | x<<8) & 0x00FF00FF00FF00FF
+	x = (x | x<<4) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+// compactBits2 reverts the spreadBits2, ignoring the odd bits.
+func compactBits2(x uint64) uint32 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x>>4) & 0x00FF00FF00FF00FF
+	x = (x | x>>8) & 0x0000FFFF0000FFFF
+	x = (x | x>>16) & 0x00000000FFFFFFFF
+	return uint32(x)
+}
+
+// spreadBits3 spreads the lowest 21 bits of v to every third bit of the result.
+func spreadBits3(v uint32) uint64 {
+	x := uint64(v) & 0x1FFFFF
+	x = (x | x<<32) & 0x001F00000000FFFF
+	x = (x | x<<16) & 0x001F0000FF0000FF
+	x = (x | x<<8) & 0x100F00F00F00F00F
+	x = (x | x<<4) & 0x10C30C30C30C30C3
+	x = (x | x<<2) & 0x1249249249249249
+	return x
+}
+
+// compactBits3 reverts the spreadBits3, ignoring the other bits.
+func compactBits3(x uint64) uint32 {
+	x &= 0x1249249249249249
+	x = (x | x>>2) & 0x10C30C30C30C30C3
+	x = (x | x>>4) & 0x100F00F00F00F00F
+	x = (x | x>>8) & 0x001F0000FF0000FF
+	x = (x | x>>16) & 0x001F00000000FFFF
+	x = (x | x>>32) & 0x00000000001FFFFF
+	return uint32(x)
+}
+
+// MortonBox is the box of the points, defined by the Z-order codes of its minimum and maximum corners.
+// The codes of all the points within the box are in the range of the Min and Max codes, but not all the codes
+// in this range are within the box. The range scan skips them by seeking to the Next code within the box.
+type MortonBox struct {
+	Min, Max uint64
+	masks    []uint64
+}
+
+// NewMortonBox2 creates the box of the points encoded with the Morton2.
+func NewMortonBox2(minX, minY, maxX, maxY uint32) MortonBox {
+	return MortonBox{
+		Min:   Morton2(minX, minY),
+		Max:   Morton2(maxX, maxY),
+		masks: []uint64{mortonMask2X, mortonMask2Y},
+	}
+}
+
+// NewMortonBox3 creates the box of the points encoded with the Morton3.
+func NewMortonBox3(minX, minY, minZ, maxX, maxY, maxZ uint32) MortonBox {
+	return MortonBox{
+		Min:   Morton3(minX, minY, minZ),
+		Max:   Morton3(maxX, maxY, maxZ),
+		masks: []uint64{mortonMask3X, mortonMask3Y, mortonMask3Z},
+	}
+}
+
+// Contains returns true if the point of the code is within the box.
+func (x MortonBox) Contains(code uint64) bool {
+	// The bits of each dimension are in the same positions, thus their masked codes keep the order of the values.
+	for _, m := range x.masks {
+		if v := code & m; v < x.Min&m || v > x.Max&m {
+			return false
+		}
+	}
+	return true
+}
+
+// Next returns the smallest code greater than the given one, which is within the box.
+// It returns false if there is no such code.
+func (x MortonBox) Next(code uint64) (uint64, bool) {
+	// 1. Check the next code, as it is often within the box during the scan.
+	if code >= x.Max {
+		return 0, false
+	}
+	code++
+	if x.Contains(code) {
+		return code, true
+	}
+
+	// 2. Find the next code within the box, with the BIGMIN algorithm of Tropf and Herzog.
+	var (
+		next     uint64
+		found    bool
+		min, max = x.Min, x.Max
+	)
+	for bit := 63; bit >= 0; bit-- {
+		mask := uint64(1) << bit
+		var lower uint64
+		for _, m := range x.masks {
+			if m&mask != 0 {
+				lower = m & (mask - 1)
+				break
+			}
+		}
+
+		switch cb, nb, xb := code&mask != 0, min&mask != 0, max&mask != 0; {
+		case !cb && !nb && xb:
+			// 2.1. The next code is either in the lower half, or it is the minimum of the upper half.
+			next, found = (min&^lower)|mask, true
+			max = (max &^ mask) | lower
+		case !cb && nb && xb:
+			// 2.2. The whole box is above the code.
+			return min, true
+		case cb && !nb && !xb:
+			// 2.3. The whole box is below the code.
+			return next, found
+		case cb && !nb && xb:
+			// 2.4. The code is in the upper half.
+			min = (min &^ lower) | mask
+		}
+	}
+	return next, found
+}
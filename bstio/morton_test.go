@@ -0,0 +1,69 @@
+package bstio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMorton(t *testing.T) {
+	for _, v := range [][3]uint32{{0, 0, 0}, {1, 2, 3}, {math.MaxUint32, 0, 0x12345678}, {0xDEADBEEF, math.MaxUint32, 1 << 31}} {
+		if x, y := Unmorton2(Morton2(v[0], v[1])); x != v[0] || y != v[1] {
+			t.Fatalf("unexpected Morton2 round trip of %v: %v, %v", v, x, y)
+		}
+		x, y, z := Unmorton3(Morton3(v[0], v[1], v[2]))
+		if x != v[0]&^0x7FF || y != v[1]&^0x7FF || z != v[2]&^0x7FF {
+			t.Fatalf("unexpected Morton3 round trip of %v: %v, %v, %v", v, x, y, z)
+		}
+	}
+
+	// The bits of the first value are the most significant.
+	if Morton2(1, 0) != 2 || Morton2(0, 1) != 1 || Morton3(1<<11, 0, 0) != 4 {
+		t.Fatal("unexpected interleaving order")
+	}
+}
+
+func TestOrderedValues(t *testing.T) {
+	ints := []int32{math.MinInt32, -5, -1, 0, 1, 7, math.MaxInt32}
+	floats := []float32{float32(math.Inf(-1)), -2.5, -1, -0.5, 0, 0.5, 1, 2.5, float32(math.Inf(1))}
+	for i := 1; i < len(ints); i++ {
+		if OrderedInt32(ints[i-1]) >= OrderedInt32(ints[i]) {
+			t.Fatalf("ordered %d is not less than ordered %d", ints[i-1], ints[i])
+		}
+	}
+	for i := 1; i < len(floats); i++ {
+		if OrderedFloat32(floats[i-1]) >= OrderedFloat32(floats[i]) {
+			t.Fatalf("ordered %v is not less than ordered %v", floats[i-1], floats[i])
+		}
+	}
+}
+
+func TestMortonBox(t *testing.T) {
+	boxes := []MortonBox{
+		NewMortonBox2(3, 5, 10, 6),
+		NewMortonBox2(0, 0, 15, 15),
+		NewMortonBox2(7, 1, 8, 14),
+		NewMortonBox3(1<<11, 2<<11, 0, 5<<11, 3<<11, 6<<11),
+		NewMortonBox3(3<<11, 3<<11, 3<<11, 4<<11, 4<<11, 4<<11),
+	}
+	for bi, box := range boxes {
+		// The Next code is the same as found by checking all the codes one by one.
+		for code := uint64(0); code <= box.Max+1; code++ {
+			expected, expectedOk := uint64(0), false
+			for c := code + 1; c <= box.Max; c++ {
+				if box.Contains(c) {
+					expected, expectedOk = c, true
+					break
+				}
+			}
+			got, ok := box.Next(code)
+			if got != expected || ok != expectedOk {
+				t.Fatalf("box %d: unexpected next of %d: %d (%v), expected: %d (%v)", bi, code, got, ok, expected, expectedOk)
+			}
+		}
+	}
+
+	x, y := uint32(4), uint32(5)
+	if !NewMortonBox2(3, 5, 10, 6).Contains(Morton2(x, y)) || NewMortonBox2(3, 5, 10, 6).Contains(Morton2(x, 7)) {
+		t.Fatal("unexpected box containment")
+	}
+}
@@ -2,8 +2,6 @@ package bstio
 
 import (
 	"io"
-
-	"github.com/devmodules/bst/bsterr"
 )
 
 // ReadNullableFlag reads the nullable flag from the reader.
@@ -13,8 +11,7 @@ func ReadNullableFlag(r io.Reader, descending bool) (byte, error) {
 	// 1. Read a nullable flag byte.
 	nf, err := ReadByte(r)
 	if err != nil {
-		return 0, bsterr.Err(bsterr.CodeDecodingBinaryValue, "failed to read nullable flag byte").
-			WithDetail("error", err.Error())
+		return 0, readError(err, 0, 1, "failed to read nullable flag byte")
 	}
 	if descending {
 		nf = ^nf
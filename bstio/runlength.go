@@ -0,0 +1,116 @@
+package bstio
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// WriteRunLength writes the values binary, composed of the values of given size, as the runs of equal consecutive values.
+// Each run is composed of the run length (uint) followed by the value binary.
+// The desc flag determines the order of the run lengths, the values are written as they are.
+func WriteRunLength(w io.Writer, values []byte, size int, desc bool) (int, error) {
+	// 1. Verify the values binary.
+	if size <= 0 || len(values)%size != 0 {
+		return 0, bsterr.Err(bsterr.CodeEncodingBinaryValue, "invalid run length values binary").
+			WithDetails(bsterr.D("size", size), bsterr.D("length", len(values)))
+	}
+
+	var bytesWritten int
+	for start := 0; start < len(values); {
+		// 2. Find the end of the run.
+		v := values[start : start+size]
+		end := start + size
+		for end < len(values) && bytes.Equal(values[end:end+size], v) {
+			end += size
+		}
+
+		// 3. Write the run length and its value.
+		n, err := WriteUint(w, uint((end-start)/size), desc)
+		if err != nil {
+			return bytesWritten + n, err
+		}
+		bytesWritten += n
+
+		n, err = w.Write(v)
+		if err != nil {
+			return bytesWritten + n, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write run length value")
+		}
+		bytesWritten += n
+		start = end
+	}
+	return bytesWritten, nil
+}
+
+// ReadRunLength reads the runs of the length values of given size, and returns their expanded binary,
+// just as it was passed to the WriteRunLength.
+// The desc flag determines the order of the run lengths.
+func ReadRunLength(r io.Reader, length uint, size int, desc bool) ([]byte, int, error) {
+	if size <= 0 {
+		return nil, 0, bsterr.Err(bsterr.CodeDecodingBinaryValue, "invalid run length value size").
+			WithDetail("size", size)
+	}
+
+	var (
+		bytesRead int
+		data      []byte
+		v         = make([]byte, size)
+	)
+	for remaining := length; remaining > 0; {
+		// 1. Read the run length and verify it against the remaining number of values.
+		rl, n, err := readRunHeader(r, remaining, desc)
+		bytesRead += n
+		if err != nil {
+			return nil, bytesRead, err
+		}
+
+		// 2. Read the run value.
+		n, err = readFull(r, v, 0, "failed to read run length value")
+		bytesRead += n
+		if err != nil {
+			return nil, bytesRead, err
+		}
+
+		// 3. Expand the run.
+		for i := uint(0); i < rl; i++ {
+			data = append(data, v...)
+		}
+		remaining -= rl
+	}
+	return data, bytesRead, nil
+}
+
+// SkipRunLength skips the runs of the length values of given size.
+// The desc flag determines the order of the run lengths.
+func SkipRunLength(rs io.ReadSeeker, length uint, size int, desc bool) (int64, error) {
+	var bytesSkipped int64
+	for remaining := length; remaining > 0; {
+		// 1. Read the run length and verify it against the remaining number of values.
+		rl, n, err := readRunHeader(rs, remaining, desc)
+		bytesSkipped += int64(n)
+		if err != nil {
+			return bytesSkipped, err
+		}
+
+		// 2. Skip the run value.
+		if _, err = rs.Seek(int64(size), io.SeekCurrent); err != nil {
+			return bytesSkipped, bsterr.ErrWrap(err, bsterr.CodeSkippingBinaryValue, "failed to skip run length value")
+		}
+		bytesSkipped += int64(size)
+		remaining -= rl
+	}
+	return bytesSkipped, nil
+}
+
+func readRunHeader(r io.Reader, remaining uint, desc bool) (uint, int, error) {
+	rl, n, err := ReadUint(r, desc)
+	if err != nil {
+		return 0, n, err
+	}
+	if rl == 0 || rl > remaining {
+		return 0, n, bsterr.Err(bsterr.CodeMalformedBinary, "invalid run length").
+			WithDetails(bsterr.D("runLength", rl), bsterr.D("remaining", remaining))
+	}
+	return rl, n, nil
+}
@@ -0,0 +1,22 @@
+package bstio
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// SortSetElements sorts the binaries of the set elements, so that the sets of the same elements have equal binaries.
+// It returns an error if any of the elements is duplicated.
+func SortSetElements(elems [][]byte) error {
+	sort.Slice(elems, func(i, j int) bool {
+		return bytes.Compare(elems[i], elems[j]) < 0
+	})
+	for i := 1; i < len(elems); i++ {
+		if bytes.Equal(elems[i-1], elems[i]) {
+			return bsterr.Err(bsterr.CodeInvalidValue, "duplicate set element").WithDetail("element", elems[i])
+		}
+	}
+	return nil
+}
@@ -63,7 +63,7 @@ func writeInt8Writer(w io.Writer, v int8, desc bool) (int, error) {
 func ReadInt8(r io.Reader, desc bool) (int8, int, error) {
 	bt, err := ReadByte(r)
 	if err != nil {
-		return 0, 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read int8 value")
+		return 0, 0, readError(err, 0, 1, "failed to read int8 value")
 	}
 	v, err := ParseInt8(bt, desc)
 	if err != nil {
@@ -110,6 +110,14 @@ func ParseSignedValueMSB(bt byte) byte {
 // Positive values has the highest bit set to 1, whereas negative values have the highest bit set to 0.
 // This ensures comparability of the values on the bytes level.
 func WriteInt16(w io.Writer, v int16, desc bool) (int, error) {
+	if b, ok := fixedBuffer(w, 2); ok {
+		uv := uint16(v) ^ (1 << 15)
+		if desc {
+			uv = ^uv
+		}
+		binary.BigEndian.PutUint16(b, uv)
+		return writeFixed(w, b, "failed to write int16 value")
+	}
 	if bw, ok := w.(io.ByteWriter); ok {
 		return writeInt16ByteWriter(bw, v, desc)
 	}
@@ -199,6 +207,13 @@ func MarshalInt16(v int16, desc bool) []byte {
 // Positive values has the highest bit set to 1, whereas negative values have the highest bit set to 0.
 // This ensures comparability of the values on the bytes level.
 func ReadInt16(r io.Reader, desc bool) (int16, int, error) {
+	if b, ok := fixedBytes(r, 2); ok {
+		uv := binary.BigEndian.Uint16(b)
+		if desc {
+			uv = ^uv
+		}
+		return int16(uv ^ (1 << 15)), 2, nil
+	}
 	if br, ok := r.(io.ByteReader); ok {
 		return readInt16ByteReader(br, desc)
 	}
@@ -207,9 +222,9 @@ func ReadInt16(r io.Reader, desc bool) (int16, int, error) {
 
 func readInt16Reader(r io.Reader, desc bool) (int16, int, error) {
 	bl := make([]byte, 2)
-	n, err := r.Read(bl)
+	n, err := readFull(r, bl, 0, "failed to read int16 value")
 	if err != nil {
-		return 0, n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read int16 value")
+		return 0, n, err
 	}
 
 	if desc {
@@ -235,7 +250,7 @@ func readInt16ByteReader(r io.ByteReader, desc bool) (int16, int, error) {
 
 		b, er := r.ReadByte()
 		if er != nil {
-			err = bsterr.ErrWrap(er, bsterr.CodeDecodingBinaryValue, "failed to read int16 value")
+			err = readError(er, n, 2, "failed to read int16 value")
 			return 0
 		}
 		n++
@@ -288,6 +303,14 @@ func SkipInt16(s io.ReadSeeker) (int64, error) {
 // Positive values has the highest bit set to 1, whereas negative values have the highest bit set to 0.
 // This ensures comparability of the values on the bytes level.
 func WriteInt32(w io.Writer, iv int32, desc bool) (int, error) {
+	if b, ok := fixedBuffer(w, 4); ok {
+		uv := uint32(iv) ^ (1 << 31)
+		if desc {
+			uv = ^uv
+		}
+		binary.BigEndian.PutUint32(b, uv)
+		return writeFixed(w, b, "failed to write int32 value")
+	}
 	if bw, ok := w.(io.ByteWriter); ok {
 		return writeInt32ByteWriter(bw, iv, desc)
 	}
@@ -358,6 +381,13 @@ func writeInt32ByteWriter(w io.ByteWriter, iv int32, desc bool) (int, error) {
 // Positive values has the highest bit set to 1, whereas negative values have the highest bit set to 0.
 // This ensures comparability of the values on the bytes level.
 func ReadInt32(r io.Reader, desc bool) (int32, int, error) {
+	if b, ok := fixedBytes(r, 4); ok {
+		uv := binary.BigEndian.Uint32(b)
+		if desc {
+			uv = ^uv
+		}
+		return int32(uv ^ (1 << 31)), 4, nil
+	}
 	if br, ok := r.(io.ByteReader); ok {
 		return readInt32ByteReader(br, desc)
 	}
@@ -375,7 +405,7 @@ func readInt32ByteReader(br io.ByteReader, desc bool) (int32, int, error) {
 		}
 		b, er := br.ReadByte()
 		if er != nil {
-			err = bsterr.ErrWrap(er, bsterr.CodeReadingFailed, "failed to read int32 value")
+			err = readError(er, n, 4, "failed to read int32 value")
 			return 0
 		}
 		n++
@@ -393,14 +423,14 @@ func readInt32ByteReader(br io.ByteReader, desc bool) (int32, int, error) {
 	} else {
 		uv &= ^uint32(1 << 31)
 	}
-	return int32(uv), n, nil
+	return int32(uv), n, err
 }
 
 func readInt32Reader(r io.Reader, desc bool) (int32, int, error) {
 	bl := make([]byte, 4)
-	n, err := r.Read(bl)
+	n, err := readFull(r, bl, 0, "failed to read int32 value")
 	if err != nil {
-		return 0, n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read int32 value")
+		return 0, n, err
 	}
 
 	uv := uint32(bl[0])<<24 | uint32(bl[1])<<16 | uint32(bl[2])<<8 | uint32(bl[3])
@@ -475,6 +505,13 @@ func MarshalInt32(i32 int32, desc bool) []byte {
 // Positive values has the highest bit set to 1, whereas negative values have the highest bit set to 0.
 // This ensures comparability of the values on the bytes level.
 func ReadInt64(r io.Reader, desc bool) (int64, int, error) {
+	if b, ok := fixedBytes(r, 8); ok {
+		uv := binary.BigEndian.Uint64(b)
+		if desc {
+			uv = ^uv
+		}
+		return int64(uv ^ (1 << 63)), 8, nil
+	}
 	if br, ok := r.(io.ByteReader); ok {
 		return readInt64ByteReader(br, desc)
 	}
@@ -483,9 +520,9 @@ func ReadInt64(r io.Reader, desc bool) (int64, int, error) {
 
 func readInt64Reader(r io.Reader, desc bool) (int64, int, error) {
 	bl := make([]byte, 8)
-	n, err := r.Read(bl)
+	n, err := readFull(r, bl, 0, "failed to read int64 value")
 	if err != nil {
-		return 0, n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read int64 value")
+		return 0, n, err
 	}
 
 	uv := uint64(bl[0])<<56 | uint64(bl[1])<<48 | uint64(bl[2])<<40 | uint64(bl[3])<<32 |
@@ -516,9 +553,9 @@ func readInt64ByteReader(r io.ByteReader, desc bool) (int64, int, error) {
 		if err != nil {
 			return 0
 		}
-		var b byte
-		b, err = r.ReadByte()
-		if err != nil {
+		b, er := r.ReadByte()
+		if er != nil {
+			err = readError(er, n, 8, "failed to read int64 value")
 			return 0
 		}
 		n++
@@ -545,6 +582,14 @@ func readInt64ByteReader(r io.ByteReader, desc bool) (int64, int, error) {
 // Positive values has the highest bit set to 1, whereas negative values have the highest bit set to 0.
 // This ensures comparability of the values on the bytes level.
 func WriteInt64(w io.Writer, iv int64, desc bool) (int, error) {
+	if b, ok := fixedBuffer(w, 8); ok {
+		uv := uint64(iv) ^ (1 << 63)
+		if desc {
+			uv = ^uv
+		}
+		binary.BigEndian.PutUint64(b, uv)
+		return writeFixed(w, b, "failed to write int64 value")
+	}
 	if bw, ok := w.(io.ByteWriter); ok {
 		return writeInt64ByteWriter(bw, iv, desc)
 	}
@@ -2,12 +2,13 @@ package bstio
 
 import (
 	"bytes"
+	"encoding/binary"
+	"hash/fnv"
 	"io"
 	"reflect"
 	"unsafe"
 
 	"github.com/devmodules/bst/bsterr"
-	"github.com/devmodules/bst/internal/iopool"
 )
 
 // WriteString encodes and writes an input string to the writer in the binary representation.
@@ -18,16 +19,26 @@ import (
 // In non-comparable mode, the string's length is firstly encoded, and then the string's bytes are written.
 // A non-comparable mode is faster and more efficient in memory allocations, but it is not guaranteed to be comparable.
 func WriteString(w io.Writer, s string, desc, comparable bool) (int, error) {
+	return WriteStringAlloc(w, s, desc, comparable, nil)
+}
+
+// WriteStringAlloc encodes and writes the string just as the WriteString, taking its scratch byte slices
+// out of the allocator. The nil allocator allocates them on the heap.
+func WriteStringAlloc(w io.Writer, s string, desc, comparable bool, a Allocator) (int, error) {
 	if comparable {
-		return WriteStringComparable(w, s, desc)
+		return writeStringComparable(w, s, desc, a)
 	}
-	return WriteStringNonComparable(w, s, desc)
+	return writeStringNonComparable(w, s, desc, a)
 }
 
 // WriteStringNonComparable encodes and writes an input string to the writer in the binary representation.
 // If the desc flag is set to true, the string is encoded in descending order.
 // A non-comparable string at first encodes the length of the string and then it's bytes.
 func WriteStringNonComparable(w io.Writer, v string, desc bool) (int, error) {
+	return writeStringNonComparable(w, v, desc, nil)
+}
+
+func writeStringNonComparable(w io.Writer, v string, desc bool, a Allocator) (int, error) {
 	// 1. Write the length of the string.
 	bytesWritten, err := WriteUint(w, uint(len(v)), desc)
 	if err != nil {
@@ -42,8 +53,9 @@ func WriteStringNonComparable(w io.Writer, v string, desc bool) (int, error) {
 	// 3. Treat the input differently for ascending and descending order.
 	var bts []byte
 	if desc {
-		// 3.1. For the descending bytes we need to modify the input string and ReverseBytes its bytes.
-		bts = []byte(v)
+		// 3.1. For the descending bytes we need to copy the input string and ReverseBytes its bytes.
+		bts = append(allocBytes(a, len(v)), v...)
+		defer freeBytes(a, bts)
 		ReverseBytes(bts)
 	} else {
 		// 3.2. Ascending order does not require any modifications, and we can unsafely cast the string to bytes.
@@ -70,6 +82,10 @@ func UnsafeStringToBytes(v string) []byte {
 // A comparable-mode is slightly slower, and is based on the specific bytes escapes, however guarantees that the
 // raw binary representation could be compared to other binary representations.
 func WriteStringComparable(w io.Writer, s string, desc bool) (int, error) {
+	return writeStringComparable(w, s, desc, nil)
+}
+
+func writeStringComparable(w io.Writer, s string, desc bool, a Allocator) (int, error) {
 	// 1. Convert the string to a byte slice.
 	if s == "" {
 		return WriteEmptyComparableBytes(w, desc)
@@ -91,11 +107,15 @@ func WriteStringComparable(w io.Writer, s string, desc bool) (int, error) {
 			break
 		}
 
+		if b == nil {
+			b = allocBytes(a, len(data)+2)
+		}
 		b = append(b, temp[:i]...)
 		b = append(b, 0x00, 0xff)
 		temp = temp[i+1:]
 		modified = true
 	}
+	defer func() { freeBytes(a, b) }()
 
 	// 4. If the value was not modified, and the value is stored in ascending order
 	//    we can directly write the value to the writer.
@@ -116,27 +136,25 @@ func WriteStringComparable(w io.Writer, s string, desc bool) (int, error) {
 		return n + 2, nil
 	}
 
-	// 5. If the value was not modified (data is still unsafe bytes), we need to convert it to a safe byte slice.
-	if !modified && desc {
-		temp = []byte(s)
-	}
-
-	// 6. Write the first buffer part to the writer.
+	// 5. The rest of the string is still unsafe bytes, thus for descending order it needs to be copied
+	//    along with the escaped part, before being reversed.
 	if desc {
-		// 6.1. Reverse it for descending order.
+		if b == nil {
+			b = allocBytes(a, len(data)+2)
+		}
+		b = append(b, temp...)
 		ReverseBytes(b)
+		temp = nil
 	}
+
+	// 6. Write the escaped part to the writer.
 	n, err := w.Write(b)
 	if err != nil {
 		return n, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write string value")
 	}
 	bytesWritten := n
 
-	// 7. Write the second buffer part to the writer.
-	if desc {
-		// 7.1. Reverse it for descending order.
-		ReverseBytes(temp)
-	}
+	// 7. Write the rest of the string to the writer.
 	n, err = w.Write(temp)
 	if err != nil {
 		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write string value")
@@ -144,40 +162,22 @@ func WriteStringComparable(w io.Writer, s string, desc bool) (int, error) {
 	bytesWritten += n
 
 	// 8. Finish up with the escape and terminator.
-	if desc {
-		if err = WriteByte(w, 0xff); err != nil {
-			return bytesWritten, err
-		}
-		if err = WriteByte(w, 0x00); err != nil {
-			return bytesWritten + 1, err
-		}
-	} else {
-		if err = WriteByte(w, 0x00); err != nil {
-			return bytesWritten, err
-		}
-		if err = WriteByte(w, 0xff); err != nil {
-			return bytesWritten + 1, err
-		}
-	}
-	return bytesWritten + 2, nil
+	n, err = WriteEmptyComparableBytes(w, desc)
+	return bytesWritten + n, err
 }
 
 // WriteEmptyComparableBytes writes up empty comparable bytes to the writer.
+// It is the bytes escape followed by the terminator, which also finishes up any non-empty comparable bytes value.
 func WriteEmptyComparableBytes(w io.Writer, desc bool) (int, error) {
+	escape := BytesEscapeAscending
 	if desc {
-		if err := WriteByte(w, 0xff); err != nil {
-			return 0, err
-		}
-		if err := WriteByte(w, 0x00); err != nil {
-			return 1, err
-		}
-	} else {
-		if err := WriteByte(w, 0x00); err != nil {
-			return 0, err
-		}
-		if err := WriteByte(w, 0xff); err != nil {
-			return 1, err
-		}
+		escape = BytesEscapeDescending
+	}
+	if err := WriteByte(w, escape.escape); err != nil {
+		return 0, err
+	}
+	if err := WriteByte(w, escape.escapedTerm); err != nil {
+		return 1, err
 	}
 	return 2, nil
 }
@@ -238,6 +238,42 @@ func StringBinarySize(v string, comparable bool) uint {
 	return uint(UintBinarySize(uint(len(v))) + len(v))
 }
 
+// BoundedStringHashSize is the size of the hash suffix of the bounded string.
+const BoundedStringHashSize = 8
+
+// BoundString replaces the string longer than maxLen bytes with its prefix, followed by the FNV-1a hash
+// of the whole string, so that the result is exactly maxLen bytes long. Shorter strings are returned unchanged.
+// The maxLen needs to be greater than the BoundedStringHashSize.
+//
+// It is used to bound the size of the comparable string keys. The bounded strings keep their order
+// only approximately:
+//   - Strings that differ within the first maxLen-BoundedStringHashSize bytes keep their order.
+//   - Strings that share the prefix are ordered by their hashes, and are not ordered with respect to the
+//     strings of the length between the prefix length and maxLen that share the same prefix.
+//   - Equal strings always produce equal results, but two different long strings with the same prefix
+//     collide (are encoded equally) if their hashes are equal, with a probability of about 2^-64.
+//     A string of exactly maxLen bytes collides with a bounded one only if its suffix matches the hash.
+//
+// The bounded strings cannot be decoded back to the original values, thus the index entries keyed by them
+// should store the original value, or a reference to it, to resolve the collisions.
+func BoundString(s string, maxLen int) string {
+	// 1. Short strings are not modified.
+	if len(s) <= maxLen || maxLen <= BoundedStringHashSize {
+		return s
+	}
+
+	// 2. Compute the hash of the whole string.
+	h := fnv.New64a()
+	_, _ = h.Write(UnsafeStringToBytes(s))
+
+	// 3. Replace the tail of the string with the big-endian hash.
+	prefix := maxLen - BoundedStringHashSize
+	b := make([]byte, maxLen)
+	copy(b, s[:prefix])
+	binary.BigEndian.PutUint64(b[prefix:], h.Sum64())
+	return string(b)
+}
+
 // EncodeStringNonComparable encodes the string in the binary format and writes it to the writer.
 func EncodeStringNonComparable(v string, desc bool) []byte {
 	bl := MarshalUint(uint(len(v)), desc)
@@ -261,114 +297,46 @@ func ReadString(r io.Reader, desc, comparable bool) (string, int, error) {
 	return ReadStringNonComparable(r, desc)
 }
 
+// ReadStringAppend reads the binary representation of the string from the reader, just like the ReadString,
+// but appends the string bytes to the dst and returns the extended slice.
+// Reusing the dst across the calls avoids the allocation of the string. On error, the dst is returned unchanged.
+func ReadStringAppend(dst []byte, r io.Reader, desc, comparable bool) ([]byte, int, error) {
+	if comparable {
+		return ReadStringComparableAppend(dst, r, desc)
+	}
+	return ReadStringNonComparableAppend(dst, r, desc)
+}
+
 // ReadStringNonComparable reads the binary representation of the non-comparable string from the reader.
 // The reader must be positioned at the start of the string.
 // If the desc flag is true, the string is expected to be encoded in descending order.
 func ReadStringNonComparable(r io.Reader, desc bool) (string, int, error) {
-	// 1. Read the length of the string.
-	length, n, err := ReadUint(r, desc)
+	bl, n, err := ReadStringNonComparableAppend(nil, r, desc)
 	if err != nil {
 		return "", n, err
 	}
+	return UnsafeBytesToString(bl), n, nil
+}
 
-	if length == 0 {
-		return "", n, nil
-	}
-
-	// 2. Read the string.
-	bl := make([]byte, length)
-	var total int
-	total, err = r.Read(bl)
-	if err != nil {
-		return "", n + total, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read string value")
-	}
-
-	// 3. If the value is encoded in descending order, ReverseBytes the bytes.
-	if desc {
-		ReverseBytes(bl)
-	}
-
-	// 4. Return the string.
-	return UnsafeBytesToString(bl), n + total, nil
+// ReadStringNonComparableAppend reads the binary representation of the non-comparable string from the reader,
+// and appends the string bytes to the dst. On error, the dst is returned unchanged.
+func ReadStringNonComparableAppend(dst []byte, r io.Reader, desc bool) ([]byte, int, error) {
+	return ReadBytesNonComparableAppend(dst, r, 0, desc)
 }
 
 // ReadStringComparable reads the binary representation of the comparable string from the reader.
 // The reader must be positioned at the start of the string.
 // If the desc flag is true, the string is expected to be encoded in descending order.
 func ReadStringComparable(r io.Reader, desc bool) (string, int, error) {
-	escape := BytesEscapeAscending
-	if desc {
-		escape = BytesEscapeDescending
-	}
-	if rs, ok := r.(io.ReadSeeker); ok {
-		return readStringValueComparableReadSeeker(rs, desc, escape)
-	}
-	return readStringValueComparableReader(r, desc, escape)
-}
-
-func readStringValueComparableReader(r io.Reader, desc bool, escape escapes) (string, int, error) {
-	// 1. Initialize the result buffer.
-	buf := iopool.GetBuffer(nil)
-	defer iopool.ReleaseBuffer(buf)
-
-	var n int
-	// 2. Iterate byte by byte over the reader until we reach the escape terminator.
-	for {
-		// 2.1. Read the next byte.
-		b, err := ReadByte(r)
-		if err != nil {
-			return "", n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read string value")
-		}
-		n++
-
-		if err = buf.WriteByte(b); err != nil {
-			return "", n, err
-		}
-
-		// 2.2. If the byte is not the escape, continue.
-		if b != escape.escape {
-			continue
-		}
-
-		// 2.3. Read the next byte.
-		b, err = ReadByte(r)
-		if err != nil {
-			return "", n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "malformed string binary value")
-		}
-		n++
-
-		// 2.4. Check if the next byte is a terminator byte, so that we can stop iterating.
-		if b == escape.escapedTerm {
-			break
-		}
-
-		// 2.5. If the next byte is not the escape, check consistency.
-		if b != escape.escaped00 {
-			return "", n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "malformed string binary value")
-		}
-
-		// 2.6. Write escaped byte and continue iteration.
-		if err = buf.WriteByte(escape.escapedFF); err != nil {
-			return "", n, err
-		}
-	}
-
-	if desc {
-		ReverseBytes(buf.Bytes)
+	bl, n, err := ReadStringComparableAppend(nil, r, desc)
+	if err != nil {
+		return "", n, err
 	}
-
-	// 3. In this position a string had to be decoded properly.
-	//    Return the string and the number of bytes read.
-	return UnsafeBytesToString(buf.Bytes), n, nil
+	return UnsafeBytesToString(bl), n, nil
 }
 
-func readStringValueComparableReadSeeker(rs io.ReadSeeker, desc bool, escape escapes) (string, int, error) {
-	bt, n, err := ReadComparableBytesSeeker(rs, desc, 16, escape)
-	if err != nil {
-		return "", n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read string value")
-	}
-	if len(bt) == 0 {
-		return "", n, nil
-	}
-	return UnsafeBytesToString(bt), n, nil
+// ReadStringComparableAppend reads the binary representation of the comparable string from the reader,
+// and appends the string bytes to the dst. On error, the dst is returned unchanged.
+func ReadStringComparableAppend(dst []byte, r io.Reader, desc bool) ([]byte, int, error) {
+	return readComparableBytesAppend(dst, r, desc, 16)
 }
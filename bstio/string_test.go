@@ -3,6 +3,7 @@ package bstio
 import (
 	"bytes"
 	"io"
+	"strings"
 	"testing"
 )
 
@@ -96,3 +97,169 @@ func BenchmarkString(b *testing.B) {
 		}
 	})
 }
+
+func TestBoundString(t *testing.T) {
+	const maxLen = 16
+
+	t.Run("Short", func(t *testing.T) {
+		if got := BoundString("short", maxLen); got != "short" {
+			t.Fatalf("short string was modified: %q", got)
+		}
+	})
+
+	t.Run("Long", func(t *testing.T) {
+		a := BoundString("a very long string value 1", maxLen)
+		b := BoundString("a very long string value 2", maxLen)
+		if len(a) != maxLen || len(b) != maxLen {
+			t.Fatalf("unexpected bounded lengths: %d, %d", len(a), len(b))
+		}
+		if a[:maxLen-BoundedStringHashSize] != "a very l" {
+			t.Fatalf("unexpected bounded prefix: %q", a[:maxLen-BoundedStringHashSize])
+		}
+		if a == b {
+			t.Fatal("different strings with shared prefix are bounded equally")
+		}
+		if a != BoundString("a very long string value 1", maxLen) {
+			t.Fatal("equal strings are bounded differently")
+		}
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		a := BoundString("aaaaaaaaaaaaaaaaaaaaaaaa", maxLen)
+		b := BoundString("aaaaaaabzzzzzzzzzzzzzzzzz", maxLen)
+
+		var ba, bb bytes.Buffer
+		if _, err := WriteStringComparable(&ba, a, false); err != nil {
+			t.Fatalf("writing string failed: %v", err)
+		}
+		if _, err := WriteStringComparable(&bb, b, false); err != nil {
+			t.Fatalf("writing string failed: %v", err)
+		}
+		if bytes.Compare(ba.Bytes(), bb.Bytes()) >= 0 {
+			t.Fatal("bounded strings with different prefixes lost their order")
+		}
+	})
+}
+
+func TestStringComparable(t *testing.T) {
+	// The values are sorted in ascending order.
+	values := []string{
+		"",
+		"\x00",
+		"\x00\x00",
+		"\x00\x01",
+		"a",
+		"a\x00",
+		"a\x00b",
+		strings.Repeat("b", 15) + "\x00" + strings.Repeat("c", 40),
+		"\xff",
+	}
+	for _, desc := range []bool{false, true} {
+		var prev []byte
+		for i, v := range values {
+			// 1. Write the value followed by a trailing byte, which must not be consumed.
+			var buf bytes.Buffer
+			n, err := WriteString(&buf, v, desc, true)
+			if err != nil {
+				t.Fatalf("%q: writing failed: %v", v, err)
+			}
+			data := append(buf.Bytes(), 0x7a)
+			if n != len(data)-1 {
+				t.Fatalf("%q: unexpected number of bytes written: %d", v, n)
+			}
+
+			// 2. Verify the order of the binaries.
+			if i > 0 {
+				cmp := bytes.Compare(prev, data[:n])
+				if desc {
+					cmp = -cmp
+				}
+				if cmp >= 0 {
+					t.Fatalf("desc: %v, %q: binary %x is not ordered after %x", desc, v, data[:n], prev)
+				}
+			}
+			prev = data[:n]
+
+			// 3. Read the value from the read seeker and from the plain reader.
+			for _, r := range []io.Reader{bytes.NewReader(data), struct{ io.Reader }{bytes.NewReader(data)}} {
+				got, rn, err := ReadString(r, desc, true)
+				if err != nil {
+					t.Fatalf("%q: reading failed: %v", v, err)
+				}
+				if got != v || rn != n {
+					t.Fatalf("%q: unexpected value read: %q, bytes: %d", v, got, rn)
+				}
+				if b, err := ReadByte(r); err != nil || b != 0x7a {
+					t.Fatalf("%q: trailing byte consumed: %x, %v", v, b, err)
+				}
+			}
+
+			// 4. Skip the value.
+			rs := bytes.NewReader(data)
+			sn, err := SkipString(rs, desc, true)
+			if err != nil {
+				t.Fatalf("%q: skipping failed: %v", v, err)
+			}
+			if int(sn) != n || rs.Len() != 1 {
+				t.Fatalf("%q: unexpected number of bytes skipped: %d", v, sn)
+			}
+		}
+	}
+}
+
+func TestReadStringAppend(t *testing.T) {
+	values := []string{"", "abc", "a\x00b\xff", strings.Repeat("x", 100)}
+	for _, comparable := range []bool{false, true} {
+		for _, desc := range []bool{false, true} {
+			for _, v := range values {
+				var buf bytes.Buffer
+				if _, err := WriteString(&buf, v, desc, comparable); err != nil {
+					t.Fatal(err)
+				}
+				data := buf.Bytes()
+
+				// 1. The value is appended after the dst content, for both the read seeker and the plain reader.
+				for _, r := range []io.Reader{bytes.NewReader(data), struct{ io.Reader }{bytes.NewReader(data)}} {
+					dst, n, err := ReadStringAppend([]byte("prefix:"), r, desc, comparable)
+					if err != nil {
+						t.Fatalf("%q: unexpected error: %v", v, err)
+					}
+					if string(dst) != "prefix:"+v || n != len(data) {
+						t.Fatalf("%q: unexpected result: %q, bytes read: %d", v, dst, n)
+					}
+				}
+
+				// 2. The dst is returned unchanged on error.
+				dst, _, err := ReadStringAppend([]byte("prefix:"), bytes.NewReader(data[:len(data)-1]), desc, comparable)
+				if err == nil && v != "" {
+					t.Fatalf("%q: expected truncated binary error", v)
+				}
+				if err != nil && string(dst) != "prefix:" {
+					t.Fatalf("%q: dst modified on error: %q", v, dst)
+				}
+			}
+		}
+	}
+
+	t.Run("NoAllocs", func(t *testing.T) {
+		for _, comparable := range []bool{false, true} {
+			var buf bytes.Buffer
+			if _, err := WriteString(&buf, strings.Repeat("a\x00", 50), true, comparable); err != nil {
+				t.Fatal(err)
+			}
+			data := buf.Bytes()
+			r := bytes.NewReader(data)
+			dst := make([]byte, 0, 1024)
+			allocs := testing.AllocsPerRun(100, func() {
+				r.Reset(data)
+				var err error
+				if dst, _, err = ReadStringAppend(dst[:0], r, true, comparable); err != nil {
+					t.Fatal(err)
+				}
+			})
+			if allocs != 0 {
+				t.Fatalf("comparable: %v, unexpected allocations: %v", comparable, allocs)
+			}
+		}
+	})
+}
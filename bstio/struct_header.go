@@ -0,0 +1,42 @@
+package bstio
+
+import (
+	"io"
+	"math"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// structFieldMinSize is the minimum binary size of the compatibility mode struct field - its index and length headers.
+const structFieldMinSize = 2
+
+// WriteStructHeader writes the header of the compatibility mode struct of count fields.
+// The header is the max index of the fields written, i.e. count-1, which wraps around for the empty struct.
+// Returns the number of bytes written.
+func WriteStructHeader(w io.Writer, count int) (int, error) {
+	return WriteUint(w, uint(count-1), false)
+}
+
+// ReadStructHeader reads the header of the compatibility mode struct, written by the WriteStructHeader.
+// Returns the number of the fields which follow the header, along with the number of bytes read.
+// The number of fields exceeding the remaining input is reported as the bsterr.CodeTruncatedBinary error.
+func ReadStructHeader(r io.Reader) (int, int, error) {
+	maxIndex, n, err := ReadUint(r, false)
+	if err != nil {
+		return 0, n, err
+	}
+
+	// 1. The max index of the empty struct wraps around.
+	if maxIndex == math.MaxUint {
+		return 0, n, nil
+	}
+	if maxIndex >= math.MaxInt {
+		return 0, n, bsterr.Err(bsterr.CodeMalformedBinary, "struct header exceeds the max index").
+			WithDetail("maxIndex", maxIndex)
+	}
+	count := int(maxIndex) + 1
+	if err = checkLength(r, uint(count), structFieldMinSize, n); err != nil {
+		return 0, n, err
+	}
+	return count, n, nil
+}
@@ -0,0 +1,49 @@
+package bstio
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestStructHeader(t *testing.T) {
+	for _, count := range []int{0, 1, 3, 300} {
+		var buf bytes.Buffer
+		n, err := WriteStructHeader(&buf, count)
+		if err != nil {
+			t.Fatalf("writing header of %d fields failed: %v", count, err)
+		}
+
+		// 1. The header is the max index of the fields.
+		maxIndex, _, err := ReadUint(bytes.NewReader(buf.Bytes()), false)
+		if err != nil {
+			t.Fatalf("reading max index failed: %v", err)
+		}
+		if maxIndex != uint(count-1) {
+			t.Fatalf("unexpected max index of %d fields: %d", count, maxIndex)
+		}
+
+		// 2. The number of fields is read back, once the fields follow.
+		buf.Write(make([]byte, count*structFieldMinSize))
+		read, rn, err := ReadStructHeader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("reading header of %d fields failed: %v", count, err)
+		}
+		if read != count || rn != n {
+			t.Fatalf("unexpected header: %d fields of %d bytes, expected: %d of %d", read, rn, count, n)
+		}
+	}
+
+	t.Run("Exceeding", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := WriteStructHeader(&buf, 3); err != nil {
+			t.Fatalf("writing header failed: %v", err)
+		}
+		buf.Write([]byte{1, 1})
+		_, _, err := ReadStructHeader(bytes.NewReader(buf.Bytes()))
+		var te *TruncatedError
+		if !errors.As(err, &te) {
+			t.Fatalf("expected truncated error, got: %v", err)
+		}
+	})
+}
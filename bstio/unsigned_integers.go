@@ -12,7 +12,7 @@ import (
 func ReadUint8(r io.Reader, desc bool) (uint8, int, error) {
 	bt, err := ReadByte(r)
 	if err != nil {
-		return 0, 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read uint8 value")
+		return 0, 0, readError(err, 0, 1, "failed to read uint8 value")
 	}
 	bt, err = ParseUint8Value(bt, desc)
 	if err != nil {
@@ -86,6 +86,13 @@ func SkipUint8Value(s io.ReadSeeker) (int64, error) {
 
 // WriteUint16 writes an unsigned 16-bit integer to the given writer.
 func WriteUint16(w io.Writer, v uint16, desc bool) (int, error) {
+	if b, ok := fixedBuffer(w, 2); ok {
+		if desc {
+			v = ^v
+		}
+		binary.BigEndian.PutUint16(b, v)
+		return writeFixed(w, b, "failed to write uint16 value")
+	}
 	if bw, ok := w.(io.ByteWriter); ok {
 		if err := writeUint16ByteWriter(bw, v, desc); err != nil {
 			return 0, err
@@ -125,6 +132,13 @@ func writeUint16ByteWriter(w io.ByteWriter, v uint16, desc bool) error {
 
 // WriteUint32 writes an unsigned 32-bit integer to the given writer.
 func WriteUint32(w io.Writer, v uint32, desc bool) (int, error) {
+	if b, ok := fixedBuffer(w, 4); ok {
+		if desc {
+			v = ^v
+		}
+		binary.BigEndian.PutUint32(b, v)
+		return writeFixed(w, b, "failed to write uint32 value")
+	}
 	if bw, ok := w.(io.ByteWriter); ok {
 		return writeUint32ByteWriter(bw, v, desc)
 	}
@@ -176,6 +190,13 @@ func writeUint32ByteWriter(bw io.ByteWriter, v uint32, desc bool) (int, error) {
 
 // WriteUint64 writes an unsigned 64-bit integer to the given writer.
 func WriteUint64(w io.Writer, v uint64, desc bool) (int, error) {
+	if b, ok := fixedBuffer(w, 8); ok {
+		if desc {
+			v = ^v
+		}
+		binary.BigEndian.PutUint64(b, v)
+		return writeFixed(w, b, "failed to write uint64 value")
+	}
 	if bw, ok := w.(io.ByteWriter); ok {
 		return writeUint64ByteWriter(bw, v, desc)
 	}
@@ -249,6 +270,13 @@ func MarshalUint16(v uint16, desc bool) []byte {
 
 // ReadUint16 reads an unsigned 16-bit integer from the reader.
 func ReadUint16(r io.Reader, desc bool) (uint16, int, error) {
+	if b, ok := fixedBytes(r, 2); ok {
+		uv := binary.BigEndian.Uint16(b)
+		if desc {
+			uv = ^uv
+		}
+		return uv, 2, nil
+	}
 	if br, ok := r.(io.ByteReader); ok {
 		return readUint16ByteReader(br, desc)
 	}
@@ -258,11 +286,11 @@ func ReadUint16(r io.Reader, desc bool) (uint16, int, error) {
 func readUint16ByteReader(br io.ByteReader, desc bool) (uint16, int, error) {
 	bt, err := br.ReadByte()
 	if err != nil {
-		return 0, 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read uint16 value")
+		return 0, 0, readError(err, 0, 2, "failed to read uint16 value")
 	}
 	b, err := br.ReadByte()
 	if err != nil {
-		return 0, 1, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read uint16 value")
+		return 0, 1, readError(err, 1, 2, "failed to read uint16 value")
 	}
 
 	v := uint16(bt)<<8 | uint16(b)
@@ -274,9 +302,9 @@ func readUint16ByteReader(br io.ByteReader, desc bool) (uint16, int, error) {
 
 func readUint16Reader(br io.Reader, desc bool) (uint16, int, error) {
 	bl := make([]byte, 2)
-	n, err := br.Read(bl)
+	n, err := readFull(br, bl, 0, "failed to read uint16 value")
 	if err != nil {
-		return 0, n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read uint16 value")
+		return 0, n, err
 	}
 
 	uv := uint16(bl[0])<<8 | uint16(bl[1])
@@ -329,6 +357,13 @@ func MarshalUint32(v uint32, desc bool) []byte {
 // ReadUint32 reads binary formatted, unsigned 32-bit integer from the reader.
 // If desc is true, the value is expected to be in descending order.
 func ReadUint32(r io.Reader, desc bool) (uint32, int, error) {
+	if b, ok := fixedBytes(r, 4); ok {
+		uv := binary.BigEndian.Uint32(b)
+		if desc {
+			uv = ^uv
+		}
+		return uv, 4, nil
+	}
 	if br, ok := r.(io.ByteReader); ok {
 		return readUint32ByteReader(br, desc)
 	}
@@ -337,9 +372,9 @@ func ReadUint32(r io.Reader, desc bool) (uint32, int, error) {
 
 func readUint32Reader(br io.Reader, desc bool) (uint32, int, error) {
 	bl := make([]byte, 4)
-	n, err := br.Read(bl)
+	n, err := readFull(br, bl, 0, "failed to read uint32 value")
 	if err != nil {
-		return 0, n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read uint32 value")
+		return 0, n, err
 	}
 
 	uv := uint32(bl[0])<<24 | uint32(bl[1])<<16 | uint32(bl[2])<<8 | uint32(bl[3])
@@ -360,7 +395,7 @@ func readUint32ByteReader(br io.ByteReader, desc bool) (uint32, int, error) {
 		}
 		b, er := br.ReadByte()
 		if er != nil {
-			err = bsterr.ErrWrap(er, bsterr.CodeDecodingBinaryValue, "failed to read uint32 value")
+			err = readError(er, n, 4, "failed to read uint32 value")
 			return 0
 		}
 		n++
@@ -407,6 +442,13 @@ func SkipUint32(s io.ReadSeeker) (int64, error) {
 // ReadUint64 reads binary formatted, unsigned 64-bit integer from the reader.
 // If desc is true, the value is expected to be in descending order.
 func ReadUint64(r io.Reader, desc bool) (uint64, int, error) {
+	if b, ok := fixedBytes(r, 8); ok {
+		uv := binary.BigEndian.Uint64(b)
+		if desc {
+			uv = ^uv
+		}
+		return uv, 8, nil
+	}
 	if br, ok := r.(io.ByteReader); ok {
 		return readUint64ByteReader(br, desc)
 	}
@@ -415,9 +457,9 @@ func ReadUint64(r io.Reader, desc bool) (uint64, int, error) {
 
 func readUint64Reader(br io.Reader, desc bool) (uint64, int, error) {
 	bl := make([]byte, 8)
-	n, err := br.Read(bl)
+	n, err := readFull(br, bl, 0, "failed to read uint64 value")
 	if err != nil {
-		return 0, n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read uint64 value")
+		return 0, n, err
 	}
 
 	uv := uint64(bl[0])<<56 | uint64(bl[1])<<48 | uint64(bl[2])<<40 | uint64(bl[3])<<32 |
@@ -441,7 +483,7 @@ func readUint64ByteReader(br io.ByteReader, desc bool) (uint64, int, error) {
 		}
 		b, er := br.ReadByte()
 		if er != nil {
-			err = bsterr.ErrWrap(er, bsterr.CodeDecodingBinaryValue, "failed to read uint64 value")
+			err = readError(er, n, 8, "failed to read uint64 value")
 			return 0
 		}
 		n++
@@ -514,25 +556,7 @@ func MarshalUint64(v uint64, desc bool) []byte {
 //   - if the value is [281474976710656,72057594037927936) - size = 8
 //   - if the value is [72057594037927936,18446744073709551616) - size = 9
 func MarshalUint(uv uint, desc bool) []byte {
-	bytesNo := findUintBytes(uv)
-
-	res := make([]byte, bytesNo+1)
-	header := byte(bytesNo)
-	if desc {
-		header = ^header
-	}
-	res[0] = header
-
-	var bt byte
-
-	for i := bytesNo; i >= 1; i-- {
-		bt = byte(uv >> uint(8*(i-1)))
-		if desc {
-			bt = ^bt
-		}
-		res[i] = bt
-	}
-	return res
+	return AppendUint(make([]byte, 0, UintBinarySize(uv)), uv, desc)
 }
 
 // MarshalUintValue encodes an unsigned integer with varying number of bytes into a binary format.
@@ -555,7 +579,7 @@ func MarshalUintValue(uv uint, size byte, desc bool) []byte {
 		if desc {
 			bt = ^bt
 		}
-		res[i] = bt
+		res[size-i] = bt
 	}
 	return res
 }
@@ -585,22 +609,16 @@ func ReadUint(r io.Reader, desc bool) (uint, int, error) {
 	// 1. Read the header byte.
 	size, err := ReadByte(r)
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, readError(err, 0, 1, "failed to read uint value")
 	}
 	n := 1
 
-	// 2. If the value is encoded in descending order, ReverseBytes the bytes.
-	if desc {
-		size = ^size
-	}
-
-	if size > 8 {
-		return 0, n, bsterr.Errf(bsterr.CodeDecodingBinaryValue, "invalid uint binary size").
-			WithDetails(
-				bsterr.D("size", size),
-				bsterr.D("expectedMax", "8"),
-			)
+	// 2. Decode the number of the value bytes.
+	hs, err := DecodeSizeHeader(size, desc)
+	if err != nil {
+		return 0, n, err
 	}
+	size = byte(hs)
 
 	readByteFn := func() uint {
 		if err != nil {
@@ -608,7 +626,7 @@ func ReadUint(r io.Reader, desc bool) (uint, int, error) {
 		}
 		b, er := ReadByte(r)
 		if er != nil {
-			err = er
+			err = readError(er, n, int(size)+1, "failed to read uint value")
 			return 0
 		}
 		if desc {
@@ -633,22 +651,10 @@ func DecodeUintBinarySize(br io.ReadSeeker, desc bool) (int, error) {
 	// 1. Read the header byte.
 	fs, err := ReadByte(br)
 	if err != nil {
-		return 0, bsterr.Errf(bsterr.CodeDecodingBinaryValue, "reading byte header failed").
-			WithDetail("err", err.Error())
-	}
-
-	if desc {
-		fs = ^fs
+		return 0, readError(err, 0, 1, "reading byte header failed")
 	}
 
-	if fs > 8 {
-		return 0, bsterr.Errf(bsterr.CodeDecodingBinaryValue, "invalid uint binary size").
-			WithDetails(
-				bsterr.D("size", fs),
-				bsterr.D("expectedMax", "8"),
-			)
-	}
-	return int(fs), nil
+	return DecodeSizeHeader(fs, desc)
 }
 
 // SkipUint skips a binary representation of the varying size unsigned integer.
@@ -663,17 +669,10 @@ func SkipUint(s io.ReadSeeker, desc bool) (int64, error) {
 	if size == 0 {
 		return bytesSkipped, nil
 	}
-	if size > 8 {
-		return 0, bsterr.Errf(bsterr.CodeDecodingBinaryValue, "invalid uint binary size").
-			WithDetails(
-				bsterr.D("size", size),
-				bsterr.D("expectedMax", "8"),
-			)
-	}
 
 	_, err = s.Seek(int64(size), io.SeekCurrent)
 	if err != nil {
-		return bytesSkipped, bsterr.Err(bsterr.CodeDecodingBinaryValue, "failed to skip uint varying size value")
+		return bytesSkipped, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip uint varying size value")
 	}
 	bytesSkipped += int64(size)
 	return bytesSkipped, nil
@@ -687,7 +686,7 @@ func WriteUint(w io.Writer, uv uint, desc bool) (int, error) {
 	}
 	n, err := w.Write(MarshalUint(uv, desc))
 	if err != nil {
-		return n, bsterr.Err(bsterr.CodeEncodingBinaryValue, "failed to write uint value")
+		return n, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write uint value")
 	}
 	return n, nil
 }
@@ -707,7 +706,7 @@ func WriteUintByteWriter(bw io.ByteWriter, uv uint, desc bool) (int, error) {
 
 	// 3. Write the size header.
 	if err := bw.WriteByte(sizeByte); err != nil {
-		return 1, bsterr.Err(bsterr.CodeEncodingBinaryValue, "failed to write uint value")
+		return 1, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write uint value")
 	}
 
 	// 4. Get slice by slice a byte of the value and write it to the writer.
@@ -718,7 +717,7 @@ func WriteUintByteWriter(bw io.ByteWriter, uv uint, desc bool) (int, error) {
 			bt = ^bt
 		}
 		if err := bw.WriteByte(bt); err != nil {
-			return i + 1, bsterr.Err(bsterr.CodeEncodingBinaryValue, "failed to write uint value")
+			return i + 1, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write uint value")
 		}
 	}
 	return size + 1, nil
@@ -745,7 +744,8 @@ func ReadUintValue(r io.Reader, size byte, desc bool) (uint, int, error) {
 		}
 		b, er := ReadByte(r)
 		if er != nil {
-			err = er
+			// The size header is already read, thus the value is truncated even at its first byte.
+			err = readError(er, n+1, int(size)+1, "failed to read uint value")
 			return 0
 		}
 		if desc {
@@ -772,7 +772,7 @@ func WriteUintValue(w io.Writer, v uint, size byte, desc bool) (int, error) {
 	}
 	n, err := w.Write(MarshalUintValue(v, size, desc))
 	if err != nil {
-		return n, bsterr.Err(bsterr.CodeEncodingBinaryValue, "failed to write uint value")
+		return n, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write uint value")
 	}
 	return n, nil
 }
@@ -786,7 +786,7 @@ func writeUintValueByteWriter(bw io.ByteWriter, uv uint, size byte, desc bool) (
 			bt = ^bt
 		}
 		if err := bw.WriteByte(bt); err != nil {
-			return i, bsterr.Err(bsterr.CodeEncodingBinaryValue, "failed to write uint value")
+			return i, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write uint value")
 		}
 	}
 	return int(size), nil
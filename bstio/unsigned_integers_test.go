@@ -370,3 +370,28 @@ func TestCompareUint(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteUintValue(t *testing.T) {
+	for _, desc := range []bool{false, true} {
+		for _, v := range []uint{0, 1, 255, 256, 0x010203, 1<<63 + 5} {
+			size := UintSizeHeader(v, false)
+			var bw bytes.Buffer
+			if _, err := WriteUintValue(&bw, v, size, desc); err != nil {
+				t.Fatalf("writing uint value failed: %v", err)
+			}
+			// The writer without the io.ByteWriter gets the marshaled binary, which needs to be the same.
+			var pw bytes.Buffer
+			n, err := WriteUintValue(plainWriter{w: &pw}, v, size, desc)
+			if err != nil || n != int(size) {
+				t.Fatalf("writing uint value failed: %d, %v", n, err)
+			}
+			if !bytes.Equal(bw.Bytes(), pw.Bytes()) {
+				t.Fatalf("uint value %d binaries differ: %v != %v", v, pw.Bytes(), bw.Bytes())
+			}
+			got, _, err := ReadUintValue(bytes.NewReader(pw.Bytes()), size, desc)
+			if err != nil || got != v {
+				t.Fatalf("unexpected uint value: %d, %v, expected: %d", got, err, v)
+			}
+		}
+	}
+}
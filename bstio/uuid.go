@@ -0,0 +1,94 @@
+package bstio
+
+import (
+	"encoding/hex"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// UUID is the 16 byte universally unique identifier. It is convertible to and from the [16]byte based types,
+// i.e. the github.com/google/uuid.UUID.
+//
+// The binary of the UUID is its 16 bytes as they are, thus the UUIDs are ordered by their bytes.
+// The desc flag inverts the bytes for the descending order.
+type UUID [16]byte
+
+// UUIDBinarySize is the size of the UUID binary.
+const UUIDBinarySize = 16
+
+// ParseUUID parses the UUID text in its canonical form, i.e. '6ba7b810-9dad-11d1-80b4-00c04fd430c8',
+// or as the 32 hex digits without the hyphens.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	switch len(s) {
+	case 36:
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return UUID{}, bsterr.Err(bsterr.CodeInvalidValue, "invalid uuid text").WithDetail("text", s)
+		}
+		s = s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	case 32:
+	default:
+		return UUID{}, bsterr.Err(bsterr.CodeInvalidValue, "invalid uuid text length").WithDetail("text", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(s)); err != nil {
+		return UUID{}, bsterr.ErrWrap(err, bsterr.CodeInvalidValue, "invalid uuid text").WithDetail("text", s)
+	}
+	return u, nil
+}
+
+// String returns the canonical text of the UUID, i.e. '6ba7b810-9dad-11d1-80b4-00c04fd430c8'.
+func (x UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[:8], x[:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], x[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], x[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], x[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], x[10:])
+	return string(b[:])
+}
+
+// AppendUUID appends the binary of the UUID to the dst. The desc flag inverts the binary for the descending order.
+func AppendUUID(dst []byte, v UUID, desc bool) []byte {
+	n := len(dst)
+	return appendOrdered(dst[:n], append(dst, v[:]...), desc)
+}
+
+// WriteUUID writes the UUID binary. The desc flag inverts the binary for the descending order.
+func WriteUUID(w io.Writer, v UUID, desc bool) (int, error) {
+	b, ok := fixedBuffer(w, UUIDBinarySize)
+	if !ok {
+		b = make([]byte, UUIDBinarySize)
+	}
+	copy(b, v[:])
+	if desc {
+		ReverseBytes(b)
+	}
+	return writeFixed(w, b, "failed to write uuid value")
+}
+
+// ReadUUID reads the UUID binary. The desc flag determines if the binary was written in descending order.
+func ReadUUID(r io.Reader, desc bool) (UUID, int, error) {
+	var v UUID
+	if b, ok := fixedBytes(r, UUIDBinarySize); ok {
+		copy(v[:], b)
+	} else if n, err := readFull(r, v[:], 0, "failed to read uuid value"); err != nil {
+		return UUID{}, n, err
+	}
+	if desc {
+		ReverseBytes(v[:])
+	}
+	return v, UUIDBinarySize, nil
+}
+
+// SkipUUID skips the UUID binary.
+func SkipUUID(rs io.ReadSeeker) (int64, error) {
+	if _, err := rs.Seek(UUIDBinarySize, io.SeekCurrent); err != nil {
+		return 0, bsterr.ErrWrap(err, bsterr.CodeSkippingBinaryValue, "failed to skip uuid value")
+	}
+	return UUIDBinarySize, nil
+}
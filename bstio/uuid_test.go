@@ -0,0 +1,53 @@
+package bstio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUUID(t *testing.T) {
+	const text = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	u, err := ParseUUID(text)
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+	if u.String() != text {
+		t.Fatalf("unexpected text: %s, expected: %s", u, text)
+	}
+	if h, err := ParseUUID("6ba7b8109dad11d180b400c04fd430c8"); err != nil || h != u {
+		t.Fatalf("unexpected uuid of hex text: %s, err: %v", h, err)
+	}
+	for _, s := range []string{"", "6ba7b810-9dad-11d1-80b4-00c04fd430c", "6ba7b810x9dad-11d1-80b4-00c04fd430c8", "zba7b8109dad11d180b400c04fd430c8"} {
+		if _, err = ParseUUID(s); err == nil {
+			t.Fatalf("parsing %q succeeded", s)
+		}
+	}
+
+	// The binary is read back and skipped, and its order follows the bytes.
+	lower := UUID{0x6b, 0xa7}
+	for _, desc := range []bool{false, true} {
+		var buf bytes.Buffer
+		if _, err = WriteUUID(&buf, u, desc); err != nil {
+			t.Fatalf("writing failed: %v", err)
+		}
+		if !bytes.Equal(buf.Bytes(), AppendUUID(nil, u, desc)) {
+			t.Fatalf("unexpected binary: %x", buf.Bytes())
+		}
+		rv, n, err := ReadUUID(bytes.NewReader(buf.Bytes()), desc)
+		if err != nil || n != UUIDBinarySize || rv != u {
+			t.Fatalf("unexpected uuid: %s, n: %d, err: %v", rv, n, err)
+		}
+		if n, err := SkipUUID(bytes.NewReader(buf.Bytes())); err != nil || n != UUIDBinarySize {
+			t.Fatalf("unexpected skip: %d, err: %v", n, err)
+		}
+
+		cmp := bytes.Compare(AppendUUID(nil, lower, desc), buf.Bytes())
+		if (cmp < 0) == desc {
+			t.Fatalf("unexpected order of %s and %s, desc: %v", lower, u, desc)
+		}
+	}
+
+	if _, _, err = ReadUUID(bytes.NewReader(u[:10]), false); err == nil {
+		t.Fatal("reading truncated uuid succeeded")
+	}
+}
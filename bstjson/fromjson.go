@@ -0,0 +1,436 @@
+package bstjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/devmodules/bst"
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// FromJSON writes the value binary of given type to w, out of its JSON, with the default composer options.
+// The JSON is strict: all the struct fields need to be present, except the nullable ones, which default to null,
+// and the fields not defined in the type are rejected. The map entries are written in the order of the JSON.
+func FromJSON(typ bsttype.Type, data []byte, w io.Writer) error {
+	// 1. Verify that the data is a single JSON value.
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeInvalidValue, "invalid JSON")
+	}
+
+	// 2. The base struct, array and map elements are written by the composer itself, whose length is defined upfront.
+	var (
+		opts  bst.ComposerOptions
+		write func(c *bst.Composer) error
+	)
+	switch tt := derefType(typ).(type) {
+	case *bsttype.Struct:
+		write = func(c *bst.Composer) error {
+			return writeStructFields(c, tt, raw)
+		}
+	case *bsttype.Array:
+		var elems []json.RawMessage
+		if err := unmarshal(raw, &elems); err != nil {
+			return err
+		}
+		if !tt.HasFixedSize() {
+			opts.Length = len(elems)
+		}
+		write = func(c *bst.Composer) error {
+			return writeElems(c, tt, elems)
+		}
+	case *bsttype.Map:
+		entries, err := decodeMapEntries(tt, raw)
+		if err != nil {
+			return err
+		}
+		opts.Length = len(entries)
+		write = func(c *bst.Composer) error {
+			return writeEntries(c, tt, entries)
+		}
+	default:
+		write = func(c *bst.Composer) error {
+			return writeValue(c, typ, raw)
+		}
+	}
+
+	// 3. Compose the value.
+	c, err := bst.NewComposer(w, typ, opts)
+	if err != nil {
+		return err
+	}
+	if err = write(c); err != nil {
+		return err
+	}
+	return c.Close()
+}
+
+// writeValue writes the value of the JSON to the current element of the composer.
+func writeValue(c *bst.Composer, t bsttype.Type, raw json.RawMessage) error {
+	// 1. The null is the value of the nullable types only.
+	t = derefType(t)
+	if isNull(raw) && t.Kind() != bsttype.KindNullable {
+		return bsterr.Err(bsterr.CodeInvalidValue, "null value of the non-nullable type").WithDetail("type", t)
+	}
+
+	// 2. Switch by the composite types.
+	switch tt := t.(type) {
+	case *bsttype.Struct:
+		return c.WriteStruct(func(sc *bst.Composer) error {
+			return writeStructFields(sc, tt, raw)
+		})
+	case *bsttype.Array:
+		return writeArray(c, tt, raw)
+	case *bsttype.Map:
+		return writeMap(c, tt, raw)
+	case *bsttype.Nullable:
+		if isNull(raw) {
+			return c.WriteNull()
+		}
+		if err := c.WriteNotNull(); err != nil {
+			return err
+		}
+		return writeValue(c, tt.Type, raw)
+	case *bsttype.Enum:
+		var s string
+		if err := unmarshal(raw, &s); err != nil {
+			return err
+		}
+		for _, elem := range tt.Elements {
+			if elem.String == s {
+				return c.WriteEnumIndex(int(elem.Index))
+			}
+		}
+		return bsterr.Err(bsterr.CodeInvalidValue, "enum element not found").WithDetail("value", s)
+	case *bsttype.OneOf:
+		var obj map[string]json.RawMessage
+		if err := unmarshal(raw, &obj); err != nil {
+			return err
+		}
+		if len(obj) != 1 {
+			return bsterr.Err(bsterr.CodeInvalidValue, "oneof value needs to be an object of a single element")
+		}
+		var name string
+		for name = range obj {
+		}
+		for _, elem := range tt.Elements {
+			if elem.Name == name {
+				if err := c.WriteOneOfByIndex(elem.Index); err != nil {
+					return err
+				}
+				return writeValue(c, elem.Type, obj[name])
+			}
+		}
+		return bsterr.Err(bsterr.CodeInvalidValue, "oneof element not found").WithDetail("name", name)
+	case *bsttype.Bytes:
+		v, err := unmarshalBytes(raw)
+		if err != nil {
+			return err
+		}
+		return c.WriteBytes(v)
+	case *bsttype.DateTime:
+		v, err := unmarshalTime(raw)
+		if err != nil {
+			return err
+		}
+		return c.WriteDateTime(v)
+	}
+
+	// 3. Switch by the kind of the basic types.
+	switch k := t.Kind(); k {
+	case bsttype.KindBoolean:
+		var v bool
+		if err := unmarshal(raw, &v); err != nil {
+			return err
+		}
+		return c.WriteBoolean(v)
+	case bsttype.KindInt, bsttype.KindInt8, bsttype.KindInt16, bsttype.KindInt32, bsttype.KindInt64:
+		v, err := strconv.ParseInt(string(raw), 10, intBits(k))
+		if err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeInvalidValue, "invalid JSON integer").WithDetail("kind", k)
+		}
+		return writeInt(c, k, v)
+	case bsttype.KindUint, bsttype.KindUint8, bsttype.KindUint16, bsttype.KindUint32, bsttype.KindUint64:
+		v, err := strconv.ParseUint(string(raw), 10, intBits(k))
+		if err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeInvalidValue, "invalid JSON unsigned integer").WithDetail("kind", k)
+		}
+		return writeUint(c, k, v)
+	case bsttype.KindFloat32:
+		var v float32
+		if err := unmarshal(raw, &v); err != nil {
+			return err
+		}
+		return c.WriteFloat32(v)
+	case bsttype.KindFloat64:
+		var v float64
+		if err := unmarshal(raw, &v); err != nil {
+			return err
+		}
+		return c.WriteFloat64(v)
+	case bsttype.KindString:
+		var v string
+		if err := unmarshal(raw, &v); err != nil {
+			return err
+		}
+		return c.WriteString(v)
+	case bsttype.KindTimestamp:
+		v, err := unmarshalTime(raw)
+		if err != nil {
+			return err
+		}
+		return c.WriteTimestamp(v)
+	case bsttype.KindDuration:
+		var s string
+		if err := unmarshal(raw, &s); err != nil {
+			return err
+		}
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeInvalidValue, "invalid JSON duration").WithDetail("value", s)
+		}
+		return c.WriteDuration(v)
+	case bsttype.KindExternalBytes:
+		v, err := unmarshalBytes(raw)
+		if err != nil {
+			return err
+		}
+		return c.WriteExternalBytes(v)
+	case bsttype.KindDecimal:
+		var n json.Number
+		if err := unmarshal(raw, &n); err != nil {
+			return err
+		}
+		v, err := bstio.ParseDecimal(n.String())
+		if err != nil {
+			return err
+		}
+		return c.WriteDecimal(v)
+	case bsttype.KindBitmap:
+		var v []bool
+		if err := unmarshal(raw, &v); err != nil {
+			return err
+		}
+		return c.WriteBitmap(bstio.BitmapOf(v...))
+	case bsttype.KindUUID:
+		var s string
+		if err := unmarshal(raw, &s); err != nil {
+			return err
+		}
+		v, err := bstio.ParseUUID(s)
+		if err != nil {
+			return err
+		}
+		return c.WriteUUID(v)
+	}
+	return bsterr.Err(bsterr.CodeInvalidType, "type could not be converted from JSON").WithDetail("type", t)
+}
+
+// writeStructFields writes the fields of the JSON object in the order of the struct type.
+func writeStructFields(sc *bst.Composer, st *bsttype.Struct, raw json.RawMessage) error {
+	// 1. Decode the object and verify that all its fields are defined.
+	var obj map[string]json.RawMessage
+	if err := unmarshal(raw, &obj); err != nil {
+		return err
+	}
+	for name := range obj {
+		if _, _, ok := st.FieldByName(name); !ok {
+			return bsterr.Err(bsterr.CodeInvalidValue, "struct field is not defined").WithDetail("field", name)
+		}
+	}
+
+	// 2. Write the fields, where the missing nullable fields are null.
+	for _, f := range st.Fields {
+		v, ok := obj[f.Name]
+		if !ok {
+			if derefType(f.Type).Kind() != bsttype.KindNullable {
+				return bsterr.Err(bsterr.CodeValueFieldMissing, "struct field is missing").WithDetail("field", f.Name)
+			}
+			v = json.RawMessage("null")
+		}
+		if err := writeValue(sc, f.Type, v); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").
+				WithDetail("field", f.Name)
+		}
+	}
+	return nil
+}
+
+// writeArray writes the array of the JSON array.
+func writeArray(c *bst.Composer, at *bsttype.Array, raw json.RawMessage) error {
+	var elems []json.RawMessage
+	if err := unmarshal(raw, &elems); err != nil {
+		return err
+	}
+	return c.WriteArray(func(ac *bst.Composer) error {
+		return writeElems(ac, at, elems)
+	}, len(elems))
+}
+
+// writeElems writes the array elements.
+func writeElems(ac *bst.Composer, at *bsttype.Array, elems []json.RawMessage) error {
+	for i, v := range elems {
+		if err := writeValue(ac, at.Type, v); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write array element").
+				WithDetail("index", i)
+		}
+	}
+	return nil
+}
+
+// writeMap writes the map of the JSON object or the array of the entry pairs.
+func writeMap(c *bst.Composer, mt *bsttype.Map, raw json.RawMessage) error {
+	entries, err := decodeMapEntries(mt, raw)
+	if err != nil {
+		return err
+	}
+	return c.WriteMap(func(mc *bst.Composer) error {
+		return writeEntries(mc, mt, entries)
+	}, len(entries))
+}
+
+// writeEntries writes the map entries.
+func writeEntries(mc *bst.Composer, mt *bsttype.Map, entries [][2]json.RawMessage) error {
+	for _, e := range entries {
+		if err := writeValue(mc, mt.Key.Type, e[0]); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write map key")
+		}
+		if err := writeValue(mc, mt.Value.Type, e[1]); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write map value")
+		}
+	}
+	return nil
+}
+
+// decodeMapEntries decodes the map entries in the order of the JSON. The scalar keys are the JSON object keys,
+// which are turned back into the JSON values of their types.
+func decodeMapEntries(mt *bsttype.Map, raw json.RawMessage) ([][2]json.RawMessage, error) {
+	// 1. The non-scalar keys are the arrays of the entry pairs.
+	if !isScalarKey(mt.Key.Type) {
+		var entries [][2]json.RawMessage
+		if err := unmarshal(raw, &entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	}
+
+	// 2. Stream the tokens of the object, so that the order of its entries is kept.
+	d := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := d.Token(); err != nil || tok != json.Delim('{') {
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "JSON map needs to be an object")
+	}
+	quoted := isQuotedKey(mt.Key.Type)
+	var entries [][2]json.RawMessage
+	for d.More() {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeInvalidValue, "invalid JSON map key")
+		}
+		key := tok.(string)
+		var v json.RawMessage
+		if err = d.Decode(&v); err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeInvalidValue, "invalid JSON map value").WithDetail("key", key)
+		}
+		k := json.RawMessage(key)
+		if quoted {
+			k = appendString(nil, key)
+		}
+		entries = append(entries, [2]json.RawMessage{k, v})
+	}
+	return entries, nil
+}
+
+// isQuotedKey checks if the JSON value of the scalar map key is a string.
+func isQuotedKey(t bsttype.Type) bool {
+	switch derefType(t).Kind() {
+	case bsttype.KindBoolean, bsttype.KindInt, bsttype.KindInt8, bsttype.KindInt16, bsttype.KindInt32, bsttype.KindInt64,
+		bsttype.KindUint, bsttype.KindUint8, bsttype.KindUint16, bsttype.KindUint32, bsttype.KindUint64,
+		bsttype.KindFloat32, bsttype.KindFloat64, bsttype.KindDecimal:
+		return false
+	}
+	return true
+}
+
+// writeInt writes the signed integer of given kind.
+func writeInt(c *bst.Composer, k bsttype.Kind, v int64) error {
+	switch k {
+	case bsttype.KindInt8:
+		return c.WriteInt8(int8(v))
+	case bsttype.KindInt16:
+		return c.WriteInt16(int16(v))
+	case bsttype.KindInt32:
+		return c.WriteInt32(int32(v))
+	case bsttype.KindInt64:
+		return c.WriteInt64(v)
+	default:
+		return c.WriteInt(int(v))
+	}
+}
+
+// writeUint writes the unsigned integer of given kind.
+func writeUint(c *bst.Composer, k bsttype.Kind, v uint64) error {
+	switch k {
+	case bsttype.KindUint8:
+		return c.WriteUint8(uint8(v))
+	case bsttype.KindUint16:
+		return c.WriteUint16(uint16(v))
+	case bsttype.KindUint32:
+		return c.WriteUint32(uint32(v))
+	case bsttype.KindUint64:
+		return c.WriteUint64(v)
+	default:
+		return c.WriteUint(uint(v))
+	}
+}
+
+// intBits returns the bit size of the integer kind.
+func intBits(k bsttype.Kind) int {
+	switch k {
+	case bsttype.KindInt8, bsttype.KindUint8:
+		return 8
+	case bsttype.KindInt16, bsttype.KindUint16:
+		return 16
+	case bsttype.KindInt32, bsttype.KindUint32:
+		return 32
+	case bsttype.KindInt, bsttype.KindUint:
+		return strconv.IntSize
+	default:
+		return 64
+	}
+}
+
+// unmarshal decodes the JSON value.
+func unmarshal(raw json.RawMessage, v any) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeInvalidValue, "invalid JSON value")
+	}
+	return nil
+}
+
+// unmarshalBytes decodes the base64 encoded JSON string.
+func unmarshalBytes(raw json.RawMessage) ([]byte, error) {
+	var v []byte
+	if err := unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// unmarshalTime decodes the RFC 3339 JSON string.
+func unmarshalTime(raw json.RawMessage) (time.Time, error) {
+	var v time.Time
+	if err := unmarshal(raw, &v); err != nil {
+		return time.Time{}, err
+	}
+	return v, nil
+}
+
+// isNull checks if the JSON value is null.
+func isNull(raw json.RawMessage) bool {
+	return string(raw) == "null"
+}
@@ -0,0 +1,97 @@
+package bstjson
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/devmodules/bst"
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestFromJSON(t *testing.T) {
+	t.Run("Marshal", func(t *testing.T) {
+		// The binary equals the one marshaled from the Go struct of the same type.
+		type address struct {
+			City string `bst:"city"`
+			Zip  uint32 `bst:"zip"`
+		}
+		type user struct {
+			ID      uint64           `bst:"id"`
+			Name    string           `bst:"name"`
+			Address address          `bst:"address"`
+			Prev    *address         `bst:"prev"`
+			Labels  map[string]int64 `bst:"labels"`
+			Scores  []int16          `bst:"scores"`
+		}
+		in := user{ID: 3, Name: "john", Address: address{City: "Warsaw", Zip: 1}, Labels: map[string]int64{"a": 1, "b": 2}, Scores: []int16{-1, 2}}
+		expected, err := bst.Marshal(in)
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		st, err := bst.StructTypeOf(in)
+		if err != nil {
+			t.Fatalf("struct type failed: %v", err)
+		}
+
+		var buf bytes.Buffer
+		data := `{"scores": [-1, 2], "labels": {"a": 1, "b": 2}, "address": {"zip": 1, "city": "Warsaw"}, "name": "john", "id": 3}`
+		if err = FromJSON(st, []byte(data), &buf); err != nil {
+			t.Fatalf("from JSON failed: %v", err)
+		}
+		if !bytes.Equal(buf.Bytes(), expected) {
+			t.Fatalf("unexpected binary:\n%v\nexpected:\n%v", buf.Bytes(), expected)
+		}
+	})
+
+	t.Run("Named", func(t *testing.T) {
+		item := &bsttype.Struct{Fields: []bsttype.StructField{{Index: 1, Name: "sku", Type: bsttype.String()}}}
+		m := &bsttype.Modules{List: []*bsttype.Module{{
+			Name:        "shop",
+			Definitions: []bsttype.ModuleDefinition{{Name: "item", Type: item}},
+		}}}
+		if err := m.Resolve(); err != nil {
+			t.Fatalf("resolving modules failed: %v", err)
+		}
+		named := &bsttype.Named{Module: "shop", Name: "item", Type: item}
+		st := &bsttype.Struct{Fields: []bsttype.StructField{
+			{Index: 1, Name: "item", Type: named},
+			{Index: 2, Name: "gift", Type: bsttype.NullableOf(named)},
+			{Index: 3, Name: "items", Type: bsttype.ArrayOf(named)},
+		}}
+		data := `{"item":{"sku":"a"},"gift":{"sku":"b"},"items":[{"sku":"c"}]}`
+		var buf bytes.Buffer
+		if err := FromJSON(st, []byte(data), &buf); err != nil {
+			t.Fatalf("from JSON failed: %v", err)
+		}
+		out, err := ToJSON(st, &buf)
+		if err != nil {
+			t.Fatalf("to JSON failed: %v", err)
+		}
+		if string(out) != data {
+			t.Fatalf("unexpected JSON:\n%s\nexpected:\n%s", out, data)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		st := testStructType()
+		invalid := map[string]string{
+			"syntax":         `{"id":`,
+			"trailing":       `{} {}`,
+			"unknownField":   `{"unknown":1}`,
+			"missingField":   `{"id":1}`,
+			"nullField":      `{"id":null}`,
+			"overflow":       `{"id":-1}`,
+			"enum":           `{"color":"blue"}`,
+			"oneof":          `{"shape":{"circle":1,"label":"a"}}`,
+			"duration":       `{"ttl":"1 minute"}`,
+			"mapObject":      `{"labels":[]}`,
+			"mapKey":         `{"counts":{"a":1}}`,
+			"fixedSizeArray": `{"flags":[true]}`,
+		}
+		for name, data := range invalid {
+			if err := FromJSON(st, []byte(data), &bytes.Buffer{}); err == nil {
+				t.Fatalf("expected %s JSON to fail", name)
+			}
+		}
+	})
+}
@@ -0,0 +1,428 @@
+// Package bstjson converts the BST value binaries to and from JSON, with the field names taken from the type definition.
+// It is meant for debugging the payloads and for the interop with the HTTP APIs, without the hand-written
+// extraction loops. The JSON representation of the values is:
+//   - Struct - object of the fields, in the order of the type definition,
+//   - Array and Bitmap - array of the elements,
+//   - Map - object of the entries, if the key is a scalar, or an array of the [key, value] pairs otherwise,
+//   - Nullable - null or the value,
+//   - Bytes and ExternalBytes - base64 encoded string,
+//   - Timestamp and DateTime - RFC 3339 string, with the nanoseconds,
+//   - Duration - string formatted as by the time.Duration.String,
+//   - UUID - string of the canonical form, i.e. '6ba7b810-9dad-11d1-80b4-00c04fd430c8',
+//   - Enum - string of the element,
+//   - OneOf - object of a single element, keyed by its name,
+//   - integers, floats and decimals - numbers, the floats need to be finite, the decimals keep their exact digits.
+//
+// The values of the named types with the format registered in the bst.DefaultFormats are JSON strings of their
+// formatted text instead, i.e. the 16 byte field rendered as the UUID. These are meant for reading only, thus
+// the FromJSON doesn't convert them back.
+//
+// The Any values are not supported.
+package bstjson
+
+import (
+	"encoding/base64"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/devmodules/bst"
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// ToJSON reads the value binary of given type from r, as written by the bst.Composer, and returns its JSON.
+// The encoding options are read from the value header. Named types need to be resolved.
+func ToJSON(typ bsttype.Type, r io.Reader) ([]byte, error) {
+	// 1. Create the extractor of the value.
+	x, err := bst.NewExtractor(r, bst.ExtractorOptions{ExpectedType: typ})
+	if err != nil {
+		return nil, err
+	}
+	defer x.Close()
+
+	// 2. The composite base types are iterated by the extractor itself, whereas other values are its single element.
+	e := jsonEncoder{formats: bst.DefaultFormats}
+	switch tt := typ.(type) {
+	case *bsttype.Struct:
+		err = e.structFields(x, tt)
+	case *bsttype.Array:
+		err = e.arrayElems(x, tt)
+	case *bsttype.Map:
+		err = e.mapEntries(x, tt)
+	default:
+		if !x.Next() {
+			if err = x.Err(); err == nil {
+				err = bsterr.Err(bsterr.CodeEndOfInput, "value not found")
+			}
+			break
+		}
+		err = e.value(x, typ)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// jsonEncoder appends the JSON of the extracted values.
+type jsonEncoder struct {
+	buf     []byte
+	formats *bst.Formats
+}
+
+// value appends the JSON of the current element of the extractor.
+func (x *jsonEncoder) value(xt *bst.Extractor, t bsttype.Type) error {
+	// 1. The named types with the registered format are rendered as their formatted text.
+	if s, ok, err := xt.ReadFormatted(x.formats, t); ok {
+		if err != nil {
+			return err
+		}
+		x.buf = appendString(x.buf, s)
+		return nil
+	}
+
+	// 2. Switch by the composite types.
+	t = derefType(t)
+	switch tt := t.(type) {
+	case *bsttype.Struct:
+		return xt.ReadStruct(func(sx *bst.Extractor) error {
+			return x.structFields(sx, tt)
+		})
+	case *bsttype.Array:
+		return xt.ReadArray(func(ax *bst.Extractor) error {
+			return x.arrayElems(ax, tt)
+		})
+	case *bsttype.Map:
+		return xt.ReadMap(func(mx *bst.Extractor) error {
+			return x.mapEntries(mx, tt)
+		})
+	case *bsttype.Nullable:
+		isNull, err := xt.IsNull()
+		if err != nil {
+			return err
+		}
+		if isNull {
+			x.buf = append(x.buf, "null"...)
+			return nil
+		}
+		return x.value(xt, tt.Type)
+	case *bsttype.Enum:
+		index, err := xt.ReadEnumIndex()
+		if err != nil {
+			return err
+		}
+		for _, elem := range tt.Elements {
+			if elem.Index == index {
+				x.buf = appendString(x.buf, elem.String)
+				return nil
+			}
+		}
+		return bsterr.Err(bsterr.CodeInvalidValue, "enum element not found").WithDetail("index", index)
+	case *bsttype.OneOf:
+		h, err := xt.ReadOneOfHeader()
+		if err != nil {
+			return err
+		}
+		for _, elem := range tt.Elements {
+			if elem.Index == h.Index {
+				x.buf = append(appendString(append(x.buf, '{'), elem.Name), ':')
+				if err = x.value(xt, elem.Type); err != nil {
+					return err
+				}
+				x.buf = append(x.buf, '}')
+				return nil
+			}
+		}
+		return bsterr.Err(bsterr.CodeInvalidValue, "oneof element not found").WithDetail("index", h.Index)
+	case *bsttype.Bytes:
+		v, err := xt.ReadBytes()
+		if err != nil {
+			return err
+		}
+		x.appendBytes(v)
+		return nil
+	case *bsttype.DateTime:
+		v, err := xt.ReadDateTime()
+		if err != nil {
+			return err
+		}
+		x.buf = append(v.AppendFormat(append(x.buf, '"'), time.RFC3339Nano), '"')
+		return nil
+	}
+
+	// 3. Switch by the kind of the basic types.
+	var err error
+	switch t.Kind() {
+	case bsttype.KindBoolean:
+		var v bool
+		if v, err = xt.ReadBoolean(); err == nil {
+			x.buf = strconv.AppendBool(x.buf, v)
+		}
+	case bsttype.KindInt, bsttype.KindInt8, bsttype.KindInt16, bsttype.KindInt32, bsttype.KindInt64:
+		var v int64
+		if v, err = readInt(xt, t.Kind()); err == nil {
+			x.buf = strconv.AppendInt(x.buf, v, 10)
+		}
+	case bsttype.KindUint, bsttype.KindUint8, bsttype.KindUint16, bsttype.KindUint32, bsttype.KindUint64:
+		var v uint64
+		if v, err = readUint(xt, t.Kind()); err == nil {
+			x.buf = strconv.AppendUint(x.buf, v, 10)
+		}
+	case bsttype.KindFloat32:
+		var v float32
+		if v, err = xt.ReadFloat32(); err == nil {
+			err = x.appendFloat(float64(v), 32)
+		}
+	case bsttype.KindFloat64:
+		var v float64
+		if v, err = xt.ReadFloat64(); err == nil {
+			err = x.appendFloat(v, 64)
+		}
+	case bsttype.KindString:
+		var v string
+		if v, err = xt.ReadString(); err == nil {
+			x.buf = appendString(x.buf, v)
+		}
+	case bsttype.KindTimestamp:
+		var v time.Time
+		if v, err = xt.ReadTimestamp(); err == nil {
+			x.buf = append(v.AppendFormat(append(x.buf, '"'), time.RFC3339Nano), '"')
+		}
+	case bsttype.KindDuration:
+		var v time.Duration
+		if v, err = xt.ReadDuration(); err == nil {
+			x.buf = appendString(x.buf, v.String())
+		}
+	case bsttype.KindExternalBytes:
+		var v []byte
+		if v, err = xt.ReadExternalBytes(); err == nil {
+			x.appendBytes(v)
+		}
+	case bsttype.KindDecimal:
+		var v bstio.Decimal
+		if v, err = xt.ReadDecimal(); err == nil {
+			x.buf = append(x.buf, v.String()...)
+		}
+	case bsttype.KindBitmap:
+		var v bstio.Bitmap
+		if v, err = xt.ReadBitmap(); err == nil {
+			x.buf = append(x.buf, '[')
+			for i := 0; i < v.Len; i++ {
+				if i > 0 {
+					x.buf = append(x.buf, ',')
+				}
+				x.buf = strconv.AppendBool(x.buf, v.Get(i))
+			}
+			x.buf = append(x.buf, ']')
+		}
+	case bsttype.KindUUID:
+		var v [16]byte
+		if v, err = xt.ReadUUID(); err == nil {
+			x.buf = appendString(x.buf, bstio.UUID(v).String())
+		}
+	default:
+		return bsterr.Err(bsterr.CodeInvalidType, "type could not be converted to JSON").WithDetail("type", t)
+	}
+	return err
+}
+
+// structFields appends the JSON object of the struct fields.
+func (x *jsonEncoder) structFields(sx *bst.Extractor, st *bsttype.Struct) error {
+	x.buf = append(x.buf, '{')
+	first := true
+	for sx.Next() {
+		if sx.Index() >= len(st.Fields) {
+			return bsterr.Err(bsterr.CodeOutOfBounds, "struct field index is out of bounds").
+				WithDetail("index", sx.Index())
+		}
+		f := st.Fields[sx.Index()]
+		if !first {
+			x.buf = append(x.buf, ',')
+		}
+		first = false
+		x.buf = append(appendString(x.buf, f.Name), ':')
+		if err := x.value(sx, f.Type); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to convert struct field").
+				WithDetail("field", f.Name)
+		}
+	}
+	x.buf = append(x.buf, '}')
+	return sx.Err()
+}
+
+// arrayElems appends the JSON array of the array elements.
+func (x *jsonEncoder) arrayElems(ax *bst.Extractor, at *bsttype.Array) error {
+	x.buf = append(x.buf, '[')
+	for ax.Next() {
+		if ax.Index() > 0 {
+			x.buf = append(x.buf, ',')
+		}
+		if err := x.value(ax, at.Type); err != nil {
+			return err
+		}
+	}
+	x.buf = append(x.buf, ']')
+	return ax.Err()
+}
+
+// mapEntries appends the JSON object of the map entries, or the array of the entry pairs if the key is not a scalar.
+func (x *jsonEncoder) mapEntries(mx *bst.Extractor, mt *bsttype.Map) error {
+	scalar := isScalarKey(mt.Key.Type)
+	open, end := byte('{'), byte('}')
+	if !scalar {
+		open, end = '[', ']'
+	}
+	x.buf = append(x.buf, open)
+	for i := 0; mx.Next(); i++ {
+		if i > 0 {
+			x.buf = append(x.buf, ',')
+		}
+		// 1. Append the key, which is quoted unless it is already a JSON string.
+		keyStart := len(x.buf)
+		if !scalar {
+			x.buf = append(x.buf, '[')
+		}
+		if err := x.value(mx, mt.Key.Type); err != nil {
+			return err
+		}
+		if scalar && x.buf[keyStart] != '"' {
+			x.buf = append(x.buf, '"')
+			copy(x.buf[keyStart+1:], x.buf[keyStart:])
+			x.buf[keyStart] = '"'
+			x.buf = append(x.buf, '"')
+		}
+		if scalar {
+			x.buf = append(x.buf, ':')
+		} else {
+			x.buf = append(x.buf, ',')
+		}
+
+		// 2. Append the value.
+		if !mx.Next() {
+			break
+		}
+		if err := x.value(mx, mt.Value.Type); err != nil {
+			return err
+		}
+		if !scalar {
+			x.buf = append(x.buf, ']')
+		}
+	}
+	x.buf = append(x.buf, end)
+	return mx.Err()
+}
+
+// appendBytes appends the base64 encoded JSON string of the bytes.
+func (x *jsonEncoder) appendBytes(v []byte) {
+	x.buf = append(base64.StdEncoding.AppendEncode(append(x.buf, '"'), v), '"')
+}
+
+// appendFloat appends the JSON number of the float. The JSON has no representation of the infinities and NaN.
+func (x *jsonEncoder) appendFloat(v float64, bitSize int) error {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return bsterr.Err(bsterr.CodeInvalidValue, "non-finite float could not be converted to JSON").
+			WithDetail("value", v)
+	}
+	x.buf = strconv.AppendFloat(x.buf, v, 'g', -1, bitSize)
+	return nil
+}
+
+// readInt reads the signed integer of given kind.
+func readInt(xt *bst.Extractor, k bsttype.Kind) (int64, error) {
+	switch k {
+	case bsttype.KindInt8:
+		v, err := xt.ReadInt8()
+		return int64(v), err
+	case bsttype.KindInt16:
+		v, err := xt.ReadInt16()
+		return int64(v), err
+	case bsttype.KindInt32:
+		v, err := xt.ReadInt32()
+		return int64(v), err
+	case bsttype.KindInt64:
+		return xt.ReadInt64()
+	default:
+		v, err := xt.ReadInt()
+		return int64(v), err
+	}
+}
+
+// readUint reads the unsigned integer of given kind.
+func readUint(xt *bst.Extractor, k bsttype.Kind) (uint64, error) {
+	switch k {
+	case bsttype.KindUint8:
+		v, err := xt.ReadUint8()
+		return uint64(v), err
+	case bsttype.KindUint16:
+		v, err := xt.ReadUint16()
+		return uint64(v), err
+	case bsttype.KindUint32:
+		v, err := xt.ReadUint32()
+		return uint64(v), err
+	case bsttype.KindUint64:
+		return xt.ReadUint64()
+	default:
+		v, err := xt.ReadUint()
+		return uint64(v), err
+	}
+}
+
+// isScalarKey checks if the map key is represented as the JSON object key.
+func isScalarKey(t bsttype.Type) bool {
+	switch derefType(t).Kind() {
+	case bsttype.KindStruct, bsttype.KindArray, bsttype.KindMap, bsttype.KindNullable, bsttype.KindOneOf,
+		bsttype.KindBitmap, bsttype.KindAny:
+		return false
+	}
+	return true
+}
+
+// derefType returns the base type of the named type.
+func derefType(t bsttype.Type) bsttype.Type {
+	for {
+		nt, ok := t.(*bsttype.Named)
+		if !ok || nt.Type == nil {
+			return t
+		}
+		t = nt.Type
+	}
+}
+
+// appendString appends the JSON string, with the invalid UTF-8 replaced by the replacement character.
+func appendString(dst []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				dst = append(dst, '\\', c)
+			case c == '\n':
+				dst = append(dst, '\\', 'n')
+			case c == '\r':
+				dst = append(dst, '\\', 'r')
+			case c == '\t':
+				dst = append(dst, '\\', 't')
+			case c < 0x20:
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			default:
+				dst = append(dst, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, "\\ufffd"...)
+		} else {
+			dst = append(dst, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(dst, '"')
+}
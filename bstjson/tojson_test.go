@@ -0,0 +1,193 @@
+package bstjson
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/devmodules/bst"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// testStructType returns the struct type with the fields of all the supported kinds.
+func testStructType() *bsttype.Struct {
+	address := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "city", Type: bsttype.String()},
+		{Index: 2, Name: "zip", Type: bsttype.Uint32()},
+	}}
+	pairKey := &bsttype.Struct{Fields: []bsttype.StructField{{Index: 1, Name: "a", Type: bsttype.Int8()}}}
+	return &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "id", Type: bsttype.Uint64()},
+		{Index: 2, Name: "name", Type: bsttype.String()},
+		{Index: 3, Name: "score", Type: bsttype.Int32(), Descending: true},
+		{Index: 4, Name: "active", Type: bsttype.Boolean()},
+		{Index: 5, Name: "flags", Type: bsttype.FixedSizeArrayOf(bsttype.Boolean(), 3)},
+		{Index: 6, Name: "tags", Type: bsttype.ArrayOf(bsttype.String())},
+		{Index: 7, Name: "data", Type: &bsttype.Bytes{}},
+		{Index: 8, Name: "created", Type: bsttype.Timestamp()},
+		{Index: 9, Name: "ttl", Type: bsttype.Duration()},
+		{Index: 10, Name: "ratio", Type: bsttype.Float64()},
+		{Index: 11, Name: "note", Type: bsttype.NullableOf(bsttype.String())},
+		{Index: 12, Name: "address", Type: address},
+		{Index: 13, Name: "labels", Type: bsttype.MapTypeOf(bsttype.String(), bsttype.Int64(), false, false)},
+		{Index: 14, Name: "counts", Type: bsttype.MapTypeOf(bsttype.Int16(), bsttype.Uint8(), false, false)},
+		{Index: 15, Name: "pairs", Type: bsttype.MapTypeOf(pairKey, bsttype.String(), false, false)},
+		{Index: 16, Name: "color", Type: &bsttype.Enum{ValueBytes: 1, Elements: []bsttype.EnumElement{
+			{String: "red", Index: 1}, {String: "green", Index: 2},
+		}}},
+		{Index: 17, Name: "shape", Type: &bsttype.OneOf{IndexBytes: 1, Elements: []bsttype.OneOfElement{
+			{Index: 1, Name: "circle", Type: bsttype.Float32()},
+			{Index: 2, Name: "label", Type: bsttype.String()},
+		}}},
+		{Index: 18, Name: "bits", Type: bsttype.Bitmap()},
+		{Index: 19, Name: "parent", Type: bsttype.NullableOf(address)},
+	}}
+}
+
+// _testJSON is the JSON of the testStructType value, as returned by the ToJSON.
+const _testJSON = `{"id":1,"name":"john \"j\"\n","score":-5,"active":true,"flags":[true,false,true],"tags":["a","b"],` +
+	`"data":"AQID","created":"2024-05-01T12:00:00.5Z","ttl":"1m30s","ratio":0.25,"note":null,` +
+	`"address":{"city":"Warsaw","zip":12345},"labels":{"y":-2,"x":1},"counts":{"3":0,"-1":255},` +
+	`"pairs":[[{"a":1},"one"]],"color":"green","shape":{"circle":1.5},"bits":[true,false,true],` +
+	`"parent":{"city":"Cracow","zip":30001}}`
+
+func TestToJSON(t *testing.T) {
+	st := testStructType()
+
+	t.Run("Struct", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := FromJSON(st, []byte(_testJSON), &buf); err != nil {
+			t.Fatalf("from JSON failed: %v", err)
+		}
+		out, err := ToJSON(st, &buf)
+		if err != nil {
+			t.Fatalf("to JSON failed: %v", err)
+		}
+		if string(out) != _testJSON {
+			t.Fatalf("unexpected JSON:\n%s\nexpected:\n%s", out, _testJSON)
+		}
+	})
+
+	t.Run("Marshaled", func(t *testing.T) {
+		type item struct {
+			Sku   string    `bst:"sku"`
+			Price float32   `bst:"price,desc"`
+			At    time.Time `bst:"at"`
+			Tags  []string  `bst:"tags"`
+			Note  *string   `bst:"note"`
+		}
+		data, err := bst.Marshal(item{Sku: "a1", Price: 9.5, At: time.Unix(0, 0).UTC(), Tags: []string{"x"}}, bst.ForIndexKey())
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		it, err := bst.StructTypeOf(item{})
+		if err != nil {
+			t.Fatalf("struct type failed: %v", err)
+		}
+		out, err := ToJSON(it, bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("to JSON failed: %v", err)
+		}
+		expected := `{"sku":"a1","price":9.5,"at":"1970-01-01T00:00:00Z","tags":["x"],"note":null}`
+		if string(out) != expected {
+			t.Fatalf("unexpected JSON:\n%s\nexpected:\n%s", out, expected)
+		}
+	})
+
+	t.Run("Basic", func(t *testing.T) {
+		types := map[string]bsttype.Type{
+			`"abc"`:                                  bsttype.String(),
+			`-12`:                                    bsttype.Int(),
+			`-12.05`:                                 bsttype.Decimal(),
+			`"6ba7b810-9dad-11d1-80b4-00c04fd430c8"`: bsttype.UUID(),
+			`[1,2]`:                                  bsttype.ArrayOf(bsttype.Uint16()),
+			`{"a":1}`:                                bsttype.MapTypeOf(bsttype.String(), bsttype.Int8(), false, false),
+		}
+		for in, typ := range types {
+			var buf bytes.Buffer
+			if err := FromJSON(typ, []byte(in), &buf); err != nil {
+				t.Fatalf("from JSON %s failed: %v", in, err)
+			}
+			out, err := ToJSON(typ, &buf)
+			if err != nil {
+				t.Fatalf("to JSON %s failed: %v", in, err)
+			}
+			if string(out) != in {
+				t.Fatalf("unexpected JSON: %s, expected: %s", out, in)
+			}
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var buf bytes.Buffer
+		c, err := bst.NewComposer(&buf, bsttype.Float64(), bst.ComposerOptions{})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteFloat64(math.Inf(1)); err != nil {
+			t.Fatalf("writing float failed: %v", err)
+		}
+		if _, err = ToJSON(bsttype.Float64(), &buf); err == nil {
+			t.Fatal("expected non-finite float to fail")
+		}
+		if _, err = ToJSON(bsttype.String(), bytes.NewReader(nil)); err == nil {
+			t.Fatal("expected empty input to fail")
+		}
+		if _, err = ToJSON(&bsttype.Struct{Fields: []bsttype.StructField{{Index: 1, Name: "a", Type: bsttype.Any()}}},
+			bytes.NewReader([]byte{0x00, 0x00})); err == nil {
+			t.Fatal("expected any value to fail")
+		}
+	})
+
+	t.Run("Formats", func(t *testing.T) {
+		// The 16 byte identifiers are rendered as the UUID text, whereas the other bytes are not.
+		idType := &bsttype.Named{Module: "app", Name: "ID", Type: &bsttype.Bytes{FixedSize: 16}}
+		st := &bsttype.Struct{Fields: []bsttype.StructField{
+			{Index: 1, Name: "id", Type: idType},
+			{Index: 2, Name: "data", Type: &bsttype.Bytes{FixedSize: 16}},
+		}}
+		id := bstio.UUID{0x6b, 0xa7, 0xb8, 0x10}
+		var buf bytes.Buffer
+		c, err := bst.NewComposer(&buf, st, bst.ComposerOptions{})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err = c.WriteBytes(id[:]); err != nil {
+				t.Fatalf("writing bytes failed: %v", err)
+			}
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+
+		bst.DefaultFormats.Register("app", "ID", func(v any) (string, error) {
+			return bstio.UUID(v.([]byte)).String(), nil
+		})
+		defer bst.DefaultFormats.Register("app", "ID", nil)
+		out, err := ToJSON(st, bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("to JSON failed: %v", err)
+		}
+		expected := `{"id":"6ba7b810-0000-0000-0000-000000000000","data":"a6e4EAAAAAAAAAAAAAAAAA=="}`
+		if string(out) != expected {
+			t.Fatalf("unexpected JSON:\n%s\nexpected:\n%s", out, expected)
+		}
+	})
+}
+
+func TestAppendString(t *testing.T) {
+	for in, expected := range map[string]string{
+		"":             `""`,
+		"a\"b\\c":      `"a\"b\\c"`,
+		"\t\x01":       `"\t\u0001"`,
+		"zażółć":       `"zażółć"`,
+		"bad\xffutf-8": `"bad\ufffdutf-8"`,
+	} {
+		if actual := string(appendString(nil, in)); actual != expected {
+			t.Fatalf("unexpected JSON string of %q: %s, expected: %s", in, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package bstjson
+
+import (
+	"encoding/json"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// jsonType is the JSON description of the type.
+type jsonType struct {
+	Kind       string        `json:"kind"`
+	Module     string        `json:"module,omitempty"`
+	Name       string        `json:"name,omitempty"`
+	Fields     []jsonField   `json:"fields,omitempty"`
+	Elem       *jsonType     `json:"elem,omitempty"`
+	FixedSize  uint          `json:"fixedSize,omitempty"`
+	Key        *jsonType     `json:"key,omitempty"`
+	Value      *jsonType     `json:"value,omitempty"`
+	Elements   []jsonElement `json:"elements,omitempty"`
+	Descending bool          `json:"desc,omitempty"`
+}
+
+// jsonField is the JSON description of the struct field.
+type jsonField struct {
+	Index      uint      `json:"index"`
+	Name       string    `json:"name"`
+	Doc        string    `json:"doc,omitempty"`
+	Descending bool      `json:"desc,omitempty"`
+	Type       *jsonType `json:"type"`
+}
+
+// jsonElement is the JSON description of the enum or oneof element.
+type jsonElement struct {
+	Index uint      `json:"index"`
+	Name  string    `json:"name"`
+	Type  *jsonType `json:"type,omitempty"`
+}
+
+// jsonModule is the JSON description of the module.
+type jsonModule struct {
+	Name        string           `json:"name"`
+	Doc         string           `json:"doc,omitempty"`
+	Definitions []jsonDefinition `json:"definitions"`
+}
+
+// jsonDefinition is the JSON description of the module definition.
+type jsonDefinition struct {
+	Name string    `json:"name"`
+	Doc  string    `json:"doc,omitempty"`
+	Type *jsonType `json:"type"`
+}
+
+// TypeJSON returns the JSON description of the type, along with the doc strings of its struct fields.
+// It is meant for the inspection of the types embedded in the self-describing values. Each type is an object
+// of its kind, and of the kind specific members, i.e. the fields of the struct, or the key and value of the map.
+// The Named types are described by their module and name only - see the ModulesJSON for their definitions.
+func TypeJSON(t bsttype.Type) ([]byte, error) {
+	jt, err := describeType(t)
+	if err != nil {
+		return nil, err
+	}
+	return marshalDescription(jt)
+}
+
+// ModulesJSON returns the JSON description of the modules, along with the doc strings of the modules
+// and their definitions. The definition types are described as by the TypeJSON.
+func ModulesJSON(m *bsttype.Modules) ([]byte, error) {
+	mods := make([]jsonModule, 0, len(m.List))
+	for _, mod := range m.List {
+		jm := jsonModule{Name: mod.Name, Doc: mod.Doc, Definitions: make([]jsonDefinition, 0, len(mod.Definitions))}
+		for _, def := range mod.Definitions {
+			jt, err := describeType(def.Type)
+			if err != nil {
+				return nil, err
+			}
+			jm.Definitions = append(jm.Definitions, jsonDefinition{Name: def.Name, Doc: def.Doc, Type: jt})
+		}
+		mods = append(mods, jm)
+	}
+	return marshalDescription(mods)
+}
+
+// marshalDescription returns the JSON of the type or modules description.
+func marshalDescription(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to marshal type description")
+	}
+	return data, nil
+}
+
+// describeType returns the JSON description of the type.
+func describeType(t bsttype.Type) (*jsonType, error) {
+	if t == nil {
+		return nil, bsterr.Err(bsterr.CodeUndefinedType, "described type is undefined")
+	}
+	jt := &jsonType{Kind: t.Kind().String()}
+	var err error
+	switch tt := t.(type) {
+	case *bsttype.Named:
+		jt.Module, jt.Name = tt.Module, tt.Name
+	case *bsttype.Struct:
+		jt.Fields = make([]jsonField, len(tt.Fields))
+		for i, f := range tt.Fields {
+			jf := jsonField{Index: f.Index, Name: f.Name, Doc: f.Doc, Descending: f.Descending}
+			if jf.Type, err = describeType(f.Type); err != nil {
+				return nil, err
+			}
+			jt.Fields[i] = jf
+		}
+	case *bsttype.Array:
+		jt.FixedSize = tt.FixedSize
+		jt.Elem, err = describeType(tt.Type)
+	case *bsttype.Nullable:
+		jt.Elem, err = describeType(tt.Type)
+	case *bsttype.Map:
+		if jt.Key, err = describeType(tt.Key.Type); err != nil {
+			return nil, err
+		}
+		jt.Key.Descending = tt.Key.Descending
+		if jt.Value, err = describeType(tt.Value.Type); err != nil {
+			return nil, err
+		}
+		jt.Value.Descending = tt.Value.Descending
+	case *bsttype.Enum:
+		jt.Elements = make([]jsonElement, len(tt.Elements))
+		for i, e := range tt.Elements {
+			jt.Elements[i] = jsonElement{Index: e.Index, Name: e.String}
+		}
+	case *bsttype.OneOf:
+		jt.Elements = make([]jsonElement, len(tt.Elements))
+		for i, e := range tt.Elements {
+			je := jsonElement{Index: e.Index, Name: e.Name}
+			if je.Type, err = describeType(e.Type); err != nil {
+				return nil, err
+			}
+			jt.Elements[i] = je
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jt, nil
+}
@@ -0,0 +1,51 @@
+package bstjson
+
+import (
+	"testing"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestTypeJSON(t *testing.T) {
+	item := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "sku", Type: &bsttype.Bytes{}, Doc: "SKU is the stock keeping unit."},
+		{Index: 2, Name: "tags", Type: bsttype.ArrayOf(bsttype.String())},
+		{Index: 3, Name: "price", Type: bsttype.NullableOf(bsttype.Float32()), Descending: true},
+		{Index: 4, Name: "counts", Type: bsttype.MapTypeOf(bsttype.String(), bsttype.Uint8(), false, true)},
+		{Index: 5, Name: "color", Type: &bsttype.Enum{ValueBytes: 1, Elements: []bsttype.EnumElement{{String: "red", Index: 1}}}},
+		{Index: 6, Name: "parent", Type: &bsttype.Named{Module: "shop", Name: "item"}},
+	}}
+
+	// 1. The type is described along with the field doc strings.
+	data, err := TypeJSON(item)
+	if err != nil {
+		t.Fatalf("describing type failed: %v", err)
+	}
+	expected := `{"kind":"Struct","fields":[` +
+		`{"index":1,"name":"sku","doc":"SKU is the stock keeping unit.","type":{"kind":"Bytes"}},` +
+		`{"index":2,"name":"tags","type":{"kind":"Array","elem":{"kind":"String"}}},` +
+		`{"index":3,"name":"price","desc":true,"type":{"kind":"Nullable","elem":{"kind":"Float32"}}},` +
+		`{"index":4,"name":"counts","type":{"kind":"Map","key":{"kind":"String"},"value":{"kind":"Uint8","desc":true}}},` +
+		`{"index":5,"name":"color","type":{"kind":"Enum","elements":[{"index":1,"name":"red"}]}},` +
+		`{"index":6,"name":"parent","type":{"kind":"Named","module":"shop","name":"item"}}]}`
+	if string(data) != expected {
+		t.Fatalf("unexpected type JSON:\n%s\nexpected:\n%s", data, expected)
+	}
+
+	// 2. The modules are described along with their doc strings.
+	m := &bsttype.Modules{List: []*bsttype.Module{{
+		Name: "shop",
+		Doc:  "Shop is the module of the orders.",
+		Definitions: []bsttype.ModuleDefinition{
+			{Name: "status", Type: bsttype.Uint8(), Doc: "Status is the order status."},
+		},
+	}}}
+	if data, err = ModulesJSON(m); err != nil {
+		t.Fatalf("describing modules failed: %v", err)
+	}
+	expected = `[{"name":"shop","doc":"Shop is the module of the orders.","definitions":[` +
+		`{"name":"status","doc":"Status is the order status.","type":{"kind":"Uint8"}}]}]`
+	if string(data) != expected {
+		t.Fatalf("unexpected modules JSON:\n%s\nexpected:\n%s", data, expected)
+	}
+}
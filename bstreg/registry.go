@@ -0,0 +1,194 @@
+// Package bstreg provides the runtime schema registry of the BST types.
+//
+// The types are registered under their module, name and version, and are identified by their fingerprint,
+// which is the schema ID. The Registry implements the bst.SchemaRegistry, thus the composer with the registry
+// writes only the schema ID in the value header, instead of the embedded type and modules, and the extractor
+// with the same schemas registered resolves the type by that ID.
+package bstreg
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/devmodules/bst"
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+// Key is the versioned name of the registered schema.
+type Key struct {
+	Module  string
+	Name    string
+	Version uint
+}
+
+// Schema is the registered schema.
+type Schema struct {
+	Key
+	// ID is the fingerprint of the schema type.
+	ID uint64
+	// Type is the registered type.
+	Type bsttype.Type
+	// Modules are the dependencies of the type, or nil if it has no named dependencies.
+	Modules *bsttype.Modules
+}
+
+// Compile-time check to ensure that Registry implements the bst.SchemaRegistry interface.
+var _ bst.SchemaRegistry = (*Registry)(nil)
+
+// Registry is the schema registry, safe for concurrent use.
+// The registered types and modules are shared by all its users, thus they should not be changed afterwards.
+type Registry struct {
+	mu    sync.RWMutex
+	byKey map[Key]*Schema
+	byID  map[uint64]*Schema
+}
+
+// New creates a new empty schema registry.
+func New() *Registry {
+	return &Registry{
+		byKey: make(map[Key]*Schema),
+		byID:  make(map[uint64]*Schema),
+	}
+}
+
+// Fingerprint computes the schema ID of given type, which is the FNV-1a hash of the type binary.
+// The binary of the Named type is only its reference, thus the binary of its definition is hashed as well, if defined.
+// The doc strings are omitted, thus documenting the type doesn't change its schema ID.
+func Fingerprint(t bsttype.Type) (uint64, error) {
+	buf := iopool.GetBuffer(nil)
+	defer iopool.ReleaseBuffer(buf)
+
+	opts := bsttype.WriteTypeOptions{OmitDocs: true}
+	if _, err := bsttype.WriteTypeWithOptions(buf, t, opts); err != nil {
+		return 0, err
+	}
+	if nt, ok := t.(*bsttype.Named); ok && nt.Type != nil {
+		if _, err := bsttype.WriteTypeWithOptions(buf, nt.Type, opts); err != nil {
+			return 0, err
+		}
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(buf.Bytes)
+	return h.Sum64(), nil
+}
+
+// Register registers the type along with its modules under given key, and returns its schema ID.
+// Registering the same type under the same key again is a no-op, whereas registering a different one fails.
+// The types of the same fingerprint, registered under different keys, share the schema of the first one.
+func (x *Registry) Register(key Key, t bsttype.Type, modules *bsttype.Modules) (uint64, error) {
+	// 1. Validate the input.
+	if key.Name == "" {
+		return 0, bsterr.Err(bsterr.CodeInvalidValue, "schema name is required").
+			WithDetail("module", key.Module)
+	}
+	if t == nil {
+		return 0, bsterr.Err(bsterr.CodeInvalidType, "schema type is undefined").
+			WithDetails(bsterr.D("module", key.Module), bsterr.D("name", key.Name), bsterr.D("version", key.Version))
+	}
+
+	// 2. The modules are resolved once, so that the extractors could use them concurrently.
+	if modules != nil && !modules.IsResolved() {
+		if err := modules.Resolve(); err != nil {
+			return 0, err
+		}
+	}
+
+	// 3. Compute the schema ID.
+	id, err := Fingerprint(t)
+	if err != nil {
+		return 0, err
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	// 4. Check if the key is already registered.
+	if s, ok := x.byKey[key]; ok {
+		if s.ID != id {
+			return 0, bsterr.Err(bsterr.CodeTypeAlreadyMapped, "schema is already registered with a different type").
+				WithDetails(bsterr.D("module", key.Module), bsterr.D("name", key.Name), bsterr.D("version", key.Version))
+		}
+		return id, nil
+	}
+
+	// 5. Register the schema.
+	s := &Schema{Key: key, ID: id, Type: t, Modules: modules}
+	x.byKey[key] = s
+	if _, ok := x.byID[id]; !ok {
+		x.byID[id] = s
+	}
+	return id, nil
+}
+
+// Lookup returns the schema registered under given key.
+func (x *Registry) Lookup(key Key) (Schema, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	s, ok := x.byKey[key]
+	if !ok {
+		return Schema{}, false
+	}
+	return *s, true
+}
+
+// ByID returns the schema of given schema ID.
+func (x *Registry) ByID(id uint64) (Schema, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	s, ok := x.byID[id]
+	if !ok {
+		return Schema{}, false
+	}
+	return *s, true
+}
+
+// Versions returns the registered versions of the schema with given module and name, in ascending order.
+func (x *Registry) Versions(module, name string) []uint {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var versions []uint
+	for key := range x.byKey {
+		if key.Module == module && key.Name == name {
+			versions = append(versions, key.Version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// Latest returns the schema of the highest version registered with given module and name.
+func (x *Registry) Latest(module, name string) (Schema, bool) {
+	versions := x.Versions(module, name)
+	if len(versions) == 0 {
+		return Schema{}, false
+	}
+	return x.Lookup(Key{Module: module, Name: name, Version: versions[len(versions)-1]})
+}
+
+// SchemaID returns the schema ID of given type, if it is registered.
+// Implements the bst.SchemaRegistry interface.
+func (x *Registry) SchemaID(t bsttype.Type) (uint64, bool) {
+	id, err := Fingerprint(t)
+	if err != nil {
+		return 0, false
+	}
+	_, ok := x.ByID(id)
+	return id, ok
+}
+
+// Schema returns the type and modules of given schema ID.
+// Implements the bst.SchemaRegistry interface.
+func (x *Registry) Schema(id uint64) (bsttype.Type, *bsttype.Modules, error) {
+	s, ok := x.ByID(id)
+	if !ok {
+		return nil, nil, bsterr.Err(bsterr.CodeUndefinedType, "schema is not registered").
+			WithDetail("schemaID", id)
+	}
+	return s.Type, s.Modules, nil
+}
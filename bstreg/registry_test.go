@@ -0,0 +1,133 @@
+package bstreg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/devmodules/bst"
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestRegistry(t *testing.T) {
+	structOf := func(extra bool) *bsttype.Struct {
+		st := &bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "ID", Type: bsttype.Uint64()},
+				{Index: 2, Name: "Name", Type: bsttype.String()},
+			},
+		}
+		if extra {
+			st.Fields = append(st.Fields, bsttype.StructField{Index: 3, Name: "Email", Type: bsttype.String()})
+		}
+		return st
+	}
+
+	reg := New()
+	v1, v2 := Key{Module: "users", Name: "User", Version: 1}, Key{Module: "users", Name: "User", Version: 2}
+	id1, err := reg.Register(v1, structOf(false), nil)
+	if err != nil {
+		t.Fatalf("registering schema failed: %v", err)
+	}
+	id2, err := reg.Register(v2, structOf(true), nil)
+	if err != nil {
+		t.Fatalf("registering schema failed: %v", err)
+	}
+	if id1 == id2 {
+		t.Fatal("different schemas share the same id")
+	}
+
+	t.Run("Lookup", func(t *testing.T) {
+		if s, ok := reg.Lookup(v1); !ok || s.ID != id1 {
+			t.Fatalf("unexpected schema: %+v", s)
+		}
+		if s, ok := reg.ByID(id2); !ok || s.Key != v2 {
+			t.Fatalf("unexpected schema: %+v", s)
+		}
+		if s, ok := reg.Latest("users", "User"); !ok || s.Version != 2 {
+			t.Fatalf("unexpected latest schema: %+v", s)
+		}
+		if versions := reg.Versions("users", "User"); len(versions) != 2 || versions[0] != 1 || versions[1] != 2 {
+			t.Fatalf("unexpected versions: %v", versions)
+		}
+		if _, ok := reg.Lookup(Key{Module: "users", Name: "User", Version: 3}); ok {
+			t.Fatal("unexpected schema of unregistered version")
+		}
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		if id, err := reg.Register(v1, structOf(false), nil); err != nil || id != id1 {
+			t.Fatalf("registering the same schema again failed: %v", err)
+		}
+		if _, err := reg.Register(v1, structOf(true), nil); err == nil {
+			t.Fatal("expected registering a different type under the same key to fail")
+		}
+		if _, err := reg.Register(Key{Module: "users"}, structOf(false), nil); err == nil {
+			t.Fatal("expected registering schema without name to fail")
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		compose := func(t *testing.T, options bst.ComposerOptions) []byte {
+			var buf bytes.Buffer
+			c, err := bst.NewComposer(&buf, structOf(true), options)
+			if err != nil {
+				t.Fatalf("creating composer failed: %v", err)
+			}
+			if err = c.WriteUint64(7); err == nil {
+				if err = c.WriteString("john"); err == nil {
+					err = c.WriteString("john@example.com")
+				}
+			}
+			if err != nil {
+				t.Fatalf("composing value failed: %v", err)
+			}
+			return buf.Bytes()
+		}
+
+		embedded := compose(t, bst.ComposerOptions{EmbedType: true})
+		withID := compose(t, bst.ComposerOptions{EmbedType: true, SchemaRegistry: reg})
+		if len(withID) >= len(embedded) {
+			t.Fatalf("schema id header is not shorter: %d bytes, embedded: %d bytes", len(withID), len(embedded))
+		}
+
+		x, err := bst.NewExtractor(bytes.NewReader(withID), bst.ExtractorOptions{SchemaRegistry: reg})
+		if err != nil {
+			t.Fatalf("creating extractor failed: %v", err)
+		}
+		defer x.Close()
+		if id, err := Fingerprint(x.EmbedType()); err != nil || id != id2 {
+			t.Fatalf("unexpected resolved type: %v", x.EmbedType())
+		}
+		var email string
+		for x.Next() {
+			if x.Index() != 2 {
+				if _, err = x.Skip(); err != nil {
+					break
+				}
+				continue
+			}
+			if email, err = x.ReadString(); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = x.Err()
+		}
+		if err != nil || email != "john@example.com" {
+			t.Fatalf("unexpected extracted value: %q, err: %v", email, err)
+		}
+
+		// The extractor without the registry, or with a registry which doesn't know the schema, fails.
+		if _, err = bst.NewExtractor(bytes.NewReader(withID), bst.ExtractorOptions{}); err == nil {
+			t.Fatal("expected extracting schema id without registry to fail")
+		}
+		if _, err = bst.NewExtractor(bytes.NewReader(withID), bst.ExtractorOptions{SchemaRegistry: New()}); err == nil {
+			t.Fatal("expected extracting unregistered schema id to fail")
+		}
+
+		// The unregistered type is embedded as is.
+		if fallback := compose(t, bst.ComposerOptions{EmbedType: true, SchemaRegistry: New()}); !bytes.Equal(fallback, embedded) {
+			t.Fatal("unregistered type is not embedded")
+		}
+	})
+}
@@ -3,6 +3,7 @@ package bstskip
 import (
 	"io"
 
+	"github.com/devmodules/bst/bstcol"
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
 	"github.com/devmodules/bst/bsttype"
@@ -20,9 +21,26 @@ func arraySkipFunc(at *bsttype.Array) SkipFunc {
 			err error
 		)
 		length := at.FixedSize
+		if !at.HasFixedSize() && options.Comparable {
+			// The comparable array binary is escaped, and terminated by the array escape sequence.
+			escape := bstio.ArrayEscapeAscending
+			if options.Descending {
+				escape = bstio.ArrayEscapeDescending
+			}
+			return bstio.SkipComparableBytes(rs, 16, escape)
+		}
+		if at.Encoding == bsttype.ArrayEncodingRunLength && !options.Comparable {
+			return skipRunLengthArray(rs, at, options)
+		}
+		if at.Encoding == bsttype.ArrayEncodingNullBitmap && !options.Comparable {
+			return skipNullBitmapArray(rs, at, options)
+		}
+		if at.Encoding == bsttype.ArrayEncodingFloatColumn && !options.Comparable {
+			return bstcol.SkipColumn(rs)
+		}
 		if !at.HasFixedSize() {
 			var ni int
-			length, ni, err = bstio.ReadUint(rs, options.Descending)
+			length, ni, err = bstio.ReadLength(rs, options.Descending, bsttype.MinEncodedSize(at.Elem()))
 			if err != nil {
 				return int64(ni), err
 			}
@@ -48,7 +66,17 @@ func arraySkipFunc(at *bsttype.Array) SkipFunc {
 			}
 			return n + int64(bytesNo), nil
 		default:
-			skipFunc := SkipFuncOf(elem)
+			// The arrays of fixed size elements are skipped with a single seek.
+			if size, ok := bsttype.FixedEncodedSize(elem); ok {
+				bytesNo := int64(length) * int64(size)
+				_, err = rs.Seek(bytesNo, io.SeekCurrent)
+				if err != nil {
+					return n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip array")
+				}
+				return n + bytesNo, nil
+			}
+
+			skipFunc := ElemSkipFuncOf(elem, options)
 			total := n
 			for i := uint(0); i < length; i++ {
 				n, err = skipFunc(rs, options)
@@ -61,3 +89,86 @@ func arraySkipFunc(at *bsttype.Array) SkipFunc {
 		}
 	}
 }
+
+func skipRunLengthArray(rs io.ReadSeeker, at *bsttype.Array, options bstio.ValueOptions) (int64, error) {
+	// 1. Find the binary size of the run values.
+	size, err := at.RunLengthValueSize()
+	if err != nil {
+		return 0, err
+	}
+
+	// 2. Read the length of the variable size array.
+	//    The runs might take fewer bytes than the elements, thus the length is not checked against the input.
+	var n int64
+	length := at.FixedSize
+	if !at.HasFixedSize() {
+		var ni int
+		length, ni, err = bstio.ReadLength(rs, options.Descending, 0)
+		if err != nil {
+			return int64(ni), err
+		}
+		n += int64(ni)
+	}
+
+	// 3. Skip the runs.
+	ns, err := bstio.SkipRunLength(rs, length, size, options.Descending)
+	return n + ns, err
+}
+
+func skipNullBitmapArray(rs io.ReadSeeker, at *bsttype.Array, options bstio.ValueOptions) (int64, error) {
+	if err := at.VerifyEncoding(); err != nil {
+		return 0, err
+	}
+
+	// 1. Read the length of the variable size array.
+	var (
+		n   int64
+		err error
+	)
+	length := at.FixedSize
+	if !at.HasFixedSize() {
+		var ni int
+		length, ni, err = bstio.ReadLength(rs, options.Descending, 0)
+		if err != nil {
+			return int64(ni), err
+		}
+		n += int64(ni)
+	}
+
+	// 2. Read the null bitmap.
+	size := (length + 7) >> 3
+	if err = bstio.CheckLength(rs, size, 1); err != nil {
+		return n, err
+	}
+	bm, ni, err := bstio.ReadFixedSizeBytes(rs, int(size), false)
+	n += int64(ni)
+	if err != nil {
+		return n, err
+	}
+
+	// 3. Skip the not null values.
+	skipFunc := SkipFuncOf(derefNullable(at.Elem()))
+	for i := uint(0); i < length; i++ {
+		if bm[i>>3]&(1<<(i&7)) != 0 {
+			continue
+		}
+		ns, err := skipFunc(rs, options)
+		n += ns
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// derefNullable returns the value type of the nullable type, referenced by the resolved named types.
+func derefNullable(t bsttype.Type) bsttype.Type {
+	for {
+		nt, ok := t.(*bsttype.Named)
+		if !ok || nt.Type == nil {
+			break
+		}
+		t = nt.Type
+	}
+	return t.(*bsttype.Nullable).Type
+}
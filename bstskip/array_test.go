@@ -0,0 +1,57 @@
+package bstskip
+
+import (
+	"testing"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+func TestSkipArray(t *testing.T) {
+	testCases := []struct {
+		Name string
+		Type *bsttype.Array
+		Data []byte
+	}{
+		{
+			Name: "Uint16",
+			Type: bsttype.ArrayOf(bsttype.Uint16()),
+			Data: []byte{
+				0x01, // Length binary size
+				0x03, // Length
+				0x00, 0x01, 0x00, 0x02, 0x00, 0x03,
+			},
+		},
+		{
+			Name: "FixedSize/Enum",
+			Type: bsttype.FixedSizeArrayOf(&bsttype.Enum{ValueBytes: bstio.BinarySizeUint8}, 2),
+			Data: []byte{0x01, 0x02},
+		},
+		{
+			Name: "FixedSize/Bytes",
+			Type: bsttype.ArrayOf(&bsttype.Bytes{FixedSize: 3}),
+			Data: []byte{
+				0x01, // Length binary size
+				0x02, // Length
+				'a', 'b', 'c', 'd', 'e', 'f',
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			// Trailing byte must not be skipped.
+			r := iopool.GetReadSeeker(append(tc.Data, 0xFF))
+			defer iopool.ReleaseReadSeeker(r)
+
+			n, err := SkipArray(r, tc.Type, bstio.ValueOptions{})
+			if err != nil {
+				t.Fatalf("skipping array failed: %v", err)
+			}
+			if int(n) != len(tc.Data) {
+				t.Fatalf("unexpected number of skipped bytes: %d, expected: %d", n, len(tc.Data))
+			}
+		})
+	}
+}
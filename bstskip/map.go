@@ -14,26 +14,57 @@ func SkipMap(br io.ReadSeeker, x *bsttype.Map, options bstio.ValueOptions) (int6
 
 func mapSkipFunc(x *bsttype.Map) SkipFunc {
 	return func(br io.ReadSeeker, options bstio.ValueOptions) (int64, error) {
-		// 1. Decode the number of entries.
-		length, n, err := bstio.ReadUint(br, options.Descending)
+		// 1. The comparable map binary is escaped, and terminated by the map escape sequence.
+		if options.Comparable {
+			escape := bstio.MapEscapeAscending
+			if options.Descending {
+				escape = bstio.MapEscapeDescending
+			}
+			return bstio.SkipComparableBytes(br, 16, escape)
+		}
+
+		// 2. Decode the number of entries.
+		minEntrySize := bsttype.MinEncodedSize(x.Key.Type) + bsttype.MinEncodedSize(x.Value.Type)
+		length, n, err := bstio.ReadLength(br, options.Descending, minEntrySize)
 		if err != nil {
 			return int64(n), err
 		}
 		bytesSkipped := int64(n)
 
-		// 2. Initialize empty map key and value.
-		ek, ev := SkipFuncOf(x.Key.Type), SkipFuncOf(x.Value.Type)
+		// 2.1. The indexed map entries and their directory are skipped at once, by the binary size of the entries.
+		if x.Indexed {
+			size, n, err := bstio.ReadMapEntriesSize(br, length, options.Descending)
+			bytesSkipped += int64(n)
+			if err != nil {
+				return bytesSkipped, err
+			}
+			skip := int64(size) + int64(length)*bstio.MapDirectoryEntrySize
+			if _, err = br.Seek(skip, io.SeekCurrent); err != nil {
+				return bytesSkipped, err
+			}
+			return bytesSkipped + skip, nil
+		}
+
+		// 3. Initialize empty map key and value, along with their order.
+		ek, ev := ElemSkipFuncOf(x.Key.Type, options), ElemSkipFuncOf(x.Value.Type, options)
+		ko, vo := options, options
+		if x.Key.Descending {
+			ko.Descending = !ko.Descending
+		}
+		if x.Value.Descending {
+			vo.Descending = !vo.Descending
+		}
 
-		// 3. Iterate over the map entries and skip each entry.
+		// 4. Iterate over the map entries and skip each entry.
 		var skipped int64
 		for i := uint(0); i < length; i++ {
-			skipped, err = ek(br, options)
+			skipped, err = ek(br, ko)
 			if err != nil {
 				return bytesSkipped + skipped, err
 			}
 			bytesSkipped += skipped
 
-			skipped, err = ev(br, options)
+			skipped, err = ev(br, vo)
 			if err != nil {
 				return bytesSkipped + skipped, err
 			}
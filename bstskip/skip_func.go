@@ -16,26 +16,31 @@ func SkipFuncOf(t bsttype.Type) SkipFunc {
 // SkipFunc is a function that skips a value.
 type SkipFunc func(br io.ReadSeeker, options bstio.ValueOptions) (int64, error)
 
-var _SkipFuncs = [bsttype.KindOneOf + 1]func(bsttype.Type) SkipFunc{
-	bsttype.KindUndefined: func(t bsttype.Type) SkipFunc { return undefinedSkipFunc },
-	bsttype.KindBoolean:   func(t bsttype.Type) SkipFunc { return booleanSkipFunc },
-	bsttype.KindInt:       func(t bsttype.Type) SkipFunc { return intSkipFunc },
-	bsttype.KindInt8:      func(t bsttype.Type) SkipFunc { return int8SkipFunc },
-	bsttype.KindInt16:     func(t bsttype.Type) SkipFunc { return int16SkipFunc },
-	bsttype.KindInt32:     func(t bsttype.Type) SkipFunc { return int32SkipFunc },
-	bsttype.KindInt64:     func(t bsttype.Type) SkipFunc { return int64SkipFunc },
-	bsttype.KindUint:      func(t bsttype.Type) SkipFunc { return uintSkipFunc },
-	bsttype.KindUint8:     func(t bsttype.Type) SkipFunc { return uint8SkipFunc },
-	bsttype.KindUint16:    func(t bsttype.Type) SkipFunc { return uint16SkipFunc },
-	bsttype.KindUint32:    func(t bsttype.Type) SkipFunc { return uint32SkipFunc },
-	bsttype.KindUint64:    func(t bsttype.Type) SkipFunc { return uint64SkipFunc },
-	bsttype.KindFloat32:   func(t bsttype.Type) SkipFunc { return float32SkipFunc },
-	bsttype.KindFloat64:   func(t bsttype.Type) SkipFunc { return float64SkipFunc },
-	bsttype.KindString:    func(t bsttype.Type) SkipFunc { return stringSkipFunc },
-	bsttype.KindDuration:  func(t bsttype.Type) SkipFunc { return int64SkipFunc },
-	bsttype.KindTimestamp: func(t bsttype.Type) SkipFunc { return int64SkipFunc },
-	bsttype.KindBytes:     func(t bsttype.Type) SkipFunc { return bytesSkipFunc(t.(*bsttype.Bytes)) },
-	bsttype.KindEnum:      func(t bsttype.Type) SkipFunc { return enumSkipFunc(t.(*bsttype.Enum)) },
+var _SkipFuncs = [bsttype.KindUUID + 1]func(bsttype.Type) SkipFunc{
+	bsttype.KindUndefined:     func(t bsttype.Type) SkipFunc { return undefinedSkipFunc },
+	bsttype.KindBoolean:       func(t bsttype.Type) SkipFunc { return booleanSkipFunc },
+	bsttype.KindInt:           func(t bsttype.Type) SkipFunc { return intSkipFunc },
+	bsttype.KindInt8:          func(t bsttype.Type) SkipFunc { return int8SkipFunc },
+	bsttype.KindInt16:         func(t bsttype.Type) SkipFunc { return int16SkipFunc },
+	bsttype.KindInt32:         func(t bsttype.Type) SkipFunc { return int32SkipFunc },
+	bsttype.KindInt64:         func(t bsttype.Type) SkipFunc { return int64SkipFunc },
+	bsttype.KindUint:          func(t bsttype.Type) SkipFunc { return uintSkipFunc },
+	bsttype.KindUint8:         func(t bsttype.Type) SkipFunc { return uint8SkipFunc },
+	bsttype.KindUint16:        func(t bsttype.Type) SkipFunc { return uint16SkipFunc },
+	bsttype.KindUint32:        func(t bsttype.Type) SkipFunc { return uint32SkipFunc },
+	bsttype.KindUint64:        func(t bsttype.Type) SkipFunc { return uint64SkipFunc },
+	bsttype.KindFloat32:       func(t bsttype.Type) SkipFunc { return float32SkipFunc },
+	bsttype.KindFloat64:       func(t bsttype.Type) SkipFunc { return float64SkipFunc },
+	bsttype.KindString:        func(t bsttype.Type) SkipFunc { return stringSkipFunc },
+	bsttype.KindDuration:      func(t bsttype.Type) SkipFunc { return int64SkipFunc },
+	bsttype.KindTimestamp:     func(t bsttype.Type) SkipFunc { return int64SkipFunc },
+	bsttype.KindDateTime:      func(t bsttype.Type) SkipFunc { return dateTimeSkipFunc },
+	bsttype.KindBytes:         func(t bsttype.Type) SkipFunc { return bytesSkipFunc(t.(*bsttype.Bytes)) },
+	bsttype.KindEnum:          func(t bsttype.Type) SkipFunc { return enumSkipFunc(t.(*bsttype.Enum)) },
+	bsttype.KindExternalBytes: func(t bsttype.Type) SkipFunc { return externalBytesSkipFunc },
+	bsttype.KindBitmap:        func(t bsttype.Type) SkipFunc { return bitmapSkipFunc },
+	bsttype.KindDecimal:       func(t bsttype.Type) SkipFunc { return decimalSkipFunc },
+	bsttype.KindUUID:          func(t bsttype.Type) SkipFunc { return uuidSkipFunc },
 }
 
 func init() {
@@ -52,10 +57,30 @@ func undefinedSkipFunc(_ io.ReadSeeker, _ bstio.ValueOptions) (int64, error) {
 	return 0, bsterr.Err(bsterr.CodeUndefinedType, "undefined type cannot be skipped")
 }
 
+func externalBytesSkipFunc(rs io.ReadSeeker, _ bstio.ValueOptions) (int64, error) {
+	return bstio.SkipBlobRef(rs)
+}
+
+func bitmapSkipFunc(rs io.ReadSeeker, o bstio.ValueOptions) (int64, error) {
+	return bstio.SkipBitmap(rs, o.Descending, o.Comparable)
+}
+
+func decimalSkipFunc(rs io.ReadSeeker, o bstio.ValueOptions) (int64, error) {
+	return bstio.SkipDecimal(rs, o.Descending)
+}
+
+func uuidSkipFunc(rs io.ReadSeeker, _ bstio.ValueOptions) (int64, error) {
+	return bstio.SkipUUID(rs)
+}
+
 func booleanSkipFunc(br io.ReadSeeker, _ bstio.ValueOptions) (int64, error) {
 	return bstio.SkipBool(br)
 }
 
+func dateTimeSkipFunc(rs io.ReadSeeker, options bstio.ValueOptions) (int64, error) {
+	return bstio.SkipDateTime(rs, options.Descending)
+}
+
 func intSkipFunc(rs io.ReadSeeker, options bstio.ValueOptions) (int64, error) {
 	return bstio.SkipInt(rs, options.Descending, options.Comparable)
 }
@@ -119,9 +144,104 @@ func enumSkipFunc(et *bsttype.Enum) SkipFunc {
 }
 
 func nullableSkipFunc(nt *bsttype.Nullable) SkipFunc {
-	return SkipFuncOf(nt.Type)
+	elemSkip := SkipFuncOf(nt.Type)
+	return func(rs io.ReadSeeker, options bstio.ValueOptions) (int64, error) {
+		// 1. Read the null flag.
+		nf, err := bstio.ReadNullableFlag(rs, options.Descending)
+		if err != nil {
+			return 0, err
+		}
+
+		// 2. A null value has no binary other than the flag.
+		if nf == bstio.NullableIsNull {
+			return 1, nil
+		}
+
+		// 3. Otherwise, skip the value.
+		n, err := elemSkip(rs, options)
+		if err != nil {
+			return n + 1, err
+		}
+		return n + 1, nil
+	}
 }
 
 func namedSkipFunc(nt *bsttype.Named) SkipFunc {
 	return SkipFuncOf(nt.Type)
 }
+
+// LengthPrefixed reports whether the value of given type, being an array or map element, is prefixed
+// with its binary size. This is the case for the variable size arrays and maps, if the LengthPrefixed option is set
+// in the compatibility mode of non-comparable binaries.
+func LengthPrefixed(t bsttype.Type, options bstio.ValueOptions) bool {
+	if !options.LengthPrefixed || !options.CompatibilityMode || options.Comparable {
+		return false
+	}
+	for {
+		nt, ok := t.(*bsttype.Named)
+		if !ok {
+			break
+		}
+		t = nt.Type
+	}
+	switch t.Kind() {
+	case bsttype.KindArray, bsttype.KindMap:
+		_, fixed := bsttype.FixedEncodedSize(t)
+		return !fixed
+	default:
+		return false
+	}
+}
+
+// SkipLengthPrefixed skips the value prefixed with its binary size.
+func SkipLengthPrefixed(rs io.ReadSeeker, _ bstio.ValueOptions) (int64, error) {
+	size, n, err := bstio.ReadUint(rs, false)
+	if err != nil {
+		return int64(n), err
+	}
+	if _, err = rs.Seek(int64(size), io.SeekCurrent); err != nil {
+		return int64(n), bsterr.ErrWrap(err, bsterr.CodeSkippingBinaryValue, "failed to skip length prefixed value")
+	}
+	return int64(n) + int64(size), nil
+}
+
+// ElemSkipFuncOf gets a skip function of the array or map element of given type.
+func ElemSkipFuncOf(t bsttype.Type, options bstio.ValueOptions) SkipFunc {
+	if LengthPrefixed(t, options) {
+		return SkipLengthPrefixed
+	}
+	return SkipFuncOf(t)
+}
+
+// FieldSkipFuncOf gets a skip function of the struct field value, taking its field encoding into account.
+func FieldSkipFuncOf(f bsttype.StructField, options bstio.ValueOptions) SkipFunc {
+	if f.Encoding == bsttype.FieldEncodingExternal && !options.Comparable {
+		return ExternalFieldSkipFunc(f.Type)
+	}
+	return SkipFuncOf(f.Type)
+}
+
+// ExternalFieldSkipFunc gets a skip function of the external field value of given type,
+// which is either the inlined value or the blob reference.
+func ExternalFieldSkipFunc(t bsttype.Type) SkipFunc {
+	inline := SkipFuncOf(t)
+	return func(rs io.ReadSeeker, options bstio.ValueOptions) (int64, error) {
+		// 1. Read the tag of the value.
+		tag, err := bstio.ReadByte(rs)
+		if err != nil {
+			return 0, bsterr.ErrWrap(err, bsterr.CodeSkippingBinaryValue, "failed to read external field tag")
+		}
+
+		// 2. Skip the inlined value or the blob reference.
+		var n int64
+		switch tag {
+		case bstio.ExternalInlineTag:
+			n, err = inline(rs, options)
+		case bstio.ExternalRefTag:
+			n, err = bstio.SkipBlobRef(rs)
+		default:
+			return 1, bsterr.Err(bsterr.CodeMalformedBinary, "invalid external field tag").WithDetail("tag", tag)
+		}
+		return n + 1, err
+	}
+}
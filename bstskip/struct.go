@@ -8,21 +8,15 @@ import (
 	"github.com/devmodules/bst/bsttype"
 )
 
-// SkipStruct skips the struct type.
-// NOTE: Add compatibility mode in the input.
-//
-//	This changes the behavior of the function.
+// SkipStruct skips the struct type, whose binary in the compatibility mode is prefixed with the struct header.
 func SkipStruct(br io.ReadSeeker, x *bsttype.Struct, options bstio.ValueOptions) (int64, error) {
-	if options.CompatibilityMode {
-		return structSkipCompatibilityStruct(x)(br, options)
-	}
 	return structSkipFunc(x)(br, options)
 }
 
 func structSkipCompatibilityStruct(x *bsttype.Struct) SkipFunc {
 	return func(br io.ReadSeeker, options bstio.ValueOptions) (int64, error) {
-		// 1. Read struct header.
-		maxIndex, n, err := bstio.ReadUint(br, false)
+		// 1. Read struct header, which is the max index of the fields that follow.
+		count, n, err := bstio.ReadStructHeader(br)
 		if err != nil {
 			return int64(n), bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to read struct header")
 		}
@@ -32,7 +26,7 @@ func structSkipCompatibilityStruct(x *bsttype.Struct) SkipFunc {
 			n64         int64
 			bytesToSkip uint
 		)
-		for i := uint(0); i < maxIndex; i++ {
+		for i := 0; i < count; i++ {
 			n64, err = bstio.SkipUint(br, false)
 			if err != nil {
 				return total, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to read compatibility field header index")
@@ -58,7 +52,14 @@ func structSkipCompatibilityStruct(x *bsttype.Struct) SkipFunc {
 }
 
 func structSkipFunc(x *bsttype.Struct) SkipFunc {
+	compatibilitySkip := structSkipCompatibilityStruct(x)
 	return func(br io.ReadSeeker, options bstio.ValueOptions) (int64, error) {
+		// 1. The structs of the compatibility mode are prefixed with their header, i.e. the nested ones
+		//    or the map keys, thus their fields are skipped by their length headers.
+		if options.CompatibilityMode {
+			return compatibilitySkip(br, options)
+		}
+
 		var (
 			total, n int64
 			err      error
@@ -67,13 +68,14 @@ func structSkipFunc(x *bsttype.Struct) SkipFunc {
 
 		for fi, f := range x.Fields {
 			if f.Type.Kind() == bsttype.KindBoolean {
-				prev, ok := x.PreviewPrevElemType(fi)
-				if !ok || boolPos == 0 || (ok && prev.Kind() == bsttype.KindBoolean) {
+				prev, ok := x.PreviewPrevElemType(fi - 1)
+				if !ok || boolPos == 0 || (ok && prev.Kind() != bsttype.KindBoolean) {
 					n, err = bstio.SkipUint8Value(br)
 					if err != nil {
 						return total, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to read bool value")
 					}
 					total += n
+					boolPos = 0
 				}
 				boolPos++
 
@@ -83,7 +85,11 @@ func structSkipFunc(x *bsttype.Struct) SkipFunc {
 				continue
 			}
 
-			n, err = SkipFuncOf(f.Type)(br, options)
+			fo := options
+			if f.Descending {
+				fo.Descending = !fo.Descending
+			}
+			n, err = FieldSkipFuncOf(f, fo)(br, fo)
 			if err != nil {
 				return total, err
 			}
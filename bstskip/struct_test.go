@@ -13,7 +13,7 @@ func TestSkipStruct(t *testing.T) {
 		data := []byte{
 			// Struct Compatibility Header:
 			0x01, // Max Index binary size
-			0x04, // Max Index value
+			0x03, // Max Index value: 3 - the fields are counted from 0
 			// Field ID:
 			// Compatibility Index:
 			0x01, // Index binary size
@@ -89,5 +89,16 @@ func TestSkipStruct(t *testing.T) {
 		if int(n) != len(data) {
 			t.Fatalf("Expected %d, got %d", len(data), n)
 		}
+
+		// The skip function of the struct type, i.e. of the nested structs or the map keys, skips it the same way.
+		sr := iopool.GetReadSeeker(data)
+		defer iopool.ReleaseReadSeeker(sr)
+		n, err = SkipFuncOf(st)(sr, bstio.ValueOptions{CompatibilityMode: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(n) != len(data) {
+			t.Fatalf("Expected %d, got %d", len(data), n)
+		}
 	})
 }
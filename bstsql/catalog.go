@@ -0,0 +1,192 @@
+// Package bstsql maps the SQL table descriptors, i.e. the rows of the database information schema,
+// onto the BST struct types. It works only with the provided descriptors and never connects to the database.
+//
+// The default column type mapping:
+//   - boolean, bool - Boolean,
+//   - tinyint - Int8, smallint, int2 - Int16, integer, int, int4, serial - Int32, bigint, int8, bigserial - Int64,
+//   - real, float4 - Float32, double precision, float8, float - Float64,
+//   - numeric, decimal - Decimal,
+//   - text, varchar, char, character varying, character, citext, json, jsonb, xml - String,
+//   - bytea, blob, binary, varbinary - Bytes, uuid - UUID,
+//   - timestamptz, timestamp with time zone - Timestamp, timestamp, timestamp without time zone, datetime - DateTime,
+//   - date - Timestamp, time, interval - Duration.
+//
+// The type parameters, i.e. 'varchar(255)' or 'numeric(10, 2)', are ignored. The array types, i.e. 'integer[]'
+// or '_int4', are mapped onto the Array of their element type.
+package bstsql
+
+import (
+	"strings"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// Column is the descriptor of the table column.
+type Column struct {
+	// Name is the name of the column, used as the struct field name.
+	Name string
+	// DataType is the SQL type of the column, i.e. 'bigint' or 'timestamp with time zone'. It is case-insensitive.
+	DataType string
+	// Nullable marks the column as nullable, which wraps its type in the Nullable.
+	Nullable bool
+	// Ordinal is the position of the column in the table, used as the struct field index.
+	// If zero, the field gets the next free index.
+	Ordinal uint
+}
+
+// Table is the descriptor of the database table.
+type Table struct {
+	// Name is the name of the table.
+	Name string
+	// Columns are the columns of the table.
+	Columns []Column
+}
+
+// TypeFunc creates the BST type of the SQL data type.
+// The returned type must be a new instance, as it becomes the part of the struct type.
+type TypeFunc func() bsttype.Type
+
+// Mapper maps the table descriptors onto the struct types.
+// The zero value uses the default column type mapping.
+type Mapper struct {
+	// Types overrides or extends the default column type mapping. The keys are the lower case SQL data types
+	// without their parameters, i.e. 'numeric' or 'character varying'.
+	Types map[string]TypeFunc
+	// Fallback creates the type of the columns with unknown data types. If nil, the unknown data types fail the mapping.
+	Fallback TypeFunc
+}
+
+// TableStruct maps the table descriptor onto the struct type with the default column type mapping.
+func TableStruct(t Table) (*bsttype.Struct, error) {
+	var m Mapper
+	return m.TableStruct(t)
+}
+
+// TableStruct maps the table descriptor onto the struct type. Each column turns into the struct field
+// of the same name, with the index of the column ordinal. All the column mapping issues are reported at once.
+func (x *Mapper) TableStruct(t Table) (*bsttype.Struct, error) {
+	st := &bsttype.Struct{}
+	var issues []string
+	for _, c := range t.Columns {
+		// 1. Map the column type.
+		ct, err := x.ColumnType(c)
+		if err != nil {
+			issues = append(issues, err.Error())
+			continue
+		}
+
+		// 2. Add the column field, with the index of its ordinal if defined.
+		var opts []bsttype.StructFieldOption
+		if c.Ordinal > 0 {
+			opts = append(opts, bsttype.WithFieldIndex(c.Ordinal))
+		}
+		if _, err = st.AddField(c.Name, ct, opts...); err != nil {
+			issues = append(issues, err.Error())
+		}
+	}
+	if len(issues) > 0 {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "failed to map table columns").
+			WithDetails(bsterr.D("table", t.Name), bsterr.D("issues", strings.Join(issues, "; ")))
+	}
+	return st, nil
+}
+
+// ColumnType maps the column onto its BST type. The nullable columns are wrapped in the Nullable.
+func (x *Mapper) ColumnType(c Column) (bsttype.Type, error) {
+	t, err := x.DataType(c.DataType)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeInvalidType, "failed to map column type").WithDetail("column", c.Name)
+	}
+	if c.Nullable {
+		t = bsttype.NullableOf(t)
+	}
+	return t, nil
+}
+
+// DataType maps the SQL data type onto the BST type. The array data types are mapped onto the Array of their element type.
+func (x *Mapper) DataType(dataType string) (bsttype.Type, error) {
+	// 1. Normalize the data type, and unwrap the array element type.
+	name := normalizeDataType(dataType)
+	elem, isArray := arrayElemType(name)
+	if isArray {
+		et, err := x.DataType(elem)
+		if err != nil {
+			return nil, err
+		}
+		return bsttype.ArrayOf(et), nil
+	}
+
+	// 2. Look up the overrides, then the defaults and finally the fallback.
+	if fn, ok := x.Types[name]; ok {
+		return fn(), nil
+	}
+	if fn, ok := _defaultTypes[name]; ok {
+		return fn(), nil
+	}
+	if x.Fallback != nil {
+		return x.Fallback(), nil
+	}
+	return nil, bsterr.Err(bsterr.CodeInvalidType, "unsupported sql data type").WithDetail("dataType", dataType)
+}
+
+// normalizeDataType lower cases the data type, strips its parameters and collapses the whitespaces,
+// i.e. 'CHARACTER VARYING(255)' turns into 'character varying'.
+func normalizeDataType(dataType string) string {
+	name := strings.ToLower(strings.TrimSpace(dataType))
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		if j := strings.IndexByte(name[i:], ')'); j >= 0 {
+			name = name[:i] + name[i+j+1:]
+		}
+	}
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// arrayElemType returns the element type of the array data type, written either as 'type[]', 'type array' or '_type'.
+func arrayElemType(name string) (string, bool) {
+	switch {
+	case strings.HasSuffix(name, "[]"):
+		return strings.TrimSpace(strings.TrimSuffix(name, "[]")), true
+	case strings.HasSuffix(name, " array"):
+		return strings.TrimSuffix(name, " array"), true
+	case strings.HasPrefix(name, "_"):
+		return strings.TrimPrefix(name, "_"), true
+	default:
+		return "", false
+	}
+}
+
+// _defaultTypes is the default mapping of the normalized SQL data types.
+var _defaultTypes = map[string]TypeFunc{
+	"boolean": boolType, "bool": boolType,
+	"tinyint":  int8Type,
+	"smallint": int16Type, "int2": int16Type, "smallserial": int16Type,
+	"integer": int32Type, "int": int32Type, "int4": int32Type, "serial": int32Type,
+	"bigint": int64Type, "int8": int64Type, "bigserial": int64Type,
+	"real": float32Type, "float4": float32Type,
+	"double precision": float64Type, "float8": float64Type, "float": float64Type, "double": float64Type,
+	"numeric": decimalType, "decimal": decimalType,
+	"text": stringType, "varchar": stringType, "char": stringType, "character varying": stringType,
+	"character": stringType, "citext": stringType, "json": stringType, "jsonb": stringType, "xml": stringType,
+	"bytea": bytesType, "blob": bytesType, "binary": bytesType, "varbinary": bytesType,
+	"uuid":        uuidType,
+	"timestamptz": timestampType, "timestamp with time zone": timestampType,
+	"timestamp": dateTimeType, "timestamp without time zone": dateTimeType, "datetime": dateTimeType,
+	"date": timestampType,
+	"time": durationType, "time without time zone": durationType, "interval": durationType,
+}
+
+func boolType() bsttype.Type      { return bsttype.Boolean() }
+func int8Type() bsttype.Type      { return bsttype.Int8() }
+func int16Type() bsttype.Type     { return bsttype.Int16() }
+func int32Type() bsttype.Type     { return bsttype.Int32() }
+func int64Type() bsttype.Type     { return bsttype.Int64() }
+func float32Type() bsttype.Type   { return bsttype.Float32() }
+func float64Type() bsttype.Type   { return bsttype.Float64() }
+func decimalType() bsttype.Type   { return bsttype.Decimal() }
+func stringType() bsttype.Type    { return bsttype.String() }
+func timestampType() bsttype.Type { return bsttype.Timestamp() }
+func durationType() bsttype.Type  { return bsttype.Duration() }
+func bytesType() bsttype.Type     { return &bsttype.Bytes{} }
+func uuidType() bsttype.Type      { return bsttype.UUID() }
+func dateTimeType() bsttype.Type  { return &bsttype.DateTime{} }
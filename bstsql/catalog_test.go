@@ -0,0 +1,80 @@
+package bstsql
+
+import (
+	"testing"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestTableStruct(t *testing.T) {
+	table := Table{
+		Name: "orders",
+		Columns: []Column{
+			{Name: "id", DataType: "BIGINT", Ordinal: 1},
+			{Name: "customer_id", DataType: "uuid", Ordinal: 2},
+			{Name: "total", DataType: "numeric(10, 2)", Ordinal: 3},
+			{Name: "note", DataType: "character varying(255)", Nullable: true, Ordinal: 4},
+			{Name: "created_at", DataType: "timestamp with time zone", Ordinal: 5},
+			{Name: "tags", DataType: "text[]", Ordinal: 7},
+			{Name: "quantities", DataType: "_int4", Ordinal: 6},
+		},
+	}
+
+	st, err := TableStruct(table)
+	if err != nil {
+		t.Fatalf("mapping table failed: %v", err)
+	}
+
+	expected := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "id", Type: bsttype.Int64()},
+			{Index: 2, Name: "customer_id", Type: bsttype.UUID()},
+			{Index: 3, Name: "total", Type: bsttype.Decimal()},
+			{Index: 4, Name: "note", Type: bsttype.NullableOf(bsttype.String())},
+			{Index: 5, Name: "created_at", Type: bsttype.Timestamp()},
+			{Index: 6, Name: "quantities", Type: bsttype.ArrayOf(bsttype.Int32())},
+			{Index: 7, Name: "tags", Type: bsttype.ArrayOf(bsttype.String())},
+		},
+	}
+	if !st.CompareType(expected) {
+		t.Errorf("unexpected struct type: %s, expected: %s", st, expected)
+	}
+}
+
+func TestMapper(t *testing.T) {
+	t.Run("Override", func(t *testing.T) {
+		m := Mapper{Types: map[string]TypeFunc{"numeric": func() bsttype.Type { return bsttype.Float64() }}}
+		ct, err := m.DataType("NUMERIC(12,4)")
+		if err != nil {
+			t.Fatalf("mapping data type failed: %v", err)
+		}
+		if ct.Kind() != bsttype.KindFloat64 {
+			t.Errorf("unexpected kind: %s, expected: %s", ct.Kind(), bsttype.KindFloat64)
+		}
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		m := Mapper{Fallback: func() bsttype.Type { return &bsttype.Bytes{} }}
+		ct, err := m.DataType("geometry")
+		if err != nil {
+			t.Fatalf("mapping data type failed: %v", err)
+		}
+		if ct.Kind() != bsttype.KindBytes {
+			t.Errorf("unexpected kind: %s, expected: %s", ct.Kind(), bsttype.KindBytes)
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := TableStruct(Table{
+			Name: "shapes",
+			Columns: []Column{
+				{Name: "id", DataType: "integer"},
+				{Name: "area", DataType: "geometry"},
+				{Name: "id", DataType: "text"},
+			},
+		})
+		if err == nil {
+			t.Fatal("expected error for the unsupported and duplicated columns")
+		}
+	})
+}
@@ -0,0 +1,278 @@
+// Package bsttest provides the helpers for the unit tests of the code which maps the Go values to the BST types.
+package bsttest
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// DefaultTag is the default key of the struct tag, which maps the Go struct fields to the struct type fields.
+const DefaultTag = "bst"
+
+// TagRules defines how the tagged Go struct fields are mapped to the struct type fields.
+// The tag value is the name of the type field, optionally followed by the comma separated options:
+//   - index=N - the Index of the type field,
+//   - nullable - the type field is Nullable, even though the Go field is not a pointer.
+//
+// The untagged fields, and the ones tagged with '-' are not mapped.
+type TagRules struct {
+	// Tag is the key of the struct tag. The DefaultTag is used if empty.
+	Tag string
+	// RequireIndex requires each tagged field to define the index of its type field.
+	RequireIndex bool
+	// RequireAllFields requires each type field to be mapped by some Go struct field.
+	RequireAllFields bool
+}
+
+// AssertCompatible fails the test if the tagged fields of the Go struct T don't line up with the struct type.
+// It is meant for the static contract tests, which prevent the drift between the Go code and its schemas.
+func AssertCompatible[T any](tb testing.TB, st *bsttype.Struct, rules TagRules) {
+	tb.Helper()
+	if err := CheckCompatible[T](st, rules); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// CheckCompatible checks if the tagged fields of the Go struct T line up with the struct type:
+// each tagged field has to match the type field by its name, kind, index and nullability.
+// The nested structs, arrays and maps are checked recursively. All the found mismatches are reported at once.
+func CheckCompatible[T any](st *bsttype.Struct, rules TagRules) error {
+	// 1. Find the Go struct type.
+	rt := reflect.TypeOf((*T)(nil)).Elem()
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return bsterr.Err(bsterr.CodeInvalidType, "compatibility could be checked only for the go structs").
+			WithDetail("type", rt)
+	}
+	if rules.Tag == "" {
+		rules.Tag = DefaultTag
+	}
+
+	// 2. Check the struct fields recursively.
+	c := checker{rules: rules}
+	c.checkStruct(rt.Name(), rt, st)
+	if len(c.issues) > 0 {
+		return bsterr.Err(bsterr.CodeTypeConstraintViolation, "go struct is not compatible with the struct type").
+			WithDetails(bsterr.D("type", rt), bsterr.D("issues", strings.Join(c.issues, "; ")))
+	}
+	return nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	bitmapType   = reflect.TypeOf(bstio.Bitmap{})
+	decimalType  = reflect.TypeOf(bstio.Decimal{})
+)
+
+// checker collects the mismatches between the Go types and the BST types.
+type checker struct {
+	rules  TagRules
+	issues []string
+}
+
+// addIssue records the mismatch found at given path.
+func (x *checker) addIssue(path, format string, args ...interface{}) {
+	x.issues = append(x.issues, path+": "+fmt.Sprintf(format, args...))
+}
+
+// checkStruct checks the tagged fields of the Go struct against the struct type fields.
+func (x *checker) checkStruct(path string, rt reflect.Type, st *bsttype.Struct) {
+	mapped := make(map[string]string, len(st.Fields))
+	for i := 0; i < rt.NumField(); i++ {
+		// 1. Parse the tag of the exported field.
+		sf := rt.Field(i)
+		tag, ok := sf.Tag.Lookup(x.rules.Tag)
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+		fieldPath := path + "." + sf.Name
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+
+		// 2. Find the type field by its name, and check it is mapped only once.
+		f, _, ok := st.FieldByName(name)
+		if !ok {
+			x.addIssue(fieldPath, "struct type has no field %q", name)
+			continue
+		}
+		if prev, ok := mapped[name]; ok {
+			x.addIssue(fieldPath, "struct type field %q is already mapped by %s", name, prev)
+			continue
+		}
+		mapped[name] = sf.Name
+
+		// 3. Check the tag options.
+		var nullable, hasIndex bool
+		for _, opt := range strings.Split(opts, ",") {
+			switch {
+			case opt == "":
+			case opt == "nullable":
+				nullable = true
+			case strings.HasPrefix(opt, "index="):
+				hasIndex = true
+				index, err := strconv.ParseUint(strings.TrimPrefix(opt, "index="), 10, 64)
+				if err != nil {
+					x.addIssue(fieldPath, "invalid index option %q", opt)
+				} else if uint(index) != f.Index {
+					x.addIssue(fieldPath, "index %d doesn't match the struct type field index %d", index, f.Index)
+				}
+			default:
+				x.addIssue(fieldPath, "unknown tag option %q", opt)
+			}
+		}
+		if x.rules.RequireIndex && !hasIndex {
+			x.addIssue(fieldPath, "index option is not defined")
+		}
+
+		// 4. Check the field type.
+		x.checkType(fieldPath, sf.Type, f.Type, nullable)
+	}
+
+	// 5. Check if all the type fields are mapped.
+	if x.rules.RequireAllFields {
+		for _, f := range st.Fields {
+			if _, ok := mapped[f.Name]; !ok {
+				x.addIssue(path, "struct type field %q is not mapped", f.Name)
+			}
+		}
+	}
+}
+
+// checkType checks if the Go type matches the BST type. The nullable flag marks the Go type as nullable,
+// despite not being a pointer.
+func (x *checker) checkType(path string, gt reflect.Type, t bsttype.Type, nullable bool) {
+	// 1. Dereference the named type. The unresolved named types could not be checked.
+	t = derefNamed(t)
+	if t == nil || t.Kind() == bsttype.KindNamed {
+		return
+	}
+
+	// 2. Check the nullability - the pointers are nullable.
+	if gt.Kind() == reflect.Pointer {
+		gt, nullable = gt.Elem(), true
+	}
+	nt, isNullable := t.(*bsttype.Nullable)
+	switch {
+	case isNullable && !nullable:
+		x.addIssue(path, "struct type field is nullable, but the go type %s is not", gt)
+		return
+	case !isNullable && nullable:
+		x.addIssue(path, "go type %s is nullable, but the struct type field %s is not", gt, t)
+		return
+	case isNullable:
+		t = derefNamed(nt.Type)
+		if t == nil || t.Kind() == bsttype.KindNamed {
+			return
+		}
+	}
+
+	// 3. Check the kinds, and the element types of the composites.
+	k := t.Kind()
+	switch {
+	case gt == timeType:
+		x.expectKind(path, gt, k, bsttype.KindTimestamp, bsttype.KindDateTime)
+	case gt == durationType:
+		x.expectKind(path, gt, k, bsttype.KindDuration)
+	case gt == bitmapType:
+		x.expectKind(path, gt, k, bsttype.KindBitmap)
+	case gt == decimalType:
+		x.expectKind(path, gt, k, bsttype.KindDecimal)
+	case gt.Kind() == reflect.Array && gt.Len() == 16 && gt.Elem().Kind() == reflect.Uint8 && gt.Name() == "UUID":
+		x.expectKind(path, gt, k, bsttype.KindUUID)
+	case gt.Kind() == reflect.Interface:
+		x.expectKind(path, gt, k, bsttype.KindAny, bsttype.KindOneOf)
+	case (gt.Kind() == reflect.Slice || gt.Kind() == reflect.Array) && gt.Elem().Kind() == reflect.Uint8 && k != bsttype.KindArray:
+		x.expectKind(path, gt, k, bsttype.KindBytes, bsttype.KindExternalBytes)
+		if bt, ok := t.(*bsttype.Bytes); ok && gt.Kind() == reflect.Array && bt.FixedSize != gt.Len() {
+			x.addIssue(path, "go array length %d doesn't match the bytes fixed size %d", gt.Len(), bt.FixedSize)
+		}
+	case gt.Kind() == reflect.Slice || gt.Kind() == reflect.Array:
+		at, ok := t.(*bsttype.Array)
+		if !ok {
+			x.expectKind(path, gt, k, bsttype.KindArray)
+			return
+		}
+		if gt.Kind() == reflect.Array && at.FixedSize != uint(gt.Len()) {
+			x.addIssue(path, "go array length %d doesn't match the array fixed size %d", gt.Len(), at.FixedSize)
+		}
+		x.checkType(path+"[]", gt.Elem(), at.Type, false)
+	case gt.Kind() == reflect.Map:
+		mt, ok := t.(*bsttype.Map)
+		if !ok {
+			x.expectKind(path, gt, k, bsttype.KindMap)
+			return
+		}
+		x.checkType(path+"[key]", gt.Key(), mt.Key.Type, false)
+		x.checkType(path+"[value]", gt.Elem(), mt.Value.Type, false)
+	case gt.Kind() == reflect.Struct:
+		st, ok := t.(*bsttype.Struct)
+		if !ok {
+			x.expectKind(path, gt, k, bsttype.KindStruct)
+			return
+		}
+		x.checkStruct(path, gt, st)
+	default:
+		expected, ok := _GoKinds[gt.Kind()]
+		if !ok {
+			x.addIssue(path, "go type %s has no matching struct type kind", gt)
+			return
+		}
+		// The enums are mapped either by their value names, or by their indexes.
+		if k == bsttype.KindEnum && (gt.Kind() == reflect.String || bsttype.IsIntegerKind(expected)) {
+			return
+		}
+		x.expectKind(path, gt, k, expected)
+	}
+}
+
+// expectKind adds an issue if the kind is none of the expected ones.
+func (x *checker) expectKind(path string, gt reflect.Type, k bsttype.Kind, expected ...bsttype.Kind) {
+	for _, e := range expected {
+		if k == e {
+			return
+		}
+	}
+	x.addIssue(path, "go type %s doesn't match the struct type field kind %s", gt, k)
+}
+
+// _GoKinds are the kinds of the basic Go types.
+var _GoKinds = map[reflect.Kind]bsttype.Kind{
+	reflect.Bool:    bsttype.KindBoolean,
+	reflect.Int:     bsttype.KindInt,
+	reflect.Int8:    bsttype.KindInt8,
+	reflect.Int16:   bsttype.KindInt16,
+	reflect.Int32:   bsttype.KindInt32,
+	reflect.Int64:   bsttype.KindInt64,
+	reflect.Uint:    bsttype.KindUint,
+	reflect.Uint8:   bsttype.KindUint8,
+	reflect.Uint16:  bsttype.KindUint16,
+	reflect.Uint32:  bsttype.KindUint32,
+	reflect.Uint64:  bsttype.KindUint64,
+	reflect.Float32: bsttype.KindFloat32,
+	reflect.Float64: bsttype.KindFloat64,
+	reflect.String:  bsttype.KindString,
+}
+
+// derefNamed returns the type wrapped by the resolved named types.
+func derefNamed(t bsttype.Type) bsttype.Type {
+	for {
+		nt, ok := t.(*bsttype.Named)
+		if !ok || nt.Type == nil {
+			return t
+		}
+		t = nt.Type
+	}
+}
@@ -0,0 +1,119 @@
+package bsttest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+type testAddress struct {
+	Street string `bst:"street,index=1"`
+	Zip    string `bst:"zip,index=2"`
+}
+
+type testUser struct {
+	ID        uint64            `bst:"id,index=1"`
+	Name      string            `bst:"name,index=2"`
+	Email     *string           `bst:"email,index=3"`
+	Tags      []string          `bst:"tags,index=4"`
+	Labels    map[string]int32  `bst:"labels,index=5"`
+	Address   testAddress       `bst:"address,index=6"`
+	Avatar    []byte            `bst:"avatar,index=7"`
+	CreatedAt time.Time         `bst:"created_at,index=8"`
+	TTL       time.Duration     `bst:"ttl,index=9,nullable"`
+	Cache     map[string]string `bst:"-"`
+	internal  int
+}
+
+func testUserType() *bsttype.Struct {
+	return &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "id", Type: bsttype.Uint64()},
+		{Index: 2, Name: "name", Type: bsttype.String()},
+		{Index: 3, Name: "email", Type: bsttype.NullableOf(bsttype.String())},
+		{Index: 4, Name: "tags", Type: &bsttype.Array{Type: bsttype.String()}},
+		{Index: 5, Name: "labels", Type: &bsttype.Map{Key: bsttype.MapElement{Type: bsttype.String()}, Value: bsttype.MapElement{Type: bsttype.Int32()}}},
+		{Index: 6, Name: "address", Type: &bsttype.Struct{Fields: []bsttype.StructField{
+			{Index: 1, Name: "street", Type: bsttype.String()},
+			{Index: 2, Name: "zip", Type: bsttype.String()},
+		}}},
+		{Index: 7, Name: "avatar", Type: &bsttype.Bytes{}},
+		{Index: 8, Name: "created_at", Type: bsttype.Timestamp()},
+		{Index: 9, Name: "ttl", Type: bsttype.NullableOf(bsttype.Duration())},
+	}}
+}
+
+func TestCheckCompatible(t *testing.T) {
+	rules := TagRules{RequireIndex: true, RequireAllFields: true}
+	AssertCompatible[testUser](t, testUserType(), rules)
+	AssertCompatible[*testUser](t, testUserType(), rules)
+
+	tests := []struct {
+		name   string
+		modify func(st *bsttype.Struct)
+		issue  string
+	}{
+		{
+			name:   "Kind",
+			modify: func(st *bsttype.Struct) { st.Fields[0].Type = bsttype.Int64() },
+			issue:  "testUser.ID: go type uint64 doesn't match the struct type field kind",
+		},
+		{
+			name:   "Index",
+			modify: func(st *bsttype.Struct) { st.Fields[1].Index = 12 },
+			issue:  "testUser.Name: index 2 doesn't match the struct type field index 12",
+		},
+		{
+			name:   "Nullable",
+			modify: func(st *bsttype.Struct) { st.Fields[1].Type = bsttype.NullableOf(bsttype.String()) },
+			issue:  "testUser.Name: struct type field is nullable",
+		},
+		{
+			name:   "NotNullable",
+			modify: func(st *bsttype.Struct) { st.Fields[2].Type = bsttype.String() },
+			issue:  "testUser.Email: go type string is nullable",
+		},
+		{
+			name:   "ArrayElem",
+			modify: func(st *bsttype.Struct) { st.Fields[3].Type = &bsttype.Array{Type: bsttype.Int8()} },
+			issue:  "testUser.Tags[]: go type string doesn't match",
+		},
+		{
+			name: "Nested",
+			modify: func(st *bsttype.Struct) {
+				st.Fields[5].Type.(*bsttype.Struct).Fields[1].Name = "postal_code"
+			},
+			issue: `testUser.Address.Zip: struct type has no field "zip"`,
+		},
+		{
+			name: "Unmapped",
+			modify: func(st *bsttype.Struct) {
+				st.Fields = append(st.Fields, bsttype.StructField{Index: 10, Name: "deleted", Type: bsttype.Boolean()})
+			},
+			issue: `testUser: struct type field "deleted" is not mapped`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			st := testUserType()
+			tc.modify(st)
+
+			err := CheckCompatible[testUser](st, rules)
+			if !errors.Is(err, bsterr.Err(bsterr.CodeTypeConstraintViolation, "go struct is not compatible with the struct type")) {
+				t.Fatalf("expected compatibility error, got: %v", err)
+			}
+			if !strings.Contains(err.Error(), tc.issue) {
+				t.Fatalf("expected issue %q, got: %v", tc.issue, err)
+			}
+		})
+	}
+
+	t.Run("NotStruct", func(t *testing.T) {
+		if err := CheckCompatible[int](testUserType(), rules); err == nil {
+			t.Fatal("expected error for non struct type")
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package bsttest
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrInjected is the default error returned by the failing operations of the fault injecting readers and writers.
+var ErrInjected = errors.New("bsttest: injected fault")
+
+// Fault defines when the fault injecting reader or writer starts failing. Once failed, all the following operations fail too.
+type Fault struct {
+	// Offset is the byte offset at which the reads or writes fail. The bytes before the offset are processed,
+	// thus the operation crossing the offset is partial. The negative offset disables the offset fault.
+	Offset int64
+	// Ops is the number of the operations which succeed before the failing one. The negative number disables the operation fault.
+	Ops int
+	// Err is the error returned by the failing operations. The ErrInjected is used if nil.
+	Err error
+}
+
+// AtOffset returns the fault at given byte offset.
+func AtOffset(offset int64) Fault {
+	return Fault{Offset: offset, Ops: -1}
+}
+
+// AfterOps returns the fault after given number of successful operations.
+func AfterOps(n int) Fault {
+	return Fault{Offset: -1, Ops: n}
+}
+
+// err returns the error of the fault.
+func (f Fault) err() error {
+	if f.Err != nil {
+		return f.Err
+	}
+	return ErrInjected
+}
+
+// faultState tracks the position and the operations of the fault injecting reader or writer.
+type faultState struct {
+	fault  Fault
+	pos    int64
+	ops    int
+	failed bool
+}
+
+// begin starts the next operation of up to n bytes, and returns the number of the bytes which could be processed.
+// The returned error is set if the operation should fail after the processed bytes.
+func (x *faultState) begin(n int) (int, error) {
+	// 1. Count the operation.
+	if err := x.countOp(); err != nil {
+		return 0, err
+	}
+
+	// 2. Limit the operation to the fault offset.
+	if x.fault.Offset >= 0 && x.pos+int64(n) > x.fault.Offset {
+		x.failed = true
+		return int(max(x.fault.Offset-x.pos, 0)), x.fault.err()
+	}
+	return n, nil
+}
+
+// countOp counts the next operation, and fails if the fault was already hit, or if the operation count is exhausted.
+func (x *faultState) countOp() error {
+	if x.failed || (x.fault.Ops >= 0 && x.ops >= x.fault.Ops) {
+		x.failed = true
+		return x.fault.err()
+	}
+	x.ops++
+	return nil
+}
+
+// FaultWriter is the io.Writer, which fails the writes according to its Fault.
+type FaultWriter struct {
+	w io.Writer
+	faultState
+}
+
+// NewFaultWriter creates a new writer, which writes to w until the fault is hit.
+func NewFaultWriter(w io.Writer, f Fault) *FaultWriter {
+	return &FaultWriter{w: w, faultState: faultState{fault: f}}
+}
+
+// Write writes the bytes up to the fault offset, and fails if the fault is hit.
+// Implements the io.Writer interface.
+func (x *FaultWriter) Write(p []byte) (int, error) {
+	n, ferr := x.begin(len(p))
+	if n > 0 {
+		var err error
+		n, err = x.w.Write(p[:n])
+		x.pos += int64(n)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, ferr
+}
+
+// Written returns the number of the bytes written to the underlying writer.
+func (x *FaultWriter) Written() int64 {
+	return x.pos
+}
+
+// Failed checks if the fault was hit.
+func (x *FaultWriter) Failed() bool {
+	return x.failed
+}
+
+// FaultReader is the io.Reader, which fails the reads according to its Fault.
+type FaultReader struct {
+	r io.Reader
+	faultState
+}
+
+// NewFaultReader creates a new reader, which reads from r until the fault is hit.
+// The reader doesn't implement the io.Seeker, thus the extractors wrap it in the shared read seeker.
+// The NewFaultReadSeeker keeps the seeking of the underlying reader.
+func NewFaultReader(r io.Reader, f Fault) *FaultReader {
+	return &FaultReader{r: r, faultState: faultState{fault: f}}
+}
+
+// Read reads the bytes up to the fault offset, and fails if the fault is hit.
+// Implements the io.Reader interface.
+func (x *FaultReader) Read(p []byte) (int, error) {
+	n, ferr := x.begin(len(p))
+	if n > 0 {
+		var err error
+		n, err = x.r.Read(p[:n])
+		x.pos += int64(n)
+		if err != nil {
+			return n, err
+		}
+		if ferr != nil && x.pos < x.fault.Offset {
+			// The underlying reader returned less than requested, thus the offset was not reached yet.
+			x.failed = false
+			return n, nil
+		}
+	}
+	return n, ferr
+}
+
+// Failed checks if the fault was hit.
+func (x *FaultReader) Failed() bool {
+	return x.failed
+}
+
+// FaultReadSeeker is the io.ReadSeeker, which fails the reads and seeks according to its Fault.
+// The seeks past the fault offset succeed, but all the following reads fail.
+type FaultReadSeeker struct {
+	FaultReader
+	s io.Seeker
+}
+
+// NewFaultReadSeeker creates a new read seeker, which reads from rs until the fault is hit.
+func NewFaultReadSeeker(rs io.ReadSeeker, f Fault) *FaultReadSeeker {
+	return &FaultReadSeeker{FaultReader: FaultReader{r: rs, faultState: faultState{fault: f}}, s: rs}
+}
+
+// Seek sets the offset of the next read.
+// Implements the io.Seeker interface.
+func (x *FaultReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if err := x.countOp(); err != nil {
+		return x.pos, err
+	}
+	pos, err := x.s.Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+	x.pos = pos
+	return pos, nil
+}
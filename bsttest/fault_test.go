@@ -0,0 +1,88 @@
+package bsttest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestFaultWriter(t *testing.T) {
+	t.Run("AtOffset", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewFaultWriter(&buf, AtOffset(5))
+		if n, err := w.Write([]byte("abc")); n != 3 || err != nil {
+			t.Fatalf("unexpected write result: %d, %v", n, err)
+		}
+		// The write crossing the offset is partial.
+		if n, err := w.Write([]byte("defg")); n != 2 || !errors.Is(err, ErrInjected) {
+			t.Fatalf("unexpected write result: %d, %v", n, err)
+		}
+		if n, err := w.Write([]byte("h")); n != 0 || !errors.Is(err, ErrInjected) {
+			t.Fatalf("unexpected write result: %d, %v", n, err)
+		}
+		if buf.String() != "abcde" || w.Written() != 5 || !w.Failed() {
+			t.Fatalf("unexpected written data: %q", buf.String())
+		}
+	})
+
+	t.Run("AfterOps", func(t *testing.T) {
+		custom := errors.New("disk full")
+		var buf bytes.Buffer
+		w := NewFaultWriter(&buf, Fault{Offset: -1, Ops: 2, Err: custom})
+		for i := 0; i < 2; i++ {
+			if _, err := w.Write([]byte("ab")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n, err := w.Write([]byte("cd")); n != 0 || !errors.Is(err, custom) {
+			t.Fatalf("unexpected write result: %d, %v", n, err)
+		}
+		if buf.String() != "abab" {
+			t.Fatalf("unexpected written data: %q", buf.String())
+		}
+	})
+}
+
+func TestFaultReader(t *testing.T) {
+	t.Run("AtOffset", func(t *testing.T) {
+		r := NewFaultReader(bytes.NewReader([]byte("abcdefgh")), AtOffset(5))
+		data, err := io.ReadAll(r)
+		if !errors.Is(err, ErrInjected) || string(data) != "abcde" || !r.Failed() {
+			t.Fatalf("unexpected read result: %q, %v", data, err)
+		}
+	})
+
+	t.Run("ShortRead", func(t *testing.T) {
+		// The underlying reader returns less than requested, thus the fault is not hit yet.
+		r := NewFaultReader(io.LimitReader(bytes.NewReader([]byte("abcdefgh")), 2), AtOffset(5))
+		p := make([]byte, 8)
+		if n, err := r.Read(p); n != 2 || err != nil || r.Failed() {
+			t.Fatalf("unexpected read result: %d, %v", n, err)
+		}
+	})
+
+	t.Run("Seek", func(t *testing.T) {
+		rs := NewFaultReadSeeker(bytes.NewReader([]byte("abcdefgh")), AtOffset(4))
+		if _, err := rs.Seek(6, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		// Reading past the offset fails.
+		if n, err := rs.Read(make([]byte, 1)); n != 0 || !errors.Is(err, ErrInjected) {
+			t.Fatalf("unexpected read result: %d, %v", n, err)
+		}
+		if _, err := rs.Seek(0, io.SeekStart); !errors.Is(err, ErrInjected) {
+			t.Fatalf("expected seek to fail after the fault, got: %v", err)
+		}
+	})
+
+	t.Run("AfterOps", func(t *testing.T) {
+		rs := NewFaultReadSeeker(bytes.NewReader([]byte("abcdefgh")), AfterOps(1))
+		if _, err := rs.Seek(2, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rs.Read(make([]byte, 1)); !errors.Is(err, ErrInjected) {
+			t.Fatalf("expected read to fail, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package bsttest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/devmodules/bst/internal/pooltrack"
+)
+
+// CheckPools verifies that all the shared modules, types, buffers and readers acquired during the test
+// are released back to their pools, once the test and its subtests finish. Each leaked object is reported
+// along with the call stack of its acquisition.
+// The tracking is process-wide, thus the CheckPools could not be used by the parallel tests.
+func CheckPools(tb testing.TB) {
+	tb.Helper()
+	if !pooltrack.Enable() {
+		tb.Fatal("shared pools are already checked, the CheckPools could not be used by the parallel tests")
+		return
+	}
+	tb.Cleanup(func() {
+		leaks := pooltrack.Disable()
+		if len(leaks) == 0 {
+			return
+		}
+		var sb strings.Builder
+		for _, l := range leaks {
+			fmt.Fprintf(&sb, "\n%T acquired at:\n%s", l.Object, l.Stack)
+		}
+		tb.Errorf("%d shared pool objects were not released:%s", len(leaks), sb.String())
+	})
+}
@@ -0,0 +1,76 @@
+package bsttest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+// recordingTB records the test failures and cleanups, instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	cleanups []func()
+	errors   []string
+}
+
+func (x *recordingTB) Helper() {}
+
+func (x *recordingTB) Cleanup(fn func()) {
+	x.cleanups = append(x.cleanups, fn)
+}
+
+func (x *recordingTB) Errorf(format string, args ...interface{}) {
+	x.errors = append(x.errors, fmt.Sprintf(format, args...))
+}
+
+func (x *recordingTB) Fatal(args ...interface{}) {
+	x.errors = append(x.errors, fmt.Sprint(args...))
+}
+
+func (x *recordingTB) finish() {
+	for i := len(x.cleanups) - 1; i >= 0; i-- {
+		x.cleanups[i]()
+	}
+}
+
+func TestCheckPools(t *testing.T) {
+	t.Run("Released", func(t *testing.T) {
+		tb := &recordingTB{TB: t}
+		CheckPools(tb)
+		st := bsttype.GetSharedType(bsttype.KindStruct)
+		bsttype.PutSharedType(st)
+		tb.finish()
+		if len(tb.errors) != 0 {
+			t.Fatalf("unexpected errors: %v", tb.errors)
+		}
+	})
+
+	t.Run("Leaked", func(t *testing.T) {
+		tb := &recordingTB{TB: t}
+		CheckPools(tb)
+		released := bsttype.GetSharedType(bsttype.KindArray)
+		leaked := bsttype.GetSharedType(bsttype.KindMap)
+		bsttype.PutSharedType(released)
+		tb.finish()
+		if len(tb.errors) != 1 {
+			t.Fatalf("expected a single error, got: %v", tb.errors)
+		}
+		// The leak is reported along with its acquisition stack.
+		if !strings.Contains(tb.errors[0], "1 shared pool objects") || !strings.Contains(tb.errors[0], "*bsttype.Map") ||
+			!strings.Contains(tb.errors[0], "TestCheckPools") {
+			t.Fatalf("unexpected leak report: %s", tb.errors[0])
+		}
+		bsttype.PutSharedType(leaked)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		CheckPools(t)
+		tb := &recordingTB{TB: t}
+		CheckPools(tb)
+		if len(tb.errors) != 1 {
+			t.Fatalf("expected the nested check to fail, got: %v", tb.errors)
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package bsttest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+// RoundTripOptions are the value options checked by the RoundTrip if none are given:
+// the regular and the comparable binaries, in both orders.
+var RoundTripOptions = []bstio.ValueOptions{
+	{},
+	{Descending: true},
+	{Comparable: true},
+	{Comparable: true, Descending: true},
+}
+
+// RoundTrip fails the test if the value v of type t breaks any of the round-trip invariants of the package,
+// for each of the value options, or the RoundTripOptions if none are given. See the CheckRoundTrip.
+// It is meant for the authors of the custom values, types and migrations, to verify their codecs.
+func RoundTrip(tb testing.TB, t bsttype.Type, v bstvalue.Value, options ...bstio.ValueOptions) {
+	tb.Helper()
+	if err := CheckRoundTrip(t, v, options...); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// CheckRoundTrip checks the round-trip invariants of the value v of type t, for each of the value options,
+// or the RoundTripOptions if none are given:
+//   - the type binary reads back as the equal type, which writes the same binary,
+//   - the value writes the same binary as it marshals to,
+//   - the value binary is skipped and read exactly, without reading the bytes which follow it,
+//   - the decoded value, both read and unmarshaled, re-encodes to the byte equal binary,
+//   - the decoded value is logically equal, i.e. it encodes to the same binaries as v with all the other options.
+func CheckRoundTrip(t bsttype.Type, v bstvalue.Value, options ...bstio.ValueOptions) error {
+	// 1. Verify that the value is of the type kind.
+	if len(options) == 0 {
+		options = RoundTripOptions
+	}
+	if v.Kind() != derefNamed(t).Kind() {
+		return bsterr.Err(bsterr.CodeInvalidType, "value kind doesn't match the type").
+			WithDetails(bsterr.D("kind", v.Kind()), bsterr.D("type", t))
+	}
+
+	// 2. Round-trip the type binary.
+	if err := checkTypeRoundTrip(t); err != nil {
+		return err
+	}
+
+	// 3. Encode the value with each of the options, to compare the decoded values against.
+	expected := make([][]byte, len(options))
+	for i, o := range options {
+		data, err := v.MarshalValue(o)
+		if err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to marshal value").
+				WithDetail("options", o)
+		}
+		expected[i] = data
+	}
+
+	// 4. Check the value invariants for each of the options.
+	for i, o := range options {
+		decoded, err := checkValueRoundTrip(t, v, expected[i], o)
+		if err != nil {
+			return err
+		}
+
+		// 4.1. The decoded values encode the same as the original with all the options.
+		for _, d := range decoded {
+			for j, oj := range options {
+				data, err := d.MarshalValue(oj)
+				if err != nil {
+					return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to marshal decoded value").
+						WithDetails(bsterr.D("decoded_options", o), bsterr.D("options", oj))
+				}
+				if !bytes.Equal(data, expected[j]) {
+					return roundTripMismatch("decoded value is not logically equal to the value", o, data, expected[j]).
+						WithDetail("options", oj)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkTypeRoundTrip checks that the type binary reads back as the equal type.
+func checkTypeRoundTrip(t bsttype.Type) error {
+	var buf bytes.Buffer
+	n, err := bsttype.WriteType(&buf, t)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write type").WithDetail("type", t)
+	}
+	if n != buf.Len() {
+		return bsterr.Err(bsterr.CodeEncodingBinaryValue, "type write reported invalid number of bytes").
+			WithDetails(bsterr.D("written", n), bsterr.D("expected", buf.Len()))
+	}
+	data := buf.Bytes()
+	rt, n, err := bsttype.ReadType(bytes.NewReader(data), false)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read type").WithDetail("type", t)
+	}
+	if n != len(data) {
+		return bsterr.Err(bsterr.CodeDecodingBinaryValue, "type read invalid number of bytes").
+			WithDetails(bsterr.D("read", n), bsterr.D("expected", len(data)))
+	}
+	if !bsttype.TypesEqual(t, rt) {
+		return bsterr.Err(bsterr.CodeTypeConstraintViolation, "read type is not equal to the type").
+			WithDetails(bsterr.D("type", t), bsterr.D("read", rt))
+	}
+	buf.Reset()
+	if _, err = bsttype.WriteType(&buf, rt); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write read type").WithDetail("type", rt)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		return bsterr.Err(bsterr.CodeTypeConstraintViolation, "read type binary differs").
+			WithDetails(bsterr.D("binary", buf.Bytes()), bsterr.D("expected", data))
+	}
+	return nil
+}
+
+// roundTripTrailer follows the value binaries, to detect the values reading past their binaries.
+var roundTripTrailer = []byte{0xA5, 0x5A}
+
+// checkValueRoundTrip checks the invariants of the value binary encoded with options o,
+// and returns the values decoded from it.
+func checkValueRoundTrip(t bsttype.Type, v bstvalue.Value, data []byte, o bstio.ValueOptions) ([]bstvalue.Value, error) {
+	// 1. The written binary is the same as the marshaled one.
+	var buf bytes.Buffer
+	n, err := v.WriteValue(&buf, o)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write value").WithDetail("options", o)
+	}
+	if n != buf.Len() {
+		return nil, bsterr.Err(bsterr.CodeEncodingBinaryValue, "value write reported invalid number of bytes").
+			WithDetails(bsterr.D("options", o), bsterr.D("written", n), bsterr.D("expected", buf.Len()))
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		return nil, roundTripMismatch("written value binary differs from the marshaled one", o, buf.Bytes(), data)
+	}
+	trailed := append(append([]byte{}, data...), roundTripTrailer...)
+
+	// 2. The binary is skipped exactly.
+	skipped, err := bstvalue.EmptyValueOf(t).Skip(bytes.NewReader(trailed), o)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip value").WithDetail("options", o)
+	}
+	if skipped != int64(len(data)) {
+		return nil, bsterr.Err(bsterr.CodeDecodingBinaryValue, "value skip moved invalid number of bytes").
+			WithDetails(bsterr.D("options", o), bsterr.D("skipped", skipped), bsterr.D("expected", len(data)))
+	}
+
+	// 3. The binary is read exactly.
+	rv := bstvalue.EmptyValueOf(t)
+	r := bytes.NewReader(trailed)
+	if n, err = rv.ReadValue(r, o); err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read value").WithDetail("options", o)
+	}
+	if read := len(trailed) - r.Len(); n != len(data) || read != len(data) {
+		return nil, bsterr.Err(bsterr.CodeDecodingBinaryValue, "value read invalid number of bytes").
+			WithDetails(bsterr.D("options", o), bsterr.D("reported", n), bsterr.D("read", read), bsterr.D("expected", len(data)))
+	}
+
+	// 4. The binary is unmarshaled. Some of the values decode their binary in place, thus it is copied.
+	uv := bstvalue.EmptyValueOf(t)
+	if err = uv.UnmarshalValue(append([]byte{}, data...), o); err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to unmarshal value").WithDetail("options", o)
+	}
+
+	// 5. The decoded values re-encode to the same binary.
+	decoded := []bstvalue.Value{rv, uv}
+	for _, d := range decoded {
+		re, err := d.MarshalValue(o)
+		if err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to marshal decoded value").
+				WithDetail("options", o)
+		}
+		if !bytes.Equal(re, data) {
+			return nil, roundTripMismatch("re-encoded value binary differs", o, re, data)
+		}
+	}
+	return decoded, nil
+}
+
+// roundTripMismatch returns the error of the binary which differs from the expected one.
+func roundTripMismatch(msg string, o bstio.ValueOptions, data, expected []byte) *bsterr.Error {
+	return bsterr.Err(bsterr.CodeTypeConstraintViolation, msg).
+		WithDetails(bsterr.D("decoded_options", o), bsterr.D("binary", data), bsterr.D("expected", expected))
+}
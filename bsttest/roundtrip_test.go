@@ -0,0 +1,65 @@
+package bsttest
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+// skewedStringValue is the string value which marshals a different binary than it writes.
+type skewedStringValue struct {
+	*bstvalue.StringValue
+}
+
+func (x skewedStringValue) MarshalValue(o bstio.ValueOptions) ([]byte, error) {
+	data, err := x.StringValue.MarshalValue(o)
+	return append(data, 0), err
+}
+
+func TestRoundTrip(t *testing.T) {
+	tagsType := bsttype.ArrayOf(bsttype.String())
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "ID", Type: bsttype.Uint64()},
+		{Index: 2, Name: "Name", Type: bsttype.String()},
+		{Index: 3, Name: "Score", Type: bsttype.Float64(), Descending: true},
+		{Index: 4, Name: "Email", Type: bsttype.NullableOf(bsttype.String())},
+		{Index: 5, Name: "Active", Type: bsttype.Boolean()},
+		{Index: 6, Name: "Created", Type: bsttype.Timestamp()},
+		{Index: 7, Name: "Amount", Type: bsttype.Decimal()},
+	}}
+	sv := bstvalue.MustNewStructValue(st, []bstvalue.Value{
+		bstvalue.NewUint64Value(math.MaxUint64),
+		bstvalue.NewStringValue("ann"),
+		bstvalue.NewFloat64Value(-0.5),
+		bstvalue.NullValueOf(bsttype.NullableOf(bsttype.String())),
+		bstvalue.NewBoolValue(true),
+		bstvalue.NewTimestampValue(time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC)),
+		bstvalue.NewDecimalValue(bstio.NewDecimal(-12345, 2)),
+	})
+
+	// 1. The package values keep the round-trip invariants.
+	RoundTrip(t, st, sv)
+	RoundTrip(t, bsttype.Int32(), bstvalue.NewInt32Value(math.MinInt32))
+	tags := bstvalue.MustArrayValueOf(tagsType, []bstvalue.Value{bstvalue.NewStringValue("a"), bstvalue.NewStringValue("")})
+	RoundTrip(t, tagsType, tags, bstio.ValueOptions{}, bstio.ValueOptions{Descending: true})
+	RoundTrip(t, bsttype.String(), bstvalue.NewStringValue("x"), bstio.ValueOptions{CompatibilityMode: true})
+
+	// 2. The violations are reported.
+	hasCode := func(err error, code bsterr.ErrCode) bool {
+		var be *bsterr.Error
+		return errors.As(err, &be) && be.Code == code
+	}
+	err := CheckRoundTrip(bsttype.String(), skewedStringValue{bstvalue.NewStringValue("x")})
+	if !hasCode(err, bsterr.CodeTypeConstraintViolation) {
+		t.Fatalf("expected the skewed value binary error, got: %v", err)
+	}
+	if err = CheckRoundTrip(bsttype.String(), bstvalue.NewInt32Value(1)); !hasCode(err, bsterr.CodeInvalidType) {
+		t.Fatalf("expected the kind mismatch error, got: %v", err)
+	}
+}
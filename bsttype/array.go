@@ -6,6 +6,7 @@ import (
 
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/internal/pooltrack"
 )
 
 // Compile-time check if Array implements interfaces.
@@ -82,17 +83,87 @@ func FixedSizeSharedArrayOf(t Type, fixedSize uint) *Array {
 	return a
 }
 
+// SetOf returns the set type of the given element type, i.e. the array of the ArrayEncodingSet encoding.
+// If the element type is nil, the function panics.
+func SetOf(t Type) *Array {
+	if t == nil {
+		panic("set element type is nil")
+	}
+	return &Array{Type: t, Encoding: ArrayEncodingSet}
+}
+
+// ArrayEncoding is the encoding of the array elements binary.
+type ArrayEncoding uint8
+
+// Enumerated array encodings. The values are a part of the type binary and never change.
+const (
+	// ArrayEncodingPlain encodes the elements one after another. The booleans are packed by 8 into a byte.
+	ArrayEncodingPlain ArrayEncoding = iota
+	// ArrayEncodingRunLength encodes the runs of equal consecutive elements as pairs of the run length (uint)
+	// and the element value. The boolean values take a single byte each.
+	// It is available for the Boolean and fixed size Enum elements, and applies only to the non-comparable binaries,
+	// as the runs would break the order of the comparable ones.
+	ArrayEncodingRunLength
+	// ArrayEncodingNullBitmap encodes the null flags of the Nullable elements as a leading bitmap,
+	// where the set bit marks a null element, followed only by the not null values.
+	// It is available for the Nullable elements, and applies only to the non-comparable binaries.
+	// The comparable binaries keep the null flag of each element, so that the null elements are ordered
+	// before the not null ones, just as the standalone nullable values are.
+	ArrayEncodingNullBitmap
+	// ArrayEncodingSet encodes the unique elements of the set, sorted by their binaries, so that the sets of the same
+	// elements have equal binaries, and could be compared or indexed by their bytes. In the comparable format,
+	// the elements are thus sorted in the order of the value, i.e. descending for the descending values. The elements are encoded
+	// just like the plain ones, in all the formats. They need to have a deterministic binary, just as the map keys,
+	// and could not be booleans, as those are packed into bytes.
+	ArrayEncodingSet
+	// ArrayEncodingFloatColumn encodes the Float32 or Float64 elements as the bstcol float array column:
+	// the header of the applied transforms, the number of elements and their IEEE-754 bits, which are byte shuffled
+	// and XOR delta encoded by the transforms, so that the downstream compression is far more effective on i.e. sensor data.
+	// The transforms are chosen by the composer options, and are read out of the column header.
+	// It applies only to the non-comparable binaries, as the transforms break the order.
+	ArrayEncodingFloatColumn
+)
+
+// String returns a human-readable name of the encoding.
+func (e ArrayEncoding) String() string {
+	switch e {
+	case ArrayEncodingPlain:
+		return "Plain"
+	case ArrayEncodingRunLength:
+		return "RunLength"
+	case ArrayEncodingNullBitmap:
+		return "NullBitmap"
+	case ArrayEncodingSet:
+		return "Set"
+	case ArrayEncodingFloatColumn:
+		return "FloatColumn"
+	default:
+		return "Unknown"
+	}
+}
+
+// Array size header bits.
+const (
+	arrayFixedSizeFlag    = 0x80
+	arrayEncodingMask     = 0x70
+	arrayEncodingShift    = 4
+	arraySizeHeaderMask   = 0x0F
+	maxKnownArrayEncoding = ArrayEncodingFloatColumn
+)
+
 // Array is a descriptor of the array type.
 // The array type binary is composed as follows:
 //   - The first byte is the type header which is in fact the Kind of the array type.
 //   - If the base type of the array is a complex, it is followed by its content.
 //   - The next byte is the array size header.
-//     If the array has fixed size, the most significant bit is set to 1 and the remaining 7 bits
+//     If the array has fixed size, the most significant bit is set to 1.
+//     The next 3 bits encode the ArrayEncoding, and the remaining 4 bits
 //     are used to encode the binary size of the fixed size integer.
 //   - If the array has fixed size, after the array size header, the fixed size integer is encoded.
 type Array struct {
 	Type      Type
 	FixedSize uint
+	Encoding  ArrayEncoding
 	isShared  bool
 }
 
@@ -101,10 +172,79 @@ func (x *Array) String() string {
 	if x.Type == nil {
 		return "UndefinedArray"
 	}
+	var enc string
+	if x.Encoding != ArrayEncodingPlain {
+		enc = ", " + x.Encoding.String()
+	}
 	if x.HasFixedSize() {
-		return fmt.Sprintf("Array[%d](%s)", x.FixedSize, x.Type.String())
+		return fmt.Sprintf("Array[%d](%s%s)", x.FixedSize, x.Type.String(), enc)
+	}
+	return fmt.Sprintf("Array(%s%s)", x.Type.String(), enc)
+}
+
+// VerifyEncoding checks if the array elements could be encoded with the array Encoding.
+func (x *Array) VerifyEncoding() error {
+	switch x.Encoding {
+	case ArrayEncodingPlain:
+		return nil
+	case ArrayEncodingRunLength:
+		_, err := x.RunLengthValueSize()
+		return err
+	case ArrayEncodingNullBitmap:
+		if derefNamed(x.Type).Kind() != KindNullable {
+			return bsterr.Err(bsterr.CodeInvalidType, "null bitmap array elements need to be nullable").
+				WithDetails(bsterr.D("elemKind", derefNamed(x.Type).Kind()))
+		}
+		return nil
+	case ArrayEncodingSet:
+		if ok, reason := CanBeMapKey(x.Type); !ok {
+			return bsterr.Err(bsterr.CodeInvalidType, "unsupported set element type: "+reason).
+				WithDetails(bsterr.D("elem", x.Type))
+		}
+		if derefNamed(x.Type).Kind() == KindBoolean {
+			return bsterr.Err(bsterr.CodeInvalidType, "set elements could not be booleans, as they are packed into bytes")
+		}
+		return nil
+	case ArrayEncodingFloatColumn:
+		_, err := x.FloatColumnWidth()
+		return err
+	default:
+		return bsterr.Err(bsterr.CodeInvalidType, "unknown array encoding").
+			WithDetails(bsterr.D("encoding", x.Encoding))
+	}
+}
+
+// RunLengthValueSize returns the binary size of a single element value of the run length encoded array.
+// It returns an error if the array elements could not be run length encoded.
+func (x *Array) RunLengthValueSize() (int, error) {
+	// 1. Dereference the element type.
+	et := derefNamed(x.Type)
+
+	// 2. The booleans are not packed in the runs, and the enums need to have a fixed size.
+	switch et.Kind() {
+	case KindBoolean:
+		return 1, nil
+	case KindEnum:
+		if size, ok := FixedEncodedSize(et); ok {
+			return size, nil
+		}
+	}
+	return 0, bsterr.Err(bsterr.CodeInvalidType, "array elements could not be run length encoded").
+		WithDetails(bsterr.D("elemKind", et.Kind()))
+}
+
+// FloatColumnWidth returns the binary width of a single element value of the float column array,
+// i.e. 4 for the Float32 and 8 for the Float64 elements. It returns an error if the array elements are not floats.
+func (x *Array) FloatColumnWidth() (int, error) {
+	switch k := derefNamed(x.Type).Kind(); k {
+	case KindFloat32:
+		return 4, nil
+	case KindFloat64:
+		return 8, nil
+	default:
+		return 0, bsterr.Err(bsterr.CodeInvalidType, "float column array elements need to be floats").
+			WithDetails(bsterr.D("elemKind", k))
 	}
-	return fmt.Sprintf("Array(%s)", x.Type.String())
 }
 
 // Kind returns the kind of the value.
@@ -117,11 +257,33 @@ func (x *Array) Elem() Type {
 	return x.Type
 }
 
+// IsSet returns true if the array is the set of unique elements.
+func (x *Array) IsSet() bool {
+	return x.Encoding == ArrayEncodingSet
+}
+
 // HasFixedSize returns true if the array has fixed size.
 func (x *Array) HasFixedSize() bool {
 	return x.FixedSize > 0
 }
 
+// FixedEncodedSize returns the size of the encoded value, if the array has fixed size
+// and its elements have fixed encoded size. The boolean elements are packed into bytes.
+// Implements TypeFixedSizer interface.
+func (x *Array) FixedEncodedSize() (int, bool) {
+	if !x.HasFixedSize() || x.Encoding != ArrayEncodingPlain {
+		return 0, false
+	}
+	if x.Type.Kind() == KindBoolean {
+		return int(x.FixedSize+7) >> 3, true
+	}
+	size, ok := FixedEncodedSize(x.Type)
+	if !ok {
+		return 0, false
+	}
+	return int(x.FixedSize) * size, true
+}
+
 // CompareType returns true if the two types are equal.
 // Implements the TypeComparer interface.
 func (x *Array) CompareType(to TypeComparer) bool {
@@ -130,7 +292,7 @@ func (x *Array) CompareType(to TypeComparer) bool {
 		return false
 	}
 
-	if x.FixedSize != ot.FixedSize {
+	if x.FixedSize != ot.FixedSize || x.Encoding != ot.Encoding {
 		return false
 	}
 	return TypesEqual(x.Type, ot.Type)
@@ -154,22 +316,19 @@ func (x *Array) SkipType(rs io.ReadSeeker) (int64, error) {
 
 	// 3. Check if the array has fixed size.
 	// If the array has fixed size, the binary size is encoded in the header byte.
-	if bt == 0 {
-		return bytesSkipped, nil
+	if _, err = parseArraySizeHeader(bt); err != nil {
+		return bytesSkipped, err
 	}
-
-	// 4. Check if the header most significant bit is set to 1.
-	if bt>>7 != 1 {
-		return bytesSkipped, bsterr.Err(bsterr.CodeDecodingBinaryType, "invalid array size header").
-			WithDetails(bsterr.D("header", bt))
+	if bt&arrayFixedSizeFlag == 0 {
+		return bytesSkipped, nil
 	}
 
-	// 5. Clear the most significant bit and check how many bytes are used to encode the array size.
+	// 4. Check how many bytes are used to encode the array size.
 	var toSkip int64
-	switch (bt << 1) >> 1 {
+	switch bt & arraySizeHeaderMask {
 	case bstio.BinarySizeZero:
 		// Rarely used to have a fixed size of 0.
-		return 1, nil
+		return bytesSkipped, nil
 	case bstio.BinarySizeUint8:
 		toSkip = 1
 	case bstio.BinarySizeUint16:
@@ -179,19 +338,18 @@ func (x *Array) SkipType(rs io.ReadSeeker) (int64, error) {
 	case bstio.BinarySizeUint64:
 		toSkip = 8
 	default:
-		return 1, bsterr.Errf(bsterr.CodeDecodingBinaryType, "invalid array type header byte").
+		return bytesSkipped, bsterr.Errf(bsterr.CodeDecodingBinaryType, "invalid array type header byte").
 			WithDetails(
 				bsterr.D("detail", "header byte should contain binary size of the fixed size integer"),
 				bsterr.D("header", bt),
 			)
 	}
 
-	// 6. Skip the fixed size integer bytes.
-	n, err := rs.Seek(toSkip, io.SeekCurrent)
-	if err != nil {
-		return bytesSkipped + n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip fixed size integer")
+	// 5. Skip the fixed size integer bytes.
+	if _, err = rs.Seek(toSkip, io.SeekCurrent); err != nil {
+		return bytesSkipped, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip fixed size integer")
 	}
-	bytesSkipped += n
+	bytesSkipped += toSkip
 
 	return bytesSkipped, nil
 }
@@ -213,22 +371,21 @@ func (x *Array) ReadType(r io.Reader) (int, error) {
 	}
 	bytesRead++
 
-	// 6. Check if the array has fixed size.
+	// 6. Parse the array encoding.
+	x.Encoding, err = parseArraySizeHeader(bt)
+	if err != nil {
+		return bytesRead, err
+	}
+
+	// 7. Check if the array has fixed size.
 	// If the array has fixed size, the binary size is encoded in the header byte.
-	if bt == 0 {
+	if bt&arrayFixedSizeFlag == 0 {
 		x.FixedSize = 0
 		return bytesRead, nil
 	}
 
-	// 7. Check if the header most significant bit is set to 1.
-	if bt>>7 != 1 {
-		return bytesRead, bsterr.Err(bsterr.CodeDecodingBinaryValue, "invalid array size header").
-			WithDetails(bsterr.D("header", bt))
-	}
-
-	// 8. Clear the most significant bit and ch
-	// eck how many bytes are used to encode the array size.
-	bt = (bt << 1) >> 1
+	// 8. Clear the fixed size flag and the encoding bits to get the number of bytes used to encode the array size.
+	bt &= arraySizeHeaderMask
 
 	fixedSize, n, err := bstio.ReadUintValue(r, bt, false)
 	if err != nil {
@@ -249,8 +406,9 @@ func (x *Array) WriteType(w io.Writer) (int, error) {
 	}
 
 	// 2. Write the array size header.
+	bth := byte(x.Encoding) << arrayEncodingShift
 	if !x.HasFixedSize() {
-		if err = bstio.WriteByte(w, 0x00); err != nil {
+		if err = bstio.WriteByte(w, bth); err != nil {
 			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write array size header")
 		}
 		bytesWritten += 1
@@ -258,7 +416,7 @@ func (x *Array) WriteType(w io.Writer) (int, error) {
 	}
 	// 3. Write the array size header for fixed size array.
 	//    10000000 (0x80) - most significant bit is set to 1.
-	bth := byte(0x80)
+	bth |= arrayFixedSizeFlag
 
 	size := bstio.UintSizeHeader(x.FixedSize, false)
 	bth |= size
@@ -276,6 +434,31 @@ func (x *Array) WriteType(w io.Writer) (int, error) {
 	return bytesWritten + n, nil
 }
 
+// derefNamed returns the type referenced by the resolved named types.
+func derefNamed(t Type) Type {
+	for {
+		nt, ok := t.(*Named)
+		if !ok || nt.Type == nil {
+			return t
+		}
+		t = nt.Type
+	}
+}
+
+// parseArraySizeHeader verifies the array size header and returns its encoding.
+func parseArraySizeHeader(bt byte) (ArrayEncoding, error) {
+	enc := ArrayEncoding((bt & arrayEncodingMask) >> arrayEncodingShift)
+	if enc > maxKnownArrayEncoding {
+		return 0, bsterr.Err(bsterr.CodeDecodingBinaryType, "unknown array encoding").
+			WithDetails(bsterr.D("header", bt))
+	}
+	if bt&arrayFixedSizeFlag == 0 && bt&arraySizeHeaderMask != 0 {
+		return 0, bsterr.Err(bsterr.CodeDecodingBinaryType, "invalid array size header").
+			WithDetails(bsterr.D("header", bt))
+	}
+	return enc, nil
+}
+
 // CheckDependencies checks if the dependencies are valid.
 // Implements the DependencyChecker interface.
 func (x *Array) CheckDependencies(m *Modules) (CheckDependenciesResult, error) {
@@ -368,13 +551,15 @@ var _sharedArrayPool = &sharedPool{defaultSize: 10}
 func getSharedArray() *Array {
 	v := _sharedArrayPool.pool.Get()
 	st, ok := v.(*Array)
-	if ok {
-		return st
+	if !ok {
+		st = &Array{}
 	}
-	return &Array{}
+	pooltrack.Acquire(st)
+	return st
 }
 
 func putSharedArray(x *Array) {
+	pooltrack.Release(x)
 	if !x.isShared {
 		return
 	}
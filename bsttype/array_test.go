@@ -72,3 +72,119 @@ func TestArrayType_WriteType(t *testing.T) {
 		})
 	}
 }
+
+func TestArrayType_FixedEncodedSize(t *testing.T) {
+	testCases := []struct {
+		Name string
+		Type *Array
+		Size int
+		OK   bool
+	}{
+		{Name: "Variable", Type: ArrayOf(Int64())},
+		{Name: "Fixed/Int64", Type: FixedSizeArrayOf(Int64(), 3), Size: 24, OK: true},
+		{Name: "Fixed/Boolean", Type: FixedSizeArrayOf(Boolean(), 9), Size: 2, OK: true},
+		{Name: "Fixed/String", Type: FixedSizeArrayOf(String(), 3)},
+		{Name: "Fixed/Fixed", Type: FixedSizeArrayOf(FixedSizeArrayOf(Uint16(), 2), 2), Size: 8, OK: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			size, ok := tc.Type.FixedEncodedSize()
+			if ok != tc.OK || size != tc.Size {
+				t.Fatalf("unexpected fixed encoded size: %d, %v, expected: %d, %v", size, ok, tc.Size, tc.OK)
+			}
+		})
+	}
+}
+
+func TestArrayType_Encoding(t *testing.T) {
+	enum := &Enum{ValueBytes: 1, Elements: []EnumElement{{String: "A", Index: 0}}}
+	testCases := []struct {
+		Name   string
+		Type   *Array
+		Header byte
+	}{
+		{Name: "Variable", Type: &Array{Type: Boolean(), Encoding: ArrayEncodingRunLength}, Header: 0x10},
+		{Name: "Fixed", Type: &Array{Type: enum, FixedSize: 300, Encoding: ArrayEncodingRunLength}, Header: 0x92},
+		{Name: "NullBitmap", Type: &Array{Type: NullableOf(Int32()), FixedSize: 4, Encoding: ArrayEncodingNullBitmap}, Header: 0xA1},
+		{Name: "Set", Type: SetOf(String()), Header: 0x30},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if _, err := tc.Type.WriteType(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Contains(buf.Bytes(), []byte{tc.Header}) {
+				t.Fatalf("expected array size header %x in %v", tc.Header, buf.Bytes())
+			}
+
+			var at Array
+			n, err := at.ReadType(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != buf.Len() {
+				t.Fatalf("expected to read %d bytes, but read %d", buf.Len(), n)
+			}
+			if !at.CompareType(tc.Type) {
+				t.Fatalf("expected %s, but got %s", tc.Type, &at)
+			}
+			if at.CompareType(&Array{Type: tc.Type.Type, FixedSize: tc.Type.FixedSize}) {
+				t.Fatal("the array types with different encodings should not be equal")
+			}
+			if _, ok := at.FixedEncodedSize(); ok {
+				t.Fatal("run length encoded array should not have a fixed encoded size")
+			}
+
+			skipped, err := at.SkipType(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if int(skipped) != buf.Len() {
+				t.Fatalf("expected to skip %d bytes, but skipped %d", buf.Len(), skipped)
+			}
+		})
+	}
+
+	t.Run("Unknown", func(t *testing.T) {
+		var at Array
+		if _, err := at.ReadType(bytes.NewReader([]byte{byte(KindInt32), 0x70})); err == nil {
+			t.Fatal("expected error for unknown array encoding")
+		}
+	})
+
+	t.Run("VerifyEncoding", func(t *testing.T) {
+		if err := (&Array{Type: NullableOf(String()), Encoding: ArrayEncodingNullBitmap}).VerifyEncoding(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := (&Array{Type: String(), Encoding: ArrayEncodingNullBitmap}).VerifyEncoding(); err == nil {
+			t.Fatal("expected error for not nullable null bitmap array elements")
+		}
+		if err := (&Array{Type: String(), Encoding: ArrayEncodingRunLength}).VerifyEncoding(); err == nil {
+			t.Fatal("expected error for run length encoded string elements")
+		}
+		if err := SetOf(String()).VerifyEncoding(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := SetOf(Float64()).VerifyEncoding(); err == nil {
+			t.Fatal("expected error for float set elements")
+		}
+		if err := SetOf(Boolean()).VerifyEncoding(); err == nil {
+			t.Fatal("expected error for boolean set elements")
+		}
+	})
+
+	t.Run("RunLengthValueSize", func(t *testing.T) {
+		if size, err := ArrayOf(Boolean()).RunLengthValueSize(); err != nil || size != 1 {
+			t.Fatalf("unexpected boolean run value size: %d, %v", size, err)
+		}
+		if size, err := ArrayOf(enum).RunLengthValueSize(); err != nil || size != 1 {
+			t.Fatalf("unexpected enum run value size: %d, %v", size, err)
+		}
+		if _, err := ArrayOf(String()).RunLengthValueSize(); err == nil {
+			t.Fatal("expected error for string elements")
+		}
+	})
+}
@@ -1,5 +1,10 @@
 package bsttype
 
+import (
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/internal/pooltrack"
+)
+
 // Compile-time checks for Basic interface implementations.
 var (
 	_ Type   = (*Basic)(nil)
@@ -41,6 +46,27 @@ func (b *Basic) Free() {
 	putSharedBasic(b)
 }
 
+// FixedEncodedSize returns the size of the encoded value, if it is fixed.
+// Implements TypeFixedSizer interface.
+func (b *Basic) FixedEncodedSize() (int, bool) {
+	switch b.TypeKind {
+	case KindBoolean, KindInt8, KindUint8:
+		return 1, true
+	case KindInt16, KindUint16:
+		return 2, true
+	case KindInt32, KindUint32, KindFloat32:
+		return 4, true
+	case KindInt64, KindUint64, KindFloat64, KindTimestamp, KindDuration:
+		return 8, true
+	case KindExternalBytes:
+		return bstio.BlobRefSize, true
+	case KindUUID:
+		return bstio.UUIDBinarySize, true
+	default:
+		return 0, false
+	}
+}
+
 func (b *Basic) copy(shared bool) Type {
 	if shared {
 		return getSharedBasic(b.TypeKind)
@@ -59,6 +85,17 @@ func AnyShared() *Basic {
 	return getSharedBasic(KindAny)
 }
 
+// Bitmap gets the basic type that represents the packed bit vector, i.e. the bstio.Bitmap.
+func Bitmap() *Basic {
+	return &Basic{TypeKind: KindBitmap}
+}
+
+// BitmapShared gets the Bitmap type from the shared type pool.
+// This type should be Freed after use.
+func BitmapShared() *Basic {
+	return getSharedBasic(KindBitmap)
+}
+
 // Boolean gets the basic type that represents the Boolean type.
 func Boolean() *Basic {
 	return &Basic{TypeKind: KindBoolean}
@@ -70,6 +107,17 @@ func BooleanShared() *Basic {
 	return getSharedBasic(KindBoolean)
 }
 
+// Decimal gets the basic type that represents the arbitrary precision decimal, i.e. the bstio.Decimal.
+func Decimal() *Basic {
+	return &Basic{TypeKind: KindDecimal}
+}
+
+// DecimalShared gets the Decimal type from the shared type pool.
+// This type should be Freed after use.
+func DecimalShared() *Basic {
+	return getSharedBasic(KindDecimal)
+}
+
 // Duration gets the basic type that represents the Duration type.
 func Duration() *Basic {
 	return &Basic{TypeKind: KindDuration}
@@ -81,6 +129,17 @@ func DurationShared() *Basic {
 	return getSharedBasic(KindDuration)
 }
 
+// ExternalBytes gets the basic type that represents the bytes stored out of line, referenced by their locator.
+func ExternalBytes() *Basic {
+	return &Basic{TypeKind: KindExternalBytes}
+}
+
+// ExternalBytesShared gets the ExternalBytes type from the shared type pool.
+// This type should be Freed after use.
+func ExternalBytesShared() *Basic {
+	return getSharedBasic(KindExternalBytes)
+}
+
 // Float32 gets the basic type that represents the Float32 type.
 func Float32() *Basic {
 	return &Basic{TypeKind: KindFloat32}
@@ -235,6 +294,17 @@ func TimestampShared() *Basic {
 	return getSharedBasic(KindTimestamp)
 }
 
+// UUID gets the basic type that represents the 16 byte universally unique identifier, i.e. the bstio.UUID.
+func UUID() *Basic {
+	return &Basic{TypeKind: KindUUID}
+}
+
+// UUIDShared gets the UUID type from the shared type pool.
+// This type should be Freed after use.
+func UUIDShared() *Basic {
+	return getSharedBasic(KindUUID)
+}
+
 //
 // Shared Pool
 //
@@ -244,14 +314,20 @@ var basicPool = &sharedPool{}
 func getSharedBasic(k Kind) *Basic {
 	bt, ok := basicPool.pool.Get().(*Basic)
 	if !ok {
-		return &Basic{isShared: true, TypeKind: k}
+		bt = &Basic{}
 	}
 	bt.isShared = true
 	bt.TypeKind = k
+	pooltrack.Acquire(bt)
 	return bt
 }
 
 func putSharedBasic(bt *Basic) {
+	pooltrack.Release(bt)
+	// The types not taken from the pool, i.e. of the user defined modules, are never reset.
+	if !bt.isShared {
+		return
+	}
 	bt.Reset()
 	basicPool.pool.Put(bt)
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/internal/pooltrack"
 )
 
 // Compile-time check to ensure that Bytes implements the Type interface.
@@ -60,6 +61,12 @@ func (x *Bytes) HasFixedSize() bool {
 	return x.FixedSize != 0
 }
 
+// FixedEncodedSize returns the size of the encoded value, if the bytes have fixed size.
+// Implements TypeFixedSizer interface.
+func (x *Bytes) FixedEncodedSize() (int, bool) {
+	return x.FixedSize, x.FixedSize > 0
+}
+
 // CompareType compares for equality between two types.
 // Implements the TypeComparer interface.
 func (x *Bytes) CompareType(to TypeComparer) bool {
@@ -222,13 +229,15 @@ var _sharedBytesPool = &sharedPool{defaultSize: 10}
 func getSharedBytes() *Bytes {
 	v := _sharedBytesPool.pool.Get()
 	st, ok := v.(*Bytes)
-	if ok {
-		return st
+	if !ok {
+		st = &Bytes{isShared: true}
 	}
-	return &Bytes{isShared: true}
+	pooltrack.Acquire(st)
+	return st
 }
 
 func putSharedBytes(x *Bytes) {
+	pooltrack.Release(x)
 	if !x.isShared {
 		return
 	}
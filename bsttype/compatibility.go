@@ -0,0 +1,289 @@
+package bsttype
+
+import (
+	"strconv"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// Compatibility is the compatibility level of the schema change, in terms of the compatibility mode binaries,
+// whose struct fields are identified by their indexes.
+type Compatibility uint8
+
+const (
+	// CompatibilityBreaking is the level of the change after which neither of the schemas reads the binaries
+	// of the other one.
+	CompatibilityBreaking Compatibility = 0
+	// CompatibilityBackward is the level of the change after which the new schema reads the binaries
+	// written with the old one.
+	CompatibilityBackward Compatibility = 1 << 0
+	// CompatibilityForward is the level of the change after which the old schema reads the binaries
+	// written with the new one.
+	CompatibilityForward Compatibility = 1 << 1
+	// CompatibilityFull is the level of the change after which both schemas read the binaries of each other.
+	CompatibilityFull = CompatibilityBackward | CompatibilityForward
+)
+
+// String returns a human-readable name of the compatibility level.
+func (x Compatibility) String() string {
+	switch x {
+	case CompatibilityBreaking:
+		return "breaking"
+	case CompatibilityBackward:
+		return "backward"
+	case CompatibilityForward:
+		return "forward"
+	case CompatibilityFull:
+		return "full"
+	default:
+		return "Compatibility(" + strconv.Itoa(int(x)) + ")"
+	}
+}
+
+// ChangeKind is the kind of the schema change.
+type ChangeKind int
+
+const (
+	// ChangeFieldAdded is the struct field added in the new schema.
+	ChangeFieldAdded ChangeKind = iota + 1
+	// ChangeFieldRemoved is the struct field removed from the old schema.
+	ChangeFieldRemoved
+	// ChangeFieldRenamed is the struct field of the same index and type, but a different name.
+	ChangeFieldRenamed
+	// ChangeFieldIndexReused is the index of the struct field reused by a field of different name and type.
+	ChangeFieldIndexReused
+	// ChangeTypeChanged is the type of the value changed.
+	ChangeTypeChanged
+)
+
+// String returns a human-readable name of the change kind.
+func (x ChangeKind) String() string {
+	switch x {
+	case ChangeFieldAdded:
+		return "field added"
+	case ChangeFieldRemoved:
+		return "field removed"
+	case ChangeFieldRenamed:
+		return "field renamed"
+	case ChangeFieldIndexReused:
+		return "field index reused"
+	case ChangeTypeChanged:
+		return "type changed"
+	default:
+		return "ChangeKind(" + strconv.Itoa(int(x)) + ")"
+	}
+}
+
+// SchemaChange is a single change between the old and the new schema.
+type SchemaChange struct {
+	// Kind is the kind of the change.
+	Kind ChangeKind
+	// Path is the dotted path of the changed struct field, where the array elements are denoted with "[]"
+	// and the map values with "{}". It is empty for the root type.
+	Path string
+	// Index is the index of the innermost struct field of the path.
+	Index uint
+	// Compatibility is the compatibility level of the change.
+	Compatibility Compatibility
+}
+
+// Report is the result of the CompatibilityCheck.
+type Report struct {
+	// Changes are the changes of the schema, in the order of the struct fields of the old and then the new schema.
+	Changes []SchemaChange
+	// Compatibility is the compatibility level of all the changes together, which is the full one if there are none.
+	Compatibility Compatibility
+}
+
+// CompatibilityCheck classifies the changes between the old and the new schema as the compatibility mode binaries
+// support them:
+//   - the added Nullable field is fully compatible, as it is read as null from the old binaries,
+//     while any other added field is forward compatible,
+//   - the removed Nullable field is fully compatible, while any other removed field is backward compatible,
+//   - the renamed field, of the same index and type, is fully compatible,
+//   - the numeric type of the field widened losslessly (see Widens) is backward compatible, and the narrowed one
+//     is forward compatible, as the binaries with the embedded type are coerced to the expected one,
+//   - the field index reused by a field of different name and type, and any other type change are breaking.
+//
+// The changes of the struct fields are found recursively, through the array elements, the map values
+// and the nullable values. It fails if either of the schemas contains a struct with duplicated field indexes.
+func CompatibilityCheck(old, new Type) (Report, error) {
+	c := compatibilityChecker{report: Report{Compatibility: CompatibilityFull}}
+	if err := c.compare("", 0, false, old, new); err != nil {
+		return Report{}, err
+	}
+	return c.report, nil
+}
+
+// Widens checks if the numeric value of the kind from is read losslessly as the value of the kind to.
+// This is the case for the integers of the same signedness and at least the same number of bits,
+// and for the float32 read as the float64:
+//
+//	from \ to | uint16 | uint32 | uint64 | uint
+//	uint8     |   x    |   x    |   x    |  x
+//	uint16    |        |   x    |   x    |  x
+//	uint32    |        |        |   x    |  x
+//	uint64    |        |        |        |  x (64-bit platforms)
+//	uint      |        |        |   x    |
+//
+//	from \ to | int16  | int32  | int64  | int
+//	int8      |   x    |   x    |   x    |  x
+//	int16     |        |   x    |   x    |  x
+//	int32     |        |        |   x    |  x
+//	int64     |        |        |        |  x (64-bit platforms)
+//	int       |        |        |   x    |
+//
+// The same kinds are not considered widened.
+func Widens(from, to Kind) bool {
+	if from == to || numericFamily(from) != numericFamily(to) {
+		return false
+	}
+	fb, ok := _numericBits[from]
+	if !ok {
+		return false
+	}
+	return fb <= _numericBits[to]
+}
+
+var _numericBits = map[Kind]int{
+	KindUint8:   8,
+	KindUint16:  16,
+	KindUint32:  32,
+	KindUint64:  64,
+	KindUint:    strconv.IntSize,
+	KindInt8:    8,
+	KindInt16:   16,
+	KindInt32:   32,
+	KindInt64:   64,
+	KindInt:     strconv.IntSize,
+	KindFloat32: 32,
+	KindFloat64: 64,
+}
+
+// numericFamily returns the family of the numeric kind, i.e. the unsigned or signed integers or the floats.
+func numericFamily(k Kind) int {
+	switch k {
+	case KindUint8, KindUint16, KindUint32, KindUint64, KindUint:
+		return 1
+	case KindInt8, KindInt16, KindInt32, KindInt64, KindInt:
+		return 2
+	case KindFloat32, KindFloat64:
+		return 3
+	default:
+		return 0
+	}
+}
+
+type compatibilityChecker struct {
+	report Report
+}
+
+func (x *compatibilityChecker) add(kind ChangeKind, path string, index uint, c Compatibility) {
+	x.report.Changes = append(x.report.Changes, SchemaChange{Kind: kind, Path: path, Index: index, Compatibility: c})
+	x.report.Compatibility &= c
+}
+
+// compare compares the types of the path, which is the value of the struct field of given index, if the field is set.
+func (x *compatibilityChecker) compare(path string, index uint, field bool, old, new Type) error {
+	// 1. Compare the definitions of the named types.
+	old, new = derefNamed(old), derefNamed(new)
+	if old == nil || new == nil {
+		return bsterr.Err(bsterr.CodeUndefinedType, "compared types are not defined").WithDetail("path", path)
+	}
+
+	// 2. Descend into the composite types of the same shape.
+	switch ot := old.(type) {
+	case *Struct:
+		if nt, ok := new.(*Struct); ok {
+			return x.compareStructs(path, ot, nt)
+		}
+	case *Array:
+		if nt, ok := new.(*Array); ok && ot.FixedSize == nt.FixedSize && ot.Encoding == nt.Encoding {
+			return x.compare(path+"[]", index, false, ot.Type, nt.Type)
+		}
+	case *Map:
+		if nt, ok := new.(*Map); ok && TypesEqual(ot.Key.Type, nt.Key.Type) && ot.Key.Descending == nt.Key.Descending &&
+			ot.Value.Descending == nt.Value.Descending {
+			return x.compare(path+"{}", index, false, ot.Value.Type, nt.Value.Type)
+		}
+	case *Nullable:
+		if nt, ok := new.(*Nullable); ok {
+			return x.compare(path, index, false, ot.Type, nt.Type)
+		}
+	}
+
+	// 3. Classify the change of the type. Only the numeric struct fields are coerced.
+	switch {
+	case TypesEqual(old, new):
+	case field && Widens(old.Kind(), new.Kind()):
+		x.add(ChangeTypeChanged, path, index, CompatibilityBackward)
+	case field && Widens(new.Kind(), old.Kind()):
+		x.add(ChangeTypeChanged, path, index, CompatibilityForward)
+	default:
+		x.add(ChangeTypeChanged, path, index, CompatibilityBreaking)
+	}
+	return nil
+}
+
+func (x *compatibilityChecker) compareStructs(path string, old, new *Struct) error {
+	// 1. Verify that the fields are identified by their indexes.
+	for _, st := range []*Struct{old, new} {
+		seen := make(map[uint]struct{}, len(st.Fields))
+		for _, f := range st.Fields {
+			if _, dup := seen[f.Index]; dup {
+				return bsterr.Err(bsterr.CodeInvalidType, "struct field index is duplicated").
+					WithDetails(bsterr.D("path", path), bsterr.D("index", f.Index))
+			}
+			seen[f.Index] = struct{}{}
+		}
+	}
+
+	// 2. Compare the old fields with the new ones of the same index.
+	prefix := path
+	if prefix != "" {
+		prefix += "."
+	}
+	for _, of := range old.Fields {
+		nf, _, found := new.FieldByIndex(of.Index)
+		if !found {
+			c := CompatibilityBackward
+			if derefNamed(of.Type).Kind() == KindNullable {
+				c = CompatibilityFull
+			}
+			x.add(ChangeFieldRemoved, prefix+of.Name, of.Index, c)
+			continue
+		}
+
+		// 2.1. The field of a different name is either renamed, or its index is reused by a new field.
+		if of.Name != nf.Name {
+			if !TypesEqual(derefNamed(of.Type), derefNamed(nf.Type)) || of.Descending != nf.Descending {
+				x.add(ChangeFieldIndexReused, prefix+nf.Name, nf.Index, CompatibilityBreaking)
+				continue
+			}
+			x.add(ChangeFieldRenamed, prefix+nf.Name, nf.Index, CompatibilityFull)
+			continue
+		}
+
+		// 2.2. The order of the field is a part of its type.
+		if of.Descending != nf.Descending || of.Encoding != nf.Encoding {
+			x.add(ChangeTypeChanged, prefix+nf.Name, nf.Index, CompatibilityBreaking)
+			continue
+		}
+		if err := x.compare(prefix+nf.Name, nf.Index, true, of.Type, nf.Type); err != nil {
+			return err
+		}
+	}
+
+	// 3. The new fields of the indexes not used by the old schema are added.
+	for _, nf := range new.Fields {
+		if _, _, found := old.FieldByIndex(nf.Index); found {
+			continue
+		}
+		c := CompatibilityForward
+		if derefNamed(nf.Type).Kind() == KindNullable {
+			c = CompatibilityFull
+		}
+		x.add(ChangeFieldAdded, prefix+nf.Name, nf.Index, c)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package bsttype
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompatibilityCheck(t *testing.T) {
+	address := func(fields ...StructField) *Struct {
+		return &Struct{Fields: append([]StructField{{Index: 1, Name: "City", Type: String()}}, fields...)}
+	}
+	user := func(fields ...StructField) *Struct {
+		return &Struct{Fields: append([]StructField{{Index: 1, Name: "Name", Type: String()}}, fields...)}
+	}
+
+	tests := []struct {
+		name     string
+		old, new Type
+		changes  []SchemaChange
+		want     Compatibility
+	}{
+		{
+			name: "Same",
+			old:  user(StructField{Index: 2, Name: "Address", Type: address()}),
+			new:  user(StructField{Index: 2, Name: "Address", Type: &Named{Module: "m", Name: "Address", Type: address()}}),
+			want: CompatibilityFull,
+		},
+		{
+			name: "AddedOptional",
+			old:  user(),
+			new:  user(StructField{Index: 2, Name: "Age", Type: NullableOf(Int32())}),
+			changes: []SchemaChange{
+				{Kind: ChangeFieldAdded, Path: "Age", Index: 2, Compatibility: CompatibilityFull},
+			},
+			want: CompatibilityFull,
+		},
+		{
+			name: "AddedRequired",
+			old:  user(),
+			new:  user(StructField{Index: 2, Name: "Age", Type: Int32()}),
+			changes: []SchemaChange{
+				{Kind: ChangeFieldAdded, Path: "Age", Index: 2, Compatibility: CompatibilityForward},
+			},
+			want: CompatibilityForward,
+		},
+		{
+			name: "Removed",
+			old:  user(StructField{Index: 2, Name: "Age", Type: Int32()}, StructField{Index: 3, Name: "Nick", Type: NullableOf(String())}),
+			new:  user(),
+			changes: []SchemaChange{
+				{Kind: ChangeFieldRemoved, Path: "Age", Index: 2, Compatibility: CompatibilityBackward},
+				{Kind: ChangeFieldRemoved, Path: "Nick", Index: 3, Compatibility: CompatibilityFull},
+			},
+			want: CompatibilityBackward,
+		},
+		{
+			name: "Renamed",
+			old:  user(StructField{Index: 2, Name: "Age", Type: Int32()}),
+			new:  user(StructField{Index: 2, Name: "Years", Type: Int32()}),
+			changes: []SchemaChange{
+				{Kind: ChangeFieldRenamed, Path: "Years", Index: 2, Compatibility: CompatibilityFull},
+			},
+			want: CompatibilityFull,
+		},
+		{
+			name: "IndexReused",
+			old:  user(StructField{Index: 2, Name: "Age", Type: Int32()}),
+			new:  user(StructField{Index: 2, Name: "Email", Type: String()}),
+			changes: []SchemaChange{
+				{Kind: ChangeFieldIndexReused, Path: "Email", Index: 2, Compatibility: CompatibilityBreaking},
+			},
+			want: CompatibilityBreaking,
+		},
+		{
+			name: "Widened",
+			old:  user(StructField{Index: 2, Name: "Age", Type: Int32()}, StructField{Index: 3, Name: "Score", Type: Float64()}),
+			new:  user(StructField{Index: 2, Name: "Age", Type: Int64()}, StructField{Index: 3, Name: "Score", Type: Float32()}),
+			changes: []SchemaChange{
+				{Kind: ChangeTypeChanged, Path: "Age", Index: 2, Compatibility: CompatibilityBackward},
+				{Kind: ChangeTypeChanged, Path: "Score", Index: 3, Compatibility: CompatibilityForward},
+			},
+			want: CompatibilityBreaking,
+		},
+		{
+			name: "CrossFamily",
+			old:  user(StructField{Index: 2, Name: "Age", Type: Int32()}),
+			new:  user(StructField{Index: 2, Name: "Age", Type: Uint64()}),
+			changes: []SchemaChange{
+				{Kind: ChangeTypeChanged, Path: "Age", Index: 2, Compatibility: CompatibilityBreaking},
+			},
+			want: CompatibilityBreaking,
+		},
+		{
+			name: "Nested",
+			old: user(
+				StructField{Index: 2, Name: "Addresses", Type: ArrayOf(address())},
+				StructField{Index: 3, Name: "Scores", Type: ArrayOf(Int32())},
+			),
+			new: user(
+				StructField{Index: 2, Name: "Addresses", Type: ArrayOf(address(StructField{Index: 2, Name: "Zip", Type: NullableOf(String())}))},
+				StructField{Index: 3, Name: "Scores", Type: ArrayOf(Int64())},
+			),
+			changes: []SchemaChange{
+				{Kind: ChangeFieldAdded, Path: "Addresses[].Zip", Index: 2, Compatibility: CompatibilityFull},
+				{Kind: ChangeTypeChanged, Path: "Scores[]", Index: 3, Compatibility: CompatibilityBreaking},
+			},
+			want: CompatibilityBreaking,
+		},
+		{
+			name: "Descending",
+			old:  user(StructField{Index: 2, Name: "Age", Type: Int32()}),
+			new:  user(StructField{Index: 2, Name: "Age", Type: Int32(), Descending: true}),
+			changes: []SchemaChange{
+				{Kind: ChangeTypeChanged, Path: "Age", Index: 2, Compatibility: CompatibilityBreaking},
+			},
+			want: CompatibilityBreaking,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := CompatibilityCheck(tc.old, tc.new)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(r.Changes, tc.changes) {
+				t.Fatalf("unexpected changes: %+v, wanted: %+v", r.Changes, tc.changes)
+			}
+			if r.Compatibility != tc.want {
+				t.Fatalf("unexpected compatibility: %s, wanted: %s", r.Compatibility, tc.want)
+			}
+		})
+	}
+
+	// The struct fields need to be identified by their indexes.
+	dup := user(StructField{Index: 1, Name: "Nick", Type: String()})
+	if _, err := CompatibilityCheck(user(), dup); err == nil {
+		t.Fatal("expected duplicated field index error")
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/internal/pooltrack"
 )
 
 var (
@@ -220,15 +221,17 @@ var _sharedDateTimePool = &sharedPool{defaultSize: 10}
 func getSharedDateTime() *DateTime {
 	v := _sharedDateTimePool.pool.Get()
 	st, ok := v.(*DateTime)
-	if ok {
-		return st
-	}
-	return &DateTime{
-		needsRelease: true,
+	if !ok {
+		st = &DateTime{
+			needsRelease: true,
+		}
 	}
+	pooltrack.Acquire(st)
+	return st
 }
 
 func putSharedDateTime(x *DateTime) {
+	pooltrack.Release(x)
 	if !x.needsRelease {
 		return
 	}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/internal/pooltrack"
 )
 
 // Compile-time check to ensure that Enum implements the Type  interface.
@@ -308,6 +309,17 @@ func (x *Enum) WriteType(w io.Writer) (int, error) {
 	return written, nil
 }
 
+// FixedEncodedSize returns the size of the encoded value, if the enum has fixed ValueBytes.
+// Implements TypeFixedSizer interface.
+func (x *Enum) FixedEncodedSize() (int, bool) {
+	switch x.ValueBytes {
+	case bstio.BinarySizeUint8, bstio.BinarySizeUint16, bstio.BinarySizeUint32, bstio.BinarySizeUint64:
+		return int(x.ValueBytes), true
+	default:
+		return 0, false
+	}
+}
+
 func (x *Enum) copy(shared bool) Type {
 	var cp *Enum
 	if shared {
@@ -334,16 +346,18 @@ var _sharedEnumPool = &sharedPool{defaultSize: 10}
 func getSharedEnum() *Enum {
 	v := _sharedEnumPool.pool.Get()
 	st, ok := v.(*Enum)
-	if ok {
-		return st
-	}
-	return &Enum{
-		Elements: make([]EnumElement, 0, _sharedEnumPool.defaultSize),
-		isShared: true,
+	if !ok {
+		st = &Enum{
+			Elements: make([]EnumElement, 0, _sharedEnumPool.defaultSize),
+			isShared: true,
+		}
 	}
+	pooltrack.Acquire(st)
+	return st
 }
 
 func putSharedEnum(x *Enum) {
+	pooltrack.Release(x)
 	if !x.isShared {
 		return
 	}
@@ -0,0 +1,104 @@
+package bsttype
+
+import (
+	"github.com/devmodules/bst/bsterr"
+)
+
+// FieldEncoding is the encoding override of the struct field value, applied on top of the encoding of its type.
+// It lets tune the encoding of single columns, instead of the whole value.
+type FieldEncoding uint8
+
+// Enumerated field encodings. The values are a part of the type binary and never change.
+const (
+	// FieldEncodingPlain encodes the field value just as its type does.
+	FieldEncodingPlain FieldEncoding = iota
+	// FieldEncodingDeflate compresses the field value with the DEFLATE algorithm.
+	// The compressed binary is written in place of the value, thus the binary layout of the field stays the same.
+	// It is available for the String and variable size Bytes fields.
+	FieldEncodingDeflate
+	// FieldEncodingDelta writes each array element as the difference from the previous one,
+	// wrapping around in the element integer width. The sorted or slowly changing values
+	// turn into small numbers, which take fewer bytes as the variable size integers and compress far better.
+	// It is available for the plain Array of integer elements.
+	FieldEncodingDelta
+	// FieldEncodingExternal offloads the field values above the composer inline threshold to the blob store,
+	// and writes their blob references instead. Each value is preceded by a tag, which tells whether
+	// it was inlined or offloaded. It is available for the String and variable size Bytes fields.
+	FieldEncodingExternal
+)
+
+// Struct field type header bits. The field encoding takes the byte following the header, only if it is flagged,
+// thus the header of the plain encoded fields stays as it was, and the encodings are not limited by the header bits.
+const (
+	fieldDescendingFlag   = 0x80
+	fieldEncodingFlag     = 0x40
+	fieldKindMask         = 0x3F
+	maxKnownFieldEncoding = FieldEncodingExternal
+)
+
+// String returns a human-readable name of the encoding.
+func (e FieldEncoding) String() string {
+	switch e {
+	case FieldEncodingPlain:
+		return "Plain"
+	case FieldEncodingDeflate:
+		return "Deflate"
+	case FieldEncodingDelta:
+		return "Delta"
+	case FieldEncodingExternal:
+		return "External"
+	default:
+		return "Unknown"
+	}
+}
+
+// VerifyEncoding checks if the field value could be encoded with the field Encoding.
+// The field encodings apply only to the non-comparable binaries, as they break the order of the values.
+func (x StructField) VerifyEncoding() error {
+	// 1. The unresolved named types are verified once they are resolved.
+	t := derefNamed(x.Type)
+	if t == nil || t.Kind() == KindNamed {
+		return nil
+	}
+
+	// 2. Check the field type against its encoding.
+	switch x.Encoding {
+	case FieldEncodingPlain:
+		return nil
+	case FieldEncodingDeflate, FieldEncodingExternal:
+		switch tt := t.(type) {
+		case *Bytes:
+			if !tt.HasFixedSize() {
+				return nil
+			}
+		case *Basic:
+			if tt.Kind() == KindString {
+				return nil
+			}
+		}
+	case FieldEncodingDelta:
+		if at, ok := t.(*Array); ok && at.Encoding == ArrayEncodingPlain && IsIntegerKind(derefNamed(at.Type).Kind()) {
+			return nil
+		}
+	default:
+		return bsterr.Err(bsterr.CodeInvalidType, "unknown struct field encoding").
+			WithDetails(bsterr.D("field", x.Name), bsterr.D("encoding", x.Encoding))
+	}
+	return bsterr.Err(bsterr.CodeInvalidType, "struct field type could not be encoded with the field encoding").
+		WithDetails(
+			bsterr.D("field", x.Name),
+			bsterr.D("encoding", x.Encoding),
+			bsterr.D("type", t),
+		)
+}
+
+// IsIntegerKind checks if the kind is one of the signed or unsigned integer kinds.
+func IsIntegerKind(k Kind) bool {
+	switch k {
+	case KindInt, KindInt8, KindInt16, KindInt32, KindInt64,
+		KindUint, KindUint8, KindUint16, KindUint32, KindUint64:
+		return true
+	default:
+		return false
+	}
+}
@@ -7,11 +7,11 @@ import (
 	"strings"
 )
 
-const _KindName = "UndefinedBooleanIntInt8Int16Int32Int64UintUint8Uint16Uint32Uint64Float32Float64StringDurationAnyTimestampNamedBytesStructArrayMapEnumDateTimeNullableOneOf"
+const _KindName = "UndefinedBooleanIntInt8Int16Int32Int64UintUint8Uint16Uint32Uint64Float32Float64StringDurationAnyTimestampNamedBytesStructArrayMapEnumDateTimeNullableOneOfExternalBytesBitmapDecimalUUID"
 
-var _KindIndex = [...]uint8{0, 9, 16, 19, 23, 28, 33, 38, 42, 47, 53, 59, 65, 72, 79, 85, 93, 96, 105, 110, 115, 121, 126, 129, 133, 141, 149, 154}
+var _KindIndex = [...]uint8{0, 9, 16, 19, 23, 28, 33, 38, 42, 47, 53, 59, 65, 72, 79, 85, 93, 96, 105, 110, 115, 121, 126, 129, 133, 141, 149, 154, 167, 173, 180, 184}
 
-const _KindLowerName = "undefinedbooleanintint8int16int32int64uintuint8uint16uint32uint64float32float64stringdurationanytimestampnamedbytesstructarraymapenumdatetimenullableoneof"
+const _KindLowerName = "undefinedbooleanintint8int16int32int64uintuint8uint16uint32uint64float32float64stringdurationanytimestampnamedbytesstructarraymapenumdatetimenullableoneofexternalbytesbitmapdecimaluuid"
 
 func (i Kind) String() string {
 	if i >= Kind(len(_KindIndex)-1) {
@@ -51,9 +51,13 @@ func _KindNoOp() {
 	_ = x[KindDateTime-(24)]
 	_ = x[KindNullable-(25)]
 	_ = x[KindOneOf-(26)]
+	_ = x[KindExternalBytes-(27)]
+	_ = x[KindBitmap-(28)]
+	_ = x[KindDecimal-(29)]
+	_ = x[KindUUID-(30)]
 }
 
-var _KindValues = []Kind{KindUndefined, KindBoolean, KindInt, KindInt8, KindInt16, KindInt32, KindInt64, KindUint, KindUint8, KindUint16, KindUint32, KindUint64, KindFloat32, KindFloat64, KindString, KindDuration, KindAny, KindTimestamp, KindNamed, KindBytes, KindStruct, KindArray, KindMap, KindEnum, KindDateTime, KindNullable, KindOneOf}
+var _KindValues = []Kind{KindUndefined, KindBoolean, KindInt, KindInt8, KindInt16, KindInt32, KindInt64, KindUint, KindUint8, KindUint16, KindUint32, KindUint64, KindFloat32, KindFloat64, KindString, KindDuration, KindAny, KindTimestamp, KindNamed, KindBytes, KindStruct, KindArray, KindMap, KindEnum, KindDateTime, KindNullable, KindOneOf, KindExternalBytes, KindBitmap, KindDecimal, KindUUID}
 
 var _KindNameToValueMap = map[string]Kind{
 	_KindName[0:9]:          KindUndefined,
@@ -110,6 +114,14 @@ var _KindNameToValueMap = map[string]Kind{
 	_KindLowerName[141:149]: KindNullable,
 	_KindName[149:154]:      KindOneOf,
 	_KindLowerName[149:154]: KindOneOf,
+	_KindName[154:167]:      KindExternalBytes,
+	_KindLowerName[154:167]: KindExternalBytes,
+	_KindName[167:173]:      KindBitmap,
+	_KindLowerName[167:173]: KindBitmap,
+	_KindName[173:180]:      KindDecimal,
+	_KindLowerName[173:180]: KindDecimal,
+	_KindName[180:184]:      KindUUID,
+	_KindLowerName[180:184]: KindUUID,
 }
 
 var _KindNames = []string{
@@ -140,6 +152,10 @@ var _KindNames = []string{
 	_KindName[133:141],
 	_KindName[141:149],
 	_KindName[149:154],
+	_KindName[154:167],
+	_KindName[167:173],
+	_KindName[173:180],
+	_KindName[180:184],
 }
 
 // KindString retrieves an enum value from the enum constants string name.
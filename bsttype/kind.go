@@ -2,33 +2,37 @@ package bsttype
 
 // _KindTypes is the map of standard types.
 var _KindTypes = [...]func(bool) Type{
-	KindUndefined: func(shared bool) Type { return getBasic(KindUndefined, shared) },
-	KindBoolean:   func(shared bool) Type { return getBasic(KindBoolean, shared) },
-	KindInt:       func(shared bool) Type { return getBasic(KindInt, shared) },
-	KindInt8:      func(shared bool) Type { return getBasic(KindInt8, shared) },
-	KindInt16:     func(shared bool) Type { return getBasic(KindInt16, shared) },
-	KindInt32:     func(shared bool) Type { return getBasic(KindInt32, shared) },
-	KindInt64:     func(shared bool) Type { return getBasic(KindInt64, shared) },
-	KindUint:      func(shared bool) Type { return getBasic(KindUint, shared) },
-	KindUint8:     func(shared bool) Type { return getBasic(KindUint8, shared) },
-	KindUint16:    func(shared bool) Type { return getBasic(KindUint16, shared) },
-	KindUint32:    func(shared bool) Type { return getBasic(KindUint32, shared) },
-	KindUint64:    func(shared bool) Type { return getBasic(KindUint64, shared) },
-	KindFloat32:   func(shared bool) Type { return getBasic(KindFloat32, shared) },
-	KindFloat64:   func(shared bool) Type { return getBasic(KindFloat64, shared) },
-	KindString:    func(shared bool) Type { return getBasic(KindString, shared) },
-	KindTimestamp: func(shared bool) Type { return getBasic(KindTimestamp, shared) },
-	KindDuration:  func(shared bool) Type { return getBasic(KindDuration, shared) },
-	KindAny:       func(shared bool) Type { return getBasic(KindAny, shared) },
-	KindNamed:     func(shared bool) Type { return getNamed(shared) },
-	KindBytes:     func(shared bool) Type { return getBytes(shared) },
-	KindStruct:    func(shared bool) Type { return getStruct(shared) },
-	KindArray:     func(shared bool) Type { return getArray(shared) },
-	KindMap:       func(shared bool) Type { return getMap(shared) },
-	KindEnum:      func(shared bool) Type { return getEnum(shared) },
-	KindDateTime:  func(shared bool) Type { return getDateTime(shared) },
-	KindNullable:  func(shared bool) Type { return getNullable(shared) },
-	KindOneOf:     func(shared bool) Type { return getOneOf(shared) },
+	KindUndefined:     func(shared bool) Type { return getBasic(KindUndefined, shared) },
+	KindBoolean:       func(shared bool) Type { return getBasic(KindBoolean, shared) },
+	KindInt:           func(shared bool) Type { return getBasic(KindInt, shared) },
+	KindInt8:          func(shared bool) Type { return getBasic(KindInt8, shared) },
+	KindInt16:         func(shared bool) Type { return getBasic(KindInt16, shared) },
+	KindInt32:         func(shared bool) Type { return getBasic(KindInt32, shared) },
+	KindInt64:         func(shared bool) Type { return getBasic(KindInt64, shared) },
+	KindUint:          func(shared bool) Type { return getBasic(KindUint, shared) },
+	KindUint8:         func(shared bool) Type { return getBasic(KindUint8, shared) },
+	KindUint16:        func(shared bool) Type { return getBasic(KindUint16, shared) },
+	KindUint32:        func(shared bool) Type { return getBasic(KindUint32, shared) },
+	KindUint64:        func(shared bool) Type { return getBasic(KindUint64, shared) },
+	KindFloat32:       func(shared bool) Type { return getBasic(KindFloat32, shared) },
+	KindFloat64:       func(shared bool) Type { return getBasic(KindFloat64, shared) },
+	KindString:        func(shared bool) Type { return getBasic(KindString, shared) },
+	KindTimestamp:     func(shared bool) Type { return getBasic(KindTimestamp, shared) },
+	KindDuration:      func(shared bool) Type { return getBasic(KindDuration, shared) },
+	KindAny:           func(shared bool) Type { return getBasic(KindAny, shared) },
+	KindNamed:         func(shared bool) Type { return getNamed(shared) },
+	KindBytes:         func(shared bool) Type { return getBytes(shared) },
+	KindStruct:        func(shared bool) Type { return getStruct(shared) },
+	KindArray:         func(shared bool) Type { return getArray(shared) },
+	KindMap:           func(shared bool) Type { return getMap(shared) },
+	KindEnum:          func(shared bool) Type { return getEnum(shared) },
+	KindDateTime:      func(shared bool) Type { return getDateTime(shared) },
+	KindNullable:      func(shared bool) Type { return getNullable(shared) },
+	KindOneOf:         func(shared bool) Type { return getOneOf(shared) },
+	KindExternalBytes: func(shared bool) Type { return getBasic(KindExternalBytes, shared) },
+	KindBitmap:        func(shared bool) Type { return getBasic(KindBitmap, shared) },
+	KindDecimal:       func(shared bool) Type { return getBasic(KindDecimal, shared) },
+	KindUUID:          func(shared bool) Type { return getBasic(KindUUID, shared) },
 }
 
 func getBasic(k Kind, shared bool) *Basic {
@@ -160,6 +164,14 @@ const (
 	KindNullable
 	// KindOneOf is the kind of the value that could take one of the provided values.
 	KindOneOf
+	// KindExternalBytes is the kind of byte values stored out of line, whose binary is the blob locator.
+	KindExternalBytes
+	// KindBitmap is the kind of the packed bit vector values, along with their length.
+	KindBitmap
+	// KindDecimal is the kind of the arbitrary precision decimal values.
+	KindDecimal
+	// KindUUID is the kind of the 16 byte universally unique identifier values.
+	KindUUID
 )
 
 // IsBasic determines if the kind is basic or its type is composed of more variables.
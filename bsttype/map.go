@@ -6,6 +6,7 @@ import (
 
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/internal/pooltrack"
 )
 
 // Compile time check to ensure that Map implements the Type interface.
@@ -41,7 +42,8 @@ type (
 	// Map binary representation looks like:
 	// Size(bits) | Name         | Description
 	//   1		  | KeyDesc      | Descending flag for the keys.
-	//   2        | -            | Empty byte.
+	//   1        | Indexed      | Indexed flag of the map.
+	//   1        | -            | Empty bit.
 	//   5        | KeyType      | Type of the key.
 	//   Variable | KeyContent   | Content of the key - optional (if the key type implements typeIsComplex interface).
 	//   1		  | ValueDesc    | Descending flag for the values.
@@ -53,6 +55,10 @@ type (
 		Key MapElement
 		// Value is the value definition of the map.
 		Value MapElement
+		// Indexed determines that the map entries are followed by the directory of their key hashes and offsets,
+		// so that a single entry could be read by its key without scanning the map. The entries are preceded
+		// by their binary size, so that the directory could be found. It is not used by the comparable format.
+		Indexed bool
 
 		needsRelease bool
 	}
@@ -66,14 +72,93 @@ type (
 	}
 )
 
+// mapIndexedFlag is the flag of the key type byte, set for the indexed maps.
+const mapIndexedFlag = 0x40
+
 // MapTypeOf creates a new map type for given key and value.
 // A keyDesc determines if the key value is expected to be stored in descending order.
 // A valueDesc determines if the value value is expected to be stored in descending order.
+// It panics if the key type could not be the map key, see the CanBeMapKey and the NewMapType.
 func MapTypeOf(key, value Type, keyDesc, valueDesc bool) *Map {
+	mt, err := NewMapType(key, value, keyDesc, valueDesc)
+	if err != nil {
+		panic(err)
+	}
+	return mt
+}
+
+// NewMapType creates a new map type for given key and value, or returns an error if the key type
+// could not be the map key, see the CanBeMapKey.
+func NewMapType(key, value Type, keyDesc, valueDesc bool) (*Map, error) {
+	if ok, reason := CanBeMapKey(key); !ok {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "unsupported map key type: "+reason).
+			WithDetail("key", key)
+	}
 	return &Map{
 		Key:   MapElement{Type: key, Descending: keyDesc},
 		Value: MapElement{Type: value, Descending: valueDesc},
+	}, nil
+}
+
+// CanBeMapKey checks if the type could be used as the map key. The map keys need to have a total order
+// and a deterministic binary, so that the sorted map entries and their lookups are well-defined.
+// If the type is not supported, the reason explains why.
+// The unresolved named types are accepted, as their definition is checked once it is known.
+func CanBeMapKey(t Type) (bool, string) {
+	return canBeMapKey(t, nil)
+}
+
+// canBeMapKey checks the key type, with the named types already seen on the path.
+func canBeMapKey(t Type, seen map[*Named]struct{}) (bool, string) {
+	if t == nil {
+		return false, "key type is not defined"
 	}
+	switch tt := t.(type) {
+	case *Named:
+		// The recursive named types are checked only once.
+		if tt.Type == nil {
+			return true, ""
+		}
+		if _, ok := seen[tt]; ok {
+			return true, ""
+		}
+		if seen == nil {
+			seen = map[*Named]struct{}{}
+		}
+		seen[tt] = struct{}{}
+		return canBeMapKey(tt.Type, seen)
+	case *Struct:
+		for _, f := range tt.Fields {
+			if ok, reason := canBeMapKey(f.Type, seen); !ok {
+				return false, "struct field " + f.Name + ": " + reason
+			}
+		}
+		return true, ""
+	case *Array:
+		if ok, reason := canBeMapKey(tt.Type, seen); !ok {
+			return false, "array element: " + reason
+		}
+		return true, ""
+	case *Nullable:
+		if ok, reason := canBeMapKey(tt.Type, seen); !ok {
+			return false, "nullable value: " + reason
+		}
+		return true, ""
+	}
+
+	switch t.Kind() {
+	case KindFloat32, KindFloat64:
+		return false, "floating point keys have no total order, as NaN is not equal to itself; use the integer or string keys instead"
+	case KindMap:
+		return false, "nested map keys are not supported; use the struct or array of the key entries instead"
+	case KindAny, KindOneOf:
+		return false, "keys of varying types have no common order; use a single key type instead"
+	case KindExternalBytes:
+		return false, "external bytes keys are ordered by the blob reference instead of the content; use the bytes keys instead"
+	case KindUndefined:
+		return false, "undefined key type"
+	}
+	return true, ""
 }
 
 // String returns a human-readable representation of the map value.
@@ -87,6 +172,9 @@ func (x MapElement) String() string {
 // String returns a human-readable representation of the map type.
 func (x *Map) String() string {
 	var sb strings.Builder
+	if x.Indexed {
+		sb.WriteString("Indexed")
+	}
 	sb.WriteString("Map[")
 	sb.WriteString(x.Key.String())
 	sb.WriteRune(']')
@@ -107,7 +195,7 @@ func (x *Map) CompareType(to TypeComparer) bool {
 		return false
 	}
 
-	return x.Key.Descending == tx.Key.Descending && x.Value.Descending == tx.Value.Descending &&
+	return x.Key.Descending == tx.Key.Descending && x.Value.Descending == tx.Value.Descending && x.Indexed == tx.Indexed &&
 		TypesEqual(x.Key.Type, tx.Key.Type) && TypesEqual(x.Value.Type, tx.Value.Type)
 }
 
@@ -120,8 +208,8 @@ func (x *Map) SkipType(rs io.ReadSeeker) (int64, error) {
 	}
 	bytesSkipped := int64(1)
 
-	// 2. Drop the first bit of the key descending flag.
-	bt = (bt << 1) >> 1
+	// 2. Drop the first bits of the key descending and indexed flags.
+	bt &^= 0x80 | mapIndexedFlag
 
 	// 3.  Get the key type.
 	kt := emptyKindType(Kind(bt), false)
@@ -176,8 +264,9 @@ func (x *Map) ReadType(r io.Reader) (int, error) {
 	// 2. Read the key descending flag.
 	x.Key.Descending = (bt & 0x80) != 0
 
-	// 3. Drop the first bit of the key descending flag.
-	bt = (bt << 1) >> 1
+	// 3. Read the indexed flag, and drop the first bits of the key descending and indexed flags.
+	x.Indexed = (bt & mapIndexedFlag) != 0
+	bt &^= 0x80 | mapIndexedFlag
 
 	// 4.  Get the key type.
 	x.Key.Type = emptyKindType(Kind(bt), false)
@@ -192,6 +281,12 @@ func (x *Map) ReadType(r io.Reader) (int, error) {
 		}
 		bytesRead += read
 	}
+
+	// 5.1. Verify that the read key type could be the map key.
+	if ok, reason := CanBeMapKey(x.Key.Type); !ok {
+		return bytesRead, bsterr.Err(bsterr.CodeDecodingBinaryType, "unsupported map key type: "+reason).
+			WithDetail("key", x.Key.Type)
+	}
 	// 6. Read the value type.
 	bt, err = bstio.ReadByte(r)
 	if err != nil {
@@ -226,10 +321,13 @@ func (x *Map) WriteType(w io.Writer) (int, error) {
 	// 1. Prepare the key type from the kind.
 	bt := byte(x.Key.Type.Kind())
 
-	// 2. Write the key descending flag.
+	// 2. Write the key descending and indexed flags.
 	if x.Key.Descending {
 		bt |= 0x80
 	}
+	if x.Indexed {
+		bt |= mapIndexedFlag
+	}
 
 	// 3. Write the key type.
 	bytesWritten, err := w.Write([]byte{bt})
@@ -434,6 +532,7 @@ func (x *Map) copy(shared bool) Type {
 		Type:       cp.Value.Type.(copier).copy(shared),
 		Descending: cp.Value.Descending,
 	}
+	cp.Indexed = x.Indexed
 	return cp
 }
 
@@ -446,15 +545,17 @@ var _sharedMapPool = &sharedPool{}
 func getSharedMap() *Map {
 	v := _sharedMapPool.pool.Get()
 	st, ok := v.(*Map)
-	if ok {
-		return st
-	}
-	return &Map{
-		needsRelease: true,
+	if !ok {
+		st = &Map{
+			needsRelease: true,
+		}
 	}
+	pooltrack.Acquire(st)
+	return st
 }
 
 func putSharedMap(x *Map) {
+	pooltrack.Release(x)
 	if !x.needsRelease {
 		return
 	}
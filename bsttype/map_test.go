@@ -50,6 +50,20 @@ var mapTypeTestCases = []struct {
 			byte(KindString),
 		},
 	},
+	{
+		Name: "Key(String)/Value(Int32)/Indexed",
+		MapType: Map{
+			Key:     MapElement{Type: String()},
+			Value:   MapElement{Type: Int32()},
+			Indexed: true,
+		},
+		Binary: []byte{
+			// Key Type Header
+			byte(KindString) | 0x40,
+			// Value Type Header
+			byte(KindInt32),
+		},
+	},
 	{
 		Name: "Key(Int32)/Bytes",
 		MapType: Map{
@@ -215,3 +229,50 @@ func TestMapType_CompareType(t *testing.T) {
 		})
 	}
 }
+
+func TestCanBeMapKey(t *testing.T) {
+	valid := []Type{
+		String(),
+		Int64(),
+		&Bytes{},
+		&Struct{Fields: []StructField{{Index: 1, Name: "ID", Type: Uint64()}}},
+		&Named{Module: "testing", Name: "unresolved"},
+	}
+	for _, kt := range valid {
+		if ok, reason := CanBeMapKey(kt); !ok {
+			t.Fatalf("expected %v to be a valid map key: %s", kt, reason)
+		}
+	}
+
+	invalid := []Type{
+		Float64(),
+		MapTypeOf(String(), String(), false, false),
+		&Struct{Fields: []StructField{{Index: 1, Name: "Score", Type: Float32()}}},
+		&Array{Type: Any()},
+		&Named{Module: "testing", Name: "resolved", Type: Float32()},
+	}
+	for _, kt := range invalid {
+		if ok, reason := CanBeMapKey(kt); ok || reason == "" {
+			t.Fatalf("expected %v to be an invalid map key", kt)
+		}
+		if _, err := NewMapType(kt, String(), false, false); err == nil {
+			t.Fatalf("expected map type with %v key to fail", kt)
+		}
+	}
+
+	t.Run("ReadType", func(t *testing.T) {
+		var mt Map
+		if _, err := mt.ReadType(bytes.NewReader([]byte{byte(KindFloat64), byte(KindString)})); err == nil {
+			t.Fatal("expected reading map type with float key to fail")
+		}
+	})
+
+	t.Run("MapTypeOf", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected MapTypeOf with float key to panic")
+			}
+		}()
+		MapTypeOf(Float32(), String(), false, false)
+	})
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/internal/pooltrack"
 )
 
 // Modules is a named list of Modules.
@@ -52,6 +53,10 @@ func (x *Modules) Read(r io.Reader, sharedDefs bool) (int, error) {
 
 		n, err = mod.Read(r)
 		if err != nil {
+			// 3.1. Keep only the completely read modules, so that they are released by the Free,
+			//      and release the partially read one.
+			x.List = x.List[:i]
+			mod.free()
 			return bytesRead, err
 		}
 		bytesRead += n
@@ -62,6 +67,14 @@ func (x *Modules) Read(r io.Reader, sharedDefs bool) (int, error) {
 	return bytesRead, nil
 }
 
+// WriteWithOptions encodes and writes binary encoded modules, with given options.
+func (x *Modules) WriteWithOptions(w io.Writer, options WriteTypeOptions) (int, error) {
+	if options.OmitDocs && !omitsDocs(w) {
+		w = docOmittingWriter{Writer: w}
+	}
+	return x.Write(w)
+}
+
 // Write encodes and writes binary encoded modules.
 func (x *Modules) Write(w io.Writer) (int, error) {
 	// 1. Write the number of modules.
@@ -110,12 +123,7 @@ func (x *Modules) Resolve() error {
 // releases all the types and definitions used by this module.
 func (x *Modules) Free() {
 	for _, mod := range x.List {
-		for _, def := range mod.Definitions {
-			PutSharedType(def.Type)
-		}
-		if mod.sharedDefs {
-			PutSharedModule(mod)
-		}
+		mod.free()
 	}
 
 	if x.sharedDefs {
@@ -284,7 +292,7 @@ func (x *Modules) Merge(m *Modules) {
 
 				if !found {
 					cp := defEx.Type.(copier)
-					mod.Definitions = append(mod.Definitions, ModuleDefinition{Name: defEx.Name, Type: cp.copy(x.sharedDefs)})
+					mod.Definitions = append(mod.Definitions, ModuleDefinition{Name: defEx.Name, Doc: defEx.Doc, Type: cp.copy(x.sharedDefs)})
 				}
 			}
 		}
@@ -330,9 +338,14 @@ func (x *Modules) DetectCycles() error {
 }
 
 // Module is a BST package that contains multiple type definitions.
+// The module binary is the name, followed by the number of definitions and the definitions themselves.
+// If any of the module or definition doc strings is defined, the definitions count size header is flagged,
+// and the module doc string follows the count, whereas each definition doc string follows its type.
 type Module struct {
 	// Name is the name of the module.
 	Name string
+	// Doc is the documentation of the module.
+	Doc string
 	// Definitions is a list of named module type definitions.
 	Definitions []ModuleDefinition
 
@@ -353,14 +366,23 @@ func (x *Module) Read(r io.Reader) (int, error) {
 
 	x.Name = name
 
-	// 2. Read the number of definitions.
-	var numDefs uint
-	numDefs, n, err = bstio.ReadUint(r, false)
+	// 2. Read the number of definitions, flagged if the doc strings follow.
+	numDefs, docs, n, err := readModuleDefinitionCount(r)
 	if err != nil {
 		return bytesRead, err
 	}
 	bytesRead += n
 
+	// 2.1. Read the doc string of the module.
+	x.Doc = ""
+	if docs {
+		x.Doc, n, err = bstio.ReadStringNonComparable(r, false)
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read module doc")
+		}
+		bytesRead += n
+	}
+
 	if int(numDefs) <= cap(x.Definitions) {
 		x.Definitions = x.Definitions[:numDefs]
 	} else {
@@ -372,6 +394,7 @@ func (x *Module) Read(r io.Reader) (int, error) {
 		// 3.1. Read the name of the definition.
 		name, n, err = bstio.ReadStringNonComparable(r, false)
 		if err != nil {
+			x.Definitions = x.Definitions[:i]
 			return bytesRead, err
 		}
 		bytesRead += n
@@ -380,16 +403,39 @@ func (x *Module) Read(r io.Reader) (int, error) {
 		var t Type
 		t, n, err = ReadType(r, x.sharedDefs)
 		if err != nil {
+			x.Definitions = x.Definitions[:i]
 			return bytesRead, err
 		}
 		bytesRead += n
 
-		// 3.3. Add the definition.
-		x.Definitions[i] = ModuleDefinition{Name: name, Type: t}
+		// 3.3. Read the doc string of the definition.
+		var doc string
+		if docs {
+			doc, n, err = bstio.ReadStringNonComparable(r, false)
+			if err != nil {
+				x.Definitions = x.Definitions[:i]
+				PutSharedType(t)
+				return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read module definition doc")
+			}
+			bytesRead += n
+		}
+
+		// 3.4. Add the definition.
+		x.Definitions[i] = ModuleDefinition{Name: name, Doc: doc, Type: t}
 	}
 	return bytesRead, nil
 }
 
+// free releases the shared types of the module definitions, and the module itself if it is shared.
+func (x *Module) free() {
+	for _, def := range x.Definitions {
+		PutSharedType(def.Type)
+	}
+	if x.sharedDefs {
+		PutSharedModule(x)
+	}
+}
+
 // Write the module binary content into the writer.
 func (x Module) Write(w io.Writer) (int, error) {
 	// 1. Write the name of the module.
@@ -399,13 +445,28 @@ func (x Module) Write(w io.Writer) (int, error) {
 	}
 	bytesWritten := n
 
-	// 2. Write the number of definitions.
-	n, err = bstio.WriteUint(w, uint(len(x.Definitions)), false)
+	// 2. Write the number of definitions, flagged if any of the doc strings is defined.
+	//    The doc strings are not written to the doc omitting writer - see the WriteTypeWithOptions.
+	docs := x.Doc != ""
+	for _, def := range x.Definitions {
+		docs = docs || def.Doc != ""
+	}
+	docs = docs && !omitsDocs(w)
+	n, err = writeModuleDefinitionCount(w, uint(len(x.Definitions)), docs)
 	if err != nil {
 		return bytesWritten, err
 	}
 	bytesWritten += n
 
+	// 2.1. Write the doc string of the module.
+	if docs {
+		n, err = bstio.WriteStringNonComparable(w, x.Doc, false)
+		if err != nil {
+			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write module doc")
+		}
+		bytesWritten += n
+	}
+
 	// 3. Write the definitions.
 	for _, def := range x.Definitions {
 		// 3.1. Write the name of the definition.
@@ -421,10 +482,88 @@ func (x Module) Write(w io.Writer) (int, error) {
 			return bytesWritten, err
 		}
 		bytesWritten += n
+
+		// 3.3. Write the doc string of the definition.
+		if docs {
+			n, err = bstio.WriteStringNonComparable(w, def.Doc, false)
+			if err != nil {
+				return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write module definition doc")
+			}
+			bytesWritten += n
+		}
 	}
 	return bytesWritten, nil
 }
 
+// moduleDocsFlag is the flag of the module definitions count size header, set if the doc strings are written.
+// The size header takes only the low bits, thus the modules without the doc strings keep their binary.
+const moduleDocsFlag = 0x80
+
+func readModuleDefinitionCount(r io.Reader) (uint, bool, int, error) {
+	bt, err := bstio.ReadByte(r)
+	if err != nil {
+		return 0, false, 0, err
+	}
+	nd, n, err := bstio.ReadUintValue(r, bt&^moduleDocsFlag, false)
+	if err != nil {
+		return 0, false, 1 + n, err
+	}
+	return nd, bt&moduleDocsFlag != 0, 1 + n, nil
+}
+
+func writeModuleDefinitionCount(w io.Writer, nd uint, docs bool) (int, error) {
+	b := bstio.MarshalUint(nd, false)
+	if docs {
+		b[0] |= moduleDocsFlag
+	}
+	return w.Write(b)
+}
+
+// DefinitionDoc returns the doc string of the module definition with given name.
+func (x *Module) DefinitionDoc(name string) (string, bool) {
+	for _, def := range x.Definitions {
+		if def.Name == name {
+			return def.Doc, true
+		}
+	}
+	return "", false
+}
+
+// Definition returns the type of the module definition with given name.
+func (x *Module) Definition(name string) (Type, bool) {
+	for _, def := range x.Definitions {
+		if def.Name == name {
+			return def.Type, true
+		}
+	}
+	return nil, false
+}
+
+// AddDefinition adds a new named type definition to the module.
+// It returns an error if the definition with given name already exists.
+func (x *Module) AddDefinition(name string, t Type) error {
+	if _, found := x.Definition(name); found {
+		return bsterr.Errf(bsterr.CodeTypeAlreadyMapped, "type %s.%s already mapped", x.Name, name)
+	}
+	x.Definitions = append(x.Definitions, ModuleDefinition{Name: name, Type: t})
+	return nil
+}
+
+// AddStructField adds a new field to the struct definition with given name - see the Struct.AddField.
+// It returns an error if the definition is not found or is not a struct.
+func (x *Module) AddStructField(def, name string, t Type, opts ...StructFieldOption) (StructField, error) {
+	dt, found := x.Definition(def)
+	if !found {
+		return StructField{}, bsterr.Errf(bsterr.CodeTypeNotMapped, "type %s.%s not found", x.Name, def)
+	}
+	st, ok := dt.(*Struct)
+	if !ok {
+		return StructField{}, bsterr.Errf(bsterr.CodeInvalidType, "type %s.%s is not a struct", x.Name, def).
+			WithDetail("kind", dt.Kind())
+	}
+	return st.AddField(name, t, opts...)
+}
+
 // ModuleDefinition is a reference of the named type it needs to be resolved from the input modules.
 // It is a temporary type used for the named type references while decoding the module types.
 // NOTE: If the named type defines a structure, it uses a Struct pointer.
@@ -436,6 +575,8 @@ type ModuleDefinition struct {
 	Name string
 	// Type is the definition of the named type.
 	Type Type
+	// Doc is the documentation of the named type.
+	Doc string
 }
 
 // DependencyResolver is an interface that is used to resolve references.
@@ -516,19 +657,20 @@ var _modulesPool = &sharedPool{defaultSize: 10}
 // NOTE: The caller is responsible for calling PutSharedModules when the Modules is no longer used.
 func GetSharedModules() *Modules {
 	v, ok := _modulesPool.pool.Get().(*Modules)
-	if ok {
-		return v
-	}
-
-	return &Modules{
-		List:       make([]*Module, 0, _modulesPool.defaultSize),
-		sharedDefs: true,
+	if !ok {
+		v = &Modules{
+			List:       make([]*Module, 0, _modulesPool.defaultSize),
+			sharedDefs: true,
+		}
 	}
+	pooltrack.Acquire(v)
+	return v
 }
 
 // PutSharedModules puts the Modules back to the pool.
 // NOTE: After calling this function, the Modules is no longer usable.
 func PutSharedModules(m *Modules) {
+	pooltrack.Release(m)
 	cp := cap(m.List)
 	m.List = m.List[:0]
 
@@ -541,18 +683,20 @@ var _modulePool = &sharedPool{defaultSize: 10}
 // NOTE: The caller is responsible for calling PutSharedModule when the Module is no longer used.
 func GetSharedModule() *Module {
 	v, ok := _modulePool.pool.Get().(*Module)
-	if ok {
-		return v
-	}
-	return &Module{
-		Definitions: make([]ModuleDefinition, 0, _modulePool.defaultSize),
-		sharedDefs:  true,
+	if !ok {
+		v = &Module{
+			Definitions: make([]ModuleDefinition, 0, _modulePool.defaultSize),
+			sharedDefs:  true,
+		}
 	}
+	pooltrack.Acquire(v)
+	return v
 }
 
 // PutSharedModule puts the module back to the pool.
 // NOTE: After calling this function, the Module is no longer usable.
 func PutSharedModule(m *Module) {
+	pooltrack.Release(m)
 	cp := len(m.Definitions)
 	*m = Module{
 		Definitions: m.Definitions[:0],
@@ -5,6 +5,7 @@ import (
 
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/internal/pooltrack"
 )
 
 // Compile-time checks for the Type interfaces.
@@ -225,6 +226,15 @@ func (x *Named) ResolveDependencies(m *Modules) (int64, error) {
 	return 1, nil
 }
 
+// FixedEncodedSize returns the fixed encoded size of the named type definition, if it is defined.
+// Implements TypeFixedSizer interface.
+func (x *Named) FixedEncodedSize() (int, bool) {
+	if x.Type == nil {
+		return 0, false
+	}
+	return FixedEncodedSize(x.Type)
+}
+
 // countRefs counts the references of the named type.
 func (x *Named) countRefs() int64 {
 	return 1
@@ -256,13 +266,15 @@ var _sharedNamedPool = &sharedPool{}
 func getSharedNamed() *Named {
 	v := _sharedNamedPool.pool.Get()
 	st, ok := v.(*Named)
-	if ok {
-		return st
+	if !ok {
+		st = &Named{needsRelease: true}
 	}
-	return &Named{needsRelease: true}
+	pooltrack.Acquire(st)
+	return st
 }
 
 func putSharedNamed(x *Named) {
+	pooltrack.Release(x)
 	if !x.needsRelease {
 		return
 	}
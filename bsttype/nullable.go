@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/internal/pooltrack"
 )
 
 // Compile-time check that Nullable implements Type interface.
@@ -42,11 +43,14 @@ type Nullable struct {
 }
 
 // NullableOf returns the nullable type that wraps input type.
+// If the input type is already nullable, its wrapped type is used, so that the nullable types are never nested.
 func NullableOf(t Type) *Nullable {
 	if t == nil {
 		t = &Basic{}
 	}
-	return &Nullable{Type: t}
+	n := &Nullable{Type: t}
+	n.Flatten()
+	return n
 }
 
 // NullableOfShared returns the nullable type that wraps input type.
@@ -73,6 +77,38 @@ func (x *Nullable) Elem() Type {
 	return x.Type
 }
 
+// Flatten canonicalizes the chain of directly nested nullable types, i.e.: Nullable(Nullable(T)) into Nullable(T).
+// A nested nullable would be encoded with multiple null flags, thus its binary would be ambiguous.
+func (x *Nullable) Flatten() {
+	for {
+		nt, ok := x.Type.(*Nullable)
+		if !ok {
+			return
+		}
+		x.Type = nt.Type
+	}
+}
+
+// Validate checks if the nullable type composition is supported.
+// A nullable type cannot wrap another nullable type, neither directly, nor through the Named types definitions.
+func (x *Nullable) Validate() error {
+	t := x.Type
+	for {
+		switch tt := t.(type) {
+		case *Nullable:
+			return bsterr.Err(bsterr.CodeInvalidType, "nullable type cannot wrap another nullable type").
+				WithDetail("type", x.String())
+		case *Named:
+			if tt.Type == nil {
+				return nil
+			}
+			t = tt.Type
+		default:
+			return nil
+		}
+	}
+}
+
 // CompareType returns true if the types are equal.
 func (x *Nullable) CompareType(to TypeComparer) bool {
 	nt, ok := to.(*Nullable)
@@ -150,13 +186,27 @@ func (x *Nullable) VerifyDependencies() error {
 }
 
 // ResolveDependencies resolves the references in the nullable type.
+// The nested nullable types are flattened, and the ones wrapping a nullable named type result in an error.
 // Implements DependencyResolver interface.
 func (x *Nullable) ResolveDependencies(m *Modules) (int64, error) {
-	mr, ok := x.Type.(DependencyResolver)
-	if !ok {
-		return 0, nil
+	// 1. Canonicalize the nested nullable types.
+	x.Flatten()
+
+	// 2. Resolve the wrapped type references.
+	var refs int64
+	if mr, ok := x.Type.(DependencyResolver); ok {
+		var err error
+		refs, err = mr.ResolveDependencies(m)
+		if err != nil {
+			return refs, err
+		}
 	}
-	return mr.ResolveDependencies(m)
+
+	// 3. Verify if the resolved composition is supported.
+	if err := x.Validate(); err != nil {
+		return refs, err
+	}
+	return refs, nil
 }
 
 func (x *Nullable) detectCycles(mod, name string) error {
@@ -204,13 +254,15 @@ var _sharedNullablesPool = &sharedPool{}
 func getSharedNullable() *Nullable {
 	v := _sharedNullablesPool.pool.Get()
 	st, ok := v.(*Nullable)
-	if ok {
-		return st
+	if !ok {
+		st = &Nullable{}
 	}
-	return &Nullable{}
+	pooltrack.Acquire(st)
+	return st
 }
 
 func putSharedNullable(x *Nullable) {
+	pooltrack.Release(x)
 	if !x.needsRelease {
 		return
 	}
@@ -153,3 +153,45 @@ func TestNullableType_CompareType(t *testing.T) {
 		})
 	}
 }
+
+func TestNullableType_Flatten(t *testing.T) {
+	t.Run("NullableOf", func(t *testing.T) {
+		nt := NullableOf(NullableOf(NullableOf(Int())))
+		if !TypesEqual(nt, &Nullable{Type: Int()}) {
+			t.Fatalf("nested nullable type not flattened: %v", nt)
+		}
+	})
+
+	t.Run("Resolve", func(t *testing.T) {
+		st := &Struct{
+			Fields: []StructField{
+				{Index: 1, Name: "Nested", Type: &Nullable{Type: &Nullable{Type: String()}}},
+			},
+		}
+		m := Modules{List: []*Module{{Name: "mod", Definitions: []ModuleDefinition{{Name: "Struct", Type: st}}}}}
+		if err := m.Resolve(); err != nil {
+			t.Fatalf("resolving modules failed: %v", err)
+		}
+		if !TypesEqual(st.Fields[0].Type, &Nullable{Type: String()}) {
+			t.Fatalf("nested nullable type not flattened: %v", st.Fields[0].Type)
+		}
+	})
+
+	t.Run("NamedNullable", func(t *testing.T) {
+		st := &Struct{
+			Fields: []StructField{
+				{Index: 1, Name: "Ref", Type: &Nullable{Type: &Named{Module: "mod", Name: "Optional"}}},
+			},
+		}
+		m := Modules{List: []*Module{{
+			Name: "mod",
+			Definitions: []ModuleDefinition{
+				{Name: "Optional", Type: &Nullable{Type: Int()}},
+				{Name: "Struct", Type: st},
+			},
+		}}}
+		if err := m.Resolve(); err == nil {
+			t.Fatal("expected error for nullable of nullable named type")
+		}
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/internal/pooltrack"
 )
 
 // Compile time check if the OneOf implements Type interface.
@@ -542,16 +543,18 @@ var _sharedOneOfPool = &sharedPool{defaultSize: 10}
 func getSharedOneOf() *OneOf {
 	v := _sharedOneOfPool.pool.Get()
 	st, ok := v.(*OneOf)
-	if ok {
-		return st
-	}
-	return &OneOf{
-		Elements:     make([]OneOfElement, 0, _sharedOneOfPool.defaultSize),
-		needsRelease: true,
+	if !ok {
+		st = &OneOf{
+			Elements:     make([]OneOfElement, 0, _sharedOneOfPool.defaultSize),
+			needsRelease: true,
+		}
 	}
+	pooltrack.Acquire(st)
+	return st
 }
 
 func putSharedOneOf(x *OneOf) {
+	pooltrack.Release(x)
 	if !x.needsRelease {
 		return
 	}
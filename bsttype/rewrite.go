@@ -0,0 +1,90 @@
+package bsttype
+
+// RewriteFunc is the function called by the Rewrite for each type of the type tree.
+// If it returns true, the input type is replaced with the returned one.
+type RewriteFunc func(t Type) (Type, bool)
+
+// Rewrite returns a transformed copy of the type t, the input type is not modified.
+// The fn is called for each type of the tree in the depth-first post-order, thus the input of the fn
+// already contains the rewritten element types - i.e. the struct fields types, or the named type definition.
+//
+// This allows i.e.:
+//   - Inlining all Named references - by replacing each *Named with its (resolved) Type.
+//   - Extracting repeated anonymous structs into named module definitions - by replacing the *Struct
+//     with a *Named reference, and adding its definition to the module.
+//
+// The recursive Named references (the ones pointing to the named type which is being rewritten) are not
+// descended again, and are passed to the fn as the shallow copies pointing to the original definition.
+func Rewrite(t Type, fn RewriteFunc) Type {
+	rw := rewriter{fn: fn}
+	return rw.rewrite(t)
+}
+
+type rewriter struct {
+	fn    RewriteFunc
+	named []*Named // stack of the named types being rewritten.
+}
+
+func (x *rewriter) rewrite(t Type) Type {
+	if t == nil {
+		return nil
+	}
+
+	// 1. Rewrite the element types into a copy of the type.
+	var cp Type
+	switch tt := t.(type) {
+	case *Struct:
+		st := &Struct{Fields: make([]StructField, len(tt.Fields))}
+		for i, f := range tt.Fields {
+			f.Type = x.rewrite(f.Type)
+			st.Fields[i] = f
+		}
+		cp = st
+	case *Array:
+		cp = &Array{Type: x.rewrite(tt.Type), FixedSize: tt.FixedSize, Encoding: tt.Encoding}
+	case *Map:
+		cp = &Map{
+			Key:   MapElement{Type: x.rewrite(tt.Key.Type), Descending: tt.Key.Descending},
+			Value: MapElement{Type: x.rewrite(tt.Value.Type), Descending: tt.Value.Descending},
+		}
+	case *Nullable:
+		cp = &Nullable{Type: x.rewrite(tt.Type)}
+	case *OneOf:
+		ot := &OneOf{IndexBytes: tt.IndexBytes, Elements: make([]OneOfElement, len(tt.Elements))}
+		for i, e := range tt.Elements {
+			e.Type = x.rewrite(e.Type)
+			ot.Elements[i] = e
+		}
+		cp = ot
+	case *Named:
+		cp = x.rewriteNamed(tt)
+	case copier:
+		cp = tt.copy(false)
+	default:
+		cp = t
+	}
+
+	// 2. Apply the rewrite function on the copy.
+	if nt, ok := x.fn(cp); ok {
+		return nt
+	}
+	return cp
+}
+
+func (x *rewriter) rewriteNamed(nt *Named) *Named {
+	cp := &Named{Module: nt.Module, Name: nt.Name, resolved: nt.resolved}
+
+	// 1. A recursive reference keeps pointing to the original definition.
+	for _, n := range x.named {
+		if n.Module == nt.Module && n.Name == nt.Name {
+			cp.Type = nt.Type
+			return cp
+		}
+	}
+
+	// 2. Rewrite the named type definition.
+	x.named = append(x.named, nt)
+	cp.Type = x.rewrite(nt.Type)
+	x.named = x.named[:len(x.named)-1]
+	return cp
+}
@@ -0,0 +1,101 @@
+package bsttype
+
+import (
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	address := &Struct{
+		Fields: []StructField{
+			{Index: 1, Name: "City", Type: String()},
+		},
+	}
+
+	t.Run("InlineNamed", func(t *testing.T) {
+		in := &Struct{
+			Fields: []StructField{
+				{Index: 1, Name: "Home", Type: &Named{Module: "mod", Name: "Address", Type: address}},
+				{Index: 2, Name: "Work", Type: NullableOf(&Named{Module: "mod", Name: "Address", Type: address})},
+			},
+		}
+
+		out := Rewrite(in, func(t Type) (Type, bool) {
+			if nt, ok := t.(*Named); ok {
+				return nt.Type, true
+			}
+			return nil, false
+		})
+
+		st, ok := out.(*Struct)
+		if !ok {
+			t.Fatalf("unexpected rewritten type: %T", out)
+		}
+		if !TypesEqual(st.Fields[0].Type, address) {
+			t.Fatalf("named type not inlined: %v", st.Fields[0].Type)
+		}
+		if !TypesEqual(st.Fields[1].Type, NullableOf(address)) {
+			t.Fatalf("nullable named type not inlined: %v", st.Fields[1].Type)
+		}
+		if _, ok = in.Fields[0].Type.(*Named); !ok {
+			t.Fatal("input type was modified")
+		}
+	})
+
+	t.Run("ExtractStructs", func(t *testing.T) {
+		in := &Struct{
+			Fields: []StructField{
+				{Index: 1, Name: "Home", Type: address},
+				{Index: 2, Name: "Work", Type: ArrayOf(address)},
+			},
+		}
+
+		mod := Module{Name: "mod"}
+		out := Rewrite(in, func(t Type) (Type, bool) {
+			if !TypesEqual(t, address) {
+				return nil, false
+			}
+			if _, found := mod.Definition("Address"); !found {
+				if err := mod.AddDefinition("Address", t); err != nil {
+					return nil, false
+				}
+			}
+			return &Named{Module: mod.Name, Name: "Address", Type: t}, true
+		})
+
+		st := out.(*Struct)
+		for _, f := range st.Fields {
+			var et Type = f.Type
+			if at, ok := et.(*Array); ok {
+				et = at.Type
+			}
+			nt, ok := et.(*Named)
+			if !ok || nt.Name != "Address" {
+				t.Fatalf("struct not extracted in field %s: %v", f.Name, f.Type)
+			}
+		}
+		if len(mod.Definitions) != 1 {
+			t.Fatalf("unexpected number of module definitions: %d", len(mod.Definitions))
+		}
+	})
+
+	t.Run("Recursive", func(t *testing.T) {
+		node := &Struct{}
+		node.Fields = []StructField{
+			{Index: 1, Name: "Value", Type: Int()},
+			{Index: 2, Name: "Next", Type: NullableOf(&Named{Module: "mod", Name: "Node", Type: node})},
+		}
+		nt := &Named{Module: "mod", Name: "Node", Type: node}
+
+		var calls int
+		out := Rewrite(nt, func(t Type) (Type, bool) {
+			calls++
+			return nil, false
+		})
+		if _, ok := out.(*Named); !ok {
+			t.Fatalf("unexpected rewritten type: %T", out)
+		}
+		if calls == 0 {
+			t.Fatal("rewrite function not called")
+		}
+	})
+}
@@ -31,6 +31,9 @@ func GetSharedType(k Kind) Type {
 // PutSharedType puts the shared type back into the cache.
 func PutSharedType(t Type) {
 	switch tp := t.(type) {
+	case nil:
+		// The element types of the skipped composite types are not read.
+		return
 	case *Struct:
 		for _, f := range tp.Fields {
 			PutSharedType(f.Type)
@@ -0,0 +1,97 @@
+package bsttype
+
+// Depth returns the nesting depth of the type tree: one for the types without element types,
+// and one more than the deepest element type for the composites - structs, arrays, maps, nullables and oneofs.
+// The named types are transparent, they add no level. The recursive named references are not descended again,
+// thus they count as a single level. It allows enforcing the nesting limits of the types from untrusted sources.
+func Depth(t Type) int {
+	var depth int
+	walkType(t, func(_ Type, d int) bool {
+		depth = max(depth, d)
+		return true
+	})
+	return depth
+}
+
+// CountFields returns the number of all the struct fields in the type tree, including the fields of the nested structs.
+// The named type definitions are counted for each of their references, apart from the recursive ones.
+func CountFields(t Type) int {
+	var count int
+	walkType(t, func(tt Type, _ int) bool {
+		if st, ok := tt.(*Struct); ok {
+			count += len(st.Fields)
+		}
+		return true
+	})
+	return count
+}
+
+// ContainsKind checks if the type tree contains a type of given kind, the type t itself included.
+func ContainsKind(t Type, k Kind) bool {
+	var found bool
+	walkType(t, func(tt Type, _ int) bool {
+		found = tt.Kind() == k
+		return !found
+	})
+	return found
+}
+
+// walkType calls the fn for each type of the type tree in the depth-first pre-order, along with its depth.
+// The walk stops once the fn returns false.
+func walkType(t Type, fn func(t Type, depth int) bool) {
+	w := walker{fn: fn}
+	w.walk(t, 1)
+}
+
+type walker struct {
+	fn    func(t Type, depth int) bool
+	named []*Named // stack of the named types being walked.
+	done  bool
+}
+
+func (x *walker) walk(t Type, depth int) {
+	if t == nil || x.done {
+		return
+	}
+
+	// 1. Visit the type itself.
+	if !x.fn(t, depth) {
+		x.done = true
+		return
+	}
+
+	// 2. Walk the element types.
+	switch tt := t.(type) {
+	case *Struct:
+		for _, f := range tt.Fields {
+			x.walk(f.Type, depth+1)
+		}
+	case *Array:
+		x.walk(tt.Type, depth+1)
+	case *Map:
+		x.walk(tt.Key.Type, depth+1)
+		x.walk(tt.Value.Type, depth+1)
+	case *Nullable:
+		x.walk(tt.Type, depth+1)
+	case *OneOf:
+		for _, e := range tt.Elements {
+			x.walk(e.Type, depth+1)
+		}
+	case *Named:
+		x.walkNamed(tt, depth)
+	}
+}
+
+func (x *walker) walkNamed(nt *Named, depth int) {
+	// 1. The recursive reference is not descended again.
+	for _, n := range x.named {
+		if n.Module == nt.Module && n.Name == nt.Name {
+			return
+		}
+	}
+
+	// 2. Walk the named type definition at the level of the reference.
+	x.named = append(x.named, nt)
+	x.walk(nt.Type, depth)
+	x.named = x.named[:len(x.named)-1]
+}
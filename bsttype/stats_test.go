@@ -0,0 +1,77 @@
+package bsttype
+
+import (
+	"testing"
+)
+
+func TestTypeStats(t *testing.T) {
+	address := &Struct{
+		Fields: []StructField{
+			{Index: 1, Name: "City", Type: String()},
+			{Index: 2, Name: "Zip", Type: NullableOf(String())},
+		},
+	}
+	node := &Named{Module: "mod", Name: "Node"}
+	node.Type = &Struct{
+		Fields: []StructField{
+			{Index: 1, Name: "Value", Type: Int64()},
+			{Index: 2, Name: "Children", Type: &Array{Type: node}},
+		},
+	}
+	user := &Struct{
+		Fields: []StructField{
+			{Index: 1, Name: "Name", Type: String()},
+			{Index: 2, Name: "Addresses", Type: &Array{Type: &Named{Module: "mod", Name: "Address", Type: address}}},
+			{Index: 3, Name: "Labels", Type: &Map{Key: MapElement{Type: String()}, Value: MapElement{Type: &Bytes{}}}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		t        Type
+		depth    int
+		fields   int
+		contains []Kind
+		missing  []Kind
+	}{
+		{name: "Basic", t: Int32(), depth: 1, fields: 0, contains: []Kind{KindInt32}, missing: []Kind{KindStruct}},
+		{name: "Nil", t: nil, depth: 0, fields: 0, missing: []Kind{KindUndefined}},
+		{
+			name:     "Nested",
+			t:        user,
+			depth:    5,
+			fields:   5,
+			contains: []Kind{KindStruct, KindArray, KindNamed, KindNullable, KindMap, KindBytes},
+			missing:  []Kind{KindOneOf, KindInt64},
+		},
+		{
+			// The recursive reference is not descended again.
+			name:     "Recursive",
+			t:        node,
+			depth:    3,
+			fields:   2,
+			contains: []Kind{KindNamed, KindInt64, KindArray},
+			missing:  []Kind{KindString},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if d := Depth(tc.t); d != tc.depth {
+				t.Errorf("expected depth %d, got %d", tc.depth, d)
+			}
+			if n := CountFields(tc.t); n != tc.fields {
+				t.Errorf("expected %d fields, got %d", tc.fields, n)
+			}
+			for _, k := range tc.contains {
+				if !ContainsKind(tc.t, k) {
+					t.Errorf("expected %s kind to be found", k)
+				}
+			}
+			for _, k := range tc.missing {
+				if ContainsKind(tc.t, k) {
+					t.Errorf("unexpected %s kind found", k)
+				}
+			}
+		})
+	}
+}
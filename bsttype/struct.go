@@ -2,11 +2,13 @@ package bsttype
 
 import (
 	"io"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/internal/pooltrack"
 )
 
 // Compile time checks for the Type interfaces.
@@ -41,7 +43,8 @@ type (
 	// Binary representation looks like:
 	// Size(bits)   | Name               | Description
 	// -------------+--------------------+------------
-	//    8		    | Field Count Size   | Header with the size of the field count.
+	//    8		    | Field Count Size   | Header with the size of the field count, flagged if the fields have defaults
+	//              |                    | or doc strings.
 	//    8-64      | Field Count        | The number of fields in the struct.
 	//    N * Count | Fields elements    | Binary representation of the fields.
 	Struct struct {
@@ -60,9 +63,12 @@ type (
 	//    0 - 64  | Name Length        | The length of the string (0 if the length size was marked as 0x00).
 	//    0 - N   | Name               | The name of the field (0 if the field is undefined)
 	//    1       | Descending flag    | The flag to indicate if the field is descending.
-	//    2       | -				   | Padding to align field type within a single byte.
-	//    5       | Type               | The type of the field.
+	//    1       | Encoding flag      | The flag to indicate if the field has the encoding override.
+	//    6       | Type               | The type of the field.
+	//    0 - 8   | Encoding           | The encoding override of the field value (FieldEncoding) - only if flagged.
 	//    0 - N   | Type Content       | The content of the type - optional if Type is not basic.
+	//    0 - N   | Default            | The default value binary - only if the field count header is flagged.
+	//    0 - N   | Doc                | The doc string of the field - only if the field count header is flagged.
 	StructField struct {
 		// Index is the identifier of the struct field.
 		Index uint
@@ -72,6 +78,15 @@ type (
 		Descending bool
 		// Type is the type of the field.
 		Type Type
+		// Encoding is the encoding override of the field value, applied on top of its type encoding.
+		Encoding FieldEncoding
+		// Default is the binary of the default value of the field, encoded in the regular, ascending mode
+		// of the field type. The Extractor returns it for the field missing in the compatibility mode binary.
+		// The bstvalue.SetFieldDefault and bstvalue.FieldDefault encode and decode it. Empty means no default.
+		Default []byte
+		// Doc is the documentation of the field, for the consumers of the self-describing binaries.
+		// It doesn't affect the encoding of the field values, nor the type equality.
+		Doc string
 	}
 )
 
@@ -103,7 +118,7 @@ func (x *Struct) String() string {
 // Implements the TypeSkipper interface.
 func (x *Struct) SkipType(rs io.ReadSeeker) (int64, error) {
 	// 1. Read the number of fields.
-	length, bl, err := bstio.ReadUint(rs, false)
+	length, defaults, docs, bl, err := readStructFieldCount(rs)
 	if err != nil {
 		return int64(bl), bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct type field length")
 	}
@@ -127,20 +142,40 @@ func (x *Struct) SkipType(rs io.ReadSeeker) (int64, error) {
 		bytesSkipped += n
 
 		// 2.3. Skip the type of the field.
-		n, err = SkipType(rs)
+		n, err = skipFieldType(rs)
 		if err != nil {
 			return bytesSkipped, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip struct field type")
 		}
 		bytesSkipped += n
+
+		// 2.4. Skip the default value of the field.
+		if defaults {
+			n, err = bstio.SkipBytes(rs, 0, false, false)
+			if err != nil {
+				return bytesSkipped, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip struct field default")
+			}
+			bytesSkipped += n
+		}
+
+		// 2.5. Skip the doc string of the field.
+		if docs {
+			n, err = bstio.SkipNonComparableString(rs, false)
+			if err != nil {
+				return bytesSkipped, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip struct field doc")
+			}
+			bytesSkipped += n
+		}
 	}
 	return bytesSkipped, nil
 }
 
 // ReadType reads the value from the byte slice.
+// The read field indices are verified to be non-zero and unique, unless the type is read by the ReadTypeWithOptions
+// with the RepairFieldIndices option, which renumbers them instead.
 // Implements the TypeReader interface.
 func (x *Struct) ReadType(r io.Reader) (int, error) {
 	// 1. Read the number of fields.
-	fl, bl, err := bstio.ReadUint(r, false)
+	fl, defaults, docs, bl, err := readStructFieldCount(r)
 	if err != nil {
 		return bl, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct type field length")
 	}
@@ -157,6 +192,7 @@ func (x *Struct) ReadType(r io.Reader) (int, error) {
 	var (
 		tp         Type
 		descending bool
+		encoding   FieldEncoding
 		index      uint
 		n          int
 		name       string
@@ -177,7 +213,7 @@ func (x *Struct) ReadType(r io.Reader) (int, error) {
 		bytesRead += n
 
 		// 3.3. Read the byte for the type of the field along with the descending flag.
-		tp, descending, n, err = readFieldType(r)
+		tp, descending, encoding, n, err = readFieldType(r)
 		if err != nil {
 			return n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field type")
 		}
@@ -188,42 +224,150 @@ func (x *Struct) ReadType(r io.Reader) (int, error) {
 			Name:       name,
 			Type:       tp,
 			Descending: descending,
+			Encoding:   encoding,
+		}
+
+		// 3.4. Read the default value of the field.
+		if defaults {
+			x.Fields[i].Default, n, err = bstio.ReadBytesNonComparable(r, 0, false)
+			if err != nil {
+				return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field default")
+			}
+			bytesRead += n
+			if len(x.Fields[i].Default) == 0 {
+				x.Fields[i].Default = nil
+			}
+		}
+
+		// 3.5. Read the doc string of the field.
+		if docs {
+			x.Fields[i].Doc, n, err = bstio.ReadStringNonComparable(r, false)
+			if err != nil {
+				return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field doc")
+			}
+			bytesRead += n
 		}
 	}
+
+	// 4. Verify or repair the field indices, which are used to match the fields in the compatibility mode.
+	if _, repair := r.(repairingReader); repair {
+		x.RepairFieldIndices()
+		return bytesRead, nil
+	}
+	if err = x.VerifyFieldIndices(); err != nil {
+		return bytesRead, err
+	}
 	return bytesRead, nil
 }
 
-func readFieldType(r io.Reader) (Type, bool, int, error) {
-	// 1. Read the header byte.
+// structDefaultsFlag is the flag of the struct field count size header, set if the fields are followed
+// by their default values. The size header takes only the low bits, thus the structs without the defaults
+// keep their binary.
+const structDefaultsFlag = 0x80
+
+// structDocsFlag is the flag of the struct field count size header, set if the fields are followed
+// by their doc strings (after the default values, if any).
+const structDocsFlag = 0x40
+
+func readStructFieldCount(r io.Reader) (uint, bool, bool, int, error) {
 	bt, err := bstio.ReadByte(r)
 	if err != nil {
-		return nil, false, 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field type")
+		return 0, false, false, 0, err
 	}
-	total := 1
+	fl, n, err := bstio.ReadUintValue(r, bt&^(structDefaultsFlag|structDocsFlag), false)
+	if err != nil {
+		return 0, false, false, 1 + n, err
+	}
+	return fl, bt&structDefaultsFlag != 0, bt&structDocsFlag != 0, 1 + n, nil
+}
 
-	// 2. First bit specifies if the field is descending.
-	descending := bt&0x80 != 0
+func writeStructFieldCount(w io.Writer, fl uint, defaults, docs bool) (int, error) {
+	b := bstio.MarshalUint(fl, false)
+	if defaults {
+		b[0] |= structDefaultsFlag
+	}
+	if docs {
+		b[0] |= structDocsFlag
+	}
+	return w.Write(b)
+}
 
-	// 3. Trim the first bit and initialize an empty type.
-	bt &^= 0x80
-	et := emptyKindType(Kind(bt), false)
+func readFieldType(r io.Reader) (Type, bool, FieldEncoding, int, error) {
+	// 1. Read the header, along with the field encoding.
+	kind, descending, encoding, total, err := readFieldTypeHeader(r)
+	if err != nil {
+		return nil, false, 0, total, err
+	}
 
-	// 4. Check if the type ha a ReadType function.
+	// 2. Initialize an empty type out of the kind.
+	et := emptyKindType(kind, false)
+
+	// 3. Check if the type ha a ReadType function.
 	tr, ok := et.(TypeReader)
 	if !ok {
-		return et, descending, total, nil
+		return et, descending, encoding, total, nil
 	}
 	n, err := tr.ReadType(r)
 	if err != nil {
-		return nil, false, 0, err
+		return nil, false, 0, 0, err
+	}
+	return et, descending, encoding, total + n, nil
+}
+
+func skipFieldType(rs io.ReadSeeker) (int64, error) {
+	// 1. Read the header, along with the field encoding.
+	kind, _, _, total, err := readFieldTypeHeader(rs)
+	if err != nil {
+		return int64(total), err
+	}
+
+	// 2. Skip the content of the type.
+	et := emptyKindType(kind, true)
+	defer PutSharedType(et)
+	ts, ok := et.(TypeSkipper)
+	if !ok {
+		return int64(total), nil
+	}
+	n, err := ts.SkipType(rs)
+	return int64(total) + n, err
+}
+
+// readFieldTypeHeader reads the struct field type header byte, along with the field encoding byte if it is flagged.
+// Returns the kind of the field type, the descending flag, the field encoding and the number of bytes read.
+func readFieldTypeHeader(r io.Reader) (Kind, bool, FieldEncoding, int, error) {
+	// 1. Read the header byte.
+	bt, err := bstio.ReadByte(r)
+	if err != nil {
+		return 0, false, 0, 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field type")
 	}
-	return et, descending, total + n, nil
+	if bt&fieldEncodingFlag == 0 {
+		return Kind(bt & fieldKindMask), bt&fieldDescendingFlag != 0, FieldEncodingPlain, 1, nil
+	}
+
+	// 2. The flagged field encoding follows the header.
+	eb, err := bstio.ReadByte(r)
+	if err != nil {
+		return 0, false, 0, 1, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field encoding")
+	}
+	encoding := FieldEncoding(eb)
+	if encoding > maxKnownFieldEncoding {
+		return 0, false, 0, 2, bsterr.Err(bsterr.CodeDecodingBinaryType, "unknown struct field encoding").
+			WithDetail("encoding", eb)
+	}
+	return Kind(bt & fieldKindMask), bt&fieldDescendingFlag != 0, encoding, 2, nil
 }
 
 // WriteType writes the value to the byte slice.
 func (x *Struct) WriteType(w io.Writer) (int, error) {
-	// 1. Write the number of fields.
-	n, err := bstio.WriteUint(w, uint(len(x.Fields)), false)
+	// 1. Write the number of fields, flagged if any of the fields has a default value or a doc string.
+	//    The doc strings are not written to the doc omitting writer - see the WriteTypeWithOptions.
+	var defaults, docs bool
+	for _, f := range x.Fields {
+		defaults = defaults || len(f.Default) > 0
+		docs = docs || f.Doc != ""
+	}
+	docs = docs && !omitsDocs(w)
+	n, err := writeStructFieldCount(w, uint(len(x.Fields)), defaults, docs)
 	if err != nil {
 		return n, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct type field length")
 	}
@@ -246,31 +390,68 @@ func (x *Struct) WriteType(w io.Writer) (int, error) {
 		bytesWritten += n
 
 		// 2.3. Write the type of the field.
-		n, err = writeFieldType(w, f.Type, f.Descending)
+		n, err = writeFieldType(w, f.Type, f.Descending, f.Encoding)
 		if err != nil {
 			return n, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field type")
 		}
 		bytesWritten += n
+
+		// 2.4. Write the default value of the field.
+		if defaults {
+			n, err = bstio.WriteBytes(w, 0, f.Default, false, false)
+			if err != nil {
+				return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field default")
+			}
+			bytesWritten += n
+		}
+
+		// 2.5. Write the doc string of the field.
+		if docs {
+			n, err = bstio.WriteStringNonComparable(w, f.Doc, false)
+			if err != nil {
+				return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field doc")
+			}
+			bytesWritten += n
+		}
 	}
 
 	return bytesWritten, nil
 }
 
-func writeFieldType(w io.Writer, vt Type, desc bool) (int, error) {
-	// 1. Convert the type kind to the byte.
+func writeFieldType(w io.Writer, vt Type, desc bool, encoding FieldEncoding) (int, error) {
+	// 1. Convert the type kind to the byte. The kind needs to fit the bits left by the header flags.
+	if vt.Kind() > fieldKindMask {
+		return 0, bsterr.Err(bsterr.CodeEncodingBinaryType, "struct field type kind doesn't fit the field type header").
+			WithDetail("kind", vt.Kind())
+	}
 	fk := byte(vt.Kind())
 
 	// 2. If the type is descending, set the descending flag for the first MSB.
 	if desc {
-		fk |= 0x80
+		fk |= fieldDescendingFlag
 	}
 
-	// 3. Write the type byte.
+	// 2.1. The field encoding override is flagged, and written in the byte following the header.
+	if encoding > maxKnownFieldEncoding {
+		return 0, bsterr.Err(bsterr.CodeEncodingBinaryType, "unknown struct field encoding").
+			WithDetail("encoding", encoding)
+	}
+	if encoding != FieldEncodingPlain {
+		fk |= fieldEncodingFlag
+	}
+
+	// 3. Write the type byte, followed by the field encoding.
 	if err := bstio.WriteByte(w, fk); err != nil {
 		return 0, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write type").
 			WithDetail("type", vt.Kind())
 	}
 	total := 1
+	if encoding != FieldEncodingPlain {
+		if err := bstio.WriteByte(w, byte(encoding)); err != nil {
+			return total, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field encoding")
+		}
+		total++
+	}
 
 	// 4.  If the type implements TypeContent interface, write the content.
 	tc, ok := vt.(TypeWriter)
@@ -299,7 +480,7 @@ func (x *Struct) CompareType(to TypeComparer) bool {
 	}
 
 	for i := range x.Fields {
-		if x.Fields[i].Name != xto.Fields[i].Name {
+		if x.Fields[i].Name != xto.Fields[i].Name || x.Fields[i].Encoding != xto.Fields[i].Encoding {
 			return false
 		}
 
@@ -318,6 +499,170 @@ func (x *Struct) PreviewPrevElemType(i int) (Type, bool) {
 	return x.Fields[i].Type, true
 }
 
+// FieldByIndex returns the field with given identifier (StructField.Index), along with its position in the Fields.
+// The found flag is false if there is no such field.
+func (x *Struct) FieldByIndex(index uint) (f StructField, pos int, found bool) {
+	for i := range x.Fields {
+		if x.Fields[i].Index == index {
+			return x.Fields[i], i, true
+		}
+	}
+	return StructField{}, -1, false
+}
+
+// FieldByName returns the field with given name, along with its position in the Fields.
+// The found flag is false if there is no such field.
+func (x *Struct) FieldByName(name string) (f StructField, pos int, found bool) {
+	for i := range x.Fields {
+		if x.Fields[i].Name == name {
+			return x.Fields[i], i, true
+		}
+	}
+	return StructField{}, -1, false
+}
+
+// MaxFieldIndex returns the greatest field identifier (StructField.Index) of the struct.
+// The field indices might not be contiguous, thus it is not the number of fields.
+// An empty struct returns 0.
+func (x *Struct) MaxFieldIndex() uint {
+	var m uint
+	for i := range x.Fields {
+		if x.Fields[i].Index > m {
+			m = x.Fields[i].Index
+		}
+	}
+	return m
+}
+
+// SortedFields returns a copy of the fields, sorted by their identifiers (StructField.Index).
+// The compatibility mode matches the fields of different struct versions in this order.
+func (x *Struct) SortedFields() []StructField {
+	fields := make([]StructField, len(x.Fields))
+	copy(fields, x.Fields)
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Index < fields[j].Index
+	})
+	return fields
+}
+
+// VerifyFieldIndices checks that the field identifiers (StructField.Index) are non-zero and unique,
+// as otherwise the fields could not be matched in the compatibility mode.
+func (x *Struct) VerifyFieldIndices() error {
+	seen := make(map[uint]string, len(x.Fields))
+	for _, f := range x.Fields {
+		if f.Index == 0 {
+			return bsterr.Err(bsterr.CodeInvalidType, "struct field index is zero").
+				WithDetail("field", f.Name)
+		}
+		if other, ok := seen[f.Index]; ok {
+			return bsterr.Err(bsterr.CodeInvalidType, "struct field index is duplicated").
+				WithDetails(
+					bsterr.D("index", f.Index),
+					bsterr.D("field", f.Name),
+					bsterr.D("other", other),
+				)
+		}
+		seen[f.Index] = f.Name
+	}
+	return nil
+}
+
+// RepairFieldIndices renumbers the zero and duplicated field identifiers (StructField.Index) with the next free ones,
+// keeping the first occurrence of each duplicated index and the order of the fields. It returns the number of
+// renumbered fields. It is meant for the tooling, as the renumbered fields might not match their previous versions.
+func (x *Struct) RepairFieldIndices() int {
+	// 1. Find the fields which need to be renumbered.
+	seen := make(map[uint]struct{}, len(x.Fields))
+	var invalid []int
+	for i, f := range x.Fields {
+		if _, ok := seen[f.Index]; ok || f.Index == 0 {
+			invalid = append(invalid, i)
+			continue
+		}
+		seen[f.Index] = struct{}{}
+	}
+
+	// 2. Renumber them in order.
+	next := x.MaxFieldIndex()
+	for _, i := range invalid {
+		next++
+		x.Fields[i].Index = next
+	}
+	return len(invalid)
+}
+
+// StructFieldOption is an option of the field added by the Struct.AddField.
+type StructFieldOption func(f *StructField)
+
+// WithFieldIndex sets the explicit identifier of the added field, instead of the next free one.
+func WithFieldIndex(index uint) StructFieldOption {
+	return func(f *StructField) {
+		f.Index = index
+	}
+}
+
+// WithFieldDescending marks the added field as encoded in descending order.
+func WithFieldDescending() StructFieldOption {
+	return func(f *StructField) {
+		f.Descending = true
+	}
+}
+
+// WithFieldEncoding sets the encoding override of the added field value.
+func WithFieldEncoding(encoding FieldEncoding) StructFieldOption {
+	return func(f *StructField) {
+		f.Encoding = encoding
+	}
+}
+
+// WithFieldDoc sets the doc string of the added field.
+func WithFieldDoc(doc string) StructFieldOption {
+	return func(f *StructField) {
+		f.Doc = doc
+	}
+}
+
+// AddField adds a new field with given name and type to the struct, and returns it.
+// The field gets the next free identifier (MaxFieldIndex + 1), unless the WithFieldIndex option is used.
+// The fields are kept sorted by their identifiers, thus the field with explicit index might be inserted
+// in the middle of the Fields. An error is returned if the name or the index is already used.
+func (x *Struct) AddField(name string, t Type, opts ...StructFieldOption) (StructField, error) {
+	// 1. Verify the input.
+	if name == "" || t == nil {
+		return StructField{}, bsterr.Err(bsterr.CodeInvalidType, "struct field requires a name and a type").
+			WithDetail("name", name)
+	}
+	if _, _, found := x.FieldByName(name); found {
+		return StructField{}, bsterr.Err(bsterr.CodeInvalidType, "struct field name already defined").
+			WithDetail("name", name)
+	}
+
+	// 2. Set up the field with the next free index and apply the options.
+	f := StructField{Index: x.MaxFieldIndex() + 1, Name: name, Type: t}
+	for _, opt := range opts {
+		opt(&f)
+	}
+	if err := f.VerifyEncoding(); err != nil {
+		return StructField{}, err
+	}
+	if _, _, found := x.FieldByIndex(f.Index); found {
+		return StructField{}, bsterr.Err(bsterr.CodeInvalidType, "struct field index already defined").
+			WithDetails(
+				bsterr.D("name", name),
+				bsterr.D("index", f.Index),
+			)
+	}
+
+	// 3. Insert the field at its sorted position.
+	pos := sort.Search(len(x.Fields), func(i int) bool {
+		return x.Fields[i].Index > f.Index
+	})
+	x.Fields = append(x.Fields, StructField{})
+	copy(x.Fields[pos+1:], x.Fields[pos:])
+	x.Fields[pos] = f
+	return f, nil
+}
+
 // CheckDependencies iterates over all fields and tries to check all named type dependency within given modules.
 // Implements DependencyChecker interface.
 func (x *Struct) CheckDependencies(m *Modules) (CheckDependenciesResult, error) {
@@ -472,6 +817,9 @@ func (x *Struct) copy(shared bool) Type {
 			Index:      f.Index,
 			Name:       f.Name,
 			Descending: f.Descending,
+			Encoding:   f.Encoding,
+			Default:    f.Default,
+			Doc:        f.Doc,
 			Type:       f.Type.(copier).copy(shared),
 		}
 	}
@@ -484,16 +832,18 @@ var _sharedStructsPool = &sharedPool{defaultSize: 10}
 func getSharedStruct() *Struct {
 	v := _sharedStructsPool.pool.Get()
 	st, ok := v.(*Struct)
-	if ok {
-		return st
-	}
-	return &Struct{
-		Fields:       make([]StructField, 0, _sharedStructsPool.defaultSize),
-		needsRelease: true,
+	if !ok {
+		st = &Struct{
+			Fields:       make([]StructField, 0, _sharedStructsPool.defaultSize),
+			needsRelease: true,
+		}
 	}
+	pooltrack.Acquire(st)
+	return st
 }
 
 func putSharedStruct(x *Struct) {
+	pooltrack.Release(x)
 	if !x.needsRelease {
 		return
 	}
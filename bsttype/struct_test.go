@@ -12,7 +12,7 @@ import (
 var _embedStructType = &Struct{
 	Fields: []StructField{
 		{
-			Index: 0,
+			Index: 1,
 			Name:  "String",
 			Type:  String(),
 		},
@@ -28,15 +28,15 @@ var structTypeTestCases = []struct {
 		Name: "StructTypeTest1",
 		Type: Struct{
 			Fields: []StructField{
-				{Index: 0, Name: "String", Type: String()},
-				{Index: 1, Name: "Uint8", Type: Uint8()},
+				{Index: 1, Name: "String", Type: String()},
+				{Index: 2, Name: "Uint8", Type: Uint8()},
 			}},
 		Binary: []byte{
 			// Fields
 			// Fields length
 			bstio.BinarySizeUint8, byte(2),
 			// String.Index
-			bstio.BinarySizeZero,
+			bstio.BinarySizeUint8, byte(1),
 			// String.Name
 			bstio.BinarySizeUint8, byte(len("String")),
 			// String value
@@ -45,7 +45,7 @@ var structTypeTestCases = []struct {
 			byte(KindString),
 			// Uint8 field
 			// Uint8.Index
-			bstio.BinarySizeUint8, byte(1),
+			bstio.BinarySizeUint8, byte(2),
 			// Uint8 Name
 			bstio.BinarySizeUint8, byte(len("Uint8")),
 			// Uint8 value
@@ -54,6 +54,37 @@ var structTypeTestCases = []struct {
 			byte(KindUint8),
 		},
 	},
+	{
+		Name: "FieldEncoding",
+		Type: Struct{
+			Fields: []StructField{
+				{Index: 1, Name: "Blob", Type: String(), Descending: true, Encoding: FieldEncodingDeflate},
+				{Index: 2, Name: "Ids", Type: &Array{Type: Uint8()}, Encoding: FieldEncodingDelta},
+			}},
+		Binary: []byte{
+			// Fields length
+			bstio.BinarySizeUint8, byte(2),
+			// Blob.Index
+			bstio.BinarySizeUint8, byte(1),
+			// Blob.Name
+			bstio.BinarySizeUint8, byte(len("Blob")),
+			'B', 'l', 'o', 'b',
+			// Blob Type - descending and encoding flags and the kind, followed by the deflate encoding.
+			0x80 | 0x40 | byte(KindString),
+			byte(FieldEncodingDeflate),
+			// Ids.Index
+			bstio.BinarySizeUint8, byte(2),
+			// Ids.Name
+			bstio.BinarySizeUint8, byte(len("Ids")),
+			'I', 'd', 's',
+			// Ids Type - encoding flag and the kind, followed by the delta encoding,
+			// the array element type and size header.
+			0x40 | byte(KindArray),
+			byte(FieldEncodingDelta),
+			byte(KindUint8),
+			0x00,
+		},
+	},
 	{
 		Name: "Empty",
 		Type: Struct{},
@@ -66,7 +97,7 @@ var structTypeTestCases = []struct {
 	{
 		Name: "Embedded",
 		Type: Struct{Fields: []StructField{
-			{Index: 0, Name: "EmbeddedStruct", Type: _embedStructType}},
+			{Index: 1, Name: "EmbeddedStruct", Type: _embedStructType}},
 		},
 		Binary: []byte{
 			// Fields
@@ -74,7 +105,7 @@ var structTypeTestCases = []struct {
 			bstio.BinarySizeUint8, byte(1),
 			// Field 1: EmbeddedStruct
 			// Field.Index
-			bstio.BinarySizeZero,
+			bstio.BinarySizeUint8, byte(1),
 			// Field.Name
 			// Field.Name length
 			bstio.BinarySizeUint8, byte(len("EmbeddedStruct")),
@@ -87,7 +118,7 @@ var structTypeTestCases = []struct {
 			bstio.BinarySizeUint8, byte(1),
 			// Embedded.Fields.Field 1: String
 			// Embedded.Fields.Field.Index
-			bstio.BinarySizeZero,
+			bstio.BinarySizeUint8, byte(1),
 			// Embedded.Fields.Field.Name
 			// Embedded.Fields.Field.Name length
 			bstio.BinarySizeUint8, byte(len("String")),
@@ -171,3 +202,324 @@ func TestStructType_SkipType(t *testing.T) {
 		})
 	}
 }
+
+func TestStructType_FieldTypeHeader(t *testing.T) {
+	t.Run("KindOutOfHeader", func(t *testing.T) {
+		st := Struct{Fields: []StructField{{Index: 1, Name: "A", Type: &Basic{TypeKind: fieldKindMask + 1}}}}
+		if _, err := st.WriteType(&bytes.Buffer{}); err == nil {
+			t.Fatal("expected error for the kind not fitting the field type header")
+		}
+	})
+
+	t.Run("UnknownEncoding", func(t *testing.T) {
+		data := []byte{
+			bstio.BinarySizeUint8, byte(1),
+			bstio.BinarySizeUint8, byte(1),
+			bstio.BinarySizeUint8, byte(len("A")), 'A',
+			fieldEncodingFlag | byte(KindString), byte(maxKnownFieldEncoding + 1),
+		}
+		var st Struct
+		if _, err := st.ReadType(bytes.NewReader(data)); err == nil {
+			t.Fatal("expected error for the unknown field encoding")
+		}
+		if _, err := st.SkipType(bytes.NewReader(data)); err == nil {
+			t.Fatal("expected error for the unknown field encoding")
+		}
+	})
+
+	t.Run("Plain", func(t *testing.T) {
+		// The plain encoded field type header has no encoding byte, as before the field encodings.
+		st := Struct{Fields: []StructField{{Index: 1, Name: "A", Type: String(), Descending: true}}}
+		var buf bytes.Buffer
+		if _, err := st.WriteType(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if h := buf.Bytes()[buf.Len()-1]; h != fieldDescendingFlag|byte(KindString) {
+			t.Fatalf("unexpected field type header: %x", h)
+		}
+	})
+}
+
+func TestStructType_Fields(t *testing.T) {
+	st := Struct{
+		Fields: []StructField{
+			{Index: 5, Name: "c", Type: String()},
+			{Index: 1, Name: "a", Type: Uint8()},
+			{Index: 3, Name: "b", Type: Boolean()},
+		},
+	}
+
+	t.Run("FieldByIndex", func(t *testing.T) {
+		f, pos, found := st.FieldByIndex(3)
+		if !found || f.Name != "b" || pos != 2 {
+			t.Fatalf("unexpected field: %v at %d, found: %v", f, pos, found)
+		}
+		if _, pos, found = st.FieldByIndex(2); found || pos != -1 {
+			t.Fatalf("unexpected field found in the index gap at %d", pos)
+		}
+	})
+
+	t.Run("FieldByName", func(t *testing.T) {
+		f, pos, found := st.FieldByName("c")
+		if !found || f.Index != 5 || pos != 0 {
+			t.Fatalf("unexpected field: %v at %d, found: %v", f, pos, found)
+		}
+		if _, _, found = st.FieldByName("d"); found {
+			t.Fatal("unexpected field found")
+		}
+	})
+
+	t.Run("MaxFieldIndex", func(t *testing.T) {
+		if m := st.MaxFieldIndex(); m != 5 {
+			t.Fatalf("unexpected max field index: %d", m)
+		}
+		if m := (&Struct{}).MaxFieldIndex(); m != 0 {
+			t.Fatalf("unexpected max field index of empty struct: %d", m)
+		}
+	})
+
+	t.Run("SortedFields", func(t *testing.T) {
+		sorted := st.SortedFields()
+		for i, name := range []string{"a", "b", "c"} {
+			if sorted[i].Name != name {
+				t.Fatalf("unexpected field at %d: %s, expected: %s", i, sorted[i].Name, name)
+			}
+		}
+		if st.Fields[0].Name != "c" {
+			t.Fatal("sorting modified the struct fields")
+		}
+	})
+}
+
+func TestStructType_AddField(t *testing.T) {
+	var st Struct
+	for _, name := range []string{"a", "b"} {
+		if _, err := st.AddField(name, String()); err != nil {
+			t.Fatalf("adding field failed: %v", err)
+		}
+	}
+	f, err := st.AddField("c", Uint8(), WithFieldIndex(5), WithFieldDescending())
+	if err != nil {
+		t.Fatalf("adding field failed: %v", err)
+	}
+	if f.Index != 5 || !f.Descending {
+		t.Fatalf("unexpected field: %v", f)
+	}
+	f, err = st.AddField("d", Boolean())
+	if err != nil {
+		t.Fatalf("adding field failed: %v", err)
+	}
+	if f.Index != 6 {
+		t.Fatalf("unexpected next field index: %d", f.Index)
+	}
+	if _, err = st.AddField("e", Boolean(), WithFieldIndex(3)); err != nil {
+		t.Fatalf("adding field failed: %v", err)
+	}
+
+	for i, name := range []string{"a", "b", "e", "c", "d"} {
+		if st.Fields[i].Name != name {
+			t.Fatalf("unexpected field at %d: %s, expected: %s", i, st.Fields[i].Name, name)
+		}
+	}
+
+	if _, err = st.AddField("a", String()); err == nil {
+		t.Fatal("expected error for duplicated field name")
+	}
+	if _, err = st.AddField("f", String(), WithFieldIndex(5)); err == nil {
+		t.Fatal("expected error for duplicated field index")
+	}
+	if _, err = st.AddField("g", Uint8(), WithFieldEncoding(FieldEncodingDeflate)); err == nil {
+		t.Fatal("expected error for invalid field encoding")
+	}
+
+	t.Run("Encoding", func(t *testing.T) {
+		valid := []StructField{
+			{Name: "String", Type: String(), Encoding: FieldEncodingDeflate},
+			{Name: "Bytes", Type: &Bytes{}, Encoding: FieldEncodingDeflate},
+			{Name: "External", Type: String(), Encoding: FieldEncodingExternal},
+			{Name: "Ints", Type: &Array{Type: Int32()}, Encoding: FieldEncodingDelta},
+			{Name: "Named", Type: &Named{Name: "Unresolved"}, Encoding: FieldEncodingDelta},
+		}
+		for _, f := range valid {
+			if err := f.VerifyEncoding(); err != nil {
+				t.Errorf("%s: unexpected error: %v", f.Name, err)
+			}
+		}
+		invalid := []StructField{
+			{Name: "FixedBytes", Type: &Bytes{FixedSize: 4}, Encoding: FieldEncodingDeflate},
+			{Name: "Strings", Type: &Array{Type: String()}, Encoding: FieldEncodingDelta},
+			{Name: "ExternalInt", Type: Int64(), Encoding: FieldEncodingExternal},
+			{Name: "RunLength", Type: &Array{Type: Uint8(), Encoding: ArrayEncodingRunLength}, Encoding: FieldEncodingDelta},
+			{Name: "Unknown", Type: String(), Encoding: maxKnownFieldEncoding + 1},
+		}
+		for _, f := range invalid {
+			if err := f.VerifyEncoding(); err == nil {
+				t.Errorf("%s: expected error", f.Name)
+			}
+		}
+	})
+
+	t.Run("Module", func(t *testing.T) {
+		m := Module{Name: "mod"}
+		if err := m.AddDefinition("Person", &Struct{}); err != nil {
+			t.Fatalf("adding definition failed: %v", err)
+		}
+		if err := m.AddDefinition("Person", &Struct{}); err == nil {
+			t.Fatal("expected error for duplicated definition")
+		}
+		f, err := m.AddStructField("Person", "Name", String())
+		if err != nil {
+			t.Fatalf("adding struct field failed: %v", err)
+		}
+		if f.Index != 1 {
+			t.Fatalf("unexpected field index: %d", f.Index)
+		}
+		if _, err = m.AddStructField("Missing", "Name", String()); err == nil {
+			t.Fatal("expected error for missing definition")
+		}
+	})
+}
+
+func TestStructType_FieldIndices(t *testing.T) {
+	invalid := &Struct{Fields: []StructField{
+		{Index: 1, Name: "A", Type: Uint8()},
+		{Index: 1, Name: "B", Type: &Array{Type: &Struct{Fields: []StructField{
+			{Index: 0, Name: "C", Type: String()},
+			{Index: 2, Name: "D", Type: String()},
+		}}}},
+		{Index: 3, Name: "E", Type: Uint8()},
+	}}
+	var buf bytes.Buffer
+	if _, err := WriteType(&buf, invalid); err != nil {
+		t.Fatalf("writing type failed: %v", err)
+	}
+
+	// 1. The duplicated and zero indices fail the read, including the nested ones.
+	if _, _, err := ReadType(bytes.NewReader(buf.Bytes()), false); err == nil {
+		t.Fatal("expected reading struct with duplicated field index to fail")
+	}
+	nested := invalid.Fields[1].Type.(*Array).Type.(*Struct)
+	if err := nested.VerifyFieldIndices(); err == nil {
+		t.Fatal("expected zero field index to fail")
+	}
+
+	// 2. The repaired type is renumbered with the next free indices, keeping the fields order.
+	rt, n, err := ReadTypeWithOptions(bytes.NewReader(buf.Bytes()), ReadTypeOptions{RepairFieldIndices: true})
+	if err != nil {
+		t.Fatalf("reading repaired type failed: %v", err)
+	}
+	if n != buf.Len() {
+		t.Fatalf("unexpected number of bytes read: %d, expected: %d", n, buf.Len())
+	}
+	st := rt.(*Struct)
+	for i, index := range []uint{1, 4, 3} {
+		if st.Fields[i].Index != index {
+			t.Fatalf("unexpected field %s index: %d, expected: %d", st.Fields[i].Name, st.Fields[i].Index, index)
+		}
+	}
+	nested = st.Fields[1].Type.(*Array).Type.(*Struct)
+	if nested.Fields[0].Index != 3 || nested.Fields[1].Index != 2 {
+		t.Fatalf("unexpected nested field indices: %v", nested)
+	}
+	if err = st.VerifyFieldIndices(); err != nil {
+		t.Fatalf("repaired struct is invalid: %v", err)
+	}
+}
+
+func TestStructType_Docs(t *testing.T) {
+	documented := func() *Struct {
+		return &Struct{Fields: []StructField{
+			{Index: 1, Name: "id", Type: Uint64(), Doc: "ID is the unique identifier."},
+			{Index: 2, Name: "name", Type: String(), Default: []byte{0x01, 0x01, 'x'}},
+			{Index: 3, Name: "address", Type: &Struct{Fields: []StructField{
+				{Index: 1, Name: "city", Type: String(), Doc: "City of the address."},
+			}}},
+		}}
+	}
+	// 1. The doc strings are read back, and skipped along with the type.
+	st := documented()
+	var buf bytes.Buffer
+	n, err := WriteType(&buf, st)
+	if err != nil || n != buf.Len() {
+		t.Fatalf("writing type failed: %d, %v", n, err)
+	}
+	data := append([]byte{}, buf.Bytes()...)
+	rt, n, err := ReadType(bytes.NewReader(data), false)
+	if err != nil || n != len(data) {
+		t.Fatalf("reading type failed: %d, %v", n, err)
+	}
+	rst := rt.(*Struct)
+	if rst.Fields[0].Doc != st.Fields[0].Doc || rst.Fields[1].Doc != "" ||
+		rst.Fields[2].Type.(*Struct).Fields[0].Doc != "City of the address." {
+		t.Fatalf("unexpected doc strings: %v", rst.Fields)
+	}
+	if !bytes.Equal(rst.Fields[1].Default, st.Fields[1].Default) {
+		t.Fatalf("unexpected default: %v", rst.Fields[1].Default)
+	}
+	if skipped, err := SkipType(bytes.NewReader(data)); err != nil || int(skipped) != len(data) {
+		t.Fatalf("skipping type failed: %d, %v", skipped, err)
+	}
+	if !TypesEqual(st, rst) {
+		t.Fatal("read type differs")
+	}
+
+	// 2. The omitted doc strings leave the binary of the undocumented type.
+	buf.Reset()
+	if _, err = WriteTypeWithOptions(&buf, st, WriteTypeOptions{OmitDocs: true}); err != nil {
+		t.Fatalf("writing type failed: %v", err)
+	}
+	omitted := append([]byte{}, buf.Bytes()...)
+	undocumented := documented()
+	undocumented.Fields[0].Doc = ""
+	undocumented.Fields[2].Type.(*Struct).Fields[0].Doc = ""
+	buf.Reset()
+	if _, err = WriteType(&buf, undocumented); err != nil {
+		t.Fatalf("writing type failed: %v", err)
+	}
+	if !bytes.Equal(omitted, buf.Bytes()) || bytes.Equal(omitted, data) {
+		t.Fatalf("unexpected binary with omitted docs: %v", omitted)
+	}
+	if !TypesEqual(st, undocumented) {
+		t.Fatal("doc strings affect the type equality")
+	}
+
+	// 3. The module and its definitions carry the doc strings as well.
+	m := &Modules{List: []*Module{{
+		Name: "shop",
+		Doc:  "Shop is the module of the orders.",
+		Definitions: []ModuleDefinition{
+			{Name: "order", Type: documented(), Doc: "Order is the placed order."},
+			{Name: "status", Type: Uint8()},
+		},
+	}}}
+	buf.Reset()
+	if _, err = m.Write(&buf); err != nil {
+		t.Fatalf("writing modules failed: %v", err)
+	}
+	var rm Modules
+	if n, err = rm.Read(bytes.NewReader(buf.Bytes()), false); err != nil || n != buf.Len() {
+		t.Fatalf("reading modules failed: %d, %v", n, err)
+	}
+	mod := rm.List[0]
+	if mod.Doc != m.List[0].Doc || mod.Definitions[0].Doc != "Order is the placed order." || mod.Definitions[1].Doc != "" {
+		t.Fatalf("unexpected module doc strings: %+v", mod)
+	}
+	if doc, ok := mod.DefinitionDoc("order"); !ok || doc != "Order is the placed order." {
+		t.Fatalf("unexpected definition doc: %q, %v", doc, ok)
+	}
+	if mod.Definitions[0].Type.(*Struct).Fields[0].Doc != "ID is the unique identifier." {
+		t.Fatal("definition type doc strings not read")
+	}
+
+	buf.Reset()
+	if _, err = m.WriteWithOptions(&buf, WriteTypeOptions{OmitDocs: true}); err != nil {
+		t.Fatalf("writing modules failed: %v", err)
+	}
+	rm = Modules{}
+	if _, err = rm.Read(bytes.NewReader(buf.Bytes()), false); err != nil {
+		t.Fatalf("reading modules failed: %v", err)
+	}
+	if mod = rm.List[0]; mod.Doc != "" || mod.Definitions[0].Doc != "" || mod.Definitions[0].Type.(*Struct).Fields[0].Doc != "" {
+		t.Fatalf("unexpected omitted doc strings: %+v", mod)
+	}
+}
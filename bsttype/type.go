@@ -41,6 +41,41 @@ type TypeComparer interface {
 	CompareType(to TypeComparer) bool
 }
 
+// TypeFixedSizer is the interface of the types whose values might have a fixed encoded size,
+// regardless of the value and the encoding options.
+type TypeFixedSizer interface {
+	// FixedEncodedSize returns the size of the encoded value, if it is fixed.
+	FixedEncodedSize() (int, bool)
+}
+
+// FixedEncodedSize returns the size of the encoded value of given type, if it is fixed.
+// It allows skipping or estimating the size of the values without decoding them.
+// The boolean values are an exception, as multiple booleans of the array or struct are packed into bytes.
+func FixedEncodedSize(t Type) (int, bool) {
+	fs, ok := t.(TypeFixedSizer)
+	if !ok {
+		return 0, false
+	}
+	return fs.FixedEncodedSize()
+}
+
+// MinEncodedSize returns the minimum size of the encoded value of given type.
+// It is the lower bound used to validate the decoded lengths of the collections against the remaining input.
+// The booleans, structs and named types might take no bytes at all, thus their minimum size is 0.
+func MinEncodedSize(t Type) int {
+	switch t.Kind() {
+	case KindUndefined, KindBoolean, KindStruct, KindNamed:
+		return 0
+	}
+	if size, ok := FixedEncodedSize(t); ok {
+		return size
+	}
+	if at, ok := t.(*Array); ok && at.HasFixedSize() {
+		return 0
+	}
+	return 1
+}
+
 // TypesEqual compares two types.
 func TypesEqual(t1, t2 Type) bool {
 	if t1.Kind() != t2.Kind() {
@@ -74,6 +109,9 @@ func ReadType(r io.Reader, sharedDefs bool) (Type, int, error) {
 	// 2. Create the type for given kind.
 	et := emptyKindType(Kind(bh), sharedDefs)
 	if et.Kind() == KindUndefined {
+		if sharedDefs {
+			PutSharedType(et)
+		}
 		return nil, total, bsterr.Err(bsterr.CodeEncodingBinaryValue, "undefined Kind for value type")
 	}
 
@@ -87,11 +125,65 @@ func ReadType(r io.Reader, sharedDefs bool) (Type, int, error) {
 	var n int
 	n, err = tr.ReadType(r)
 	if err != nil {
+		// 4.1. Release the partially read shared type.
+		if sharedDefs {
+			PutSharedType(et)
+		}
 		return nil, total + n, err
 	}
 	return et, total + n, nil
 }
 
+// ReadTypeOptions are the options of the ReadTypeWithOptions.
+type ReadTypeOptions struct {
+	// SharedDefs reads the type definitions out of the shared pool.
+	SharedDefs bool
+	// RepairFieldIndices renumbers the zero and duplicated struct field indices, instead of failing the read.
+	// It is meant for the tooling which inspects the untrusted or legacy binaries, see the Struct.RepairFieldIndices.
+	RepairFieldIndices bool
+}
+
+// repairingReader marks the reader of the type which struct field indices are repaired instead of being verified.
+// The nested types are read from the same reader, thus they are repaired as well.
+type repairingReader struct {
+	io.Reader
+}
+
+// ReadTypeWithOptions reads the binary representation of the Type from the reader, with given options.
+func ReadTypeWithOptions(r io.Reader, options ReadTypeOptions) (Type, int, error) {
+	if options.RepairFieldIndices {
+		r = repairingReader{Reader: r}
+	}
+	return ReadType(r, options.SharedDefs)
+}
+
+// WriteTypeOptions are the options of the WriteTypeWithOptions.
+type WriteTypeOptions struct {
+	// OmitDocs omits the doc strings of the struct fields and the module definitions, i.e. to keep the type
+	// binary small, or to compare the types regardless of their documentation.
+	OmitDocs bool
+}
+
+// docOmittingWriter marks the writer of the type which doc strings are omitted.
+// The nested types are written to the same writer, thus their doc strings are omitted as well.
+type docOmittingWriter struct {
+	io.Writer
+}
+
+// omitsDocs returns true if the doc strings are not written to w.
+func omitsDocs(w io.Writer) bool {
+	_, ok := w.(docOmittingWriter)
+	return ok
+}
+
+// WriteTypeWithOptions writes the binary representation of the Type to the writer, with given options.
+func WriteTypeWithOptions(w io.Writer, vt Type, options WriteTypeOptions) (int, error) {
+	if options.OmitDocs && !omitsDocs(w) {
+		w = docOmittingWriter{Writer: w}
+	}
+	return WriteType(w, vt)
+}
+
 // WriteType writes the type in binary representation to the writer.
 // Returns the number of bytes written.
 func WriteType(w io.Writer, vt Type) (int, error) {
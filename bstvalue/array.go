@@ -6,6 +6,7 @@ import (
 	"io"
 	"strings"
 
+	"github.com/devmodules/bst/bstcol"
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
 	"github.com/devmodules/bst/bstskip"
@@ -19,6 +20,8 @@ var _ Value = (*ArrayValue)(nil)
 type ArrayValue struct {
 	ArrayType *bsttype.Array
 	Values    []Value
+
+	cache marshalCache
 }
 
 // MustArrayValueOf returns an array value of the given type and values.
@@ -119,7 +122,20 @@ func (x *ArrayValue) Skip(br io.ReadSeeker, options bstio.ValueOptions) (int64,
 // UnmarshalValue unmarshals the value from the database format.
 // Implements the Value interface.
 func (x *ArrayValue) UnmarshalValue(data []byte, options bstio.ValueOptions) error {
+	x.cache.invalidate()
 	r := bytes.NewReader(data)
+	if x.runLength(options) {
+		_, err := x.readRunLength(r, options)
+		return err
+	}
+	if x.nullBitmap(options) {
+		_, err := x.readNullBitmap(r, options)
+		return err
+	}
+	if x.floatColumn(options) {
+		_, err := x.readFloatColumn(r)
+		return err
+	}
 	if x.ArrayType.Type.Kind() == bsttype.KindBoolean {
 		_, err := x.readBools(r, options)
 		return err
@@ -131,6 +147,16 @@ func (x *ArrayValue) UnmarshalValue(data []byte, options bstio.ValueOptions) err
 // ReadValue reads the value from the reader.
 // Implements the Value interface.
 func (x *ArrayValue) ReadValue(br io.Reader, options bstio.ValueOptions) (int, error) {
+	x.cache.invalidate()
+	if x.runLength(options) {
+		return x.readRunLength(br, options)
+	}
+	if x.nullBitmap(options) {
+		return x.readNullBitmap(br, options)
+	}
+	if x.floatColumn(options) {
+		return x.readFloatColumn(br)
+	}
 	if x.ArrayType.Type.Kind() == bsttype.KindBoolean {
 		return x.readBools(br, options)
 	}
@@ -140,9 +166,38 @@ func (x *ArrayValue) ReadValue(br io.Reader, options bstio.ValueOptions) (int, e
 // WriteValue writes the value to the writer.
 // Implements the Value interface.
 func (x *ArrayValue) WriteValue(w io.Writer, options bstio.ValueOptions) (int, error) {
+	return x.cache.write(w, options, x.writeValue)
+}
+
+// EnableMarshalCache enables memoization of the ArrayValue binaries by the value options, so that marshaling
+// the same value multiple times encodes it only once. The cache is invalidated by the ArrayValue methods
+// that modify the value, however any direct modification of the Values, or of the nested values,
+// needs to be followed by the InvalidateMarshalCache call.
+func (x *ArrayValue) EnableMarshalCache() {
+	x.cache.enable()
+}
+
+// InvalidateMarshalCache clears the memoized binaries of the value.
+func (x *ArrayValue) InvalidateMarshalCache() {
+	x.cache.invalidate()
+}
+
+func (x *ArrayValue) writeValue(w io.Writer, options bstio.ValueOptions) (int, error) {
+	if x.runLength(options) {
+		return x.writeRunLength(w, options)
+	}
+	if x.nullBitmap(options) {
+		return x.writeNullBitmap(w, options)
+	}
+	if x.floatColumn(options) {
+		return x.writeFloatColumn(w)
+	}
 	if x.ArrayType.Type.Kind() == bsttype.KindBoolean {
 		return x.writeBools(w, options)
 	}
+	if x.ArrayType.IsSet() {
+		return x.writeSet(w, options)
+	}
 	return x.write(w, options)
 }
 
@@ -171,6 +226,7 @@ func (x *ArrayValue) DropNthElem(n int) {
 		return
 	}
 	x.Values = append(x.Values[:n], x.Values[n+1:]...)
+	x.cache.invalidate()
 }
 
 // Append appends the value to the array.
@@ -182,6 +238,7 @@ func (x *ArrayValue) Append(sv Value) error {
 
 	// 2. Check if the value is of the same type.
 	x.Values = append(x.Values, sv)
+	x.cache.invalidate()
 	return nil
 }
 
@@ -191,7 +248,7 @@ func (x *ArrayValue) readArray(br io.Reader, options bstio.ValueOptions) (int, e
 	var bytesRead int
 	if !x.ArrayType.HasFixedSize() {
 		// 2. Read the length for variable length array.
-		luv, n, err := bstio.ReadUint(br, options.Descending)
+		luv, n, err := bstio.ReadLength(br, options.Descending, bsttype.MinEncodedSize(x.ArrayType.Elem()))
 		if err != nil {
 			return n, err
 		}
@@ -235,6 +292,11 @@ func (x *ArrayValue) readBools(br io.Reader, options bstio.ValueOptions) (int, e
 		}
 		bytesRead += n
 
+		// 2.1. The booleans are packed by 8 into a byte.
+		if err = bstio.CheckLength(br, (luv+7)>>3, 1); err != nil {
+			return bytesRead, err
+		}
+
 		// 3. Allocate the array values.
 		//	  If the array has a fixed size, the length is already allocated.
 		x.Values = make([]Value, luv)
@@ -290,6 +352,45 @@ func (x *ArrayValue) write(w io.Writer, options bstio.ValueOptions) (int, error)
 	return bytesWritten, nil
 }
 
+// writeSet writes the elements of the set sorted by their binaries, so that the sets of the same elements
+// have equal binaries. It fails if any of the elements is duplicated.
+func (x *ArrayValue) writeSet(w io.Writer, options bstio.ValueOptions) (int, error) {
+	// 1. Encode and sort the elements.
+	elems := make([][]byte, len(x.Values))
+	for i, v := range x.Values {
+		if v == nil {
+			v = EmptyValueOf(x.ArrayType.Elem())
+			x.Values[i] = v
+		}
+		var buf bytes.Buffer
+		if _, err := v.WriteValue(&buf, options); err != nil {
+			return 0, err
+		}
+		elems[i] = buf.Bytes()
+	}
+	if err := bstio.SortSetElements(elems); err != nil {
+		return 0, err
+	}
+
+	// 2. Write the length of the variable size set, followed by the sorted elements.
+	var bytesWritten int
+	if !x.ArrayType.HasFixedSize() {
+		n, err := bstio.WriteUint(w, uint(len(elems)), options.Descending)
+		if err != nil {
+			return n, err
+		}
+		bytesWritten += n
+	}
+	for _, elem := range elems {
+		n, err := w.Write(elem)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, err
+		}
+	}
+	return bytesWritten, nil
+}
+
 func (x *ArrayValue) writeBools(w io.Writer, options bstio.ValueOptions) (int, error) {
 	var (
 		bytesWritten     int
@@ -331,3 +432,276 @@ func (x *ArrayValue) writeBools(w io.Writer, options bstio.ValueOptions) (int, e
 	}
 	return bytesWritten, nil
 }
+
+// runLength returns true if the array elements are run length encoded with given options.
+func (x *ArrayValue) runLength(options bstio.ValueOptions) bool {
+	return x.ArrayType.Encoding == bsttype.ArrayEncodingRunLength && !options.Comparable
+}
+
+func (x *ArrayValue) readRunLength(br io.Reader, options bstio.ValueOptions) (int, error) {
+	// 1. Find the binary size of the run values.
+	size, err := x.ArrayType.RunLengthValueSize()
+	if err != nil {
+		return 0, err
+	}
+
+	// 2. Read the length of the variable size array.
+	//    The runs might take fewer bytes than the elements, thus the length is not checked against the input.
+	var bytesRead int
+	length := x.ArrayType.FixedSize
+	if !x.ArrayType.HasFixedSize() {
+		length, bytesRead, err = bstio.ReadLength(br, options.Descending, 0)
+		if err != nil {
+			return bytesRead, err
+		}
+	}
+
+	// 3. Read and expand the runs.
+	data, n, err := bstio.ReadRunLength(br, length, size, options.Descending)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, err
+	}
+
+	// 4. Unmarshal the elements.
+	x.Values = make([]Value, length)
+	for i := range x.Values {
+		ev := EmptyValueOf(x.ArrayType.Elem())
+		if ev == nil {
+			panic(fmt.Sprintf("unsupported array element type %v", x.ArrayType.Elem()))
+		}
+		if err = ev.UnmarshalValue(data[i*size:(i+1)*size], options); err != nil {
+			return bytesRead, err
+		}
+		x.Values[i] = ev
+	}
+	return bytesRead, nil
+}
+
+func (x *ArrayValue) writeRunLength(w io.Writer, options bstio.ValueOptions) (int, error) {
+	// 1. Find the binary size of the run values.
+	size, err := x.ArrayType.RunLengthValueSize()
+	if err != nil {
+		return 0, err
+	}
+
+	// 2. Marshal the elements one after another.
+	data := make([]byte, 0, len(x.Values)*size)
+	for i, v := range x.Values {
+		if v == nil {
+			v = EmptyValueOf(x.ArrayType.Elem())
+			x.Values[i] = v
+		}
+		ev, err := v.MarshalValue(options)
+		if err != nil {
+			return 0, err
+		}
+		if len(ev) != size {
+			return 0, bsterr.Err(bsterr.CodeEncodingBinaryValue, "invalid run length array element size").
+				WithDetails(bsterr.D("expected", size), bsterr.D("actual", len(ev)))
+		}
+		data = append(data, ev...)
+	}
+
+	// 3. Write the length of the variable size array.
+	var bytesWritten int
+	if !x.ArrayType.HasFixedSize() {
+		bytesWritten, err = bstio.WriteUint(w, uint(len(x.Values)), options.Descending)
+		if err != nil {
+			return bytesWritten, err
+		}
+	}
+
+	// 4. Write the runs of the elements.
+	n, err := bstio.WriteRunLength(w, data, size, options.Descending)
+	return bytesWritten + n, err
+}
+
+// floatColumn returns true if the array elements are encoded as the float array column with given options.
+func (x *ArrayValue) floatColumn(options bstio.ValueOptions) bool {
+	return x.ArrayType.Encoding == bsttype.ArrayEncodingFloatColumn && !options.Comparable
+}
+
+func (x *ArrayValue) readFloatColumn(br io.Reader) (int, error) {
+	width, err := x.ArrayType.FloatColumnWidth()
+	if err != nil {
+		return 0, err
+	}
+
+	// 1. Read the column binary.
+	data, bytesRead, err := bstcol.ReadColumn(br)
+	if err != nil {
+		return bytesRead, err
+	}
+
+	// 2. Decode the column values, of the width of the array elements.
+	var values []Value
+	if width == 8 {
+		floats, err := bstcol.DecodeFloat64Array(nil, data)
+		if err != nil {
+			return bytesRead, err
+		}
+		values = make([]Value, len(floats))
+		for i, v := range floats {
+			values[i] = NewFloat64Value(v)
+		}
+	} else {
+		floats, err := bstcol.DecodeFloat32Array(nil, data)
+		if err != nil {
+			return bytesRead, err
+		}
+		values = make([]Value, len(floats))
+		for i, v := range floats {
+			values[i] = NewFloat32Value(v)
+		}
+	}
+
+	// 3. The fixed size array needs to have all its elements.
+	if x.ArrayType.HasFixedSize() && uint(len(values)) != x.ArrayType.FixedSize {
+		return bytesRead, bsterr.Err(bsterr.CodeMalformedBinary, "float column array length doesn't match its fixed size").
+			WithDetails(bsterr.D("length", len(values)), bsterr.D("fixedSize", x.ArrayType.FixedSize))
+	}
+	x.Values = values
+	return bytesRead, nil
+}
+
+func (x *ArrayValue) writeFloatColumn(w io.Writer) (int, error) {
+	width, err := x.ArrayType.FloatColumnWidth()
+	if err != nil {
+		return 0, err
+	}
+
+	// 1. Collect the element values, the missing ones are zero.
+	var data []byte
+	if width == 8 {
+		floats := make([]float64, len(x.Values))
+		for i, v := range x.Values {
+			if fv, ok := v.(*Float64Value); ok {
+				floats[i] = fv.Value
+			} else if v != nil {
+				return 0, bsterr.Err(bsterr.CodeTypeConstraintViolation, "array element is not a float64")
+			}
+		}
+		data = bstcol.AppendFloat64Array(nil, floats, bstcol.ForCompression())
+	} else {
+		floats := make([]float32, len(x.Values))
+		for i, v := range x.Values {
+			if fv, ok := v.(*Float32Value); ok {
+				floats[i] = fv.Value
+			} else if v != nil {
+				return 0, bsterr.Err(bsterr.CodeTypeConstraintViolation, "array element is not a float32")
+			}
+		}
+		data = bstcol.AppendFloat32Array(nil, floats, bstcol.ForCompression())
+	}
+
+	// 2. Write the column binary, whose transforms are the ones making it the most compressible.
+	n, err := w.Write(data)
+	if err != nil {
+		return n, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write float column array")
+	}
+	return n, nil
+}
+
+// nullBitmap returns true if the null flags of the array elements are encoded as a bitmap with given options.
+func (x *ArrayValue) nullBitmap(options bstio.ValueOptions) bool {
+	return x.ArrayType.Encoding == bsttype.ArrayEncodingNullBitmap && !options.Comparable
+}
+
+func (x *ArrayValue) readNullBitmap(br io.Reader, options bstio.ValueOptions) (int, error) {
+	if err := x.ArrayType.VerifyEncoding(); err != nil {
+		return 0, err
+	}
+
+	// 1. Read the length of the variable size array.
+	var (
+		bytesRead int
+		err       error
+	)
+	length := x.ArrayType.FixedSize
+	if !x.ArrayType.HasFixedSize() {
+		length, bytesRead, err = bstio.ReadLength(br, options.Descending, 0)
+		if err != nil {
+			return bytesRead, err
+		}
+	}
+
+	// 2. Read the null bitmap.
+	size := (length + 7) >> 3
+	if err = bstio.CheckLength(br, size, 1); err != nil {
+		return bytesRead, err
+	}
+	bm, n, err := bstio.ReadFixedSizeBytes(br, int(size), false)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, err
+	}
+
+	// 3. Read the not null values.
+	x.Values = make([]Value, length)
+	for i := range x.Values {
+		ev := EmptyValueOf(x.ArrayType.Elem())
+		nv, ok := ev.(*NullableValue)
+		if !ok {
+			panic(fmt.Sprintf("unsupported null bitmap array element type %v", x.ArrayType.Elem()))
+		}
+		if nv.NullableType == nil {
+			nv.NullableType, _ = x.ArrayType.Elem().(*bsttype.Nullable)
+		}
+		x.Values[i] = nv
+		if bm[i>>3]&(1<<(i&7)) != 0 {
+			continue
+		}
+		nv.IsNull = false
+		n, err = nv.Value.ReadValue(br, options)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, err
+		}
+	}
+	return bytesRead, nil
+}
+
+func (x *ArrayValue) writeNullBitmap(w io.Writer, options bstio.ValueOptions) (int, error) {
+	if err := x.ArrayType.VerifyEncoding(); err != nil {
+		return 0, err
+	}
+
+	// 1. Write the length of the variable size array.
+	var (
+		bytesWritten int
+		err          error
+	)
+	if !x.ArrayType.HasFixedSize() {
+		bytesWritten, err = bstio.WriteUint(w, uint(len(x.Values)), options.Descending)
+		if err != nil {
+			return bytesWritten, err
+		}
+	}
+
+	// 2. Write the null bitmap.
+	bm := make([]byte, (len(x.Values)+7)>>3)
+	for i, v := range x.Values {
+		if nv, ok := v.(*NullableValue); !ok || nv.IsNull {
+			bm[i>>3] |= 1 << (i & 7)
+		}
+	}
+	n, err := w.Write(bm)
+	bytesWritten += n
+	if err != nil {
+		return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write null bitmap")
+	}
+
+	// 3. Write the not null values.
+	for i, v := range x.Values {
+		if bm[i>>3]&(1<<(i&7)) != 0 {
+			continue
+		}
+		n, err = v.(*NullableValue).Value.WriteValue(w, options)
+		bytesWritten += n
+		if err != nil {
+			return bytesWritten, err
+		}
+	}
+	return bytesWritten, nil
+}
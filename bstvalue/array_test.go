@@ -112,6 +112,25 @@ func TestArrayValue_ReadValue(t *testing.T) {
 	}
 }
 
+func TestArrayValue_ReadValue_Length(t *testing.T) {
+	// The array length header exceeding the input must fail before allocating the values.
+	var buf bytes.Buffer
+	if _, err := bstio.WriteUint(&buf, 1<<40, false); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	buf.Write([]byte{1, 2, 3})
+
+	for _, at := range []*bsttype.Array{bsttype.ArrayOf(bsttype.Int32()), bsttype.ArrayOf(bsttype.Boolean())} {
+		av := &ArrayValue{ArrayType: at}
+		if _, err := av.ReadValue(bytes.NewReader(buf.Bytes()), bstio.ValueOptions{}); err == nil {
+			t.Fatalf("expected length error for %v", at)
+		}
+		if len(av.Values) != 0 {
+			t.Fatalf("values allocated for %v: %d", at, len(av.Values))
+		}
+	}
+}
+
 func TestArrayValue_WriteValue(t *testing.T) {
 	for _, tc := range arrayTestCases {
 		t.Run(tc.Name, func(t *testing.T) {
@@ -190,3 +209,163 @@ func TestArrayValue_Skip(t *testing.T) {
 		})
 	}
 }
+
+func TestArrayValue_MarshalCache(t *testing.T) {
+	av := &ArrayValue{ArrayType: bsttype.ArrayOf(bsttype.String())}
+	av.EnableMarshalCache()
+	if err := av.Append(NewStringValue("a")); err != nil {
+		t.Fatalf("appending value failed: %v", err)
+	}
+
+	asc, err := av.MarshalValue(bstio.ValueOptions{})
+	if err != nil {
+		t.Fatalf("marshaling value failed: %v", err)
+	}
+	desc, err := av.MarshalValue(bstio.ValueOptions{Descending: true})
+	if err != nil {
+		t.Fatalf("marshaling value failed: %v", err)
+	}
+	if bytes.Equal(asc, desc) {
+		t.Fatal("binaries of different options are equal")
+	}
+
+	// A direct modification is not detected until the cache is invalidated.
+	av.Values[0] = NewStringValue("b")
+	cached, err := av.MarshalValue(bstio.ValueOptions{})
+	if err != nil {
+		t.Fatalf("marshaling value failed: %v", err)
+	}
+	if !bytes.Equal(cached, asc) {
+		t.Fatalf("unexpected cached binary: %v, expected: %v", cached, asc)
+	}
+
+	av.InvalidateMarshalCache()
+	modified, err := av.MarshalValue(bstio.ValueOptions{})
+	if err != nil {
+		t.Fatalf("marshaling value failed: %v", err)
+	}
+	if bytes.Equal(modified, asc) {
+		t.Fatal("invalidated cache returned stale binary")
+	}
+
+	// Append invalidates the cache.
+	if err = av.Append(NewStringValue("c")); err != nil {
+		t.Fatalf("appending value failed: %v", err)
+	}
+	appended, err := av.MarshalValue(bstio.ValueOptions{})
+	if err != nil {
+		t.Fatalf("marshaling value failed: %v", err)
+	}
+	if bytes.Equal(appended, modified) {
+		t.Fatal("append did not invalidate the cache")
+	}
+}
+
+func TestArrayValue_RunLength(t *testing.T) {
+	enum := &bsttype.Enum{ValueBytes: 1, Elements: []bsttype.EnumElement{{String: "Off", Index: 0}, {String: "On", Index: 1}}}
+	testCases := []struct {
+		Name   string
+		Type   *bsttype.Array
+		Values []Value
+		Binary []byte
+	}{
+		{
+			Name:   "Bool/VarSize",
+			Type:   &bsttype.Array{Type: bsttype.Boolean(), Encoding: bsttype.ArrayEncodingRunLength},
+			Values: []Value{&BoolValue{}, &BoolValue{}, &BoolValue{}, &BoolValue{Value: true}},
+			Binary: []byte{
+				// Size of the array.
+				bstio.BinarySizeUint8, 0x04,
+				// Runs of 3 false and 1 true values.
+				bstio.BinarySizeUint8, 0x03, 0x00,
+				bstio.BinarySizeUint8, 0x01, 0x01,
+			},
+		},
+		{
+			Name: "Enum/FixedSize",
+			Type: &bsttype.Array{Type: enum, FixedSize: 3, Encoding: bsttype.ArrayEncodingRunLength},
+			Values: []Value{
+				MustNewEnumValue(enum, 1), MustNewEnumValue(enum, 1), MustNewEnumValue(enum, 1),
+			},
+			Binary: []byte{
+				// Single run of 3 values.
+				bstio.BinarySizeUint8, 0x03, 0x01,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			av := MustArrayValueOf(tc.Type, tc.Values)
+			data, err := av.MarshalValue(bstio.ValueOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, tc.Binary) {
+				t.Fatalf("expected %v, but got %v", tc.Binary, data)
+			}
+
+			got := EmptyArrayValue(tc.Type)
+			n, err := got.ReadValue(bytes.NewReader(data), bstio.ValueOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(data) {
+				t.Fatalf("expected to read %d bytes, but read %d", len(data), n)
+			}
+			if got.String() != av.String() {
+				t.Fatalf("expected %s, but got %s", av, got)
+			}
+
+			skipped, err := got.Skip(bytes.NewReader(data), bstio.ValueOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if int(skipped) != len(data) {
+				t.Fatalf("expected to skip %d bytes, but skipped %d", len(data), skipped)
+			}
+		})
+	}
+}
+
+func TestArrayValue_NullBitmap(t *testing.T) {
+	nt := bsttype.NullableOf(bsttype.Int8())
+	at := &bsttype.Array{Type: nt, Encoding: bsttype.ArrayEncodingNullBitmap}
+	av := MustArrayValueOf(at, []Value{
+		NullValueOf(nt),
+		&NullableValue{NullableType: nt, Value: NewInt8Value(3)},
+		NullValueOf(nt),
+	})
+
+	data, err := av.MarshalValue(bstio.ValueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		// Size of the array.
+		bstio.BinarySizeUint8, 0x03,
+		// Null bitmap.
+		0b00000101,
+		// Not null value.
+		0x83,
+	}
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("expected %v, but got %v", expected, data)
+	}
+
+	got := EmptyArrayValue(at)
+	if err = got.UnmarshalValue(data, bstio.ValueOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != av.String() {
+		t.Fatalf("expected %s, but got %s", av, got)
+	}
+
+	skipped, err := got.Skip(bytes.NewReader(data), bstio.ValueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(skipped) != len(data) {
+		t.Fatalf("expected to skip %d bytes, but skipped %d", len(data), skipped)
+	}
+}
@@ -0,0 +1,85 @@
+package bstvalue
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// Compile-time check to ensure that BitmapValue implements the Value interface.
+var _ Value = (*BitmapValue)(nil)
+
+// BitmapValue is the value descriptor for the packed bit vector.
+type BitmapValue struct {
+	Value bstio.Bitmap
+}
+
+// NewBitmapValue returns a new BitmapValue.
+func NewBitmapValue(b bstio.Bitmap) *BitmapValue {
+	return &BitmapValue{Value: b}
+}
+
+func emptyBitmapValue(_ bsttype.Type) Value {
+	return &BitmapValue{}
+}
+
+// String returns a human-readable representation of the BitmapValue.
+func (x BitmapValue) String() string {
+	return fmt.Sprintf("Bitmap(%d/%d)", x.Value.Count(), x.Value.Len)
+}
+
+// Type returns the type of the value.
+// Implements the Value interface.
+func (*BitmapValue) Type() bsttype.Type {
+	return bsttype.Bitmap()
+}
+
+// Kind returns the basic kind of the value.
+// Implements the Value interface.
+func (*BitmapValue) Kind() bsttype.Kind {
+	return bsttype.KindBitmap
+}
+
+// Skip the bytes in the reader to the next value.
+// Implements the Value interface.
+func (*BitmapValue) Skip(rs io.ReadSeeker, o bstio.ValueOptions) (int64, error) {
+	return bstio.SkipBitmap(rs, o.Descending, o.Comparable)
+}
+
+// MarshalValue writes the value to the byte slice.
+// Implements the Value interface.
+func (x *BitmapValue) MarshalValue(o bstio.ValueOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := x.WriteValue(&buf, o); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalValue reads the value from the byte slice.
+// Implements the Value interface.
+func (x *BitmapValue) UnmarshalValue(in []byte, o bstio.ValueOptions) error {
+	_, err := x.ReadValue(bytes.NewReader(in), o)
+	return err
+}
+
+// ReadValue reads the value from the reader.
+// Implements the Value interface.
+func (x *BitmapValue) ReadValue(r io.Reader, o bstio.ValueOptions) (int, error) {
+	b, n, err := bstio.ReadBitmap(r, o.Descending, o.Comparable)
+	if err != nil {
+		return n, err
+	}
+
+	x.Value = b
+	return n, nil
+}
+
+// WriteValue writes the value to the writer.
+// Implements the Value interface.
+func (x *BitmapValue) WriteValue(w io.Writer, o bstio.ValueOptions) (int, error) {
+	return bstio.WriteBitmap(w, x.Value, o.Descending, o.Comparable)
+}
@@ -0,0 +1,80 @@
+package bstvalue
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// Compile-time check to ensure that DecimalValue implements the Value interface.
+var _ Value = (*DecimalValue)(nil)
+
+// DecimalValue is the value descriptor for the arbitrary precision decimal.
+type DecimalValue struct {
+	Value bstio.Decimal
+}
+
+// NewDecimalValue returns a new DecimalValue.
+func NewDecimalValue(d bstio.Decimal) *DecimalValue {
+	return &DecimalValue{Value: d}
+}
+
+func emptyDecimalValue(_ bsttype.Type) Value {
+	return &DecimalValue{}
+}
+
+// String returns a human-readable representation of the DecimalValue.
+func (x DecimalValue) String() string {
+	return "Decimal(" + x.Value.String() + ")"
+}
+
+// Type returns the type of the value.
+// Implements the Value interface.
+func (*DecimalValue) Type() bsttype.Type {
+	return bsttype.Decimal()
+}
+
+// Kind returns the basic kind of the value.
+// Implements the Value interface.
+func (*DecimalValue) Kind() bsttype.Kind {
+	return bsttype.KindDecimal
+}
+
+// Skip the bytes in the reader to the next value.
+// Implements the Value interface.
+func (*DecimalValue) Skip(rs io.ReadSeeker, o bstio.ValueOptions) (int64, error) {
+	return bstio.SkipDecimal(rs, o.Descending)
+}
+
+// MarshalValue writes the value to the byte slice.
+// Implements the Value interface.
+func (x *DecimalValue) MarshalValue(o bstio.ValueOptions) ([]byte, error) {
+	return bstio.AppendDecimal(nil, x.Value, o.Descending)
+}
+
+// UnmarshalValue reads the value from the byte slice.
+// Implements the Value interface.
+func (x *DecimalValue) UnmarshalValue(in []byte, o bstio.ValueOptions) error {
+	_, err := x.ReadValue(bytes.NewReader(in), o)
+	return err
+}
+
+// ReadValue reads the value from the reader.
+// Implements the Value interface.
+func (x *DecimalValue) ReadValue(r io.Reader, o bstio.ValueOptions) (int, error) {
+	d, n, err := bstio.ReadDecimal(r, o.Descending)
+	if err != nil {
+		return n, err
+	}
+
+	x.Value = d
+	return n, nil
+}
+
+// WriteValue writes the value to the writer.
+// Implements the Value interface.
+func (x *DecimalValue) WriteValue(w io.Writer, o bstio.ValueOptions) (int, error) {
+	return bstio.WriteDecimal(w, x.Value, o.Descending)
+}
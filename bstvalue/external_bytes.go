@@ -0,0 +1,82 @@
+package bstvalue
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// Compile-time check to ensure that ExternalBytesValue implements the Value interface.
+var _ Value = (*ExternalBytesValue)(nil)
+
+// ExternalBytesValue is the value descriptor for the bytes stored out of line.
+// It holds only the reference of the blob, which is resolved by the blob store of the extractor.
+type ExternalBytesValue struct {
+	Ref bstio.BlobRef
+}
+
+// NewExternalBytesValue returns a new ExternalBytesValue.
+func NewExternalBytesValue(ref bstio.BlobRef) *ExternalBytesValue {
+	return &ExternalBytesValue{Ref: ref}
+}
+
+func emptyExternalBytesValue(_ bsttype.Type) Value {
+	return &ExternalBytesValue{}
+}
+
+// String returns a human-readable description of the ExternalBytesValue.
+func (x ExternalBytesValue) String() string {
+	return fmt.Sprintf("ExternalBytes(%s)", x.Ref)
+}
+
+// Type returns the type of the value.
+// Implements the Value interface.
+func (*ExternalBytesValue) Type() bsttype.Type {
+	return bsttype.ExternalBytes()
+}
+
+// Kind returns the basic kind of the value.
+// Implements the Value interface.
+func (*ExternalBytesValue) Kind() bsttype.Kind {
+	return bsttype.KindExternalBytes
+}
+
+// Skip the bytes in the reader to the next value.
+// Implements the Value interface.
+func (*ExternalBytesValue) Skip(rs io.ReadSeeker, _ bstio.ValueOptions) (int64, error) {
+	return bstio.SkipBlobRef(rs)
+}
+
+// MarshalValue writes the value to the byte slice.
+// Implements the Value interface.
+func (x *ExternalBytesValue) MarshalValue(o bstio.ValueOptions) ([]byte, error) {
+	return bstio.MarshalBlobRef(x.Ref, o.Descending), nil
+}
+
+// UnmarshalValue reads the value from the byte slice.
+// Implements the Value interface.
+func (x *ExternalBytesValue) UnmarshalValue(in []byte, o bstio.ValueOptions) error {
+	_, err := x.ReadValue(bytes.NewReader(in), o)
+	return err
+}
+
+// ReadValue reads the value from the byte slice.
+// Implements the Value interface.
+func (x *ExternalBytesValue) ReadValue(r io.Reader, o bstio.ValueOptions) (int, error) {
+	ref, n, err := bstio.ReadBlobRef(r, o.Descending)
+	if err != nil {
+		return n, err
+	}
+
+	x.Ref = ref
+	return n, nil
+}
+
+// WriteValue writes the value to the byte slice.
+// Implements the Value interface.
+func (x *ExternalBytesValue) WriteValue(w io.Writer, o bstio.ValueOptions) (int, error) {
+	return bstio.WriteBlobRef(w, x.Ref, o.Descending)
+}
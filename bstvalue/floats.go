@@ -67,6 +67,9 @@ func (x *Float32Value) Skip(rs io.ReadSeeker, _ bstio.ValueOptions) (int64, erro
 // MarshalValue writes the value to the byte slice.
 // Implements the Value interface.
 func (x *Float32Value) MarshalValue(o bstio.ValueOptions) ([]byte, error) {
+	if o.OrderedFloats {
+		return bstio.AppendOrderedFloat32(make([]byte, 0, 4), x.Value, o.Descending), nil
+	}
 	return bstio.MarshalFloat32(x.Value, o.Descending), nil
 }
 
@@ -81,7 +84,11 @@ func (x *Float32Value) UnmarshalValue(in []byte, o bstio.ValueOptions) error {
 			)
 	}
 
-	fv, err := bstio.ParseFloat32(in, o.Descending)
+	parse := bstio.ParseFloat32
+	if o.OrderedFloats {
+		parse = bstio.ParseOrderedFloat32
+	}
+	fv, err := parse(in, o.Descending)
 	if err != nil {
 		return err
 	}
@@ -92,7 +99,11 @@ func (x *Float32Value) UnmarshalValue(in []byte, o bstio.ValueOptions) error {
 // ReadValue reads the value from the byte slice.
 // Implements the Value interface.
 func (x *Float32Value) ReadValue(r io.Reader, o bstio.ValueOptions) (int, error) {
-	v, n, err := bstio.ReadFloat32(r, o.Descending)
+	read := bstio.ReadFloat32
+	if o.OrderedFloats {
+		read = bstio.ReadOrderedFloat32
+	}
+	v, n, err := read(r, o.Descending)
 	if err != nil {
 		return 0, err
 	}
@@ -103,7 +114,7 @@ func (x *Float32Value) ReadValue(r io.Reader, o bstio.ValueOptions) (int, error)
 // WriteValue writes the value to the byte slice.
 // Implements the Value interface.
 func (x *Float32Value) WriteValue(w io.Writer, o bstio.ValueOptions) (int, error) {
-	v := bstio.MarshalFloat32(x.Value, o.Descending)
+	v, _ := x.MarshalValue(o)
 	n, err := w.Write(v)
 	if err != nil {
 		return n, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write float value")
@@ -154,6 +165,9 @@ func (x *Float64Value) Skip(rs io.ReadSeeker, _ bstio.ValueOptions) (int64, erro
 // MarshalValue writes the value to the byte slice.
 // Implements the Value interface.
 func (x *Float64Value) MarshalValue(o bstio.ValueOptions) ([]byte, error) {
+	if o.OrderedFloats {
+		return bstio.AppendOrderedFloat64(make([]byte, 0, 8), x.Value, o.Descending), nil
+	}
 	return bstio.MarshalFloat64(x.Value, o.Descending), nil
 }
 
@@ -168,7 +182,11 @@ func (x *Float64Value) UnmarshalValue(in []byte, o bstio.ValueOptions) error {
 			)
 	}
 
-	fv, err := bstio.ParseFloat64(in, o.Descending)
+	parse := bstio.ParseFloat64
+	if o.OrderedFloats {
+		parse = bstio.ParseOrderedFloat64
+	}
+	fv, err := parse(in, o.Descending)
 	if err != nil {
 		return err
 	}
@@ -179,7 +197,11 @@ func (x *Float64Value) UnmarshalValue(in []byte, o bstio.ValueOptions) error {
 // ReadValue reads the value from the byte slice.
 // Implements the Value interface.
 func (x *Float64Value) ReadValue(r io.Reader, o bstio.ValueOptions) (int, error) {
-	v, n, err := bstio.ReadFloat64(r, o.Descending)
+	read := bstio.ReadFloat64
+	if o.OrderedFloats {
+		read = bstio.ReadOrderedFloat64
+	}
+	v, n, err := read(r, o.Descending)
 	if err != nil {
 		return n, err
 	}
@@ -190,7 +212,7 @@ func (x *Float64Value) ReadValue(r io.Reader, o bstio.ValueOptions) (int, error)
 // WriteValue writes the value to the byte slice.
 // Implements the Value interface.
 func (x *Float64Value) WriteValue(w io.Writer, o bstio.ValueOptions) (int, error) {
-	v := bstio.MarshalFloat64(x.Value, o.Descending)
+	v, _ := x.MarshalValue(o)
 	n, err := w.Write(v)
 	if err != nil {
 		return n, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write float value")
@@ -21,6 +21,8 @@ type (
 	MapValue struct {
 		MapType *bsttype.Map
 		btree   *btree.BTree
+
+		cache marshalCache
 	}
 
 	// MapValueKV is the key value pair for a map value.
@@ -122,6 +124,7 @@ func (x *MapValue) Put(key, value Value) error {
 		Value:   value,
 	}
 	x.btree.ReplaceOrInsert(i)
+	x.cache.invalidate()
 
 	return nil
 }
@@ -162,6 +165,9 @@ func (x *MapValue) Delete(key Value) (bool, error) {
 
 	k := &mapValueKV{kb: data}
 	v := x.btree.Delete(k)
+	if v != nil {
+		x.cache.invalidate()
+	}
 	return v != nil, nil
 }
 
@@ -287,14 +293,27 @@ func (x *MapValue) UnmarshalValue(in []byte, options bstio.ValueOptions) error {
 // ReadValue reads the value from the reader.
 // Implements the Value interface.
 func (x *MapValue) ReadValue(r io.Reader, options bstio.ValueOptions) (int, error) {
+	x.cache.invalidate()
+
 	// 1. Read the number of entries.
-	length, lt, err := bstio.ReadUint(r, options.Descending)
+	minEntrySize := bsttype.MinEncodedSize(x.MapType.Key.Type) + bsttype.MinEncodedSize(x.MapType.Value.Type)
+	length, lt, err := bstio.ReadLength(r, options.Descending, minEntrySize)
 	if err != nil {
 		return lt, err
 	}
 
 	bytesRead := lt
 
+	// 1.1. The entries of the indexed map are preceded by their binary size.
+	indexed := x.MapType.Indexed && !options.Comparable
+	if indexed {
+		_, n, err := bstio.ReadUint(r, options.Descending)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read map entries size")
+		}
+	}
+
 	x.btree = btree.New(2)
 
 	// 2. Prepare the key reader and key, value options.
@@ -343,12 +362,42 @@ func (x *MapValue) ReadValue(r io.Reader, options bstio.ValueOptions) (int, erro
 
 		x.btree.ReplaceOrInsert(item)
 	}
+
+	// 3. Skip the directory of the indexed map.
+	if indexed {
+		n, err := io.CopyN(io.Discard, r, int64(length)*bstio.MapDirectoryEntrySize)
+		bytesRead += int(n)
+		if err != nil {
+			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read map directory")
+		}
+	}
 	return bytesRead, nil
 }
 
 // WriteValue writes the value to the writer.
 // Implements the Value interface.
 func (x *MapValue) WriteValue(w io.Writer, options bstio.ValueOptions) (int, error) {
+	return x.cache.write(w, options, x.writeValue)
+}
+
+// EnableMarshalCache enables memoization of the MapValue binaries by the value options, so that marshaling
+// the same value multiple times encodes it only once. The cache is invalidated by the MapValue methods
+// that modify the value, however any modification of the nested values needs to be followed
+// by the InvalidateMarshalCache call.
+func (x *MapValue) EnableMarshalCache() {
+	x.cache.enable()
+}
+
+// InvalidateMarshalCache clears the memoized binaries of the value.
+func (x *MapValue) InvalidateMarshalCache() {
+	x.cache.invalidate()
+}
+
+func (x *MapValue) writeValue(w io.Writer, options bstio.ValueOptions) (int, error) {
+	if x.MapType.Indexed && !options.Comparable {
+		return x.writeIndexedValue(w, options)
+	}
+
 	// 1. Write the number of entries.
 	total, err := bstio.WriteUint(w, uint(x.btree.Len()), options.Descending)
 	if err != nil {
@@ -382,6 +431,57 @@ func (x *MapValue) WriteValue(w io.Writer, options bstio.ValueOptions) (int, err
 	return total, nil
 }
 
+// writeIndexedValue writes the indexed map, whose entries are preceded by their binary size and followed by
+// the directory of their keys.
+func (x *MapValue) writeIndexedValue(w io.Writer, options bstio.ValueOptions) (int, error) {
+	// 1. Encode the entries along with their directory.
+	var (
+		buf bytes.Buffer
+		dir = make([]bstio.MapDirectoryEntry, 0, x.btree.Len())
+		err error
+	)
+	x.btree.Ascend(func(i btree.Item) bool {
+		kv := i.(*mapValueKV)
+		offset := buf.Len()
+		if _, err = kv.Key.WriteValue(&buf, options); err != nil {
+			err = bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write map key")
+			return false
+		}
+		var e bstio.MapDirectoryEntry
+		if e, err = bstio.NewMapDirectoryEntry(buf.Bytes()[offset:], offset); err != nil {
+			return false
+		}
+		dir = append(dir, e)
+		if _, err = kv.Value.WriteValue(&buf, options); err != nil {
+			err = bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write map value")
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// 2. Write the number of entries and their binary size.
+	total, err := bstio.WriteUint(w, uint(x.btree.Len()), options.Descending)
+	if err != nil {
+		return total, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write map length")
+	}
+	n, err := bstio.WriteUint(w, uint(buf.Len()), options.Descending)
+	total += n
+	if err != nil {
+		return total, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write map entries size")
+	}
+
+	// 3. Write the entries followed by the directory.
+	n, err = w.Write(bstio.AppendMapDirectory(buf.Bytes(), dir))
+	total += n
+	if err != nil {
+		return total, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write map entries")
+	}
+	return total, nil
+}
+
 // Len returns the number of entries in the map.
 func (x *MapValue) Len() int {
 	return x.btree.Len()
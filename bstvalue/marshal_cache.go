@@ -0,0 +1,55 @@
+package bstvalue
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+)
+
+// marshalCache memoizes the binaries of the composite value, by the value options.
+// It is disabled by default, as it keeps the binaries in the memory as long as the value lives.
+type marshalCache struct {
+	enabled bool
+	entries map[bstio.ValueOptions][]byte
+}
+
+func (x *marshalCache) enable() {
+	x.enabled = true
+}
+
+func (x *marshalCache) invalidate() {
+	for o := range x.entries {
+		delete(x.entries, o)
+	}
+}
+
+// write writes the cached binary for given options, or the one written by the fn, which is then cached.
+func (x *marshalCache) write(w io.Writer, options bstio.ValueOptions, fn func(io.Writer, bstio.ValueOptions) (int, error)) (int, error) {
+	// 1. Without the cache enabled write the value directly.
+	if !x.enabled {
+		return fn(w, options)
+	}
+
+	// 2. Write the binary cached for given options.
+	data, ok := x.entries[options]
+	if !ok {
+		// 2.1. Encode the value and store its binary.
+		var buf bytes.Buffer
+		if _, err := fn(&buf, options); err != nil {
+			return 0, err
+		}
+		data = buf.Bytes()
+		if x.entries == nil {
+			x.entries = make(map[bstio.ValueOptions][]byte)
+		}
+		x.entries[options] = data
+	}
+
+	n, err := w.Write(data)
+	if err != nil {
+		return n, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write cached value binary")
+	}
+	return n, nil
+}
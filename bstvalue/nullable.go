@@ -94,7 +94,7 @@ func (x *NullableValue) Skip(rs io.ReadSeeker, options bstio.ValueOptions) (int6
 	// 2. Read the null flag.
 	nf, err := bstio.ReadByte(rs)
 	if err != nil {
-		return 0, bsterr.Err(bsterr.CodeDecodingBinaryValue, "failed to read null flag")
+		return 0, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read null flag")
 	}
 
 	if options.Descending {
@@ -109,7 +109,7 @@ func (x *NullableValue) Skip(rs io.ReadSeeker, options bstio.ValueOptions) (int6
 	// 4. Otherwise, skip the value.
 	skipped, err := x.Elem().Skip(rs, options)
 	if err != nil {
-		return skipped + 1, bsterr.Err(bsterr.CodeDecodingBinaryValue, "failed to skip value")
+		return skipped + 1, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to skip value")
 	}
 	return skipped + 1, nil
 }
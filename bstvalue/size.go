@@ -0,0 +1,45 @@
+package bstvalue
+
+import (
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// ComputeBinarySize returns the binary size of the value of given type, encoded with the options.
+// It is meant for the capacity planning of the buffers, and the length prefixes written ahead of the values.
+// The size of the fixed size types is known upfront, while the other values are counted while written,
+// without being buffered.
+func ComputeBinarySize(t bsttype.Type, v Value, options bstio.ValueOptions) (int, error) {
+	// 1. Verify that the value is of given type.
+	if !bsttype.TypesEqual(t, v.Type()) {
+		return 0, bsterr.Err(bsterr.CodeMismatchingValueType, "value type mismatch").
+			WithDetails(
+				bsterr.D("expected", t),
+				bsterr.D("actual", v.Type()),
+			)
+	}
+
+	// 2. The fixed size values need not be written, apart from the booleans, which are packed only within
+	//    the composites.
+	if size, ok := bsttype.FixedEncodedSize(t); ok && v.Kind() != bsttype.KindBoolean {
+		return size, nil
+	}
+
+	// 3. Count the bytes of the written value.
+	var sc sizeCounter
+	if _, err := v.WriteValue(&sc, options); err != nil {
+		return 0, err
+	}
+	return int(sc), nil
+}
+
+// sizeCounter is the writer counting the bytes written, which are discarded.
+type sizeCounter int
+
+// Write counts the bytes of p.
+// Implements io.Writer interface.
+func (x *sizeCounter) Write(p []byte) (int, error) {
+	*x += sizeCounter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,54 @@
+package bstvalue
+
+import (
+	"testing"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestComputeBinarySize(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "name", Type: bsttype.String()},
+		{Index: 2, Name: "active", Type: bsttype.Boolean()},
+		{Index: 3, Name: "tags", Type: bsttype.ArrayOf(bsttype.String())},
+	}}
+	tags, err := ArrayValueOf(bsttype.ArrayOf(bsttype.String()), []Value{NewStringValue("a"), NewStringValue("bc")})
+	if err != nil {
+		t.Fatalf("creating array value failed: %v", err)
+	}
+	testCases := []struct {
+		name string
+		t    bsttype.Type
+		v    Value
+	}{
+		{name: "Uint32", t: bsttype.Uint32(), v: NewUint32Value(42)},
+		{name: "Boolean", t: bsttype.Boolean(), v: NewBoolValue(true)},
+		{name: "String", t: bsttype.String(), v: NewStringValue("sample")},
+		{name: "Array", t: tags.ArrayType, v: tags},
+		{name: "Struct", t: st, v: MustNewStructValue(st, []Value{NewStringValue("name"), NewBoolValue(true), tags})},
+	}
+	for _, tc := range testCases {
+		for _, opts := range []bstio.ValueOptions{{}, {Descending: true}, {Comparable: true}} {
+			t.Run(tc.name, func(t *testing.T) {
+				size, err := ComputeBinarySize(tc.t, tc.v, opts)
+				if err != nil {
+					t.Fatalf("computing binary size failed: %v", err)
+				}
+				bin, err := tc.v.MarshalValue(opts)
+				if err != nil {
+					t.Fatalf("marshaling value failed: %v", err)
+				}
+				if size != len(bin) {
+					t.Fatalf("binary size %d differs from the marshaled one %d", size, len(bin))
+				}
+			})
+		}
+	}
+
+	t.Run("TypeMismatch", func(t *testing.T) {
+		if _, err := ComputeBinarySize(bsttype.String(), NewUint32Value(1), bstio.ValueOptions{}); err == nil {
+			t.Fatal("expected type mismatch error")
+		}
+	})
+}
@@ -25,6 +25,8 @@ var _ Value = (*StructValue)(nil)
 type StructValue struct {
 	StructType *bsttype.Struct
 	Fields     []Value
+
+	cache marshalCache
 }
 
 // MustNewStructValue creates a new struct value.
@@ -68,6 +70,103 @@ func EmptyStructValueOf(t *bsttype.Struct) *StructValue {
 	return v
 }
 
+// Field returns the value of the field of given name.
+// The found flag is false if there is no such field, or its value is not set.
+func (x *StructValue) Field(name string) (Value, bool) {
+	_, pos, found := x.StructType.FieldByName(name)
+	return x.fieldAt(pos, found)
+}
+
+// FieldByIndex returns the value of the field of given index (StructField.Index).
+// The found flag is false if there is no such field, or its value is not set.
+func (x *StructValue) FieldByIndex(index uint) (Value, bool) {
+	_, pos, found := x.StructType.FieldByIndex(index)
+	return x.fieldAt(pos, found)
+}
+
+// SetField sets the value of the field of given name, verifying that it is of the field type.
+func (x *StructValue) SetField(name string, v Value) error {
+	f, pos, found := x.StructType.FieldByName(name)
+	if !found {
+		return bsterr.Err(bsterr.CodeValueFieldMissing, "struct field not found").WithDetail("name", name)
+	}
+	return x.setFieldAt(f, pos, v)
+}
+
+// SetFieldByIndex sets the value of the field of given index (StructField.Index), verifying that it is
+// of the field type.
+func (x *StructValue) SetFieldByIndex(index uint, v Value) error {
+	f, pos, found := x.StructType.FieldByIndex(index)
+	if !found {
+		return bsterr.Err(bsterr.CodeValueFieldMissing, "struct field not found").WithDetail("index", index)
+	}
+	return x.setFieldAt(f, pos, v)
+}
+
+// SetFieldDefault sets the default value of the struct field, verifying that it is of the field type.
+// The value is encoded in the regular, ascending mode of the field type, as the StructField.Default.
+func SetFieldDefault(f *bsttype.StructField, v Value) error {
+	// 1. Verify the type of the value.
+	if v == nil || !bsttype.TypesEqual(v.Type(), f.Type) {
+		var vt bsttype.Type
+		if v != nil {
+			vt = v.Type()
+		}
+		return bsterr.Err(bsterr.CodeMismatchingValueType, "default value has wrong type for field").
+			WithDetails(bsterr.D("typeField", f), bsterr.D("valueField", vt))
+	}
+
+	// 2. Encode the default value.
+	b, err := v.MarshalValue(bstio.ValueOptions{})
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to encode field default value").
+			WithDetail("field", f.Name)
+	}
+	f.Default = b
+	return nil
+}
+
+// FieldDefault decodes the default value of the struct field.
+// The found flag is false if the field has no default value.
+func FieldDefault(f bsttype.StructField) (Value, bool, error) {
+	if len(f.Default) == 0 {
+		return nil, false, nil
+	}
+	v := EmptyValueOf(f.Type)
+	if err := v.UnmarshalValue(f.Default, bstio.ValueOptions{}); err != nil {
+		return nil, false, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to decode field default value").
+			WithDetail("field", f.Name)
+	}
+	return v, true, nil
+}
+
+func (x *StructValue) fieldAt(pos int, found bool) (Value, bool) {
+	if !found || pos >= len(x.Fields) || x.Fields[pos] == nil {
+		return nil, false
+	}
+	return x.Fields[pos], true
+}
+
+func (x *StructValue) setFieldAt(f bsttype.StructField, pos int, v Value) error {
+	// 1. Verify the type of the value.
+	if v == nil || !bsttype.TypesEqual(v.Type(), f.Type) {
+		var vt bsttype.Type
+		if v != nil {
+			vt = v.Type()
+		}
+		return bsterr.Err(bsterr.CodeMismatchingValueType, "struct value has wrong type for field").
+			WithDetails(bsterr.D("typeField", f), bsterr.D("valueField", vt))
+	}
+
+	// 2. The fields of the value created without them are resolved lazily, with the empty values.
+	for i := len(x.Fields); i < len(x.StructType.Fields); i++ {
+		x.Fields = append(x.Fields, EmptyValueOf(x.StructType.Fields[i].Type))
+	}
+	x.Fields[pos] = v
+	x.cache.invalidate()
+	return nil
+}
+
 // String provides a human-readable representation of the struct value.
 // Implements fmt.Stringer and the Value interface.
 func (x StructValue) String() string {
@@ -127,11 +226,15 @@ func (x *StructValue) UnmarshalValue(in []byte, options bstio.ValueOptions) erro
 
 // ReadValue reads the value from the byte slice.
 func (x *StructValue) ReadValue(r io.Reader, options bstio.ValueOptions) (int, error) {
+	x.cache.invalidate()
 	var (
 		bytesRead, n     int
 		boolBuf, boolPos byte
 		err              error
 	)
+	if err = x.verifyPlainFields(options); err != nil {
+		return 0, err
+	}
 	desc := options.Descending
 	for fi, f := range x.Fields {
 		fDesc := desc
@@ -140,13 +243,14 @@ func (x *StructValue) ReadValue(r io.Reader, options bstio.ValueOptions) (int, e
 		}
 
 		if f.Kind() == bsttype.KindBoolean {
-			prev, ok := x.StructType.PreviewPrevElemType(fi)
-			if !ok || boolPos == 0 || (ok && prev.Kind() == bsttype.KindBoolean) {
+			prev, ok := x.StructType.PreviewPrevElemType(fi - 1)
+			if !ok || boolPos == 0 || (ok && prev.Kind() != bsttype.KindBoolean) {
 				boolBuf, err = bstio.ReadByte(r)
 				if err != nil {
 					return bytesRead, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to read bool value")
 				}
 				bytesRead++
+				boolPos = 0
 			}
 			v := boolBuf&(1<<boolPos) != 0
 			if fDesc {
@@ -162,7 +266,10 @@ func (x *StructValue) ReadValue(r io.Reader, options bstio.ValueOptions) (int, e
 			continue
 		}
 
-		n, err = f.ReadValue(r, bstio.ValueOptions{Descending: fDesc})
+		// The field order is the only option that differs from the struct.
+		fo := options
+		fo.Descending = fDesc
+		n, err = f.ReadValue(r, fo)
 		if err != nil {
 			return bytesRead, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").
 				WithDetail("field", x.StructType.Fields[fi].Name)
@@ -172,35 +279,58 @@ func (x *StructValue) ReadValue(r io.Reader, options bstio.ValueOptions) (int, e
 	return bytesRead, nil
 }
 
+// EnableMarshalCache enables memoization of the StructValue binaries by the value options, so that marshaling
+// the same value multiple times encodes it only once. The cache is invalidated by the StructValue methods
+// that modify the value, however any direct modification of the Fields, or of the nested values,
+// needs to be followed by the InvalidateMarshalCache call.
+func (x *StructValue) EnableMarshalCache() {
+	x.cache.enable()
+}
+
+// InvalidateMarshalCache clears the memoized binaries of the value.
+func (x *StructValue) InvalidateMarshalCache() {
+	x.cache.invalidate()
+}
+
 // WriteValue writes the value to the byte slice.
 func (x *StructValue) WriteValue(w io.Writer, options bstio.ValueOptions) (int, error) {
+	return x.cache.write(w, options, x.writeValue)
+}
+
+func (x *StructValue) writeValue(w io.Writer, options bstio.ValueOptions) (int, error) {
 	var (
 		bytesWritten int
 		boolBuf      byte
 		boolPos      int
 	)
+	if err := x.verifyPlainFields(options); err != nil {
+		return 0, err
+	}
 	for fi, f := range x.Fields {
+		// 1. The field order is the only option that differs from the struct.
+		fo := options
+		if x.StructType.Fields[fi].Descending {
+			fo.Descending = !fo.Descending
+		}
+
+		// 2. Booleans are packed into bits, where each bit is inverted by the field order.
 		if bv, ok := f.(*BoolValue); ok {
-			var bin byte
-			if bv.Value {
-				bin = 0x01
+			if bv.Value != fo.Descending {
+				boolBuf |= 1 << boolPos
 			}
-			boolBuf |= bin << boolPos
 			boolPos++
 			if boolPos == 8 || !x.isNextBool(fi) {
-				if options.Descending {
-					boolBuf = ^boolBuf
-				}
 				_, err := w.Write([]byte{boolBuf})
 				if err != nil {
 					return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").
 						WithDetail("field", x.StructType.Fields[fi].Name)
 				}
 				bytesWritten++
+				boolBuf, boolPos = 0, 0
 			}
 			continue
 		}
-		n, err := f.WriteValue(w, options)
+		n, err := f.WriteValue(w, fo)
 		if err != nil {
 			return bytesWritten, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to write struct field").
 				WithDetails(bsterr.D("field", x.StructType.Fields[fi].Name))
@@ -210,8 +340,23 @@ func (x *StructValue) WriteValue(w io.Writer, options bstio.ValueOptions) (int,
 	return bytesWritten, nil
 }
 
+// verifyPlainFields checks that no struct field has an encoding override, as these are applied only
+// by the Composer and Extractor. The comparable binaries ignore the field encodings.
+func (x *StructValue) verifyPlainFields(options bstio.ValueOptions) error {
+	if options.Comparable {
+		return nil
+	}
+	for _, f := range x.StructType.Fields {
+		if f.Encoding != bsttype.FieldEncodingPlain {
+			return bsterr.Err(bsterr.CodeInvalidType, "struct value doesn't support the field encoding").
+				WithDetails(bsterr.D("field", f.Name), bsterr.D("encoding", f.Encoding))
+		}
+	}
+	return nil
+}
+
 func (x *StructValue) isNextBool(i int) bool {
-	if i+1 < len(x.Fields)-1 {
+	if i+1 < len(x.Fields) {
 		return x.Fields[i+1].Kind() == bsttype.KindBoolean
 	}
 	return false
@@ -168,3 +168,206 @@ func TestStructType_Skip(t *testing.T) {
 		})
 	}
 }
+
+func TestStructValue_Descending(t *testing.T) {
+	st := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "Name", Type: bsttype.String(), Descending: true},
+			{Index: 2, Name: "Active", Type: bsttype.Boolean()},
+			{Index: 3, Name: "Admin", Type: bsttype.Boolean(), Descending: true},
+			{Index: 4, Name: "Age", Type: bsttype.Int32()},
+			{Index: 5, Name: "Verified", Type: bsttype.Boolean()},
+		},
+	}
+	newValue := func(age int32) *StructValue {
+		return MustNewStructValue(st, []Value{
+			NewStringValue("a\x00b"),
+			NewBoolValue(true),
+			NewBoolValue(false),
+			NewInt32Value(age),
+			NewBoolValue(true),
+		})
+	}
+
+	for _, options := range []bstio.ValueOptions{
+		{},
+		{Descending: true},
+		{Comparable: true},
+		{Comparable: true, Descending: true},
+	} {
+		// 1. The value is read back along with the per field order.
+		lo, hi := newValue(-1), newValue(1)
+		loData, err := lo.MarshalValue(options)
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %s", options, err)
+		}
+		sv := EmptyStructValueOf(st)
+		if err = sv.UnmarshalValue(loData, options); err != nil {
+			t.Fatalf("%+v: unexpected error: %s", options, err)
+		}
+		if !reflect.DeepEqual(sv.Fields, lo.Fields) {
+			t.Fatalf("%+v: unexpected value: %v", options, sv)
+		}
+
+		// 2. The skip consumes the whole binary.
+		skipped, err := sv.Skip(bytes.NewReader(loData), options)
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %s", options, err)
+		}
+		if int(skipped) != len(loData) {
+			t.Fatalf("%+v: unexpected number of bytes skipped: %d, wanted: %d", options, skipped, len(loData))
+		}
+
+		// 3. The comparable binaries are ordered by the options order.
+		if !options.Comparable {
+			continue
+		}
+		hiData, err := hi.MarshalValue(options)
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %s", options, err)
+		}
+		cmp := bytes.Compare(loData, hiData)
+		if options.Descending {
+			cmp = -cmp
+		}
+		if cmp >= 0 {
+			t.Fatalf("%+v: binary %x is not ordered before %x", options, loData, hiData)
+		}
+	}
+}
+
+func TestStructValue_BoolPacking(t *testing.T) {
+	st := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "Active", Type: bsttype.Boolean()},
+			{Index: 2, Name: "Admin", Type: bsttype.Boolean()},
+			{Index: 3, Name: "Age", Type: bsttype.Int32()},
+			{Index: 4, Name: "Verified", Type: bsttype.Boolean()},
+		},
+	}
+	sv := MustNewStructValue(st, []Value{
+		NewBoolValue(true),
+		NewBoolValue(true),
+		NewInt32Value(7),
+		NewBoolValue(true),
+	})
+	data, err := sv.MarshalValue(bstio.ValueOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The consecutive booleans share a byte, and the one after the int32 starts a new byte.
+	if len(data) != 1+4+1 || data[0] != 0b11 || data[5] != 0b1 {
+		t.Fatalf("unexpected binary: %08b", data)
+	}
+
+	read := EmptyStructValueOf(st)
+	if err = read.UnmarshalValue(data, bstio.ValueOptions{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(read.Fields, sv.Fields) {
+		t.Fatalf("unexpected value: %v", read)
+	}
+	skipped, err := read.Skip(bytes.NewReader(data), bstio.ValueOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if int(skipped) != len(data) {
+		t.Fatalf("unexpected number of bytes skipped: %d, wanted: %d", skipped, len(data))
+	}
+}
+
+func TestStructValue_FieldAccess(t *testing.T) {
+	st := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "Name", Type: bsttype.String()},
+			{Index: 3, Name: "Age", Type: bsttype.Int32()},
+		},
+	}
+
+	// 1. The fields of the value created without them are set lazily.
+	v := &StructValue{StructType: st}
+	if _, found := v.Field("Age"); found {
+		t.Fatal("expected the unset field not to be found")
+	}
+	if err := v.SetFieldByIndex(3, NewInt32Value(42)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(v.Fields) != 2 || v.Fields[0].Kind() != bsttype.KindString {
+		t.Fatalf("unexpected fields: %v", v.Fields)
+	}
+	if err := v.SetField("Name", NewStringValue("John")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// 2. The fields are found by the name and the index, with the index gaps respected.
+	if f, found := v.Field("Name"); !found || f.(*StringValue).Value != "John" {
+		t.Fatalf("unexpected Name field: %v", f)
+	}
+	if f, found := v.FieldByIndex(3); !found || f.(*Int32Value).Value != 42 {
+		t.Fatalf("unexpected Age field: %v", f)
+	}
+	if _, found := v.FieldByIndex(2); found {
+		t.Fatal("expected the field of the index gap not to be found")
+	}
+
+	// 3. The values of other types and the missing fields are rejected.
+	if err := v.SetField("Age", NewInt64Value(42)); err == nil {
+		t.Fatal("expected mismatching value type error")
+	}
+	if err := v.SetField("Missing", NewInt32Value(42)); err == nil {
+		t.Fatal("expected missing field error")
+	}
+	if err := v.SetFieldByIndex(2, NewInt32Value(42)); err == nil {
+		t.Fatal("expected missing field error")
+	}
+}
+
+func TestStructValue_FieldDefault(t *testing.T) {
+	st := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "Name", Type: bsttype.String()},
+			{Index: 2, Name: "Age", Type: bsttype.Int32()},
+		},
+	}
+	if err := SetFieldDefault(&st.Fields[1], NewInt32Value(-7)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := SetFieldDefault(&st.Fields[0], NewInt32Value(1)); err == nil {
+		t.Fatal("expected mismatching value type error")
+	}
+
+	// 1. The defaults are serialized with the type, while the fields without them are not affected.
+	var buf bytes.Buffer
+	if _, err := bsttype.WriteType(&buf, st); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n, err := bsttype.SkipType(bytes.NewReader(buf.Bytes())); err != nil || int(n) != buf.Len() {
+		t.Fatalf("unexpected skipped bytes: %d of %d, err: %v", n, buf.Len(), err)
+	}
+	rt, _, err := bsttype.ReadType(bytes.NewReader(buf.Bytes()), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	rst := rt.(*bsttype.Struct)
+	if _, found, err := FieldDefault(rst.Fields[0]); found || err != nil {
+		t.Fatalf("unexpected Name default, found: %v, err: %v", found, err)
+	}
+	v, found, err := FieldDefault(rst.Fields[1])
+	if err != nil || !found || v.(*Int32Value).Value != -7 {
+		t.Fatalf("unexpected Age default: %v, found: %v, err: %v", v, found, err)
+	}
+
+	// 2. The structs without the defaults are read without them.
+	var plain bytes.Buffer
+	st.Fields[1].Default = nil
+	if _, err = bsttype.WriteType(&plain, st); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rt, _, err = bsttype.ReadType(bytes.NewReader(plain.Bytes()), false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if f := rt.(*bsttype.Struct).Fields[1]; f.Default != nil {
+		t.Fatalf("unexpected Age default: %x", f.Default)
+	}
+}
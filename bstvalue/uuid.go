@@ -0,0 +1,80 @@
+package bstvalue
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// Compile-time check to ensure that UUIDValue implements the Value interface.
+var _ Value = (*UUIDValue)(nil)
+
+// UUIDValue is the value descriptor for the 16 byte universally unique identifier.
+type UUIDValue struct {
+	Value bstio.UUID
+}
+
+// NewUUIDValue returns a new UUIDValue. It accepts any of the [16]byte based types, i.e. the github.com/google/uuid.UUID.
+func NewUUIDValue(u [16]byte) *UUIDValue {
+	return &UUIDValue{Value: u}
+}
+
+func emptyUUIDValue(_ bsttype.Type) Value {
+	return &UUIDValue{}
+}
+
+// String returns a human-readable representation of the UUIDValue.
+func (x UUIDValue) String() string {
+	return "UUID(" + x.Value.String() + ")"
+}
+
+// Type returns the type of the value.
+// Implements the Value interface.
+func (*UUIDValue) Type() bsttype.Type {
+	return bsttype.UUID()
+}
+
+// Kind returns the basic kind of the value.
+// Implements the Value interface.
+func (*UUIDValue) Kind() bsttype.Kind {
+	return bsttype.KindUUID
+}
+
+// Skip the bytes in the reader to the next value.
+// Implements the Value interface.
+func (*UUIDValue) Skip(rs io.ReadSeeker, _ bstio.ValueOptions) (int64, error) {
+	return bstio.SkipUUID(rs)
+}
+
+// MarshalValue writes the value to the byte slice.
+// Implements the Value interface.
+func (x *UUIDValue) MarshalValue(o bstio.ValueOptions) ([]byte, error) {
+	return bstio.AppendUUID(make([]byte, 0, bstio.UUIDBinarySize), x.Value, o.Descending), nil
+}
+
+// UnmarshalValue reads the value from the byte slice.
+// Implements the Value interface.
+func (x *UUIDValue) UnmarshalValue(in []byte, o bstio.ValueOptions) error {
+	_, err := x.ReadValue(bytes.NewReader(in), o)
+	return err
+}
+
+// ReadValue reads the value from the reader.
+// Implements the Value interface.
+func (x *UUIDValue) ReadValue(r io.Reader, o bstio.ValueOptions) (int, error) {
+	u, n, err := bstio.ReadUUID(r, o.Descending)
+	if err != nil {
+		return n, err
+	}
+
+	x.Value = u
+	return n, nil
+}
+
+// WriteValue writes the value to the writer.
+// Implements the Value interface.
+func (x *UUIDValue) WriteValue(w io.Writer, o bstio.ValueOptions) (int, error) {
+	return bstio.WriteUUID(w, x.Value, o.Descending)
+}
@@ -27,27 +27,31 @@ type Value interface {
 	String() string
 }
 
-var _StdTypeValues = [bsttype.KindOneOf + 1]func(bsttype.Type) Value{
-	bsttype.KindUndefined: emptyUndefinedValue,
-	bsttype.KindBoolean:   emptyBoolValue,
-	bsttype.KindInt:       emptyIntValue,
-	bsttype.KindInt8:      emptyInt8Value,
-	bsttype.KindInt16:     emptyInt16Value,
-	bsttype.KindInt32:     emptyInt32Value,
-	bsttype.KindInt64:     emptyInt64Value,
-	bsttype.KindUint:      emptyUintValue,
-	bsttype.KindUint8:     emptyUint8Value,
-	bsttype.KindUint16:    emptyUint16Value,
-	bsttype.KindUint32:    emptyUint32Value,
-	bsttype.KindUint64:    emptyUint64Value,
-	bsttype.KindFloat32:   emptyFloat32Value,
-	bsttype.KindFloat64:   emptyFloat64Value,
-	bsttype.KindString:    emptyStringValue,
-	bsttype.KindBytes:     emptyBytesValue,
-	bsttype.KindArray:     emptyArrayValue,
-	bsttype.KindDuration:  emptyDurationValue,
-	bsttype.KindTimestamp: emptyTimestampValue,
-	bsttype.KindAny:       emptyAnyValue,
+var _StdTypeValues = [bsttype.KindUUID + 1]func(bsttype.Type) Value{
+	bsttype.KindUndefined:     emptyUndefinedValue,
+	bsttype.KindBoolean:       emptyBoolValue,
+	bsttype.KindInt:           emptyIntValue,
+	bsttype.KindInt8:          emptyInt8Value,
+	bsttype.KindInt16:         emptyInt16Value,
+	bsttype.KindInt32:         emptyInt32Value,
+	bsttype.KindInt64:         emptyInt64Value,
+	bsttype.KindUint:          emptyUintValue,
+	bsttype.KindUint8:         emptyUint8Value,
+	bsttype.KindUint16:        emptyUint16Value,
+	bsttype.KindUint32:        emptyUint32Value,
+	bsttype.KindUint64:        emptyUint64Value,
+	bsttype.KindFloat32:       emptyFloat32Value,
+	bsttype.KindFloat64:       emptyFloat64Value,
+	bsttype.KindString:        emptyStringValue,
+	bsttype.KindBytes:         emptyBytesValue,
+	bsttype.KindArray:         emptyArrayValue,
+	bsttype.KindDuration:      emptyDurationValue,
+	bsttype.KindTimestamp:     emptyTimestampValue,
+	bsttype.KindAny:           emptyAnyValue,
+	bsttype.KindExternalBytes: emptyExternalBytesValue,
+	bsttype.KindBitmap:        emptyBitmapValue,
+	bsttype.KindDecimal:       emptyDecimalValue,
+	bsttype.KindUUID:          emptyUUIDValue,
 }
 
 func init() {
@@ -0,0 +1,197 @@
+// Package bstwal provides the write-ahead-log record framing for the BST encoded payloads.
+//
+// Each record is composed of:
+//   - Payload length (bstio uint).
+//   - Sequence number (uint64).
+//   - CRC32C checksum (uint32) of the length, sequence number and payload bytes.
+//   - Payload.
+//
+// The Reader stops at the first torn or corrupted record, which allows recovering the log after a crash
+// by truncating it at the Reader.Offset.
+package bstwal
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+)
+
+// DefaultMaxPayloadSize is the default maximum size of the record payload accepted by the Reader.
+const DefaultMaxPayloadSize = 64 << 20
+
+var _castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Record is a single write-ahead-log record.
+type Record struct {
+	Seq     uint64
+	Payload []byte
+}
+
+// Appender writes the records to the log with increasing sequence numbers.
+type Appender struct {
+	w   io.Writer
+	seq uint64
+	buf bytes.Buffer
+}
+
+// NewAppender creates a new appender which writes to w, starting with the nextSeq sequence number.
+// When continuing a recovered log, the nextSeq should be the sequence number of the last record increased by one.
+func NewAppender(w io.Writer, nextSeq uint64) *Appender {
+	return &Appender{w: w, seq: nextSeq}
+}
+
+// NextSeq returns the sequence number of the next appended record.
+func (x *Appender) NextSeq() uint64 {
+	return x.seq
+}
+
+// Append writes the record with given payload and returns its sequence number.
+// The whole record is written with a single Write call.
+func (x *Appender) Append(payload []byte) (uint64, error) {
+	// 1. Frame the record.
+	x.buf.Reset()
+	if _, err := bstio.WriteUint(&x.buf, uint(len(payload)), false); err != nil {
+		return 0, err
+	}
+	if _, err := bstio.WriteUint64(&x.buf, x.seq, false); err != nil {
+		return 0, err
+	}
+	crc := crc32.Update(crc32.Checksum(x.buf.Bytes(), _castagnoli), _castagnoli, payload)
+	if _, err := bstio.WriteUint32(&x.buf, crc, false); err != nil {
+		return 0, err
+	}
+	x.buf.Write(payload)
+
+	// 2. Write the record.
+	if _, err := x.w.Write(x.buf.Bytes()); err != nil {
+		return 0, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write wal record").
+			WithDetail("seq", x.seq)
+	}
+
+	// 3. Increase the sequence number.
+	seq := x.seq
+	x.seq++
+	return seq, nil
+}
+
+// Reader reads the records from the log.
+// It stops at the first torn record - the one that is incomplete, has invalid checksum or not increasing
+// sequence number, and reports it by the Torn method.
+type Reader struct {
+	// MaxPayloadSize is the maximum payload length accepted by the reader.
+	// The records with greater length are treated as torn.
+	MaxPayloadSize uint
+
+	r       io.Reader
+	rec     Record
+	offset  int64
+	torn    bool
+	started bool
+	err     error
+	header  bytes.Buffer
+}
+
+// NewReader creates a new log reader.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r, MaxPayloadSize: DefaultMaxPayloadSize}
+}
+
+// Next reads the next record. It returns false at the end of the log, on the torn record or an error.
+func (x *Reader) Next() bool {
+	if x.err != nil || x.torn {
+		return false
+	}
+
+	// 1. Read the payload length. A clean end of the log occurs only before the record.
+	x.header.Reset()
+	tr := io.TeeReader(x.r, &x.header)
+	length, _, err := bstio.ReadUint(tr, false)
+	if err != nil {
+		if x.header.Len() == 0 && isEOF(err) {
+			return false
+		}
+		return x.fail(err)
+	}
+	if length > x.MaxPayloadSize {
+		x.torn = true
+		return false
+	}
+
+	// 2. Read the sequence number and the checksum.
+	var fixed [12]byte
+	if _, err = io.ReadFull(x.r, fixed[:]); err != nil {
+		return x.fail(err)
+	}
+	seq, err := bstio.ParseUint64(fixed[:8], false)
+	if err != nil {
+		return x.fail(err)
+	}
+	crc, err := bstio.ParseUint32(fixed[8:], false)
+	if err != nil {
+		return x.fail(err)
+	}
+	x.header.Write(fixed[:8])
+
+	// 3. Read the payload.
+	payload := make([]byte, length)
+	if _, err = io.ReadFull(x.r, payload); err != nil {
+		return x.fail(err)
+	}
+
+	// 4. Verify the checksum and the sequence number order.
+	if crc32.Update(crc32.Checksum(x.header.Bytes(), _castagnoli), _castagnoli, payload) != crc {
+		x.torn = true
+		return false
+	}
+	if x.started && seq <= x.rec.Seq {
+		x.torn = true
+		return false
+	}
+
+	// 5. Set up the record.
+	x.started = true
+	x.rec = Record{Seq: seq, Payload: payload}
+	x.offset += int64(x.header.Len() + 4 + len(payload))
+	return true
+}
+
+// Record returns the last read record.
+func (x *Reader) Record() Record {
+	return x.rec
+}
+
+// Torn returns true if the reader stopped on a torn record.
+func (x *Reader) Torn() bool {
+	return x.torn
+}
+
+// Offset returns the offset just after the last valid record.
+// In order to recover the log, it should be truncated to this offset.
+func (x *Reader) Offset() int64 {
+	return x.offset
+}
+
+// Err returns the error which stopped the reader, other than the torn record.
+func (x *Reader) Err() error {
+	return x.err
+}
+
+func (x *Reader) fail(err error) bool {
+	// An unexpected end of the log means that the last record was not fully written,
+	// and a malformed header means that it was corrupted.
+	var be *bsterr.Error
+	if isEOF(err) || (errors.As(err, &be) && be.Code == bsterr.CodeDecodingBinaryValue) {
+		x.torn = true
+		return false
+	}
+	x.err = err
+	return false
+}
+
+func isEOF(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
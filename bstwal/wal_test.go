@@ -0,0 +1,88 @@
+package bstwal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAppender(&buf, 1)
+	payloads := [][]byte{[]byte("first"), {}, []byte("third record")}
+	for i, p := range payloads {
+		seq, err := a.Append(p)
+		if err != nil {
+			t.Fatalf("appending record failed: %v", err)
+		}
+		if seq != uint64(i+1) {
+			t.Fatalf("unexpected sequence number: %d", seq)
+		}
+	}
+	full := buf.Len()
+
+	t.Run("Clean", func(t *testing.T) {
+		r := NewReader(bytes.NewReader(buf.Bytes()))
+		var i int
+		for r.Next() {
+			rec := r.Record()
+			if rec.Seq != uint64(i+1) || !bytes.Equal(rec.Payload, payloads[i]) {
+				t.Fatalf("unexpected record %d: %v", i, rec)
+			}
+			i++
+		}
+		if r.Err() != nil {
+			t.Fatalf("unexpected error: %v", r.Err())
+		}
+		if r.Torn() {
+			t.Fatal("unexpected torn record")
+		}
+		if i != len(payloads) {
+			t.Fatalf("unexpected number of records: %d", i)
+		}
+		if r.Offset() != int64(full) {
+			t.Fatalf("unexpected offset: %d, expected: %d", r.Offset(), full)
+		}
+	})
+
+	t.Run("TornTail", func(t *testing.T) {
+		r := NewReader(bytes.NewReader(buf.Bytes()[:full-3]))
+		var i int
+		for r.Next() {
+			i++
+		}
+		if r.Err() != nil {
+			t.Fatalf("unexpected error: %v", r.Err())
+		}
+		if !r.Torn() {
+			t.Fatal("expected torn record")
+		}
+		if i != 2 {
+			t.Fatalf("unexpected number of records: %d", i)
+		}
+
+		// Recovering the log by truncation and appending continues the sequence.
+		log := bytes.NewBuffer(bytes.Clone(buf.Bytes()[:r.Offset()]))
+		if _, err := NewAppender(log, r.Record().Seq+1).Append([]byte("recovered")); err != nil {
+			t.Fatalf("appending record failed: %v", err)
+		}
+		r = NewReader(log)
+		for r.Next() {
+		}
+		if r.Torn() || r.Record().Seq != 3 || string(r.Record().Payload) != "recovered" {
+			t.Fatalf("unexpected recovered log state: %v", r.Record())
+		}
+	})
+
+	t.Run("Corrupted", func(t *testing.T) {
+		data := bytes.Clone(buf.Bytes())
+		data[len(data)-1] ^= 0xff
+		r := NewReader(bytes.NewReader(data))
+		var i int
+		for r.Next() {
+			i++
+		}
+		if !r.Torn() || i != 2 {
+			t.Fatalf("expected torn record after two valid ones, got: %d, torn: %v", i, r.Torn())
+		}
+	})
+}
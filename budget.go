@@ -0,0 +1,100 @@
+package bst
+
+import (
+	"io"
+	"math"
+	"sync/atomic"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+)
+
+// DecodeBudget is the quota of the bytes read and the elements extracted by the extractors, shared by all
+// the nested struct, array and map frames of an extractor. A single budget could also be shared by multiple
+// extractors, i.e. of all the values decoded on behalf of a tenant, thus it is safe for concurrent use.
+// Once the budget is exceeded, the extractor fails with the bsterr.CodeBudgetExceeded error.
+type DecodeBudget struct {
+	maxBytes, maxElements int64
+	bytes, elements       atomic.Int64
+}
+
+// NewDecodeBudget creates a new decode budget of given maximum number of bytes and elements.
+// The zero maximum means no limit.
+func NewDecodeBudget(maxBytes, maxElements int64) *DecodeBudget {
+	return &DecodeBudget{maxBytes: maxBytes, maxElements: maxElements}
+}
+
+// Used returns the number of bytes and elements already used.
+func (x *DecodeBudget) Used() (bytes, elements int64) {
+	return x.bytes.Load(), x.elements.Load()
+}
+
+// Reset releases all the used bytes and elements, i.e. once the next request of the tenant is decoded.
+func (x *DecodeBudget) Reset() {
+	x.bytes.Store(0)
+	x.elements.Store(0)
+}
+
+// useBytes uses n bytes of the budget.
+func (x *DecodeBudget) useBytes(n int) error {
+	used := x.bytes.Add(int64(n))
+	if x.maxBytes > 0 && used > x.maxBytes {
+		return bsterr.Err(bsterr.CodeBudgetExceeded, "decode budget of bytes exceeded").
+			WithDetails(bsterr.D("max", x.maxBytes), bsterr.D("used", used))
+	}
+	return nil
+}
+
+// useElement uses a single element of the budget.
+func (x *DecodeBudget) useElement() error {
+	used := x.elements.Add(1)
+	if x.maxElements > 0 && used > x.maxElements {
+		return bsterr.Err(bsterr.CodeBudgetExceeded, "decode budget of elements exceeded").
+			WithDetails(bsterr.D("max", x.maxElements), bsterr.D("used", used))
+	}
+	return nil
+}
+
+// checkElements verifies that n more elements fit within the budget, without using them. It rejects the collections
+// of declared length beyond the budget before any of their elements is extracted.
+func (x *DecodeBudget) checkElements(n int) error {
+	if x.maxElements > 0 && x.elements.Load()+int64(n) > x.maxElements {
+		return bsterr.Err(bsterr.CodeBudgetExceeded, "decode budget of elements exceeded").
+			WithDetails(bsterr.D("max", x.maxElements), bsterr.D("length", n))
+	}
+	return nil
+}
+
+// budgetReader is the root reader of the extractor with the Budget option, which uses the budget for the bytes read.
+// The skipped values are seeked over, thus these are not read, nor counted.
+type budgetReader struct {
+	io.ReadSeeker
+	budget *DecodeBudget
+}
+
+// Read reads from the underlying reader, and uses the budget for the bytes read.
+// Once the budget is exceeded, no bytes are returned, as the io.ReadFull ignores the error of the complete read.
+// Implements io.Reader interface.
+func (x *budgetReader) Read(p []byte) (int, error) {
+	n, err := x.ReadSeeker.Read(p)
+	if n > 0 {
+		if berr := x.budget.useBytes(n); berr != nil {
+			return 0, berr
+		}
+	}
+	return n, err
+}
+
+// RemainingSize returns the number of the remaining bytes of the underlying reader, if it is known.
+// Implements bstio.RemainingSizer interface.
+func (x *budgetReader) RemainingSize() (int64, bool) {
+	return bstio.RemainingSize(x.ReadSeeker)
+}
+
+// checkBudgetLength verifies that the declared length of the initialized array or map fits within the budget.
+func (x *Extractor) checkBudgetLength() error {
+	if x.opts.Budget == nil || x.maxIndex < 0 || x.maxIndex == math.MaxInt {
+		return nil
+	}
+	return x.opts.Budget.checkElements(x.maxIndex + 1)
+}
@@ -0,0 +1,114 @@
+package bst
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+func TestDecodeBudget(t *testing.T) {
+	type item struct {
+		Name string   `bst:"name"`
+		Tags []string `bst:"tags"`
+	}
+	type order struct {
+		ID    uint64         `bst:"id"`
+		Items []item         `bst:"items"`
+		Attrs map[string]int `bst:"attrs"`
+	}
+	in := order{
+		ID:    1,
+		Items: []item{{Name: "a", Tags: []string{"x", "y"}}, {Name: "b"}},
+		Attrs: map[string]int{"k": 1},
+	}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	// isBudgetExceeded checks if any of the wrapped errors is the budget exceeded one.
+	isBudgetExceeded := func(err error) bool {
+		for ; err != nil; err = errors.Unwrap(err) {
+			if be, ok := err.(*bsterr.Error); ok && be.Code == bsterr.CodeBudgetExceeded {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("Unlimited", func(t *testing.T) {
+		b := NewDecodeBudget(0, 0)
+		var out order
+		if err := Unmarshal(data, &out, WithDecodeBudget(b)); err != nil {
+			t.Fatalf("unmarshal failed: %v", err)
+		}
+		bytesUsed, elements := b.Used()
+		if bytesUsed != int64(len(data)) {
+			t.Fatalf("unexpected bytes used: %d, expected: %d", bytesUsed, len(data))
+		}
+		if elements == 0 {
+			t.Fatal("expected the nested elements to use the budget")
+		}
+
+		b.Reset()
+		if bytesUsed, elements = b.Used(); bytesUsed != 0 || elements != 0 {
+			t.Fatalf("unexpected budget used after reset: %d, %d", bytesUsed, elements)
+		}
+	})
+
+	t.Run("Bytes", func(t *testing.T) {
+		var out order
+		err := Unmarshal(data, &out, WithDecodeBudget(NewDecodeBudget(int64(len(data)-1), 0)))
+		if !isBudgetExceeded(err) {
+			t.Fatalf("expected budget exceeded error, got: %v", err)
+		}
+	})
+
+	t.Run("Elements", func(t *testing.T) {
+		b := NewDecodeBudget(0, 0)
+		var out order
+		if err := Unmarshal(data, &out, WithDecodeBudget(b)); err != nil {
+			t.Fatalf("unmarshal failed: %v", err)
+		}
+		_, elements := b.Used()
+
+		err := Unmarshal(data, &out, WithDecodeBudget(NewDecodeBudget(0, elements-1)))
+		if !isBudgetExceeded(err) {
+			t.Fatalf("expected budget exceeded error, got: %v", err)
+		}
+	})
+
+	t.Run("Length", func(t *testing.T) {
+		// The declared length of the array beyond the budget fails before its elements are extracted.
+		type values struct {
+			Values []uint16 `bst:"values"`
+		}
+		long, err := Marshal(values{Values: make([]uint16, 100)})
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		b := NewDecodeBudget(0, 10)
+		var out values
+		if err = Unmarshal(long, &out, WithDecodeBudget(b)); !isBudgetExceeded(err) {
+			t.Fatalf("expected budget exceeded error, got: %v", err)
+		}
+		if _, elements := b.Used(); elements != 1 {
+			t.Fatalf("unexpected elements used: %d", elements)
+		}
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		// A single budget is shared by the values decoded on behalf of a tenant.
+		b := NewDecodeBudget(int64(len(data))*2, 0)
+		var out order
+		for i := 0; i < 2; i++ {
+			if err := Unmarshal(data, &out, WithDecodeBudget(b)); err != nil {
+				t.Fatalf("unmarshal %d failed: %v", i, err)
+			}
+		}
+		if err := Unmarshal(data, &out, WithDecodeBudget(b)); !isBudgetExceeded(err) {
+			t.Fatalf("expected budget exceeded error, got: %v", err)
+		}
+	})
+}
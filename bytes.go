@@ -25,7 +25,37 @@ func (x *Composer) WriteBytes(v []byte) error {
 			)
 	}
 
-	// 3. If the base is a struct, check if the field header needs to be written.
+	// 2.1. Replace the value of the tokenized field with its token.
+	if tv, ok, err := x.tokenizeField(v); err != nil {
+		return err
+	} else if ok {
+		v = tv
+	}
+
+	// 2.2. Encrypt the value of the encrypted field.
+	if ev, ok, err := x.encryptField(v); err != nil {
+		return err
+	} else if ok {
+		v = ev
+	}
+
+	// 3. Compress the value of the deflate encoded field.
+	if x.fieldEncoding() == bsttype.FieldEncodingDeflate {
+		cv, err := bstio.Deflate(v)
+		if err != nil {
+			return err
+		}
+		v = cv
+	}
+
+	// 4. Inline or offload the value of the external encoded field.
+	if x.fieldEncoding() == bsttype.FieldEncodingExternal {
+		return x.writeExternalField(v, bstio.BytesBinarySize(bt.FixedSize, v, x.elemDesc, x.opts.Comparable), func() (int, error) {
+			return bstio.WriteBytesAlloc(x.w, bt.FixedSize, v, x.elemDesc, x.opts.Comparable, x.opts.Allocator)
+		})
+	}
+
+	// 5. If the base is a struct, check if the field header needs to be written.
 	if x.needWriteFieldHeader() {
 		n, err := x.writeFieldHeader(x.w, x.fieldIndex(), bstio.BytesBinarySize(bt.FixedSize, v, x.elemDesc, x.opts.Comparable))
 		if err != nil {
@@ -35,15 +65,16 @@ func (x *Composer) WriteBytes(v []byte) error {
 		x.bytesWritten += n
 	}
 
-	// 4. Write the value.
-	n, err := bstio.WriteBytes(x.w, bt.FixedSize, v, x.elemDesc, x.opts.Comparable)
+	// 6. Write the value.
+	n, err := bstio.WriteBytesAlloc(x.w, bt.FixedSize, v, x.elemDesc, x.opts.Comparable, x.opts.Allocator)
 	if err != nil {
 		return err
 	}
 
 	x.bytesWritten += n
+	x.countValueOverhead(n, len(v))
 
-	// 5. Mark the element as written.
+	// 7. Mark the element as written.
 	if err = x.finishElem(); err != nil {
 		return err
 	}
@@ -70,12 +101,92 @@ func (x *Extractor) ReadBytes() ([]byte, error) {
 			)
 	}
 
-	// 3. Read the bytes value.
+	// 3. Read the tag of the external encoded field, and return its value if it was offloaded.
+	if x.fieldEncoding() == bsttype.FieldEncodingExternal {
+		v, offloaded, err := x.readExternalField()
+		if err != nil {
+			return nil, err
+		}
+		if offloaded {
+			if v, err = x.decryptField(v); err != nil {
+				return nil, err
+			}
+			if v, err = x.detokenizeField(v); err != nil {
+				return nil, err
+			}
+			if x.opts.Trace != nil {
+				x.traceElem(TraceOpRead, v)
+			}
+			x.finishElem()
+			return v, nil
+		}
+	}
+
+	// 4. Read the bytes value.
 	v, n, err := bstio.ReadBytes(x.r, bt.FixedSize, x.elemDesc, x.opts.Comparable)
 	x.bytesRead += n
 	if err != nil {
 		return nil, err
 	}
+
+	// 5. Decompress the value of the deflate encoded field.
+	if x.fieldEncoding() == bsttype.FieldEncodingDeflate {
+		if v, err = x.inflateField(v); err != nil {
+			return nil, err
+		}
+	}
+
+	// 6. Decrypt the value of the encrypted field.
+	if v, err = x.decryptField(v); err != nil {
+		return nil, err
+	}
+
+	// 7. Replace the token of the tokenized field with its value.
+	if v, err = x.detokenizeField(v); err != nil {
+		return nil, err
+	}
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
+	x.finishElem()
+	return v, nil
+}
+
+// ReadBytesView reads the 'bytes' elem value from the extractor, just like the ReadBytes, but it avoids the copy
+// of the value read out of the in-memory input, i.e. the bstio.BytesReader or the buffered plain io.Reader.
+// The view references the input, thus it must not be modified, and it is valid only until the extractor
+// is closed, or as long as the bstio.BytesReader input is. The values which need to be decoded, i.e. the comparable,
+// descending, deflate encoded, encrypted or tokenized ones, as well as the ones of other inputs are copied.
+func (x *Extractor) ReadBytesView() ([]byte, error) {
+	if x.err != nil {
+		return nil, x.err
+	}
+	bt, ok := x.elemType.(*bsttype.Bytes)
+	if !ok || x.elemDone || !x.viewable() {
+		return x.ReadBytes()
+	}
+	return x.readView(bt.FixedSize)
+}
+
+// viewable checks if the current string or bytes element binary is just its value, thus it could be viewed.
+func (x *Extractor) viewable() bool {
+	return !x.opts.Comparable && !x.elemDesc && x.fieldEncoding() == bsttype.FieldEncodingPlain &&
+		x.opts.Encryption == nil && len(x.opts.Tokenizers) == 0 && x.index <= x.maxIndex
+}
+
+// readView reads the view of the viewable string or bytes element of given fixed size.
+func (x *Extractor) readView(fixedSize int) ([]byte, error) {
+	v, n, err := bstio.ReadBytesView(x.r, fixedSize)
+	x.bytesRead += n
+	if err != nil {
+		return nil, err
+	}
+
+	// The view is capped, so that appending to it never overwrites the following input.
+	v = v[:len(v):len(v)]
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
@@ -0,0 +1,159 @@
+package bst
+
+import (
+	"bytes"
+	"io"
+	"sort"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bstskip"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+// canonicalMap returns true if the entries of the composed map are sorted by their key binaries.
+func (x *Composer) canonicalMap() bool {
+	_, ok := x.baseType.(*bsttype.Map)
+	return ok && x.opts.CanonicalMaps
+}
+
+// verifyCanonicalMap checks if the entries of the map could be sorted.
+// The boolean keys followed by the boolean values are packed into shared bytes, thus they are not separable.
+func verifyCanonicalMap(mt *bsttype.Map) error {
+	if derefNamedType(mt.Key.Type).Kind() == bsttype.KindBoolean && derefNamedType(mt.Value.Type).Kind() == bsttype.KindBoolean {
+		return bsterr.Err(bsterr.CodeInvalidType, "canonical map could not have both boolean keys and values").
+			WithDetail("type", mt)
+	}
+	return nil
+}
+
+// mapEntryOptions returns the value options of the keys and values of the map entries.
+func mapEntryOptions(mt *bsttype.Map, o bstio.ValueOptions) (bstio.ValueOptions, bstio.ValueOptions) {
+	kOpts, vOpts := o, o
+	if mt.Key.Descending {
+		kOpts.Descending = !kOpts.Descending
+	}
+	if mt.Value.Descending {
+		vOpts.Descending = !vOpts.Descending
+	}
+	return kOpts, vOpts
+}
+
+// sortMapEntries sorts the buffered entries of the canonical map by their key binaries,
+// and verifies that the keys are unique.
+func (x *Composer) sortMapEntries() error {
+	// 1. Split the buffered binary into the entries.
+	sb, ok := x.w.(*iopool.SharedBuffer)
+	if !ok {
+		return bsterr.Err(bsterr.CodeWritingFailed, "canonical map entries are not buffered")
+	}
+	mt := x.baseType.(*bsttype.Map)
+	kOpts, vOpts := mapEntryOptions(mt, bstio.ValueOptions{
+		Descending:        x.opts.Descending,
+		Comparable:        x.opts.Comparable,
+		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    x.opts.LengthPrefixedCollections,
+		OrderedFloats:     x.opts.OrderedFloats,
+	})
+	ks, vs := bstskip.ElemSkipFuncOf(mt.Key.Type, kOpts), bstskip.ElemSkipFuncOf(mt.Value.Type, vOpts)
+
+	type entry struct {
+		key, data []byte
+	}
+	entries := make([]entry, 0, x.index)
+	rs := bytes.NewReader(sb.Bytes)
+	for start := 0; start < len(sb.Bytes); {
+		kn, err := ks(rs, kOpts)
+		if err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to split canonical map key")
+		}
+		vn, err := vs(rs, vOpts)
+		if err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to split canonical map value")
+		}
+		end := start + int(kn+vn)
+		entries = append(entries, entry{key: sb.Bytes[start : start+int(kn)], data: sb.Bytes[start:end]})
+		start = end
+	}
+
+	// 2. Sort the entries, and replace the buffered binary with the sorted one.
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+	//    The directory of the indexed map locates the entries at their sorted offsets.
+	indexed := x.indexedMap()
+	if indexed {
+		x.mapDir = x.mapDir[:0]
+	}
+	sorted := make([]byte, 0, len(sb.Bytes))
+	for i, e := range entries {
+		if i > 0 && bytes.Equal(entries[i-1].key, e.key) {
+			return bsterr.Err(bsterr.CodeInvalidValue, "duplicate map key").WithPII("key", e.key)
+		}
+		if indexed {
+			de, err := bstio.NewMapDirectoryEntry(e.key, len(sorted))
+			if err != nil {
+				return err
+			}
+			x.mapDir = append(x.mapDir, de)
+		}
+		sorted = append(sorted, e.data...)
+	}
+	copy(sb.Bytes, sorted)
+	return nil
+}
+
+// verifyMapEntries verifies that the keys of the map extractor are unique and sorted by their binaries.
+// The reader is positioned back at the first entry.
+func (x *Extractor) verifyMapEntries() error {
+	// 1. Prepare the skippers of the map keys and values.
+	mt := x.embedType.(*bsttype.Map)
+	kOpts, vOpts := mapEntryOptions(mt, bstio.ValueOptions{
+		Descending:        x.opts.Descending,
+		Comparable:        x.opts.Comparable,
+		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    x.opts.LengthPrefixedCollections,
+		OrderedFloats:     x.opts.OrderedFloats,
+	})
+	ks, vs := bstskip.ElemSkipFuncOf(mt.Key.Type, kOpts), bstskip.ElemSkipFuncOf(mt.Value.Type, vOpts)
+	start, err := x.r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	// 2. Compare the binary of each key with the previous one.
+	var prev []byte
+	pos := start
+	for i := 0; i <= x.maxIndex; i++ {
+		n, err := ks(x.r, kOpts)
+		if err != nil {
+			return err
+		}
+		if _, err = x.r.Seek(pos, io.SeekStart); err != nil {
+			return err
+		}
+		key := make([]byte, n)
+		if _, err = io.ReadFull(x.r, key); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read map key")
+		}
+		if i > 0 {
+			switch c := bytes.Compare(prev, key); {
+			case c == 0:
+				return bsterr.Err(bsterr.CodeInvalidValue, "duplicate map key").WithDetail("index", i)
+			case c > 0:
+				return bsterr.Err(bsterr.CodeInvalidValue, "map keys are not sorted").WithDetail("index", i)
+			}
+		}
+		prev = key
+		vn, err := vs(x.r, vOpts)
+		if err != nil {
+			return err
+		}
+		pos += n + vn
+	}
+
+	// 3. Seek back to the first entry.
+	_, err = x.r.Seek(start, io.SeekStart)
+	return err
+}
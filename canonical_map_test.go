@@ -0,0 +1,202 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestComposerCanonicalMaps(t *testing.T) {
+	labels := &bsttype.Map{Key: bsttype.MapElement{Type: bsttype.String()}, Value: bsttype.MapElement{Type: bsttype.Uint32()}}
+	indexed := &bsttype.Map{Key: bsttype.MapElement{Type: bsttype.String()}, Value: bsttype.MapElement{Type: bsttype.Uint32()}, Indexed: true}
+	structOf := func(mt *bsttype.Map) *bsttype.Struct {
+		return &bsttype.Struct{Fields: []bsttype.StructField{
+			{Index: 1, Name: "Labels", Type: mt},
+			{Index: 2, Name: "Count", Type: bsttype.Uint32()},
+		}}
+	}
+	values := map[string]uint32{"a": 1, "b": 2, "c": 3}
+	// record composes the struct with the map entries written in given key order.
+	record := func(t *testing.T, st *bsttype.Struct, opts ComposerOptions, optLength int, keys ...string) ([]byte, error) {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		err = c.WriteMap(func(mc *Composer) error {
+			for _, k := range keys {
+				if err := mc.WriteString(k); err != nil {
+					return err
+				}
+				if err := mc.WriteUint32(values[k]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, optLength)
+		if err != nil {
+			return nil, err
+		}
+		if err = c.WriteUint32(uint32(len(keys))); err != nil {
+			t.Fatalf("writing count failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes(), nil
+	}
+	// read reads the map keys, and verifies the trailing field.
+	read := func(st *bsttype.Struct, data []byte, opts ExtractorOptions) ([]string, error) {
+		opts.ExpectedType = st
+		x, err := NewExtractor(bytes.NewReader(data), opts)
+		if err != nil {
+			return nil, err
+		}
+		defer x.Close()
+		var keys []string
+		x.Next()
+		err = x.ReadMap(func(mx *Extractor) error {
+			for mx.Next() {
+				k, err := mx.ReadString()
+				if err != nil {
+					return err
+				}
+				mx.Next()
+				v, err := mx.ReadUint32()
+				if err != nil {
+					return err
+				}
+				if v != values[k] {
+					t.Fatalf("unexpected value of %s: %d", k, v)
+				}
+				keys = append(keys, k)
+			}
+			return mx.Err()
+		})
+		if err != nil {
+			return nil, err
+		}
+		x.Next()
+		if n, err := x.ReadUint32(); err != nil || int(n) != len(keys) {
+			t.Fatalf("unexpected count: %d, %v", n, err)
+		}
+		return keys, nil
+	}
+	hasCode := func(err error, code bsterr.ErrCode) bool {
+		var be *bsterr.Error
+		return errors.As(err, &be) && be.Code == code
+	}
+
+	testCases := []struct {
+		name      string
+		mt        *bsttype.Map
+		optLength int
+		opts      ComposerOptions
+	}{
+		{name: "Plain", mt: labels},
+		{name: "DefinedLength", mt: labels, optLength: 3},
+		{name: "Comparable", mt: labels, opts: ComposerOptions{Comparable: true}},
+		{name: "ComparableDescending", mt: labels, opts: ComposerOptions{Comparable: true, Descending: true}},
+		{name: "CompatibilityMode", mt: labels, opts: ComposerOptions{CompatibilityMode: true}},
+		{name: "LengthPrefixed", mt: labels, optLength: 3, opts: ComposerOptions{CompatibilityMode: true, LengthPrefixedCollections: true}},
+		{name: "Indexed", mt: indexed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := structOf(tc.mt)
+			xOpts := ExtractorOptions{
+				Descending:                tc.opts.Descending,
+				Comparable:                tc.opts.Comparable,
+				CompatibilityMode:         tc.opts.CompatibilityMode,
+				LengthPrefixedCollections: tc.opts.LengthPrefixedCollections,
+			}
+
+			// 1. The canonical binary doesn't depend on the order of the written entries.
+			opts := tc.opts
+			opts.CanonicalMaps = true
+			data, err := record(t, st, opts, tc.optLength, "c", "a", "b")
+			if err != nil {
+				t.Fatalf("writing map failed: %v", err)
+			}
+			other, err := record(t, st, opts, tc.optLength, "b", "c", "a")
+			if err != nil {
+				t.Fatalf("writing map failed: %v", err)
+			}
+			if !bytes.Equal(data, other) {
+				t.Fatalf("canonical binaries differ: %x != %x", data, other)
+			}
+
+			// 2. The canonical map is verified, and read in the order of the key binaries.
+			expected := "abc"
+			if tc.opts.Descending {
+				expected = "cba"
+			}
+			canonical := xOpts
+			canonical.CanonicalMaps = true
+			keys, err := read(st, data, canonical)
+			if err != nil {
+				t.Fatalf("reading canonical map failed: %v", err)
+			}
+			if len(keys) != 3 || keys[0]+keys[1]+keys[2] != expected {
+				t.Fatalf("unexpected keys order: %v", keys)
+			}
+
+			// 3. The map written out of order is read, but fails the verification.
+			unsorted, err := record(t, st, tc.opts, tc.optLength, "c", "a", "b")
+			if err != nil {
+				t.Fatalf("writing map failed: %v", err)
+			}
+			if _, err = read(st, unsorted, xOpts); err != nil {
+				t.Fatalf("reading unsorted map failed: %v", err)
+			}
+			if _, err = read(st, unsorted, canonical); !hasCode(err, bsterr.CodeInvalidValue) {
+				t.Fatalf("expected unsorted map error, got: %v", err)
+			}
+
+			// 4. The duplicate keys are rejected.
+			if _, err = record(t, st, opts, 0, "a", "b", "a"); !hasCode(err, bsterr.CodeInvalidValue) {
+				t.Fatalf("expected duplicate key error, got: %v", err)
+			}
+		})
+	}
+
+	t.Run("IndexedLookup", func(t *testing.T) {
+		st := structOf(indexed)
+		data, err := record(t, st, ComposerOptions{CanonicalMaps: true}, 0, "c", "a", "b")
+		if err != nil {
+			t.Fatalf("writing map failed: %v", err)
+		}
+		for _, k := range []string{"a", "b", "c"} {
+			x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: st})
+			if err != nil {
+				t.Fatalf("creating extractor failed: %v", err)
+			}
+			x.Next()
+			var v uint32
+			found, err := x.ReadMapKey(bstvalue.NewStringValue(k), func(mx *Extractor) error {
+				v, err = mx.ReadUint32()
+				return err
+			})
+			if err != nil || !found || v != values[k] {
+				t.Fatalf("unexpected value of %s: %d, %v, %v", k, v, found, err)
+			}
+			x.Close()
+		}
+	})
+
+	t.Run("Booleans", func(t *testing.T) {
+		mt := &bsttype.Map{Key: bsttype.MapElement{Type: bsttype.Boolean()}, Value: bsttype.MapElement{Type: bsttype.Boolean()}}
+		c, err := NewComposer(&bytes.Buffer{}, structOf(mt), ComposerOptions{CanonicalMaps: true})
+		if err == nil {
+			err = c.WriteMap(func(mc *Composer) error { return mc.WriteBoolean(true) }, 0)
+		}
+		if !hasCode(err, bsterr.CodeInvalidType) {
+			t.Fatalf("expected boolean canonical map error, got: %v", err)
+		}
+	})
+}
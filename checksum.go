@@ -0,0 +1,162 @@
+package bst
+
+import (
+	"bytes"
+	"hash"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+)
+
+// The value of the composer with the Checksum option is flagged by the low 4 bits of the header extension byte,
+// which follows the header with its 7th bit set. The checksum trailer follows the value binary - it is the big-endian checksum of the value,
+// including its header, the checksum kind and the embedded type.
+
+// valueChecksum is the root writer of the composer with the Checksum option, which hashes the written binary.
+// The checksum trailer is written on the composer close.
+type valueChecksum struct {
+	w    io.Writer
+	kind bstio.ChecksumKind
+	h    hash.Hash
+}
+
+// Write writes the p to the underlying writer, and hashes the written part.
+// Implements io.Writer interface.
+func (x *valueChecksum) Write(p []byte) (int, error) {
+	n, err := x.w.Write(p)
+	x.h.Write(p[:n])
+	return n, err
+}
+
+// reset prepares the checksum of given kind, writing to w.
+func (x *valueChecksum) reset(w io.Writer, kind bstio.ChecksumKind) error {
+	h, err := kind.New()
+	if err != nil {
+		return err
+	}
+	*x = valueChecksum{w: w, kind: kind, h: h}
+	return nil
+}
+
+// writeTrailer writes the checksum trailer of the hashed binary.
+func (x *valueChecksum) writeTrailer() (int, error) {
+	n, err := x.w.Write(x.h.Sum(nil))
+	if err != nil {
+		return n, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write checksum trailer")
+	}
+	return n, nil
+}
+
+// readHeaderExtension reads the header extension, with the kinds of the checksum trailer and the body compression.
+func (x *Extractor) readHeaderExtension() error {
+	b, err := bstio.ReadByte(x.r)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read header extension")
+	}
+	x.bytesRead++
+
+	// 1. The low 4 bits are the checksum kind.
+	checksum := bstio.ChecksumKind(b & 0x0f)
+	if checksum != bstio.ChecksumNone && checksum.Size() == 0 {
+		return bsterr.Err(bsterr.CodeChecksumMismatch, "unknown checksum kind").WithDetail("kind", uint8(checksum))
+	}
+
+	// 2. The high 4 bits are the compression kind, whose top bit is set if the embedded type is compressed as well.
+	compression := bstio.CompressionKind((b >> 4) &^ (compressedHeaderFlag >> 4))
+	if !compression.Known() {
+		return bsterr.Err(bsterr.CodeMalformedBinary, "unknown compression kind").WithDetail("kind", uint8(compression))
+	}
+	compressedHeader := b&compressedHeaderFlag != 0
+	if compressedHeader && compression == bstio.CompressionNone {
+		return bsterr.Err(bsterr.CodeMalformedBinary, "compressed embedded type flag set without the compression")
+	}
+	x.checksum, x.compression, x.compressedHeader = checksum, compression, compressedHeader
+	return nil
+}
+
+// VerifyAndClose verifies the checksum trailer of the value, and closes the extractor - see the Close.
+// The elements of the value which were not extracted yet are skipped, to find the trailer following the value,
+// and the value binary is then read once more to compute its checksum. Once verified, the reader is positioned
+// just after the trailer, thus the values with checksums could be read one after another.
+// The value without the checksum passes the verification, unless the RequireChecksum option is set.
+// It could not be used with the Streaming option, as the value binary is read twice.
+func (x *Extractor) VerifyAndClose() error {
+	defer x.Close()
+	return x.verifyChecksum()
+}
+
+// verifyChecksum verifies the checksum trailer which follows the value binary.
+func (x *Extractor) verifyChecksum() error {
+	// 1. Check if the value has the checksum.
+	if x.checksum == bstio.ChecksumNone {
+		if x.opts.RequireChecksum {
+			return bsterr.Err(bsterr.CodeChecksumMismatch, "checksum trailer not found")
+		}
+		return nil
+	}
+	if x.opts.Streaming {
+		return bsterr.Err(bsterr.CodeInvalidValue, "checksum could not be verified while streaming")
+	}
+
+	// 2. Skip the remaining elements, so that the trailer follows.
+	//    The trailer of the compressed value follows its compressed body, which was already read.
+	if x.compressed == nil {
+		if err := x.skipRemaining(); err != nil {
+			return err
+		}
+	}
+
+	// 3. Read the trailer. The value is hashed out of the root reader, so that it doesn't use the decode budget.
+	r := rootReader(x.r)
+	if x.compressed != nil {
+		r = rootReader(x.compressed)
+	}
+	end, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to locate checksum trailer")
+	}
+	expected := make([]byte, x.checksum.Size())
+	if _, err = io.ReadFull(r, expected); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeChecksumMismatch, "checksum trailer not found").
+			WithDetail("offset", end)
+	}
+
+	// 4. Hash the value binary once more, and compare the checksums.
+	h, err := x.checksum.New()
+	if err != nil {
+		return err
+	}
+	start := int64(x.startOffset)
+	if _, err = r.Seek(start, io.SeekStart); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to seek value start")
+	}
+	if _, err = io.CopyN(h, r, end-start); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read value")
+	}
+	if !bytes.Equal(h.Sum(nil), expected) {
+		return bsterr.Err(bsterr.CodeChecksumMismatch, "value checksum mismatch").
+			WithDetails(bsterr.D("kind", x.checksum), bsterr.D("offset", start), bsterr.D("size", end-start))
+	}
+
+	// 5. Position the reader after the trailer.
+	if _, err = r.Seek(end+int64(len(expected)), io.SeekStart); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to seek checksum trailer end")
+	}
+	return nil
+}
+
+// skipRemaining skips the remaining elements of the value, along with the current one if it was not extracted.
+func (x *Extractor) skipRemaining() error {
+	if !x.elemDone && x.index >= 0 && x.index <= x.maxIndex {
+		if _, err := x.Skip(); err != nil {
+			return err
+		}
+	}
+	for x.Next() {
+		if _, err := x.Skip(); err != nil {
+			return err
+		}
+	}
+	return x.err
+}
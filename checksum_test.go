@@ -0,0 +1,205 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestComposerChecksum(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "name", Type: bsttype.String()},
+		{Index: 2, Name: "count", Type: bsttype.Uint32()},
+	}}
+	keys := &SignatureKeys{CurrentID: 1, Keys: map[uint32]SigningKey{
+		1: {Algorithm: SignHMACSHA256, Key: []byte("secret")},
+	}}
+	// compose writes the struct value with given options.
+	compose := func(t *testing.T, opts ComposerOptions) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteString("checked"); err != nil {
+			t.Fatalf("writing string failed: %v", err)
+		}
+		if err = c.WriteUint32(42); err != nil {
+			t.Fatalf("writing uint32 failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		if c.bytesWritten != buf.Len() {
+			t.Fatalf("unexpected bytes written: %d != %d", c.bytesWritten, buf.Len())
+		}
+		return buf.Bytes()
+	}
+	// verify reads the name of the struct value out of r, and verifies the checksum of the value.
+	verify := func(r *bytes.Reader, opts ExtractorOptions) error {
+		opts.ExpectedType = st
+		x, err := NewExtractor(r, opts)
+		if err != nil {
+			return err
+		}
+		if !x.Next() {
+			return x.Err()
+		}
+		name, err := x.ReadString()
+		if err != nil {
+			return err
+		}
+		if name != "checked" {
+			t.Fatalf("unexpected name: %q", name)
+		}
+		return x.VerifyAndClose()
+	}
+	hasCode := func(err error, code bsterr.ErrCode) bool {
+		var be *bsterr.Error
+		return errors.As(err, &be) && be.Code == code
+	}
+
+	testCases := []struct {
+		name  string
+		opts  ComposerOptions
+		xOpts ExtractorOptions
+	}{
+		{name: "Plain"},
+		{name: "CompatibilityMode", opts: ComposerOptions{CompatibilityMode: true}},
+		{name: "EmbedType", opts: ComposerOptions{EmbedType: true}},
+		{name: "MaxValueBytes", opts: ComposerOptions{MaxValueBytes: 64}},
+		{name: "Budget", xOpts: ExtractorOptions{Budget: NewDecodeBudget(1024, 0)}},
+		{name: "Signature", opts: ComposerOptions{Signature: keys}, xOpts: ExtractorOptions{Signature: keys}},
+	}
+	for _, kind := range []bstio.ChecksumKind{bstio.ChecksumCRC32C, bstio.ChecksumXXHash64} {
+		for _, tc := range testCases {
+			t.Run(kind.String()+"/"+tc.name, func(t *testing.T) {
+				opts := tc.opts
+				opts.Checksum = kind
+				data := compose(t, opts)
+				plain := compose(t, tc.opts)
+				size := len(plain) + 1 + kind.Size()
+				if len(data) != size {
+					t.Fatalf("unexpected value size: %d != %d", len(data), size)
+				}
+
+				// 1. The checksum is verified, also of the value partially read.
+				xOpts := tc.xOpts
+				xOpts.RequireChecksum = true
+				if err := verify(bytes.NewReader(data), xOpts); err != nil {
+					t.Fatalf("verifying checksum failed: %v", err)
+				}
+
+				// 2. The value without the checksum fails only if it is required.
+				if err := verify(bytes.NewReader(plain), tc.xOpts); err != nil {
+					t.Fatalf("verifying value without checksum failed: %v", err)
+				}
+				if err := verify(bytes.NewReader(plain), xOpts); !hasCode(err, bsterr.CodeChecksumMismatch) {
+					t.Fatalf("expected missing checksum error, got: %v", err)
+				}
+				if tc.opts.Signature != nil {
+					return
+				}
+
+				// 3. The corrupted value fails the verification.
+				corrupted := bytes.Clone(data)
+				corrupted[len(corrupted)-kind.Size()-1] ^= 0xff
+				if err := verify(bytes.NewReader(corrupted), xOpts); !hasCode(err, bsterr.CodeChecksumMismatch) {
+					t.Fatalf("expected checksum mismatch error, got: %v", err)
+				}
+				if err := verify(bytes.NewReader(data[:len(data)-1]), xOpts); !hasCode(err, bsterr.CodeChecksumMismatch) {
+					t.Fatalf("expected truncated checksum error, got: %v", err)
+				}
+
+				// 4. The verified values are read one after another.
+				r := bytes.NewReader(append(bytes.Clone(data), data...))
+				for i := 0; i < 2; i++ {
+					if err := verify(r, xOpts); err != nil {
+						t.Fatalf("verifying %d value checksum failed: %v", i, err)
+					}
+				}
+				if r.Len() != 0 {
+					t.Fatalf("unexpected remaining bytes: %d", r.Len())
+				}
+			})
+		}
+	}
+
+	t.Run("Roots", func(t *testing.T) {
+		var buf bytes.Buffer
+		opts := ComposerOptions{Checksum: bstio.ChecksumCRC32C}
+		at := &bsttype.Array{Type: bsttype.String()}
+		c, err := NewComposer(&buf, at, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		for _, s := range []string{"a", "b", "c"} {
+			if err = c.WriteString(s); err != nil {
+				t.Fatalf("writing string failed: %v", err)
+			}
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		if err = c.ResetOn(&buf, bsttype.Uint64(), opts); err != nil {
+			t.Fatalf("resetting composer failed: %v", err)
+		}
+		if err = c.WriteUint64(7); err != nil {
+			t.Fatalf("writing uint64 failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+
+		// The array is skipped entirely, and the basic value follows its trailer.
+		r := bytes.NewReader(buf.Bytes())
+		x, err := NewExtractor(r, ExtractorOptions{ExpectedType: at})
+		if err != nil {
+			t.Fatalf("creating extractor failed: %v", err)
+		}
+		if err = x.VerifyAndClose(); err != nil {
+			t.Fatalf("verifying array checksum failed: %v", err)
+		}
+		if err = x.ResetTo(r, ExtractorOptions{ExpectedType: bsttype.Uint64()}); err != nil {
+			t.Fatalf("resetting extractor failed: %v", err)
+		}
+		x.Next()
+		if v, err := x.ReadUint64(); err != nil || v != 7 {
+			t.Fatalf("unexpected value: %d, %v", v, err)
+		}
+		if err = x.VerifyAndClose(); err != nil {
+			t.Fatalf("verifying uint64 checksum failed: %v", err)
+		}
+		if r.Len() != 0 {
+			t.Fatalf("unexpected remaining bytes: %d", r.Len())
+		}
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		if _, err := NewOptions(ForIndexKey(), WithChecksum(bstio.ChecksumCRC32C)); !hasCode(err, bsterr.CodeInvalidValue) {
+			t.Fatalf("expected comparable checksum error, got: %v", err)
+		}
+		if _, err := NewComposer(&bytes.Buffer{}, st, ComposerOptions{Checksum: bstio.ChecksumCRC32C, Comparable: true}); !hasCode(err, bsterr.CodeInvalidValue) {
+			t.Fatalf("expected comparable checksum error, got: %v", err)
+		}
+		if _, err := NewComposer(&bytes.Buffer{}, st, ComposerOptions{Checksum: 9}); !hasCode(err, bsterr.CodeInvalidValue) {
+			t.Fatalf("expected unknown checksum error, got: %v", err)
+		}
+		o, err := NewOptions(WithChecksum(bstio.ChecksumXXHash64))
+		if err != nil {
+			t.Fatalf("creating options failed: %v", err)
+		}
+		data := compose(t, o.ComposerOptions())
+		if err = verify(bytes.NewReader(data), o.ExtractorOptions(st)); err != nil {
+			t.Fatalf("verifying checksum failed: %v", err)
+		}
+		if err = verify(bytes.NewReader(data), ExtractorOptions{Streaming: true}); !hasCode(err, bsterr.CodeInvalidValue) {
+			t.Fatalf("expected streaming error, got: %v", err)
+		}
+	})
+}
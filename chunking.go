@@ -0,0 +1,147 @@
+package bst
+
+import (
+	"math/bits"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+)
+
+// Default content-defined chunk sizes.
+const (
+	DefaultChunkMinSize = 2 << 10
+	DefaultChunkAvgSize = 8 << 10
+	DefaultChunkMaxSize = 64 << 10
+)
+
+// ChunkingOptions are the options of the content-defined chunking. The zero sizes are replaced by their defaults.
+type ChunkingOptions struct {
+	// MinSize is the minimum size of the chunk, other than the last one.
+	MinSize int
+	// AvgSize is the expected average size of the chunk. It is rounded down to the power of two.
+	AvgSize int
+	// MaxSize is the maximum size of the chunk.
+	MaxSize int
+}
+
+// withDefaults returns the options with the zero sizes replaced by their defaults.
+func (x ChunkingOptions) withDefaults() ChunkingOptions {
+	if x.MinSize <= 0 {
+		x.MinSize = DefaultChunkMinSize
+	}
+	if x.AvgSize <= 0 {
+		x.AvgSize = DefaultChunkAvgSize
+	}
+	if x.MaxSize <= 0 {
+		x.MaxSize = DefaultChunkMaxSize
+	}
+	return x
+}
+
+// validate checks that the chunk sizes are ordered.
+func (x ChunkingOptions) validate() error {
+	if x.MinSize > x.AvgSize || x.AvgSize > x.MaxSize {
+		return bsterr.Err(bsterr.CodeInvalidValue, "chunk sizes need to satisfy min <= avg <= max").
+			WithDetails(bsterr.D("min", x.MinSize), bsterr.D("avg", x.AvgSize), bsterr.D("max", x.MaxSize))
+	}
+	return nil
+}
+
+// _gearTable is the table of the random values of the gear rolling hash, generated with the SplitMix64,
+// so that the chunk boundaries are stable across the releases.
+var _gearTable = func() (table [256]uint64) {
+	seed := uint64(0x6273742d63646321)
+	for i := range table {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// SplitChunks splits the data into the content-defined chunks, using the gear rolling hash. As the chunk boundaries
+// depend only on the content nearby, an insertion or removal in a large payload changes only the chunks around it,
+// and the rest of the chunks are deduplicated by their blob references.
+// The chunks are the sub-slices of the data.
+func SplitChunks(data []byte, options ChunkingOptions) ([][]byte, error) {
+	options = options.withDefaults()
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	// 1. The boundary is found where the highest bits of the hash are zero, with the number of bits given by
+	//    the average chunk size. The highest bits depend on the last 64 bytes.
+	mask := ^uint64(0) << (64 - (bits.Len(uint(options.AvgSize)) - 1))
+
+	var chunks [][]byte
+	for len(data) > 0 {
+		// 2. The last chunk might be shorter than the minimum size.
+		if len(data) <= options.MinSize {
+			chunks = append(chunks, data)
+			break
+		}
+
+		// 3. Roll the hash from the minimum size, until the boundary or the maximum size is found.
+		end := min(len(data), options.MaxSize)
+		cut := end
+		var h uint64
+		for i := options.MinSize; i < end; i++ {
+			h = (h << 1) + _gearTable[data[i]]
+			if h&mask == 0 {
+				cut = i + 1
+				break
+			}
+		}
+		chunks = append(chunks, data[:cut])
+		data = data[cut:]
+	}
+	return chunks, nil
+}
+
+// PutChunks splits the data into the content-defined chunks, stores them in the blob store and returns their
+// references in order. The references could be written as the ExternalBytes values, i.e. as the array elements.
+// The chunks already present in the store are deduplicated by the store.
+func PutChunks(store BlobStore, data []byte, options ChunkingOptions) ([]bstio.BlobRef, error) {
+	// 1. Split the data into chunks.
+	chunks, err := SplitChunks(data, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Store each chunk by its reference.
+	refs := make([]bstio.BlobRef, len(chunks))
+	for i, chunk := range chunks {
+		refs[i] = bstio.NewBlobRef(chunk)
+		if err = store.PutBlob(refs[i], chunk); err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to store chunk").WithDetail("ref", refs[i])
+		}
+	}
+	return refs, nil
+}
+
+// GetChunks fetches the chunks of given references from the blob store, and joins them back into the data.
+// Each chunk is verified against its reference.
+func GetChunks(store BlobStore, refs []bstio.BlobRef) ([]byte, error) {
+	// 1. Allocate the data of the total size.
+	var size uint64
+	for _, ref := range refs {
+		size += ref.Size
+	}
+	data := make([]byte, 0, size)
+
+	// 2. Fetch and verify each chunk.
+	for _, ref := range refs {
+		chunk, err := store.GetBlob(ref)
+		if err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to fetch chunk").WithDetail("ref", ref)
+		}
+		if !ref.Matches(chunk) {
+			return nil, bsterr.Err(bsterr.CodeMalformedBinary, "chunk doesn't match its reference").
+				WithDetails(bsterr.D("ref", ref), bsterr.D("size", len(chunk)))
+		}
+		data = append(data, chunk...)
+	}
+	return data, nil
+}
@@ -0,0 +1,69 @@
+package bst
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/devmodules/bst/bstio"
+)
+
+func TestChunks(t *testing.T) {
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	// 1. The chunks are within the size limits and compose the data.
+	chunks, err := SplitChunks(data, ChunkingOptions{})
+	if err != nil {
+		t.Fatalf("splitting chunks failed: %v", err)
+	}
+	if len(chunks) < 16 {
+		t.Fatalf("too few chunks: %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > DefaultChunkMaxSize || (i < len(chunks)-1 && len(chunk) < DefaultChunkMinSize) {
+			t.Fatalf("chunk %d size out of limits: %d", i, len(chunk))
+		}
+	}
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("chunks don't compose the data")
+	}
+
+	// 2. The edited data shares most of the chunks with the original one.
+	store := NewMemoryBlobStore()
+	refs, err := PutChunks(store, data, ChunkingOptions{})
+	if err != nil {
+		t.Fatalf("storing chunks failed: %v", err)
+	}
+	edited := append(append(append([]byte(nil), data[:500000]...), "inserted attachment text"...), data[500000:]...)
+	editedRefs, err := PutChunks(store, edited, ChunkingOptions{})
+	if err != nil {
+		t.Fatalf("storing chunks failed: %v", err)
+	}
+	if added := store.Len() - len(refs); added > 3 {
+		t.Fatalf("edited data added %d chunks out of %d", added, len(editedRefs))
+	}
+
+	// 3. The chunks are fetched back.
+	for _, tc := range []struct {
+		refs     []bstio.BlobRef
+		expected []byte
+	}{{refs, data}, {editedRefs, edited}} {
+		got, err := GetChunks(store, tc.refs)
+		if err != nil {
+			t.Fatalf("fetching chunks failed: %v", err)
+		}
+		if !bytes.Equal(got, tc.expected) {
+			t.Fatal("fetched chunks don't match the data")
+		}
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := SplitChunks(data, ChunkingOptions{MinSize: 100, AvgSize: 10, MaxSize: 1000}); err == nil {
+			t.Fatal("expected unordered chunk sizes to fail")
+		}
+		if _, err := GetChunks(NewMemoryBlobStore(), refs); err == nil {
+			t.Fatal("expected missing chunks to fail")
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package bst
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+// FieldPath is the path of a possibly nested struct field, composed of the field names separated with dots,
+// i.e. "Address.City".
+type FieldPath string
+
+// split returns the name of the first field of the path, and the path of the nested field if any.
+func (p FieldPath) split() (string, FieldPath, bool) {
+	head, rest, nested := strings.Cut(string(p), ".")
+	return head, FieldPath(rest), nested
+}
+
+// ValueWriter writes the binary of the overriding field value, with the options of the field.
+// All the bstvalue.Value implementations satisfy it.
+type ValueWriter = bstvalue.ValueWriter
+
+// ValueWriterFunc is the function adapter of the ValueWriter.
+type ValueWriterFunc func(w io.Writer, options bstio.ValueOptions) (int, error)
+
+// WriteValue calls the function.
+// Implements the ValueWriter interface.
+func (fn ValueWriterFunc) WriteValue(w io.Writer, options bstio.ValueOptions) (int, error) {
+	return fn(w, options)
+}
+
+// CloneWith copies the headless binary of the struct type t, encoded with given options, replacing the fields
+// defined in the overrides with the binaries written by their writers. All other fields are copied verbatim,
+// without being decoded. The nested struct fields are addressed by their dotted path.
+// In the compatibility mode the overridden fields missing in the input are added to the copy.
+// The fields with non-plain encodings, other than in the comparable binaries, could not be overridden.
+func CloneWith(in []byte, t bsttype.Type, overrides map[FieldPath]ValueWriter, options bstio.ValueOptions) ([]byte, error) {
+	// 1. Turn the writers into the field rewrites, which ignore the input field binary.
+	rewrites := make(map[FieldPath]fieldRewrite, len(overrides))
+	for path, vw := range overrides {
+		if vw == nil {
+			return nil, bsterr.Err(bsterr.CodeInvalidValue, "clone override writer is not defined").
+				WithDetail("path", path)
+		}
+		rewrites[path] = func(_ bsttype.Type, _ []byte, fo bstio.ValueOptions) ([]byte, error) {
+			var buf bytes.Buffer
+			if _, err := vw.WriteValue(&buf, fo); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		}
+	}
+
+	// 2. Rewrite the struct binary.
+	var buf bytes.Buffer
+	if err := rewriteStruct(&buf, in, t, rewrites, options); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fieldRewrite returns the new binary of the struct field of type t, written with the field options fo.
+// The data is nil if the field is not present in the compatibility mode binary, and the nil result keeps it absent.
+type fieldRewrite func(t bsttype.Type, data []byte, fo bstio.ValueOptions) ([]byte, error)
+
+// rewriteStruct writes the headless struct binary, with the fields at given paths rewritten.
+// All other fields are copied verbatim.
+func rewriteStruct(w *bytes.Buffer, in []byte, t bsttype.Type, rewrites map[FieldPath]fieldRewrite, o bstio.ValueOptions) error {
+	// 1. Dereference the named type and verify that it is a struct.
+	st, ok := derefNamedType(t).(*bsttype.Struct)
+	if !ok {
+		return bsterr.Err(bsterr.CodeInvalidType, "field rewrites are supported only for struct types").
+			WithDetail("type", t)
+	}
+
+	// 2. Without rewrites, the binary is copied as a whole.
+	if len(rewrites) == 0 {
+		w.Write(in)
+		return nil
+	}
+
+	// 3. Group the rewrites by the struct fields, and validate them.
+	direct, nested, err := groupFieldRewrites(st, rewrites, o)
+	if err != nil {
+		return err
+	}
+
+	// 4. Split the value into the field segments.
+	segments, err := splitStructSegmentsOf(in, st, o)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to split struct value")
+	}
+
+	// 5. Replace the segments of the rewritten fields.
+	for pos, f := range st.Fields {
+		fn, isDirect := direct[f.Name]
+		sub, isNested := nested[f.Name]
+		if !isDirect && !isNested {
+			continue
+		}
+
+		index := structSegmentIndex(f, pos, o)
+		si := findRewriteSegment(segments, index, pos, o)
+		fo := structFieldOptions(f, o)
+
+		var data []byte
+		switch {
+		case isDirect && si >= 0 && !o.CompatibilityMode && f.Type.Kind() == bsttype.KindBoolean:
+			data, err = rewritePackedBoolean(segments[si], pos, f.Type, fn, fo)
+		case isDirect && si >= 0:
+			data, err = fn(f.Type, segments[si].data, fo)
+		case isDirect:
+			data, err = fn(f.Type, nil, fo)
+			if err == nil && data == nil {
+				continue
+			}
+		case si < 0:
+			return bsterr.Err(bsterr.CodeUndefinedValue, "nested struct field is not present in the value").
+				WithDetail("field", f.Name)
+		default:
+			// The nested struct keeps the compatibility mode, and flips the order of the descending field.
+			no := o
+			no.Descending = fo.Descending
+			var nb bytes.Buffer
+			err = rewriteStruct(&nb, segments[si].data, f.Type, sub, no)
+			data = nb.Bytes()
+		}
+		if err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to rewrite struct field").
+				WithDetail("field", f.Name)
+		}
+
+		if si >= 0 {
+			segments[si].data = data
+		} else {
+			segments = append(segments, structSegment{index: index, count: 1, data: data})
+		}
+	}
+
+	// 6. Write the segments. In the compatibility mode the added fields are sorted by their indices.
+	if o.CompatibilityMode {
+		sort.Slice(segments, func(i, j int) bool { return segments[i].index < segments[j].index })
+		return writeStructSegments(w, segments)
+	}
+	for _, sg := range segments {
+		w.Write(sg.data)
+	}
+	return nil
+}
+
+// groupFieldRewrites splits the rewrites into the rewrites of the struct fields and the rewrites of the nested struct fields.
+func groupFieldRewrites(st *bsttype.Struct, rewrites map[FieldPath]fieldRewrite, o bstio.ValueOptions) (map[string]fieldRewrite, map[string]map[FieldPath]fieldRewrite, error) {
+	direct := make(map[string]fieldRewrite)
+	nested := make(map[string]map[FieldPath]fieldRewrite)
+	for path, fn := range rewrites {
+		// 1. Find the rewritten field.
+		name, rest, isNested := path.split()
+		f, _, found := st.FieldByName(name)
+		if !found {
+			return nil, nil, bsterr.Err(bsterr.CodeInvalidValue, "struct field path not found").
+				WithDetail("path", path)
+		}
+
+		// 2. The encoded fields could not be spliced, as their binary differs from the value binary.
+		if !o.Comparable && f.Encoding != bsttype.FieldEncodingPlain {
+			return nil, nil, bsterr.Err(bsterr.CodeInvalidType, "encoded struct fields could not be rewritten").
+				WithDetails(bsterr.D("path", path), bsterr.D("encoding", f.Encoding))
+		}
+
+		// 3. Collect the rewrite, the field could be rewritten either as a whole or by its nested fields.
+		if !isNested {
+			direct[name] = fn
+		} else {
+			if _, ok := derefNamedType(f.Type).(*bsttype.Struct); !ok {
+				return nil, nil, bsterr.Err(bsterr.CodeInvalidType, "nested field path parent is not a struct").
+					WithDetails(bsterr.D("path", path), bsterr.D("type", f.Type))
+			}
+			if nested[name] == nil {
+				nested[name] = make(map[FieldPath]fieldRewrite)
+			}
+			nested[name][rest] = fn
+		}
+		if _, ok := direct[name]; ok && nested[name] != nil {
+			return nil, nil, bsterr.Err(bsterr.CodeInvalidValue, "both the field and its nested fields are rewritten").
+				WithDetail("field", name)
+		}
+	}
+	return direct, nested, nil
+}
+
+// findRewriteSegment returns the position of the segment containing the field, or -1 if the field is not present.
+// In the regular mode the packed boolean segments contain multiple fields.
+func findRewriteSegment(segments []structSegment, index uint, pos int, o bstio.ValueOptions) int {
+	for i, sg := range segments {
+		if o.CompatibilityMode && sg.index == index {
+			return i
+		}
+		if !o.CompatibilityMode && uint(pos) >= sg.index && uint(pos) < sg.index+uint(sg.count) {
+			return i
+		}
+	}
+	return -1
+}
+
+// rewritePackedBoolean rewrites the bit of the boolean field within the copy of the packed booleans segment.
+// In the regular mode even a single boolean field is written as the packed bit. As the bit is inverted by the field order
+// just as the boolean binary is, the bit value is the same as its single byte boolean binary.
+func rewritePackedBoolean(sg structSegment, pos int, t bsttype.Type, fn fieldRewrite, fo bstio.ValueOptions) ([]byte, error) {
+	// 1. Rewrite the single byte binary of the boolean.
+	bit := pos - int(sg.index)
+	in := bstio.BoolFalse
+	if sg.data[bit>>3]&(1<<(bit&7)) != 0 {
+		in = bstio.BoolTrue
+	}
+	bin, err := fn(t, []byte{in}, fo)
+	if err != nil {
+		return nil, err
+	}
+	if len(bin) != 1 || (bin[0] != bstio.BoolTrue && bin[0] != bstio.BoolFalse) {
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "invalid boolean field binary").
+			WithDetail("binary", bin)
+	}
+
+	// 2. Set the bit within the copy of the packed booleans.
+	data := append([]byte(nil), sg.data...)
+	if bin[0] == bstio.BoolTrue {
+		data[bit>>3] |= 1 << (bit & 7)
+	} else {
+		data[bit>>3] &^= 1 << (bit & 7)
+	}
+	return data, nil
+}
@@ -0,0 +1,143 @@
+package bst
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestCloneWith(t *testing.T) {
+	at := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "City", Type: bsttype.String()},
+			{Index: 2, Name: "Zip", Type: bsttype.Uint32()},
+		},
+	}
+	st := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "Name", Type: bsttype.String()},
+			{Index: 2, Name: "Active", Type: bsttype.Boolean()},
+			{Index: 3, Name: "Admin", Type: bsttype.Boolean(), Descending: true},
+			{Index: 4, Name: "Address", Type: at},
+			{Index: 5, Name: "Score", Type: bsttype.Int64(), Descending: true},
+		},
+	}
+
+	row := func(t *testing.T, name string, active, admin bool, city string, score int64, o bstio.ValueOptions) []byte {
+		sv := bstvalue.MustNewStructValue(st, []bstvalue.Value{
+			bstvalue.NewStringValue(name),
+			bstvalue.NewBoolValue(active),
+			bstvalue.NewBoolValue(admin),
+			bstvalue.MustNewStructValue(at, []bstvalue.Value{
+				bstvalue.NewStringValue(city),
+				bstvalue.NewUint32Value(12345),
+			}),
+			bstvalue.NewInt64Value(score),
+		})
+		data, err := sv.MarshalValue(o)
+		if err != nil {
+			t.Fatalf("marshaling row failed: %v", err)
+		}
+		return data
+	}
+
+	for _, o := range []bstio.ValueOptions{{}, {Descending: true}} {
+		in := row(t, "john", true, false, "Warsaw", 10, o)
+
+		got, err := CloneWith(in, st, map[FieldPath]ValueWriter{
+			"Name":         bstvalue.NewStringValue("jane"),
+			"Admin":        bstvalue.NewBoolValue(true),
+			"Address.City": bstvalue.NewStringValue("Cracow"),
+			"Score": ValueWriterFunc(func(w io.Writer, options bstio.ValueOptions) (int, error) {
+				if options.Descending == o.Descending {
+					t.Errorf("score writer got wrong order: %v", options.Descending)
+				}
+				return bstvalue.NewInt64Value(20).WriteValue(w, options)
+			}),
+		}, o)
+		if err != nil {
+			t.Fatalf("clone failed: %v", err)
+		}
+
+		expected := row(t, "jane", true, true, "Cracow", 20, o)
+		if !bytes.Equal(got, expected) {
+			t.Fatalf("unexpected clone (descending: %v):\n%v\nexpected:\n%v", o.Descending, got, expected)
+		}
+
+		t.Run("NoOverrides", func(t *testing.T) {
+			got, err = CloneWith(in, st, nil, o)
+			if err != nil {
+				t.Fatalf("clone failed: %v", err)
+			}
+			if !bytes.Equal(got, in) {
+				t.Fatalf("unexpected clone:\n%v\nexpected:\n%v", got, in)
+			}
+		})
+	}
+
+	t.Run("Compatibility", func(t *testing.T) {
+		ct := &bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "A", Type: bsttype.Uint8()},
+				{Index: 2, Name: "B", Type: bsttype.Uint8()},
+				{Index: 3, Name: "C", Type: bsttype.Uint8()},
+			},
+		}
+		// The row missing the field B is composed with the older revision of the type.
+		in := composeCompatibilityRow(t, &bsttype.Struct{Fields: []bsttype.StructField{ct.Fields[0], ct.Fields[2]}}, 5, 7)
+		got, err := CloneWith(in, ct, map[FieldPath]ValueWriter{
+			"B": bstvalue.NewUint8Value(3),
+			"C": bstvalue.NewUint8Value(9),
+		}, bstio.ValueOptions{CompatibilityMode: true})
+		if err != nil {
+			t.Fatalf("clone failed: %v", err)
+		}
+
+		expected := composeCompatibilityRow(t, ct, 5, 3, 9)
+		if !bytes.Equal(got, expected) {
+			t.Fatalf("unexpected clone:\n%v\nexpected:\n%v", got, expected)
+		}
+
+		// The last field of the full composed row is kept, and the header matches the composed one.
+		got, err = CloneWith(composeCompatibilityRow(t, ct, 1, 2, 3), ct, map[FieldPath]ValueWriter{
+			"B": bstvalue.NewUint8Value(9),
+		}, bstio.ValueOptions{CompatibilityMode: true})
+		if err != nil {
+			t.Fatalf("clone failed: %v", err)
+		}
+		if expected = composeCompatibilityRow(t, ct, 1, 9, 3); !bytes.Equal(got, expected) {
+			t.Fatalf("unexpected clone:\n%v\nexpected:\n%v", got, expected)
+		}
+		if fields := extractCompatibilityRow(t, ct, got); len(fields) != 3 || fields["C"] != 3 {
+			t.Fatalf("unexpected cloned fields: %v", fields)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		in := row(t, "john", true, false, "Warsaw", 10, bstio.ValueOptions{})
+		tests := []struct {
+			name      string
+			overrides map[FieldPath]ValueWriter
+		}{
+			{name: "UnknownField", overrides: map[FieldPath]ValueWriter{"Unknown": bstvalue.NewStringValue("x")}},
+			{name: "UnknownNestedField", overrides: map[FieldPath]ValueWriter{"Address.Street": bstvalue.NewStringValue("x")}},
+			{name: "NotStruct", overrides: map[FieldPath]ValueWriter{"Name.First": bstvalue.NewStringValue("x")}},
+			{name: "FieldAndNested", overrides: map[FieldPath]ValueWriter{
+				"Address":      bstvalue.NewStringValue("x"),
+				"Address.City": bstvalue.NewStringValue("x"),
+			}},
+			{name: "NilWriter", overrides: map[FieldPath]ValueWriter{"Name": nil}},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				if _, err := CloneWith(in, st, tc.overrides, bstio.ValueOptions{}); err == nil {
+					t.Fatal("expected clone to fail")
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package bst
+
+import (
+	"github.com/devmodules/bst/bsttype"
+)
+
+// coercible checks if the current element could be read losslessly as the one of the expected kind.
+// The numeric struct fields are coerced in the compatibility mode, so that the schema widening of the numeric field
+// doesn't require the migration of the data written before. The field of the embedded kind is read as the one
+// of the expected kind by its reader, i.e. the ReadUint32, only if the embedded kind widens to it, see bsttype.Widens.
+func (x *Extractor) coercible(expected bsttype.Kind) bool {
+	if !x.opts.CompatibilityMode {
+		return false
+	}
+	return bsttype.Widens(x.embeddedElemType().Kind(), expected)
+}
+
+// embeddedElemType returns the type the current element was encoded with. It differs from the element type only
+// for the struct fields of the compatibility mode, whose embedded type differs from the expected one.
+func (x *Extractor) embeddedElemType() bsttype.Type {
+	if x.opts.CompatibilityMode && x.embed.elemType != nil && x.embedType.Kind() == bsttype.KindStruct {
+		return x.embed.elemType
+	}
+	return x.elemType
+}
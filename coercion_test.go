@@ -0,0 +1,114 @@
+package bst
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestExtractorCoercion(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "u8", Type: bsttype.Uint8()},
+		{Index: 2, Name: "u32", Type: bsttype.Uint32()},
+		{Index: 3, Name: "i8", Type: bsttype.Int8()},
+		{Index: 4, Name: "i32", Type: bsttype.Int32()},
+		{Index: 5, Name: "f32", Type: bsttype.Float32()},
+		{Index: 6, Name: "note", Type: bsttype.String()},
+	}}
+	// The widened schema of the struct, which the extractor expects.
+	wide := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "u8", Type: bsttype.Uint16()},
+		{Index: 2, Name: "u32", Type: bsttype.Uint()},
+		{Index: 3, Name: "i8", Type: bsttype.Int64()},
+		{Index: 4, Name: "i32", Type: bsttype.Int()},
+		{Index: 5, Name: "f32", Type: bsttype.Float64()},
+		{Index: 6, Name: "note", Type: bsttype.String()},
+	}}
+	// record composes the struct value of the narrow fields.
+	record := func(t *testing.T, opts ComposerOptions) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		for _, write := range []func() error{
+			func() error { return c.WriteUint8(200) },
+			func() error { return c.WriteUint32(1 << 31) },
+			func() error { return c.WriteInt8(-100) },
+			func() error { return c.WriteInt32(-1 << 30) },
+			func() error { return c.WriteFloat32(1.5) },
+			func() error { return c.WriteString("note") },
+		} {
+			if err = write(); err != nil {
+				t.Fatalf("writing field failed: %v", err)
+			}
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	for _, desc := range []bool{false, true} {
+		// The fields of the embedded type are read as the widened ones of the expected type.
+		data := record(t, ComposerOptions{CompatibilityMode: true, Descending: desc, EmbedType: true})
+		x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: wide, CompatibilityMode: true, Descending: desc})
+		if err != nil {
+			t.Fatalf("creating extractor failed: %v", err)
+		}
+		x.Next()
+		if v, err := x.ReadUint16(); err != nil || v != 200 {
+			t.Fatalf("unexpected uint16: %d, %v", v, err)
+		}
+		x.Next()
+		if v, err := x.ReadUint(); err != nil || v != 1<<31 {
+			t.Fatalf("unexpected uint: %d, %v", v, err)
+		}
+		x.Next()
+		if v, err := x.ReadInt64(); err != nil || v != -100 {
+			t.Fatalf("unexpected int64: %d, %v", v, err)
+		}
+		x.Next()
+		if v, err := x.ReadInt(); err != nil || v != -1<<30 {
+			t.Fatalf("unexpected int: %d, %v", v, err)
+		}
+		x.Next()
+		if v, err := x.ReadFloat64(); err != nil || v != 1.5 {
+			t.Fatalf("unexpected float64: %v, %v", v, err)
+		}
+		x.Close()
+	}
+
+	// The narrowing and cross family coercions are not applied.
+	data := record(t, ComposerOptions{CompatibilityMode: true})
+	x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: st, CompatibilityMode: true})
+	if err != nil {
+		t.Fatalf("creating extractor failed: %v", err)
+	}
+	x.Next()
+	if _, err = x.ReadInt16(); err == nil {
+		t.Fatal("expected cross family coercion error")
+	}
+	x.Next()
+	x.Skip()
+	x.Next()
+	x.Skip()
+	x.Next()
+	if _, err = x.ReadInt16(); err == nil {
+		t.Fatal("expected narrowing coercion error")
+	}
+	x.Close()
+
+	// The coercions are not applied outside the compatibility mode.
+	data = record(t, ComposerOptions{})
+	if x, err = NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: st}); err != nil {
+		t.Fatalf("creating extractor failed: %v", err)
+	}
+	defer x.Close()
+	x.Next()
+	if _, err = x.ReadUint16(); err == nil {
+		t.Fatal("expected coercion error outside the compatibility mode")
+	}
+}
@@ -0,0 +1,240 @@
+package bst
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// numericValue is a decoded numeric value, used to compare and combine the values without the type specific code.
+// Only one of the fields is set, depending on the kind of the value.
+type numericValue struct {
+	kind bsttype.Kind
+	i    int64
+	u    uint64
+	f    float64
+}
+
+func (n numericValue) compare(o numericValue) int {
+	switch {
+	case n.i < o.i, n.u < o.u, n.f < o.f:
+		return -1
+	case n.i > o.i, n.u > o.u, n.f > o.f:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func decodeNumericValue(t bsttype.Type, in []byte, o bstio.ValueOptions) (numericValue, error) {
+	r := bytes.NewReader(in)
+	n := numericValue{kind: t.Kind()}
+	var err error
+	switch t.Kind() {
+	case bsttype.KindInt:
+		var v int
+		v, _, err = bstio.ReadInt(r, o.Descending, o.Comparable)
+		n.i = int64(v)
+	case bsttype.KindInt8:
+		var v int8
+		v, _, err = bstio.ReadInt8(r, o.Descending)
+		n.i = int64(v)
+	case bsttype.KindInt16:
+		var v int16
+		v, _, err = bstio.ReadInt16(r, o.Descending)
+		n.i = int64(v)
+	case bsttype.KindInt32:
+		var v int32
+		v, _, err = bstio.ReadInt32(r, o.Descending)
+		n.i = int64(v)
+	case bsttype.KindInt64, bsttype.KindDuration, bsttype.KindTimestamp:
+		n.i, _, err = bstio.ReadInt64(r, o.Descending)
+	case bsttype.KindUint:
+		var v uint
+		v, _, err = bstio.ReadUint(r, o.Descending)
+		n.u = uint64(v)
+	case bsttype.KindUint8:
+		var v uint8
+		v, _, err = bstio.ReadUint8(r, o.Descending)
+		n.u = uint64(v)
+	case bsttype.KindUint16:
+		var v uint16
+		v, _, err = bstio.ReadUint16(r, o.Descending)
+		n.u = uint64(v)
+	case bsttype.KindUint32:
+		var v uint32
+		v, _, err = bstio.ReadUint32(r, o.Descending)
+		n.u = uint64(v)
+	case bsttype.KindUint64:
+		n.u, _, err = bstio.ReadUint64(r, o.Descending)
+	case bsttype.KindFloat32:
+		var v float32
+		if o.OrderedFloats {
+			v, _, err = bstio.ReadOrderedFloat32(r, o.Descending)
+		} else {
+			v, _, err = bstio.ReadFloat32(r, o.Descending)
+		}
+		n.f = float64(v)
+	case bsttype.KindFloat64:
+		if o.OrderedFloats {
+			n.f, _, err = bstio.ReadOrderedFloat64(r, o.Descending)
+		} else {
+			n.f, _, err = bstio.ReadFloat64(r, o.Descending)
+		}
+	case bsttype.KindDateTime:
+		var v time.Time
+		v, _, err = bstio.ReadDateTime(r, o.Descending, time.UTC)
+		n.i = v.UnixNano()
+	default:
+		return n, bsterr.Err(bsterr.CodeInvalidType, "numeric value kind expected").
+			WithDetail("kind", t.Kind())
+	}
+	if err != nil {
+		return n, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to decode numeric value")
+	}
+	return n, nil
+}
+
+func encodeNumericValue(w io.Writer, t bsttype.Type, n numericValue, o bstio.ValueOptions) error {
+	var err error
+	switch t.Kind() {
+	case bsttype.KindInt:
+		_, err = bstio.WriteInt(w, int(n.i), o.Descending, o.Comparable)
+	case bsttype.KindInt8:
+		_, err = bstio.WriteInt8(w, int8(n.i), o.Descending)
+	case bsttype.KindInt16:
+		_, err = bstio.WriteInt16(w, int16(n.i), o.Descending)
+	case bsttype.KindInt32:
+		_, err = bstio.WriteInt32(w, int32(n.i), o.Descending)
+	case bsttype.KindInt64, bsttype.KindDuration:
+		_, err = bstio.WriteInt64(w, n.i, o.Descending)
+	case bsttype.KindUint:
+		_, err = bstio.WriteUint(w, uint(n.u), o.Descending)
+	case bsttype.KindUint8:
+		_, err = bstio.WriteUint8(w, uint8(n.u), o.Descending)
+	case bsttype.KindUint16:
+		_, err = bstio.WriteUint16(w, uint16(n.u), o.Descending)
+	case bsttype.KindUint32:
+		_, err = bstio.WriteUint32(w, uint32(n.u), o.Descending)
+	case bsttype.KindUint64:
+		_, err = bstio.WriteUint64(w, n.u, o.Descending)
+	case bsttype.KindFloat32:
+		if o.OrderedFloats {
+			_, err = bstio.WriteOrderedFloat32(w, float32(n.f), o.Descending)
+		} else {
+			_, err = bstio.WriteFloat32(w, float32(n.f), o.Descending)
+		}
+	case bsttype.KindFloat64:
+		if o.OrderedFloats {
+			_, err = bstio.WriteOrderedFloat64(w, n.f, o.Descending)
+		} else {
+			_, err = bstio.WriteFloat64(w, n.f, o.Descending)
+		}
+	default:
+		return bsterr.Err(bsterr.CodeInvalidType, "numeric value kind expected").
+			WithDetail("kind", t.Kind())
+	}
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to encode numeric value")
+	}
+	return nil
+}
+
+// isOrderedKind checks if the values of given kind could be compared by the compareValueBinary function.
+func isOrderedKind(k bsttype.Kind) bool {
+	switch k {
+	case bsttype.KindInt, bsttype.KindInt8, bsttype.KindInt16, bsttype.KindInt32, bsttype.KindInt64,
+		bsttype.KindUint, bsttype.KindUint8, bsttype.KindUint16, bsttype.KindUint32, bsttype.KindUint64,
+		bsttype.KindFloat32, bsttype.KindFloat64, bsttype.KindDuration, bsttype.KindTimestamp, bsttype.KindDateTime,
+		bsttype.KindString, bsttype.KindBytes, bsttype.KindEnum, bsttype.KindDecimal, bsttype.KindUUID:
+		return true
+	default:
+		return false
+	}
+}
+
+// compareValueBinary decodes and compares two binaries of the values of given type.
+// The result is negative if a < b, positive if a > b and zero if both are equal.
+func compareValueBinary(t bsttype.Type, a, b []byte, o bstio.ValueOptions) (int, error) {
+	switch tt := t.(type) {
+	case *bsttype.Named:
+		return compareValueBinary(tt.Type, a, b, o)
+	case *bsttype.Bytes:
+		va, _, err := bstio.ReadBytes(bytes.NewReader(a), tt.FixedSize, o.Descending, o.Comparable)
+		if err != nil {
+			return 0, err
+		}
+		vb, _, err := bstio.ReadBytes(bytes.NewReader(b), tt.FixedSize, o.Descending, o.Comparable)
+		if err != nil {
+			return 0, err
+		}
+		return bytes.Compare(va, vb), nil
+	case *bsttype.Enum:
+		va, _, err := bstio.ReadEnumIndex(bytes.NewReader(a), tt.ValueBytes, o.Descending)
+		if err != nil {
+			return 0, err
+		}
+		vb, _, err := bstio.ReadEnumIndex(bytes.NewReader(b), tt.ValueBytes, o.Descending)
+		if err != nil {
+			return 0, err
+		}
+		return va - vb, nil
+	}
+
+	if t.Kind() == bsttype.KindString {
+		va, _, err := bstio.ReadString(bytes.NewReader(a), o.Descending, o.Comparable)
+		if err != nil {
+			return 0, err
+		}
+		vb, _, err := bstio.ReadString(bytes.NewReader(b), o.Descending, o.Comparable)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case va < vb:
+			return -1, nil
+		case va > vb:
+			return 1, nil
+		default:
+			return 0, nil
+		}
+	}
+
+	if t.Kind() == bsttype.KindUUID {
+		va, _, err := bstio.ReadUUID(bytes.NewReader(a), o.Descending)
+		if err != nil {
+			return 0, err
+		}
+		vb, _, err := bstio.ReadUUID(bytes.NewReader(b), o.Descending)
+		if err != nil {
+			return 0, err
+		}
+		return bytes.Compare(va[:], vb[:]), nil
+	}
+
+	if t.Kind() == bsttype.KindDecimal {
+		va, _, err := bstio.ReadDecimal(bytes.NewReader(a), o.Descending)
+		if err != nil {
+			return 0, err
+		}
+		vb, _, err := bstio.ReadDecimal(bytes.NewReader(b), o.Descending)
+		if err != nil {
+			return 0, err
+		}
+		return va.Cmp(vb), nil
+	}
+
+	na, err := decodeNumericValue(t, a, o)
+	if err != nil {
+		return 0, err
+	}
+	nb, err := decodeNumericValue(t, b, o)
+	if err != nil {
+		return 0, err
+	}
+	return na.compare(nb), nil
+}
@@ -5,8 +5,10 @@ import (
 	"io"
 	"math"
 
+	"github.com/devmodules/bst/bstcol"
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bstskip"
 	"github.com/devmodules/bst/bsttype"
 	"github.com/devmodules/bst/internal/iopool"
 )
@@ -19,9 +21,88 @@ type ComposerOptions struct {
 	EmbedType         bool
 	Modules           *bsttype.Modules
 	Length            int
+	// EmbedDereferenced embeds the definition of the Named base type, instead of the named reference
+	// along with the composed modules. It applies only with the EmbedType option, if the named type has its Type defined.
+	// The extractor of such value embeds the dereferenced type, thus it does not need any type registry.
+	EmbedDereferenced bool
+	// StringBounds defines the maximum binary lengths of the comparable string struct fields, by the field names.
+	// Longer strings are replaced with their truncated prefix and a hash suffix - see the bstio.BoundString.
+	// The bounds apply only in the comparable mode, to all the struct fields (also nested) with given name.
+	StringBounds map[string]int
+	// KeyTransforms defines the transforms of the comparable string struct fields, by the field names, i.e. the case
+	// folding of the case-insensitive unique index keys. The transforms apply only in the comparable mode,
+	// to all the struct fields (also nested) with given name, before their StringBounds.
+	// The non-comparable row payload keeps the original values. See the ChainKeyTransforms.
+	KeyTransforms map[string]KeyTransform
+	// LengthPrefixedCollections prefixes the variable size array and map elements of the arrays and maps
+	// with their binary size, so that the extractor could skip them without parsing.
+	// It applies only in the compatibility mode, for non-comparable binaries.
+	LengthPrefixedCollections bool
+	// BlobStore stores the ExternalBytes values, whose references are written instead.
+	BlobStore BlobStore
+	// InlineThreshold is the binary length of the External encoded String and Bytes field values,
+	// from which on they are offloaded to the BlobStore. Shorter values are written inline.
+	// The zero threshold offloads all the values.
+	InlineThreshold int
+	// Allocator provides the scratch byte slices used to encode the values, i.e. the escaped comparable bytes.
+	// If nil, the scratch slices are allocated on the heap. See the bstio.ScratchAllocator.
+	Allocator bstio.Allocator
+	// MaxValueBytes is the maximum binary size of the composed value, including the embedded type.
+	// Once exceeded, the composer fails with the bsterr.CodeValueTooLarge error, and writes nothing more.
+	// The write which exceeds the limit is rejected as a whole, and the binaries of the buffered collections are
+	// verified on each element, thus the value is never written nor buffered beyond the limit by much.
+	// The zero value means no limit.
+	MaxValueBytes int
+	// SchemaRegistry, if set along with the EmbedType, replaces the embedded type and modules with the schema ID
+	// of the type, given it is registered. Otherwise, the type is embedded as is.
+	SchemaRegistry SchemaRegistry
+	// Encryption defines the encrypted String and Bytes struct fields, whose values are encrypted before
+	// being written. In the comparable mode, only the deterministic encryption could be used.
+	Encryption *FieldEncryption
+	// Tokenizers replace the values of the String and Bytes struct fields with their tokens, by the field names.
+	// The tokenizers apply to all the struct fields (also nested) with given name, before their encryption.
+	Tokenizers map[string]Tokenizer
+	// ImplicitTrailingNulls lets the struct be closed early, i.e. by the Close or the return of the WriteStruct function,
+	// with its remaining trailing fields written as nulls. It is meant for the streaming producers lacking the values
+	// of the newest fields, which are then read as nulls also in the compatibility mode. All the remaining fields need
+	// to be Nullable, otherwise the closing fails.
+	ImplicitTrailingNulls bool
+	// Signature appends the signature trailer of the current key to the composed value on the Close.
+	// The signature covers the whole value binary, including its header and embedded type.
+	Signature *SignatureKeys
+	// CanonicalMaps writes the map entries sorted by their key binaries, regardless of the order they are written in,
+	// so that the maps of the same entries have equal binaries, i.e. for the content hashes and signatures.
+	// The entries are buffered until the map is closed, and writing a duplicate key fails.
+	// The maps of both boolean keys and values could not be canonical.
+	CanonicalMaps bool
+	// EmbedDocs embeds the doc strings of the struct fields and the module definitions along with the embedded
+	// types and modules, so that the self-describing values are self-documenting as well.
+	// By default, the doc strings are omitted from the value binary.
+	EmbedDocs bool
+	// Checksum appends the checksum trailer of given kind to the composed value on the Close, so that the corrupted
+	// values are detected by the Extractor.VerifyAndClose. The checksum covers the whole value binary, including its
+	// header and embedded type. Along with the Signature, the checksum trailer is signed as well.
+	Checksum bstio.ChecksumKind
+	// Compression compresses the value body with the codec of given kind, once the composer is closed.
+	// The header and the embedded type or schema ID are not compressed, unless the CompressEmbeddedType is set.
+	// The body is buffered until the Close, thus the MaxValueBytes limits the compressed size only.
+	// The comparable values could not be compressed.
+	Compression bstio.CompressionKind
+	// CompressEmbeddedType compresses the embedded modules and type, or the schema ID, along with the value body.
+	// The type of such value could not be inspected without decompressing it. It requires the Compression.
+	CompressEmbeddedType bool
+	// FloatColumn defines the transforms of the float arrays of the bsttype.ArrayEncodingFloatColumn encoding.
+	// If nil, both the byte shuffle and the delta transforms are applied, which make the arrays the most compressible.
+	FloatColumn *bstcol.ArrayEncodingOptions
+	// OrderedFloats writes the float values in the ordered format, whose binaries of the negative values compare
+	// on the bytes level just like the values, i.e. for the comparable index keys. By default, the order of the negative
+	// values is reversed among themselves. The formats differ in the binaries of the negative values, thus the values
+	// need to be extracted with the same option. See the bstio.WriteOrderedFloat64.
+	OrderedFloats bool
 }
 
 // Composer is the composer for the binary serialization of the BST.
+// It is not safe for concurrent use. The 'bstguard' build tag enables the detection of its use by multiple goroutines.
 type Composer struct {
 	baseType        bsttype.Type
 	index, maxIndex int
@@ -33,8 +114,20 @@ type Composer struct {
 	elemDesc, isKey, lengthWritten, definedLength,
 	done, fhWritten, bufWrites bool
 	bytesWritten    int
+	stats           ComposeStats
 	modules         *bsttype.Modules
 	externalModules bool
+	nullBitmap      []byte
+	delta           bool
+	deltaPrev       uint64
+	guard           ownerGuard
+	limit           *valueLimiter
+	signer          *valueSigner
+	checksum        *valueChecksum
+	compressor      *valueCompressor
+	mapDir          []bstio.MapDirectoryEntry
+	mapEntryStart   int
+	setBounds       []int
 }
 
 // NewComposer creates a new binary value composer.
@@ -58,20 +151,44 @@ func NewComposer(w io.Writer, baseType bsttype.Type, opts ComposerOptions) (*Com
 
 // Close the composer, finishing any pending writes.
 func (x *Composer) Close() error {
+	x.guard.check("composer")
+	defer x.guard.release()
 	if !x.externalModules && x.modules != nil {
 		defer x.modules.Free()
 	}
 
+	var err error
 	switch bt := x.baseType.(type) {
 	case *bsttype.Struct:
-		return x.closeStruct(bt)
+		err = x.closeStruct(bt)
 	case *bsttype.Array:
-		return x.closeArray(bt)
+		err = x.closeArray(bt)
 	case *bsttype.Map:
-		return x.closeMap()
-	default:
-		return nil
+		err = x.closeMap()
+	}
+	if err != nil {
+		return err
+	}
+	if err = x.finishCompression(); err != nil {
+		return err
+	}
+
+	// The checksum trailer follows the whole value binary, and is followed by the signature trailer.
+	w := x.w
+	if x.checksum != nil && w == io.Writer(x.checksum) {
+		n, err := x.checksum.writeTrailer()
+		x.bytesWritten += n
+		if err != nil {
+			return err
+		}
+		w = x.checksum.w
+	}
+	if x.signer != nil && w == io.Writer(x.signer) {
+		n, err := x.signer.writeTrailer()
+		x.bytesWritten += n
+		return err
 	}
+	return nil
 }
 
 // IsDone returns true if the composer has finished writing the current element.
@@ -82,8 +199,9 @@ func (x *Composer) IsDone() bool {
 // ResetOn resetWithRoot the state, writer and the base type of the composer, so it could be used again
 // without unnecessary allocations.
 func (x *Composer) ResetOn(w io.Writer, baseType bsttype.Type, opts ComposerOptions) error {
-	// 1. Reset the composer to the initial state.
-	*x = Composer{w: w}
+	// 1. Reset the composer to the initial state. The reset composer could be used by another goroutine.
+	x.guard.release()
+	*x = Composer{w: w, guard: x.guard, limit: x.limit, signer: x.signer, checksum: x.checksum, compressor: x.compressor}
 
 	if err := x.applyOptions(opts); err != nil {
 		return err
@@ -98,9 +216,11 @@ func (x *Composer) ResetOn(w io.Writer, baseType bsttype.Type, opts ComposerOpti
 
 // Reset the state of the composer to its initial state.
 func (x *Composer) Reset(opts ComposerOptions) error {
+	x.guard.release()
 	x.boolBuf = 0x00
 	x.boolBufPos = 0
 	x.bytesWritten = 0
+	x.stats = ComposeStats{}
 	x.isKey = false
 	x.index = 0
 	x.maxIndex = 0
@@ -158,23 +278,28 @@ func (x *Composer) initializeComposer(baseType bsttype.Type, header bool) (err e
 }
 
 func (x *Composer) initializeNamedComposer(bt *bsttype.Named, header bool) error {
-	// 1. Set up the base type.
+	// 1. If the dereferenced type should be embedded, initialize the composer directly with its definition.
+	if header && x.opts.EmbedType && x.opts.EmbedDereferenced && bt.Type != nil {
+		return x.initializeComposer(bt.Type, true)
+	}
+
+	// 2. Set up the base type.
 	x.baseType = bt
 
-	// 2. If the header option is true, resolve all dependencies if needed and write the header.
+	// 3. If the header option is true, resolve all dependencies if needed and write the header.
 	if header {
-		// 3. Prepare type dependencies
+		// 4. Prepare type dependencies
 		if err := x.prepareTypeDependencies(); err != nil {
 			return err
 		}
 
-		// 4. Write the header.
+		// 5. Write the header.
 		if err := x.writeHeader(); err != nil {
 			return err
 		}
 	}
 
-	// 5. Recursively initialize the composer.
+	// 6. Recursively initialize the composer.
 	return x.initializeComposer(bt.Type, false)
 }
 
@@ -195,17 +320,25 @@ func (x *Composer) initializeArrayComposer(bt *bsttype.Array, header bool) error
 		}
 	}
 
-	// 3. Initialize the composer for an array.
+	// 3. Verify if the array elements could be encoded with the array encoding.
+	//    The set encoding applies to all the formats.
+	if !x.opts.Comparable || bt.IsSet() {
+		if err := bt.VerifyEncoding(); err != nil {
+			return err
+		}
+	}
+
+	// 4. Initialize the composer for an array.
 	x.initializeArray(bt)
 
-	// 4. verify if the composer is valid.
+	// 5. verify if the composer is valid.
 	if err := x.verifyArrayBase(); err != nil {
 		return err
 	}
 
-	// 5. If the array length is defined and the array is not of fixed size
-	// 	then write the length.
-	if !bt.HasFixedSize() && x.definedLength && !x.opts.Comparable {
+	// 6. If the array length is defined and the array is not of fixed size
+	// 	then write the length. The arrays with non-plain encoding write their length on close.
+	if !bt.HasFixedSize() && x.definedLength && !x.opts.Comparable && bt.Encoding == bsttype.ArrayEncodingPlain {
 		if err := x.writeArrayLength(bt); err != nil {
 			return err
 		}
@@ -239,7 +372,10 @@ func (x *Composer) initializeMapComposer(bt *bsttype.Map, header bool) error {
 	}
 
 	// 5. If the length was predefined, write it to the writer.
-	if x.definedLength {
+	//    Comparable maps are terminated instead, thus their length is never written.
+	//    The length of the indexed map is written on close, followed by the binary size of its buffered entries.
+	//    The length of the canonical map is written on close, as its entries are buffered to be sorted.
+	if x.definedLength && !x.opts.Comparable && !x.indexedMap() && !x.canonicalMap() {
 		if err := x.writeMapLength(); err != nil {
 			return err
 		}
@@ -268,7 +404,7 @@ func (x *Composer) initializeStructComposer(st *bsttype.Struct, header bool) err
 	x.maxIndex = len(st.Fields) - 1
 	if x.maxIndex >= 0 {
 		// 4.1. If the structure has fields, set the first element to the 0th field index.
-		x.elemType = st.Fields[0].Type
+		x.elemType = derefNamedType(st.Fields[0].Type)
 		x.elemDesc = st.Fields[0].Descending
 	}
 
@@ -282,6 +418,11 @@ func (x *Composer) initializeStructComposer(st *bsttype.Struct, header bool) err
 		return err
 	}
 
+	// 6.1. Verify if the fields could be encoded with their field encodings.
+	if err := x.verifyFieldEncodings(st); err != nil {
+		return err
+	}
+
 	// 7. If the type is in compatibility mode write the struct header to the writer.
 	if x.opts.CompatibilityMode {
 		if err := x.writeStructHeader(); err != nil {
@@ -384,14 +525,28 @@ func (x *Composer) prepareTypeDependencies() error {
 }
 
 func (x *Composer) finishElem() error {
+	x.guard.check("composer")
 	switch et := x.baseType.(type) {
 	case *bsttype.Struct:
-		return x.finishStructElem(et)
+		if err := x.finishStructElem(et); err != nil {
+			return err
+		}
 	case *bsttype.Array:
+		if err := x.recordSetElem(); err != nil {
+			return err
+		}
 		x.finishArrayElem(et)
 	case *bsttype.Map:
+		if err := x.recordMapEntry(); err != nil {
+			return err
+		}
 		x.finishMapElem(et)
 	}
+
+	// Verify that the buffered binaries didn't exceed the maximum value size.
+	if x.limit != nil {
+		return x.limit.checkBuffered(x.w)
+	}
 	return nil
 }
 
@@ -404,7 +559,7 @@ func (x *Composer) finishStructElem(et *bsttype.Struct) error {
 		// 1.2. Write the field header.
 		n, err := x.writeFieldHeader(root, x.fieldIndex(), uint(fb.Len()))
 		if err != nil {
-			return err
+			return x.flushFailed(fb, err)
 		}
 
 		x.bytesWritten += n
@@ -412,7 +567,7 @@ func (x *Composer) finishStructElem(et *bsttype.Struct) error {
 		// 1.3. Write buffered field value.
 		_, err = fb.WriteTo(root)
 		if err != nil {
-			return err
+			return x.flushFailed(fb, err)
 		}
 
 		// 1.4 Reset root writer.
@@ -519,7 +674,9 @@ func (x *Composer) finishMapElem(et *bsttype.Map) {
 	x.index++
 
 	// 4. If the index reached maximum, mark the composer as done.
+	//    The entry is complete, thus the composer points back to the key.
 	if x.index > x.maxIndex {
+		x.isKey = true
 		x.done = true
 		return
 	}
@@ -578,7 +735,7 @@ func (x *Composer) initializeStruct(st *bsttype.Struct) {
 	x.maxIndex = len(st.Fields) - 1
 	if x.maxIndex >= 0 {
 		// 3. If the struct has fields, set the current element to the first field.
-		x.elemType = st.Fields[0].Type
+		x.elemType = derefNamedType(st.Fields[0].Type)
 		x.elemDesc = st.Fields[0].Descending
 	}
 
@@ -593,29 +750,54 @@ func (x *Composer) initializeArray(at *bsttype.Array) {
 	x.baseType = at
 
 	// 2. Set up an element type of the array.
-	x.elemType = at.Elem()
+	x.elemType = derefNamedType(at.Elem())
 	x.elemDesc = x.opts.Descending
 
 	// 3. If the array has fixed size, set the maximum index to the array size.
 	if at.HasFixedSize() {
 		x.maxIndex = int(at.FixedSize) - 1
-		return
 	}
 
 	// 4. If the length of the array was not specified, set the maximum index to MaxInt.
 	//    The composer needs to be closed for undefined length arrays.
+	x.nullBitmap = x.nullBitmap[:0]
+	x.setBounds = x.setBounds[:0]
 	if at.FixedSize == 0 && (!x.definedLength || x.opts.Comparable) {
 		x.maxIndex = math.MaxInt
 		x.w = iopool.GetBuffer(x.w)
+		return
+	}
+
+	// 5. The elements of the arrays with non-plain encoding are always buffered, and written on close.
+	if x.runLengthArray() || x.nullBitmapArray() || x.floatColumnArray() || at.IsSet() {
+		x.w = iopool.GetBuffer(x.w)
 	}
 }
 
+// nullBitmapArray returns true if the null flags of the composed array elements are encoded as a bitmap.
+func (x *Composer) nullBitmapArray() bool {
+	at, ok := x.baseType.(*bsttype.Array)
+	return ok && at.Encoding == bsttype.ArrayEncodingNullBitmap && !x.opts.Comparable
+}
+
+// runLengthArray returns true if the elements of the composed array are run length encoded.
+func (x *Composer) runLengthArray() bool {
+	at, ok := x.baseType.(*bsttype.Array)
+	return ok && at.Encoding == bsttype.ArrayEncodingRunLength && !x.opts.Comparable
+}
+
+// floatColumnArray returns true if the elements of the composed array are encoded as the float array column.
+func (x *Composer) floatColumnArray() bool {
+	at, ok := x.baseType.(*bsttype.Array)
+	return ok && at.Encoding == bsttype.ArrayEncodingFloatColumn && !x.opts.Comparable
+}
+
 func (x *Composer) initializeMap(st *bsttype.Map) {
 	// 1. Set up the base type to the map.
 	x.baseType = st
 
 	// 2. Set up current pointer to the Key of the map.
-	x.elemType = st.Key.Type
+	x.elemType = derefNamedType(st.Key.Type)
 	x.elemDesc = st.Key.Descending
 	if x.opts.Descending {
 		x.elemDesc = !x.elemDesc
@@ -627,6 +809,13 @@ func (x *Composer) initializeMap(st *bsttype.Map) {
 	if !x.definedLength || x.opts.Comparable {
 		x.maxIndex = math.MaxInt
 		x.w = iopool.GetBuffer(x.w)
+		return
+	}
+
+	// 4. The entries of the indexed map are buffered, so that their binary size is written first.
+	//    The entries of the canonical map are buffered, so that they are sorted before being written.
+	if x.indexedMap() || x.canonicalMap() {
+		x.w = iopool.GetBuffer(x.w)
 	}
 }
 
@@ -634,6 +823,14 @@ func (x *Composer) verifyMapBase() error {
 	if x.maxIndex < 0 {
 		return bsterr.Err(bsterr.CodeInvalidValue, "undefined map size")
 	}
+	if x.indexedMap() {
+		if err := verifyIndexedMap(x.baseType.(*bsttype.Map)); err != nil {
+			return err
+		}
+	}
+	if x.canonicalMap() {
+		return verifyCanonicalMap(x.baseType.(*bsttype.Map))
+	}
 	return nil
 }
 
@@ -670,6 +867,7 @@ func (x *Composer) writeMapLength() error {
 	}
 
 	x.bytesWritten += n
+	x.stats.Lengths += n
 	return nil
 }
 
@@ -686,6 +884,7 @@ func (x *Composer) writeArrayLength(bt *bsttype.Array) error {
 	}
 
 	x.bytesWritten += n
+	x.stats.Lengths += n
 	return nil
 }
 
@@ -699,25 +898,111 @@ func (x *Composer) writeFieldHeader(w io.Writer, index uint, size uint) (int, er
 	// 2. Write field size.
 	n, err = bstio.WriteUint(w, size, false)
 	if err != nil {
+		x.stats.FieldHeaders += bytesWritten
 		return bytesWritten, err
 	}
 	bytesWritten += n
 
 	// 3. Mark the field header as written.
 	x.fhWritten = true
+	x.stats.FieldHeaders += bytesWritten
 	return bytesWritten, nil
 }
 
+// lengthPrefixedCollections checks if the nested collections are prefixed with their binary size.
+func (x *Composer) lengthPrefixedCollections() bool {
+	return x.opts.LengthPrefixedCollections && x.opts.CompatibilityMode && !x.opts.Comparable
+}
+
+// lengthPrefixedElem checks if the current array or map element is prefixed with its binary size.
+// The declared element type decides it, thus i.e. the arrays wrapped in the nullable elements are not prefixed.
+func (x *Composer) lengthPrefixedElem() bool {
+	if !x.lengthPrefixedCollections() {
+		return false
+	}
+	var et bsttype.Type
+	switch bt := x.baseType.(type) {
+	case *bsttype.Array:
+		et = bt.Elem()
+	case *bsttype.Map:
+		if x.isKey {
+			et = bt.Key.Type
+		} else {
+			et = bt.Value.Type
+		}
+	default:
+		return false
+	}
+	return bstskip.LengthPrefixed(et, bstio.ValueOptions{
+		Comparable:        x.opts.Comparable,
+		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    true,
+	})
+}
+
+// writeLengthPrefixed writes the buffered element binary, preceded by its size.
+func (x *Composer) writeLengthPrefixed() error {
+	sb := x.w.(*iopool.SharedBuffer)
+	root := sb.Root
+
+	// 1. Write the binary size of the element.
+	n, err := bstio.WriteUint(root, uint(sb.Len()), false)
+	if err != nil {
+		return x.flushFailed(sb, err)
+	}
+	x.bytesWritten += n
+	x.stats.Lengths += n
+
+	// 2. Write the buffered element binary, which was already counted.
+	if _, err = sb.WriteTo(root); err != nil {
+		return x.flushFailed(sb, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write length prefixed element"))
+	}
+
+	// 3. Restore the root writer and release the buffer.
+	x.w = root
+	iopool.ReleaseBuffer(sb)
+	return nil
+}
+
 func (x *Composer) setFieldBuffer() {
 	buf := iopool.GetBuffer(x.w)
 	x.w = buf
 	x.bufWrites = true
 }
 
+// flushFailed releases the buffer, whose binary failed to be written to its root writer, and restores the root writer.
+// The buffered binary would never be written, as the composition failed.
+func (x *Composer) flushFailed(sb *iopool.SharedBuffer, err error) error {
+	x.w = sb.Root
+	iopool.ReleaseBuffer(sb)
+	return err
+}
+
+// abortComposite restores the composer from the snapshot taken before writing the nested composite, once it failed.
+// The buffers stacked on top of the writer w by the composite and its nested composers are released,
+// as their binaries would never be written, and the writer state is restored.
+func (x *Composer) abortComposite(sp *Composer, w io.Writer, bufWrites bool, err error) error {
+	for {
+		// The buffer is compared with the writer w, as the writers of other types might not be comparable.
+		sb, ok := x.w.(*iopool.SharedBuffer)
+		if !ok || io.Writer(sb) == w {
+			break
+		}
+		x.w = sb.Root
+		iopool.ReleaseBuffer(sb)
+	}
+	*x = *sp
+	x.w, x.bufWrites = w, bufWrites
+	return err
+}
+
 func (x *Composer) closeStruct(et *bsttype.Struct) error {
 	if !x.externalModules && x.modules != nil {
 		defer x.modules.Free()
 	}
+	if err := x.writeTrailingNulls(et); err != nil {
+		return err
+	}
 	if !x.opts.CompatibilityMode {
 		return nil
 	}
@@ -732,14 +1017,36 @@ func (x *Composer) closeStruct(et *bsttype.Struct) error {
 }
 
 func (x *Composer) closeArray(bt *bsttype.Array) error {
-	// 1. Nothing needs to be done for the fixed size arrays.
-	if bt.HasFixedSize() || (x.definedLength && !x.opts.Comparable) {
-		// 1.1 Mark the array composer as done.
+	// 1. The run length encoded and null bitmap arrays are written out of the buffered elements.
+	if x.runLengthArray() {
+		return x.closeRunLengthArray(bt)
+	}
+	if x.nullBitmapArray() {
+		return x.closeNullBitmapArray(bt)
+	}
+	if x.floatColumnArray() {
+		return x.closeFloatColumnArray()
+	}
+
+	// 1.1. The buffered set elements are sorted, and written as the ones of the variable size array,
+	//      unless the set has fixed size.
+	if bt.IsSet() {
+		if err := x.sortSetElems(); err != nil {
+			return err
+		}
+		if bt.HasFixedSize() {
+			return x.closeFixedSizeSet()
+		}
+	}
+
+	// 2. Nothing needs to be done for the fixed size arrays.
+	if bt.HasFixedSize() || (x.definedLength && !x.opts.Comparable && !bt.IsSet()) {
+		// 2.1 Mark the array composer as done.
 		x.done = true
 		return nil
 	}
 
-	// 2. Check if last value that was written was a boolean.
+	// 3. Check if last value that was written was a boolean.
 	if x.boolBufPos > 0 {
 		if err := bstio.WriteByte(x.w, x.boolBuf); err != nil {
 			return err
@@ -749,7 +1056,7 @@ func (x *Composer) closeArray(bt *bsttype.Array) error {
 		x.bytesWritten++
 	}
 
-	// 3. Variable size array was written to the buffer, and its length
+	// 4. Variable size array was written to the buffer, and its length
 	//     was not written.
 	sb, ok := x.w.(*iopool.SharedBuffer)
 	if !ok {
@@ -759,43 +1066,216 @@ func (x *Composer) closeArray(bt *bsttype.Array) error {
 	root := sb.Root
 
 	if !x.opts.Comparable {
-		// 4.1. If the value is non-comparable an array length is written.
+		// 5.1. If the value is non-comparable an array length is written.
 		n, err := bstio.WriteUint(root, uint(x.index), x.opts.Descending)
 		if err != nil {
-			return err
+			return x.flushFailed(sb, err)
 		}
 
 		x.bytesWritten += n
+		x.stats.Lengths += n
 
-		// 4.2. Write the array to the buffer.
+		// 5.2. Write the array to the buffer.
 		_, err = sb.WriteTo(root)
 		if err != nil {
-			return err
+			return x.flushFailed(sb, err)
 		}
 	} else {
-		// 5. For comparable arrays, shared buffer data is stored as comparable bytes.
-		n, err := bstio.WriteBufferedBytesInternalComparable(root, sb, bstio.ArrayEscape, x.elemDesc)
+		// 6. For comparable arrays, shared buffer data is stored as comparable bytes.
+		//    Descending elements were already inverted, and the escaped binary is inverted as a whole,
+		//    thus the elements needs to be reverted back to the ascending order first.
+		if x.opts.Descending {
+			bstio.ReverseBytes(sb.Bytes)
+		}
+		n, err := bstio.WriteBufferedBytesInternalComparableAlloc(root, sb, bstio.ArrayEscape, x.opts.Descending, x.opts.Allocator)
 		if err != nil {
-			return err
+			return x.flushFailed(sb, err)
 		}
-		// 5.1. The number of bytes written is a difference between the number of bytes written
+		// 6.1. The number of bytes written is a difference between the number of bytes written
 		//      by above function and the number of bytes written by the shared buffer.
 		//      This is because a shared buffer bytes were already counted on each element.
 		x.bytesWritten += n - len(sb.Bytes)
+		x.stats.Escapes += n - len(sb.Bytes)
 	}
 
-	// 6. Reset the buffer.
+	// 7. Reset the buffer.
 	x.w = root
 
-	// 7. Release the buffer.
+	// 8. Release the buffer.
 	iopool.ReleaseBuffer(sb)
 
-	// 8. Mark the array composer as done.
+	// 9. Mark the array composer as done.
 	x.done = true
 
 	return nil
 }
 
+func (x *Composer) closeRunLengthArray(bt *bsttype.Array) error {
+	// 1. The array elements were written to the buffer.
+	sb, ok := x.w.(*iopool.SharedBuffer)
+	if !ok {
+		return bsterr.Err(bsterr.CodeWritingFailed, "run length encoded array was not buffered")
+	}
+
+	// 2. Verify that all the elements of the array with known length were written.
+	if x.maxIndex != math.MaxInt && x.index <= x.maxIndex {
+		return bsterr.Err(bsterr.CodeWritingFailed, "not all array elements were written").
+			WithDetails(bsterr.D("written", x.index), bsterr.D("expected", x.maxIndex+1))
+	}
+
+	size, err := bt.RunLengthValueSize()
+	if err != nil {
+		return err
+	}
+	root := sb.Root
+
+	// 3. Write the length of the variable size array.
+	if !bt.HasFixedSize() {
+		n, err := bstio.WriteUint(root, uint(x.index), x.opts.Descending)
+		if err != nil {
+			return x.flushFailed(sb, err)
+		}
+		x.bytesWritten += n
+		x.stats.Lengths += n
+	}
+
+	// 4. Write the runs of the buffered elements.
+	//    The buffered elements bytes were already counted, thus only the difference is added.
+	n, err := bstio.WriteRunLength(root, sb.Bytes, size, x.opts.Descending)
+	if err != nil {
+		return x.flushFailed(sb, err)
+	}
+	x.bytesWritten += n - len(sb.Bytes)
+
+	// 5. Reset and release the buffer.
+	x.w = root
+	iopool.ReleaseBuffer(sb)
+
+	// 6. Mark the array composer as done.
+	x.done = true
+	return nil
+}
+
+func (x *Composer) closeFloatColumnArray() error {
+	// 1. The array elements were written to the buffer.
+	sb, ok := x.w.(*iopool.SharedBuffer)
+	if !ok {
+		return bsterr.Err(bsterr.CodeWritingFailed, "float column array was not buffered")
+	}
+
+	// 2. Verify that all the elements of the array with known length were written.
+	if x.maxIndex != math.MaxInt && x.index <= x.maxIndex {
+		return bsterr.Err(bsterr.CodeWritingFailed, "not all array elements were written").
+			WithDetails(bsterr.D("written", x.index), bsterr.D("expected", x.maxIndex+1))
+	}
+
+	opts := bstcol.ForCompression()
+	if x.opts.FloatColumn != nil {
+		opts = *x.opts.FloatColumn
+	}
+
+	// 3. Parse the buffered element binaries, and transform them into the column.
+	//    The column stores the number of elements, thus the array length is not written.
+	var (
+		data []byte
+		err  error
+	)
+	switch x.elemType.Kind() {
+	case bsttype.KindFloat64:
+		parse := bstio.ParseFloat64
+		if x.opts.OrderedFloats {
+			parse = bstio.ParseOrderedFloat64
+		}
+		values := make([]float64, len(sb.Bytes)/8)
+		for i := range values {
+			if values[i], err = parse(sb.Bytes[i*8:i*8+8], x.opts.Descending); err != nil {
+				return x.flushFailed(sb, err)
+			}
+		}
+		data = bstcol.AppendFloat64Array(nil, values, opts)
+	case bsttype.KindFloat32:
+		parse := bstio.ParseFloat32
+		if x.opts.OrderedFloats {
+			parse = bstio.ParseOrderedFloat32
+		}
+		values := make([]float32, len(sb.Bytes)/4)
+		for i := range values {
+			if values[i], err = parse(sb.Bytes[i*4:i*4+4], x.opts.Descending); err != nil {
+				return x.flushFailed(sb, err)
+			}
+		}
+		data = bstcol.AppendFloat32Array(nil, values, opts)
+	default:
+		return x.flushFailed(sb, bsterr.Err(bsterr.CodeInvalidType, "float column array elements need to be floats").
+			WithDetails(bsterr.D("elemKind", x.elemType.Kind())))
+	}
+
+	// 4. Write the column. The buffered elements bytes were already counted, thus only the difference is added.
+	root := sb.Root
+	n, err := root.Write(data)
+	if err != nil {
+		return x.flushFailed(sb, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write float column array"))
+	}
+	x.bytesWritten += n - len(sb.Bytes)
+
+	// 5. Reset and release the buffer.
+	x.w = root
+	iopool.ReleaseBuffer(sb)
+
+	// 6. Mark the array composer as done.
+	x.done = true
+	return nil
+}
+
+func (x *Composer) closeNullBitmapArray(bt *bsttype.Array) error {
+	// 1. The not null array elements were written to the buffer.
+	sb, ok := x.w.(*iopool.SharedBuffer)
+	if !ok {
+		return bsterr.Err(bsterr.CodeWritingFailed, "null bitmap array was not buffered")
+	}
+
+	// 2. Verify that all the elements of the array with known length were written.
+	if x.maxIndex != math.MaxInt && x.index <= x.maxIndex {
+		return bsterr.Err(bsterr.CodeWritingFailed, "not all array elements were written").
+			WithDetails(bsterr.D("written", x.index), bsterr.D("expected", x.maxIndex+1))
+	}
+	root := sb.Root
+
+	// 3. Write the length of the variable size array.
+	if !bt.HasFixedSize() {
+		n, err := bstio.WriteUint(root, uint(x.index), x.opts.Descending)
+		if err != nil {
+			return x.flushFailed(sb, err)
+		}
+		x.bytesWritten += n
+		x.stats.Lengths += n
+	}
+
+	// 4. Write the null bitmap, extended to cover all the elements.
+	size := (x.index + 7) >> 3
+	for len(x.nullBitmap) < size {
+		x.nullBitmap = append(x.nullBitmap, 0)
+	}
+	n, err := root.Write(x.nullBitmap[:size])
+	if err != nil {
+		return x.flushFailed(sb, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write null bitmap"))
+	}
+	x.bytesWritten += n
+
+	// 5. Write the buffered not null values, which bytes were already counted.
+	if _, err = sb.WriteTo(root); err != nil {
+		return x.flushFailed(sb, err)
+	}
+
+	// 6. Reset and release the buffer.
+	x.w = root
+	iopool.ReleaseBuffer(sb)
+
+	// 7. Mark the array composer as done.
+	x.done = true
+	return nil
+}
+
 func (x *Composer) closeMap() error {
 	// 1. Verify if both the map key and value were written.
 	if !x.isKey {
@@ -812,8 +1292,15 @@ func (x *Composer) closeMap() error {
 		x.bytesWritten++
 	}
 
+	// 2.1. The entries of the canonical map are sorted by their keys.
+	if x.canonicalMap() {
+		if err := x.sortMapEntries(); err != nil {
+			return err
+		}
+	}
+
 	// 2. If the length was already defined, nothing needs to be done.
-	if x.definedLength && !x.opts.Comparable {
+	if x.definedLength && !x.opts.Comparable && !x.indexedMap() && !x.canonicalMap() {
 		// 2.1. Mark the map composer as done.
 		x.done = true
 		return nil
@@ -831,26 +1318,54 @@ func (x *Composer) closeMap() error {
 		// 3.1. If the value is non-comparable a map length is written.
 		n, err := bstio.WriteUint(root, uint(x.index), x.opts.Descending)
 		if err != nil {
-			return err
+			return x.flushFailed(sb, err)
 		}
 
 		x.bytesWritten += n
+		x.stats.Lengths += n
+
+		// 3.2. The entries of the indexed map are preceded by their binary size.
+		indexed := x.indexedMap()
+		if indexed {
+			n, err = bstio.WriteUint(root, uint(sb.Len()), x.opts.Descending)
+			if err != nil {
+				return x.flushFailed(sb, err)
+			}
+			x.bytesWritten += n
+			x.stats.Lengths += n
+		}
 
-		// 3.2. Write the map to the buffer.
+		// 3.3. Write the map to the buffer.
 		_, err = sb.WriteTo(root)
 		if err != nil {
-			return err
+			return x.flushFailed(sb, err)
+		}
+
+		// 3.4. The entries of the indexed map are followed by their directory.
+		if indexed {
+			n, err = root.Write(bstio.AppendMapDirectory(nil, x.mapDir))
+			if err != nil {
+				return x.flushFailed(sb, err)
+			}
+			x.bytesWritten += n
+			x.stats.Lengths += n
 		}
 	} else {
 		// 4.1. For comparable maps, shared buffer data is stored as comparable bytes.
-		n, err := bstio.WriteBufferedBytesInternalComparable(root, sb, bstio.MapEscape, x.elemDesc)
+		//      Descending entries were already inverted, and the escaped binary is inverted as a whole,
+		//      thus the entries needs to be reverted back to the ascending order first.
+		if x.opts.Descending {
+			bstio.ReverseBytes(sb.Bytes)
+		}
+		n, err := bstio.WriteBufferedBytesInternalComparableAlloc(root, sb, bstio.MapEscape, x.opts.Descending, x.opts.Allocator)
 		if err != nil {
-			return err
+			return x.flushFailed(sb, err)
 		}
 		// 4.2. The number of bytes written is a difference between the number of bytes written
 		//      by above function and the number of bytes written by the shared buffer.
 		//      This is because a shared buffer bytes were already counted on each element.
 		x.bytesWritten += n - len(sb.Bytes)
+		x.stats.Escapes += n - len(sb.Bytes)
 	}
 
 	// 5. Reset the buffer.
@@ -901,39 +1416,98 @@ func (x *Composer) writeHeader() error {
 		h |= 1 << 4
 	}
 
+	// 6.1. 5th bit - nested collections are length prefixed.
+	if x.lengthPrefixedCollections() {
+		h |= 1 << 5
+	}
+
+	// 6.2. 6th bit - the type is identified by the schema ID of the registry, instead of being embedded.
+	var schemaID uint64
+	if x.opts.EmbedType && x.opts.SchemaRegistry != nil {
+		var ok bool
+		if schemaID, ok = x.opts.SchemaRegistry.SchemaID(x.baseType); ok {
+			h &^= 1<<0 | 1<<4
+			h |= 1 << 6
+		}
+	}
+
+	// 6.3. 7th bit - the header extension byte follows the header, with the kinds of the checksum trailer
+	//      and the compression of the body.
+	if x.opts.Checksum != bstio.ChecksumNone || x.opts.Compression != bstio.CompressionNone {
+		h |= 1 << 7
+	}
+
 	// 6. Write the header.
 	if err := bstio.WriteByte(x.w, h); err != nil {
 		return err
 	}
 	x.bytesWritten++
+	x.stats.Header++
+
+	// 6.4. Write the header extension just after the header - the low 4 bits are the checksum kind,
+	//      and the high ones are the compression kind, whose top bit flags the compressed embedded type.
+	if h&(1<<7) != 0 {
+		ext := byte(x.opts.Checksum) | byte(x.opts.Compression)<<4
+		if x.opts.CompressEmbeddedType {
+			ext |= compressedHeaderFlag
+		}
+		if err := bstio.WriteByte(x.w, ext); err != nil {
+			return err
+		}
+		x.bytesWritten++
+		x.stats.Header++
+	}
 
-	// 7. If the type is embedded, write the type binary just after the header.
-	if x.opts.EmbedType {
-		// 7.1. Write modules binary.
+	// 6.5. The compressed embedded type is a part of the compressed body, which follows the header extension.
+	if x.opts.CompressEmbeddedType {
+		x.startCompression()
+	}
+
+	// 7. If the schema ID is used, write it just after the header, as the varying size integer.
+	if h&(1<<6) != 0 {
+		n, err := bstio.WriteUint(x.w, uint(schemaID), false)
+		if err != nil {
+			return err
+		}
+		x.bytesWritten += n
+		x.stats.Header += n
+	} else if x.opts.EmbedType {
+		// 8. If the type is embedded, write the type binary just after the header.
+		// 8.1. Write modules binary.
 		if x.modules != nil {
-			n, err := x.modules.Write(x.w)
+			n, err := x.modules.WriteWithOptions(x.w, x.typeWriteOptions())
 			if err != nil {
 				return err
 			}
 			x.bytesWritten += n
+			x.stats.Header += n
 		}
 
-		// 7.2. Write the binary of the type that will be encoded.
-		n, err := bsttype.WriteType(x.w, x.baseType)
+		// 8.2. Write the binary of the type that will be encoded.
+		n, err := bsttype.WriteTypeWithOptions(x.w, x.baseType, x.typeWriteOptions())
 		if err != nil {
 			return err
 		}
 
 		x.bytesWritten += n
+		x.stats.Header += n
 	}
 
+	// 9. The value body following the header is compressed, unless its compression has already started.
+	x.startCompression()
 	return nil
 }
 
+// typeWriteOptions returns the options of the types and modules embedded in the composed value.
+func (x *Composer) typeWriteOptions() bsttype.WriteTypeOptions {
+	return bsttype.WriteTypeOptions{OmitDocs: !x.opts.EmbedDocs}
+}
+
 func (x *Composer) initializeBasicComposer(bt bsttype.Type, header bool) error {
 	// 1. Initialize the composer for basic types.
 	x.baseType = bt
 	x.elemType = bt
+	x.elemDesc = x.opts.Descending
 
 	var err error
 	if header {
@@ -950,12 +1524,13 @@ func (x *Composer) initializeBasicComposer(bt bsttype.Type, header bool) error {
 }
 
 func (x *Composer) writeStructHeader() error {
-	// 1. Write the max index of the struct.
-	n, err := bstio.WriteUint(x.w, uint(x.maxIndex), x.opts.Descending)
+	// 1. Write the max index of the struct. The header is not a part of the value order, just as the field headers.
+	n, err := bstio.WriteStructHeader(x.w, x.maxIndex+1)
 	if err != nil {
 		return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "writing struct header failed")
 	}
 	x.bytesWritten += n
+	x.stats.Lengths += n
 	return nil
 }
 
@@ -966,9 +1541,72 @@ func (x *Composer) applyOptions(opts ComposerOptions) error {
 		x.externalModules = true
 	}
 	x.opts.EmbedType = opts.EmbedType
+	for name, bound := range opts.StringBounds {
+		if bound <= bstio.BoundedStringHashSize {
+			return bsterr.Err(bsterr.CodeInvalidValue, "string bound needs to be greater than the hash size").
+				WithDetails(
+					bsterr.D("field", name),
+					bsterr.D("bound", bound),
+				)
+		}
+	}
 	if opts.Length != 0 {
 		x.definedLength = true
 		x.maxIndex = opts.Length - 1
 	}
+
+	// Wrap the root writer with the value size limiter, the signer and the checksum, which are reused by the reset composer.
+	// The compressor wraps the root writer once the header is written.
+	if x.compressor != nil && x.w == io.Writer(x.compressor) {
+		x.w = x.compressor.w
+	}
+	if x.checksum != nil && x.w == io.Writer(x.checksum) {
+		x.w = x.checksum.w
+	}
+	if x.signer != nil && x.w == io.Writer(x.signer) {
+		x.w = x.signer.w
+	}
+	if x.limit != nil && x.w == io.Writer(x.limit) {
+		x.w = x.limit.w
+	}
+	if opts.MaxValueBytes > 0 {
+		if x.limit == nil {
+			x.limit = &valueLimiter{}
+		}
+		*x.limit = valueLimiter{w: x.w, max: opts.MaxValueBytes}
+		x.w = x.limit
+	}
+	if opts.Signature != nil {
+		if x.signer == nil {
+			x.signer = &valueSigner{}
+		}
+		if err := x.signer.reset(x.w, opts.Signature); err != nil {
+			return err
+		}
+		x.w = x.signer
+	}
+	if opts.Compression != bstio.CompressionNone {
+		if opts.Comparable {
+			return bsterr.Err(bsterr.CodeInvalidValue, "comparable format could not be compressed")
+		}
+		if !opts.Compression.Known() {
+			return bsterr.Err(bsterr.CodeInvalidValue, "unknown compression kind").WithDetail("kind", uint8(opts.Compression))
+		}
+	} else if opts.CompressEmbeddedType {
+		return bsterr.Err(bsterr.CodeInvalidValue, "compressed embedded type requires the compression")
+	}
+	if opts.Checksum != bstio.ChecksumNone {
+		// The comparable values are scanned in chunks, thus the trailer could not be located by the extractor.
+		if opts.Comparable {
+			return bsterr.Err(bsterr.CodeInvalidValue, "comparable format could not have the checksum trailer")
+		}
+		if x.checksum == nil {
+			x.checksum = &valueChecksum{}
+		}
+		if err := x.checksum.reset(x.w, opts.Checksum); err != nil {
+			return err
+		}
+		x.w = x.checksum
+	}
 	return nil
 }
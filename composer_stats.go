@@ -0,0 +1,58 @@
+package bst
+
+// ComposeStats are the write amplification counters of the composed binary. They split the written bytes
+// into the value payload and the format overhead, which quantifies the cost of the composer modes
+// (i.e. the compatibility or comparable mode) for given schema.
+type ComposeStats struct {
+	// Total is the number of all the written bytes.
+	Total int
+	// Header is the number of the composer header bytes, along with the embedded type and modules.
+	Header int
+	// FieldHeaders is the number of the struct field header bytes - the field indexes and sizes
+	// written in the compatibility mode.
+	FieldHeaders int
+	// Lengths is the number of the length bytes - the struct headers, the lengths of the arrays, maps,
+	// strings and bytes, the binary size prefixes of the length prefixed collection elements, along with
+	// the entry sizes and directories of the indexed maps.
+	Lengths int
+	// Escapes is the number of the bytes added by the comparable encoding - the escapes and the terminators
+	// of the strings, bytes, arrays and maps.
+	Escapes int
+}
+
+// Overhead returns the number of the format overhead bytes.
+func (x ComposeStats) Overhead() int {
+	return x.Header + x.FieldHeaders + x.Lengths + x.Escapes
+}
+
+// Payload returns the number of the value payload bytes, i.e. all the written bytes apart from the overhead.
+func (x ComposeStats) Payload() int {
+	return x.Total - x.Overhead()
+}
+
+// add adds the counters of the nested composer.
+func (x *ComposeStats) add(s ComposeStats) {
+	x.Total += s.Total
+	x.Header += s.Header
+	x.FieldHeaders += s.FieldHeaders
+	x.Lengths += s.Lengths
+	x.Escapes += s.Escapes
+}
+
+// Stats returns the write amplification counters of the bytes written by the composer so far.
+// The counters are cleared on Reset and ResetOn.
+func (x *Composer) Stats() ComposeStats {
+	s := x.stats
+	s.Total = x.bytesWritten
+	return s
+}
+
+// countValueOverhead counts the overhead of the n bytes long string or bytes binary of the value of given size.
+// The comparable binaries are escaped and terminated, while the non-comparable ones are prefixed with their length.
+func (x *Composer) countValueOverhead(n, size int) {
+	if x.opts.Comparable {
+		x.stats.Escapes += n - size
+	} else {
+		x.stats.Lengths += n - size
+	}
+}
@@ -2,11 +2,15 @@ package bst
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"math"
 	"testing"
 	"time"
 
+	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttest"
 	"github.com/devmodules/bst/bsttype"
 	"github.com/devmodules/bst/internal/diff"
 )
@@ -255,8 +259,8 @@ func TestComposerArray(t *testing.T) {
 			t.Fatalf("unexpected number of bytes written: %d", len(data))
 		}
 
-		if !bytes.Equal(data, []byte{0x00, 0x1, 10, 0b01010101, 0b00000010}) {
-			t.Fatalf("unexpected bool value binary value: %v, expected: %v", data, []byte{0x00, 0x1, 10, 0b01010101, 0b00000011})
+		if !bytes.Equal(data, []byte{0x00, 0x1, 10, 0b01010101, 0b00000001}) {
+			t.Fatalf("unexpected bool value binary value: %v, expected: %v", data, []byte{0x00, 0x1, 10, 0b01010101, 0b00000001})
 		}
 
 		buf.Reset()
@@ -298,8 +302,8 @@ func TestComposerArray(t *testing.T) {
 				t.Fatalf("unexpected number of bytes written: %d", len(data))
 			}
 
-			if !bytes.Equal(data, []byte{0x00, 0x1, 10, 0b01010101, 0b00000010}) {
-				t.Fatalf("unexpected bool value binary value: %v, expected: %v", data, []byte{0x00, 0x1, 10, 0b01010101, 0b00000011})
+			if !bytes.Equal(data, []byte{0x00, 0x1, 10, 0b01010101, 0b00000001}) {
+				t.Fatalf("unexpected bool value binary value: %v, expected: %v", data, []byte{0x00, 0x1, 10, 0b01010101, 0b00000001})
 			}
 
 			buf.Reset()
@@ -712,6 +716,118 @@ func TestComposerStruct(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("StringBounds", func(t *testing.T) {
+		st := bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "a", Type: bsttype.String()},
+				{Index: 2, Name: "b", Type: bsttype.String()},
+			},
+		}
+		const long = "a very long string value"
+
+		buf.Reset()
+		defer buf.Reset()
+		c, err := NewComposer(buf, &st, ComposerOptions{Comparable: true, StringBounds: map[string]int{"a": 16}})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteString(long); err != nil {
+			t.Fatalf("writing string failed: %v", err)
+		}
+		if err = c.WriteString(long); err != nil {
+			t.Fatalf("writing string failed: %v", err)
+		}
+
+		// The data should be: header, bounded 'a' and unbounded 'b'.
+		expected := 1 + bstio.StringBinarySize(bstio.BoundString(long, 16), true) + bstio.StringBinarySize(long, true)
+		if uint(len(buf.Bytes())) != expected {
+			t.Fatalf("unexpected number of bytes written: %d", len(buf.Bytes()))
+		}
+
+		if _, err = NewComposer(buf, &st, ComposerOptions{StringBounds: map[string]int{"a": 4}}); err == nil {
+			t.Fatal("expected error for the bound not greater than the hash size")
+		}
+	})
+
+	t.Run("KeyTransforms", func(t *testing.T) {
+		st := bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "Email", Type: bsttype.String()},
+				{Index: 2, Name: "Name", Type: bsttype.String()},
+			},
+		}
+		transforms := map[string]KeyTransform{"Email": ChainKeyTransforms(TrimSpace, FoldCase)}
+		compose := func(t *testing.T, opts ComposerOptions, email, name string) []byte {
+			var b bytes.Buffer
+			c, err := NewComposer(&b, &st, opts)
+			if err != nil {
+				t.Fatalf("creating composer failed: %v", err)
+			}
+			if err = c.WriteString(email); err != nil {
+				t.Fatalf("writing string failed: %v", err)
+			}
+			if err = c.WriteString(name); err != nil {
+				t.Fatalf("writing string failed: %v", err)
+			}
+			return b.Bytes()
+		}
+
+		// 1. The keys of the equivalent emails are equal, whereas the not transformed fields still differ.
+		opts := ComposerOptions{Comparable: true, KeyTransforms: transforms}
+		key := compose(t, opts, " John@Example.COM\t", "John")
+		if !bytes.Equal(key, compose(t, opts, "john@example.com", "John")) {
+			t.Fatal("expected equal keys of the equivalent emails")
+		}
+		if bytes.Equal(key, compose(t, opts, "john@example.com", "john")) {
+			t.Fatal("expected the not transformed field to differ")
+		}
+
+		// 2. The case folding is locale independent, and maps the whole folding orbit to the same rune.
+		if FoldCase("\u212A\u017F\u03A3") != "ks\u03C3" {
+			t.Fatalf("unexpected folded case: %q", FoldCase("\u212A\u017F\u03A3"))
+		}
+
+		// 3. The non-comparable row payload keeps the original value.
+		row := compose(t, ComposerOptions{KeyTransforms: transforms}, " John@Example.COM", "John")
+		if !bytes.Equal(row, compose(t, ComposerOptions{}, " John@Example.COM", "John")) {
+			t.Fatal("expected the row payload to keep the original value")
+		}
+	})
+}
+
+func TestComposerNullable(t *testing.T) {
+	// The nested nullable type is flattened, thus each value has a single null flag,
+	// and the comparable binaries of the null and non-null values keep their order.
+	nt := bsttype.NullableOf(bsttype.NullableOf(bsttype.String()))
+
+	compose := func(v *string) []byte {
+		buf := &bytes.Buffer{}
+		c, err := NewComposer(buf, nt, ComposerOptions{Comparable: true})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if v == nil {
+			err = c.WriteNull()
+		} else if err = c.WriteNotNull(); err == nil {
+			err = c.WriteString(*v)
+		}
+		if err != nil {
+			t.Fatalf("writing nullable failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	empty, value := "", "a"
+	bins := [][]byte{compose(nil), compose(&empty), compose(&value)}
+	if len(bins[0]) != 2 {
+		t.Fatalf("unexpected null value binary: %v", bins[0])
+	}
+	for i := 1; i < len(bins); i++ {
+		if bytes.Compare(bins[i-1], bins[i]) >= 0 {
+			t.Fatalf("unexpected order of the nullable binaries: %v >= %v", bins[i-1], bins[i])
+		}
+	}
 }
 
 func TestComposerNamed(t *testing.T) {
@@ -827,6 +943,38 @@ func TestComposerNamed(t *testing.T) {
 		buf.Reset()
 	})
 
+	t.Run("EmbedDereferenced", func(t *testing.T) {
+		nt := &bsttype.Named{
+			Name:   "test",
+			Module: "testing",
+			Type:   bsttype.Uint8(),
+		}
+
+		c, err := NewComposer(buf, nt, ComposerOptions{EmbedType: true, EmbedDereferenced: true})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+
+		if err = c.WriteUint8(8); err != nil {
+			t.Fatalf("writing uint8 failed: %v", err)
+		}
+
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+
+		data := buf.Bytes()
+		// The data should be:
+		// 0b00000001             - data header, embedded type without modules
+		// byte(bsttype.KindUint8) - embedded dereferenced type kind
+		// 0x08                   - value
+		expected := []byte{0b00000001, byte(bsttype.KindUint8), 0x08}
+		if !bytes.Equal(data, expected) {
+			t.Fatalf("unexpected named value binary value: %v, expected: %v", data, expected)
+		}
+		buf.Reset()
+	})
+
 	t.Run("EmbedModules", func(t *testing.T) {
 		nt := &bsttype.Named{
 			Name:   "test",
@@ -932,3 +1080,624 @@ func TestComposerNamed(t *testing.T) {
 		buf.Reset()
 	})
 }
+
+func TestComposerWriteFailure(t *testing.T) {
+	bsttest.CheckPools(t)
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.String()},
+		{Index: 2, Name: "Scores", Type: &bsttype.Array{Type: bsttype.Int32()}},
+		{Index: 3, Name: "Ratio", Type: bsttype.Float64()},
+	}}
+	compose := func(w io.Writer, opts ComposerOptions) error {
+		c, err := NewComposer(w, st, opts)
+		if err != nil {
+			return err
+		}
+		if err = c.WriteString("name"); err != nil {
+			return err
+		}
+		if err = c.WriteArray(func(ac *Composer) error {
+			for _, v := range []int32{1, 2, 3} {
+				if err := ac.WriteInt32(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, 3); err != nil {
+			return err
+		}
+		if err = c.WriteFloat64(0.5); err != nil {
+			return err
+		}
+		return c.Close()
+	}
+
+	for _, opts := range []ComposerOptions{{}, {EmbedType: true}, {CompatibilityMode: true}, {Comparable: true}} {
+		var buf bytes.Buffer
+		if err := compose(&buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		size := int64(buf.Len())
+
+		// Each failing write has to be reported, wherever it happens.
+		for offset := int64(0); offset < size; offset++ {
+			w := bsttest.NewFaultWriter(io.Discard, bsttest.AtOffset(offset))
+			if err := compose(w, opts); !errors.Is(err, bsttest.ErrInjected) {
+				t.Fatalf("%+v: expected injected write error at offset %d, got: %v", opts, offset, err)
+			}
+		}
+	}
+
+	// The buffers of the aborted nested composite are released as well.
+	errAbort := errors.New("abort")
+	c, err := NewComposer(io.Discard, st, ComposerOptions{CompatibilityMode: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteString("name"); err != nil {
+		t.Fatal(err)
+	}
+	err = c.WriteArray(func(ac *Composer) error {
+		if err := ac.WriteInt32(1); err != nil {
+			return err
+		}
+		return errAbort
+	}, 0)
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected abort error, got: %v", err)
+	}
+}
+
+func TestComposerStats(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.String()},
+		{Index: 2, Name: "Scores", Type: &bsttype.Array{Type: bsttype.Int32()}},
+		{Index: 3, Name: "Tags", Type: &bsttype.Map{Key: bsttype.MapElement{Type: bsttype.String()}, Value: bsttype.MapElement{Type: bsttype.Int8()}}},
+	}}
+
+	testCases := []struct {
+		Name     string
+		Opts     ComposerOptions
+		Expected ComposeStats
+	}{
+		{
+			Name:     "Plain",
+			Opts:     ComposerOptions{},
+			Expected: ComposeStats{Total: 27, Header: 1, Lengths: 8},
+		},
+		{
+			Name:     "EmbedType",
+			Opts:     ComposerOptions{EmbedType: true},
+			Expected: ComposeStats{Total: 63, Header: 37, Lengths: 8},
+		},
+		{
+			Name:     "Compatibility",
+			Opts:     ComposerOptions{CompatibilityMode: true},
+			Expected: ComposeStats{Total: 41, Header: 1, FieldHeaders: 12, Lengths: 10},
+		},
+		{
+			Name:     "Comparable",
+			Opts:     ComposerOptions{Comparable: true},
+			Expected: ComposeStats{Total: 28, Header: 1, Escapes: 9},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := NewComposer(&buf, st, tc.Opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = c.WriteString("name"); err != nil {
+				t.Fatal(err)
+			}
+			if err = c.WriteArray(func(ac *Composer) error {
+				for _, v := range []int32{1, 2, 3} {
+					if err := ac.WriteInt32(v); err != nil {
+						return err
+					}
+				}
+				return nil
+			}, 3); err != nil {
+				t.Fatal(err)
+			}
+			if err = c.WriteMap(func(mc *Composer) error {
+				if err := mc.WriteString("a"); err != nil {
+					return err
+				}
+				return mc.WriteInt8(1)
+			}, 1); err != nil {
+				t.Fatal(err)
+			}
+			if err = c.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The payload is the same in all the modes: 4 bytes of the string, 3 int32 values and a single map entry.
+			stats := c.Stats()
+			if stats != tc.Expected {
+				t.Errorf("unexpected stats: %+v, expected: %+v", stats, tc.Expected)
+			}
+			if stats.Total != buf.Len() {
+				t.Errorf("unexpected total: %d, expected: %d", stats.Total, buf.Len())
+			}
+			if stats.Payload() != 4+3*4+2 {
+				t.Errorf("unexpected payload: %d, expected: %d", stats.Payload(), 4+3*4+2)
+			}
+
+			// The stats are cleared on reset, apart from the rewritten headers.
+			if err = c.Reset(tc.Opts); err != nil {
+				t.Fatal(err)
+			}
+			if stats = c.Stats(); stats.Payload() != 0 {
+				t.Errorf("unexpected stats after reset: %+v", stats)
+			}
+		})
+	}
+}
+
+// countingAllocator counts the allocations and releases of the wrapped allocator.
+type countingAllocator struct {
+	bstio.ScratchAllocator
+	allocs, frees int
+}
+
+func (x *countingAllocator) Alloc(n int) []byte {
+	x.allocs++
+	return x.ScratchAllocator.Alloc(n)
+}
+
+func (x *countingAllocator) Free(b []byte) {
+	x.frees++
+	x.ScratchAllocator.Free(b)
+}
+
+func TestComposerAllocator(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.String()},
+		{Index: 2, Name: "Data", Type: &bsttype.Bytes{}, Descending: true},
+		{Index: 3, Name: "Tags", Type: &bsttype.Array{Type: bsttype.String()}},
+	}}
+
+	compose := func(t *testing.T, opts ComposerOptions) []byte {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = c.WriteString("a\x00b"); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.WriteBytes([]byte{0x00, 0x01}); err != nil {
+			t.Fatal(err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, v := range []string{"x", "y\x00"} {
+				if err := ac.WriteString(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	for _, opts := range []ComposerOptions{{}, {Comparable: true}, {Comparable: true, Descending: true}} {
+		expected := compose(t, opts)
+
+		var a countingAllocator
+		opts.Allocator = &a
+		got := compose(t, opts)
+		if !bytes.Equal(got, expected) {
+			t.Fatalf("unexpected binary with allocator (%+v):\n%v\nexpected:\n%v", opts, got, expected)
+		}
+		if a.allocs == 0 || a.allocs != a.frees {
+			t.Fatalf("unexpected allocations: %d and frees: %d", a.allocs, a.frees)
+		}
+	}
+}
+
+func TestComposerMaxValueBytes(t *testing.T) {
+	bsttest.CheckPools(t)
+	errTooLarge := bsterr.Err(bsterr.CodeValueTooLarge, "value exceeds the maximum binary size")
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.String()},
+		{Index: 2, Name: "Tags", Type: &bsttype.Array{Type: bsttype.String()}},
+		{Index: 3, Name: "Ratio", Type: bsttype.Float64()},
+	}}
+	compose := func(w io.Writer, opts ComposerOptions) error {
+		c, err := NewComposer(w, st, opts)
+		if err != nil {
+			return err
+		}
+		if err = c.WriteString("name"); err != nil {
+			return err
+		}
+		if err = c.WriteArray(func(ac *Composer) error {
+			for _, v := range []string{"a", "bc", "def"} {
+				if err := ac.WriteString(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, 3); err != nil {
+			return err
+		}
+		if err = c.WriteFloat64(0.5); err != nil {
+			return err
+		}
+		return c.Close()
+	}
+
+	for _, opts := range []ComposerOptions{{}, {EmbedType: true}, {CompatibilityMode: true}, {Comparable: true}} {
+		var expected bytes.Buffer
+		if err := compose(&expected, opts); err != nil {
+			t.Fatal(err)
+		}
+
+		// 1. The value of the maximum size is composed as usual.
+		opts.MaxValueBytes = expected.Len()
+		var buf bytes.Buffer
+		if err := compose(&buf, opts); err != nil {
+			t.Fatalf("%+v: unexpected error: %v", opts, err)
+		}
+		if !bytes.Equal(buf.Bytes(), expected.Bytes()) {
+			t.Fatalf("%+v: unexpected binary:\n%v\nexpected:\n%v", opts, buf.Bytes(), expected.Bytes())
+		}
+
+		// 2. Any lower limit fails the composition, without writing beyond the limit.
+		for limit := 1; limit < expected.Len(); limit++ {
+			opts.MaxValueBytes = limit
+			buf.Reset()
+			if err := compose(&buf, opts); !errors.Is(err, errTooLarge) {
+				t.Fatalf("%+v: expected value too large error, got: %v", opts, err)
+			}
+			if buf.Len() > limit {
+				t.Fatalf("%+v: written %d bytes beyond the limit", opts, buf.Len())
+			}
+		}
+	}
+
+	// 3. The buffered collection is verified on each element, and its buffer is released on close.
+	var buf bytes.Buffer
+	c, err := NewComposer(&buf, &bsttype.Array{Type: &bsttype.Bytes{}}, ComposerOptions{MaxValueBytes: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteBytes(make([]byte, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteBytes(make([]byte, 8)); !errors.Is(err, errTooLarge) {
+		t.Fatalf("expected value too large error, got: %v", err)
+	}
+	if err = c.Close(); !errors.Is(err, errTooLarge) {
+		t.Fatalf("expected value too large error on close, got: %v", err)
+	}
+	if buf.Len() > 16 {
+		t.Fatalf("written %d bytes beyond the limit", buf.Len())
+	}
+}
+
+func TestComposerImplicitTrailingNulls(t *testing.T) {
+	item := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "sku", Type: bsttype.String()},
+		{Index: 2, Name: "discount", Type: bsttype.NullableOf(bsttype.Uint8())},
+	}}
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "name", Type: bsttype.String()},
+		{Index: 2, Name: "item", Type: item},
+		{Index: 3, Name: "note", Type: bsttype.NullableOf(bsttype.String())},
+		{Index: 4, Name: "tags", Type: bsttype.NullableOf(bsttype.ArrayOf(bsttype.String()))},
+	}}
+
+	// compose writes the struct, with the trailing nulls written explicitly or omitted.
+	compose := func(t *testing.T, opts ComposerOptions, explicit bool) []byte {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteString("a"); err != nil {
+			t.Fatalf("writing name failed: %v", err)
+		}
+		err = c.WriteStruct(func(sc *Composer) error {
+			if err := sc.WriteString("sku"); err != nil {
+				return err
+			}
+			if explicit {
+				return sc.WriteNull()
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("writing item failed: %v", err)
+		}
+		if explicit {
+			for i := 0; i < 2; i++ {
+				if err = c.WriteNull(); err != nil {
+					t.Fatalf("writing null failed: %v", err)
+				}
+			}
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	for _, opts := range []ComposerOptions{{}, {CompatibilityMode: true}, {Comparable: true, Descending: true}} {
+		expected := compose(t, opts, true)
+		opts.ImplicitTrailingNulls = true
+		if actual := compose(t, opts, false); !bytes.Equal(actual, expected) {
+			t.Fatalf("unexpected binary with options %+v: %x, expected: %x", opts, actual, expected)
+		}
+	}
+
+	t.Run("NotNullable", func(t *testing.T) {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, ComposerOptions{CompatibilityMode: true, ImplicitTrailingNulls: true})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteString("a"); err != nil {
+			t.Fatalf("writing name failed: %v", err)
+		}
+		if err = c.Close(); err == nil {
+			t.Fatal("expected closing with omitted struct field to fail")
+		}
+
+		// Neither is the nested struct with the omitted non-nullable field.
+		err = c.WriteStruct(func(sc *Composer) error { return nil })
+		if err == nil {
+			t.Fatal("expected writing struct with omitted fields to fail")
+		}
+	})
+}
+
+func TestComposerWithLength(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "tags", Type: bsttype.ArrayOf(bsttype.String())},
+		{Index: 2, Name: "counts", Type: &bsttype.Map{Key: bsttype.MapElement{Type: bsttype.String()}, Value: bsttype.MapElement{Type: bsttype.Uint32()}}},
+	}}
+	tags := []string{"a", "b", "c"}
+	// compose writes the struct, with the collections of either defined or undefined length.
+	compose := func(t *testing.T, n int, defined bool) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, ComposerOptions{})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		writeTags := func(ac *Composer) error {
+			for _, tag := range tags[:n] {
+				size := buf.Len()
+				if err := ac.WriteString(tag); err != nil {
+					return err
+				}
+				// The elements of the array of defined length are not buffered.
+				if defined && buf.Len() == size {
+					t.Fatal("array element was buffered")
+				}
+			}
+			return nil
+		}
+		writeCounts := func(mc *Composer) error {
+			for i, tag := range tags[:n] {
+				if err := mc.WriteString(tag); err != nil {
+					return err
+				}
+				if err := mc.WriteUint32(uint32(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if defined {
+			err = c.WriteArrayWithLength(n, writeTags)
+		} else {
+			err = c.WriteArray(writeTags, 0)
+		}
+		if err != nil {
+			t.Fatalf("writing array failed: %v", err)
+		}
+		if defined {
+			err = c.WriteMapWithLength(n, writeCounts)
+		} else {
+			err = c.WriteMap(writeCounts, 0)
+		}
+		if err != nil {
+			t.Fatalf("writing map failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	// The collections of defined length have the same binary as the buffered ones.
+	for _, n := range []int{0, len(tags)} {
+		if defined, undefined := compose(t, n, true), compose(t, n, false); !bytes.Equal(defined, undefined) {
+			t.Fatalf("binaries of %d elements differ: %x != %x", n, defined, undefined)
+		}
+	}
+
+	// Writing more or less elements than the defined length fails.
+	c, err := NewComposer(&bytes.Buffer{}, st, ComposerOptions{})
+	if err != nil {
+		t.Fatalf("creating composer failed: %v", err)
+	}
+	if err = c.WriteArrayWithLength(0, func(ac *Composer) error { return ac.WriteString("a") }); err == nil {
+		t.Fatal("expected too many elements error")
+	}
+	if err = c.WriteArrayWithLength(2, func(ac *Composer) error { return ac.WriteString("a") }); err == nil {
+		t.Fatal("expected missing elements error")
+	}
+	if err = c.WriteArrayWithLength(-1, func(*Composer) error { return nil }); err == nil {
+		t.Fatal("expected negative length error")
+	}
+}
+
+func TestComposerEmbedDocs(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "id", Type: bsttype.Uint64(), Doc: "ID is the unique identifier."},
+		{Index: 2, Name: "name", Type: bsttype.String()},
+	}}
+	undocumented := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "id", Type: bsttype.Uint64()},
+		{Index: 2, Name: "name", Type: bsttype.String()},
+	}}
+	compose := func(t *testing.T, st *bsttype.Struct, embedDocs bool) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, ComposerOptions{EmbedType: true, EmbedDocs: embedDocs})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteUint64(7); err != nil {
+			t.Fatalf("writing id failed: %v", err)
+		}
+		if err = c.WriteString("ann"); err != nil {
+			t.Fatalf("writing name failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	// 1. By default, the doc strings are omitted from the embedded type.
+	if data, expected := compose(t, st, false), compose(t, undocumented, false); !bytes.Equal(data, expected) {
+		t.Fatalf("doc strings embedded by default: %v, expected: %v", data, expected)
+	}
+
+	// 2. The embedded doc strings are surfaced by the extractor of the self-describing value.
+	x, err := NewExtractor(bytes.NewReader(compose(t, st, true)), ExtractorOptions{})
+	if err != nil {
+		t.Fatalf("creating extractor failed: %v", err)
+	}
+	defer x.Close()
+	et, ok := x.EmbedType().(*bsttype.Struct)
+	if !ok || et.Fields[0].Doc != st.Fields[0].Doc || et.Fields[1].Doc != "" {
+		t.Fatalf("unexpected embedded type: %v", x.EmbedType())
+	}
+	if !x.Next() {
+		t.Fatalf("id field not found: %v", x.Err())
+	}
+	if id, err := x.ReadUint64(); err != nil || id != 7 {
+		t.Fatalf("unexpected id: %d, %v", id, err)
+	}
+	if !x.Next() {
+		t.Fatalf("name field not found: %v", x.Err())
+	}
+	if name, err := x.ReadString(); err != nil || name != "ann" {
+		t.Fatalf("unexpected name: %q, %v", name, err)
+	}
+}
+
+// singleSchemaRegistry is the SchemaRegistry of a single type, registered under the id.
+type singleSchemaRegistry struct {
+	id uint64
+	t  bsttype.Type
+}
+
+func (x singleSchemaRegistry) SchemaID(t bsttype.Type) (uint64, bool) {
+	return x.id, t == x.t
+}
+
+func (x singleSchemaRegistry) Schema(id uint64) (bsttype.Type, *bsttype.Modules, error) {
+	if id != x.id {
+		return nil, nil, bsterr.Err(bsterr.CodeUndefinedType, "schema not found").WithDetail("schemaID", id)
+	}
+	return x.t, nil, nil
+}
+
+func TestComposerSchemaID(t *testing.T) {
+	for _, id := range []uint64{7, math.MaxUint64} {
+		reg := singleSchemaRegistry{id: id, t: bsttype.Uint8()}
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, reg.t, ComposerOptions{EmbedType: true, SchemaRegistry: reg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = c.WriteUint8(42); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// The schema ID is written as the varying size integer just after the header.
+		expected := append([]byte{1 << 6}, bstio.MarshalUint(uint(id), false)...)
+		if data := buf.Bytes(); !bytes.Equal(data[:len(data)-1], expected) {
+			t.Fatalf("unexpected header of schema id %d: %x, expected: %x", id, data[:len(data)-1], expected)
+		}
+
+		x, err := NewExtractor(bytes.NewReader(buf.Bytes()), ExtractorOptions{SchemaRegistry: reg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, err := x.ReadUint8(); err != nil || v != 42 {
+			t.Fatalf("unexpected value: %d, err: %v", v, err)
+		}
+		if x.BytesRead() != buf.Len() {
+			t.Fatalf("unexpected number of bytes read: %d, expected: %d", x.BytesRead(), buf.Len())
+		}
+		x.Close()
+	}
+}
+
+func TestComposerOrderedFloats(t *testing.T) {
+	values := []float64{math.Inf(-1), -10, -1.5, -1, 0, 1, 1.5, 10, math.Inf(1)}
+	for _, desc := range []bool{false, true} {
+		var prev []byte
+		for i, v := range values {
+			opts := ComposerOptions{Comparable: true, Descending: desc, OrderedFloats: true}
+			var buf bytes.Buffer
+			c, err := NewComposer(&buf, bsttype.Float64(), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = c.WriteFloat64(v); err != nil {
+				t.Fatal(err)
+			}
+			if err = c.Close(); err != nil {
+				t.Fatal(err)
+			}
+			data := buf.Bytes()
+
+			// 1. The ordered binaries compare just like the values, also the negative ones.
+			if i > 0 {
+				cmp := bytes.Compare(prev, data)
+				if (!desc && cmp >= 0) || (desc && cmp <= 0) {
+					t.Fatalf("unexpected order of %v and %v, desc: %v", values[i-1], v, desc)
+				}
+			}
+			prev = data
+
+			// 2. The value is read back only with the same option, unless it is not negative.
+			for _, ordered := range []bool{true, false} {
+				x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{
+					ExpectedType: bsttype.Float64(), Comparable: true, Descending: desc, OrderedFloats: ordered,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := x.ReadFloat64()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if (got == v) != (ordered || v >= 0) {
+					t.Fatalf("unexpected value %v of %v, ordered: %v", got, v, ordered)
+				}
+				x.Close()
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package bst
+
+import (
+	"bytes"
+	"io"
+	"math"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+)
+
+// The value of the composer with the Compression option is flagged by the high 4 bits of the header extension byte.
+// The header, its extension and the embedded type or schema ID are not compressed, so that the value could be
+// inspected without decompressing it. These are followed by the size of the compressed body, and the body itself.
+// With the CompressEmbeddedType option, the top bit of the extension byte is set, and the compressed body follows
+// the extension byte straight away, thus the embedded modules and type, or the schema ID, are compressed as well.
+
+// compressedHeaderFlag is the bit of the header extension byte, set if the embedded type is compressed.
+const compressedHeaderFlag = 0x80
+
+// DefaultMaxDecompressedBytes is the default maximum size of the decompressed value body.
+const DefaultMaxDecompressedBytes = 64 << 20
+
+// valueCompressor is the root writer of the composer with the Compression option, which buffers the value body
+// written after the header. The body is compressed and written on the composer close.
+type valueCompressor struct {
+	w    io.Writer
+	kind bstio.CompressionKind
+	buf  bytes.Buffer
+}
+
+// Write buffers the p as a part of the value body.
+// Implements io.Writer interface.
+func (x *valueCompressor) Write(p []byte) (int, error) {
+	return x.buf.Write(p)
+}
+
+// reset prepares the compressor of given kind, writing to w.
+func (x *valueCompressor) reset(w io.Writer, kind bstio.CompressionKind) {
+	x.w, x.kind = w, kind
+	x.buf.Reset()
+}
+
+// writeBody compresses the buffered body, and writes it prefixed with its size.
+// Returns the number of bytes written.
+func (x *valueCompressor) writeBody() (int, error) {
+	data, err := x.kind.Compress(x.buf.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	n, err := bstio.WriteUint(x.w, uint(len(data)), false)
+	if err != nil {
+		return n, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write compressed body size")
+	}
+	bw, err := x.w.Write(data)
+	n += bw
+	if err != nil {
+		return n, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write compressed body")
+	}
+	return n, nil
+}
+
+// startCompression wraps the root writer with the compressor, once the header is written.
+func (x *Composer) startCompression() {
+	if x.opts.Compression == bstio.CompressionNone {
+		return
+	}
+	if x.compressor == nil {
+		x.compressor = &valueCompressor{}
+	} else if x.w == io.Writer(x.compressor) {
+		// The compression was already started along with the embedded type.
+		return
+	}
+	x.compressor.reset(x.w, x.opts.Compression)
+	x.w = x.compressor
+}
+
+// finishCompression writes the compressed body, and unwraps the root writer of the compressor.
+func (x *Composer) finishCompression() error {
+	if x.compressor == nil || x.w != io.Writer(x.compressor) {
+		return nil
+	}
+	x.w = x.compressor.w
+	n, err := x.compressor.writeBody()
+
+	// The body is counted as written once buffered, thus only the difference of the compressed size is added.
+	x.bytesWritten += n - x.compressor.buf.Len()
+	return err
+}
+
+// inflateBody reads the compressed body of the value, and replaces the reader of the extractor with the one
+// of the decompressed body. The compressed value reader is kept, so that the checksum trailer could be verified.
+func (x *Extractor) inflateBody() error {
+	// 1. Read the compressed body. Its size is not trusted, thus the body is read in chunks and the buffer grows
+	//    only as far as the input does.
+	size, n, err := bstio.ReadUint(x.r, false)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read compressed body size")
+	}
+	x.bytesRead += n
+	if remaining, ok := bstio.RemainingSize(x.r); ok && uint64(size) > uint64(remaining) {
+		return bsterr.Err(bsterr.CodeTruncatedBinary, "compressed body exceeds the input").
+			WithDetails(bsterr.D("size", size), bsterr.D("remaining", remaining))
+	}
+	var data bytes.Buffer
+	data.Grow(int(min(size, 1<<16)))
+	read, err := io.Copy(&data, io.LimitReader(x.r, int64(min(uint64(size), math.MaxInt64))))
+	x.bytesRead += int(read)
+	if err == nil && uint64(read) < uint64(size) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeTruncatedBinary, "failed to read compressed body").
+			WithDetails(bsterr.D("size", size), bsterr.D("read", read))
+	}
+
+	// 2. Decompress the body, within the decompressed size limit and the remaining decode budget.
+	body, err := x.compression.Decompress(data.Bytes(), x.maxDecompressedSize())
+	if err != nil {
+		return err
+	}
+
+	// 3. Extract the value out of the decompressed body, whose reads still use the decode budget.
+	x.compressed = x.r
+	x.r = bytes.NewReader(body)
+	if x.opts.Budget != nil {
+		x.r = &budgetReader{ReadSeeker: x.r, budget: x.opts.Budget}
+	}
+	return nil
+}
+
+// inflateField decompresses the value of the deflate encoded field, within the decompressed size limit
+// and the remaining decode budget. The inflated bytes are used from the budget, on top of the read ones.
+func (x *Extractor) inflateField(v []byte) ([]byte, error) {
+	dv, err := bstio.Inflate(v, x.maxDecompressedSize())
+	if err != nil {
+		return nil, err
+	}
+	if x.opts.Budget != nil {
+		if err = x.opts.Budget.useBytes(len(dv)); err != nil {
+			return nil, err
+		}
+	}
+	return dv, nil
+}
+
+// maxDecompressedSize returns the maximum size of the decompressed binary, which is the MaxDecompressedBytes
+// bound by the remaining decode budget. The zero size means no limit.
+func (x *Extractor) maxDecompressedSize() int64 {
+	maxSize := x.opts.MaxDecompressedBytes
+	if maxSize == 0 {
+		maxSize = DefaultMaxDecompressedBytes
+	}
+	if x.opts.Budget != nil && x.opts.Budget.maxBytes > 0 {
+		used, _ := x.opts.Budget.Used()
+		remaining := max(x.opts.Budget.maxBytes-used, 1)
+		if maxSize < 0 || remaining < maxSize {
+			maxSize = remaining
+		}
+	}
+	return max(maxSize, 0)
+}
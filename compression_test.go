@@ -0,0 +1,276 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestComposerCompression(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "body", Type: bsttype.String()},
+		{Index: 2, Name: "tags", Type: &bsttype.Array{Type: bsttype.String()}},
+		{Index: 3, Name: "count", Type: bsttype.Uint32()},
+	}}
+	body := strings.Repeat("compressible document body ", 100)
+	tags := []string{"a", "b", "c"}
+	keys := &SignatureKeys{CurrentID: 1, Keys: map[uint32]SigningKey{
+		1: {Algorithm: SignHMACSHA256, Key: []byte("secret")},
+	}}
+	// compose writes the struct value with given options.
+	compose := func(t *testing.T, opts ComposerOptions) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteString(body); err != nil {
+			t.Fatalf("writing string failed: %v", err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, tag := range tags {
+				if err := ac.WriteString(tag); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, len(tags))
+		if err != nil {
+			t.Fatalf("writing array failed: %v", err)
+		}
+		if err = c.WriteUint32(42); err != nil {
+			t.Fatalf("writing uint32 failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		if c.Stats().Total != buf.Len() {
+			t.Fatalf("unexpected bytes written: %d != %d", c.Stats().Total, buf.Len())
+		}
+		return buf.Bytes()
+	}
+	// extract reads the struct value out of r, and verifies its fields.
+	extract := func(r *bytes.Reader, opts ExtractorOptions) error {
+		if opts.ExpectedType == nil {
+			opts.ExpectedType = st
+		}
+		x, err := NewExtractor(r, opts)
+		if err != nil {
+			return err
+		}
+		defer x.Close()
+		var (
+			s     string
+			read  []string
+			count uint32
+		)
+		for x.Next() {
+			switch x.Index() {
+			case 0:
+				s, err = x.ReadString()
+			case 1:
+				err = x.ReadArray(func(ax *Extractor) error {
+					for ax.Next() {
+						tag, err := ax.ReadString()
+						if err != nil {
+							return err
+						}
+						read = append(read, tag)
+					}
+					return ax.Err()
+				})
+			case 2:
+				count, err = x.ReadUint32()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err = x.Err(); err != nil {
+			return err
+		}
+		if s != body || strings.Join(read, ",") != "a,b,c" || count != 42 {
+			t.Fatalf("unexpected value: %d bytes, %v, %d", len(s), read, count)
+		}
+		return nil
+	}
+	hasCode := func(err error, code bsterr.ErrCode) bool {
+		var be *bsterr.Error
+		return errors.As(err, &be) && be.Code == code
+	}
+
+	testCases := []struct {
+		name  string
+		opts  ComposerOptions
+		xOpts ExtractorOptions
+	}{
+		{name: "Plain"},
+		{name: "CompatibilityMode", opts: ComposerOptions{CompatibilityMode: true}},
+		{name: "LengthPrefixed", opts: ComposerOptions{CompatibilityMode: true, LengthPrefixedCollections: true}},
+		{name: "EmbedType", opts: ComposerOptions{EmbedType: true}, xOpts: ExtractorOptions{ExpectedType: st}},
+		{name: "Checksum", opts: ComposerOptions{Checksum: bstio.ChecksumCRC32C}},
+		{name: "Signature", opts: ComposerOptions{Signature: keys}, xOpts: ExtractorOptions{Signature: keys}},
+		{name: "Streaming", xOpts: ExtractorOptions{Streaming: true}},
+	}
+	for _, kind := range []bstio.CompressionKind{bstio.CompressionDeflate, bstio.CompressionGzip} {
+		for _, tc := range testCases {
+			t.Run(kind.String()+"/"+tc.name, func(t *testing.T) {
+				opts := tc.opts
+				opts.Compression = kind
+				data := compose(t, opts)
+				plain := compose(t, tc.opts)
+				if len(data) >= len(plain)/4 {
+					t.Fatalf("value not compressed: %d >= %d/4", len(data), len(plain))
+				}
+
+				// 1. The compressed value is decompressed transparently.
+				if err := extract(bytes.NewReader(data), tc.xOpts); err != nil {
+					t.Fatalf("extracting compressed value failed: %v", err)
+				}
+				if tc.opts.Signature != nil {
+					return
+				}
+
+				// 2. The compressed values are read one after another, or verified by their checksums.
+				r := bytes.NewReader(append(bytes.Clone(data), data...))
+				for i := 0; i < 2; i++ {
+					if tc.opts.Checksum == bstio.ChecksumNone {
+						if err := extract(r, tc.xOpts); err != nil {
+							t.Fatalf("extracting %d compressed value failed: %v", i, err)
+						}
+						continue
+					}
+					x, err := NewExtractor(r, ExtractorOptions{ExpectedType: st, RequireChecksum: true})
+					if err != nil {
+						t.Fatalf("creating extractor failed: %v", err)
+					}
+					if err = x.VerifyAndClose(); err != nil {
+						t.Fatalf("verifying %d checksum failed: %v", i, err)
+					}
+				}
+				if r.Len() != 0 {
+					t.Fatalf("unexpected remaining bytes: %d", r.Len())
+				}
+
+				// 3. The truncated compressed body fails.
+				if err := extract(bytes.NewReader(data[:len(data)-10]), tc.xOpts); err == nil {
+					t.Fatalf("expected truncated body error")
+				}
+			})
+		}
+	}
+
+	t.Run("Budget", func(t *testing.T) {
+		data := compose(t, ComposerOptions{Compression: bstio.CompressionDeflate})
+		if err := extract(bytes.NewReader(data), ExtractorOptions{Budget: NewDecodeBudget(int64(len(body)*2), 0)}); err != nil {
+			t.Fatalf("extracting compressed value failed: %v", err)
+		}
+		err := extract(bytes.NewReader(data), ExtractorOptions{Budget: NewDecodeBudget(int64(len(body)/2), 0)})
+		if !hasCode(err, bsterr.CodeValueTooLarge) {
+			t.Fatalf("expected decompressed size error, got: %v", err)
+		}
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		if _, err := NewOptions(ForIndexKey(), WithCompression(bstio.CompressionGzip)); !hasCode(err, bsterr.CodeInvalidValue) {
+			t.Fatalf("expected comparable compression error, got: %v", err)
+		}
+		if _, err := NewComposer(&bytes.Buffer{}, st, ComposerOptions{Compression: 15}); !hasCode(err, bsterr.CodeInvalidValue) {
+			t.Fatalf("expected unknown compression error, got: %v", err)
+		}
+		o, err := NewOptions(WithCompression(bstio.CompressionGzip), WithChecksum(bstio.ChecksumXXHash64))
+		if err != nil {
+			t.Fatalf("creating options failed: %v", err)
+		}
+		data := compose(t, o.ComposerOptions())
+		if err = extract(bytes.NewReader(data), o.ExtractorOptions(st)); err != nil {
+			t.Fatalf("extracting compressed value failed: %v", err)
+		}
+
+		// The header extension of an unknown compression kind is rejected.
+		data[1] = 0xf0
+		if err = extract(bytes.NewReader(data), ExtractorOptions{}); !hasCode(err, bsterr.CodeMalformedBinary) {
+			t.Fatalf("expected unknown compression error, got: %v", err)
+		}
+	})
+
+	t.Run("MaxDecompressedBytes", func(t *testing.T) {
+		data := compose(t, ComposerOptions{Compression: bstio.CompressionGzip})
+		err := extract(bytes.NewReader(data), ExtractorOptions{MaxDecompressedBytes: int64(len(body) / 2)})
+		if !hasCode(err, bsterr.CodeValueTooLarge) {
+			t.Fatalf("expected decompressed size error, got: %v", err)
+		}
+		if err = extract(bytes.NewReader(data), ExtractorOptions{MaxDecompressedBytes: -1}); err != nil {
+			t.Fatalf("extracting unlimited compressed value failed: %v", err)
+		}
+	})
+
+	t.Run("HugeBodySize", func(t *testing.T) {
+		// The declared size of the compressed body is not allocated upfront.
+		data := compose(t, ComposerOptions{Compression: bstio.CompressionDeflate})
+		data = append(append(data[:2:2], bstio.MarshalUint(math.MaxInt64, false)...), 0x01, 0x02)
+		for _, streaming := range []bool{false, true} {
+			err := extract(bytes.NewReader(data), ExtractorOptions{Streaming: streaming})
+			if !hasCode(err, bsterr.CodeTruncatedBinary) {
+				t.Fatalf("expected truncated body error while streaming: %v, got: %v", streaming, err)
+			}
+		}
+	})
+
+	t.Run("CompressEmbeddedType", func(t *testing.T) {
+		opts := ComposerOptions{EmbedType: true, Compression: bstio.CompressionDeflate}
+		plain := compose(t, opts)
+		opts.CompressEmbeddedType = true
+		opts.Checksum = bstio.ChecksumCRC32C
+		data := compose(t, opts)
+		if data[1]&compressedHeaderFlag == 0 {
+			t.Fatalf("compressed embedded type not flagged: %x", data[1])
+		}
+		if bytes.Contains(data, []byte("count")) || !bytes.Contains(plain, []byte("count")) {
+			t.Fatalf("embedded type not compressed")
+		}
+
+		// 1. The embedded type is read out of the decompressed body.
+		x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{RequireChecksum: true})
+		if err != nil {
+			t.Fatalf("creating extractor failed: %v", err)
+		}
+		if !bsttype.TypesEqual(x.EmbedType(), st) {
+			t.Fatalf("unexpected embedded type: %v", x.EmbedType())
+		}
+		if err = x.VerifyAndClose(); err != nil {
+			t.Fatalf("verifying checksum failed: %v", err)
+		}
+		if err = extract(bytes.NewReader(data), ExtractorOptions{ExpectedType: st}); err != nil {
+			t.Fatalf("extracting compressed value failed: %v", err)
+		}
+
+		// 2. The lookups decompress the value as well.
+		v, err := Lookup(data, nil, "count")
+		if err != nil {
+			t.Fatalf("looking up compressed value failed: %v", err)
+		}
+		if v.String() != "Uint32(42)" {
+			t.Fatalf("unexpected looked up value: %s", v)
+		}
+
+		// 3. The flag requires the compression.
+		if _, err = NewComposer(&bytes.Buffer{}, st, ComposerOptions{CompressEmbeddedType: true}); !hasCode(err, bsterr.CodeInvalidValue) {
+			t.Fatalf("expected compressed embedded type error, got: %v", err)
+		}
+		if _, err = NewOptions(WithCompressedEmbeddedType()); !hasCode(err, bsterr.CodeInvalidValue) {
+			t.Fatalf("expected compressed embedded type options error, got: %v", err)
+		}
+		data[1] = compressedHeaderFlag
+		if _, err = NewExtractor(bytes.NewReader(data), ExtractorOptions{}); !hasCode(err, bsterr.CodeMalformedBinary) {
+			t.Fatalf("expected malformed header extension error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package bst
+
+import (
+	"io"
+	"math"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// ValueCount is the cardinality of the value, counted without decoding its elements.
+type ValueCount struct {
+	// Elements is the number of the elements of the top-level array or map. It is zero for the other types.
+	Elements int
+	// Structs is the number of the counted structs, i.e. the top-level struct, the struct elements of the top-level
+	// array or the struct values of the top-level map.
+	Structs int
+	// Fields are the numbers of the counted structs containing the field, by the field names. The compatibility mode
+	// structs contain only the fields written in their binaries, whereas the other structs contain all the fields.
+	Fields map[string]int
+}
+
+// Count counts the elements of the top-level array or map of type t read from r, along with the presence of the fields
+// of its structs, using only the skip and seek operations. It is meant for the cheap cardinality checks before deciding
+// to decode the value as a whole. The named types need to be resolved.
+func Count(r io.Reader, t bsttype.Type, opts ExtractorOptions) (ValueCount, error) {
+	// 1. Create the extractor of the value, of the underlying type of the named type.
+	bt := derefNamedType(t)
+	if bt == nil {
+		return ValueCount{}, bsterr.Err(bsterr.CodeInvalidType, "unresolved named type to count").WithDetail("type", t)
+	}
+	opts.ExpectedType = bt
+	x, err := NewExtractor(r, opts)
+	if err != nil {
+		return ValueCount{}, err
+	}
+	defer x.Close()
+
+	// 2. Count the struct fields or the collection elements.
+	var vc ValueCount
+	switch tt := bt.(type) {
+	case *bsttype.Struct:
+		vc.Fields = make(map[string]int, len(tt.Fields))
+		err = x.countStructFields(tt, &vc)
+	case *bsttype.Array:
+		err = x.countElements(tt.Type, &vc)
+	case *bsttype.Map:
+		err = x.countElements(tt.Value.Type, &vc)
+	}
+	if err != nil {
+		return ValueCount{}, err
+	}
+	return vc, nil
+}
+
+// countElements counts the elements of the array or map extractor, and the fields of its struct elements.
+func (x *Extractor) countElements(et bsttype.Type, vc *ValueCount) error {
+	st, _ := derefNamedType(et).(*bsttype.Struct)
+	if st != nil {
+		vc.Fields = make(map[string]int, len(st.Fields))
+	}
+
+	// 1. The declared length is taken as it is, unless the fields of the compatibility mode struct elements are counted.
+	if x.maxIndex != math.MaxInt && (st == nil || !x.opts.CompatibilityMode) {
+		vc.Elements = x.maxIndex + 1
+		if st != nil {
+			vc.addStructs(st, vc.Elements)
+		}
+		return nil
+	}
+
+	// 2. Otherwise, the elements are iterated over. The map keys are skipped, and the struct values are counted.
+	_, isMap := x.embedType.(*bsttype.Map)
+	for x.Next() {
+		if isMap && !x.KeyDone() {
+			if _, err := x.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		vc.Elements++
+
+		var err error
+		switch {
+		case st != nil && x.opts.CompatibilityMode:
+			err = x.ReadStruct(func(sx *Extractor) error {
+				return sx.countStructFields(st, vc)
+			})
+		case st != nil:
+			vc.addStructs(st, 1)
+			_, err = x.Skip()
+		default:
+			_, err = x.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return x.Err()
+}
+
+// countStructFields counts the fields of the struct extractor. The fields of the compatibility mode struct are counted
+// by their field headers, and their binaries are seeked over.
+func (x *Extractor) countStructFields(st *bsttype.Struct, vc *ValueCount) error {
+	// 1. The non-compatibility mode struct contains all the fields, and its binary is skipped on finishing it.
+	if !x.opts.CompatibilityMode {
+		vc.addStructs(st, 1)
+		return nil
+	}
+
+	// 2. Read the field headers, and seek over the field binaries.
+	vc.Structs++
+	for x.embed.index <= x.embed.maxIndex {
+		fh, err := x.readCompatibleField()
+		if err != nil {
+			return err
+		}
+		if _, err = x.r.Seek(int64(fh.length), io.SeekCurrent); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to seek to the next field")
+		}
+		x.bytesRead += fh.length
+		x.embed.index++
+
+		// 2.1. The fields unknown to the type, i.e. written by its newer version, are not counted.
+		if f, _, ok := st.FieldByIndex(uint(fh.index)); ok {
+			vc.Fields[f.Name]++
+		}
+	}
+
+	// 3. All the fields are already seeked over, thus there is nothing left to finish.
+	x.elemDone = true
+	return nil
+}
+
+// addStructs counts n structs containing all the fields.
+func (x *ValueCount) addStructs(st *bsttype.Struct, n int) {
+	x.Structs += n
+	for _, f := range st.Fields {
+		x.Fields[f.Name] += n
+	}
+}
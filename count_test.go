@@ -0,0 +1,132 @@
+package bst
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestCount(t *testing.T) {
+	type itemV1 struct {
+		Sku   string `bst:"sku"`
+		Price uint32 `bst:"price"`
+	}
+	type itemV2 struct {
+		Sku   string   `bst:"sku"`
+		Price uint32   `bst:"price"`
+		Tags  []string `bst:"tags"`
+	}
+	v1, err := StructTypeOf(itemV1{})
+	if err != nil {
+		t.Fatalf("struct type failed: %v", err)
+	}
+	v2, err := StructTypeOf(itemV2{})
+	if err != nil {
+		t.Fatalf("struct type failed: %v", err)
+	}
+	opts := EncodingOptions{CompatibilityMode: true}
+
+	// compose writes the collection of n older version items, whose map keys are the item numbers.
+	compose := func(t *testing.T, ct bsttype.Type, n int) []byte {
+		var buf bytes.Buffer
+		co := opts.ComposerOptions()
+		co.Length = n
+		c, err := NewComposer(&buf, ct, co)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		for i := 0; i < n; i++ {
+			if ct.Kind() == bsttype.KindMap {
+				if err = c.WriteUint8(uint8(i)); err != nil {
+					t.Fatalf("writing key failed: %v", err)
+				}
+			}
+			err = c.WriteStruct(func(sc *Composer) error {
+				if err := sc.WriteString("sku"); err != nil {
+					return err
+				}
+				return sc.WriteUint32(uint32(i))
+			})
+			if err != nil {
+				t.Fatalf("writing item failed: %v", err)
+			}
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	t.Run("Collections", func(t *testing.T) {
+		collections := map[string][2]bsttype.Type{
+			"Array": {bsttype.ArrayOf(v1), bsttype.ArrayOf(v2)},
+			"Map":   {bsttype.MapTypeOf(bsttype.Uint8(), v1, false, false), bsttype.MapTypeOf(bsttype.Uint8(), v2, false, false)},
+		}
+		for name, types := range collections {
+			data := compose(t, types[0], 3)
+			vc, err := Count(bytes.NewReader(data), types[1], opts.ExtractorOptions(nil))
+			if err != nil {
+				t.Fatalf("counting %s failed: %v", name, err)
+			}
+			expected := ValueCount{Elements: 3, Structs: 3, Fields: map[string]int{"sku": 3, "price": 3}}
+			if !reflect.DeepEqual(vc, expected) {
+				t.Fatalf("unexpected %s count: %+v, expected: %+v", name, vc, expected)
+			}
+		}
+	})
+
+	t.Run("Struct", func(t *testing.T) {
+		// The fields of the older version of the type are present, whereas the new one is not.
+		data, err := Marshal(itemV1{Sku: "a", Price: 1}, WithCompatibilityMode())
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		vc, err := Count(bytes.NewReader(data), v2, EncodingOptions{CompatibilityMode: true}.ExtractorOptions(nil))
+		if err != nil {
+			t.Fatalf("count failed: %v", err)
+		}
+		expected := ValueCount{Structs: 1, Fields: map[string]int{"sku": 1, "price": 1}}
+		if !reflect.DeepEqual(vc, expected) {
+			t.Fatalf("unexpected count: %+v, expected: %+v", vc, expected)
+		}
+
+		// The non-compatibility mode struct contains all the fields.
+		data, err = Marshal(itemV2{Sku: "a"})
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		if vc, err = Count(bytes.NewReader(data), v2, ExtractorOptions{}); err != nil {
+			t.Fatalf("count failed: %v", err)
+		}
+		expected.Fields["tags"] = 1
+		if !reflect.DeepEqual(vc, expected) {
+			t.Fatalf("unexpected count: %+v, expected: %+v", vc, expected)
+		}
+	})
+
+	t.Run("Array", func(t *testing.T) {
+		at := bsttype.ArrayOf(bsttype.Uint16())
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, at, ComposerOptions{Length: 100})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		for i := 0; i < 100; i++ {
+			if err = c.WriteUint16(uint16(i)); err != nil {
+				t.Fatalf("writing element failed: %v", err)
+			}
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		vc, err := Count(bytes.NewReader(buf.Bytes()), at, ExtractorOptions{})
+		if err != nil {
+			t.Fatalf("count failed: %v", err)
+		}
+		if vc.Elements != 100 || vc.Structs != 0 {
+			t.Fatalf("unexpected count: %+v", vc)
+		}
+	})
+}
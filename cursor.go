@@ -0,0 +1,228 @@
+package bst
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+// CursorDirection is the direction of the paginated scan.
+type CursorDirection uint8
+
+// Enumerated cursor directions.
+const (
+	CursorForward CursorDirection = iota
+	CursorBackward
+)
+
+// Cursor is the position of the paginated scan over the comparable keys, encoded as an opaque pagination token.
+type Cursor struct {
+	// Prefix is the prefix of all the scanned keys.
+	Prefix []byte
+	// LastKey is the last key seen by the scan, including the prefix. The nil key means the scan didn't start yet.
+	LastKey []byte
+	// Direction is the direction of the scan.
+	Direction CursorDirection
+	// Snapshot identifies the snapshot the scan reads from, i.e. the read timestamp or the sequence number,
+	// so that all the pages are consistent with each other.
+	Snapshot uint64
+}
+
+// CursorOptions are the options of the cursor token encoding.
+type CursorOptions struct {
+	// Secret is the HMAC-SHA256 key of the cursor tokens. If defined, the tokens are signed, and the decoded tokens
+	// need to have a valid signature, which makes them tamper-evident.
+	Secret []byte
+}
+
+const (
+	// cursorVersion is the version of the cursor token layout.
+	cursorVersion = 1
+	// cursorSigned is the flag of the signed cursor tokens.
+	cursorSigned = 0x80
+)
+
+var _cursorType = &bsttype.Struct{
+	Fields: []bsttype.StructField{
+		{Index: 1, Name: "Prefix", Type: &bsttype.Bytes{}},
+		{Index: 2, Name: "LastKey", Type: bsttype.NullableOf(&bsttype.Bytes{})},
+		{Index: 3, Name: "Direction", Type: bsttype.Uint8()},
+		{Index: 4, Name: "Snapshot", Type: bsttype.Uint64()},
+	},
+}
+
+// CursorType returns the struct type used to serialize the Cursor.
+// The returned type must not be modified.
+func CursorType() *bsttype.Struct {
+	return _cursorType
+}
+
+// Bounds returns the key range remaining to be scanned, where the start is inclusive and the end is exclusive.
+// The nil end means the range is unbounded.
+func (x Cursor) Bounds() (start, end []byte) {
+	// 1. The range of the keys with the prefix.
+	start, end = x.Prefix, prefixEnd(x.Prefix)
+	if x.LastKey == nil {
+		return start, end
+	}
+
+	// 2. Narrow it to the keys after the last seen one, in the scan direction.
+	if x.Direction == CursorBackward {
+		return start, x.LastKey
+	}
+	return append(append(make([]byte, 0, len(x.LastKey)+1), x.LastKey...), 0x00), end
+}
+
+// prefixEnd returns the least key greater than all the keys with the prefix, or nil if there is no such key.
+func prefixEnd(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			end := append([]byte(nil), prefix[:i+1]...)
+			end[i]++
+			return end
+		}
+	}
+	return nil
+}
+
+// validate verifies that the cursor is well-formed.
+func (x Cursor) validate() error {
+	if x.Direction > CursorBackward {
+		return bsterr.Err(bsterr.CodeInvalidValue, "invalid cursor direction").WithDetail("direction", x.Direction)
+	}
+	if x.LastKey != nil && !bytes.HasPrefix(x.LastKey, x.Prefix) {
+		return bsterr.Err(bsterr.CodeInvalidValue, "cursor last key doesn't have the cursor prefix")
+	}
+	return nil
+}
+
+// EncodeCursor encodes the cursor as the opaque URL safe token. The token is composed of the version header,
+// the comparable binary of the CursorType value and optionally the signature.
+// The comparable binary makes the tokens of the same scan ordered by their positions.
+func EncodeCursor(c Cursor, opts CursorOptions) (string, error) {
+	// 1. Verify the cursor.
+	if err := c.validate(); err != nil {
+		return "", err
+	}
+
+	// 2. Write the version header.
+	header := byte(cursorVersion)
+	if opts.Secret != nil {
+		header |= cursorSigned
+	}
+	buf := bytes.NewBuffer([]byte{header})
+
+	// 3. Compose the cursor value.
+	cc, err := NewComposer(buf, _cursorType, ComposerOptions{Comparable: true})
+	if err != nil {
+		return "", err
+	}
+	if err = cc.WriteBytes(c.Prefix); err != nil {
+		return "", err
+	}
+	if c.LastKey == nil {
+		err = cc.WriteNull()
+	} else if err = cc.WriteNotNull(); err == nil {
+		err = cc.WriteBytes(c.LastKey)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err = cc.WriteUint8(uint8(c.Direction)); err != nil {
+		return "", err
+	}
+	if err = cc.WriteUint64(c.Snapshot); err != nil {
+		return "", err
+	}
+
+	// 4. Sign the token, if the secret is defined.
+	token := buf.Bytes()
+	if opts.Secret != nil {
+		token = append(token, cursorMAC(opts.Secret, token)...)
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// DecodeCursor decodes the cursor token encoded by the EncodeCursor with the same options.
+// If the options define the secret, the token needs to be signed with it.
+func DecodeCursor(token string, opts CursorOptions) (Cursor, error) {
+	// 1. Decode the token and verify its version.
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, bsterr.ErrWrap(err, bsterr.CodeMalformedBinary, "malformed cursor token")
+	}
+	if len(data) == 0 || data[0]&^cursorSigned != cursorVersion {
+		return Cursor{}, bsterr.Err(bsterr.CodeMalformedBinary, "unsupported cursor token version")
+	}
+
+	// 2. Verify and strip the signature. The signed flag needs to match the options,
+	//    so that the signature could not be stripped from the token.
+	signed := data[0]&cursorSigned != 0
+	if signed != (opts.Secret != nil) {
+		return Cursor{}, bsterr.Err(bsterr.CodeMalformedBinary, "cursor token signature mismatch")
+	}
+	if signed {
+		if len(data) < sha256.Size+1 {
+			return Cursor{}, bsterr.Err(bsterr.CodeMalformedBinary, "cursor token signature mismatch")
+		}
+		body := data[:len(data)-sha256.Size]
+		if !hmac.Equal(cursorMAC(opts.Secret, body), data[len(body):]) {
+			return Cursor{}, bsterr.Err(bsterr.CodeMalformedBinary, "cursor token signature mismatch")
+		}
+		data = body
+	}
+
+	// 3. Extract the cursor value.
+	r := iopool.GetReadSeeker(data[1:])
+	defer iopool.ReleaseReadSeeker(r)
+
+	x, err := NewExtractor(r, ExtractorOptions{ExpectedType: _cursorType, Comparable: true})
+	if err != nil {
+		return Cursor{}, err
+	}
+	defer x.Close()
+
+	var c Cursor
+	for x.Next() {
+		switch x.Index() {
+		case 0:
+			c.Prefix, err = x.ReadBytes()
+		case 1:
+			var isNull bool
+			isNull, err = x.IsNull()
+			if err == nil && !isNull {
+				c.LastKey, err = x.ReadBytes()
+			}
+		case 2:
+			var d uint8
+			d, err = x.ReadUint8()
+			c.Direction = CursorDirection(d)
+		case 3:
+			c.Snapshot, err = x.ReadUint64()
+		default:
+			_, err = x.Skip()
+		}
+		if err != nil {
+			return Cursor{}, bsterr.ErrWrap(err, bsterr.CodeMalformedBinary, "malformed cursor token")
+		}
+	}
+	if err = x.Err(); err != nil {
+		return Cursor{}, bsterr.ErrWrap(err, bsterr.CodeMalformedBinary, "malformed cursor token")
+	}
+	if err = c.validate(); err != nil {
+		return Cursor{}, err
+	}
+	return c, nil
+}
+
+// cursorMAC returns the HMAC-SHA256 signature of the token body.
+func cursorMAC(secret, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
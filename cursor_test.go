@@ -0,0 +1,115 @@
+package bst
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	cursors := []Cursor{
+		{Prefix: []byte("users/")},
+		{Prefix: []byte("users/"), LastKey: []byte("users/\x00bob"), Snapshot: 42},
+		{Prefix: []byte{}, LastKey: []byte("carol"), Direction: CursorBackward, Snapshot: 7},
+	}
+	for _, opts := range []CursorOptions{{}, {Secret: []byte("secret")}} {
+		for _, c := range cursors {
+			token, err := EncodeCursor(c, opts)
+			if err != nil {
+				t.Fatalf("encoding cursor failed: %v", err)
+			}
+			got, err := DecodeCursor(token, opts)
+			if err != nil {
+				t.Fatalf("decoding cursor failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, c) {
+				t.Fatalf("unexpected cursor: %+v, expected: %+v", got, c)
+			}
+		}
+	}
+
+	t.Run("Ordered", func(t *testing.T) {
+		// The tokens of the same scan are ordered by their last keys.
+		var prev []byte
+		for _, k := range []string{"a/", "a/\x00", "a/b", "a/ba", "a/c"} {
+			token, err := EncodeCursor(Cursor{Prefix: []byte("a/"), LastKey: []byte(k)}, CursorOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := base64.RawURLEncoding.DecodeString(token)
+			if bytes.Compare(prev, data) >= 0 {
+				t.Fatalf("cursor token of %q is not ordered after the previous one", k)
+			}
+			prev = data
+		}
+	})
+
+	t.Run("Bounds", func(t *testing.T) {
+		tests := []struct {
+			c          Cursor
+			start, end []byte
+		}{
+			{c: Cursor{Prefix: []byte("ab")}, start: []byte("ab"), end: []byte("ac")},
+			{c: Cursor{Prefix: []byte{0x01, 0xff}}, start: []byte{0x01, 0xff}, end: []byte{0x02}},
+			{c: Cursor{Prefix: []byte{0xff}}, start: []byte{0xff}, end: nil},
+			{c: Cursor{Prefix: []byte("ab"), LastKey: []byte("abc")}, start: []byte("abc\x00"), end: []byte("ac")},
+			{c: Cursor{Prefix: []byte("ab"), LastKey: []byte("abc"), Direction: CursorBackward}, start: []byte("ab"), end: []byte("abc")},
+		}
+		for _, tc := range tests {
+			start, end := tc.c.Bounds()
+			if !bytes.Equal(start, tc.start) || !bytes.Equal(end, tc.end) || (end == nil) != (tc.end == nil) {
+				t.Fatalf("unexpected bounds of %+v: [%q, %q), expected: [%q, %q)", tc.c, start, end, tc.start, tc.end)
+			}
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		c := cursors[1]
+		secret := CursorOptions{Secret: []byte("secret")}
+		signed, err := EncodeCursor(c, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unsigned, err := EncodeCursor(c, CursorOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, _ := base64.RawURLEncoding.DecodeString(signed)
+		data[len(data)-sha256.Size-1] ^= 0x01
+		tampered := base64.RawURLEncoding.EncodeToString(data)
+
+		tests := []struct {
+			name  string
+			token string
+			opts  CursorOptions
+		}{
+			{name: "ModifiedBody", token: tampered, opts: secret},
+			{name: "OtherSecret", token: signed, opts: CursorOptions{Secret: []byte("other")}},
+			{name: "StrippedSignature", token: unsigned, opts: secret},
+			{name: "NoSecret", token: signed, opts: CursorOptions{}},
+			{name: "Malformed", token: "!", opts: CursorOptions{}},
+			{name: "Empty", token: "", opts: CursorOptions{}},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				if _, err := DecodeCursor(tc.token, tc.opts); err == nil {
+					t.Fatal("expected decoding cursor to fail")
+				}
+			})
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, c := range []Cursor{
+			{Prefix: []byte("a/"), LastKey: []byte("b/")},
+			{Direction: 2},
+		} {
+			if _, err := EncodeCursor(c, CursorOptions{}); err == nil {
+				t.Fatalf("expected encoding cursor %+v to fail", c)
+			}
+		}
+	})
+}
@@ -76,11 +76,14 @@ func (x *Extractor) ReadDateTime() (time.Time, error) {
 	}
 
 	// 4. Read the datetime value.
-	v, n, err := bstio.ReadDateTime(x.r, x.opts.Descending, dt.Location())
+	v, n, err := bstio.ReadDateTime(x.r, x.elemDesc, dt.Location())
 	x.bytesRead += n
 	if err != nil {
 		return time.Time{}, err
 	}
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
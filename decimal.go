@@ -0,0 +1,92 @@
+package bst
+
+import (
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// WriteDecimal writes the decimal value to the composer.
+func (x *Composer) WriteDecimal(v bstio.Decimal) error {
+	// 1. Check if the element was already written.
+	if x.done {
+		return bsterr.Err(bsterr.CodeAlreadyWritten, "element already written")
+	}
+
+	// 2. Verify if current element matches expected type.
+	if x.elemType.Kind() != bsttype.KindDecimal {
+		return bsterr.Err(bsterr.CodeInvalidType, "invalid type to write").
+			WithDetails(
+				bsterr.D("expected", bsttype.KindDecimal),
+				bsterr.D("actual", x.elemType.Kind()),
+			)
+	}
+
+	// 3. Verify the decimal, before anything is written.
+	if err := v.Validate(); err != nil {
+		return err
+	}
+
+	// 4. If the base is a struct, check if the field header needs to be written.
+	if x.needWriteFieldHeader() {
+		n, err := x.writeFieldHeader(x.w, x.fieldIndex(), bstio.DecimalBinarySize(v))
+		if err != nil {
+			return err
+		}
+
+		x.bytesWritten += n
+	}
+
+	// 5. Write the value.
+	n, err := bstio.WriteDecimalAlloc(x.w, v, x.elemDesc, x.opts.Allocator)
+	if err != nil {
+		return err
+	}
+
+	x.bytesWritten += n
+
+	// 6. Mark the element as written.
+	if err = x.finishElem(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadDecimal reads the decimal value from the extractor. The decimal is normalized, thus its scale
+// might differ from the written one, i.e. the 1.50 is read as 1.5.
+func (x *Extractor) ReadDecimal() (bstio.Decimal, error) {
+	if x.err != nil {
+		return bstio.Decimal{}, x.err
+	}
+	// 1. Check if reading element value is already finished.
+	if x.elemDone {
+		return bstio.Decimal{}, bsterr.Err(bsterr.CodeAlreadyRead, "elem already done")
+	}
+
+	// 2. Check if current element is still in range.
+	if x.index > x.maxIndex {
+		return bstio.Decimal{}, bsterr.Err(bsterr.CodeOutOfBounds, "buffIndex out of bounds")
+	}
+
+	// 3. Verify if current element matches the expected type.
+	if x.elemType.Kind() != bsttype.KindDecimal {
+		return bstio.Decimal{}, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
+			WithDetails(
+				bsterr.D("expected", bsttype.KindDecimal),
+				bsterr.D("actual", x.elemType.Kind()),
+			)
+	}
+
+	// 4. Read the decimal.
+	v, n, err := bstio.ReadDecimal(x.r, x.elemDesc)
+	x.bytesRead += n
+	if err != nil {
+		return bstio.Decimal{}, err
+	}
+
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
+	x.finishElem()
+	return v, nil
+}
@@ -0,0 +1,158 @@
+package bst
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// Diff computes a compact patch between two headless binaries of the same struct type t, encoded with given options.
+// The patch contains only the binaries of the fields that differ in the newValue, which makes it suitable
+// for the replication streams, where most of the fields stay unchanged.
+// The newValue binary is reconstructed by the ApplyPatch function.
+//
+// A patch binary is composed of:
+//   - Number of changed fields (uint).
+//   - For each changed field: field index, binary length (uint) and new field binary.
+//   - Number of removed fields (uint) - non-zero only in the compatibility mode.
+//   - For each removed field: field index (uint).
+//
+// In the regular mode, the field index is the position of the field in the struct, and the consecutive
+// boolean fields are treated as a single field, as they share their binary.
+// In the compatibility mode, the field index is the StructField.Index.
+func Diff(oldValue, newValue []byte, t bsttype.Type, options bstio.ValueOptions) ([]byte, error) {
+	// 1. Dereference the named type and verify that it is a struct.
+	st, ok := derefNamedType(t).(*bsttype.Struct)
+	if !ok {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "diff is supported only for struct types").
+			WithDetail("type", t)
+	}
+
+	// 2. Split both values into the field segments.
+	so, err := splitStructSegmentsOf(oldValue, st, options)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to split old value")
+	}
+	sn, err := splitStructSegmentsOf(newValue, st, options)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to split new value")
+	}
+
+	// 3. Find the changed segments.
+	var changed []structSegment
+	for _, sg := range sn {
+		prev, found := findStructSegment(so, sg.index)
+		if found && bytes.Equal(prev.data, sg.data) {
+			continue
+		}
+		changed = append(changed, sg)
+	}
+
+	// 4. Find the removed segments - possible only in the compatibility mode.
+	var removed []uint
+	for _, sg := range so {
+		if _, found := findStructSegment(sn, sg.index); !found {
+			removed = append(removed, sg.index)
+		}
+	}
+
+	// 5. Write the patch. Unlike the struct header, its header is the number of the changed fields.
+	var buf bytes.Buffer
+	if _, err = bstio.WriteUint(&buf, uint(len(changed)), false); err != nil {
+		return nil, err
+	}
+	if err = writeSegmentList(&buf, changed); err != nil {
+		return nil, err
+	}
+	if _, err = bstio.WriteUint(&buf, uint(len(removed)), false); err != nil {
+		return nil, err
+	}
+	for _, index := range removed {
+		if _, err = bstio.WriteUint(&buf, index, false); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// ApplyPatch reconstructs the new value binary, by applying the patch created by the Diff function on the oldValue.
+// The type and options need to be the same as the ones used to create the patch.
+func ApplyPatch(oldValue, patch []byte, t bsttype.Type, options bstio.ValueOptions) ([]byte, error) {
+	// 1. Dereference the named type and verify that it is a struct.
+	st, ok := derefNamedType(t).(*bsttype.Struct)
+	if !ok {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "patch is supported only for struct types").
+			WithDetail("type", t)
+	}
+
+	// 2. Split the old value into the field segments.
+	so, err := splitStructSegmentsOf(oldValue, st, options)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to split old value")
+	}
+
+	// 3. Read the changed and removed fields from the patch.
+	pr := bytes.NewReader(patch)
+	changedCount, _, err := bstio.ReadLength(pr, false, 2)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read patch changes")
+	}
+	changed, err := readSegmentList(patch, pr, int(changedCount))
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read patch changes")
+	}
+	removedCount, _, err := bstio.ReadLength(pr, false, 1)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read patch removals")
+	}
+	removed := make(map[uint]struct{}, removedCount)
+	for i := uint(0); i < removedCount; i++ {
+		index, _, err := bstio.ReadUint(pr, false)
+		if err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read patch removal")
+		}
+		removed[index] = struct{}{}
+	}
+
+	// 4. In the regular mode, all the fields are always present, thus only the changed segments are replaced.
+	var buf bytes.Buffer
+	if !options.CompatibilityMode {
+		if len(removed) > 0 {
+			return nil, bsterr.Err(bsterr.CodeMalformedBinary, "patch removes fields in non compatibility mode")
+		}
+		for _, sg := range changed {
+			if _, found := findStructSegment(so, sg.index); !found {
+				return nil, bsterr.Err(bsterr.CodeMalformedBinary, "patch field not found in the value").
+					WithDetail("index", sg.index)
+			}
+		}
+		for _, sg := range so {
+			if ng, found := findStructSegment(changed, sg.index); found {
+				sg = ng
+			}
+			buf.Write(sg.data)
+		}
+		return buf.Bytes(), nil
+	}
+
+	// 5. In the compatibility mode, the result is a sorted union of not removed old and changed segments.
+	result := changed
+	for _, sg := range so {
+		if _, found := removed[sg.index]; found {
+			continue
+		}
+		if _, found := findStructSegment(changed, sg.index); found {
+			continue
+		}
+		result = append(result, sg)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].index < result[j].index })
+
+	if err = writeStructSegments(&buf, result); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,136 @@
+package bst
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestDiff(t *testing.T) {
+	t.Run("Regular", func(t *testing.T) {
+		st := &bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "ID", Type: bsttype.Uint64()},
+				{Index: 2, Name: "Name", Type: bsttype.String()},
+				{Index: 3, Name: "Description", Type: bsttype.String()},
+				{Index: 4, Name: "Visits", Type: bsttype.Uint32()},
+			},
+		}
+		row := func(t *testing.T, name string, visits uint32) []byte {
+			sv := bstvalue.MustNewStructValue(st, []bstvalue.Value{
+				bstvalue.NewUint64Value(1),
+				bstvalue.NewStringValue(name),
+				bstvalue.NewStringValue("some long description which stays the same"),
+				bstvalue.NewUint32Value(visits),
+			})
+			data, err := sv.MarshalValue(bstio.ValueOptions{Descending: true})
+			if err != nil {
+				t.Fatalf("marshaling row failed: %v", err)
+			}
+			return data
+		}
+
+		oldValue, newValue := row(t, "old", 10), row(t, "new", 11)
+
+		patch, err := Diff(oldValue, newValue, st, bstio.ValueOptions{Descending: true})
+		if err != nil {
+			t.Fatalf("diff failed: %v", err)
+		}
+
+		if len(patch) >= len(newValue) {
+			t.Fatalf("patch is not smaller than the value: %d >= %d", len(patch), len(newValue))
+		}
+
+		got, err := ApplyPatch(oldValue, patch, st, bstio.ValueOptions{Descending: true})
+		if err != nil {
+			t.Fatalf("apply patch failed: %v", err)
+		}
+
+		if !bytes.Equal(got, newValue) {
+			t.Fatalf("unexpected patched value:\n%v\nexpected:\n%v", got, newValue)
+		}
+
+		t.Run("NoChanges", func(t *testing.T) {
+			patch, err = Diff(oldValue, oldValue, st, bstio.ValueOptions{Descending: true})
+			if err != nil {
+				t.Fatalf("diff failed: %v", err)
+			}
+
+			// Empty patch contains only two zero-length headers.
+			if !bytes.Equal(patch, []byte{0x00, 0x00}) {
+				t.Fatalf("unexpected empty patch: %v", patch)
+			}
+		})
+	})
+
+	t.Run("Compatibility", func(t *testing.T) {
+		ct := &bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "A", Type: bsttype.Uint8()},
+				{Index: 2, Name: "B", Type: bsttype.Uint8()},
+				{Index: 3, Name: "C", Type: bsttype.Uint8()},
+			},
+		}
+		// The rows missing a field are composed with the older revisions of the type.
+		oldValue := composeCompatibilityRow(t, &bsttype.Struct{Fields: ct.Fields[:2]}, 5, 3)
+		newValue := composeCompatibilityRow(t, &bsttype.Struct{Fields: []bsttype.StructField{ct.Fields[0], ct.Fields[2]}}, 5, 9)
+		o := bstio.ValueOptions{CompatibilityMode: true}
+
+		patch, err := Diff(oldValue, newValue, ct, o)
+		if err != nil {
+			t.Fatalf("diff failed: %v", err)
+		}
+
+		expected := []byte{
+			0x01, 0x01, // Number of changed fields: 1
+			0x01, 0x03, 0x01, 0x01, 0x09, // C: 9
+			0x01, 0x01, // Number of removed fields: 1
+			0x01, 0x02, // B
+		}
+		if !bytes.Equal(patch, expected) {
+			t.Fatalf("unexpected patch:\n%v\nexpected:\n%v", patch, expected)
+		}
+
+		got, err := ApplyPatch(oldValue, patch, ct, o)
+		if err != nil {
+			t.Fatalf("apply patch failed: %v", err)
+		}
+
+		if !bytes.Equal(got, newValue) {
+			t.Fatalf("unexpected patched value:\n%v\nexpected:\n%v", got, newValue)
+		}
+
+		t.Run("RoundTrip", func(t *testing.T) {
+			// The change of the last field of the composed rows is patched, and the patched row is extracted.
+			oldValue := composeCompatibilityRow(t, ct, 1, 2, 3)
+			newValue := composeCompatibilityRow(t, ct, 1, 2, 99)
+			patch, err := Diff(oldValue, newValue, ct, o)
+			if err != nil {
+				t.Fatalf("diff failed: %v", err)
+			}
+			expected := []byte{
+				0x01, 0x01, // Number of changed fields: 1
+				0x01, 0x03, 0x01, 0x01, 0x63, // C: 99
+				0x00, // Number of removed fields: 0
+			}
+			if !bytes.Equal(patch, expected) {
+				t.Fatalf("unexpected patch:\n%v\nexpected:\n%v", patch, expected)
+			}
+
+			got, err := ApplyPatch(oldValue, patch, ct, o)
+			if err != nil {
+				t.Fatalf("apply patch failed: %v", err)
+			}
+			if !bytes.Equal(got, newValue) {
+				t.Fatalf("unexpected patched value:\n%v\nexpected:\n%v", got, newValue)
+			}
+			fields := extractCompatibilityRow(t, ct, got)
+			if len(fields) != 3 || fields["A"] != 1 || fields["B"] != 2 || fields["C"] != 99 {
+				t.Fatalf("unexpected patched fields: %v", fields)
+			}
+		})
+	})
+}
@@ -80,6 +80,9 @@ func (x *Extractor) ReadDuration() (time.Duration, error) {
 		return 0, err
 	}
 
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, time.Duration(v))
+	}
 	x.finishElem()
 
 	return time.Duration(v), nil
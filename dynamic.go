@@ -0,0 +1,213 @@
+package bst
+
+import (
+	"reflect"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// ReadDynamic reads the current element as the native Go value, chosen by the element type.
+// It is meant for the generic pipelines, i.e. templating or rule engines, which don't know the schema at compile time.
+// The values are returned as:
+//   - Struct - map[string]any of the fields, by their names,
+//   - Array - []any of the elements,
+//   - Map - map[string]any if the key is a string, or map[any]any otherwise, the keys need to be comparable,
+//   - Nullable - nil or the value,
+//   - Enum - string of the element,
+//   - OneOf - map[string]any of a single element, keyed by its name,
+//   - Any - the value of the type read from its header,
+//   - signed integers - int64, unsigned integers - uint64, floats - float64,
+//   - Bytes and ExternalBytes - []byte,
+//   - Timestamp and DateTime - time.Time, Duration - time.Duration,
+//   - Decimal - bstio.Decimal, Bitmap - bstio.Bitmap, UUID - bstio.UUID.
+func (x *Extractor) ReadDynamic() (any, error) {
+	if x.err != nil {
+		return nil, x.err
+	}
+	// 1. Check if reading element value is already finished.
+	if x.elemDone {
+		return nil, bsterr.Err(bsterr.CodeAlreadyRead, "elem already done")
+	}
+
+	// 2. Switch by the composite types.
+	t, err := x.derefType(x.elemType)
+	if err != nil {
+		return nil, err
+	}
+	switch tt := t.(type) {
+	case *bsttype.Struct:
+		var res map[string]any
+		err = x.ReadStruct(func(sx *Extractor) error {
+			res, err = sx.dynamicStruct(tt)
+			return err
+		})
+		return res, err
+	case *bsttype.Array:
+		var res []any
+		err = x.ReadArray(func(ax *Extractor) error {
+			res, err = ax.dynamicArray()
+			return err
+		})
+		return res, err
+	case *bsttype.Map:
+		var res any
+		err = x.ReadMap(func(mx *Extractor) error {
+			res, err = mx.dynamicMap(tt)
+			return err
+		})
+		return res, err
+	case *bsttype.Nullable:
+		isNull, err := x.IsNull()
+		if err != nil || isNull {
+			return nil, err
+		}
+		return x.ReadDynamic()
+	case *bsttype.Enum:
+		index, err := x.ReadEnumIndex()
+		if err != nil {
+			return nil, err
+		}
+		for _, elem := range tt.Elements {
+			if elem.Index == index {
+				return elem.String, nil
+			}
+		}
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "enum element not found").WithDetail("index", index)
+	case *bsttype.OneOf:
+		h, err := x.ReadOneOfHeader()
+		if err != nil {
+			return nil, err
+		}
+		for _, elem := range tt.Elements {
+			if elem.Index == h.Index {
+				v, err := x.ReadDynamic()
+				if err != nil {
+					return nil, err
+				}
+				return map[string]any{elem.Name: v}, nil
+			}
+		}
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "oneof element not found").WithDetail("index", h.Index)
+	case *bsttype.Bytes:
+		return x.ReadBytes()
+	case *bsttype.DateTime:
+		return x.ReadDateTime()
+	}
+
+	// 3. Switch by the kind of the basic types.
+	switch t.Kind() {
+	case bsttype.KindAny:
+		if _, err = x.ReadAnyType(); err != nil {
+			return nil, err
+		}
+		return x.ReadDynamic()
+	case bsttype.KindBoolean:
+		return x.ReadBoolean()
+	case bsttype.KindInt, bsttype.KindInt8, bsttype.KindInt16, bsttype.KindInt32, bsttype.KindInt64:
+		return x.Int()
+	case bsttype.KindUint, bsttype.KindUint8, bsttype.KindUint16, bsttype.KindUint32, bsttype.KindUint64:
+		return x.Uint()
+	case bsttype.KindFloat32:
+		v, err := x.ReadFloat32()
+		return float64(v), err
+	case bsttype.KindFloat64:
+		return x.ReadFloat64()
+	case bsttype.KindString:
+		return x.ReadString()
+	case bsttype.KindTimestamp:
+		return x.ReadTimestamp()
+	case bsttype.KindDuration:
+		return x.ReadDuration()
+	case bsttype.KindExternalBytes:
+		return x.ReadExternalBytes()
+	case bsttype.KindDecimal:
+		return x.ReadDecimal()
+	case bsttype.KindBitmap:
+		return x.ReadBitmap()
+	case bsttype.KindUUID:
+		v, err := x.ReadUUID()
+		return bstio.UUID(v), err
+	default:
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "type could not be read dynamically").WithDetail("type", t)
+	}
+}
+
+// dynamicStruct reads the fields of the struct extractor, by their names.
+func (x *Extractor) dynamicStruct(st *bsttype.Struct) (map[string]any, error) {
+	res := make(map[string]any, len(st.Fields))
+	for x.Next() {
+		if x.Index() >= len(st.Fields) {
+			return nil, bsterr.Err(bsterr.CodeOutOfBounds, "struct field index is out of bounds").
+				WithDetail("index", x.Index())
+		}
+		name := st.Fields[x.Index()].Name
+		v, err := x.ReadDynamic()
+		if err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read struct field").
+				WithDetail("field", name)
+		}
+		res[name] = v
+	}
+	return res, x.Err()
+}
+
+// dynamicArray reads the elements of the array extractor.
+func (x *Extractor) dynamicArray() ([]any, error) {
+	res := []any{}
+	for x.Next() {
+		v, err := x.ReadDynamic()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, x.Err()
+}
+
+// dynamicMap reads the entries of the map extractor, into the map[string]any if the key is a string,
+// or the map[any]any otherwise.
+func (x *Extractor) dynamicMap(mt *bsttype.Map) (any, error) {
+	var (
+		strMap map[string]any
+		anyMap map[any]any
+	)
+	if kt, _ := x.derefType(mt.Key.Type); kt != nil && kt.Kind() == bsttype.KindString {
+		strMap = map[string]any{}
+	} else {
+		anyMap = map[any]any{}
+	}
+	for x.Next() {
+		// 1. Read the key, which needs to be comparable to be used in the map.
+		k, err := x.ReadDynamic()
+		if err != nil {
+			return nil, err
+		}
+		if k != nil && !reflect.TypeOf(k).Comparable() {
+			return nil, bsterr.Err(bsterr.CodeInvalidType, "map key is not comparable").
+				WithDetail("type", mt.Key.Type)
+		}
+
+		// 2. Read the value.
+		if !x.Next() {
+			break
+		}
+		v, err := x.ReadDynamic()
+		if err != nil {
+			return nil, err
+		}
+		if strMap != nil {
+			strMap[k.(string)] = v
+		} else {
+			anyMap[k] = v
+		}
+	}
+	if err := x.Err(); err != nil {
+		return nil, err
+	}
+	if strMap != nil {
+		return strMap, nil
+	}
+	return anyMap, nil
+}
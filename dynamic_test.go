@@ -0,0 +1,112 @@
+package bst
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestExtractorReadDynamic(t *testing.T) {
+	type inner struct {
+		Score float32 `bst:"score"`
+	}
+	type record struct {
+		Name    string           `bst:"name"`
+		Count   int32            `bst:"count"`
+		Tags    []string         `bst:"tags"`
+		Attrs   map[string]uint8 `bst:"attrs"`
+		Parent  *string          `bst:"parent"`
+		Inner   inner            `bst:"inner"`
+		Created time.Time        `bst:"created"`
+	}
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	v := record{
+		Name:    "rec",
+		Count:   -3,
+		Tags:    []string{"a", "b"},
+		Attrs:   map[string]uint8{"x": 1},
+		Inner:   inner{Score: 1.5},
+		Created: created,
+	}
+	st, err := StructTypeOf(v)
+	if err != nil {
+		t.Fatalf("struct type failed: %v", err)
+	}
+	data, err := Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: st})
+	if err != nil {
+		t.Fatalf("creating extractor failed: %v", err)
+	}
+	defer x.Close()
+
+	res := map[string]any{}
+	for x.Next() {
+		name := x.FieldName()
+		if res[name], err = x.ReadDynamic(); err != nil {
+			t.Fatalf("reading field %s failed: %v", name, err)
+		}
+	}
+	if err = x.Err(); err != nil {
+		t.Fatalf("extracting failed: %v", err)
+	}
+
+	expected := map[string]any{
+		"name":    "rec",
+		"count":   int64(-3),
+		"tags":    []any{"a", "b"},
+		"attrs":   map[string]any{"x": uint64(1)},
+		"parent":  nil,
+		"inner":   map[string]any{"score": float64(1.5)},
+		"created": created,
+	}
+	for k, ev := range expected {
+		rv := res[k]
+		if et, ok := ev.(time.Time); ok {
+			if rt, ok := rv.(time.Time); !ok || !rt.Equal(et) {
+				t.Errorf("field %s: got %#v, expected %v", k, rv, et)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(rv, ev) {
+			t.Errorf("field %s: got %#v, expected %#v", k, rv, ev)
+		}
+	}
+
+	t.Run("NonStringKey", func(t *testing.T) {
+		mt := bsttype.MapTypeOf(bsttype.Uint8(), bsttype.String(), false, false)
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, mt, ComposerOptions{Length: 1})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteUint8(7); err != nil {
+			t.Fatalf("writing key failed: %v", err)
+		}
+		if err = c.WriteString("seven"); err != nil {
+			t.Fatalf("writing value failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+
+		x, err := NewExtractor(bytes.NewReader(buf.Bytes()), ExtractorOptions{ExpectedType: mt})
+		if err != nil {
+			t.Fatalf("creating extractor failed: %v", err)
+		}
+		defer x.Close()
+		v, err := x.dynamicMap(mt)
+		if err != nil {
+			t.Fatalf("reading map failed: %v", err)
+		}
+		if !reflect.DeepEqual(v, map[any]any{uint64(7): "seven"}) {
+			t.Fatalf("unexpected map: %#v", v)
+		}
+	})
+}
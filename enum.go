@@ -93,6 +93,61 @@ func (x *Extractor) ReadEnumIndex() (uint, error) {
 		return 0, err
 	}
 
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, index)
+	}
 	x.finishElem()
 	return uint(index), nil
 }
+
+// WriteEnum writes the enum value of given element name to the composer.
+// The name needs to be one of the enum elements.
+func (x *Composer) WriteEnum(name string) error {
+	// 1. Check if the element was already written.
+	if x.done {
+		return bsterr.Err(bsterr.CodeAlreadyWritten, "element already written")
+	}
+
+	// 2. Verify if current element matches expected type.
+	et, ok := x.elemType.(*bsttype.Enum)
+	if !ok {
+		return bsterr.Err(bsterr.CodeInvalidType, "invalid type to write").
+			WithDetails(
+				bsterr.D("expected", bsttype.KindEnum),
+				bsterr.D("actual", x.elemType.Kind()),
+			)
+	}
+
+	// 3. Find the index of the element name.
+	index, ok := et.StringIndex(name)
+	if !ok {
+		return bsterr.Err(bsterr.CodeInvalidValue, "invalid enum value").
+			WithDetails(
+				bsterr.D("value", name),
+				bsterr.D("type", et),
+			)
+	}
+	return x.WriteEnumIndex(int(index))
+}
+
+// ReadEnum reads the enum value from the extractor, and returns the name of its element.
+// The value which is not one of the enum elements results in an error.
+func (x *Extractor) ReadEnum() (string, error) {
+	// 1. Read the enum index, which verifies the element type.
+	et, _ := x.elemType.(*bsttype.Enum)
+	index, err := x.ReadEnumIndex()
+	if err != nil {
+		return "", err
+	}
+
+	// 2. Find the element name of the index.
+	name, ok := et.IndexString(index)
+	if !ok {
+		return "", bsterr.Err(bsterr.CodeInvalidValue, "invalid enum value").
+			WithDetails(
+				bsterr.D("value", index),
+				bsterr.D("type", et),
+			)
+	}
+	return name, nil
+}
@@ -0,0 +1,145 @@
+package bst
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// pathSegment is a single step of the path from the root value to the currently extracted element.
+type pathSegment struct {
+	field string
+	index int
+	kind  bsttype.Kind
+	isKey bool
+}
+
+func (s pathSegment) appendTo(sb *strings.Builder) {
+	switch s.kind {
+	case bsttype.KindStruct:
+		sb.WriteByte('.')
+		sb.WriteString(s.field)
+	case bsttype.KindArray:
+		sb.WriteByte('[')
+		sb.WriteString(strconv.Itoa(s.index))
+		sb.WriteByte(']')
+	case bsttype.KindMap:
+		sb.WriteByte('[')
+		sb.WriteString(strconv.Itoa(s.index))
+		if s.isKey {
+			sb.WriteString(":key]")
+		} else {
+			sb.WriteString(":value]")
+		}
+	}
+}
+
+// Path returns the path from the root value to the current element, i.e. '$.items[2].name'.
+// Struct fields are denoted by their names, array elements by their indexes and map entries by their indexes
+// with the ':key' or ':value' suffix.
+func (x *Extractor) Path() string {
+	var sb strings.Builder
+	sb.WriteByte('$')
+	for _, s := range x.path {
+		s.appendTo(&sb)
+	}
+	if s, ok := x.currentPathSegment(); ok {
+		s.appendTo(&sb)
+	}
+	return sb.String()
+}
+
+// ExpectKind verifies that the current element is of given kind.
+// If it is not, the returned error contains the expected and actual kinds along with the path of the element.
+func (x *Extractor) ExpectKind(kind bsttype.Kind) error {
+	if x.err != nil {
+		return x.err
+	}
+
+	// 1. Check if current element is still in range.
+	if x.elemType == nil || x.index > x.maxIndex {
+		return bsterr.Err(bsterr.CodeOutOfBounds, "no current element to check").
+			WithDetails(
+				bsterr.D("expected", kind),
+				bsterr.D("path", x.Path()),
+			)
+	}
+
+	// 2. Verify the kind of the element.
+	if x.elemType.Kind() != kind {
+		return bsterr.Err(bsterr.CodeInvalidType, "unexpected element kind").
+			WithDetails(
+				bsterr.D("expected", kind),
+				bsterr.D("actual", x.elemType.Kind()),
+				bsterr.D("path", x.Path()),
+			)
+	}
+	return nil
+}
+
+// ExpectStruct verifies that the current element is a struct and returns its type.
+func (x *Extractor) ExpectStruct() (*bsttype.Struct, error) {
+	if err := x.ExpectKind(bsttype.KindStruct); err != nil {
+		return nil, err
+	}
+	return x.elemType.(*bsttype.Struct), nil
+}
+
+// ExpectArray verifies that the current element is an array and returns its type.
+func (x *Extractor) ExpectArray() (*bsttype.Array, error) {
+	if err := x.ExpectKind(bsttype.KindArray); err != nil {
+		return nil, err
+	}
+	return x.elemType.(*bsttype.Array), nil
+}
+
+// ExpectMap verifies that the current element is a map and returns its type.
+func (x *Extractor) ExpectMap() (*bsttype.Map, error) {
+	if err := x.ExpectKind(bsttype.KindMap); err != nil {
+		return nil, err
+	}
+	return x.elemType.(*bsttype.Map), nil
+}
+
+// ExpectEnum verifies that the current element is an enum and returns its type.
+func (x *Extractor) ExpectEnum() (*bsttype.Enum, error) {
+	if err := x.ExpectKind(bsttype.KindEnum); err != nil {
+		return nil, err
+	}
+	return x.elemType.(*bsttype.Enum), nil
+}
+
+// ExpectNullable verifies that the current element is nullable and returns its type.
+func (x *Extractor) ExpectNullable() (*bsttype.Nullable, error) {
+	if err := x.ExpectKind(bsttype.KindNullable); err != nil {
+		return nil, err
+	}
+	return x.elemType.(*bsttype.Nullable), nil
+}
+
+// currentPathSegment returns the path segment of the current element, if the extractor is within a composite value.
+func (x *Extractor) currentPathSegment() (pathSegment, bool) {
+	if x.embedType == nil || x.index < 0 || x.index > x.maxIndex {
+		return pathSegment{}, false
+	}
+	switch x.embedType.Kind() {
+	case bsttype.KindStruct:
+		// 1. The expected struct type defines the fields of the extractor indexes, if provided.
+		st, ok := x.opts.ExpectedType.(*bsttype.Struct)
+		if !ok {
+			st = x.embedType.(*bsttype.Struct)
+		}
+		if x.index >= len(st.Fields) {
+			return pathSegment{}, false
+		}
+		return pathSegment{kind: bsttype.KindStruct, field: st.Fields[x.index].Name}, true
+	case bsttype.KindArray:
+		return pathSegment{kind: bsttype.KindArray, index: x.index}, true
+	case bsttype.KindMap:
+		return pathSegment{kind: bsttype.KindMap, index: x.index, isKey: x.isKey}, true
+	default:
+		return pathSegment{}, false
+	}
+}
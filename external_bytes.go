@@ -0,0 +1,280 @@
+package bst
+
+import (
+	"sync"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// BlobStore is the storage of the ExternalBytes blobs, which keeps the large binaries out of the value binary.
+// The value binary contains only the blob reference, which is used to fetch the blob back.
+type BlobStore interface {
+	// PutBlob stores the blob by its reference. Storing the same blob again should be a no-op.
+	PutBlob(ref bstio.BlobRef, data []byte) error
+	// GetBlob fetches the blob by its reference.
+	GetBlob(ref bstio.BlobRef) ([]byte, error)
+}
+
+// Compile-time check to ensure that MemoryBlobStore implements the BlobStore interface.
+var _ BlobStore = (*MemoryBlobStore)(nil)
+
+// MemoryBlobStore is the in-memory BlobStore implementation, safe for concurrent use.
+type MemoryBlobStore struct {
+	mu    sync.RWMutex
+	blobs map[bstio.BlobRef][]byte
+}
+
+// NewMemoryBlobStore creates a new empty in-memory blob store.
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{blobs: map[bstio.BlobRef][]byte{}}
+}
+
+// PutBlob stores the copy of the blob by its reference.
+// Implements the BlobStore interface.
+func (x *MemoryBlobStore) PutBlob(ref bstio.BlobRef, data []byte) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.blobs[ref]; !ok {
+		x.blobs[ref] = append([]byte(nil), data...)
+	}
+	return nil
+}
+
+// GetBlob fetches the blob by its reference.
+// Implements the BlobStore interface.
+func (x *MemoryBlobStore) GetBlob(ref bstio.BlobRef) ([]byte, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	data, ok := x.blobs[ref]
+	if !ok {
+		return nil, bsterr.Err(bsterr.CodeUndefinedValue, "blob not found").WithDetail("ref", ref)
+	}
+	return data, nil
+}
+
+// Len returns the number of the stored blobs.
+func (x *MemoryBlobStore) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.blobs)
+}
+
+// WriteExternalBytes stores the byte slice value in the composer BlobStore and writes its reference.
+func (x *Composer) WriteExternalBytes(v []byte) error {
+	// 1. Check if the blob store is defined.
+	if x.opts.BlobStore == nil {
+		return bsterr.Err(bsterr.CodeInvalidValue, "blob store is not defined for the external bytes")
+	}
+
+	// 2. Offload the value to the blob store.
+	ref := bstio.NewBlobRef(v)
+	if err := x.opts.BlobStore.PutBlob(ref, v); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to store external bytes").WithDetail("ref", ref)
+	}
+
+	// 3. Write the reference of the stored value.
+	return x.WriteBlobRef(ref)
+}
+
+// WriteBlobRef writes the reference of the external bytes value, which is already stored in the blob store.
+func (x *Composer) WriteBlobRef(ref bstio.BlobRef) error {
+	// 1. Check if the element was already written.
+	if x.done {
+		return bsterr.Err(bsterr.CodeAlreadyWritten, "element already written")
+	}
+
+	// 2. Verify if current element matches expected type.
+	if x.elemType.Kind() != bsttype.KindExternalBytes {
+		return bsterr.Err(bsterr.CodeInvalidType, "invalid type to write").
+			WithDetails(
+				bsterr.D("expected", bsttype.KindExternalBytes),
+				bsterr.D("actual", x.elemType.Kind()),
+			)
+	}
+
+	// 3. If the base is a struct, check if the field header needs to be written.
+	if x.needWriteFieldHeader() {
+		n, err := x.writeFieldHeader(x.w, x.fieldIndex(), bstio.BlobRefSize)
+		if err != nil {
+			return err
+		}
+
+		x.bytesWritten += n
+	}
+
+	// 4. Write the reference.
+	n, err := bstio.WriteBlobRef(x.w, ref, x.elemDesc)
+	if err != nil {
+		return err
+	}
+
+	x.bytesWritten += n
+
+	// 5. Mark the element as written.
+	if err = x.finishElem(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadExternalBytes reads the reference of the external bytes value, and fetches the value from the extractor BlobStore.
+// The fetched value is verified against its reference.
+func (x *Extractor) ReadExternalBytes() ([]byte, error) {
+	// 1. Check if the blob store is defined, before the reference is read.
+	if x.err == nil && x.opts.BlobStore == nil {
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "blob store is not defined for the external bytes")
+	}
+
+	// 2. Read the reference.
+	ref, err := x.ReadBlobRef()
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Fetch the value and verify it matches the reference.
+	return x.fetchBlob(ref)
+}
+
+// ReadBlobRef reads the reference of the external bytes value, without fetching the value.
+func (x *Extractor) ReadBlobRef() (bstio.BlobRef, error) {
+	if x.err != nil {
+		return bstio.BlobRef{}, x.err
+	}
+	// 1. Check if reading element value is already finished.
+	if x.elemDone {
+		return bstio.BlobRef{}, bsterr.Err(bsterr.CodeAlreadyRead, "elem already done")
+	}
+
+	// 2. Check if current element is still in range.
+	if x.index > x.maxIndex {
+		return bstio.BlobRef{}, bsterr.Err(bsterr.CodeOutOfBounds, "buffIndex out of bounds")
+	}
+
+	// 3. Verify if current element matches the expected type.
+	if x.elemType.Kind() != bsttype.KindExternalBytes {
+		return bstio.BlobRef{}, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
+			WithDetails(
+				bsterr.D("expected", bsttype.KindExternalBytes),
+				bsterr.D("actual", x.elemType.Kind()),
+			)
+	}
+
+	// 4. Read the reference.
+	ref, n, err := bstio.ReadBlobRef(x.r, x.elemDesc)
+	x.bytesRead += n
+	if err != nil {
+		return bstio.BlobRef{}, err
+	}
+
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, ref)
+	}
+	x.finishElem()
+	return ref, nil
+}
+
+// writeExternalField writes the value of the external encoded struct field. The values shorter than the InlineThreshold
+// are written inline, the rest is offloaded to the BlobStore and only their reference is written.
+// The writeInline function writes the inline value binary of given size.
+func (x *Composer) writeExternalField(v []byte, inlineSize uint, writeInline func() (int, error)) error {
+	// 1. Decide whether the value is inlined or offloaded, and offload it if needed.
+	tag, size := bstio.ExternalInlineTag, inlineSize
+	var ref bstio.BlobRef
+	if len(v) >= x.opts.InlineThreshold {
+		if x.opts.BlobStore == nil {
+			return bsterr.Err(bsterr.CodeInvalidValue, "blob store is not defined for the external field").
+				WithDetail("size", len(v))
+		}
+		ref = bstio.NewBlobRef(v)
+		if err := x.opts.BlobStore.PutBlob(ref, v); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to store external field value").WithDetail("ref", ref)
+		}
+		tag, size = bstio.ExternalRefTag, bstio.BlobRefSize
+	}
+
+	// 2. If the base is a struct, check if the field header needs to be written.
+	if x.needWriteFieldHeader() {
+		n, err := x.writeFieldHeader(x.w, x.fieldIndex(), 1+size)
+		if err != nil {
+			return err
+		}
+
+		x.bytesWritten += n
+	}
+
+	// 3. Write the tag, followed by the inline value or the reference.
+	if err := bstio.WriteByte(x.w, tag); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write external field tag")
+	}
+	x.bytesWritten++
+
+	var n int
+	var err error
+	if tag == bstio.ExternalInlineTag {
+		n, err = writeInline()
+	} else {
+		n, err = bstio.WriteBlobRef(x.w, ref, x.elemDesc)
+	}
+	if err != nil {
+		return err
+	}
+
+	x.bytesWritten += n
+	if tag == bstio.ExternalInlineTag {
+		x.countValueOverhead(n, len(v))
+	}
+
+	// 4. Mark the element as written.
+	return x.finishElem()
+}
+
+// readExternalField reads the tag of the external encoded struct field value. If the value was offloaded,
+// its reference is read and the value is fetched from the BlobStore. Otherwise, the inline value is left to be read.
+func (x *Extractor) readExternalField() ([]byte, bool, error) {
+	// 1. Read the tag of the value.
+	tag, err := bstio.ReadByte(x.r)
+	if err != nil {
+		return nil, false, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read external field tag")
+	}
+	x.bytesRead++
+
+	switch tag {
+	case bstio.ExternalInlineTag:
+		return nil, false, nil
+	case bstio.ExternalRefTag:
+	default:
+		return nil, false, bsterr.Err(bsterr.CodeMalformedBinary, "invalid external field tag").WithDetail("tag", tag)
+	}
+
+	// 2. Check if the blob store is defined.
+	if x.opts.BlobStore == nil {
+		return nil, false, bsterr.Err(bsterr.CodeInvalidValue, "blob store is not defined for the external field")
+	}
+
+	// 3. Read the reference and fetch the value.
+	ref, n, err := bstio.ReadBlobRef(x.r, x.elemDesc)
+	x.bytesRead += n
+	if err != nil {
+		return nil, false, err
+	}
+	v, err := x.fetchBlob(ref)
+	if err != nil {
+		return nil, false, err
+	}
+	return v, true, nil
+}
+
+// fetchBlob fetches the blob from the BlobStore, and verifies it matches its reference.
+func (x *Extractor) fetchBlob(ref bstio.BlobRef) ([]byte, error) {
+	v, err := x.opts.BlobStore.GetBlob(ref)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to fetch external bytes").WithDetail("ref", ref)
+	}
+	if !ref.Matches(v) {
+		return nil, bsterr.Err(bsterr.CodeMalformedBinary, "external bytes don't match their reference").
+			WithDetails(bsterr.D("ref", ref), bsterr.D("size", len(v)))
+	}
+	return v, nil
+}
@@ -18,6 +18,52 @@ type ExtractorOptions struct {
 	CompatibilityMode bool
 	ExpectedType      bsttype.Type
 	Modules           *bsttype.Modules
+	// Trace, if set, records every read operation of the extractor.
+	Trace *Trace
+	// LengthPrefixedCollections determines that the variable size array and map elements of the arrays and maps
+	// are prefixed with their binary size. It is read from the header, thus needs to be set only for headless data.
+	LengthPrefixedCollections bool
+	// BlobStore fetches the ExternalBytes values by their references.
+	BlobStore BlobStore
+	// SchemaRegistry resolves the type of the value, whose header contains the schema ID instead of the embedded type.
+	SchemaRegistry SchemaRegistry
+	// Encryption defines the encrypted String and Bytes struct fields, whose values are decrypted once read.
+	Encryption *FieldEncryption
+	// Budget limits the bytes read and the elements extracted by the extractor, along with all its nested frames.
+	Budget *DecodeBudget
+	// FrameSize, if positive, is the binary size of the frame containing the value, i.e. known from the WAL record.
+	// All the reads are bounds-checked against it, and the ones beyond the frame fail with the CodeFrameOverrun error.
+	FrameSize int
+	// Signature verifies the signature trailer of the value, by the key of its ID, before the value is extracted.
+	// The reader needs to contain just the signed value, as it is read as a whole, unless the FrameSize bounds it.
+	// The value read uses the remaining Budget at once, and the one exceeding it fails with the CodeValueTooLarge
+	// error. The offsets of the extractor are then counted from the start of the value.
+	Signature *SignatureKeys
+	// Tokenizers replace the tokens of the String and Bytes struct fields with their values, by the field names.
+	// These are meant for the privileged callers only, others read the tokens as the field values.
+	Tokenizers map[string]Tokenizer
+	// Streaming reads the input as the plain io.Reader, even if it is an io.ReadSeeker, i.e. the network connection.
+	// The input is not buffered, and the skipped values are discarded while read, thus the value is decoded straight
+	// off the stream. The reads which go back in the input, i.e. the ReadMapKey, FindMapEntry and ReadSet, fail.
+	// The comparable values are scanned in chunks, thus the bytes following such value might be read ahead.
+	Streaming bool
+	// FieldMask, if set, selects the struct fields visited by the Next, by their dotted paths, i.e. "Address.City".
+	// All the other fields are skipped. The path selecting a struct field selects all of its nested fields,
+	// and the struct elements of the arrays and map values share the mask of the field of their collection.
+	FieldMask []string
+	// CanonicalMaps verifies that the entries of each map are sorted by their key binaries, with no duplicate keys,
+	// as written by the composer with the CanonicalMaps option, before the map is read. The maps failing
+	// the verification are not read. The entries are scanned upfront, thus it could not be used with the Streaming.
+	CanonicalMaps bool
+	// RequireChecksum fails the VerifyAndClose of the value which has no checksum trailer.
+	RequireChecksum bool
+	// OrderedFloats reads the float values in the ordered format, as written by the composer with the OrderedFloats.
+	OrderedFloats bool
+	// MaxDecompressedBytes limits the size of the decompressed body of the compressed value, and of each inflated
+	// value of the deflate encoded fields. If zero, the DefaultMaxDecompressedBytes is used, and the negative one
+	// removes the limit. The binary exceeding it fails with the CodeValueTooLarge error. The remaining Budget limits
+	// it as well, and the inflated field values use the budget.
+	MaxDecompressedBytes int64
 }
 
 // Extractor is binary serializable type extractor.
@@ -32,13 +78,30 @@ type ExtractorOptions struct {
 // extracting that element elements. This prevents for new allocation of the extractor, as the
 // one on which the method is called is reused.
 // In order to optimize the allocated memory, the extractor could be reused for the next type.
+//
+// The extractor is not safe for concurrent use. The 'bstguard' build tag enables the detection of its use by multiple goroutines.
 type Extractor struct {
+	extractorFrame
+	opts             ExtractorOptions
+	startOffset      int
+	checksum         bstio.ChecksumKind
+	compression      bstio.CompressionKind
+	compressedHeader bool
+	compressed       io.ReadSeeker
+	guard            ownerGuard
+	frames           []savedFrame
+	paths            []pathSegment
+}
+
+// extractorFrame is the state of the extractor within the currently extracted composite value.
+// Reading a nested composite pushes the frame of its parent on the stack of the extractor, and pops it once done,
+// so that only the frame is copied, and the stack is reused by the following nested reads.
+type extractorFrame struct {
+	r                                         io.ReadSeeker
 	embedType, elemType                       bsttype.Type
 	index, maxIndex                           int
 	embed                                     extractorBaseStatus
 	isKey, keyDone, elemDone, baseDone        bool
-	r                                         io.ReadSeeker
-	opts                                      ExtractorOptions
 	boolBuf                                   byte
 	boolBufPosition                           int
 	headerRead, elemDesc                      bool
@@ -47,6 +110,21 @@ type Extractor struct {
 	fieldHeader                               fieldHeader
 	clearElemFn                               func()
 	clearModules, clearEmbedType, clearReader bool
+	nullBitmap                                []byte
+	path                                      []pathSegment
+	elemStart, traceOffset                    int
+	delta                                     bool
+	deltaPrev                                 uint64
+	mapEntries, mapEnd                        int64
+	mask                                      fieldMask
+	fieldDefault                              *fieldDefault
+}
+
+// savedFrame is the frame of the parent composite, along with its options which differ from the nested ones.
+type savedFrame struct {
+	extractorFrame
+	expectedType bsttype.Type
+	descending   bool
 }
 
 type extractorBaseStatus struct {
@@ -54,36 +132,62 @@ type extractorBaseStatus struct {
 	maxIndex int
 
 	elemType bsttype.Type
+	encoding bsttype.FieldEncoding
 	used     bool
 }
 
 // NewExtractor creates a new value extractor read from the input reader, of the given type.
 func NewExtractor(r io.Reader, opts ExtractorOptions) (*Extractor, error) {
-	var (
-		rs              io.ReadSeeker
-		ok, clearReader bool
-	)
-	// 1. Check if the reader is not a read seeker and if so, wrap it in as a shared read seeker.
-	if rs, ok = r.(io.ReadSeeker); !ok {
-		rs = iopool.WrapReader(r)
-		clearReader = true
-	}
+	// 1. Check if the reader is not a read seeker and if so, wrap it in as a shared read seeker,
+	//    unless it is streamed.
+	rs, clearReader := extractorReader(r, opts)
 
-	// 2. Define the extractor.
-	x := &Extractor{r: rs, clearReader: clearReader}
+	// 2. Define the extractor, starting at the current offset of the reader.
+	x := &Extractor{
+		extractorFrame: extractorFrame{r: rs, clearReader: clearReader},
+		startOffset:    readerOffset(rs, clearReader),
+	}
 
-	// 3. Initialize the extractor with provided options.
+	// 3. Initialize the extractor with provided options. The resources acquired before the failure are released.
 	if err := x.init(opts); err != nil {
+		x.Close()
 		return nil, err
 	}
 	return x, nil
 }
 
 // BytesRead returns the number of the bytes read during extraction.
+// The sub extractors count the bytes read since the start of their nested value.
 func (x *Extractor) BytesRead() int {
 	return x.bytesRead
 }
 
+// Offset returns the absolute offset of the next byte to be read in the underlying reader.
+// Unlike the BytesRead, it is not reset by the sub extractors. The offset of the reader which is not
+// an io.ReadSeeker is counted from the start of the extraction.
+func (x *Extractor) Offset() int {
+	return x.startOffset + x.traceOffset + x.bytesRead
+}
+
+// StartOffset returns the absolute offset of the current element binary in the underlying reader,
+// marked by the last call to Next. The field header of the compatibility mode precedes it.
+func (x *Extractor) StartOffset() int {
+	return x.startOffset + x.traceOffset + x.elemStart
+}
+
+// readerOffset returns the current offset of the read seeker. The offset of the wrapped reader is zero,
+// just as the offset of the read seeker which fails to report it.
+func readerOffset(rs io.ReadSeeker, wrapped bool) int {
+	if wrapped {
+		return 0
+	}
+	pos, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0
+	}
+	return int(pos)
+}
+
 // Comparable returns true if the data is encoded in an ordered (comparable) fashion.
 func (x *Extractor) Comparable() bool {
 	return x.opts.Comparable
@@ -94,11 +198,17 @@ func (x *Extractor) Comparable() bool {
 // It releases all resources allocated by the extractor.
 // This function could be called asynchronously once all extractions are done.
 func (x *Extractor) Close() {
+	defer x.guard.release()
 	// 1.  The close of the extractor should clear all the shared and releasable resources.
+	//     The reader of the field default value is not the extractor one, thus it is restored first.
+	x.leaveFieldDefault()
 	//     At first check if the reader is shared and if so, release it.
 	if x.clearReader {
-		rs := x.r.(*iopool.SharedReadSeeker)
-		iopool.ReleaseReadSeeker(rs)
+		r := x.r
+		if x.compressed != nil {
+			r = x.compressed
+		}
+		iopool.ReleaseReadSeeker(rootReader(r).(*iopool.SharedReadSeeker))
 	}
 
 	// 2. Clear the modules if they were allocated as shared.
@@ -131,7 +241,7 @@ func (x *Extractor) readHeader() error {
 	// 2. The first byte of the input stream is expected to contain metadata about the value.
 	bt, err := bstio.ReadByte(x.r)
 	if err != nil {
-		return bsterr.Err(bsterr.CodeReadingFailed, "failed to read data header")
+		return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read data header")
 	}
 	x.bytesRead++
 
@@ -141,6 +251,9 @@ func (x *Extractor) readHeader() error {
 	//    - Bit 2: Value is stored in comparable fashion
 	//    - Bit 3: Value is stored in descending order
 	//    - Bit 4: Modules embed.
+	//    - Bit 5: Nested collections are length prefixed.
+	//    - Bit 6: The type is identified by the schema ID.
+	//    - Bit 7: The header extension follows, with the kinds of the checksum trailer and the body compression.
 	var typeEmbed bool
 
 	// 3.1. 0th bit is used to determine if the data is embedded.
@@ -169,12 +282,37 @@ func (x *Extractor) readHeader() error {
 		modulesEmbed = true
 	}
 
+	// 3.5. 5th bit - determines if nested collections are length prefixed.
+	if (bt>>5)&0x01 != 0 {
+		x.opts.LengthPrefixedCollections = true
+	}
+
+	// 3.6. 7th bit - determines if the header extension is read just after the header.
+	if (bt>>7)&0x01 != 0 {
+		if err = x.readHeaderExtension(); err != nil {
+			return err
+		}
+	}
+
+	// 3.7. If the embedded type is compressed, the compressed body follows the header extension.
+	if x.compressedHeader {
+		if err = x.inflateBody(); err != nil {
+			return err
+		}
+	}
+
+	// 3.8. 6th bit - determines if the type is identified by the schema ID.
+	if (bt>>6)&0x01 != 0 {
+		return x.readSchemaID()
+	}
+
 	if modulesEmbed {
 		// 4. Read, the modules embed in the header.
 		m := bsttype.GetSharedModules()
 		var n int
 		n, err = m.Read(x.r, true)
 		if err != nil {
+			m.Free()
 			return err
 		}
 		x.bytesRead += n
@@ -212,18 +350,50 @@ func (x *Extractor) readHeader() error {
 	return nil
 }
 
+// readSchemaID reads the schema ID following the header, and resolves the type and modules from the schema registry.
+func (x *Extractor) readSchemaID() error {
+	// 1. Read the schema ID.
+	v, n, err := bstio.ReadUint(x.r, false)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read schema id")
+	}
+	x.bytesRead += n
+	id := uint64(v)
+
+	// 2. The registry is required to resolve the schema.
+	if x.opts.SchemaRegistry == nil {
+		return bsterr.Err(bsterr.CodeUndefinedType, "schema registry is required to resolve the schema id").
+			WithDetail("schemaID", id)
+	}
+	et, m, err := x.opts.SchemaRegistry.Schema(id)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeUndefinedType, "failed to resolve the schema id").
+			WithDetail("schemaID", id)
+	}
+
+	// 3. The registry type and modules are shared, thus they are neither merged nor freed.
+	//    The modules provided by the user take precedence over the registered ones.
+	if x.opts.Modules == nil {
+		x.opts.Modules = m
+	}
+	x.embedType = et
+	x.headerRead = true
+	return nil
+}
+
 // ResetTo reuses the extractor for the needs of the input type.
 func (x *Extractor) ResetTo(r io.Reader, opts ExtractorOptions) error {
-	var (
-		rs              io.ReadSeeker
-		ok, clearReader bool
-	)
-	// 1. Check if the reader is not a read seeker and if so, wrap it in as a shared read seeker.
-	if rs, ok = r.(io.ReadSeeker); !ok {
-		rs = iopool.WrapReader(r)
-		clearReader = true
+	// 1. Check if the reader is not a read seeker and if so, wrap it in as a shared read seeker,
+	//    unless it is streamed.
+	rs, clearReader := extractorReader(r, opts)
+	x.guard.release()
+	*x = Extractor{
+		extractorFrame: extractorFrame{r: rs, clearReader: clearReader},
+		startOffset:    readerOffset(rs, clearReader),
+		guard:          x.guard,
+		frames:         x.frames[:0],
+		paths:          x.paths[:0],
 	}
-	*x = Extractor{r: rs, clearReader: clearReader}
 
 	// 2. Initialize it.
 	if err := x.init(opts); err != nil {
@@ -234,6 +404,7 @@ func (x *Extractor) ResetTo(r io.Reader, opts ExtractorOptions) error {
 
 // Next advances the extractor to the next field.
 func (x *Extractor) Next() bool {
+	x.guard.check("extractor")
 	// 1. Check if the error occurred in the previous step.
 	if x.err != nil {
 		return false
@@ -244,17 +415,31 @@ func (x *Extractor) Next() bool {
 	}
 
 	// 2. Switch by the kind of embedded type.
+	var ok bool
 	switch x.embedType.Kind() {
 	case bsttype.KindArray:
-		return x.nextArrayElem()
+		ok = x.nextArrayElem()
 	case bsttype.KindMap:
-		return x.nextMapElem()
+		ok = x.nextMapElem()
 	case bsttype.KindStruct:
-		return x.nextStructElem()
+		ok = x.nextMaskedStructElem()
 	default:
 		// This is about the basic type.
-		return x.nextDefaultElem()
+		ok = x.nextDefaultElem()
 	}
+
+	// 3. Mark the start of the element binary for the trace and the StartOffset.
+	if ok {
+		x.elemStart = x.bytesRead
+	}
+
+	// 4. Use the decode budget for the element.
+	if ok && x.opts.Budget != nil {
+		if x.err = x.opts.Budget.useElement(); x.err != nil {
+			return false
+		}
+	}
+	return ok
 }
 
 // KeyDone marks the current key as done.
@@ -302,34 +487,151 @@ func (x *Extractor) Skip() (int64, error) {
 
 	var skipped int64
 
-	skipFunc := bstskip.SkipFuncOf(x.elemType)
-	opts := bstio.ValueOptions{
-		Comparable: x.opts.Comparable,
-		Descending: x.opts.Descending,
+	// 1. The null flags of the null bitmap array elements were read upfront, thus only their values are skipped.
+	st := x.embeddedElemType()
+	if nt, ok := st.(*bsttype.Nullable); ok && x.nullBitmapArray() {
+		if x.nullBitmapIsNull(x.index) {
+			if x.opts.Trace != nil {
+				x.traceElem(TraceOpSkip, nil)
+			}
+			x.finishElem()
+			return 0, nil
+		}
+		st = nt.Type
 	}
-	if x.elemDesc {
-		opts.Descending = !opts.Descending
+
+	// 2. The delta encoded elements are decoded relative to the previous ones, thus they are read instead.
+	if x.delta {
+		start := x.bytesRead
+		if err := x.readDeltaElem(); err != nil {
+			return 0, err
+		}
+		return int64(x.bytesRead - start), nil
 	}
+
+	skipFunc, opts := x.elemSkipFunc(st)
 	n, err := skipFunc(x.r, opts)
 	if err != nil {
 		return 0, err
 	}
 	skipped += n
 	x.bytesRead += int(n)
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpSkip, nil)
+	}
 	x.finishElem()
 
 	return skipped, nil
 }
 
-// reset current extractor to the initial state
-func (x *Extractor) reset() {
-	*x = Extractor{
-		r:     x.r,
-		opts:  x.opts,
-		index: -1,
+// elemSkipFunc returns the function skipping the binary of the current element of given type, along with its options.
+func (x *Extractor) elemSkipFunc(st bsttype.Type) (bstskip.SkipFunc, bstio.ValueOptions) {
+	skipFunc := bstskip.SkipFuncOf(st)
+	if x.lengthPrefixedElem() {
+		skipFunc = bstskip.SkipLengthPrefixed
+	}
+	if x.fieldEncoding() == bsttype.FieldEncodingExternal {
+		skipFunc = bstskip.ExternalFieldSkipFunc(st)
+	}
+	return skipFunc, bstio.ValueOptions{
+		Comparable:        x.opts.Comparable,
+		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    x.opts.LengthPrefixedCollections,
+		Descending:        x.elemDesc,
+	}
+}
+
+// enterNested pushes the frame of the current composite on the stack, and resets the extractor to the initial state
+// of the nested composite value. The nested value inherits the effective order of the element it is read as,
+// and the path of that element. The nested frame needs to be left by the leaveNested or the abortNested.
+func (x *Extractor) enterNested() {
+	// 1. Push the current frame, along with the options changed by the nested one.
+	x.frames = append(x.frames, savedFrame{
+		extractorFrame: x.extractorFrame,
+		expectedType:   x.opts.ExpectedType,
+		descending:     x.opts.Descending,
+	})
+
+	// 2. Reset the frame for the nested value.
+	x.opts.Descending = x.elemDesc
+	// The path of each frame is the prefix of the path of its nested frames, thus they share the reused backing array.
+	// The nested struct of the field is masked by the field mask of the field, and the map keys are not masked.
+	path, mask := x.path, x.mask
+	if s, ok := x.currentPathSegment(); ok {
+		path = append(x.paths[:len(path)], s)
+		x.paths = path
+		switch {
+		case s.kind == bsttype.KindStruct:
+			mask = mask.nested(s.field)
+		case s.isKey:
+			mask = nil
+		}
+	}
+	x.extractorFrame = extractorFrame{
+		r:           x.r,
+		index:       -1,
+		path:        path,
+		traceOffset: x.traceOffset + x.bytesRead,
+		mask:        mask,
 	}
 }
 
+// leaveNested pops the frame of the parent composite from the stack, once its nested composite was read.
+func (x *Extractor) leaveNested() {
+	i := len(x.frames) - 1
+	sf := &x.frames[i]
+	x.extractorFrame = sf.extractorFrame
+	x.opts.ExpectedType, x.opts.Descending = sf.expectedType, sf.descending
+
+	// The popped frame is cleared, so that the reused stack doesn't keep its references.
+	*sf = savedFrame{}
+	x.frames = x.frames[:i]
+}
+
+// lengthPrefixedElem checks if the current array or map element is prefixed with its binary size.
+func (x *Extractor) lengthPrefixedElem() bool {
+	if !x.opts.LengthPrefixedCollections || !x.opts.CompatibilityMode || x.opts.Comparable {
+		return false
+	}
+	var et bsttype.Type
+	switch bt := x.embedType.(type) {
+	case *bsttype.Array:
+		et = bt.Elem()
+	case *bsttype.Map:
+		if x.isKey {
+			et = bt.Key.Type
+		} else {
+			et = bt.Value.Type
+		}
+	default:
+		return false
+	}
+	return bstskip.LengthPrefixed(et, bstio.ValueOptions{
+		Comparable:        x.opts.Comparable,
+		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    true,
+	})
+}
+
+// skipLengthPrefix reads the binary size prefix of the current element, if it is length prefixed.
+// The extracted element is read as it is, thus its size is not needed.
+func (x *Extractor) skipLengthPrefix() error {
+	if !x.lengthPrefixedElem() {
+		return nil
+	}
+	_, n, err := bstio.ReadUint(x.r, false)
+	x.bytesRead += n
+	return err
+}
+
+// abortNested restores the frame of the parent composite once reading the nested composite failed, and marks it failed.
+// This way the following reads fail, and the Close still releases the shared resources of the base extractor.
+func (x *Extractor) abortNested(err error) error {
+	x.leaveNested()
+	x.err = err
+	return err
+}
+
 func (x *Extractor) previewPrevElem() (bsttype.Type, bool) {
 	switch x.embedType.Kind() {
 	case bsttype.KindStruct:
@@ -347,16 +649,41 @@ func (x *Extractor) init(options ExtractorOptions) error {
 	// 1. Apply provided options.
 	x.opts = options
 
-	// 3. Verify if the extractor is formed in a valid way.
+	// 3. Verify if the extractor is formed in a valid way, and compile its field mask.
 	if err := x.validate(); err != nil {
 		return err
 	}
+	var err error
+	if x.mask, err = newFieldMask(options.FieldMask); err != nil {
+		return err
+	}
 
-	// 4. If the extractor is not headless, then read the header.
+	// 3.1. Bound the reader to the frame of the value, and wrap it with the one using the decode budget.
+	//      Both are inherited by the nested frames.
+	if x.opts.FrameSize > 0 {
+		x.r = &frameReader{ReadSeeker: x.r, start: int64(x.startOffset), size: int64(x.opts.FrameSize)}
+	}
+	if x.opts.Budget != nil {
+		x.r = &budgetReader{ReadSeeker: x.r, budget: x.opts.Budget}
+	}
+
+	// 3.2. Verify the signature of the signed value, which is then extracted out of its verified binary.
+	if x.opts.Signature != nil {
+		if err := x.verifySignature(); err != nil {
+			return err
+		}
+	}
+
+	// 4. If the extractor is not headless, then read the header, and decompress the body following it.
 	if !x.opts.Headless {
 		if err := x.readHeader(); err != nil {
 			return err
 		}
+		if x.compression != bstio.CompressionNone && x.compressed == nil {
+			if err := x.inflateBody(); err != nil {
+				return err
+			}
+		}
 	}
 
 	// 5. If the embed type is not provided then set it from the expected type.
@@ -373,9 +700,15 @@ func (x *Extractor) init(options ExtractorOptions) error {
 	case bsttype.KindStruct:
 		return x.initStructBase()
 	case bsttype.KindArray:
-		return x.initializeArray()
+		if err := x.initializeArray(); err != nil {
+			return err
+		}
+		return x.checkBudgetLength()
 	case bsttype.KindMap:
-		return x.initializeMap()
+		if err := x.initializeMap(); err != nil {
+			return err
+		}
+		return x.checkBudgetLength()
 	case bsttype.KindNamed:
 		return x.initializeNamed()
 	default:
@@ -389,6 +722,7 @@ type wrappedReader struct {
 }
 
 func (x *Extractor) finishElem() {
+	x.guard.check("extractor")
 	switch x.embedType.Kind() {
 	case bsttype.KindStruct:
 		x.finishStructElem()
@@ -415,6 +749,7 @@ func (x *Extractor) validate() error {
 func (x *Extractor) initializeNamed() error {
 	// 1. Dereference the type to extract the underlying type.
 	nt := x.embedType.(*bsttype.Named)
+	x.elemDesc = x.opts.Descending
 	if nt.Type != nil {
 		x.elemType = nt.Type
 		return nil
@@ -449,6 +784,7 @@ func (x *Extractor) initializeNamed() error {
 
 func (x *Extractor) initializeDefault() error {
 	x.elemType = x.embedType
+	x.elemDesc = x.opts.Descending
 	return nil
 }
 
@@ -2,10 +2,22 @@ package bst
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/devmodules/bst/bstcol"
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bstskip"
+	"github.com/devmodules/bst/bsttest"
 	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
 	"github.com/devmodules/bst/internal/iopool"
 )
 
@@ -1102,3 +1114,2302 @@ func TestExtractorStruct(t *testing.T) {
 		})
 	})
 }
+
+func TestExtractorDescending(t *testing.T) {
+	// Each field is composed with either the low or the high value, and the struct binaries with a single
+	// field differing are compared. The flip marks the fields whose own descending flag inverts the order.
+	type descField struct {
+		field bsttype.StructField
+		flip  bool
+		write func(c *Composer, hi bool) error
+		read  func(x *Extractor, hi bool) error
+	}
+	pick := func(hi bool, lo, h int) int {
+		if hi {
+			return h
+		}
+		return lo
+	}
+	expect := func(hi bool, got, lo, h any) error {
+		want := lo
+		if hi {
+			want = h
+		}
+		if got != want {
+			return bsterr.Err(bsterr.CodeInvalidValue, "unexpected value").
+				WithDetails(bsterr.D("got", got), bsterr.D("want", want))
+		}
+		return nil
+	}
+	base := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	nested := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "A", Type: bsttype.Int16()},
+		{Index: 2, Name: "B", Type: bsttype.String()},
+	}}
+	enum := &bsttype.Enum{ValueBytes: 1, Elements: []bsttype.EnumElement{{String: "A", Index: 0}, {String: "B", Index: 1}}}
+
+	fields := []descField{
+		{
+			field: bsttype.StructField{Name: "Int8", Type: bsttype.Int8()},
+			write: func(c *Composer, hi bool) error { return c.WriteInt8(int8(pick(hi, -3, 4))) },
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadInt8()
+				if err != nil {
+					return err
+				}
+				return expect(hi, int(v), -3, 4)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Int32", Type: bsttype.Int32()},
+			write: func(c *Composer, hi bool) error { return c.WriteInt32(int32(pick(hi, -300, 400))) },
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadInt32()
+				if err != nil {
+					return err
+				}
+				return expect(hi, int(v), -300, 400)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Int", Type: bsttype.Int()},
+			write: func(c *Composer, hi bool) error { return c.WriteInt(pick(hi, -1<<20, 1<<30)) },
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadInt()
+				if err != nil {
+					return err
+				}
+				return expect(hi, v, -1<<20, 1<<30)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Uint16", Type: bsttype.Uint16(), Descending: true},
+			flip:  true,
+			write: func(c *Composer, hi bool) error { return c.WriteUint16(uint16(pick(hi, 2, 512))) },
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadUint16()
+				if err != nil {
+					return err
+				}
+				return expect(hi, int(v), 2, 512)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Uint", Type: bsttype.Uint()},
+			write: func(c *Composer, hi bool) error { return c.WriteUint(uint(pick(hi, 7, 70000))) },
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadUint()
+				if err != nil {
+					return err
+				}
+				return expect(hi, int(v), 7, 70000)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Bool", Type: bsttype.Boolean()},
+			write: func(c *Composer, hi bool) error { return c.WriteBoolean(hi) },
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadBoolean()
+				if err != nil {
+					return err
+				}
+				return expect(hi, v, false, true)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Float64", Type: bsttype.Float64()},
+			write: func(c *Composer, hi bool) error { return c.WriteFloat64(float64(pick(hi, -2, 3)) / 4) },
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadFloat64()
+				if err != nil {
+					return err
+				}
+				return expect(hi, v, -0.5, 0.75)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "String", Type: bsttype.String()},
+			write: func(c *Composer, hi bool) error { return c.WriteString([]string{"ab", "ab\x00c"}[pick(hi, 0, 1)]) },
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadString()
+				if err != nil {
+					return err
+				}
+				return expect(hi, v, "ab", "ab\x00c")
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Bytes", Type: &bsttype.Bytes{}},
+			write: func(c *Composer, hi bool) error { return c.WriteBytes([][]byte{{}, {0x00}}[pick(hi, 0, 1)]) },
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadBytes()
+				if err != nil {
+					return err
+				}
+				return expect(hi, len(v), 0, 1)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "DateTime", Type: &bsttype.DateTime{}},
+			write: func(c *Composer, hi bool) error {
+				return c.WriteDateTime(base.Add(time.Duration(pick(hi, 0, 1)) * time.Hour))
+			},
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadDateTime()
+				if err != nil {
+					return err
+				}
+				return expect(hi, v.Sub(base), time.Duration(0), time.Hour)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Timestamp", Type: bsttype.Timestamp()},
+			write: func(c *Composer, hi bool) error {
+				return c.WriteTimestamp(base.Add(time.Duration(pick(hi, -1, 1)) * time.Second))
+			},
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadTimestamp()
+				if err != nil {
+					return err
+				}
+				return expect(hi, v.Sub(base), -time.Second, time.Second)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Duration", Type: bsttype.Duration()},
+			write: func(c *Composer, hi bool) error { return c.WriteDuration(time.Duration(pick(hi, -5, 5))) },
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadDuration()
+				if err != nil {
+					return err
+				}
+				return expect(hi, v, time.Duration(-5), time.Duration(5))
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Enum", Type: enum},
+			write: func(c *Composer, hi bool) error { return c.WriteEnumIndex(pick(hi, 0, 1)) },
+			read: func(x *Extractor, hi bool) error {
+				v, err := x.ReadEnumIndex()
+				if err != nil {
+					return err
+				}
+				return expect(hi, int(v), 0, 1)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Nullable", Type: bsttype.NullableOf(bsttype.Int32())},
+			write: func(c *Composer, hi bool) error {
+				if !hi {
+					return c.WriteNull()
+				}
+				if err := c.WriteNotNull(); err != nil {
+					return err
+				}
+				return c.WriteInt32(-9)
+			},
+			read: func(x *Extractor, hi bool) error {
+				isNull, err := x.IsNull()
+				if err != nil || isNull {
+					return expect(hi, isNull, true, false)
+				}
+				v, err := x.ReadInt32()
+				if err != nil {
+					return err
+				}
+				return expect(hi, v, nil, int32(-9))
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Array", Type: bsttype.ArrayOf(bsttype.Uint8())},
+			write: func(c *Composer, hi bool) error {
+				return c.WriteArray(func(ac *Composer) error {
+					for _, v := range []uint8{2, uint8(pick(hi, 2, 3))} {
+						if err := ac.WriteUint8(v); err != nil {
+							return err
+						}
+					}
+					return nil
+				}, 2)
+			},
+			read: func(x *Extractor, hi bool) error {
+				var got []uint8
+				err := x.ReadArray(func(ax *Extractor) error {
+					for ax.Next() {
+						v, err := ax.ReadUint8()
+						if err != nil {
+							return err
+						}
+						got = append(got, v)
+					}
+					return ax.Err()
+				})
+				if err != nil {
+					return err
+				}
+				if len(got) != 2 || got[0] != 2 {
+					return bsterr.Err(bsterr.CodeInvalidValue, "unexpected array").WithDetail("got", got)
+				}
+				return expect(hi, int(got[1]), 2, 3)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Map", Type: bsttype.MapTypeOf(bsttype.String(), bsttype.Int32(), false, true)},
+			flip:  true,
+			write: func(c *Composer, hi bool) error {
+				return c.WriteMap(func(mc *Composer) error {
+					if err := mc.WriteString("k"); err != nil {
+						return err
+					}
+					return mc.WriteInt32(int32(pick(hi, 1, 2)))
+				}, 1)
+			},
+			read: func(x *Extractor, hi bool) error {
+				var got int32
+				err := x.ReadMap(func(mx *Extractor) error {
+					for mx.Next() {
+						if _, err := mx.ReadString(); err != nil {
+							return err
+						}
+						if !mx.Next() {
+							return mx.Err()
+						}
+						v, err := mx.ReadInt32()
+						if err != nil {
+							return err
+						}
+						got = v
+					}
+					return mx.Err()
+				})
+				if err != nil {
+					return err
+				}
+				return expect(hi, int(got), 1, 2)
+			},
+		},
+		{
+			field: bsttype.StructField{Name: "Struct", Type: nested, Descending: true},
+			flip:  true,
+			write: func(c *Composer, hi bool) error {
+				return c.WriteStruct(func(sc *Composer) error {
+					if err := sc.WriteInt16(-1); err != nil {
+						return err
+					}
+					return sc.WriteString([]string{"x", "y"}[pick(hi, 0, 1)])
+				})
+			},
+			read: func(x *Extractor, hi bool) error {
+				var got string
+				err := x.ReadStruct(func(sx *Extractor) error {
+					for sx.Next() {
+						var err error
+						if sx.Index() == 0 {
+							_, err = sx.ReadInt16()
+						} else {
+							got, err = sx.ReadString()
+						}
+						if err != nil {
+							return err
+						}
+					}
+					return sx.Err()
+				})
+				if err != nil {
+					return err
+				}
+				return expect(hi, got, "x", "y")
+			},
+		},
+	}
+
+	// 1. All the values share the same struct type, which must not be modified by the descending options.
+	st := &bsttype.Struct{}
+	for i, f := range fields {
+		f.field.Index = uint(i + 1)
+		st.Fields = append(st.Fields, f.field)
+	}
+
+	compose := func(t *testing.T, desc bool, hiField int) []byte {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, ComposerOptions{Comparable: true, Descending: desc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, f := range fields {
+			if err = f.write(c, i == hiField); err != nil {
+				t.Fatalf("writing field %s failed: %v", f.field.Name, err)
+			}
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	extract := func(t *testing.T, data []byte, hiField int) {
+		x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: st})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer x.Close()
+		for x.Next() {
+			f := fields[x.Index()]
+			// Every other low field is skipped, to verify the skipping honors the order too.
+			if x.Index() != hiField && x.Index()%2 == 1 {
+				if _, err = x.Skip(); err != nil {
+					t.Fatalf("skipping field %s failed: %v", f.field.Name, err)
+				}
+				continue
+			}
+			if err = f.read(x, x.Index() == hiField); err != nil {
+				t.Fatalf("reading field %s failed: %v", f.field.Name, err)
+			}
+		}
+		if err = x.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, desc := range []bool{false, true} {
+		lo := compose(t, desc, -1)
+		extract(t, lo, -1)
+		for i, f := range fields {
+			hi := compose(t, desc, i)
+			extract(t, hi, i)
+
+			// 2. The header is the same for both values, the order of the values is determined by the field.
+			cmp := bytes.Compare(lo[1:], hi[1:])
+			if desc != f.flip {
+				cmp = -cmp
+			}
+			if cmp >= 0 {
+				t.Errorf("desc: %v, field %s: low value binary %x is not ordered before high %x", desc, f.field.Name, lo, hi)
+			}
+		}
+	}
+
+	// 3. The field flags of the shared type are unchanged.
+	for i, f := range fields {
+		if st.Fields[i].Descending != f.field.Descending {
+			t.Fatalf("shared type field %s was modified", f.field.Name)
+		}
+	}
+}
+
+func TestExtractorRunLengthArray(t *testing.T) {
+	enum := &bsttype.Enum{ValueBytes: 1, Elements: []bsttype.EnumElement{
+		{String: "Off", Index: 0}, {String: "On", Index: 1}, {String: "Fault", Index: 2},
+	}}
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Flags", Type: &bsttype.Array{Type: bsttype.Boolean(), Encoding: bsttype.ArrayEncodingRunLength}},
+		{Index: 2, Name: "States", Type: &bsttype.Array{Type: enum, FixedSize: 6, Encoding: bsttype.ArrayEncodingRunLength}},
+		{Index: 3, Name: "Tail", Type: bsttype.Uint8()},
+	}}
+	flags := make([]bool, 100)
+	for i := 40; i < 90; i++ {
+		flags[i] = true
+	}
+	states := []uint{0, 0, 1, 1, 1, 2}
+
+	compose := func(t *testing.T, opts ComposerOptions) []byte {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, v := range flags {
+				if err := ac.WriteBoolean(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, len(flags))
+		if err != nil {
+			t.Fatalf("writing flags failed: %v", err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, v := range states {
+				if err := ac.WriteEnumIndex(int(v)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, 0)
+		if err != nil {
+			t.Fatalf("writing states failed: %v", err)
+		}
+		if err = c.WriteUint8(7); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	extract := func(t *testing.T, data []byte, comparable, partial bool) {
+		x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: st, Comparable: comparable})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer x.Close()
+
+		for x.Next() {
+			switch x.Index() {
+			case 0:
+				err = x.ReadArray(func(ax *Extractor) error {
+					if ax.Length() != len(flags) {
+						return bsterr.Err(bsterr.CodeInvalidValue, "unexpected flags length").WithDetail("length", ax.Length())
+					}
+					for ax.Next() {
+						v, err := ax.ReadBoolean()
+						if err != nil {
+							return err
+						}
+						if v != flags[ax.Index()] {
+							return bsterr.Err(bsterr.CodeInvalidValue, "unexpected flag").WithDetail("index", ax.Index())
+						}
+						// The remaining elements are skipped when the array is finished.
+						if partial && ax.Index() == 45 {
+							return nil
+						}
+					}
+					return ax.Err()
+				})
+			case 1:
+				if partial {
+					_, err = x.Skip()
+					break
+				}
+				var got []uint
+				err = x.ReadArray(func(ax *Extractor) error {
+					for ax.Next() {
+						v, err := ax.ReadEnumIndex()
+						if err != nil {
+							return err
+						}
+						got = append(got, v)
+					}
+					return ax.Err()
+				})
+				if err == nil && fmt.Sprint(got) != fmt.Sprint(states) {
+					t.Fatalf("unexpected states: %v", got)
+				}
+			case 2:
+				var v uint8
+				v, err = x.ReadUint8()
+				if err == nil && v != 7 {
+					t.Fatalf("unexpected tail: %d", v)
+				}
+			}
+			if err != nil {
+				t.Fatalf("extracting field %d failed: %v", x.Index(), err)
+			}
+		}
+		if err = x.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if x.BytesRead() != len(data) {
+			t.Fatalf("unexpected number of bytes read: %d, expected: %d", x.BytesRead(), len(data))
+		}
+	}
+
+	t.Run("NonComparable", func(t *testing.T) {
+		data := compose(t, ComposerOptions{})
+		// header, flags: length + 3 runs, states: 3 runs, tail.
+		if len(data) != 1+2+3*3+3*3+1 {
+			t.Fatalf("unexpected binary size: %d", len(data))
+		}
+		extract(t, data, false, false)
+		extract(t, data, false, true)
+
+		n, err := bstskip.SkipFuncOf(st)(bytes.NewReader(data[1:]), bstio.ValueOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(n) != len(data)-1 {
+			t.Fatalf("unexpected number of bytes skipped: %d, expected: %d", n, len(data)-1)
+		}
+	})
+
+	t.Run("Comparable", func(t *testing.T) {
+		// The comparable binaries are not run length encoded, as it would break their order.
+		data := compose(t, ComposerOptions{Comparable: true})
+
+		rle := st
+		st = &bsttype.Struct{Fields: []bsttype.StructField{
+			{Index: 1, Name: "Flags", Type: bsttype.ArrayOf(bsttype.Boolean())},
+			{Index: 2, Name: "States", Type: bsttype.FixedSizeArrayOf(enum, 6)},
+			{Index: 3, Name: "Tail", Type: bsttype.Uint8()},
+		}}
+		defer func() { st = rle }()
+		if plain := compose(t, ComposerOptions{Comparable: true}); !bytes.Equal(data, plain) {
+			t.Fatalf("comparable binary differs from the plain one: %x != %x", data, plain)
+		}
+	})
+
+	t.Run("InvalidElem", func(t *testing.T) {
+		at := &bsttype.Array{Type: bsttype.String(), Encoding: bsttype.ArrayEncodingRunLength}
+		if _, err := NewComposer(&bytes.Buffer{}, at, ComposerOptions{}); err == nil {
+			t.Fatal("expected error for run length encoded string array")
+		}
+	})
+}
+
+func TestExtractorFloatColumnArray(t *testing.T) {
+	readings := &bsttype.Named{Name: "Readings", Type: bsttype.Float64()}
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Readings", Type: &bsttype.Array{Type: readings, Encoding: bsttype.ArrayEncodingFloatColumn}},
+		{Index: 2, Name: "Position", Type: &bsttype.Array{Type: bsttype.Float32(), FixedSize: 3, Encoding: bsttype.ArrayEncodingFloatColumn}},
+		{Index: 3, Name: "Tail", Type: bsttype.Uint8()},
+	}}
+	values := []float64{21.5, 21.625, 21.75, -3, 0, 1e300}
+	position := []float32{1.5, -2.25, 3}
+
+	compose := func(t *testing.T, opts ComposerOptions) []byte {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, v := range values {
+				if err := ac.WriteFloat64(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, 0)
+		if err != nil {
+			t.Fatalf("writing readings failed: %v", err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, v := range position {
+				if err := ac.WriteFloat32(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, 0)
+		if err != nil {
+			t.Fatalf("writing position failed: %v", err)
+		}
+		if err = c.WriteUint8(7); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	extract := func(t *testing.T, data []byte, opts ExtractorOptions) {
+		opts.ExpectedType = st
+		x, err := NewExtractor(bytes.NewReader(data), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer x.Close()
+
+		for x.Next() {
+			switch x.Index() {
+			case 0:
+				var got []float64
+				err = x.ReadArray(func(ax *Extractor) error {
+					for ax.Next() {
+						v, err := ax.ReadFloat64()
+						if err != nil {
+							return err
+						}
+						got = append(got, v)
+					}
+					return ax.Err()
+				})
+				if err == nil && fmt.Sprint(got) != fmt.Sprint(values) {
+					t.Fatalf("unexpected readings: %v", got)
+				}
+			case 1:
+				var got []float32
+				err = x.ReadArray(func(ax *Extractor) error {
+					for ax.Next() {
+						v, err := ax.ReadFloat32()
+						if err != nil {
+							return err
+						}
+						got = append(got, v)
+					}
+					return ax.Err()
+				})
+				if err == nil && fmt.Sprint(got) != fmt.Sprint(position) {
+					t.Fatalf("unexpected position: %v", got)
+				}
+			case 2:
+				var v uint8
+				v, err = x.ReadUint8()
+				if err == nil && v != 7 {
+					t.Fatalf("unexpected tail: %d", v)
+				}
+			}
+			if err != nil {
+				t.Fatalf("extracting field %d failed: %v", x.Index(), err)
+			}
+		}
+		if err = x.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if x.BytesRead() != len(data) {
+			t.Fatalf("unexpected number of bytes read: %d, expected: %d", x.BytesRead(), len(data))
+		}
+	}
+
+	t.Run("NonComparable", func(t *testing.T) {
+		data := compose(t, ComposerOptions{})
+		// header, readings: column header + count + values, position: column header + count + values, tail.
+		if len(data) != 1+(1+2+6*8)+(1+2+3*4)+1 {
+			t.Fatalf("unexpected binary size: %d", len(data))
+		}
+		extract(t, data, ExtractorOptions{})
+
+		n, err := bstskip.SkipFuncOf(st)(bytes.NewReader(data[1:]), bstio.ValueOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(n) != len(data)-1 {
+			t.Fatalf("unexpected number of bytes skipped: %d, expected: %d", n, len(data)-1)
+		}
+
+		// The values read the same column binary.
+		sv := bstvalue.EmptyStructValueOf(st)
+		if err = sv.UnmarshalValue(data[1:], bstio.ValueOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if s := sv.String(); !strings.Contains(s, "21.625") || !strings.Contains(s, "-2.25") {
+			t.Fatalf("unexpected struct value: %s", s)
+		}
+	})
+
+	t.Run("Descending", func(t *testing.T) {
+		data := compose(t, ComposerOptions{Descending: true})
+		extract(t, data, ExtractorOptions{Descending: true})
+	})
+
+	t.Run("OrderedFloats", func(t *testing.T) {
+		data := compose(t, ComposerOptions{OrderedFloats: true})
+		extract(t, data, ExtractorOptions{OrderedFloats: true})
+	})
+
+	t.Run("Transforms", func(t *testing.T) {
+		plain := compose(t, ComposerOptions{FloatColumn: &bstcol.ArrayEncodingOptions{}})
+		transformed := compose(t, ComposerOptions{})
+		if bytes.Equal(plain, transformed) {
+			t.Fatal("expected the transforms to change the column binary")
+		}
+		extract(t, plain, ExtractorOptions{})
+	})
+
+	t.Run("Comparable", func(t *testing.T) {
+		// The comparable binaries are not column encoded, as it would break their order.
+		data := compose(t, ComposerOptions{Comparable: true})
+		extract(t, data, ExtractorOptions{Comparable: true})
+	})
+
+	t.Run("FixedSizeMismatch", func(t *testing.T) {
+		data := compose(t, ComposerOptions{})
+		// The position column count is replaced with 2.
+		pos := 1 + 1 + 2 + 6*8 + 1 + 1
+		malformed := bytes.Clone(data)
+		malformed[pos] = 0x02
+		x, err := NewExtractor(bytes.NewReader(malformed), ExtractorOptions{ExpectedType: st})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer x.Close()
+		for x.Next() {
+			if x.Index() != 1 {
+				if _, err = x.Skip(); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			err = x.ReadArray(func(ax *Extractor) error { return nil })
+			var be *bsterr.Error
+			if !errors.As(err, &be) || be.Code != bsterr.CodeMalformedBinary {
+				t.Fatalf("expected malformed binary error, got: %v", err)
+			}
+			return
+		}
+		t.Fatal("position field not extracted")
+	})
+
+	t.Run("InvalidElem", func(t *testing.T) {
+		at := &bsttype.Array{Type: bsttype.Int64(), Encoding: bsttype.ArrayEncodingFloatColumn}
+		if _, err := NewComposer(&bytes.Buffer{}, at, ComposerOptions{}); err == nil {
+			t.Fatal("expected error for float column int64 array")
+		}
+	})
+}
+
+func TestExtractorNullBitmapArray(t *testing.T) {
+	values := []*int32{nil, ptr(int32(1)), nil, nil, ptr(int32(-2)), nil, nil, nil, nil, ptr(int32(3))}
+	arrayOf := func(enc bsttype.ArrayEncoding) *bsttype.Struct {
+		return &bsttype.Struct{Fields: []bsttype.StructField{
+			{Index: 1, Name: "Values", Type: &bsttype.Array{Type: bsttype.NullableOf(bsttype.Int32()), Encoding: enc}},
+			{Index: 2, Name: "Tail", Type: bsttype.Uint8()},
+		}}
+	}
+
+	compose := func(t *testing.T, st *bsttype.Struct, opts ComposerOptions) []byte {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, v := range values {
+				if v == nil {
+					if err := ac.WriteNull(); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := ac.WriteNotNull(); err != nil {
+					return err
+				}
+				if err := ac.WriteInt32(*v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, len(values))
+		if err != nil {
+			t.Fatalf("writing values failed: %v", err)
+		}
+		if err = c.WriteUint8(7); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	extract := func(t *testing.T, st *bsttype.Struct, data []byte, comparable bool, stop int) {
+		x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: st, Comparable: comparable})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer x.Close()
+
+		for x.Next() {
+			switch x.Index() {
+			case 0:
+				err = x.ReadArray(func(ax *Extractor) error {
+					for ax.Next() {
+						if ax.Index() == stop {
+							return nil
+						}
+						// Every third element is skipped, the remaining are read.
+						if ax.Index()%3 == 2 {
+							if _, err := ax.Skip(); err != nil {
+								return err
+							}
+							continue
+						}
+						isNull, err := ax.IsNull()
+						if err != nil {
+							return err
+						}
+						want := values[ax.Index()]
+						if isNull != (want == nil) {
+							return bsterr.Err(bsterr.CodeInvalidValue, "unexpected null flag").WithDetail("index", ax.Index())
+						}
+						if isNull {
+							continue
+						}
+						v, err := ax.ReadInt32()
+						if err != nil {
+							return err
+						}
+						if v != *want {
+							return bsterr.Err(bsterr.CodeInvalidValue, "unexpected value").WithDetail("index", ax.Index())
+						}
+					}
+					return ax.Err()
+				})
+			case 1:
+				var v uint8
+				v, err = x.ReadUint8()
+				if err == nil && v != 7 {
+					t.Fatalf("unexpected tail: %d", v)
+				}
+			}
+			if err != nil {
+				t.Fatalf("extracting field %d failed: %v", x.Index(), err)
+			}
+		}
+		if err = x.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if x.BytesRead() != len(data) {
+			t.Fatalf("unexpected number of bytes read: %d, expected: %d", x.BytesRead(), len(data))
+		}
+	}
+
+	t.Run("NullBitmap", func(t *testing.T) {
+		st := arrayOf(bsttype.ArrayEncodingNullBitmap)
+		data := compose(t, st, ComposerOptions{})
+		// header, length, 2 bytes of bitmap, 3 values, tail.
+		if len(data) != 1+2+2+3*4+1 {
+			t.Fatalf("unexpected binary size: %d", len(data))
+		}
+		for _, stop := range []int{-1, 0, 5} {
+			extract(t, st, data, false, stop)
+		}
+
+		n, err := bstskip.SkipFuncOf(st)(bytes.NewReader(data[1:]), bstio.ValueOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(n) != len(data)-1 {
+			t.Fatalf("unexpected number of bytes skipped: %d, expected: %d", n, len(data)-1)
+		}
+	})
+
+	t.Run("Plain", func(t *testing.T) {
+		st := arrayOf(bsttype.ArrayEncodingPlain)
+		data := compose(t, st, ComposerOptions{})
+		for _, stop := range []int{-1, 0, 5} {
+			extract(t, st, data, false, stop)
+		}
+	})
+
+	t.Run("Comparable", func(t *testing.T) {
+		// The comparable binaries keep the null flags of the elements, so that the nulls are ordered first.
+		data := compose(t, arrayOf(bsttype.ArrayEncodingNullBitmap), ComposerOptions{Comparable: true})
+		if plain := compose(t, arrayOf(bsttype.ArrayEncodingPlain), ComposerOptions{Comparable: true}); !bytes.Equal(data, plain) {
+			t.Fatalf("comparable binary differs from the plain one: %x != %x", data, plain)
+		}
+	})
+
+	t.Run("InvalidElem", func(t *testing.T) {
+		at := &bsttype.Array{Type: bsttype.Int32(), Encoding: bsttype.ArrayEncodingNullBitmap}
+		if _, err := NewComposer(&bytes.Buffer{}, at, ComposerOptions{}); err == nil {
+			t.Fatal("expected error for not nullable null bitmap array elements")
+		}
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestExtractorExpect(t *testing.T) {
+	item := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.String()},
+		{Index: 2, Name: "Count", Type: bsttype.Uint8()},
+	}}
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Items", Type: &bsttype.Array{Type: item}},
+	}}
+
+	var buf bytes.Buffer
+	c, err := NewComposer(&buf, st, ComposerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.WriteArray(func(ac *Composer) error {
+		for i := 0; i < 2; i++ {
+			err := ac.WriteStruct(func(sc *Composer) error {
+				if err := sc.WriteString("item"); err != nil {
+					return err
+				}
+				return sc.WriteUint8(uint8(i))
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	x, err := NewExtractor(bytes.NewReader(buf.Bytes()), ExtractorOptions{ExpectedType: st})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+
+	if !x.Next() {
+		t.Fatal(x.Err())
+	}
+	at, err := x.ExpectArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at.Type != item {
+		t.Fatalf("unexpected array type: %v", at)
+	}
+	if _, err = x.ExpectStruct(); err == nil {
+		t.Fatal("expected error for mismatching kind")
+	}
+
+	var mismatch error
+	err = x.ReadArray(func(ax *Extractor) error {
+		for ax.Next() {
+			if _, err := ax.ExpectStruct(); err != nil {
+				return err
+			}
+			// The sub extractor reuses the array extractor, thus the array index needs to be kept.
+			index := ax.Index()
+			err := ax.ReadStruct(func(sx *Extractor) error {
+				for sx.Next() {
+					if sx.Index() == 1 && index == 1 {
+						mismatch = sx.ExpectKind(bsttype.KindString)
+						if got := sx.Path(); got != "$.Items[1].Count" {
+							t.Errorf("unexpected path: %s", got)
+						}
+					}
+					if _, err := sx.Skip(); err != nil {
+						return err
+					}
+				}
+				return sx.Err()
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return ax.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var be *bsterr.Error
+	if !errors.As(mismatch, &be) {
+		t.Fatalf("expected bst error, got: %v", mismatch)
+	}
+	if be.Code != bsterr.CodeInvalidType {
+		t.Fatalf("unexpected error code: %d", be.Code)
+	}
+	details := map[string]interface{}{}
+	for _, d := range be.Details {
+		details[d.Key] = d.Value
+	}
+	if details["path"] != "$.Items[1].Count" || details["expected"] != bsttype.KindString || details["actual"] != bsttype.KindUint8 {
+		t.Fatalf("unexpected error details: %v", be.Details)
+	}
+	if got := x.Path(); got != "$.Items" {
+		t.Fatalf("unexpected path after nested extraction: %s", got)
+	}
+}
+
+func TestExtractorTrace(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.String()},
+		{Index: 2, Name: "Tags", Type: &bsttype.Array{Type: bsttype.Uint8()}},
+		{Index: 3, Name: "Opt", Type: bsttype.NullableOf(bsttype.Int32()), Descending: true},
+		{Index: 4, Name: "Skipped", Type: bsttype.Uint16()},
+	}}
+
+	var buf bytes.Buffer
+	c, err := NewComposer(&buf, st, ComposerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteString("abc"); err != nil {
+		t.Fatal(err)
+	}
+	err = c.WriteArray(func(ac *Composer) error {
+		for _, v := range []uint8{1, 2} {
+			if err := ac.WriteUint8(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteNotNull(); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteInt32(-5); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteUint16(9); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var trace Trace
+	x, err := NewExtractor(bytes.NewReader(buf.Bytes()), ExtractorOptions{ExpectedType: st, Trace: &trace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+
+	for x.Next() {
+		switch x.Index() {
+		case 0:
+			_, err = x.ReadString()
+		case 1:
+			err = x.ReadArray(func(ax *Extractor) error {
+				for ax.Next() {
+					if _, err := ax.ReadUint8(); err != nil {
+						return err
+					}
+				}
+				return ax.Err()
+			})
+		case 2:
+			if _, err = x.IsNull(); err == nil {
+				_, err = x.ReadInt32()
+			}
+		case 3:
+			_, err = x.Skip()
+		}
+		if err != nil {
+			t.Fatalf("extracting field %d failed: %v", x.Index(), err)
+		}
+	}
+	if err = x.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The binary is: header (1), string length and value (2 + 3), array length (2) and values (2),
+	// nullable flag (1), int32 (4), uint16 (2).
+	expected := []TraceEvent{
+		{Offset: 1, Size: 5, Op: TraceOpRead, Path: "$.Name", Kind: "String", Value: `"abc"`},
+		{Offset: 6, Size: 4, Op: TraceOpComposite, Path: "$.Tags", Kind: "Array"},
+		{Offset: 8, Size: 1, Op: TraceOpRead, Path: "$.Tags[0]", Kind: "Uint8", Value: "1"},
+		{Offset: 9, Size: 1, Op: TraceOpRead, Path: "$.Tags[1]", Kind: "Uint8", Value: "2"},
+		{Offset: 10, Size: 1, Op: TraceOpNullFlag, Path: "$.Opt", Kind: "Nullable", Value: "false", Descending: true},
+		{Offset: 11, Size: 4, Op: TraceOpRead, Path: "$.Opt", Kind: "Int32", Value: "-5", Descending: true},
+		{Offset: 15, Size: 2, Op: TraceOpSkip, Path: "$.Skipped", Kind: "Uint16"},
+	}
+	if len(trace.Events) != len(expected) {
+		t.Fatalf("unexpected number of trace events: %d, expected: %d - %+v", len(trace.Events), len(expected), trace.Events)
+	}
+	for i, e := range expected {
+		if trace.Events[i] != e {
+			t.Errorf("unexpected trace event %d: %+v, expected: %+v", i, trace.Events[i], e)
+		}
+	}
+
+	var out bytes.Buffer
+	if err = trace.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Trace
+	if err = json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Events) != len(expected) || decoded.Events[1] != expected[1] {
+		t.Fatalf("unexpected decoded trace: %s", out.String())
+	}
+}
+
+func TestExtractorOffset(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.String()},
+		{Index: 2, Name: "Tags", Type: &bsttype.Array{Type: bsttype.Uint8()}},
+		{Index: 3, Name: "Opt", Type: bsttype.NullableOf(bsttype.Int32())},
+		{Index: 4, Name: "Skipped", Type: bsttype.Uint16()},
+	}}
+
+	// The value is preceded by three bytes of some other data.
+	buf := bytes.NewBuffer([]byte{0xAA, 0xBB, 0xCC})
+	c, err := NewComposer(buf, st, ComposerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteString("abc"); err != nil {
+		t.Fatal(err)
+	}
+	err = c.WriteArray(func(ac *Composer) error {
+		for _, v := range []uint8{1, 2} {
+			if err := ac.WriteUint8(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteNotNull(); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteInt32(-5); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteUint16(9); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+	if _, err = r.Seek(3, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	x, err := NewExtractor(r, ExtractorOptions{ExpectedType: st})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+
+	// The binary is: prefix (3), header (1), string length and value (2 + 3), array length (2) and values (2),
+	// nullable flag (1), int32 (4), uint16 (2).
+	var starts []int
+	for x.Next() {
+		starts = append(starts, x.StartOffset())
+		switch x.Index() {
+		case 0:
+			_, err = x.ReadString()
+		case 1:
+			err = x.ReadArray(func(ax *Extractor) error {
+				for ax.Next() {
+					starts = append(starts, ax.StartOffset())
+					if _, err := ax.ReadUint8(); err != nil {
+						return err
+					}
+				}
+				if ax.BytesRead() != 4 || ax.Offset() != 13 {
+					t.Errorf("unexpected array bytes read: %d and offset: %d", ax.BytesRead(), ax.Offset())
+				}
+				return ax.Err()
+			})
+		case 2:
+			if _, err = x.IsNull(); err == nil {
+				_, err = x.ReadInt32()
+			}
+		case 3:
+			_, err = x.Skip()
+		}
+		if err != nil {
+			t.Fatalf("extracting field %d failed: %v", x.Index(), err)
+		}
+	}
+	if err = x.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int{4, 9, 11, 12, 13, 18}
+	if !reflect.DeepEqual(starts, expected) {
+		t.Fatalf("unexpected start offsets: %v, expected: %v", starts, expected)
+	}
+	if x.Offset() != 20 || x.BytesRead() != 17 {
+		t.Fatalf("unexpected offset: %d and bytes read: %d", x.Offset(), x.BytesRead())
+	}
+}
+
+func TestExtractorLengthPrefixedCollections(t *testing.T) {
+	matrix := &bsttype.Array{Type: &bsttype.Array{Type: bsttype.Uint16()}}
+	index := bsttype.MapTypeOf(bsttype.String(), &bsttype.Array{Type: bsttype.String()}, false, false)
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Matrix", Type: matrix},
+		{Index: 2, Name: "Index", Type: index},
+		{Index: 3, Name: "Tail", Type: bsttype.Uint8()},
+	}}
+	rows := [][]uint16{{1, 2, 3}, {4}, {5, 6}}
+	keys := []string{"a", "b"}
+	values := [][]string{{"x", "y"}, {"z"}}
+
+	compose := func(t *testing.T, prefixed bool) []byte {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, ComposerOptions{CompatibilityMode: true, LengthPrefixedCollections: prefixed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, row := range rows {
+				err := ac.WriteArray(func(rc *Composer) error {
+					for _, v := range row {
+						if err := rc.WriteUint16(v); err != nil {
+							return err
+						}
+					}
+					return nil
+				}, len(row))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}, len(rows))
+		if err != nil {
+			t.Fatalf("writing matrix failed: %v", err)
+		}
+		err = c.WriteMap(func(mc *Composer) error {
+			for i, k := range keys {
+				if err := mc.WriteString(k); err != nil {
+					return err
+				}
+				err := mc.WriteArray(func(vc *Composer) error {
+					for _, v := range values[i] {
+						if err := vc.WriteString(v); err != nil {
+							return err
+						}
+					}
+					return nil
+				}, len(values[i]))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}, len(keys))
+		if err != nil {
+			t.Fatalf("writing index failed: %v", err)
+		}
+		if err = c.WriteUint8(7); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	plain := compose(t, false)
+	data := compose(t, true)
+
+	// Each of the three matrix rows and two index values is prefixed with its size (size header and the size).
+	if len(data) != len(plain)+5*2 {
+		t.Fatalf("unexpected length prefixed binary size: %d, plain: %d", len(data), len(plain))
+	}
+	if data[0]&(1<<5) == 0 {
+		t.Fatalf("length prefixed collections header flag not set: %08b", data[0])
+	}
+
+	x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: st})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+
+	for x.Next() {
+		switch x.Index() {
+		case 0:
+			err = x.ReadArray(func(ax *Extractor) error {
+				for ax.Next() {
+					// The first row is read partially, the second one is skipped and the last is read fully.
+					row := ax.Index()
+					if row == 1 {
+						if _, err := ax.Skip(); err != nil {
+							return err
+						}
+						continue
+					}
+					err := ax.ReadArray(func(rx *Extractor) error {
+						for rx.Next() {
+							v, err := rx.ReadUint16()
+							if err != nil {
+								return err
+							}
+							if v != rows[row][rx.Index()] {
+								return bsterr.Err(bsterr.CodeInvalidValue, "unexpected matrix value").WithDetail("value", v)
+							}
+							if row == 0 {
+								return nil
+							}
+						}
+						return rx.Err()
+					})
+					if err != nil {
+						return err
+					}
+				}
+				return ax.Err()
+			})
+		case 1:
+			// Only the first key and value are read, the remaining entries are skipped.
+			err = x.ReadMap(func(mx *Extractor) error {
+				if !mx.Next() {
+					return mx.Err()
+				}
+				k, err := mx.ReadString()
+				if err != nil {
+					return err
+				}
+				if !mx.Next() {
+					return mx.Err()
+				}
+				var got []string
+				err = mx.ReadArray(func(vx *Extractor) error {
+					for vx.Next() {
+						v, err := vx.ReadString()
+						if err != nil {
+							return err
+						}
+						got = append(got, v)
+					}
+					return vx.Err()
+				})
+				if err != nil {
+					return err
+				}
+				if k != keys[0] || fmt.Sprint(got) != fmt.Sprint(values[0]) {
+					return bsterr.Err(bsterr.CodeInvalidValue, "unexpected index entry").WithDetails(bsterr.D("key", k), bsterr.D("value", got))
+				}
+				return nil
+			})
+		case 2:
+			var v uint8
+			v, err = x.ReadUint8()
+			if err == nil && v != 7 {
+				t.Fatalf("unexpected tail: %d", v)
+			}
+		}
+		if err != nil {
+			t.Fatalf("extracting field %d failed: %v", x.Index(), err)
+		}
+	}
+	if err = x.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if x.BytesRead() != len(data) {
+		t.Fatalf("unexpected number of bytes read: %d, expected: %d", x.BytesRead(), len(data))
+	}
+
+	t.Run("Skip", func(t *testing.T) {
+		// The matrix rows are skipped by their size prefixes.
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, matrix, ComposerOptions{CompatibilityMode: true, LengthPrefixedCollections: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range rows {
+			err = c.WriteArray(func(rc *Composer) error {
+				for _, v := range row {
+					if err := rc.WriteUint16(v); err != nil {
+						return err
+					}
+				}
+				return nil
+			}, len(row))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		data := buf.Bytes()
+		opts := bstio.ValueOptions{CompatibilityMode: true, LengthPrefixed: true}
+		n, err := bstskip.SkipArray(bytes.NewReader(data[1:]), matrix, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(n) != len(data)-1 {
+			t.Fatalf("unexpected number of bytes skipped: %d, expected: %d", n, len(data)-1)
+		}
+	})
+}
+
+func TestExtractorFieldEncoding(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Body", Type: bsttype.String(), Encoding: bsttype.FieldEncodingDeflate},
+		{Index: 2, Name: "Blob", Type: &bsttype.Bytes{}, Encoding: bsttype.FieldEncodingDeflate, Descending: true},
+		{Index: 3, Name: "Times", Type: &bsttype.Array{Type: bsttype.Int64()}, Encoding: bsttype.FieldEncodingDelta},
+		{Index: 4, Name: "Counts", Type: &bsttype.Array{Type: bsttype.Uint8()}, Encoding: bsttype.FieldEncodingDelta},
+		{Index: 5, Name: "Tail", Type: bsttype.Uint8()},
+	}}
+	body := strings.Repeat("the quick brown fox jumps over the lazy dog ", 20)
+	blob := bytes.Repeat([]byte{0x01, 0x02, 0x03}, 50)
+	times := []int64{1700000000, 1700000005, 1700000003, -4}
+	counts := []uint8{250, 3, 3, 255}
+
+	compose := func(t *testing.T, opts ComposerOptions) []byte {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = c.WriteString(body); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.WriteBytes(bytes.Clone(blob)); err != nil {
+			t.Fatal(err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, v := range times {
+				if err := ac.WriteInt64(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, len(times))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, v := range counts {
+				if err := ac.WriteUint8(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, len(counts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = c.WriteUint8(7); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	extract := func(t *testing.T, data []byte, opts ExtractorOptions) {
+		x, err := NewExtractor(bytes.NewReader(data), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer x.Close()
+
+		for x.Next() {
+			switch x.Index() {
+			case 0:
+				var v string
+				v, err = x.ReadString()
+				if err == nil && v != body {
+					t.Fatalf("unexpected body: %q", v)
+				}
+			case 1:
+				var v []byte
+				v, err = x.ReadBytes()
+				if err == nil && !bytes.Equal(v, blob) {
+					t.Fatalf("unexpected blob: %x", v)
+				}
+			case 2:
+				// The second time is skipped, which still needs to be decoded for the following ones.
+				err = x.ReadArray(func(ax *Extractor) error {
+					for ax.Next() {
+						if ax.Index() == 1 {
+							if _, err := ax.Skip(); err != nil {
+								return err
+							}
+							continue
+						}
+						v, err := ax.ReadInt64()
+						if err != nil {
+							return err
+						}
+						if v != times[ax.Index()] {
+							return bsterr.Err(bsterr.CodeInvalidValue, "unexpected time").WithDetail("value", v)
+						}
+					}
+					return ax.Err()
+				})
+			case 3:
+				err = x.ReadArray(func(ax *Extractor) error {
+					for ax.Next() {
+						v, err := ax.Uint()
+						if err != nil {
+							return err
+						}
+						if uint8(v) != counts[ax.Index()] {
+							return bsterr.Err(bsterr.CodeInvalidValue, "unexpected count").WithDetail("value", v)
+						}
+					}
+					return ax.Err()
+				})
+			case 4:
+				var v uint8
+				v, err = x.ReadUint8()
+				if err == nil && v != 7 {
+					t.Fatalf("unexpected tail: %d", v)
+				}
+			}
+			if err != nil {
+				t.Fatalf("extracting field %d failed: %v", x.Index(), err)
+			}
+		}
+		if err = x.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if x.BytesRead() != len(data) {
+			t.Fatalf("unexpected number of bytes read: %d, expected: %d", x.BytesRead(), len(data))
+		}
+	}
+
+	t.Run("Embedded", func(t *testing.T) {
+		data := compose(t, ComposerOptions{EmbedType: true})
+		if bytes.Contains(data, []byte("quick brown")) {
+			t.Fatal("body was not compressed")
+		}
+		extract(t, data, ExtractorOptions{})
+	})
+
+	t.Run("CompatibilityMode", func(t *testing.T) {
+		data := compose(t, ComposerOptions{CompatibilityMode: true, Descending: true})
+		extract(t, data, ExtractorOptions{ExpectedType: st, CompatibilityMode: true})
+	})
+
+	t.Run("Comparable", func(t *testing.T) {
+		// The comparable binaries ignore the field encodings.
+		data := compose(t, ComposerOptions{Comparable: true})
+		if !bytes.Contains(data, []byte("quick brown")) {
+			t.Fatal("comparable body was compressed")
+		}
+		extract(t, data, ExtractorOptions{ExpectedType: st, Comparable: true})
+	})
+
+	t.Run("InflatedLimit", func(t *testing.T) {
+		// The few hundred bytes of the deflated field inflate to 1 MiB.
+		bt := &bsttype.Struct{Fields: []bsttype.StructField{
+			{Index: 1, Name: "Body", Type: bsttype.String(), Encoding: bsttype.FieldEncodingDeflate},
+		}}
+		bomb := strings.Repeat("0", 1<<20)
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, bt, ComposerOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = c.WriteString(bomb); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+		if len(data) > 4096 {
+			t.Fatalf("body was not compressed: %d bytes", len(data))
+		}
+		read := func(opts ExtractorOptions) error {
+			opts.ExpectedType = bt
+			x, err := NewExtractor(bytes.NewReader(data), opts)
+			if err != nil {
+				return err
+			}
+			defer x.Close()
+			for x.Next() {
+				if _, err = x.ReadString(); err != nil {
+					return err
+				}
+			}
+			return x.Err()
+		}
+		hasCode := func(err error, code bsterr.ErrCode) bool {
+			var be *bsterr.Error
+			return errors.As(err, &be) && be.Code == code
+		}
+
+		// 1. The inflated value is limited by the MaxDecompressedBytes, and the remaining decode budget.
+		if err = read(ExtractorOptions{MaxDecompressedBytes: 1 << 10}); !hasCode(err, bsterr.CodeValueTooLarge) {
+			t.Fatalf("expected inflated size error, got: %v", err)
+		}
+		if err = read(ExtractorOptions{Budget: NewDecodeBudget(64<<10, 0)}); !hasCode(err, bsterr.CodeValueTooLarge) {
+			t.Fatalf("expected inflated budget error, got: %v", err)
+		}
+
+		// 2. The inflated bytes are used from the budget.
+		budget := NewDecodeBudget(2<<20, 0)
+		if err = read(ExtractorOptions{Budget: budget}); err != nil {
+			t.Fatalf("extracting inflated value failed: %v", err)
+		}
+		if used, _ := budget.Used(); used != int64(len(data)+len(bomb)) {
+			t.Fatalf("unexpected budget bytes used: %d, expected: %d", used, len(data)+len(bomb))
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		it := &bsttype.Struct{Fields: []bsttype.StructField{
+			{Index: 1, Name: "Name", Type: bsttype.String(), Encoding: bsttype.FieldEncodingDelta},
+		}}
+		_, err := NewComposer(&bytes.Buffer{}, it, ComposerOptions{})
+		if !errors.Is(err, bsterr.Err(bsterr.CodeInvalidType, "struct field type could not be encoded with the field encoding")) {
+			t.Fatalf("expected invalid field encoding error, got: %v", err)
+		}
+	})
+}
+
+func TestExtractorExternalBytes(t *testing.T) {
+	bsttest.CheckPools(t)
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.String()},
+		{Index: 2, Name: "Image", Type: bsttype.ExternalBytes()},
+		{Index: 3, Name: "Thumbnail", Type: bsttype.ExternalBytes()},
+	}}
+	image := bytes.Repeat([]byte{0xAB}, 4096)
+	thumb := []byte{0x01, 0x02}
+	store := NewMemoryBlobStore()
+
+	var buf bytes.Buffer
+	c, err := NewComposer(&buf, st, ComposerOptions{EmbedType: true, BlobStore: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteString("photo"); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteExternalBytes(image); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteExternalBytes(thumb); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// The blobs are offloaded, thus the value binary stays small.
+	if store.Len() != 2 || len(data) > 2*bstio.BlobRefSize+64 {
+		t.Fatalf("unexpected blobs: %d, binary size: %d", store.Len(), len(data))
+	}
+
+	t.Run("Fetch", func(t *testing.T) {
+		x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{BlobStore: store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer x.Close()
+
+		for x.Next() {
+			switch x.Index() {
+			case 0:
+				_, err = x.Skip()
+			case 1:
+				var v []byte
+				v, err = x.ReadExternalBytes()
+				if err == nil && !bytes.Equal(v, image) {
+					t.Fatal("unexpected image")
+				}
+			case 2:
+				var ref bstio.BlobRef
+				ref, err = x.ReadBlobRef()
+				if err == nil && ref != bstio.NewBlobRef(thumb) {
+					t.Fatalf("unexpected thumbnail reference: %s", ref)
+				}
+			}
+			if err != nil {
+				t.Fatalf("extracting field %d failed: %v", x.Index(), err)
+			}
+		}
+		if err = x.Err(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("NoStore", func(t *testing.T) {
+		x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer x.Close()
+
+		x.Next()
+		if _, err = x.Skip(); err != nil {
+			t.Fatal(err)
+		}
+		x.Next()
+		if _, err = x.ReadExternalBytes(); !errors.Is(err, bsterr.Err(bsterr.CodeInvalidValue, "blob store is not defined for the external bytes")) {
+			t.Fatalf("expected undefined blob store error, got: %v", err)
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		// The blob fetched from the store is verified against its reference.
+		tampered := NewMemoryBlobStore()
+		if err := tampered.PutBlob(bstio.NewBlobRef(image), image[1:]); err != nil {
+			t.Fatal(err)
+		}
+		x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{BlobStore: tampered})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer x.Close()
+
+		x.Next()
+		if _, err = x.Skip(); err != nil {
+			t.Fatal(err)
+		}
+		x.Next()
+		if _, err = x.ReadExternalBytes(); !errors.Is(err, bsterr.Err(bsterr.CodeMalformedBinary, "external bytes don't match their reference")) {
+			t.Fatalf("expected mismatching blob error, got: %v", err)
+		}
+	})
+}
+
+func TestExtractorInlineThreshold(t *testing.T) {
+	bsttest.CheckPools(t)
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Title", Type: bsttype.String(), Encoding: bsttype.FieldEncodingExternal},
+		{Index: 2, Name: "Body", Type: &bsttype.Bytes{}, Encoding: bsttype.FieldEncodingExternal},
+		{Index: 3, Name: "Count", Type: bsttype.Int64()},
+	}}
+	title := "short title"
+	body := bytes.Repeat([]byte("lorem ipsum "), 100)
+
+	compose := func(t *testing.T, opts ComposerOptions) []byte {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = c.WriteString(title); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.WriteBytes(body); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.WriteInt64(42); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	extract := func(t *testing.T, data []byte, opts ExtractorOptions, skip bool) {
+		x, err := NewExtractor(bytes.NewReader(data), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer x.Close()
+
+		for x.Next() {
+			if skip && x.Index() < 2 {
+				_, err = x.Skip()
+			} else {
+				switch x.Index() {
+				case 0:
+					var v string
+					v, err = x.ReadString()
+					if err == nil && v != title {
+						t.Fatalf("unexpected title: %q", v)
+					}
+				case 1:
+					var v []byte
+					v, err = x.ReadBytes()
+					if err == nil && !bytes.Equal(v, body) {
+						t.Fatal("unexpected body")
+					}
+				case 2:
+					var v int64
+					v, err = x.ReadInt64()
+					if err == nil && v != 42 {
+						t.Fatalf("unexpected count: %d", v)
+					}
+				}
+			}
+			if err != nil {
+				t.Fatalf("extracting field %d failed: %v", x.Index(), err)
+			}
+		}
+		if err = x.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Embedded", func(t *testing.T) {
+		store := NewMemoryBlobStore()
+		data := compose(t, ComposerOptions{EmbedType: true, BlobStore: store, InlineThreshold: 64})
+
+		// Only the body is above the threshold.
+		if store.Len() != 1 || len(data) > len(body)/2 {
+			t.Fatalf("unexpected blobs: %d, binary size: %d", store.Len(), len(data))
+		}
+		extract(t, data, ExtractorOptions{BlobStore: store}, false)
+		extract(t, data, ExtractorOptions{BlobStore: store}, true)
+	})
+
+	t.Run("CompatibilityMode", func(t *testing.T) {
+		store := NewMemoryBlobStore()
+		data := compose(t, ComposerOptions{CompatibilityMode: true, BlobStore: store})
+
+		// The zero threshold offloads all the values.
+		if store.Len() != 2 {
+			t.Fatalf("unexpected blobs: %d", store.Len())
+		}
+		extract(t, data, ExtractorOptions{CompatibilityMode: true, ExpectedType: st, BlobStore: store}, false)
+		extract(t, data, ExtractorOptions{CompatibilityMode: true, ExpectedType: st, BlobStore: store}, true)
+	})
+
+	t.Run("Inline", func(t *testing.T) {
+		// No value is offloaded, thus the store is not needed.
+		data := compose(t, ComposerOptions{InlineThreshold: len(body) + 1})
+		extract(t, data, ExtractorOptions{ExpectedType: st}, false)
+	})
+
+	t.Run("NoStore", func(t *testing.T) {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, ComposerOptions{InlineThreshold: 64})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = c.WriteString(title); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.WriteBytes(body); !errors.Is(err, bsterr.Err(bsterr.CodeInvalidValue, "blob store is not defined for the external field")) {
+			t.Fatalf("expected undefined blob store error, got: %v", err)
+		}
+	})
+}
+
+func TestExtractorReadFailure(t *testing.T) {
+	bsttest.CheckPools(t)
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.String()},
+		{Index: 2, Name: "Scores", Type: &bsttype.Array{Type: bsttype.Int32()}},
+		{Index: 3, Name: "Ratio", Type: bsttype.Float64()},
+	}}
+	var buf bytes.Buffer
+	c, err := NewComposer(&buf, st, ComposerOptions{EmbedType: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteString("name"); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteArray(func(ac *Composer) error {
+		for _, v := range []int32{1, 2, 3} {
+			if err := ac.WriteInt32(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteFloat64(0.5); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	extract := func(x *Extractor) error {
+		defer x.Close()
+		for x.Next() {
+			var err error
+			switch x.Index() {
+			case 0:
+				_, err = x.ReadString()
+			case 1:
+				err = x.ReadArray(func(ax *Extractor) error {
+					for ax.Next() {
+						if _, err := ax.ReadInt32(); err != nil {
+							return err
+						}
+					}
+					return ax.Err()
+				})
+			case 2:
+				_, err = x.ReadFloat64()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return x.Err()
+	}
+
+	// Each failing read has to be reported, wherever it happens.
+	for offset := int64(0); offset < int64(len(data)); offset++ {
+		x, err := NewExtractor(bsttest.NewFaultReadSeeker(bytes.NewReader(data), bsttest.AtOffset(offset)), ExtractorOptions{})
+		if err == nil {
+			err = extract(x)
+		}
+		if !errors.Is(err, bsttest.ErrInjected) {
+			t.Fatalf("expected injected read error at offset %d, got: %v", offset, err)
+		}
+	}
+}
+
+func TestExtractorBitmap(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Flags", Type: bsttype.Bitmap()},
+		{Index: 2, Name: "Reversed", Type: bsttype.Bitmap(), Descending: true},
+		{Index: 3, Name: "Count", Type: bsttype.Int8()},
+	}}
+	flags := make([]bool, 100)
+	for i := range flags {
+		flags[i] = i%7 == 0
+	}
+	bitmap := bstio.BitmapOf(flags...)
+
+	testCases := []struct {
+		Name string
+		Opts EncodingOptions
+	}{
+		{Name: "Plain", Opts: EncodingOptions{}},
+		{Name: "EmbedType", Opts: EncodingOptions{EmbedType: true}},
+		{Name: "Compatibility", Opts: EncodingOptions{CompatibilityMode: true}},
+		{Name: "Comparable", Opts: EncodingOptions{Comparable: true}},
+		{Name: "Descending", Opts: EncodingOptions{Comparable: true, Descending: true}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := NewComposer(&buf, st, tc.Opts.ComposerOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = c.WriteBitmap(bitmap); err != nil {
+				t.Fatal(err)
+			}
+			if err = c.WriteBitmap(bstio.BitmapOf(true, false, true)); err != nil {
+				t.Fatal(err)
+			}
+			if err = c.WriteInt8(7); err != nil {
+				t.Fatal(err)
+			}
+			if err = c.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The bitmaps are read back, or skipped.
+			for _, skip := range []bool{false, true} {
+				x, err := NewExtractor(bytes.NewReader(buf.Bytes()), tc.Opts.ExtractorOptions(st))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for x.Next() {
+					switch {
+					case x.Index() == 2:
+						v, err := x.ReadInt8()
+						if err != nil || v != 7 {
+							t.Fatalf("unexpected count: %d, err: %v", v, err)
+						}
+					case skip:
+						if _, err = x.Skip(); err != nil {
+							t.Fatal(err)
+						}
+					case x.Index() == 0:
+						v, err := x.ReadBitmap()
+						if err != nil {
+							t.Fatal(err)
+						}
+						if v.Len != bitmap.Len || v.Count() != 15 || !bytes.Equal(v.Bits, bitmap.Bits) {
+							t.Fatalf("unexpected flags: %v", v.Bools())
+						}
+					default:
+						v, err := x.ReadBitmap()
+						if err != nil {
+							t.Fatal(err)
+						}
+						if v.Len != 3 || !v.Get(0) || v.Get(1) || !v.Get(2) {
+							t.Fatalf("unexpected reversed flags: %v", v.Bools())
+						}
+					}
+				}
+				if err = x.Err(); err != nil {
+					t.Fatal(err)
+				}
+				x.Close()
+			}
+		})
+	}
+}
+
+func TestExtractorDecimal(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Price", Type: bsttype.Decimal()},
+		{Index: 2, Name: "Discount", Type: bsttype.Decimal(), Descending: true},
+		{Index: 3, Name: "Count", Type: bsttype.Int8()},
+	}}
+	price, err := bstio.ParseDecimal("12345678901234567890.123456789")
+	if err != nil {
+		t.Fatal(err)
+	}
+	discount := bstio.NewDecimal(-150, 2)
+
+	testCases := []struct {
+		Name string
+		Opts EncodingOptions
+	}{
+		{Name: "Plain", Opts: EncodingOptions{}},
+		{Name: "EmbedType", Opts: EncodingOptions{EmbedType: true}},
+		{Name: "Compatibility", Opts: EncodingOptions{CompatibilityMode: true}},
+		{Name: "Descending", Opts: EncodingOptions{Comparable: true, Descending: true}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := NewComposer(&buf, st, tc.Opts.ComposerOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = c.WriteDecimal(price); err != nil {
+				t.Fatal(err)
+			}
+			if err = c.WriteDecimal(discount); err != nil {
+				t.Fatal(err)
+			}
+			if err = c.WriteInt8(7); err != nil {
+				t.Fatal(err)
+			}
+			if err = c.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The decimals are read back, or skipped.
+			for _, skip := range []bool{false, true} {
+				x, err := NewExtractor(bytes.NewReader(buf.Bytes()), tc.Opts.ExtractorOptions(st))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for x.Next() {
+					switch {
+					case x.Index() == 2:
+						v, err := x.ReadInt8()
+						if err != nil || v != 7 {
+							t.Fatalf("unexpected count: %d, err: %v", v, err)
+						}
+					case skip:
+						if _, err = x.Skip(); err != nil {
+							t.Fatal(err)
+						}
+					default:
+						expected := price
+						if x.Index() == 1 {
+							expected = discount
+						}
+						v, err := x.ReadDecimal()
+						if err != nil {
+							t.Fatal(err)
+						}
+						if v.Cmp(expected) != 0 {
+							t.Fatalf("unexpected decimal: %s, expected: %s", v, expected)
+						}
+					}
+				}
+				if err = x.Err(); err != nil {
+					t.Fatal(err)
+				}
+				x.Close()
+			}
+		})
+	}
+}
+
+func TestExtractorUUID(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "ID", Type: bsttype.UUID()},
+		{Index: 2, Name: "Parent", Type: bsttype.UUID(), Descending: true},
+	}}
+	id, err := bstio.ParseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := [16]byte{0xff, 1, 2, 3}
+
+	testCases := []struct {
+		Name string
+		Opts EncodingOptions
+	}{
+		{Name: "Plain", Opts: EncodingOptions{}},
+		{Name: "Compatibility", Opts: EncodingOptions{CompatibilityMode: true}},
+		{Name: "Descending", Opts: EncodingOptions{Comparable: true, Descending: true}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := NewComposer(&buf, st, tc.Opts.ComposerOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = c.WriteUUID(id); err != nil {
+				t.Fatal(err)
+			}
+			if err = c.WriteUUID(parent); err != nil {
+				t.Fatal(err)
+			}
+			if err = c.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			x, err := NewExtractor(bytes.NewReader(buf.Bytes()), tc.Opts.ExtractorOptions(st))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer x.Close()
+			for x.Next() {
+				expected := id
+				if x.Index() == 1 {
+					expected = parent
+				}
+				v, err := x.ReadUUID()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v != expected {
+					t.Fatalf("unexpected uuid: %x, expected: %x", v, expected)
+				}
+			}
+			if err = x.Err(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	t.Run("Marshal", func(t *testing.T) {
+		// UUID mirrors the github.com/google/uuid.UUID.
+		type UUID [16]byte
+		type entity struct {
+			ID     UUID          `bst:"id"`
+			Owners map[UUID]bool `bst:"owners"`
+		}
+		in := entity{ID: UUID(id), Owners: map[UUID]bool{UUID(parent): true, {}: false}}
+		et, err := StructTypeOf(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k := et.Fields[0].Type.Kind(); k != bsttype.KindUUID {
+			t.Fatalf("unexpected field kind: %s", k)
+		}
+		data, err := Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out entity
+		if err = Unmarshal(data, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Fatalf("unexpected entity: %+v, expected: %+v", out, in)
+		}
+	})
+}
+
+func TestExtractorEnum(t *testing.T) {
+	status := &bsttype.Enum{ValueBytes: 1, Elements: []bsttype.EnumElement{
+		{String: "Draft", Index: 1}, {String: "Sent", Index: 2}, {String: "Paid", Index: 5},
+	}}
+	narrow := &bsttype.Enum{ValueBytes: 1, Elements: []bsttype.EnumElement{{String: "Draft", Index: 1}}}
+	at := bsttype.ArrayOf(status)
+	names := []string{"Paid", "Draft", "Sent"}
+
+	for _, opts := range []EncodingOptions{{}, {Comparable: true, Descending: true}} {
+		var buf bytes.Buffer
+		co := opts.ComposerOptions()
+		co.Length = len(names)
+		c, err := NewComposer(&buf, at, co)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if err = c.WriteEnum(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		x, err := NewExtractor(bytes.NewReader(buf.Bytes()), opts.ExtractorOptions(at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x.Next() {
+			v, err := x.ReadEnum()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != names[x.Index()] {
+				t.Fatalf("unexpected enum: %s, expected: %s", v, names[x.Index()])
+			}
+		}
+		if err = x.Err(); err != nil {
+			t.Fatal(err)
+		}
+		x.Close()
+
+		// The values out of the enum elements are rejected.
+		x, err = NewExtractor(bytes.NewReader(buf.Bytes()), opts.ExtractorOptions(bsttype.ArrayOf(narrow)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !x.Next() {
+			t.Fatal(x.Err())
+		}
+		if _, err = x.ReadEnum(); err == nil {
+			t.Fatal("expected reading unknown enum value to fail")
+		}
+		x.Close()
+	}
+
+	c, err := NewComposer(&bytes.Buffer{}, bsttype.ArrayOf(status), ComposerOptions{Length: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteEnum("Void"); err == nil {
+		t.Fatal("expected writing unknown enum name to fail")
+	}
+}
+
+func BenchmarkExtractorNested(b *testing.B) {
+	it := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "ID", Type: bsttype.Uint8()},
+		{Index: 2, Name: "Tags", Type: &bsttype.Array{Type: bsttype.Uint8()}},
+	}}
+	at := &bsttype.Array{Type: it}
+	items := make([]bstvalue.Value, 1000)
+	for i := range items {
+		items[i] = bstvalue.MustNewStructValue(it, []bstvalue.Value{
+			bstvalue.NewUint8Value(uint8(i)),
+			bstvalue.MustArrayValueOf(it.Fields[1].Type.(*bsttype.Array), []bstvalue.Value{
+				bstvalue.NewUint8Value(1), bstvalue.NewUint8Value(2),
+			}),
+		})
+	}
+	data, err := bstvalue.MustArrayValueOf(at, items).MarshalValue(bstio.ValueOptions{})
+	if err != nil {
+		b.Fatalf("marshaling array failed: %v", err)
+	}
+
+	readItem := func(sx *Extractor) error {
+		for sx.Next() {
+			if sx.Index() == 0 {
+				if _, err := sx.ReadUint8(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := sx.ReadArray(func(ax *Extractor) error {
+				for ax.Next() {
+					if _, err := ax.ReadUint8(); err != nil {
+						return err
+					}
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{Headless: true, ExpectedType: at})
+		if err != nil {
+			b.Fatalf("creating extractor failed: %v", err)
+		}
+		for x.Next() {
+			if err = x.ReadStruct(readItem); err != nil {
+				b.Fatalf("reading item failed: %v", err)
+			}
+		}
+		x.Close()
+	}
+}
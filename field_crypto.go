@@ -0,0 +1,268 @@
+package bst
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// EncryptionMode is the mode of the field-level encryption.
+type EncryptionMode uint8
+
+// Enumerated encryption modes. The values are a part of the encrypted value binary and never change.
+const (
+	// EncryptRandomized encrypts the value with a random nonce, thus the equal values have different ciphertexts.
+	EncryptRandomized EncryptionMode = 1
+	// EncryptDeterministic encrypts the value with a nonce derived from the value, thus the equal values of the same
+	// field and key have equal ciphertexts. It reveals the equality of the values, which lets the encrypted fields
+	// be searched or indexed by equality, also in the comparable format.
+	EncryptDeterministic EncryptionMode = 2
+)
+
+// Field encryption constants.
+const (
+	// FieldEncryptionKeySize is the size of the field encryption keys.
+	FieldEncryptionKeySize = 32
+
+	fieldCipherHeaderSize = 5
+	fieldCipherNonceSize  = 12
+)
+
+// KeyProvider provides the keys of the field-level encryption. The keys are identified by the IDs, which are
+// recorded in each encrypted value, so that the keys could be rotated without re-encrypting the stored values.
+type KeyProvider interface {
+	// EncryptionKey returns the ID and the key used to encrypt the values of the struct field with given name.
+	EncryptionKey(field string) (keyID uint32, key []byte, err error)
+	// DecryptionKey returns the key of given ID.
+	DecryptionKey(keyID uint32) ([]byte, error)
+}
+
+// Compile-time check to ensure that StaticKeyProvider implements the KeyProvider interface.
+var _ KeyProvider = StaticKeyProvider{}
+
+// StaticKeyProvider is the KeyProvider of the fixed set of keys, which encrypts all the fields with the current key.
+type StaticKeyProvider struct {
+	// CurrentID is the ID of the key used to encrypt the values.
+	CurrentID uint32
+	// Keys are the keys by their IDs, including the rotated ones, still used to decrypt the values.
+	Keys map[uint32][]byte
+}
+
+// EncryptionKey returns the current key.
+// Implements the KeyProvider interface.
+func (x StaticKeyProvider) EncryptionKey(string) (uint32, []byte, error) {
+	key, err := x.DecryptionKey(x.CurrentID)
+	return x.CurrentID, key, err
+}
+
+// DecryptionKey returns the key of given ID.
+// Implements the KeyProvider interface.
+func (x StaticKeyProvider) DecryptionKey(keyID uint32) ([]byte, error) {
+	key, ok := x.Keys[keyID]
+	if !ok {
+		return nil, bsterr.Err(bsterr.CodeUndefinedValue, "encryption key not found").WithDetail("keyID", keyID)
+	}
+	return key, nil
+}
+
+// FieldEncryption defines the encrypted String and Bytes struct fields, by the field names. The encryption applies
+// to all the struct fields (also nested) with given name. The encrypted value is written in place of the plain one,
+// thus the binary layout of the field stays the same, and the field could still be skipped.
+// The encrypted value is composed of:
+//   - Encryption mode (1 byte).
+//   - Key ID (uint32).
+//   - Nonce (12 bytes).
+//   - AES-256-GCM sealed value, authenticated along with the mode, key ID and the field name.
+type FieldEncryption struct {
+	// Fields are the encryption modes of the fields, by the field names.
+	Fields map[string]EncryptionMode
+	// Keys provides the keys of the encrypted fields.
+	Keys KeyProvider
+}
+
+// modeOf returns the encryption mode of the field with given name.
+func (x *FieldEncryption) modeOf(field string) (EncryptionMode, bool) {
+	if x == nil || len(x.Fields) == 0 {
+		return 0, false
+	}
+	mode, ok := x.Fields[field]
+	return mode, ok
+}
+
+// seal encrypts the value of the field.
+func (x *FieldEncryption) seal(field string, mode EncryptionMode, v []byte) ([]byte, error) {
+	// 1. Get the encryption key of the field.
+	if x.Keys == nil {
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "key provider is not defined for the encrypted field").
+			WithDetail("field", field)
+	}
+	keyID, key, err := x.Keys.EncryptionKey(field)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to get field encryption key").
+			WithDetail("field", field)
+	}
+	aead, macKey, err := newFieldCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Write the header and the nonce, which is either random or derived from the field name and its value.
+	out := make([]byte, fieldCipherHeaderSize+fieldCipherNonceSize, fieldCipherHeaderSize+fieldCipherNonceSize+len(v)+aead.Overhead())
+	out[0] = byte(mode)
+	binary.BigEndian.PutUint32(out[1:fieldCipherHeaderSize], keyID)
+	nonce := out[fieldCipherHeaderSize:]
+	switch mode {
+	case EncryptRandomized:
+		if _, err = rand.Read(nonce); err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to generate nonce")
+		}
+	case EncryptDeterministic:
+		mac := hmac.New(sha256.New, macKey)
+		mac.Write([]byte(field))
+		mac.Write([]byte{0})
+		mac.Write(v)
+		copy(nonce, mac.Sum(nil))
+	default:
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "unknown field encryption mode").
+			WithDetails(bsterr.D("field", field), bsterr.D("mode", uint8(mode)))
+	}
+
+	// 3. Seal the value.
+	return aead.Seal(out, nonce, v, fieldCipherAdditionalData(out[:fieldCipherHeaderSize], field)), nil
+}
+
+// open decrypts the value of the field.
+func (x *FieldEncryption) open(field string, v []byte) ([]byte, error) {
+	// 1. Parse the header.
+	if len(v) < fieldCipherHeaderSize+fieldCipherNonceSize {
+		return nil, bsterr.Err(bsterr.CodeMalformedBinary, "encrypted field value is too short").
+			WithDetails(bsterr.D("field", field), bsterr.D("size", len(v)))
+	}
+	mode := EncryptionMode(v[0])
+	if mode != EncryptRandomized && mode != EncryptDeterministic {
+		return nil, bsterr.Err(bsterr.CodeMalformedBinary, "unknown field encryption mode").
+			WithDetails(bsterr.D("field", field), bsterr.D("mode", uint8(mode)))
+	}
+	keyID := binary.BigEndian.Uint32(v[1:fieldCipherHeaderSize])
+
+	// 2. Get the decryption key of the recorded ID.
+	if x.Keys == nil {
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "key provider is not defined for the encrypted field").
+			WithDetail("field", field)
+	}
+	key, err := x.Keys.DecryptionKey(keyID)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to get field decryption key").
+			WithDetails(bsterr.D("field", field), bsterr.D("keyID", keyID))
+	}
+	aead, _, err := newFieldCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Open the sealed value.
+	nonce := v[fieldCipherHeaderSize : fieldCipherHeaderSize+fieldCipherNonceSize]
+	pv, err := aead.Open(nil, nonce, v[fieldCipherHeaderSize+fieldCipherNonceSize:], fieldCipherAdditionalData(v[:fieldCipherHeaderSize], field))
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to decrypt field value").
+			WithDetails(bsterr.D("field", field), bsterr.D("keyID", keyID))
+	}
+	return pv, nil
+}
+
+// newFieldCipher creates the AES-GCM cipher and the nonce derivation key, both derived from the field encryption key.
+func newFieldCipher(key []byte) (cipher.AEAD, []byte, error) {
+	if len(key) != FieldEncryptionKeySize {
+		return nil, nil, bsterr.Err(bsterr.CodeInvalidValue, "invalid field encryption key size").
+			WithDetail("size", len(key))
+	}
+	derive := func(label string) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(label))
+		return mac.Sum(nil)
+	}
+	block, err := aes.NewCipher(derive("bst field encryption"))
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, derive("bst field nonce"), nil
+}
+
+// fieldCipherAdditionalData returns the authenticated data of the encrypted value, so that neither its header
+// could be changed, nor the value could be moved to another field.
+func fieldCipherAdditionalData(header []byte, field string) []byte {
+	return append(append(make([]byte, 0, len(header)+len(field)), header...), field...)
+}
+
+// encryptField encrypts the value of the struct field, whose encryption is defined in the Encryption option.
+// It returns false if the field is not encrypted.
+func (x *Composer) encryptField(v []byte) ([]byte, bool, error) {
+	// 1. Check if the current struct field is encrypted.
+	if x.opts.Encryption == nil {
+		return nil, false, nil
+	}
+	name, ok := x.structFieldName()
+	if !ok {
+		return nil, false, nil
+	}
+	mode, ok := x.opts.Encryption.modeOf(name)
+	if !ok {
+		return nil, false, nil
+	}
+
+	// 2. The fixed size bytes could not hold the encrypted value, whereas the randomized one would break
+	//    the equality of the comparable binaries.
+	if bt, ok := x.elemType.(*bsttype.Bytes); ok && bt.HasFixedSize() {
+		return nil, false, bsterr.Err(bsterr.CodeInvalidType, "fixed size bytes field could not be encrypted").
+			WithDetail("field", name)
+	}
+	if x.opts.Comparable && mode != EncryptDeterministic {
+		return nil, false, bsterr.Err(bsterr.CodeInvalidValue, "comparable field encryption needs to be deterministic").
+			WithDetail("field", name)
+	}
+
+	// 3. Seal the value.
+	ev, err := x.opts.Encryption.seal(name, mode, v)
+	if err != nil {
+		return nil, false, err
+	}
+	return ev, true, nil
+}
+
+// decryptField decrypts the value of the struct field, whose encryption is defined in the Encryption option.
+// The value of the field which is not encrypted is returned as is.
+func (x *Extractor) decryptField(v []byte) ([]byte, error) {
+	if x.opts.Encryption == nil {
+		return v, nil
+	}
+	name, ok := x.structFieldName()
+	if !ok {
+		return v, nil
+	}
+	if _, ok = x.opts.Encryption.modeOf(name); !ok {
+		return v, nil
+	}
+	return x.opts.Encryption.open(name, v)
+}
+
+// structFieldName returns the name of the current struct field, preferably of the expected type.
+func (x *Extractor) structFieldName() (string, bool) {
+	st, ok := x.opts.ExpectedType.(*bsttype.Struct)
+	if !ok {
+		st, ok = x.embedType.(*bsttype.Struct)
+	}
+	if !ok || x.index < 0 || x.index >= len(st.Fields) {
+		return "", false
+	}
+	return st.Fields[x.index].Name, true
+}
@@ -0,0 +1,117 @@
+package bst
+
+import (
+	"bytes"
+	"testing"
+)
+
+type cryptoPerson struct {
+	ID   uint64 `bst:"id"`
+	SSN  string `bst:"ssn"`
+	Note []byte `bst:"note"`
+}
+
+func TestFieldEncryption(t *testing.T) {
+	keys := StaticKeyProvider{
+		CurrentID: 1,
+		Keys:      map[uint32][]byte{1: bytes.Repeat([]byte{0x01}, FieldEncryptionKeySize)},
+	}
+	encryption := &FieldEncryption{
+		Fields: map[string]EncryptionMode{"ssn": EncryptDeterministic, "note": EncryptRandomized},
+		Keys:   keys,
+	}
+	in := cryptoPerson{ID: 7, SSN: "123-45-6789", Note: []byte("confidential note")}
+
+	data, err := Marshal(in, WithFieldEncryption(encryption))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if bytes.Contains(data, []byte(in.SSN)) || bytes.Contains(data, in.Note) {
+		t.Fatal("encrypted field values are written in plain")
+	}
+
+	var out cryptoPerson
+	if err = Unmarshal(data, &out, WithFieldEncryption(encryption)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if out.ID != in.ID || out.SSN != in.SSN || !bytes.Equal(out.Note, in.Note) {
+		t.Fatalf("unexpected decrypted value: %+v", out)
+	}
+
+	// The randomized field differs with each marshal.
+	again, err := Marshal(in, WithFieldEncryption(encryption))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if bytes.Equal(data, again) {
+		t.Fatal("randomized encryption produced equal binaries")
+	}
+
+	t.Run("Searchable", func(t *testing.T) {
+		deterministic := &FieldEncryption{Fields: map[string]EncryptionMode{"ssn": EncryptDeterministic}, Keys: keys}
+		key := func(v cryptoPerson) []byte {
+			data, err := Marshal(v, ForIndexKey(), WithFieldEncryption(deterministic))
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+			return data
+		}
+		a, b := key(cryptoPerson{SSN: "123"}), key(cryptoPerson{SSN: "123"})
+		if !bytes.Equal(a, b) {
+			t.Fatal("deterministic encryption produced different keys for equal values")
+		}
+		if bytes.Equal(a, key(cryptoPerson{SSN: "124"})) {
+			t.Fatal("deterministic encryption produced equal keys for different values")
+		}
+
+		var out cryptoPerson
+		if err := Unmarshal(a, &out, WithFieldEncryption(deterministic)); err != nil || out.SSN != "123" {
+			t.Fatalf("unexpected decrypted key: %+v, err: %v", out, err)
+		}
+
+		// The randomized encryption breaks the equality of the comparable binaries.
+		if _, err := Marshal(in, ForIndexKey(), WithFieldEncryption(encryption)); err == nil {
+			t.Fatal("expected randomized encryption in the comparable format to fail")
+		}
+	})
+
+	t.Run("KeyRotation", func(t *testing.T) {
+		rotated := &FieldEncryption{
+			Fields: encryption.Fields,
+			Keys: StaticKeyProvider{
+				CurrentID: 2,
+				Keys: map[uint32][]byte{
+					1: keys.Keys[1],
+					2: bytes.Repeat([]byte{0x02}, FieldEncryptionKeySize),
+				},
+			},
+		}
+		var out cryptoPerson
+		if err := Unmarshal(data, &out, WithFieldEncryption(rotated)); err != nil || out.SSN != in.SSN {
+			t.Fatalf("decrypting with the rotated key provider failed: %+v, err: %v", out, err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var out cryptoPerson
+		if err := Unmarshal(data, &out); err != nil || out.SSN == in.SSN {
+			t.Fatalf("expected encrypted value without the encryption option, got: %+v, err: %v", out, err)
+		}
+
+		unknownKey := &FieldEncryption{Fields: encryption.Fields, Keys: StaticKeyProvider{}}
+		if err := Unmarshal(data, &out, WithFieldEncryption(unknownKey)); err == nil {
+			t.Fatal("expected decrypting with unknown key to fail")
+		}
+
+		tampered := append([]byte(nil), data...)
+		tampered[len(tampered)-1] ^= 0xff
+		if err := Unmarshal(tampered, &out, WithFieldEncryption(encryption)); err == nil {
+			t.Fatal("expected decrypting tampered value to fail")
+		}
+
+		short := &FieldEncryption{Fields: encryption.Fields, Keys: StaticKeyProvider{Keys: map[uint32][]byte{0: {0x01}}}}
+		if _, err := Marshal(in, WithFieldEncryption(short)); err == nil {
+			t.Fatal("expected encrypting with invalid key size to fail")
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package bst
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+// fieldDefault is the state of the struct extractor replaced while the default value of the expected field,
+// missing in the compatibility mode binary, is read.
+type fieldDefault struct {
+	r                          io.ReadSeeker
+	bytesRead                  int
+	boolBuf                    byte
+	boolBufPosition            int
+	comparable, lengthPrefixed bool
+}
+
+// enterFieldDefault sets up the current expected struct field, missing in the compatibility mode binary,
+// to be read from its default value. The default is encoded in the regular, ascending mode, thus the extractor
+// reads it as such, until the leaveFieldDefault restores its state. It returns false if the field has no default.
+func (x *Extractor) enterFieldDefault() bool {
+	// 1. Find the default of the current expected field.
+	xt, ok := x.opts.ExpectedType.(*bsttype.Struct)
+	if !ok || x.index < 0 || x.index > x.maxIndex {
+		return false
+	}
+	f := xt.Fields[x.index]
+	if len(f.Default) == 0 {
+		return false
+	}
+	x.elemType, x.err = x.derefType(f.Type)
+	if x.err != nil {
+		return false
+	}
+
+	// 2. Keep the state replaced by the default value reader.
+	x.fieldDefault = &fieldDefault{
+		r:               x.r,
+		bytesRead:       x.bytesRead,
+		boolBuf:         x.boolBuf,
+		boolBufPosition: x.boolBufPosition,
+		comparable:      x.opts.Comparable,
+		lengthPrefixed:  x.opts.LengthPrefixedCollections,
+	}
+
+	// 3. Read the default value as the plain, regular mode binary.
+	x.r = bytes.NewReader(f.Default)
+	x.boolBuf, x.boolBufPosition = 0, 0
+	x.opts.CompatibilityMode, x.opts.Comparable, x.opts.LengthPrefixedCollections = false, false, false
+	x.embed.elemType = x.elemType
+	x.embed.encoding = bsttype.FieldEncodingPlain
+	x.elemDesc = false
+	x.elemDone = false
+	return true
+}
+
+// leaveFieldDefault restores the state of the struct extractor, once the default value of the field was read.
+func (x *Extractor) leaveFieldDefault() {
+	d := x.fieldDefault
+	if d == nil {
+		return
+	}
+	x.r, x.bytesRead = d.r, d.bytesRead
+	x.boolBuf, x.boolBufPosition = d.boolBuf, d.boolBufPosition
+	x.opts.CompatibilityMode, x.opts.Comparable, x.opts.LengthPrefixedCollections = true, d.comparable, d.lengthPrefixed
+	x.fieldDefault = nil
+}
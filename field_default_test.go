@@ -0,0 +1,86 @@
+package bst
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestExtractorFieldDefault(t *testing.T) {
+	old := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.String()},
+		{Index: 3, Name: "Note", Type: bsttype.String()},
+	}}
+	// The new schema adds the fields with defaults, in between and after the old ones.
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.String()},
+		{Index: 2, Name: "Tags", Type: bsttype.ArrayOf(bsttype.String()), Descending: true},
+		{Index: 3, Name: "Note", Type: bsttype.String()},
+		{Index: 4, Name: "Active", Type: bsttype.Boolean()},
+		{Index: 5, Name: "Level", Type: bsttype.Int32()},
+	}}
+	defaults := []bstvalue.Value{
+		bstvalue.MustArrayValueOf(bsttype.ArrayOf(bsttype.String()), []bstvalue.Value{
+			bstvalue.NewStringValue("a"), bstvalue.NewStringValue("b"),
+		}),
+		bstvalue.NewBoolValue(true),
+		bstvalue.NewInt32Value(-5),
+	}
+	for i, pos := range []int{1, 3, 4} {
+		if err := bstvalue.SetFieldDefault(&st.Fields[pos], defaults[i]); err != nil {
+			t.Fatalf("setting field default failed: %v", err)
+		}
+	}
+	want := map[string]any{
+		"Name":   "John",
+		"Tags":   []any{"a", "b"},
+		"Note":   "note",
+		"Active": true,
+		"Level":  int64(-5),
+	}
+
+	for _, opts := range []ComposerOptions{
+		{CompatibilityMode: true},
+		{CompatibilityMode: true, Descending: true},
+		{CompatibilityMode: true, Comparable: true},
+		{CompatibilityMode: true, EmbedType: true},
+	} {
+		// 1. Compose the value of the old schema.
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, old, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteString("John"); err != nil {
+			t.Fatalf("writing field failed: %v", err)
+		}
+		if err = c.WriteString("note"); err != nil {
+			t.Fatalf("writing field failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+
+		// 2. The fields missing in the binary are read as their defaults.
+		x, err := NewExtractor(bytes.NewReader(buf.Bytes()), ExtractorOptions{
+			ExpectedType:      st,
+			CompatibilityMode: true,
+			Descending:        opts.Descending,
+			Comparable:        opts.Comparable,
+		})
+		if err != nil {
+			t.Fatalf("creating extractor failed: %v", err)
+		}
+		got, err := x.dynamicStruct(st)
+		x.Close()
+		if err != nil {
+			t.Fatalf("reading value failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("unexpected value: %v, wanted: %v", got, want)
+		}
+	}
+}
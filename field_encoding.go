@@ -0,0 +1,87 @@
+package bst
+
+import (
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+type integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// deltaEncode returns the difference of the value from the previous one, and stores the value as the previous.
+// The difference wraps around in the integer width, thus it is always reversible.
+func deltaEncode[T integer](prev *uint64, v T) T {
+	d := v - T(*prev)
+	*prev = uint64(v)
+	return d
+}
+
+// deltaDecode reverts the deltaEncode of the value.
+func deltaDecode[T integer](prev *uint64, d T) T {
+	v := T(*prev) + d
+	*prev = uint64(v)
+	return v
+}
+
+// fieldEncoding returns the encoding of the current struct field.
+// The comparable binaries ignore the field encodings, as they would break the order of the values.
+func (x *Composer) fieldEncoding() bsttype.FieldEncoding {
+	st, ok := x.baseType.(*bsttype.Struct)
+	if !ok || x.opts.Comparable || x.index > x.maxIndex {
+		return bsttype.FieldEncodingPlain
+	}
+	return st.Fields[x.index].Encoding
+}
+
+// verifyFieldEncodings checks if the struct fields could be encoded with their field encodings.
+func (x *Composer) verifyFieldEncodings(st *bsttype.Struct) error {
+	if x.opts.Comparable {
+		return nil
+	}
+	for _, f := range st.Fields {
+		if err := f.VerifyEncoding(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldEncoding returns the encoding of the current struct field, as it was written in the binary.
+func (x *Extractor) fieldEncoding() bsttype.FieldEncoding {
+	if x.opts.Comparable || x.embedType == nil || x.embedType.Kind() != bsttype.KindStruct {
+		return bsttype.FieldEncodingPlain
+	}
+	return x.embed.encoding
+}
+
+// readDeltaElem reads the delta encoded array element, so that the following elements could be decoded.
+func (x *Extractor) readDeltaElem() error {
+	var err error
+	switch x.elemType.Kind() {
+	case bsttype.KindInt8:
+		_, err = x.ReadInt8()
+	case bsttype.KindInt16:
+		_, err = x.ReadInt16()
+	case bsttype.KindInt32:
+		_, err = x.ReadInt32()
+	case bsttype.KindInt64:
+		_, err = x.ReadInt64()
+	case bsttype.KindInt:
+		_, err = x.ReadInt()
+	case bsttype.KindUint8:
+		_, err = x.ReadUint8()
+	case bsttype.KindUint16:
+		_, err = x.ReadUint16()
+	case bsttype.KindUint32:
+		_, err = x.ReadUint32()
+	case bsttype.KindUint64:
+		_, err = x.ReadUint64()
+	case bsttype.KindUint:
+		_, err = x.ReadUint()
+	default:
+		err = bsterr.Err(bsterr.CodeInvalidType, "delta encoded array element is not an integer").
+			WithDetail("kind", x.elemType.Kind())
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package bst
+
+import (
+	"strings"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// fieldMask is the tree of the struct field names selected by the FieldMask option, by their nesting.
+// The nil mask selects all the fields, and so does the nil mask of the selected field for its nested struct.
+type fieldMask map[string]fieldMask
+
+// newFieldMask compiles the dotted field paths into the field mask tree. The path selecting a field
+// selects all of its nested fields as well.
+func newFieldMask(paths []string) (fieldMask, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	m := fieldMask{}
+	for _, p := range paths {
+		names := strings.Split(p, ".")
+		cur := m
+		for i, name := range names {
+			if name == "" {
+				return nil, bsterr.Err(bsterr.CodeInvalidValue, "empty field mask path segment").WithDetail("path", p)
+			}
+			// 1. The field selected as a whole remains selected, regardless of its nested paths.
+			sub, ok := cur[name]
+			if ok && sub == nil {
+				break
+			}
+			if i == len(names)-1 {
+				cur[name] = nil
+				break
+			}
+			if !ok {
+				sub = fieldMask{}
+				cur[name] = sub
+			}
+			cur = sub
+		}
+	}
+	return m, nil
+}
+
+// selects checks if the field of given name is selected by the mask.
+func (x fieldMask) selects(name string) bool {
+	if x == nil {
+		return true
+	}
+	_, ok := x[name]
+	return ok
+}
+
+// nested returns the mask of the nested struct of the selected field.
+func (x fieldMask) nested(name string) fieldMask {
+	if x == nil {
+		return nil
+	}
+	return x[name]
+}
+
+// nextMaskedStructElem advances the struct extractor to the next field selected by the field mask,
+// skipping all the others.
+func (x *Extractor) nextMaskedStructElem() bool {
+	for x.nextStructElem() {
+		name, ok := x.structFieldName()
+		if !ok || x.mask.selects(name) {
+			return true
+		}
+		// The booleans are read rather than skipped, so that the buffer of the packed ones is kept.
+		if x.elemType.Kind() == bsttype.KindBoolean {
+			_, x.err = x.ReadBoolean()
+		} else {
+			_, x.err = x.Skip()
+		}
+		if x.err != nil {
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package bst
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestExtractorFieldMask(t *testing.T) {
+	address := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Street", Type: bsttype.String()},
+		{Index: 2, Name: "City", Type: bsttype.String()},
+	}}
+	item := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "ID", Type: bsttype.Uint32()},
+		{Index: 2, Name: "Tag", Type: bsttype.String()},
+	}}
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "ID", Type: bsttype.Uint32()},
+		{Index: 2, Name: "Active", Type: bsttype.Boolean()},
+		{Index: 3, Name: "Admin", Type: bsttype.Boolean()},
+		{Index: 4, Name: "Address", Type: address},
+		{Index: 5, Name: "Items", Type: bsttype.ArrayOf(item)},
+		{Index: 6, Name: "Home", Type: address},
+	}}
+
+	writeAddress := func(street, city string) func(c *Composer) error {
+		return func(c *Composer) error {
+			if err := c.WriteString(street); err != nil {
+				return err
+			}
+			return c.WriteString(city)
+		}
+	}
+	compose := func(t *testing.T, opts ComposerOptions) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		for _, write := range []func() error{
+			func() error { return c.WriteUint32(7) },
+			func() error { return c.WriteBoolean(true) },
+			func() error { return c.WriteBoolean(false) },
+			func() error { return c.WriteStruct(writeAddress("Main", "Warsaw")) },
+			func() error {
+				return c.WriteArray(func(c *Composer) error {
+					for i, tag := range []string{"a", "b"} {
+						if err := c.WriteStruct(func(c *Composer) error {
+							if err := c.WriteUint32(uint32(i)); err != nil {
+								return err
+							}
+							return c.WriteString(tag)
+						}); err != nil {
+							return err
+						}
+					}
+					return nil
+				}, 2)
+			},
+			func() error { return c.WriteStruct(writeAddress("Side", "Krakow")) },
+		} {
+			if err = write(); err != nil {
+				t.Fatalf("writing field failed: %v", err)
+			}
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name string
+		mask []string
+		want map[string]any
+	}{
+		{
+			name: "Nested",
+			mask: []string{"Admin", "Address.City", "Items.Tag", "Home"},
+			want: map[string]any{
+				"Admin":   false,
+				"Address": map[string]any{"City": "Warsaw"},
+				"Items":   []any{map[string]any{"Tag": "a"}, map[string]any{"Tag": "b"}},
+				"Home":    map[string]any{"Street": "Side", "City": "Krakow"},
+			},
+		},
+		{
+			name: "WholeField",
+			mask: []string{"Address", "Address.City", "ID"},
+			want: map[string]any{
+				"ID":      uint64(7),
+				"Address": map[string]any{"Street": "Main", "City": "Warsaw"},
+			},
+		},
+		{
+			name: "Unknown",
+			mask: []string{"Unknown"},
+			want: map[string]any{},
+		},
+	}
+
+	for _, opts := range []ComposerOptions{{}, {Descending: true}} {
+		data := compose(t, opts)
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: st, FieldMask: tc.mask})
+				if err != nil {
+					t.Fatalf("creating extractor failed: %v", err)
+				}
+				defer x.Close()
+				got, err := x.dynamicStruct(st)
+				if err != nil {
+					t.Fatalf("reading value failed: %v", err)
+				}
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Fatalf("unexpected masked value: %v, wanted: %v", got, tc.want)
+				}
+			})
+		}
+	}
+
+	// The empty path segments are invalid.
+	if _, err := NewExtractor(bytes.NewReader(compose(t, ComposerOptions{})), ExtractorOptions{
+		ExpectedType: st,
+		FieldMask:    []string{"Address..City"},
+	}); err == nil {
+		t.Fatal("expected invalid field mask error")
+	}
+}
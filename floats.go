@@ -33,7 +33,11 @@ func (x *Composer) WriteFloat32(v float32) error {
 	}
 
 	// 4. Write the value.
-	n, err := bstio.WriteFloat32(x.w, v, x.elemDesc)
+	write := bstio.WriteFloat32
+	if x.opts.OrderedFloats {
+		write = bstio.WriteOrderedFloat32
+	}
+	n, err := write(x.w, v, x.elemDesc)
 	if err != nil {
 		return err
 	}
@@ -74,7 +78,11 @@ func (x *Composer) WriteFloat64(v float64) error {
 	}
 
 	// 4. Write the value.
-	n, err := bstio.WriteFloat64(x.w, v, x.elemDesc)
+	write := bstio.WriteFloat64
+	if x.opts.OrderedFloats {
+		write = bstio.WriteOrderedFloat64
+	}
+	n, err := write(x.w, v, x.elemDesc)
 	if err != nil {
 		return err
 	}
@@ -107,11 +115,18 @@ func (x *Extractor) ReadFloat32() (float32, error) {
 	}
 
 	// 3. Read the float32 value.
-	v, n, err := bstio.ReadFloat32(x.r, x.elemDesc)
+	read := bstio.ReadFloat32
+	if x.opts.OrderedFloats {
+		read = bstio.ReadOrderedFloat32
+	}
+	v, n, err := read(x.r, x.elemDesc)
 	x.bytesRead += n
 	if err != nil {
 		return 0, err
 	}
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
@@ -127,6 +142,12 @@ func (x *Extractor) ReadFloat64() (float64, error) {
 	}
 
 	// 2. Verify if current element matches the expected type.
+	//    The narrower float of the compatibility mode is read, widened losslessly.
+	if x.coercible(bsttype.KindFloat64) {
+		x.elemType = x.embeddedElemType()
+		v, err := x.ReadFloat32()
+		return float64(v), err
+	}
 	if x.elemType.Kind() != bsttype.KindFloat64 {
 		return 0, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
 			WithDetails(
@@ -136,11 +157,18 @@ func (x *Extractor) ReadFloat64() (float64, error) {
 	}
 
 	// 3. Read the float64 value.
-	v, n, err := bstio.ReadFloat64(x.r, x.elemDesc)
+	read := bstio.ReadFloat64
+	if x.opts.OrderedFloats {
+		read = bstio.ReadOrderedFloat64
+	}
+	v, n, err := read(x.r, x.elemDesc)
 	x.bytesRead += n
 	if err != nil {
 		return 0, err
 	}
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
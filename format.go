@@ -0,0 +1,91 @@
+package bst
+
+import (
+	"sync"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// FormatFunc renders the value of the named type as the text. The value is given as read by the
+// Extractor.ReadDynamic, i.e. the []byte of the fixed size Bytes.
+type FormatFunc func(v any) (string, error)
+
+// Formats is the registry of the custom text renderings of the named types, used by the inspection tools,
+// i.e. the bstjson.ToJSON, so that the operators see the values as the application does, i.e. the 16 byte field
+// as the UUID text. The renderings never change the value binaries. It is safe for concurrent use.
+type Formats struct {
+	mu    sync.RWMutex
+	funcs map[formatKey]FormatFunc
+}
+
+// formatKey identifies the named type of the registered format.
+type formatKey struct {
+	module, name string
+}
+
+// DefaultFormats is the registry of the formats used by the inspection tools by default.
+// It is empty, unless the application registers its formats.
+var DefaultFormats = NewFormats()
+
+// NewFormats creates a new empty formats registry.
+func NewFormats() *Formats {
+	return &Formats{funcs: make(map[formatKey]FormatFunc)}
+}
+
+// Register sets the format of the named type of given module and name, replacing the previous one.
+// The nil fn removes the format.
+func (x *Formats) Register(module, name string, fn FormatFunc) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if fn == nil {
+		delete(x.funcs, formatKey{module: module, name: name})
+		return
+	}
+	x.funcs[formatKey{module: module, name: name}] = fn
+}
+
+// Lookup returns the format of the type, if it is the named type with the registered format.
+// The named types wrapping the other named types are looked up outermost first.
+func (x *Formats) Lookup(t bsttype.Type) (FormatFunc, bool) {
+	if x == nil {
+		return nil, false
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if len(x.funcs) == 0 {
+		return nil, false
+	}
+	for {
+		nt, ok := t.(*bsttype.Named)
+		if !ok {
+			return nil, false
+		}
+		if fn, ok := x.funcs[formatKey{module: nt.Module, name: nt.Name}]; ok {
+			return fn, true
+		}
+		t = nt.Type
+	}
+}
+
+// ReadFormatted reads the current element of the named type t, and renders it with its format.
+// It returns false without reading the element, if there is no format of the type.
+func (x *Extractor) ReadFormatted(formats *Formats, t bsttype.Type) (string, bool, error) {
+	// 1. Find the format of the type.
+	fn, ok := formats.Lookup(t)
+	if !ok {
+		return "", false, nil
+	}
+
+	// 2. Read the value and render it.
+	v, err := x.ReadDynamic()
+	if err != nil {
+		return "", true, err
+	}
+	s, err := fn(v)
+	if err != nil {
+		return "", true, bsterr.ErrWrap(err, bsterr.CodeInvalidValue, "failed to format value").
+			WithDetail("type", t)
+	}
+	return s, true, nil
+}
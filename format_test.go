@@ -0,0 +1,97 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestFormats(t *testing.T) {
+	inner := &bsttype.Named{Module: "app", Name: "Cents", Type: bsttype.Int64()}
+	outer := &bsttype.Named{Module: "app", Name: "Price", Type: inner}
+	// The value is of the plain struct, whose fields are formatted as of the named types.
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "price", Type: bsttype.Int64()},
+		{Index: 2, Name: "count", Type: bsttype.Int64()},
+	}}
+	fieldTypes := []bsttype.Type{outer, bsttype.Int64()}
+	var buf bytes.Buffer
+	c, err := NewComposer(&buf, st, ComposerOptions{})
+	if err != nil {
+		t.Fatalf("creating composer failed: %v", err)
+	}
+	for _, v := range []int64{1250, 3} {
+		if err = c.WriteInt64(v); err != nil {
+			t.Fatalf("writing int failed: %v", err)
+		}
+	}
+	if err = c.Close(); err != nil {
+		t.Fatalf("closing composer failed: %v", err)
+	}
+
+	// read formats the fields of the struct, or returns the unformatted marker.
+	read := func(t *testing.T, formats *Formats) []string {
+		x, err := NewExtractor(bytes.NewReader(buf.Bytes()), ExtractorOptions{ExpectedType: st})
+		if err != nil {
+			t.Fatalf("creating extractor failed: %v", err)
+		}
+		defer x.Close()
+		var res []string
+		for x.Next() {
+			s, ok, err := x.ReadFormatted(formats, fieldTypes[x.Index()])
+			if err != nil {
+				t.Fatalf("reading formatted failed: %v", err)
+			}
+			if !ok {
+				s = "-"
+				if _, err = x.Skip(); err != nil {
+					t.Fatalf("skipping failed: %v", err)
+				}
+			}
+			res = append(res, s)
+		}
+		return res
+	}
+
+	formats := NewFormats()
+	formats.Register("app", "Cents", func(v any) (string, error) {
+		return strconv.FormatFloat(float64(v.(int64))/100, 'f', 2, 64), nil
+	})
+	if res := read(t, formats); res[0] != "12.50" || res[1] != "-" {
+		t.Fatalf("unexpected inner format: %v", res)
+	}
+
+	// The outermost named type format takes precedence.
+	formats.Register("app", "Price", func(v any) (string, error) {
+		return "$" + strconv.FormatInt(v.(int64), 10), nil
+	})
+	if res := read(t, formats); res[0] != "$1250" {
+		t.Fatalf("unexpected outer format: %v", res)
+	}
+
+	// The removed formats, and nil registry, leave the values unformatted.
+	formats.Register("app", "Price", nil)
+	formats.Register("app", "Cents", nil)
+	if res := read(t, formats); res[0] != "-" {
+		t.Fatalf("unexpected removed format: %v", res)
+	}
+	if res := read(t, nil); res[0] != "-" {
+		t.Fatalf("unexpected nil registry format: %v", res)
+	}
+
+	// The format errors are returned.
+	errFormat := errors.New("format failed")
+	formats.Register("app", "Price", func(any) (string, error) { return "", errFormat })
+	x, err := NewExtractor(bytes.NewReader(buf.Bytes()), ExtractorOptions{ExpectedType: st})
+	if err != nil {
+		t.Fatalf("creating extractor failed: %v", err)
+	}
+	defer x.Close()
+	x.Next()
+	if _, _, err = x.ReadFormatted(formats, outer); !errors.Is(err, errFormat) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
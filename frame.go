@@ -0,0 +1,87 @@
+package bst
+
+import (
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// frameReader is the root reader of the extractor with the FrameSize option. It bounds the reads and seeks
+// to the frame of the value, i.e. the WAL record, so that the malformed value never consumes the next one.
+// The reads never go beyond the frame end, and the ones starting at the frame end fail.
+type frameReader struct {
+	io.ReadSeeker
+	start, size, pos int64
+}
+
+// Read reads from the underlying reader, at most up to the frame end.
+// Implements io.Reader interface.
+func (x *frameReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	remaining := x.size - x.pos
+	if remaining <= 0 {
+		return 0, x.overrun(int64(len(p)))
+	}
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := x.ReadSeeker.Read(p)
+	x.pos += int64(n)
+	return n, err
+}
+
+// Seek sets the offset for the next read, which needs to be within the frame.
+// The io.SeekEnd is relative to the frame end.
+// Implements io.Seeker interface.
+func (x *frameReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset - x.start
+	case io.SeekCurrent:
+		pos = x.pos + offset
+	case io.SeekEnd:
+		pos = x.size + offset
+	default:
+		return 0, bsterr.Err(bsterr.CodeReadingFailed, "invalid seek whence").WithDetail("whence", whence)
+	}
+	if pos > x.size {
+		return 0, x.overrun(pos - x.pos)
+	}
+	if pos < 0 {
+		return 0, bsterr.Err(bsterr.CodeOutOfBounds, "seek before the frame start").WithDetail("offset", pos)
+	}
+	if _, err := x.ReadSeeker.Seek(x.start+pos, io.SeekStart); err != nil {
+		return 0, err
+	}
+	x.pos = pos
+	return x.start + pos, nil
+}
+
+// RemainingSize returns the number of the remaining bytes of the frame.
+// Implements bstio.RemainingSizer interface.
+func (x *frameReader) RemainingSize() (int64, bool) {
+	return x.size - x.pos, true
+}
+
+// overrun returns the error of the n bytes read beyond the frame end.
+func (x *frameReader) overrun(n int64) error {
+	return bsterr.Err(bsterr.CodeFrameOverrun, "value overruns frame").
+		WithDetails(bsterr.D("frameSize", x.size), bsterr.D("offset", x.pos), bsterr.D("length", n))
+}
+
+// rootReader returns the reader the extractor was created on, unwrapped from its frame and budget readers.
+func rootReader(r io.ReadSeeker) io.ReadSeeker {
+	for {
+		switch rt := r.(type) {
+		case *budgetReader:
+			r = rt.ReadSeeker
+		case *frameReader:
+			r = rt.ReadSeeker
+		default:
+			return r
+		}
+	}
+}
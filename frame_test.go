@@ -0,0 +1,112 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestFrameSize(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "name", Type: bsttype.String()},
+		{Index: 2, Name: "count", Type: bsttype.Uint32()},
+	}}
+	// record composes the value of the struct type.
+	record := func(name string, count uint32) []byte {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, ComposerOptions{})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteString(name); err != nil {
+			t.Fatalf("writing string failed: %v", err)
+		}
+		if err = c.WriteUint32(count); err != nil {
+			t.Fatalf("writing uint32 failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+	isFrameOverrun := func(err error) bool {
+		for ; err != nil; err = errors.Unwrap(err) {
+			if be, ok := err.(*bsterr.Error); ok && be.Code == bsterr.CodeFrameOverrun {
+				return true
+			}
+		}
+		return false
+	}
+	first, second := record("first", 1), record("second", 2)
+	wal := append(append([]byte{}, first...), second...)
+
+	t.Run("Valid", func(t *testing.T) {
+		// The consecutive records are extracted within their frames.
+		r := bytes.NewReader(wal)
+		for i, size := range []int{len(first), len(second)} {
+			x, err := NewExtractor(r, ExtractorOptions{ExpectedType: st, FrameSize: size})
+			if err != nil {
+				t.Fatalf("creating extractor %d failed: %v", i, err)
+			}
+			for x.Next() {
+				if _, err = x.Skip(); err != nil {
+					t.Fatalf("skipping field of record %d failed: %v", i, err)
+				}
+			}
+			if err = x.Err(); err != nil {
+				t.Fatalf("extracting record %d failed: %v", i, err)
+			}
+			x.Close()
+		}
+		if r.Len() != 0 {
+			t.Fatalf("unexpected bytes left: %d", r.Len())
+		}
+	})
+
+	t.Run("Overrun", func(t *testing.T) {
+		// The frame shorter than the value, i.e. of the malformed record, fails without consuming the next one.
+		r := bytes.NewReader(wal)
+		x, err := NewExtractor(r, ExtractorOptions{ExpectedType: st, FrameSize: len(first) - 2})
+		if err != nil {
+			t.Fatalf("creating extractor failed: %v", err)
+		}
+		defer x.Close()
+		var name string
+		if x.Next() {
+			if name, err = x.ReadString(); err != nil {
+				t.Fatalf("reading string failed: %v", err)
+			}
+		}
+		if name != "first" {
+			t.Fatalf("unexpected name: %s", name)
+		}
+		if x.Next() {
+			_, err = x.ReadUint32()
+		} else {
+			err = x.Err()
+		}
+		if !isFrameOverrun(err) {
+			t.Fatalf("expected frame overrun error, got: %v", err)
+		}
+		if pos, _ := r.Seek(0, io.SeekCurrent); pos > int64(len(first)-2) {
+			t.Fatalf("read beyond the frame: %d", pos)
+		}
+	})
+
+	t.Run("Seek", func(t *testing.T) {
+		fr := &frameReader{ReadSeeker: bytes.NewReader(wal), size: int64(len(first))}
+		if _, err := fr.Seek(int64(len(first))+1, io.SeekStart); !isFrameOverrun(err) {
+			t.Fatalf("expected frame overrun error, got: %v", err)
+		}
+		if pos, err := fr.Seek(0, io.SeekEnd); err != nil || pos != int64(len(first)) {
+			t.Fatalf("unexpected frame end: %d, %v", pos, err)
+		}
+		if _, err := fr.Read(make([]byte, 1)); !isFrameOverrun(err) {
+			t.Fatalf("expected frame overrun error, got: %v", err)
+		}
+	})
+}
@@ -2,10 +2,11 @@ module github.com/devmodules/bst
 
 go 1.22.3
 
+require github.com/google/uuid v1.6.0
+
 require (
 	github.com/alecthomas/participle/v2 v2.1.1 // indirect
 	github.com/google/btree v1.1.2 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/rogpeppe/go-internal v1.9.0 // indirect
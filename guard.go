@@ -0,0 +1,59 @@
+//go:build bstguard
+// +build bstguard
+
+package bst
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/devmodules/bst/bsterr"
+)
+
+// ownerGuard detects the use of a single Composer or Extractor by multiple goroutines, which results in corrupted
+// binaries that are hard to diagnose. The guarded value is owned by the goroutine which used it first, until it is
+// closed or reset. Its use by any other goroutine panics with the CodeConcurrentUse error.
+// The guard is enabled with the 'bstguard' build tag, as obtaining the goroutine id is costly.
+// The owner is shared by pointer, so that the nested composites and the snapshots of the value share it too.
+type ownerGuard struct {
+	owner *atomic.Int64
+}
+
+// check verifies that the current goroutine owns the value, or takes its ownership if it is not owned.
+func (x *ownerGuard) check(name string) {
+	if x.owner == nil {
+		x.owner = new(atomic.Int64)
+	}
+	id := goroutineID()
+	if x.owner.CompareAndSwap(0, id) {
+		return
+	}
+	if owner := x.owner.Load(); owner != id {
+		panic(bsterr.Err(bsterr.CodeConcurrentUse, name+" is used by multiple goroutines").
+			WithDetails(bsterr.D("owner", owner), bsterr.D("goroutine", id)))
+	}
+}
+
+// release releases the ownership of the value, so that it could be used by another goroutine.
+func (x *ownerGuard) release() {
+	if x.owner != nil {
+		x.owner.Store(0)
+	}
+}
+
+// goroutineID returns the id of the current goroutine, parsed out of its stack trace header: 'goroutine 123 [running]:'.
+func goroutineID() int64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		panic(bsterr.ErrWrap(err, bsterr.CodeConcurrentUse, "failed to parse goroutine id"))
+	}
+	return id
+}
@@ -0,0 +1,12 @@
+//go:build !bstguard
+// +build !bstguard
+
+package bst
+
+// ownerGuard is the no-op guard against the use of a single Composer or Extractor by multiple goroutines.
+// The guard is enabled with the 'bstguard' build tag.
+type ownerGuard struct{}
+
+func (ownerGuard) check(string) {}
+
+func (ownerGuard) release() {}
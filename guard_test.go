@@ -0,0 +1,92 @@
+//go:build bstguard
+// +build bstguard
+
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestOwnerGuard(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.String()},
+		{Index: 2, Name: "Tags", Type: &bsttype.Array{Type: bsttype.String()}},
+		{Index: 3, Name: "Count", Type: bsttype.Uint32()},
+	}}
+
+	// inGoroutine runs the fn in another goroutine and returns the error it panicked with.
+	inGoroutine := func(fn func()) (err error) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer func() {
+				if r := recover(); r != nil {
+					err, _ = r.(error)
+				}
+			}()
+			fn()
+		}()
+		<-done
+		return err
+	}
+	isConcurrentUse := func(err error) bool {
+		var be *bsterr.Error
+		return errors.As(err, &be) && be.Code == bsterr.CodeConcurrentUse
+	}
+
+	var buf bytes.Buffer
+	c, err := NewComposer(&buf, st, ComposerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. The nested composites are written by the owner goroutine.
+	if err = c.WriteString("abc"); err != nil {
+		t.Fatal(err)
+	}
+	err = c.WriteArray(func(ac *Composer) error {
+		return ac.WriteString("x")
+	}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2. The use by another goroutine panics.
+	if err = inGoroutine(func() { _ = c.WriteUint32(1) }); !isConcurrentUse(err) {
+		t.Fatalf("expected concurrent use panic, got: %v", err)
+	}
+
+	// 3. Once closed, the composer could be reset and used by another goroutine.
+	//    The interrupted struct fails to close, but its ownership is released anyway.
+	_ = c.Close()
+	err = inGoroutine(func() {
+		buf.Reset()
+		if err := c.ResetOn(&buf, bsttype.Uint32(), ComposerOptions{}); err != nil {
+			t.Error(err)
+		}
+		if err := c.WriteUint32(1); err != nil {
+			t.Error(err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected panic: %v", err)
+	}
+
+	// 4. The extractor is guarded just as well.
+	x, err := NewExtractor(bytes.NewReader(buf.Bytes()), ExtractorOptions{ExpectedType: bsttype.Uint32()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+	if !x.Next() {
+		t.Fatal("expected next element")
+	}
+	if err = inGoroutine(func() { _, _ = x.ReadUint32() }); !isConcurrentUse(err) {
+		t.Fatalf("expected concurrent use panic, got: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package bst
+
+import (
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+// IndexField is the row field composing the secondary index key.
+type IndexField struct {
+	// Name is the name of the row struct field.
+	Name string
+	// Descending inverts the order of the field within the key.
+	Descending bool
+}
+
+// IndexSpec defines the secondary index keys of the struct rows. The key is the comparable binary of the struct
+// composed of the spec fields, in their order - see the KeyType.
+// If one of the fields is an array, the spec defines the multi-value index, with the key for each of its elements.
+type IndexSpec struct {
+	Fields []IndexField
+}
+
+// KeyType returns the struct type of the index keys of the rows of type t.
+// The array field is replaced with its element type.
+func (x IndexSpec) KeyType(t bsttype.Type) (*bsttype.Struct, error) {
+	kt, _, _, err := x.resolve(t)
+	return kt, err
+}
+
+// resolve returns the key type, the row struct type, and the position of the unnested array field in the spec, or -1.
+func (x IndexSpec) resolve(t bsttype.Type) (*bsttype.Struct, *bsttype.Struct, int, error) {
+	// 1. Dereference the named type and verify that it is a struct.
+	st, ok := derefNamedType(t).(*bsttype.Struct)
+	if !ok {
+		return nil, nil, -1, bsterr.Err(bsterr.CodeInvalidType, "index keys are supported only for struct types").
+			WithDetail("type", t)
+	}
+	if len(x.Fields) == 0 {
+		return nil, nil, -1, bsterr.Err(bsterr.CodeInvalidValue, "index spec has no fields")
+	}
+
+	// 2. Compose the key type of the spec fields, with the array field replaced by its element.
+	kt := &bsttype.Struct{Fields: make([]bsttype.StructField, len(x.Fields))}
+	unnest := -1
+	for i, idx := range x.Fields {
+		f, _, found := st.FieldByName(idx.Name)
+		if !found {
+			return nil, nil, -1, bsterr.Err(bsterr.CodeInvalidValue, "index field not found").
+				WithDetail("field", idx.Name)
+		}
+		ft := derefNamedType(f.Type)
+		if at, isArray := ft.(*bsttype.Array); isArray {
+			if unnest >= 0 {
+				return nil, nil, -1, bsterr.Err(bsterr.CodeInvalidValue, "index spec could unnest only a single array field").
+					WithDetails(bsterr.D("field", idx.Name), bsterr.D("unnested", x.Fields[unnest].Name))
+			}
+			unnest = i
+			ft = derefNamedType(at.Type)
+		}
+		kt.Fields[i] = bsttype.StructField{Index: uint(i + 1), Name: idx.Name, Type: ft, Descending: idx.Descending}
+	}
+	return kt, st, unnest, nil
+}
+
+// IndexKeys returns the index keys of the row binary of type t, encoded with given options.
+// Only the segments of the spec fields are decoded, all the other fields are skipped.
+// For the multi-value index spec, a key is returned for each element of the array field, with the element value
+// substituted at the field position, which makes it the inverted index entry. The empty array has no keys.
+func IndexKeys(row []byte, t bsttype.Type, spec IndexSpec, options bstio.ValueOptions) ([][]byte, error) {
+	// 1. Resolve the key type.
+	kt, st, unnest, err := spec.resolve(t)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Split the row into the field segments.
+	segments, err := splitStructSegmentsOf(row, st, options)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to split index row")
+	}
+
+	// 3. Decode the values of the key fields.
+	values := make([]bstvalue.Value, len(spec.Fields))
+	for i, idx := range spec.Fields {
+		f, pos, _ := st.FieldByName(idx.Name)
+		if values[i], err = indexFieldValue(segments, f, pos, options); err != nil {
+			return nil, err
+		}
+	}
+
+	// 4. Compose the single key, or the key for each unnested array element.
+	if unnest < 0 {
+		key, err := bstvalue.MustNewStructValue(kt, values).MarshalValue(bstio.ValueOptions{Comparable: true})
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{key}, nil
+	}
+	av, ok := values[unnest].(*bstvalue.ArrayValue)
+	if !ok {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "unnested index field is not an array value").
+			WithDetail("field", spec.Fields[unnest].Name)
+	}
+	keys := make([][]byte, 0, av.Len())
+	for n := 0; n < av.Len(); n++ {
+		values[unnest] = av.NthElem(n)
+		key, err := bstvalue.MustNewStructValue(kt, values).MarshalValue(bstio.ValueOptions{Comparable: true})
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// indexFieldValue decodes the value of the row field from its segment.
+func indexFieldValue(segments []structSegment, f bsttype.StructField, pos int, o bstio.ValueOptions) (bstvalue.Value, error) {
+	// 1. Find the field segment.
+	if !o.Comparable && f.Encoding != bsttype.FieldEncodingPlain {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "encoded struct fields could not be indexed").
+			WithDetails(bsterr.D("field", f.Name), bsterr.D("encoding", f.Encoding))
+	}
+	si := findRewriteSegment(segments, structSegmentIndex(f, pos, o), pos, o)
+	if si < 0 {
+		return nil, bsterr.Err(bsterr.CodeUndefinedValue, "index field is not present in the row").
+			WithDetail("field", f.Name)
+	}
+	sg := segments[si]
+	fo := structFieldOptions(f, o)
+
+	// 2. In the regular mode the boolean is the bit of the packed booleans segment, which is the same
+	//    as its single byte binary.
+	data := sg.data
+	ft := derefNamedType(f.Type)
+	if !o.CompatibilityMode && ft.Kind() == bsttype.KindBoolean {
+		bit := pos - int(sg.index)
+		data = []byte{bstio.BoolFalse}
+		if sg.data[bit>>3]&(1<<(bit&7)) != 0 {
+			data[0] = bstio.BoolTrue
+		}
+	}
+
+	// 3. Decode the field value.
+	v := bstvalue.EmptyValueOf(ft)
+	if v == nil {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "no value is defined for the field type").WithDetail("type", ft)
+	}
+	if err := v.UnmarshalValue(data, fo); err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to decode index field").
+			WithDetail("field", f.Name)
+	}
+	return v, nil
+}
@@ -0,0 +1,153 @@
+package bst
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestIndexKeys(t *testing.T) {
+	tagsType := &bsttype.Array{Type: bsttype.String()}
+	st := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "ID", Type: bsttype.Uint32()},
+			{Index: 2, Name: "Tags", Type: tagsType},
+			{Index: 3, Name: "Active", Type: bsttype.Boolean()},
+			{Index: 4, Name: "Score", Type: bsttype.Int32(), Descending: true},
+		},
+	}
+	row := func(t *testing.T, tags []string, o bstio.ValueOptions) []byte {
+		tv := make([]bstvalue.Value, len(tags))
+		for i, tag := range tags {
+			tv[i] = bstvalue.NewStringValue(tag)
+		}
+		sv := bstvalue.MustNewStructValue(st, []bstvalue.Value{
+			bstvalue.NewUint32Value(7),
+			bstvalue.MustArrayValueOf(tagsType, tv),
+			bstvalue.NewBoolValue(true),
+			bstvalue.NewInt32Value(-3),
+		})
+		data, err := sv.MarshalValue(o)
+		if err != nil {
+			t.Fatalf("marshaling row failed: %v", err)
+		}
+		return data
+	}
+	key := func(t *testing.T, kt *bsttype.Struct, fn func(c *Composer) error) []byte {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, kt, ComposerOptions{Comparable: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = fn(c); err != nil {
+			t.Fatalf("composing key failed: %v", err)
+		}
+		// Skip the header byte of the composed value.
+		return buf.Bytes()[1:]
+	}
+
+	for _, o := range []bstio.ValueOptions{{}, {Descending: true}} {
+		spec := IndexSpec{Fields: []IndexField{{Name: "Tags"}, {Name: "ID", Descending: true}}}
+		kt, err := spec.KeyType(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 1. The multi-value index has a key per array element.
+		keys, err := IndexKeys(row(t, []string{"go", "db", "go"}, o), st, spec, o)
+		if err != nil {
+			t.Fatalf("index keys failed (options: %+v): %v", o, err)
+		}
+		if len(keys) != 3 {
+			t.Fatalf("expected 3 keys, got: %d", len(keys))
+		}
+		for i, tag := range []string{"go", "db", "go"} {
+			expected := key(t, kt, func(c *Composer) error {
+				if err := c.WriteString(tag); err != nil {
+					return err
+				}
+				return c.WriteUint32(7)
+			})
+			if !bytes.Equal(keys[i], expected) {
+				t.Fatalf("unexpected key %d (options: %+v):\n%v\nexpected:\n%v", i, o, keys[i], expected)
+			}
+		}
+
+		// 2. The empty array has no keys.
+		if keys, err = IndexKeys(row(t, nil, o), st, spec, o); err != nil || len(keys) != 0 {
+			t.Fatalf("expected no keys of the empty array, got: %v, %v", keys, err)
+		}
+
+		// 3. The index without the array field has a single key, with the packed boolean decoded.
+		spec = IndexSpec{Fields: []IndexField{{Name: "Active"}, {Name: "Score"}}}
+		if kt, err = spec.KeyType(st); err != nil {
+			t.Fatal(err)
+		}
+		keys, err = IndexKeys(row(t, []string{"go"}, o), st, spec, o)
+		if err != nil {
+			t.Fatalf("index keys failed (options: %+v): %v", o, err)
+		}
+		expected := key(t, kt, func(c *Composer) error {
+			if err := c.WriteBoolean(true); err != nil {
+				return err
+			}
+			return c.WriteInt32(-3)
+		})
+		if len(keys) != 1 || !bytes.Equal(keys[0], expected) {
+			t.Fatalf("unexpected keys (options: %+v):\n%v\nexpected:\n%v", o, keys, expected)
+		}
+	}
+
+	t.Run("Compatibility", func(t *testing.T) {
+		ct := &bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "A", Type: bsttype.Uint8()},
+				{Index: 2, Name: "B", Type: bsttype.Uint8()},
+				{Index: 5, Name: "C", Type: bsttype.Uint8()},
+			},
+		}
+		o := bstio.ValueOptions{CompatibilityMode: true}
+
+		// The last field of the composed row is indexed.
+		spec := IndexSpec{Fields: []IndexField{{Name: "C"}, {Name: "A"}}}
+		kt, err := spec.KeyType(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys, err := IndexKeys(composeCompatibilityRow(t, ct, 1, 2, 3), ct, spec, o)
+		if err != nil {
+			t.Fatalf("index keys failed: %v", err)
+		}
+		expected := key(t, kt, func(c *Composer) error {
+			if err := c.WriteUint8(3); err != nil {
+				return err
+			}
+			return c.WriteUint8(1)
+		})
+		if len(keys) != 1 || !bytes.Equal(keys[0], expected) {
+			t.Fatalf("unexpected keys:\n%v\nexpected:\n%v", keys, expected)
+		}
+
+		// The field missing in the row composed with the older revision of the type is not indexed.
+		old := composeCompatibilityRow(t, &bsttype.Struct{Fields: ct.Fields[:2]}, 1, 2)
+		if _, err = IndexKeys(old, ct, spec, o); err == nil {
+			t.Fatal("expected missing index field error")
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		in := row(t, []string{"go"}, bstio.ValueOptions{})
+		for _, spec := range []IndexSpec{
+			{},
+			{Fields: []IndexField{{Name: "Unknown"}}},
+			{Fields: []IndexField{{Name: "Tags"}, {Name: "Tags"}}},
+		} {
+			if _, err := IndexKeys(in, st, spec, bstio.ValueOptions{}); err == nil {
+				t.Fatalf("expected index keys of %+v to fail", spec)
+			}
+		}
+	})
+}
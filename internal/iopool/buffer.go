@@ -5,6 +5,8 @@ import (
 	"sort"
 	"sync"
 	"sync/atomic"
+
+	"github.com/devmodules/bst/internal/pooltrack"
 )
 
 const (
@@ -56,6 +58,7 @@ func (p *bufferPool) getBuffer(root io.Writer) *SharedBuffer {
 		buf = &SharedBuffer{Bytes: make([]byte, 0, atomic.LoadUint64(&p.defaultSize))}
 	}
 	buf.Root = root
+	pooltrack.Acquire(buf)
 	return buf
 }
 
@@ -69,6 +72,7 @@ func ReleaseBuffer(b *SharedBuffer) { defaultBufferPool.release(b) }
 //
 // The SharedBuffer mustn't be accessed after returning to the pool.
 func (p *bufferPool) release(b *SharedBuffer) {
+	pooltrack.Release(b)
 	idx := buffIndex(len(b.Bytes))
 
 	if atomic.AddUint64(&p.calls[idx], 1) > calibrateCallsThreshold {
@@ -220,6 +224,12 @@ func (b *SharedBuffer) WriteByte(c byte) error {
 	return nil
 }
 
+// AvailableBuffer returns an empty slice with the unused capacity of the buffer.
+// The slice is meant to be appended to and passed to an immediately succeeding Write call.
+func (b *SharedBuffer) AvailableBuffer() []byte {
+	return b.Bytes[len(b.Bytes):]
+}
+
 // Set sets bytes to the input byte slice.
 func (b *SharedBuffer) Set(p []byte) {
 	b.Bytes = append(b.Bytes[:0], p...)
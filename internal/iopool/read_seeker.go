@@ -38,11 +38,12 @@ func (w *SharedReadSeeker) ResetWithBytes(in []byte) {
 	w.streamPos = 0
 	w.bufferTop = 0
 	w.eof = false
+	// The input is copied, rather than referenced, as the buffer is reused once the reader is released,
+	// and it must not overwrite the input of the previous user.
 	if len(in) > len(w.buffer) {
-		w.buffer = in
-	} else {
-		copy(w.buffer, in)
+		w.buffer = make([]byte, len(in))
 	}
+	copy(w.buffer, in)
 	w.bufferTop = int64(len(in))
 }
 
@@ -73,6 +74,14 @@ func (w *SharedReadSeeker) Seek(offset int64, whence int) (int64, error) {
 	return w.streamPos, nil
 }
 
+// RemainingSize returns the number of the remaining bytes, which is known only if the whole input is already buffered.
+func (w *SharedReadSeeker) RemainingSize() (int64, bool) {
+	if w.root != nil && !w.eof {
+		return 0, false
+	}
+	return w.bufferTop - w.streamPos, true
+}
+
 // Read implements the io.Reader interface.
 func (w *SharedReadSeeker) Read(p []byte) (int, error) {
 	if w.streamPos >= w.bufferTop {
@@ -114,11 +123,29 @@ func (w *SharedReadSeeker) ReadByte() (byte, error) {
 	return b, nil
 }
 
+// Next returns the next n bytes of the buffer and advances the reader past them.
+// If less than n bytes are buffered, it returns false and the reader is not advanced.
+// The returned slice is valid only until the next read.
+func (w *SharedReadSeeker) Next(n int) ([]byte, bool) {
+	if w.streamPos+int64(n) > w.bufferTop {
+		return nil, false
+	}
+	b := w.buffer[w.streamPos : w.streamPos+int64(n)]
+	w.streamPos += int64(n)
+	return b, true
+}
+
 func (w *SharedReadSeeker) fillBuffer(minToRead int) (int, error) {
 	// 1. Check if we need to extend the buffer.
 	if w.bufferTop+int64(minToRead) > int64(len(w.buffer)) {
 		// 2. Extend the buffer - at least twice.
 		size := int64(len(w.buffer)) * 2
+		if size == 0 {
+			size = int64(cap(w.buffer))
+		}
+		if size == 0 {
+			size = int64(minToRead)
+		}
 		for size < w.bufferTop+int64(minToRead) {
 			size *= 2
 		}
@@ -132,7 +159,7 @@ func (w *SharedReadSeeker) fillBuffer(minToRead int) (int, error) {
 	toRead := maxInt64(int64(minToRead), int64(len(w.buffer))-w.bufferTop)
 
 	// 4. Read the bytes.
-	bytesRead, err := w.root.Read(w.buffer[w.streamPos : w.streamPos+toRead])
+	bytesRead, err := w.root.Read(w.buffer[w.bufferTop : w.bufferTop+toRead])
 	if err != nil {
 		if !errors.Is(err, io.EOF) {
 			return bytesRead, err
@@ -140,7 +167,7 @@ func (w *SharedReadSeeker) fillBuffer(minToRead int) (int, error) {
 		w.eof = true
 	}
 
-	w.bufferTop = +int64(bytesRead)
+	w.bufferTop += int64(bytesRead)
 	return bytesRead, nil
 }
 
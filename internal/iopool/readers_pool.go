@@ -5,6 +5,8 @@ import (
 	"sort"
 	"sync"
 	"sync/atomic"
+
+	"github.com/devmodules/bst/internal/pooltrack"
 )
 
 type readersPool struct {
@@ -43,6 +45,7 @@ func (p *readersPool) wrap(root io.Reader) *SharedReadSeeker {
 		r = v.(*SharedReadSeeker)
 		r.ResetWithRoot(root)
 	}
+	pooltrack.Acquire(r)
 	return r
 }
 
@@ -60,11 +63,12 @@ func (p *readersPool) get(in []byte) *SharedReadSeeker {
 		r = &SharedReadSeeker{root: nil, buffer: make([]byte, len(in), size), bufferTop: int64(len(in))}
 		copy(r.buffer, in)
 	}
-
+	pooltrack.Acquire(r)
 	return r
 }
 
 func (p *readersPool) release(r *SharedReadSeeker) {
+	pooltrack.Release(r)
 	idx := buffIndex(len(r.buffer))
 
 	if atomic.AddUint64(&p.calls[idx], 1) > calibrateCallsThreshold {
@@ -0,0 +1,92 @@
+// Package pooltrack tracks the objects acquired from the shared pools, so that the tests could detect the pool leaks.
+// The tracking is process-wide and disabled by default, thus it costs a single atomic load per pool operation.
+package pooltrack
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// maxStackDepth is the maximum number of the recorded acquisition stack frames.
+const maxStackDepth = 32
+
+var (
+	enabled atomic.Bool
+	mu      sync.Mutex
+	live    map[interface{}][]uintptr
+)
+
+// Leak is the shared pool object, which was acquired but not released while the tracking was enabled.
+type Leak struct {
+	// Object is the leaked object.
+	Object interface{}
+	// Stack is the formatted call stack of the object acquisition.
+	Stack string
+}
+
+// Enable starts tracking the shared pool objects. It returns false if the tracking is already enabled.
+func Enable() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if enabled.Load() {
+		return false
+	}
+	live = make(map[interface{}][]uintptr)
+	enabled.Store(true)
+	return true
+}
+
+// Disable stops tracking the shared pool objects, and returns the ones acquired but not released since the Enable.
+func Disable() []Leak {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled.Store(false)
+	leaks := make([]Leak, 0, len(live))
+	for v, pcs := range live {
+		leaks = append(leaks, Leak{Object: v, Stack: formatStack(pcs)})
+	}
+	live = nil
+	return leaks
+}
+
+// Acquire records the acquisition of the shared pool object. The object must be a pointer.
+func Acquire(v interface{}) {
+	if !enabled.Load() {
+		return
+	}
+	pcs := make([]uintptr, maxStackDepth)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+
+	mu.Lock()
+	if live != nil {
+		live[v] = pcs
+	}
+	mu.Unlock()
+}
+
+// Release records the release of the shared pool object. The objects acquired before the tracking was enabled are ignored.
+func Release(v interface{}) {
+	if !enabled.Load() {
+		return
+	}
+	mu.Lock()
+	delete(live, v)
+	mu.Unlock()
+}
+
+// formatStack formats the call stack, one 'function (file:line)' frame per line.
+func formatStack(pcs []uintptr) string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&sb, "\t%s (%s:%d)\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,493 @@
+package bst
+
+import (
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+// FormatKey renders the comparable key binary of type t, i.e. the index key returned by the IndexKeys,
+// as the text that could be read by humans, i.e. in the logs, the CLI and the support tickets,
+// and turned back into the same binary with the ParseKey.
+// The syntax is directed by the type:
+//   - the booleans are 'true' or 'false', the integers and floats are decimal numbers, 'NaN', '+Inf' or '-Inf',
+//   - the strings are Go quoted, the bytes are '0x' followed by the hex digits,
+//   - the timestamps and date times are quoted RFC 3339 texts, the durations are Go durations, i.e. '1h30m',
+//   - the decimals and UUIDs are their canonical texts, the enums are their value names,
+//   - the null nullable is 'null', the arrays are '[a, b]' and the structs are '{Name: v, Other: w}',
+//     with all the fields in the order of the type.
+//
+// The names which are not the identifiers are quoted. The maps, any, one of, bitmap and external bytes
+// are not supported as the key values.
+func FormatKey(key []byte, t bsttype.Type) (string, error) {
+	// 1. Verify that the type could be rendered.
+	if err := checkKeyTextType(t); err != nil {
+		return "", err
+	}
+
+	// 2. Decode the key value.
+	v := bstvalue.EmptyValueOf(t)
+	if err := v.UnmarshalValue(key, bstio.ValueOptions{Comparable: true}); err != nil {
+		return "", bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to decode key value").
+			WithDetail("type", t)
+	}
+
+	// 3. Render the value.
+	var sb strings.Builder
+	if err := formatKeyValue(&sb, v); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// ParseKey parses the key text of type t, in the syntax of the FormatKey, and returns its comparable binary.
+func ParseKey(s string, t bsttype.Type) ([]byte, error) {
+	// 1. Verify that the type could be parsed.
+	if err := checkKeyTextType(t); err != nil {
+		return nil, err
+	}
+
+	// 2. Parse the value and verify that nothing follows it.
+	p := keyParser{s: s}
+	v, err := p.value(t)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return nil, p.err("unexpected text after the key value")
+	}
+
+	// 3. Encode the comparable binary.
+	return v.MarshalValue(bstio.ValueOptions{Comparable: true})
+}
+
+// checkKeyTextType verifies that the values of type t have the key text syntax.
+func checkKeyTextType(t bsttype.Type) error {
+	switch tt := derefNamedType(t).(type) {
+	case *bsttype.Array:
+		return checkKeyTextType(tt.Type)
+	case *bsttype.Nullable:
+		return checkKeyTextType(tt.Type)
+	case *bsttype.Struct:
+		for _, f := range tt.Fields {
+			if err := checkKeyTextType(f.Type); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	switch derefNamedType(t).Kind() {
+	case bsttype.KindUndefined, bsttype.KindAny, bsttype.KindMap, bsttype.KindOneOf,
+		bsttype.KindBitmap, bsttype.KindExternalBytes:
+		return bsterr.Err(bsterr.CodeInvalidType, "type is not supported by the key text").
+			WithDetail("type", t)
+	}
+	return nil
+}
+
+// formatKeyValue writes the key text of the value.
+func formatKeyValue(sb *strings.Builder, v bstvalue.Value) error {
+	switch tv := v.(type) {
+	case *bstvalue.BoolValue:
+		sb.WriteString(strconv.FormatBool(tv.Value))
+	case *bstvalue.IntValue:
+		sb.WriteString(strconv.FormatInt(int64(tv.Value), 10))
+	case *bstvalue.Int8Value:
+		sb.WriteString(strconv.FormatInt(int64(tv.Value), 10))
+	case *bstvalue.Int16Value:
+		sb.WriteString(strconv.FormatInt(int64(tv.Value), 10))
+	case *bstvalue.Int32Value:
+		sb.WriteString(strconv.FormatInt(int64(tv.Value), 10))
+	case *bstvalue.Int64Value:
+		sb.WriteString(strconv.FormatInt(tv.Value, 10))
+	case *bstvalue.UintValue:
+		sb.WriteString(strconv.FormatUint(uint64(tv.Value), 10))
+	case *bstvalue.Uint8Value:
+		sb.WriteString(strconv.FormatUint(uint64(tv.Value), 10))
+	case *bstvalue.Uint16Value:
+		sb.WriteString(strconv.FormatUint(uint64(tv.Value), 10))
+	case *bstvalue.Uint32Value:
+		sb.WriteString(strconv.FormatUint(uint64(tv.Value), 10))
+	case *bstvalue.Uint64Value:
+		sb.WriteString(strconv.FormatUint(tv.Value, 10))
+	case *bstvalue.Float32Value:
+		sb.WriteString(formatKeyFloat(float64(tv.Value), 32))
+	case *bstvalue.Float64Value:
+		sb.WriteString(formatKeyFloat(tv.Value, 64))
+	case *bstvalue.StringValue:
+		sb.WriteString(strconv.Quote(tv.Value))
+	case *bstvalue.Bytes:
+		sb.WriteString("0x")
+		sb.WriteString(hex.EncodeToString(tv.Value))
+	case *bstvalue.TimestampValue:
+		sb.WriteString(strconv.Quote(tv.Value.UTC().Format(time.RFC3339Nano)))
+	case *bstvalue.DateTime:
+		sb.WriteString(strconv.Quote(tv.Value.Format(time.RFC3339Nano)))
+	case *bstvalue.DurationValue:
+		sb.WriteString(tv.Value.String())
+	case *bstvalue.DecimalValue:
+		sb.WriteString(tv.Value.String())
+	case *bstvalue.UUIDValue:
+		sb.WriteString(tv.Value.String())
+	case *bstvalue.EnumValue:
+		name, ok := tv.IndexString()
+		if !ok {
+			return bsterr.Err(bsterr.CodeInvalidValue, "enum key value is not defined by its type").
+				WithDetails(bsterr.D("index", tv.Index), bsterr.D("type", tv.EnumType))
+		}
+		sb.WriteString(formatKeyName(name))
+	case *bstvalue.NullableValue:
+		if tv.IsNull {
+			sb.WriteString("null")
+			return nil
+		}
+		return formatKeyValue(sb, tv.Value)
+	case *bstvalue.ArrayValue:
+		sb.WriteByte('[')
+		for i, ev := range tv.Values {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if err := formatKeyValue(sb, ev); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+	case *bstvalue.StructValue:
+		sb.WriteByte('{')
+		for i, f := range tv.StructType.Fields {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(formatKeyName(f.Name))
+			sb.WriteString(": ")
+			if err := formatKeyValue(sb, tv.Fields[i]); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte('}')
+	default:
+		return bsterr.Err(bsterr.CodeInvalidType, "value is not supported by the key text").
+			WithDetail("kind", v.Kind())
+	}
+	return nil
+}
+
+// formatKeyFloat returns the shortest text of the float, which parses back to the same bits.
+func formatKeyFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+// formatKeyName returns the struct field or enum value name, quoted if it is not an identifier.
+func formatKeyName(name string) string {
+	if isKeyIdentifier(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// isKeyIdentifier checks if the name could be written without the quotes.
+func isKeyIdentifier(name string) bool {
+	if name == "" || name == "null" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// keyParser is the recursive descent parser of the key text.
+type keyParser struct {
+	s   string
+	pos int
+}
+
+// keyWord is the single token of the key text, either bare or quoted.
+type keyWord struct {
+	text   string
+	quoted bool
+	offset int
+}
+
+// err returns the invalid key text error at the current offset.
+func (x *keyParser) err(msg string) error {
+	return bsterr.Err(bsterr.CodeInvalidValue, msg).WithDetail("offset", x.pos)
+}
+
+// skipSpace moves the offset over the white space.
+func (x *keyParser) skipSpace() {
+	for x.pos < len(x.s) {
+		switch x.s[x.pos] {
+		case ' ', '\t', '\n', '\r':
+			x.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume moves over the expected punctuation character.
+func (x *keyParser) consume(c byte) error {
+	x.skipSpace()
+	if x.pos >= len(x.s) || x.s[x.pos] != c {
+		return x.err("expected '" + string(c) + "' in the key text")
+	}
+	x.pos++
+	return nil
+}
+
+// peek checks if the next character is the given punctuation, without consuming it.
+func (x *keyParser) peek(c byte) bool {
+	x.skipSpace()
+	return x.pos < len(x.s) && x.s[x.pos] == c
+}
+
+// word reads the next bare or quoted token.
+func (x *keyParser) word() (keyWord, error) {
+	// 1. The quoted word ends with the unescaped quote.
+	x.skipSpace()
+	start := x.pos
+	if x.pos < len(x.s) && x.s[x.pos] == '"' {
+		end := x.pos + 1
+		for ; end < len(x.s) && x.s[end] != '"'; end++ {
+			if x.s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(x.s) {
+			return keyWord{}, x.err("unterminated quoted text in the key text")
+		}
+		text, err := strconv.Unquote(x.s[x.pos : end+1])
+		if err != nil {
+			return keyWord{}, bsterr.ErrWrap(err, bsterr.CodeInvalidValue, "invalid quoted text in the key text").
+				WithDetail("offset", x.pos)
+		}
+		x.pos = end + 1
+		return keyWord{text: text, quoted: true, offset: start}, nil
+	}
+
+	// 2. The bare word ends with the white space or the punctuation.
+	for ; x.pos < len(x.s); x.pos++ {
+		if strings.IndexByte(" \t\n\r,:[]{}\"", x.s[x.pos]) >= 0 {
+			break
+		}
+	}
+	if x.pos == start {
+		return keyWord{}, x.err("expected value in the key text")
+	}
+	return keyWord{text: x.s[start:x.pos], offset: start}, nil
+}
+
+// value parses the value of type t.
+func (x *keyParser) value(t bsttype.Type) (bstvalue.Value, error) {
+	switch tt := derefNamedType(t).(type) {
+	case *bsttype.Struct:
+		return x.structValue(tt)
+	case *bsttype.Array:
+		return x.arrayValue(tt)
+	case *bsttype.Nullable:
+		return x.nullableValue(tt)
+	}
+	w, err := x.word()
+	if err != nil {
+		return nil, err
+	}
+	v := bstvalue.EmptyValueOf(t)
+	if err = parseKeyScalar(v, w); err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeInvalidValue, "invalid value in the key text").
+			WithDetails(bsterr.D("offset", w.offset), bsterr.D("text", w.text), bsterr.D("type", t))
+	}
+	return v, nil
+}
+
+// structValue parses the struct with all its fields, in the order of the type.
+func (x *keyParser) structValue(st *bsttype.Struct) (bstvalue.Value, error) {
+	if err := x.consume('{'); err != nil {
+		return nil, err
+	}
+	fields := make([]bstvalue.Value, len(st.Fields))
+	for i, f := range st.Fields {
+		if i > 0 {
+			if err := x.consume(','); err != nil {
+				return nil, err
+			}
+		}
+		w, err := x.word()
+		if err != nil {
+			return nil, err
+		}
+		if w.text != f.Name {
+			return nil, bsterr.Err(bsterr.CodeInvalidValue, "unexpected struct field in the key text").
+				WithDetails(bsterr.D("offset", w.offset), bsterr.D("field", w.text), bsterr.D("expected", f.Name))
+		}
+		if err = x.consume(':'); err != nil {
+			return nil, err
+		}
+		if fields[i], err = x.value(f.Type); err != nil {
+			return nil, err
+		}
+	}
+	if err := x.consume('}'); err != nil {
+		return nil, err
+	}
+	return &bstvalue.StructValue{StructType: st, Fields: fields}, nil
+}
+
+// arrayValue parses the array of any number of elements.
+func (x *keyParser) arrayValue(at *bsttype.Array) (bstvalue.Value, error) {
+	if err := x.consume('['); err != nil {
+		return nil, err
+	}
+	var values []bstvalue.Value
+	for !x.peek(']') {
+		if len(values) > 0 {
+			if err := x.consume(','); err != nil {
+				return nil, err
+			}
+		}
+		ev, err := x.value(at.Type)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, ev)
+	}
+	x.pos++
+	if at.HasFixedSize() && uint(len(values)) != at.FixedSize {
+		return nil, x.err("invalid number of fixed size array elements in the key text")
+	}
+	return &bstvalue.ArrayValue{ArrayType: at, Values: values}, nil
+}
+
+// nullableValue parses the bare 'null' or the value of the nullable element type.
+func (x *keyParser) nullableValue(nt *bsttype.Nullable) (bstvalue.Value, error) {
+	x.skipSpace()
+	if strings.HasPrefix(x.s[x.pos:], "null") {
+		end := x.pos + len("null")
+		if end == len(x.s) || strings.IndexByte(" \t\n\r,:[]{}\"", x.s[end]) >= 0 {
+			x.pos = end
+			return &bstvalue.NullableValue{NullableType: nt, Value: bstvalue.EmptyValueOf(nt.Type), IsNull: true}, nil
+		}
+	}
+	v, err := x.value(nt.Type)
+	if err != nil {
+		return nil, err
+	}
+	return &bstvalue.NullableValue{NullableType: nt, Value: v}, nil
+}
+
+// parseKeyScalar sets the scalar value from the key text word.
+func parseKeyScalar(v bstvalue.Value, w keyWord) error {
+	var err error
+	switch tv := v.(type) {
+	case *bstvalue.StringValue:
+		if !w.quoted {
+			return bsterr.Err(bsterr.CodeInvalidValue, "string key value must be quoted")
+		}
+		tv.Value = w.text
+		return nil
+	case *bstvalue.TimestampValue:
+		tv.Value, err = time.Parse(time.RFC3339Nano, w.text)
+		return err
+	case *bstvalue.DateTime:
+		tv.Value, err = time.Parse(time.RFC3339Nano, w.text)
+		return err
+	case *bstvalue.EnumValue:
+		idx, found := tv.EnumType.StringIndex(w.text)
+		if !found {
+			return bsterr.Err(bsterr.CodeInvalidValue, "enum value name not found")
+		}
+		tv.Index = int(idx)
+		return nil
+	}
+	if w.quoted {
+		return bsterr.Err(bsterr.CodeInvalidValue, "key value must not be quoted")
+	}
+	var (
+		i int64
+		u uint64
+		f float64
+	)
+	switch tv := v.(type) {
+	case *bstvalue.BoolValue:
+		tv.Value, err = strconv.ParseBool(w.text)
+	case *bstvalue.IntValue:
+		i, err = strconv.ParseInt(w.text, 10, strconv.IntSize)
+		tv.Value = int(i)
+	case *bstvalue.Int8Value:
+		i, err = strconv.ParseInt(w.text, 10, 8)
+		tv.Value = int8(i)
+	case *bstvalue.Int16Value:
+		i, err = strconv.ParseInt(w.text, 10, 16)
+		tv.Value = int16(i)
+	case *bstvalue.Int32Value:
+		i, err = strconv.ParseInt(w.text, 10, 32)
+		tv.Value = int32(i)
+	case *bstvalue.Int64Value:
+		tv.Value, err = strconv.ParseInt(w.text, 10, 64)
+	case *bstvalue.UintValue:
+		u, err = strconv.ParseUint(w.text, 10, strconv.IntSize)
+		tv.Value = uint(u)
+	case *bstvalue.Uint8Value:
+		u, err = strconv.ParseUint(w.text, 10, 8)
+		tv.Value = uint8(u)
+	case *bstvalue.Uint16Value:
+		u, err = strconv.ParseUint(w.text, 10, 16)
+		tv.Value = uint16(u)
+	case *bstvalue.Uint32Value:
+		u, err = strconv.ParseUint(w.text, 10, 32)
+		tv.Value = uint32(u)
+	case *bstvalue.Uint64Value:
+		tv.Value, err = strconv.ParseUint(w.text, 10, 64)
+	case *bstvalue.Float32Value:
+		f, err = strconv.ParseFloat(w.text, 32)
+		tv.Value = float32(f)
+	case *bstvalue.Float64Value:
+		tv.Value, err = strconv.ParseFloat(w.text, 64)
+	case *bstvalue.Bytes:
+		if !strings.HasPrefix(w.text, "0x") {
+			return bsterr.Err(bsterr.CodeInvalidValue, "bytes key value must start with '0x'")
+		}
+		var b []byte
+		if b, err = hex.DecodeString(w.text[2:]); err != nil {
+			return err
+		}
+		var bv *bstvalue.Bytes
+		if bv, err = bstvalue.NewBytes(b, tv.BytesType); err != nil {
+			return err
+		}
+		*tv = *bv
+	case *bstvalue.DurationValue:
+		tv.Value, err = time.ParseDuration(w.text)
+	case *bstvalue.DecimalValue:
+		tv.Value, err = bstio.ParseDecimal(w.text)
+	case *bstvalue.UUIDValue:
+		tv.Value, err = bstio.ParseUUID(w.text)
+	default:
+		return bsterr.Err(bsterr.CodeInvalidType, "value is not supported by the key text").
+			WithDetail("kind", v.Kind())
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestFormatKey(t *testing.T) {
+	status := &bsttype.Enum{ValueBytes: 1, Elements: []bsttype.EnumElement{
+		{String: "active", Index: 0}, {String: "on hold", Index: 1},
+	}}
+	tagsType := bsttype.ArrayOf(bsttype.String())
+	noteType := bsttype.NullableOf(bsttype.String())
+	kt := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "Tenant", Type: bsttype.Uint32()},
+			{Index: 2, Name: "Score", Type: bsttype.Int64(), Descending: true},
+			{Index: 3, Name: "Name", Type: bsttype.String()},
+			{Index: 4, Name: "Ratio", Type: bsttype.Float64()},
+			{Index: 5, Name: "Status", Type: status},
+			{Index: 6, Name: "Tags", Type: tagsType},
+			{Index: 7, Name: "Note", Type: noteType},
+			{Index: 8, Name: "Created", Type: bsttype.Timestamp()},
+			{Index: 9, Name: "TTL", Type: bsttype.Duration()},
+			{Index: 10, Name: "ID", Type: bsttype.UUID()},
+			{Index: 11, Name: "Amount", Type: bsttype.Decimal()},
+			{Index: 12, Name: "Hash", Type: &bsttype.Bytes{}},
+			{Index: 13, Name: "Active", Type: bsttype.Boolean()},
+		},
+	}
+	id, err := bstio.ParseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(t *testing.T, score int64, ratio float64, name string, st uint, tags []string, note *string) []byte {
+		tv := make([]bstvalue.Value, len(tags))
+		for i, tag := range tags {
+			tv[i] = bstvalue.NewStringValue(tag)
+		}
+		nv := bstvalue.NullValueOf(noteType)
+		if note != nil {
+			nv = bstvalue.MustNullableValue(bstvalue.NewStringValue(*note), false)
+		}
+		data, err := bstvalue.MustNewStructValue(kt, []bstvalue.Value{
+			bstvalue.NewUint32Value(7),
+			bstvalue.NewInt64Value(score),
+			bstvalue.NewStringValue(name),
+			bstvalue.NewFloat64Value(ratio),
+			bstvalue.MustNewEnumValue(status, st),
+			bstvalue.MustArrayValueOf(tagsType, tv),
+			nv,
+			bstvalue.NewTimestampValue(time.Date(2024, 5, 6, 7, 8, 9, 123, time.UTC)),
+			bstvalue.NewDurationValue(90 * time.Minute),
+			bstvalue.NewUUIDValue(id),
+			bstvalue.NewDecimalValue(bstio.NewDecimal(-12345, 2)),
+			bstvalue.MustNewBytes([]byte{0xde, 0xad}, &bsttype.Bytes{}),
+			bstvalue.NewBoolValue(true),
+		}).MarshalValue(bstio.ValueOptions{Comparable: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	// 1. The key renders in the type directed syntax.
+	note := "a \"quoted\" note"
+	k := key(t, -3, 0.25, "Ann", 1, []string{"go", "db"}, &note)
+	text, err := FormatKey(k, kt)
+	if err != nil {
+		t.Fatalf("formatting key failed: %v", err)
+	}
+	expected := `{Tenant: 7, Score: -3, Name: "Ann", Ratio: 0.25, Status: "on hold", Tags: ["go", "db"], ` +
+		`Note: "a \"quoted\" note", Created: "2024-05-06T07:08:09.000000123Z", TTL: 1h30m0s, ` +
+		`ID: 6ba7b810-9dad-11d1-80b4-00c04fd430c8, Amount: -123.45, Hash: 0xdead, Active: true}`
+	if text != expected {
+		t.Fatalf("unexpected key text:\n%s\nexpected:\n%s", text, expected)
+	}
+
+	// 2. The formatted keys parse back to the same binaries.
+	empty := ""
+	nullText := "null"
+	for _, k = range [][]byte{
+		k,
+		key(t, math.MinInt64, math.Inf(-1), "", 0, nil, nil),
+		key(t, math.MaxInt64, math.NaN(), "null", 1, []string{""}, &nullText),
+		key(t, 0, math.SmallestNonzeroFloat64, "\x00\xff", 0, []string{"[a, b]", "{c: d}"}, &empty),
+	} {
+		if text, err = FormatKey(k, kt); err != nil {
+			t.Fatalf("formatting key failed: %v", err)
+		}
+		parsed, err := ParseKey(text, kt)
+		if err != nil {
+			t.Fatalf("parsing key text %s failed: %v", text, err)
+		}
+		if !bytes.Equal(parsed, k) {
+			t.Fatalf("parsed key of %s differs:\n%v\nexpected:\n%v", text, parsed, k)
+		}
+	}
+
+	hasCode := func(err error, code bsterr.ErrCode) bool {
+		var be *bsterr.Error
+		return errors.As(err, &be) && be.Code == code
+	}
+
+	// 3. The malformed texts and the unsupported types are rejected.
+	for _, s := range []string{
+		"",
+		"{Tenant: 7}",
+		"{Score: -3" + expected[len("{Tenant: 7, Score: -3"):],
+		expected[:len(expected)-1],
+		expected + " x",
+		"{Tenant: Ann" + expected[len("{Tenant: 7"):],
+		"{Tenant: 7, Score: -3, Name: Ann" + expected[len(`{Tenant: 7, Score: -3, Name: "Ann"`):],
+	} {
+		if _, err = ParseKey(s, kt); !hasCode(err, bsterr.CodeInvalidValue) {
+			t.Fatalf("expected invalid value error for %q, got: %v", s, err)
+		}
+	}
+	if _, err = ParseKey("{}", bsttype.MapTypeOf(bsttype.String(), bsttype.String(), false, false)); !hasCode(err, bsterr.CodeInvalidType) {
+		t.Fatalf("expected invalid type error for the map key, got: %v", err)
+	}
+}
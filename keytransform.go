@@ -0,0 +1,35 @@
+package bst
+
+import (
+	"strings"
+	"unicode"
+)
+
+// KeyTransform transforms the string before it is written in the comparable binary, so that the keys
+// of the equivalent strings are equal. Apart from the FoldCase and TrimSpace, the Unicode normalization,
+// i.e. the golang.org/x/text/unicode/norm NFC.String, could be used as the transform as well.
+type KeyTransform func(s string) string
+
+// ChainKeyTransforms returns the transform applying given transforms in order, i.e. the TrimSpace followed by the FoldCase.
+func ChainKeyTransforms(transforms ...KeyTransform) KeyTransform {
+	return func(s string) string {
+		for _, fn := range transforms {
+			s = fn(s)
+		}
+		return s
+	}
+}
+
+// FoldCase is the KeyTransform which folds the case of the string with the Unicode simple case folding,
+// independent of the locale. All the runes of the same case folding orbit, i.e. 'K', 'k' and the Kelvin sign,
+// are mapped to the same lower case rune.
+func FoldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		return unicode.ToLower(unicode.ToUpper(r))
+	}, s)
+}
+
+// TrimSpace is the KeyTransform which removes the leading and trailing white space of the string.
+func TrimSpace(s string) string {
+	return strings.TrimSpace(s)
+}
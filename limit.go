@@ -0,0 +1,55 @@
+package bst
+
+import (
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+// valueLimiter is the root writer of the composer with the MaxValueBytes option.
+// It rejects the write which would exceed the limit as a whole, so that no part of it is written,
+// and keeps rejecting all the following writes, as the composed value is already invalid.
+type valueLimiter struct {
+	w        io.Writer
+	max      int
+	written  int
+	exceeded bool
+}
+
+// Write writes the p to the underlying writer, if it fits within the limit.
+// Implements io.Writer interface.
+func (x *valueLimiter) Write(p []byte) (int, error) {
+	if err := x.check(len(p)); err != nil {
+		return 0, err
+	}
+	n, err := x.w.Write(p)
+	x.written += n
+	return n, err
+}
+
+// checkBuffered verifies that the binaries buffered on top of the limiter by the writer w, along with
+// the already written binary, fit within the limit. The buffered binaries are written on closing their collections,
+// thus checking them early prevents buffering huge values.
+func (x *valueLimiter) checkBuffered(w io.Writer) error {
+	var buffered int
+	for {
+		sb, ok := w.(*iopool.SharedBuffer)
+		if !ok {
+			break
+		}
+		buffered += sb.Len()
+		w = sb.Root
+	}
+	return x.check(buffered)
+}
+
+// check verifies that n more bytes fit within the limit.
+func (x *valueLimiter) check(n int) error {
+	if x.exceeded || x.written+n > x.max {
+		x.exceeded = true
+		return bsterr.Err(bsterr.CodeValueTooLarge, "value exceeds the maximum binary size").
+			WithDetails(bsterr.D("max", x.max), bsterr.D("size", x.written+n))
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package bst
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bstskip"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+// Lookup returns the value at the path of the data binary of type typ, composed along with its header.
+// The path is composed of the struct field names separated with dots, where each of them might be followed
+// by the array element indexes, i.e. "user.address.city" or "items[3].id". The path might be split into
+// multiple arguments as well, i.e. Lookup(data, typ, "items", "[3]", "id").
+// If the typ is nil, the type embedded in the binary is used.
+//
+// Only the binaries of the values on the path are read. The preceding struct fields and array elements are
+// skipped without decoding, and the following ones are not read at all. The compatibility mode struct fields
+// are found by their field headers. The body of the compressed binary is decompressed as a whole beforehand,
+// up to the DefaultMaxDecompressedBytes.
+// Lookups through the run-length and null bitmap encoded arrays, the variable size arrays of the comparable binaries
+// and the fields with non-plain encodings are not supported. The looked up values of the compatibility mode
+// binaries could not contain the structs.
+func Lookup(data []byte, typ bsttype.Type, path ...string) (bstvalue.Value, error) {
+	// 1. Read the header of the binary, to find out its options and the embedded type.
+	x := &Extractor{extractorFrame: extractorFrame{r: bytes.NewReader(data)}}
+	x.opts.ExpectedType = typ
+	defer x.Close()
+	if err := x.readHeader(); err != nil {
+		return nil, err
+	}
+
+	// 1.1. The compressed value is looked up in the rest of its decompressed body.
+	if x.compression == bstio.CompressionNone {
+		data = data[x.bytesRead:]
+	} else {
+		if x.compressed == nil {
+			if err := x.inflateBody(); err != nil {
+				return nil, err
+			}
+		}
+		body, err := io.ReadAll(x.r)
+		if err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read decompressed body")
+		}
+		data = body
+	}
+	t := typ
+	if t == nil {
+		t = x.embedType
+	}
+	if t == nil {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "no type provided for the lookup and no type embedded in the binary")
+	}
+
+	// 2. Parse the path steps.
+	steps, err := parseLookupPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Narrow the binary down to the value of each step.
+	l := lookup{
+		data: data,
+		t:    t,
+		o: bstio.ValueOptions{
+			Descending:        x.opts.Descending,
+			Comparable:        x.opts.Comparable,
+			CompatibilityMode: x.opts.CompatibilityMode,
+			LengthPrefixed:    x.opts.LengthPrefixedCollections,
+		},
+	}
+	for _, s := range steps {
+		if err = l.step(s); err != nil {
+			return nil, err
+		}
+	}
+
+	// 4. Decode the value found. The values decode the structs of the regular mode only.
+	if l.o.CompatibilityMode && bsttype.ContainsKind(l.t, bsttype.KindStruct) {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "compatibility mode struct values could not be decoded").
+			WithDetail("type", l.t)
+	}
+	v := bstvalue.EmptyValueOf(l.t)
+	if v == nil {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "no value is defined for the type").WithDetail("type", l.t)
+	}
+	if err = v.UnmarshalValue(l.data, l.o); err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to decode looked up value")
+	}
+	return v, nil
+}
+
+// lookupStep is a single step of the lookup path, which is either the struct field name or the array index.
+type lookupStep struct {
+	field string
+	index int
+}
+
+func parseLookupPath(path []string) ([]lookupStep, error) {
+	var steps []lookupStep
+	for _, p := range path {
+		for _, seg := range strings.Split(p, ".") {
+			// 1. The field name precedes the indexes.
+			name, indexes, _ := strings.Cut(seg, "[")
+			if name != "" {
+				steps = append(steps, lookupStep{field: name, index: -1})
+			}
+			if indexes == "" {
+				if name == "" {
+					return nil, bsterr.Err(bsterr.CodeInvalidValue, "empty lookup path segment").WithDetail("path", p)
+				}
+				continue
+			}
+
+			// 2. Each of the indexes is enclosed in the brackets.
+			for _, ix := range strings.Split("["+indexes, "[")[1:] {
+				n, err := strconv.Atoi(strings.TrimSuffix(ix, "]"))
+				if err != nil || !strings.HasSuffix(ix, "]") || n < 0 {
+					return nil, bsterr.Err(bsterr.CodeInvalidValue, "invalid lookup path array index").
+						WithDetail("segment", seg)
+				}
+				steps = append(steps, lookupStep{index: n})
+			}
+		}
+	}
+	return steps, nil
+}
+
+// lookup is the binary of the value of type t, encoded with the options o, narrowed down by the lookup steps.
+type lookup struct {
+	data []byte
+	t    bsttype.Type
+	o    bstio.ValueOptions
+}
+
+func (x *lookup) step(s lookupStep) error {
+	// 1. The named types are dereferenced, and the null values end the lookup.
+	x.t = derefNamedType(x.t)
+	if nt, ok := x.t.(*bsttype.Nullable); ok {
+		r := bytes.NewReader(x.data)
+		nf, err := bstio.ReadNullableFlag(r, x.o.Descending)
+		if err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read nullable flag")
+		}
+		if nf == bstio.NullableIsNull {
+			return bsterr.Err(bsterr.CodeUndefinedValue, "looked up value is null")
+		}
+		x.data, x.t = x.data[1:], derefNamedType(nt.Type)
+	}
+
+	// 2. Step into the struct field or the array element.
+	if s.index < 0 {
+		st, ok := x.t.(*bsttype.Struct)
+		if !ok {
+			return bsterr.Err(bsterr.CodeInvalidType, "looked up field of the non struct type").
+				WithDetails(bsterr.D("field", s.field), bsterr.D("type", x.t))
+		}
+		return x.field(st, s.field)
+	}
+	at, ok := x.t.(*bsttype.Array)
+	if !ok {
+		return bsterr.Err(bsterr.CodeInvalidType, "looked up element of the non array type").
+			WithDetails(bsterr.D("index", s.index), bsterr.D("type", x.t))
+	}
+	return x.elem(at, s.index)
+}
+
+func (x *lookup) field(st *bsttype.Struct, name string) error {
+	// 1. Find the field.
+	f, pos, found := st.FieldByName(name)
+	if !found {
+		return bsterr.Err(bsterr.CodeValueFieldMissing, "looked up field is not defined in the struct type").
+			WithDetail("field", name)
+	}
+	if !x.o.Comparable && f.Encoding != bsttype.FieldEncodingPlain {
+		return bsterr.Err(bsterr.CodeInvalidType, "encoded struct fields could not be looked up").
+			WithDetails(bsterr.D("field", f.Name), bsterr.D("encoding", f.Encoding))
+	}
+	fo := x.o
+	if f.Descending {
+		fo.Descending = !fo.Descending
+	}
+
+	// 2. In the compatibility mode the field is found by the field headers.
+	if x.o.CompatibilityMode {
+		return x.compatibilityField(f, fo)
+	}
+
+	// 3. Otherwise, the preceding fields are skipped.
+	r := bytes.NewReader(x.data)
+	for i := 0; i < pos; i++ {
+		// 3.1. Consecutive boolean fields are packed into a single byte each eight fields.
+		if st.Fields[i].Type.Kind() == bsttype.KindBoolean {
+			count := 1
+			for i+count < len(st.Fields) && st.Fields[i+count].Type.Kind() == bsttype.KindBoolean {
+				count++
+			}
+			if i+count > pos {
+				// 3.1.1. The looked up boolean is a bit of the packed booleans.
+				return x.bit(len(x.data)-r.Len(), pos-i, f.Type, fo)
+			}
+			if _, err := r.Seek(int64((count+7)>>3), io.SeekCurrent); err != nil {
+				return bsterr.ErrWrap(err, bsterr.CodeSkippingBinaryValue, "failed to skip boolean fields")
+			}
+			i += count - 1
+			continue
+		}
+
+		// 3.2. Any other field is skipped.
+		po := x.o
+		if st.Fields[i].Descending {
+			po.Descending = !po.Descending
+		}
+		if _, err := bstskip.FieldSkipFuncOf(st.Fields[i], po)(r, po); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeSkippingBinaryValue, "failed to skip struct field").
+				WithDetail("field", st.Fields[i].Name)
+		}
+	}
+	if f.Type.Kind() == bsttype.KindBoolean {
+		// 3.3. The looked up boolean starts the packed booleans.
+		return x.bit(len(x.data)-r.Len(), 0, f.Type, fo)
+	}
+
+	// 4. Skip the field itself, to find out its binary.
+	return x.narrow(r, bstskip.FieldSkipFuncOf(f, fo), f.Type, fo)
+}
+
+func (x *lookup) elem(at *bsttype.Array, index int) error {
+	// 1. Verify that the elements could be found without decoding the array.
+	if x.o.Comparable && !at.HasFixedSize() {
+		return bsterr.Err(bsterr.CodeInvalidType, "comparable variable size array elements could not be looked up")
+	}
+	if !x.o.Comparable && at.Encoding != bsttype.ArrayEncodingPlain {
+		return bsterr.Err(bsterr.CodeInvalidType, "encoded array elements could not be looked up").
+			WithDetail("encoding", at.Encoding)
+	}
+
+	// 2. Read the array length.
+	r := bytes.NewReader(x.data)
+	length := at.FixedSize
+	if !at.HasFixedSize() {
+		var err error
+		length, _, err = bstio.ReadLength(r, x.o.Descending, bsttype.MinEncodedSize(at.Elem()))
+		if err != nil {
+			return err
+		}
+	}
+	if uint(index) >= length {
+		return bsterr.Err(bsterr.CodeOutOfBounds, "looked up array index out of bounds").
+			WithDetails(bsterr.D("index", index), bsterr.D("length", length))
+	}
+
+	// 3. The boolean elements are the bits of the packed bytes.
+	elem := at.Elem()
+	if elem.Kind() == bsttype.KindBoolean {
+		return x.bit(len(x.data)-r.Len(), index, elem, x.o)
+	}
+
+	// 4. The preceding elements are skipped, with a single seek for the fixed size ones.
+	skipFunc := bstskip.ElemSkipFuncOf(elem, x.o)
+	if size, ok := bsttype.FixedEncodedSize(elem); ok {
+		if _, err := r.Seek(int64(index)*int64(size), io.SeekCurrent); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeSkippingBinaryValue, "failed to skip array elements")
+		}
+	} else {
+		for i := 0; i < index; i++ {
+			if _, err := skipFunc(r, x.o); err != nil {
+				return bsterr.ErrWrap(err, bsterr.CodeSkippingBinaryValue, "failed to skip array element").
+					WithDetail("index", i)
+			}
+		}
+	}
+
+	// 5. The length prefix of the element is not a part of its binary.
+	if bstskip.LengthPrefixed(elem, x.o) {
+		if _, _, err := bstio.ReadUint(r, false); err != nil {
+			return err
+		}
+		skipFunc = bstskip.SkipFuncOf(elem)
+	}
+	return x.narrow(r, skipFunc, elem, x.o)
+}
+
+// narrow narrows the lookup down to the value at the current offset of the reader over its data.
+func (x *lookup) narrow(r *bytes.Reader, skipFunc bstskip.SkipFunc, t bsttype.Type, o bstio.ValueOptions) error {
+	start := len(x.data) - r.Len()
+	n, err := skipFunc(r, o)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeSkippingBinaryValue, "failed to skip looked up value")
+	}
+	x.data, x.t, x.o = x.data[start:start+int(n)], t, o
+	return nil
+}
+
+// bit narrows the lookup down to the boolean value of given bit of the booleans packed from the offset of its data.
+// The bit is inverted for the descending booleans, thus the value binary is normalized to the ascending one.
+func (x *lookup) bit(off, bit int, t bsttype.Type, o bstio.ValueOptions) error {
+	if off+bit>>3 >= len(x.data) {
+		return bsterr.Err(bsterr.CodeMalformedBinary, "not enough bytes for packed booleans")
+	}
+	v := x.data[off+bit>>3]&(1<<(bit&7)) != 0
+	if o.Descending {
+		v = !v
+	}
+	x.data, x.t, x.o = []byte{bstio.BoolFalse}, t, o
+	x.o.Descending = false
+	if v {
+		x.data[0] = bstio.BoolTrue
+	}
+	return nil
+}
+
+// compatibilityField narrows the lookup down to the binary of the compatibility mode struct field.
+// The struct header is the max index of the fields written, followed by the fields prefixed with their
+// index and binary length headers.
+func (x *lookup) compatibilityField(f bsttype.StructField, fo bstio.ValueOptions) error {
+	// 1. Read the struct header.
+	r := bytes.NewReader(x.data)
+	count, _, err := bstio.ReadStructHeader(r)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read struct header")
+	}
+
+	// 2. Find the field by its header, seeking over the other fields.
+	for i := 0; i < count; i++ {
+		fh, err := readLookupFieldHeader(r)
+		if err != nil {
+			return err
+		}
+		if fh.index == int(f.Index) {
+			// 2.1. The boolean field takes the whole byte, with its bit being the value.
+			start := len(x.data) - r.Len()
+			if derefNamedType(f.Type).Kind() == bsttype.KindBoolean {
+				return x.bit(start, 0, f.Type, fo)
+			}
+			x.data, x.t, x.o = x.data[start:start+fh.length], f.Type, fo
+			return nil
+		}
+		if _, err = r.Seek(int64(fh.length), io.SeekCurrent); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeSkippingBinaryValue, "failed to skip struct field")
+		}
+	}
+	return bsterr.Err(bsterr.CodeUndefinedValue, "looked up field is not present in the binary").
+		WithDetail("field", f.Name)
+}
+
+// lookupFieldHeader is the compatibility mode field header, along with its binary size.
+type lookupFieldHeader struct {
+	index, length, size int
+}
+
+func readLookupFieldHeader(r io.Reader) (lookupFieldHeader, error) {
+	idx, n, err := bstio.ReadUint(r, false)
+	if err != nil {
+		return lookupFieldHeader{}, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read field index")
+	}
+	length, nl, err := bstio.ReadLength(r, false, 1)
+	if err != nil {
+		return lookupFieldHeader{}, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read field length")
+	}
+	return lookupFieldHeader{index: int(idx), length: int(length), size: n + nl}, nil
+}
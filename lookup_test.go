@@ -0,0 +1,196 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bstskip"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestLookup(t *testing.T) {
+	address := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "street", Type: bsttype.String()},
+		{Index: 2, Name: "city", Type: bsttype.String()},
+	}}
+	item := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "id", Type: bsttype.Uint64()},
+		{Index: 2, Name: "tag", Type: bsttype.String(), Descending: true},
+	}}
+	user := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "id", Type: bsttype.Uint32()},
+		{Index: 2, Name: "admin", Type: bsttype.Boolean()},
+		{Index: 3, Name: "active", Type: bsttype.Boolean()},
+		{Index: 4, Name: "address", Type: address},
+		{Index: 5, Name: "items", Type: bsttype.ArrayOf(item)},
+		{Index: 6, Name: "flags", Type: bsttype.ArrayOf(bsttype.Boolean())},
+		{Index: 7, Name: "nick", Type: bsttype.NullableOf(address)},
+		{Index: 9, Name: "scores", Type: bsttype.ArrayOf(bsttype.ArrayOf(bsttype.Int16()))},
+	}}
+	root := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "user", Type: user},
+	}}
+
+	compose := func(t *testing.T, opts ComposerOptions) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, root, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		err = c.WriteStruct(func(c *Composer) error {
+			if err := c.WriteUint32(7); err != nil {
+				return err
+			}
+			if err := c.WriteBoolean(false); err != nil {
+				return err
+			}
+			if err := c.WriteBoolean(true); err != nil {
+				return err
+			}
+			if err := c.WriteStruct(func(c *Composer) error {
+				if err := c.WriteString("Main"); err != nil {
+					return err
+				}
+				return c.WriteString("Warsaw")
+			}); err != nil {
+				return err
+			}
+			if err := c.WriteArray(func(c *Composer) error {
+				for i, tag := range []string{"a", "bb", "ccc", "dddd"} {
+					if err := c.WriteStruct(func(c *Composer) error {
+						if err := c.WriteUint64(uint64(100 + i)); err != nil {
+							return err
+						}
+						return c.WriteString(tag)
+					}); err != nil {
+						return err
+					}
+				}
+				return nil
+			}, 4); err != nil {
+				return err
+			}
+			if err := c.WriteArray(func(c *Composer) error {
+				for i := 0; i < 10; i++ {
+					if err := c.WriteBoolean(i == 9); err != nil {
+						return err
+					}
+				}
+				return nil
+			}, 10); err != nil {
+				return err
+			}
+			if err := c.WriteNull(); err != nil {
+				return err
+			}
+			return c.WriteArray(func(c *Composer) error {
+				for i := 0; i < 3; i++ {
+					if err := c.WriteArray(func(c *Composer) error {
+						for j := 0; j <= i; j++ {
+							if err := c.WriteInt16(int16(-10*i - j)); err != nil {
+								return err
+							}
+						}
+						return nil
+					}, i+1); err != nil {
+						return err
+					}
+				}
+				return nil
+			}, 3)
+		})
+		if err != nil {
+			t.Fatalf("writing value failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{path: []string{"user.id"}, want: "Uint32(7)"},
+		{path: []string{"user.admin"}, want: "Bool(false)"},
+		{path: []string{"user.active"}, want: "Bool(true)"},
+		{path: []string{"user.address.city"}, want: `String("Warsaw")`},
+		{path: []string{"user", "address", "street"}, want: `String("Main")`},
+		{path: []string{"user.items[3].id"}, want: "Uint64(103)"},
+		{path: []string{"user.items", "[2]", "tag"}, want: `String("ccc")`},
+		{path: []string{"user.flags[8]"}, want: "Bool(false)"},
+		{path: []string{"user.flags[9]"}, want: "Bool(true)"},
+		{path: []string{"user.scores[2][1]"}, want: "Int16(-21)"},
+	}
+
+	for i, opts := range []ComposerOptions{
+		{},
+		{Descending: true},
+		{CompatibilityMode: true},
+		{CompatibilityMode: true, Descending: true, LengthPrefixedCollections: true},
+		{EmbedType: true},
+	} {
+		data := compose(t, opts)
+		typ := bsttype.Type(root)
+		if opts.EmbedType {
+			typ = nil
+		}
+		for _, tc := range tests {
+			v, err := Lookup(data, typ, tc.path...)
+			if err != nil {
+				t.Fatalf("%d: looking up %v failed: %v", i, tc.path, err)
+			}
+			if v.String() != tc.want {
+				t.Fatalf("%d: unexpected value of %v: %s, wanted: %s", i, tc.path, v, tc.want)
+			}
+		}
+
+		// The nested struct value is decoded as a whole, in the regular mode.
+		v, err := Lookup(data, typ, "user.items[1]")
+		if opts.CompatibilityMode {
+			if err == nil {
+				t.Fatalf("%d: expected compatibility mode struct value error", i)
+			}
+		} else if err != nil {
+			t.Fatalf("%d: looking up the item failed: %v", i, err)
+		} else if sv, ok := v.(*bstvalue.StructValue); !ok || sv.Fields[1].String() != `String("bb")` {
+			t.Fatalf("%d: unexpected item value: %v", i, v)
+		}
+
+		// The failed lookups.
+		for _, tc := range []struct {
+			path []string
+			code bsterr.ErrCode
+		}{
+			{path: []string{"user.missing"}, code: bsterr.CodeValueFieldMissing},
+			{path: []string{"user.items[4]"}, code: bsterr.CodeOutOfBounds},
+			{path: []string{"user.nick.city"}, code: bsterr.CodeUndefinedValue},
+			{path: []string{"user.id.value"}, code: bsterr.CodeInvalidType},
+			{path: []string{"user.items[x]"}, code: bsterr.CodeInvalidValue},
+		} {
+			_, err = Lookup(data, typ, tc.path...)
+			var be *bsterr.Error
+			if !errors.As(err, &be) || be.Code != tc.code {
+				t.Fatalf("%d: unexpected error of %v: %v, wanted code: %v", i, tc.path, err, tc.code)
+			}
+		}
+	}
+	t.Run("CompatibilityStructSkip", func(t *testing.T) {
+		// The composed struct is skipped as a whole, up to its last field, by the skip shared with the lookups.
+		data := compose(t, ComposerOptions{CompatibilityMode: true})[1:]
+		o := bstio.ValueOptions{CompatibilityMode: true}
+		n, err := bstskip.SkipStruct(bytes.NewReader(data), root, o)
+		if err != nil {
+			t.Fatalf("skipping struct failed: %v", err)
+		}
+		if int(n) != len(data) {
+			t.Fatalf("unexpected number of bytes skipped: %d, expected: %d", n, len(data))
+		}
+	})
+}
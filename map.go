@@ -3,6 +3,7 @@ package bst
 import (
 	"errors"
 	"io"
+	"math"
 
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
@@ -20,21 +21,28 @@ func (x *Composer) WriteMap(fn func(c *Composer) error, optLength int) error {
 	}
 
 	// 2. If the base is a struct, check if the field header needs to be written.
+	w, bufWrites := x.w, x.bufWrites
 	if x.needWriteFieldHeader() {
 		x.setFieldBuffer()
 	}
 
+	// 2.1. The length prefixed map element is buffered, so that its binary size could be written first.
+	prefixed := x.lengthPrefixedElem()
+	if prefixed {
+		x.w = iopool.GetBuffer(x.w)
+	}
+
 	// 3. Create a savepoint and resetWithRoot given composer.
 	sp := *x
 
 	// 4. Verify if current element matches expected type.
 	mt, ok := x.elemType.(*bsttype.Map)
 	if !ok {
-		return bsterr.Err(bsterr.CodeInvalidType, "invalid type to write").
+		return x.abortComposite(&sp, w, bufWrites, bsterr.Err(bsterr.CodeInvalidType, "invalid type to write").
 			WithDetails(
 				bsterr.D("expected", bsttype.KindMap),
 				bsterr.D("actual", x.elemType.Kind()),
-			)
+			))
 	}
 
 	// 5. resetWithRoot current composer state.
@@ -48,32 +56,40 @@ func (x *Composer) WriteMap(fn func(c *Composer) error, optLength int) error {
 
 	// 7. Initialize map composer.
 	if err := x.initializeMapComposer(mt, false); err != nil {
-		return err
+		return x.abortComposite(&sp, w, bufWrites, err)
 	}
 
 	// 8. Call input function.
 	if err := fn(x); err != nil {
-		return err
+		return x.abortComposite(&sp, w, bufWrites, err)
 	}
 
-	// 9. Verify if writing was completed
-	if x.index <= x.maxIndex && !x.definedLength {
-		return bsterr.Err(bsterr.CodeWritingFailed, "not all expected elements in the map were written")
+	// 9. Verify if writing was completed. The maps of undefined size are completed by any number of entries.
+	if x.index <= x.maxIndex && x.maxIndex != math.MaxInt {
+		return x.abortComposite(&sp, w, bufWrites, bsterr.Err(bsterr.CodeWritingFailed, "not all expected elements in the map were written"))
 	}
 
 	// 10. Close the map composer.
 	if err := x.closeMap(); err != nil {
-		return err
+		return x.abortComposite(&sp, w, bufWrites, err)
 	}
 
-	// 11. Store the number of bytes written to the map composer.
-	bw := x.bytesWritten
+	// 11. Store the number of bytes written to the map composer, along with its write stats.
+	bw, stats := x.bytesWritten, x.stats
 
 	// 12. Restore the savepoint.
 	*x = sp
 
-	// 13. Increase the number of bytes written by the map composer.
+	// 13. Increase the number of bytes written and the write stats by the map composer.
 	x.bytesWritten += bw
+	x.stats.add(stats)
+
+	// 13.1. Write the buffered length prefixed element.
+	if prefixed {
+		if err := x.writeLengthPrefixed(); err != nil {
+			return err
+		}
+	}
 
 	// 14. Finish the element.
 	if err := x.finishElem(); err != nil {
@@ -82,6 +98,31 @@ func (x *Composer) WriteMap(fn func(c *Composer) error, optLength int) error {
 	return nil
 }
 
+// WriteMapWithLength writes a map value of the number of entries known upfront to the composer.
+// Unlike the map of undefined length, its entries are written straight to the composer writer rather than buffered
+// and copied on close, unless the indexed map or the comparable format requires it.
+// The input function needs to write exactly n entries.
+func (x *Composer) WriteMapWithLength(n int, fn func(c *Composer) error) error {
+	if n < 0 {
+		return bsterr.Err(bsterr.CodeInvalidValue, "negative map length").WithDetail("length", n)
+	}
+	if n > 0 {
+		return x.WriteMap(fn, n)
+	}
+
+	// The zero length denotes the undefined one to the WriteMap, thus no entry could be written.
+	return x.WriteMap(func(c *Composer) error {
+		if err := fn(c); err != nil {
+			return err
+		}
+		if c.index > 0 || !c.isKey {
+			return bsterr.Err(bsterr.CodeWritingFailed, "sub-composer wrote more entries than the map length").
+				WithDetails(bsterr.D("written", c.index), bsterr.D("length", n))
+		}
+		return nil
+	}, 0)
+}
+
 // ReadMap extracts and reads the map value. The input function
 // determines how the map should be extracted.
 func (x *Extractor) ReadMap(fn func(x *Extractor) error) error {
@@ -95,10 +136,13 @@ func (x *Extractor) ReadMap(fn func(x *Extractor) error) error {
 		return bsterr.Err(bsterr.CodeAlreadyRead, "element already read")
 	}
 
-	// 3. Create a snapshot of the current state.
-	sp := *x
+	// 2.1. Read the binary size prefix of the length prefixed map element.
+	if err := x.skipLengthPrefix(); err != nil {
+		x.err = err
+		return err
+	}
 
-	// 4. Ensure that the element is of a map type.
+	// 3. Ensure that the element is of a map type.
 	if x.elemType.Kind() != bsttype.KindMap {
 		x.err = bsterr.Err(bsterr.CodeInvalidType, "invalid type to read").
 			WithDetails(
@@ -108,42 +152,59 @@ func (x *Extractor) ReadMap(fn func(x *Extractor) error) error {
 		return x.err
 	}
 
+	// 3.1. Record the map in the trace, preceding the events of its elements.
+	var traceIndex int
+	if x.opts.Trace != nil {
+		traceIndex = x.traceEvent(TraceOpComposite, nil)
+	}
+
 	// 5. Keep expected and embedded map types.
 	xt := x.elemType
 	et := x.embed.elemType
 
-	// 6. Reset the state of the extractor.
-	x.reset()
+	// 6. Push the current frame and reset the state of the extractor.
+	x.enterNested()
 
 	// 7. Set up embedded and expected types.
 	x.opts.ExpectedType = xt
 	x.embedType = et
 
-	// 8. Initialize the extractor for the map.
+	// 8. Initialize the extractor for the map, whose length needs to fit within the decode budget.
 	if err := x.initializeMap(); err != nil {
-		x.err = err
-		return err
+		return x.abortNested(err)
+	}
+	if err := x.checkBudgetLength(); err != nil {
+		return x.abortNested(err)
+	}
+
+	// 8.1. Verify the order of the canonical map entries.
+	if x.opts.CanonicalMaps {
+		if err := x.verifyMapEntries(); err != nil {
+			return x.abortNested(err)
+		}
 	}
 
 	// 8. Execute the extraction function.
 	if err := fn(x); err != nil {
-		x.err = err
-		return err
+		return x.abortNested(err)
 	}
 
 	// 9. Check if the map was fully extracted.
 	if err := x.finishMap(); err != nil {
-		return err
+		return x.abortNested(err)
 	}
 
 	// 10. Keep the number of bytes read.
 	br := x.bytesRead
 
-	// 11. Restore an extractor from the snapshot.
-	*x = sp
+	// 11. Restore the frame of the parent composite.
+	x.leaveNested()
 
 	// 12. Update the number of bytes read.
 	x.bytesRead += br
+	if x.opts.Trace != nil {
+		x.traceComposite(traceIndex)
+	}
 
 	// 13. Finish this element.
 	x.finishElem()
@@ -188,7 +249,8 @@ func (x *Extractor) initializeMap() error {
 	// 2. If the extractor is not in comparable format, we need to read the length of the map.
 	if !x.opts.Comparable {
 		// 2.1. Read the length of the map.
-		ln, n, err := bstio.ReadUint(x.r, x.opts.Descending)
+		minEntrySize := bsttype.MinEncodedSize(bt.Key.Type) + bsttype.MinEncodedSize(bt.Value.Type)
+		ln, n, err := bstio.ReadLength(x.r, x.opts.Descending, minEntrySize)
 		if err != nil {
 			return err
 		}
@@ -196,6 +258,11 @@ func (x *Extractor) initializeMap() error {
 
 		// 2.2. Set the maximum index of the map.
 		x.maxIndex = int(ln - 1)
+
+		// 2.3. Locate the entries and the end of the indexed map, by the binary size of its entries.
+		if bt.Indexed {
+			return x.initializeIndexedMap(ln)
+		}
 		return nil
 	}
 
@@ -209,7 +276,8 @@ func (x *Extractor) initializeMap() error {
 	}
 
 	// 4. Read the raw bytes of the map.
-	data, n, err := bstio.ReadComparableBytesReader(x.r, x.opts.Descending, escape)
+	//    The unescaped descending entries are kept inverted, as they are read in the descending order.
+	data, n, err := bstio.ReadComparableBytesReader(x.r, false, escape)
 	if err != nil {
 		return err
 	}
@@ -224,18 +292,23 @@ func (x *Extractor) initializeMap() error {
 		Descending:        x.opts.Descending,
 		Comparable:        x.opts.Comparable,
 		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    x.opts.LengthPrefixedCollections,
+		OrderedFloats:     x.opts.OrderedFloats,
 	}
 	if bt.Key.Descending {
-		kOpts.Descending = true
+		kOpts.Descending = !kOpts.Descending
 	}
 	vOpts := bstio.ValueOptions{
 		Descending:        x.opts.Descending,
 		Comparable:        x.opts.Comparable,
 		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    x.opts.LengthPrefixedCollections,
+		OrderedFloats:     x.opts.OrderedFloats,
 	}
 	if bt.Value.Descending {
-		vOpts.Descending = true
+		vOpts.Descending = !vOpts.Descending
 	}
+	x.maxIndex = -1
 	for {
 		// 5.1. Skip the element key.
 		if _, err = sk(rs, kOpts); err != nil {
@@ -331,8 +404,9 @@ func (x *Extractor) nextMapElem() bool {
 		x.elemDesc = !x.elemDesc
 	}
 
-	// 4. Reset the done flag.
+	// 4. Reset the done flags, so that the key of the next pair is read first.
 	x.elemDone = false
+	x.isKey, x.keyDone = true, false
 	return true
 }
 
@@ -399,7 +473,12 @@ func (x *Extractor) previewPrevMapType() (bsttype.Type, bool) {
 }
 
 func (x *Extractor) finishMap() error {
-	// 1. Check if the map is already done.
+	// 1. The indexed map is finished at its end, past the directory following its entries.
+	if x.mapEnd > 0 {
+		return x.finishIndexedMap()
+	}
+
+	// 1.1. Check if the map is already done.
 	if x.baseDone {
 		return nil
 	}
@@ -416,13 +495,12 @@ func (x *Extractor) finishMap() error {
 
 	// 3. Prepare the skipper for the key and value types.
 	mt := x.embedType.(*bsttype.Map)
-	ks := bstskip.SkipFuncOf(mt.Key.Type)
-	vs := bstskip.SkipFuncOf(mt.Value.Type)
-
 	kOpts := bstio.ValueOptions{
 		Descending:        x.opts.Descending,
 		Comparable:        x.opts.Comparable,
 		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    x.opts.LengthPrefixedCollections,
+		OrderedFloats:     x.opts.OrderedFloats,
 	}
 	if mt.Key.Descending {
 		kOpts.Descending = !kOpts.Descending
@@ -431,10 +509,14 @@ func (x *Extractor) finishMap() error {
 		Descending:        x.opts.Descending,
 		Comparable:        x.opts.Comparable,
 		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    x.opts.LengthPrefixedCollections,
+		OrderedFloats:     x.opts.OrderedFloats,
 	}
 	if mt.Value.Descending {
 		vOpts.Descending = !vOpts.Descending
 	}
+	ks := bstskip.ElemSkipFuncOf(mt.Key.Type, kOpts)
+	vs := bstskip.ElemSkipFuncOf(mt.Value.Type, vOpts)
 
 	// 4. Skip all the keys and values.
 	for x.index < x.maxIndex {
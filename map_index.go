@@ -0,0 +1,246 @@
+package bst
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bstskip"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+// indexedMap returns true if the composed map is followed by the directory of its entries.
+func (x *Composer) indexedMap() bool {
+	mt, ok := x.baseType.(*bsttype.Map)
+	return ok && mt.Indexed && !x.opts.Comparable
+}
+
+// verifyIndexedMap checks if the entries of the map could be located by its directory.
+// The boolean keys followed by the boolean values are packed into shared bytes, thus they have no own offsets.
+func verifyIndexedMap(mt *bsttype.Map) error {
+	if derefNamedType(mt.Key.Type).Kind() == bsttype.KindBoolean && derefNamedType(mt.Value.Type).Kind() == bsttype.KindBoolean {
+		return bsterr.Err(bsterr.CodeInvalidType, "indexed map could not have both boolean keys and values").
+			WithDetail("type", mt)
+	}
+	return nil
+}
+
+// recordMapEntry records the directory entry of the indexed map entry, once its key is written.
+func (x *Composer) recordMapEntry() error {
+	if !x.indexedMap() {
+		return nil
+	}
+	sb, ok := x.w.(*iopool.SharedBuffer)
+	if !ok {
+		return bsterr.Err(bsterr.CodeWritingFailed, "indexed map entries are not buffered")
+	}
+
+	// 1. The entry written after the value starts at the end of the buffered entries.
+	if !x.isKey {
+		x.mapEntryStart = sb.Len()
+		return nil
+	}
+
+	// 2. The written key is located by the hash of its binary.
+	e, err := bstio.NewMapDirectoryEntry(sb.Bytes[x.mapEntryStart:], x.mapEntryStart)
+	if err != nil {
+		return err
+	}
+	x.mapDir = append(x.mapDir, e)
+	return nil
+}
+
+// ReadMapKey reads the value of the map entry of given key, by the fn called with the map extractor positioned
+// at the entry value. The key is compared by its binary, encoded in the format of the map keys.
+// The entry of the indexed map is found by its directory, without reading the other entries, while the entries
+// of other maps are scanned. It returns false without calling the fn, if the key is not found.
+func (x *Extractor) ReadMapKey(key bstvalue.Value, fn func(x *Extractor) error) (bool, error) {
+	return x.readMapEntry(func(_ bsttype.Type, o bstio.ValueOptions) ([]byte, error) {
+		kb, err := key.MarshalValue(o)
+		if err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to encode map key")
+		}
+		return kb, nil
+	}, fn)
+}
+
+// FindMapEntry reads the value of the map entry of the key written by the writeKey, just as the ReadMapKey does.
+// The key is composed of the map key type, in the format of the map keys, and compared by its binary, which is
+// deterministic for the map key types. The scan stops at the matching key, and the remaining entries are skipped
+// without being decoded.
+func (x *Extractor) FindMapEntry(writeKey func(c *Composer) error, fn func(x *Extractor) error) (bool, error) {
+	return x.readMapEntry(func(kt bsttype.Type, o bstio.ValueOptions) ([]byte, error) {
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, kt, ComposerOptions{
+			Descending:                o.Descending,
+			Comparable:                o.Comparable,
+			CompatibilityMode:         o.CompatibilityMode,
+			LengthPrefixedCollections: o.LengthPrefixed,
+			OrderedFloats:             o.OrderedFloats,
+			Modules:                   x.opts.Modules,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = writeKey(c); err != nil {
+			return nil, err
+		}
+		if err = c.Close(); err != nil {
+			return nil, err
+		}
+
+		// The key binary follows the single byte header of the composer, as its type is not embedded.
+		return buf.Bytes()[1:], nil
+	}, fn)
+}
+
+// readMapEntry reads the value of the map entry of the key encoded by the encodeKey, with given key type and options.
+func (x *Extractor) readMapEntry(encodeKey func(kt bsttype.Type, o bstio.ValueOptions) ([]byte, error), fn func(x *Extractor) error) (bool, error) {
+	var found bool
+	err := x.ReadMap(func(mx *Extractor) error {
+		var err error
+		if found, err = mx.seekMapKey(encodeKey); err != nil || !found {
+			return err
+		}
+		return fn(mx)
+	})
+	return found, err
+}
+
+// seekMapKey positions the map extractor at the value of the entry of the key encoded by the encodeKey.
+func (x *Extractor) seekMapKey(encodeKey func(kt bsttype.Type, o bstio.ValueOptions) ([]byte, error)) (bool, error) {
+	// 1. Encode the key as it is written in the map.
+	mt := x.embedType.(*bsttype.Map)
+	kOpts := bstio.ValueOptions{
+		Descending:        x.opts.Descending,
+		Comparable:        x.opts.Comparable,
+		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    x.opts.LengthPrefixedCollections,
+		OrderedFloats:     x.opts.OrderedFloats,
+	}
+	if mt.Key.Descending {
+		kOpts.Descending = !kOpts.Descending
+	}
+	kt, err := x.derefType(mt.Key.Type)
+	if err != nil {
+		return false, err
+	}
+	kb, err := encodeKey(kt, kOpts)
+	if err != nil {
+		return false, err
+	}
+
+	// 2. The entries of the non-indexed map are scanned, until the key is found.
+	if x.mapEnd == 0 {
+		for x.Next() {
+			start, err := x.r.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return false, err
+			}
+			n, err := x.Skip()
+			if err != nil {
+				return false, err
+			}
+			if eq, err := x.mapKeyEquals(kb, start, n); err != nil || eq {
+				return eq, err
+			}
+			if _, err = x.Skip(); err != nil {
+				return false, err
+			}
+		}
+		return false, x.Err()
+	}
+
+	// 3. The entries of the indexed map are located by the key hash, and their keys are compared,
+	//    as the hashes could collide.
+	pos, err := x.r.Seek(x.mapEnd-int64(x.maxIndex+1)*bstio.MapDirectoryEntrySize, io.SeekStart)
+	if err != nil {
+		return false, err
+	}
+	offsets, err := bstio.SearchMapDirectory(x.r, uint(x.maxIndex+1), bstio.MapKeyHash(kb))
+	if err != nil {
+		return false, err
+	}
+	skipKey := bstskip.ElemSkipFuncOf(mt.Key.Type, kOpts)
+	for _, offset := range offsets {
+		start, err := x.r.Seek(x.mapEntries+int64(offset), io.SeekStart)
+		if err != nil {
+			return false, err
+		}
+		n, err := skipKey(x.r, kOpts)
+		if err != nil {
+			return false, err
+		}
+		eq, err := x.mapKeyEquals(kb, start, n)
+		if err != nil {
+			return false, err
+		}
+		if !eq {
+			continue
+		}
+
+		// 3.1. Position the extractor at the value of the last entry, so that the map is finished once it is read.
+		x.bytesRead += int(start + n - pos)
+		x.index, x.isKey, x.keyDone, x.elemDone = x.maxIndex, true, false, false
+		x.finishMapElem()
+		return true, x.err
+	}
+
+	// 4. Restore the position of the extractor, so that the map could be finished.
+	if _, err = x.r.Seek(pos, io.SeekStart); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// mapKeyEquals compares the key binary with the n bytes long key at the start offset, read just before.
+// The reader is positioned back after the read key.
+func (x *Extractor) mapKeyEquals(kb []byte, start, n int64) (bool, error) {
+	if n != int64(len(kb)) {
+		return false, nil
+	}
+	if _, err := x.r.Seek(start, io.SeekStart); err != nil {
+		return false, err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(x.r, buf); err != nil {
+		return false, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read map key")
+	}
+	return bytes.Equal(buf, kb), nil
+}
+
+// initializeIndexedMap reads the binary size of the entries of the indexed map of given length, and locates
+// its entries and end. The entries along with the directory must fit the remaining input.
+func (x *Extractor) initializeIndexedMap(length uint) error {
+	size, n, err := bstio.ReadMapEntriesSize(x.r, length, x.opts.Descending)
+	if err != nil {
+		return err
+	}
+	x.bytesRead += n
+	if x.mapEntries, err = x.r.Seek(0, io.SeekCurrent); err != nil {
+		return err
+	}
+	x.mapEnd = x.mapEntries + int64(size) + int64(length)*bstio.MapDirectoryEntrySize
+	return nil
+}
+
+// finishIndexedMap positions the reader at the end of the indexed map, skipping its remaining entries
+// along with the directory at once.
+func (x *Extractor) finishIndexedMap() error {
+	pos, err := x.r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		x.err = err
+		return err
+	}
+	if _, err = x.r.Seek(x.mapEnd, io.SeekStart); err != nil {
+		x.err = err
+		return err
+	}
+	x.bytesRead += int(x.mapEnd - pos)
+	x.baseDone = true
+	x.elemDone = true
+	return nil
+}
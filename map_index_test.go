@@ -0,0 +1,345 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestExtractorReadMapKey(t *testing.T) {
+	const entries = 100
+	key := func(i int) string { return fmt.Sprintf("key-%03d", i) }
+	// record composes the struct of the map of given type, followed by the trailing field.
+	record := func(t *testing.T, mt *bsttype.Map, opts ComposerOptions, optLength int) (*bsttype.Struct, []byte) {
+		t.Helper()
+		st := &bsttype.Struct{Fields: []bsttype.StructField{
+			{Index: 1, Name: "entries", Type: mt},
+			{Index: 2, Name: "trailer", Type: bsttype.String()},
+		}}
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		err = c.WriteMap(func(mc *Composer) error {
+			for i := 0; i < entries; i++ {
+				if err := mc.WriteString(key(i)); err != nil {
+					return err
+				}
+				if err := mc.WriteUint32(uint32(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, optLength)
+		if err != nil {
+			t.Fatalf("writing map failed: %v", err)
+		}
+		if err = c.WriteString("end"); err != nil {
+			t.Fatalf("writing trailer failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return st, buf.Bytes()
+	}
+	// readKey reads the value of the key out of the map field by the ReadMapKey.
+	readKey := func(x *Extractor, k string, fn func(mx *Extractor) error) (bool, error) {
+		return x.ReadMapKey(bstvalue.NewStringValue(k), fn)
+	}
+	// findEntry reads the value of the key out of the map field by the FindMapEntry.
+	findEntry := func(x *Extractor, k string, fn func(mx *Extractor) error) (bool, error) {
+		return x.FindMapEntry(func(c *Composer) error { return c.WriteString(k) }, fn)
+	}
+	// lookup reads the value of the key out of the map field, and verifies the trailing field.
+	lookup := func(t *testing.T, st *bsttype.Struct, data []byte, opts ExtractorOptions, k string,
+		read func(x *Extractor, k string, fn func(mx *Extractor) error) (bool, error),
+	) (uint32, bool) {
+		t.Helper()
+		opts.ExpectedType = st
+		x, err := NewExtractor(bytes.NewReader(data), opts)
+		if err != nil {
+			t.Fatalf("creating extractor failed: %v", err)
+		}
+		defer x.Close()
+		var (
+			v     uint32
+			found bool
+		)
+		if !x.Next() {
+			t.Fatalf("map field not found: %v", x.Err())
+		}
+		found, err = read(x, k, func(mx *Extractor) error {
+			v, err = mx.ReadUint32()
+			return err
+		})
+		if err != nil {
+			t.Fatalf("reading map key failed: %v", err)
+		}
+		if !x.Next() {
+			t.Fatalf("trailer field not found: %v", x.Err())
+		}
+		trailer, err := x.ReadString()
+		if err != nil || trailer != "end" {
+			t.Fatalf("unexpected trailer: %q, %v", trailer, err)
+		}
+		return v, found
+	}
+	indexed := &bsttype.Map{Key: bsttype.MapElement{Type: bsttype.String()}, Value: bsttype.MapElement{Type: bsttype.Uint32()}, Indexed: true}
+	plain := &bsttype.Map{Key: bsttype.MapElement{Type: bsttype.String()}, Value: bsttype.MapElement{Type: bsttype.Uint32()}}
+
+	testCases := []struct {
+		name      string
+		mt        *bsttype.Map
+		optLength int
+		desc      bool
+		compat    bool
+		comp      bool
+	}{
+		{name: "Indexed", mt: indexed},
+		{name: "IndexedDefinedLength", mt: indexed, optLength: entries},
+		{name: "IndexedDescending", mt: indexed, desc: true},
+		{name: "IndexedCompatibilityMode", mt: indexed, compat: true},
+		{name: "Plain", mt: plain},
+		{name: "PlainComparable", mt: plain, comp: true},
+		{name: "IndexedComparableDescending", mt: indexed, comp: true, desc: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st, data := record(t, tc.mt, ComposerOptions{Descending: tc.desc, CompatibilityMode: tc.compat, Comparable: tc.comp}, tc.optLength)
+			opts := ExtractorOptions{Descending: tc.desc, CompatibilityMode: tc.compat, Comparable: tc.comp}
+			for _, read := range []func(x *Extractor, k string, fn func(mx *Extractor) error) (bool, error){readKey, findEntry} {
+				for _, i := range []int{0, 42, entries - 1} {
+					v, found := lookup(t, st, data, opts, key(i), read)
+					if !found || v != uint32(i) {
+						t.Fatalf("unexpected value of %s: %d, %v", key(i), v, found)
+					}
+				}
+				if _, found := lookup(t, st, data, opts, "missing", read); found {
+					t.Fatal("unexpected missing key found")
+				}
+			}
+
+			// The map is read entry by entry, and skipped as a whole, as well.
+			x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: st, Descending: tc.desc, CompatibilityMode: tc.compat, Comparable: tc.comp})
+			if err != nil {
+				t.Fatalf("creating extractor failed: %v", err)
+			}
+			defer x.Close()
+			var n int
+			x.Next()
+			err = x.ReadMap(func(mx *Extractor) error {
+				for mx.Next() {
+					k, err := mx.ReadString()
+					if err != nil {
+						return err
+					}
+					mx.Next()
+					v, err := mx.ReadUint32()
+					if err != nil {
+						return err
+					}
+					if k != key(int(v)) {
+						return fmt.Errorf("unexpected entry: %s: %d", k, v)
+					}
+					n++
+				}
+				return mx.Err()
+			})
+			if err != nil || n != entries {
+				t.Fatalf("reading map failed: %d entries, %v", n, err)
+			}
+			if x.Next(); x.Index() != 1 {
+				t.Fatalf("unexpected field index: %d", x.Index())
+			}
+			x.Close()
+
+			x, err = NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: st, Descending: tc.desc, CompatibilityMode: tc.compat, Comparable: tc.comp})
+			if err != nil {
+				t.Fatalf("creating extractor failed: %v", err)
+			}
+			x.Next()
+			if _, err = x.Skip(); err != nil {
+				t.Fatalf("skipping map failed: %v", err)
+			}
+			x.Next()
+			if trailer, err := x.ReadString(); err != nil || trailer != "end" {
+				t.Fatalf("unexpected trailer: %q, %v", trailer, err)
+			}
+		})
+	}
+
+	t.Run("MapValue", func(t *testing.T) {
+		// The indexed map value is encoded the same way by the composer and the map value.
+		_, data := record(t, indexed, ComposerOptions{}, 0)
+		mv := bstvalue.EmptyMapValue(indexed)
+		for i := 0; i < entries; i++ {
+			if err := mv.Put(bstvalue.NewStringValue(key(i)), bstvalue.NewUint32Value(uint32(i))); err != nil {
+				t.Fatalf("putting map entry failed: %v", err)
+			}
+		}
+		mb, err := mv.MarshalValue(bstio.ValueOptions{})
+		if err != nil {
+			t.Fatalf("marshaling map value failed: %v", err)
+		}
+		if !bytes.Contains(data, mb) {
+			t.Fatalf("map value binary differs from the composed one")
+		}
+		rv := bstvalue.EmptyMapValue(indexed)
+		if err = rv.UnmarshalValue(mb, bstio.ValueOptions{}); err != nil || rv.Len() != entries {
+			t.Fatalf("unmarshaling map value failed: %d entries, %v", rv.Len(), err)
+		}
+	})
+
+	t.Run("Booleans", func(t *testing.T) {
+		mt := &bsttype.Map{Key: bsttype.MapElement{Type: bsttype.Boolean()}, Value: bsttype.MapElement{Type: bsttype.Boolean()}, Indexed: true}
+		c, err := NewComposer(&bytes.Buffer{}, mt, ComposerOptions{})
+		if err == nil {
+			err = c.WriteBoolean(true)
+		}
+		if err == nil {
+			t.Fatal("expected boolean indexed map error")
+		}
+	})
+
+	t.Run("StructKeys", func(t *testing.T) {
+		kt := &bsttype.Struct{Fields: []bsttype.StructField{
+			{Index: 1, Name: "name", Type: bsttype.String()},
+			{Index: 2, Name: "score", Type: bsttype.Float64()},
+			{Index: 3, Name: "groups", Type: bsttype.ArrayOf(bsttype.ArrayOf(bsttype.String()))},
+		}}
+		mt := &bsttype.Map{Key: bsttype.MapElement{Type: kt}, Value: bsttype.MapElement{Type: bsttype.Uint32()}, Indexed: true}
+		st := &bsttype.Struct{Fields: []bsttype.StructField{{Index: 1, Name: "entries", Type: mt}}}
+		// writeKey writes the fields of the struct key of the i-th entry, whose nested arrays are prefixed
+		// with their sizes.
+		writeKey := func(i int) func(c *Composer) error {
+			return func(c *Composer) error {
+				if err := c.WriteString(key(i)); err != nil {
+					return err
+				}
+				if err := c.WriteFloat64(-float64(i) - 0.5); err != nil {
+					return err
+				}
+				return c.WriteArray(func(ac *Composer) error {
+					return ac.WriteArray(func(gc *Composer) error {
+						return gc.WriteString(key(i))
+					}, 1)
+				}, 1)
+			}
+		}
+
+		// 1. Compose the map in the compatibility mode, with the length prefixed collections and the ordered floats.
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, ComposerOptions{CompatibilityMode: true, LengthPrefixedCollections: true, OrderedFloats: true})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		err = c.WriteMap(func(mc *Composer) error {
+			for i := 0; i < 10; i++ {
+				if err := mc.WriteStruct(writeKey(i)); err != nil {
+					return err
+				}
+				if err := mc.WriteUint32(uint32(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, 0)
+		if err != nil {
+			t.Fatalf("writing map failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+
+		// 2. The probe keys are composed with the same options, thus match the written ones.
+		for _, i := range []int{0, 7} {
+			x, err := NewExtractor(bytes.NewReader(buf.Bytes()), ExtractorOptions{
+				ExpectedType:              st,
+				CompatibilityMode:         true,
+				LengthPrefixedCollections: true,
+				OrderedFloats:             true,
+			})
+			if err != nil {
+				t.Fatalf("creating extractor failed: %v", err)
+			}
+			if !x.Next() {
+				t.Fatalf("map field not found: %v", x.Err())
+			}
+			var v uint32
+			found, err := x.FindMapEntry(writeKey(i), func(mx *Extractor) error {
+				v, err = mx.ReadUint32()
+				return err
+			})
+			x.Close()
+			if err != nil || !found || v != uint32(i) {
+				t.Fatalf("unexpected value of key %d: %d, %v, %v", i, v, found, err)
+			}
+		}
+	})
+
+	t.Run("EntriesSize", func(t *testing.T) {
+		hasCode := func(err error, code bsterr.ErrCode) bool {
+			var be *bsterr.Error
+			return errors.As(err, &be) && be.Code == code
+		}
+		st, data := record(t, indexed, ComposerOptions{}, 0)
+
+		// The map of 100 entries is preceded by the struct header and its two bytes length.
+		const sizeOffset = 3
+		size, sn, err := bstio.ReadUint(bytes.NewReader(data[sizeOffset:]), false)
+		if err != nil {
+			t.Fatalf("reading entries size failed: %v", err)
+		}
+		// corrupt replaces the binary size of the map entries.
+		corrupt := func(size uint) []byte {
+			var buf bytes.Buffer
+			buf.Write(data[:sizeOffset])
+			if _, err := bstio.WriteUint(&buf, size, false); err != nil {
+				t.Fatalf("writing entries size failed: %v", err)
+			}
+			buf.Write(data[sizeOffset+sn:])
+			return buf.Bytes()
+		}
+		for _, tc := range []struct {
+			name string
+			size uint
+			code bsterr.ErrCode
+		}{
+			{name: "Exceeding", size: size + 100, code: bsterr.CodeTruncatedBinary},
+			{name: "Overflowing", size: math.MaxInt - 1, code: bsterr.CodeMalformedBinary},
+			{name: "Max", size: math.MaxUint, code: bsterr.CodeMalformedBinary},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				d := corrupt(tc.size)
+				x, err := NewExtractor(bytes.NewReader(d), ExtractorOptions{ExpectedType: st})
+				if err != nil {
+					t.Fatalf("creating extractor failed: %v", err)
+				}
+				defer x.Close()
+				x.Next()
+				if err = x.ReadMap(func(mx *Extractor) error { return nil }); !hasCode(err, tc.code) {
+					t.Fatalf("expected %v error of reading map, got: %v", tc.code, err)
+				}
+
+				// The map is skipped with the same check.
+				x, err = NewExtractor(bytes.NewReader(d), ExtractorOptions{ExpectedType: st})
+				if err != nil {
+					t.Fatalf("creating extractor failed: %v", err)
+				}
+				defer x.Close()
+				x.Next()
+				if _, err = x.Skip(); !hasCode(err, tc.code) {
+					t.Fatalf("expected %v error of skipping map, got: %v", tc.code, err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,704 @@
+package bst
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+// Marshal encodes the Go struct (or a pointer to it) with given options. The struct type is derived out of the Go type
+// by the StructTypeOf, and the values are written in the order of the struct fields.
+func Marshal(v any, opts ...Option) ([]byte, error) {
+	// 1. Validate the options.
+	o, err := NewOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Dereference the value and get its struct mapping.
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "marshaled value needs to be a struct").
+			WithDetail("type", reflect.TypeOf(v))
+	}
+	ms, err := marshalStructOf(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Compose the struct fields.
+	var buf bytes.Buffer
+	c, err := NewComposer(&buf, ms.t, o.ComposerOptions())
+	if err != nil {
+		return nil, err
+	}
+	if err = ms.writeFields(c, rv); err != nil {
+		return nil, err
+	}
+	if err = c.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the binary encoded by the Marshal into the Go struct pointed by v.
+// The encoding options are read from the binary header, thus the options are needed only to provide
+// the modules or the blob store. The struct fields which are not present in the binary are left unchanged.
+func Unmarshal(data []byte, v any, opts ...Option) error {
+	// 1. Validate the options.
+	o, err := NewOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	// 2. The value needs to be a non-nil pointer to the struct.
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return bsterr.Err(bsterr.CodeInvalidValue, "unmarshaled value needs to be a non-nil pointer to a struct").
+			WithDetail("type", reflect.TypeOf(v))
+	}
+	ms, err := marshalStructOf(rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+
+	// 3. Extract the struct fields.
+	r := iopool.GetReadSeeker(data)
+	defer iopool.ReleaseReadSeeker(r)
+
+	x, err := NewExtractor(r, o.ExtractorOptions(ms.t))
+	if err != nil {
+		return err
+	}
+	defer x.Close()
+
+	return ms.readFields(x, rv.Elem())
+}
+
+// StructTypeOf derives the struct type out of the Go struct (or a pointer to it), as used by the Marshal.
+// Each exported field is mapped by its `bst:"name,index=3,desc"` tag, where:
+//   - name - is the name of the field, by default the name of the Go field,
+//   - index - is the index of the field, by default the highest index of the preceding fields increased by one,
+//   - desc - determines that the field is encoded in descending order.
+//
+// The struct fields are ordered by their indices, rather than by the order of the Go fields.
+// The fields tagged with `bst:"-"` are omitted. The pointers are mapped to the Nullable types, the slices and arrays
+// to the Array types (except the []byte, which is mapped to the Bytes), the time.Time to the Timestamp,
+// the time.Duration to the Duration, the bstio.Decimal to the Decimal and the [16]byte based types named UUID,
+// i.e. the bstio.UUID or github.com/google/uuid.UUID, to the UUID. The mapping is cached, thus the returned type should not be modified.
+func StructTypeOf(v any) (*bsttype.Struct, error) {
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "struct type is required").WithDetail("type", rt)
+	}
+	ms, err := marshalStructOf(rt)
+	if err != nil {
+		return nil, err
+	}
+	return ms.t, nil
+}
+
+var (
+	_timeType     = reflect.TypeOf(time.Time{})
+	_durationType = reflect.TypeOf(time.Duration(0))
+	_decimalType  = reflect.TypeOf(bstio.Decimal{})
+	_uuidType     = reflect.TypeOf(bstio.UUID{})
+
+	// _marshalStructs is the cache of the struct mappings by their Go types.
+	_marshalStructs sync.Map
+)
+
+// marshalCodec is the mapping of the Go type into the bsttype.Type, along with the functions which write and read
+// its values.
+type marshalCodec struct {
+	t     bsttype.Type
+	write func(c *Composer, v reflect.Value) error
+	read  func(x *Extractor, v reflect.Value) error
+}
+
+// marshalStruct is the mapping of the Go struct type into the bsttype.Struct.
+type marshalStruct struct {
+	t      *bsttype.Struct
+	fields []marshalField
+}
+
+// marshalField is the mapping of the Go struct field, where the goIndex is its index within the Go struct.
+type marshalField struct {
+	goIndex int
+	codec   *marshalCodec
+}
+
+// marshalStructOf returns the cached mapping of the Go struct type, or creates a new one.
+func marshalStructOf(rt reflect.Type) (*marshalStruct, error) {
+	if ms, ok := _marshalStructs.Load(rt); ok {
+		return ms.(*marshalStruct), nil
+	}
+	ms, err := newMarshalStruct(rt, map[reflect.Type]struct{}{})
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := _marshalStructs.LoadOrStore(rt, ms)
+	return actual.(*marshalStruct), nil
+}
+
+// newMarshalStruct creates the mapping of the Go struct type. The seen set contains the struct types being mapped,
+// so that the recursive struct types are detected.
+func newMarshalStruct(rt reflect.Type, seen map[reflect.Type]struct{}) (*marshalStruct, error) {
+	// 1. The recursive struct types could not be mapped, as the Struct type has no references.
+	if _, ok := seen[rt]; ok {
+		return nil, bsterr.Err(bsterr.CodeCyclicDependency, "recursive struct type could not be marshaled").
+			WithDetail("type", rt)
+	}
+	seen[rt] = struct{}{}
+	defer delete(seen, rt)
+
+	// 2. Map each exported field.
+	ms := &marshalStruct{t: &bsttype.Struct{}}
+	var maxIndex uint
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		// 2.1. Parse the field tag.
+		field, skip, err := parseMarshalTag(sf)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			continue
+		}
+		if field.Index == 0 {
+			field.Index = maxIndex + 1
+		}
+		maxIndex = max(maxIndex, field.Index)
+
+		// 2.2. Map the field type.
+		codec, err := newMarshalCodec(sf.Type, seen)
+		if err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeTypeNotMapped, "failed to map struct field").
+				WithDetails(bsterr.D("type", rt), bsterr.D("field", sf.Name))
+		}
+		field.Type = codec.t
+		ms.t.Fields = append(ms.t.Fields, field)
+		ms.fields = append(ms.fields, marshalField{goIndex: i, codec: codec})
+	}
+
+	// 3. Verify that the field indices are unique.
+	if err := ms.t.VerifyFieldIndices(); err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeInvalidType, "invalid struct field indices").
+			WithDetail("type", rt)
+	}
+
+	// 4. The struct fields are encoded in the order of their indices.
+	sort.Sort(ms)
+	return ms, nil
+}
+
+// parseMarshalTag parses the `bst:"name,index=3,desc"` tag of the struct field.
+func parseMarshalTag(sf reflect.StructField) (field bsttype.StructField, skip bool, err error) {
+	tag := sf.Tag.Get("bst")
+	if tag == "-" {
+		return field, true, nil
+	}
+
+	// 1. The first part is the name of the field.
+	parts := strings.Split(tag, ",")
+	field.Name = parts[0]
+	if field.Name == "" {
+		field.Name = sf.Name
+	}
+
+	// 2. The rest are the field options.
+	for _, part := range parts[1:] {
+		switch {
+		case part == "desc":
+			field.Descending = true
+		case strings.HasPrefix(part, "index="):
+			index, err := strconv.ParseUint(strings.TrimPrefix(part, "index="), 10, 64)
+			if err != nil || index == 0 {
+				return field, false, bsterr.Err(bsterr.CodeInvalidValue, "invalid struct field tag index").
+					WithDetails(bsterr.D("field", sf.Name), bsterr.D("tag", tag))
+			}
+			field.Index = uint(index)
+		default:
+			return field, false, bsterr.Err(bsterr.CodeInvalidValue, "unknown struct field tag option").
+				WithDetails(bsterr.D("field", sf.Name), bsterr.D("option", part))
+		}
+	}
+	return field, false, nil
+}
+
+// Len implements the sort.Interface, which orders the fields by their indices.
+func (x *marshalStruct) Len() int {
+	return len(x.fields)
+}
+
+// Less implements the sort.Interface, which orders the fields by their indices.
+func (x *marshalStruct) Less(i, j int) bool {
+	return x.t.Fields[i].Index < x.t.Fields[j].Index
+}
+
+// Swap implements the sort.Interface, which orders the fields by their indices.
+func (x *marshalStruct) Swap(i, j int) {
+	x.t.Fields[i], x.t.Fields[j] = x.t.Fields[j], x.t.Fields[i]
+	x.fields[i], x.fields[j] = x.fields[j], x.fields[i]
+}
+
+// writeFields writes the fields of the struct value v, into the composer of the struct.
+func (x *marshalStruct) writeFields(c *Composer, v reflect.Value) error {
+	for _, f := range x.fields {
+		if err := f.codec.write(c, v.Field(f.goIndex)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFields reads the fields of the struct extractor into the struct value v.
+func (x *marshalStruct) readFields(xt *Extractor, v reflect.Value) error {
+	for xt.Next() {
+		f := x.fields[xt.Index()]
+		if err := f.codec.read(xt, v.Field(f.goIndex)); err != nil {
+			return err
+		}
+	}
+	return xt.Err()
+}
+
+// isUUIDType checks if the Go type is the [16]byte based type named UUID, i.e. the bstio.UUID
+// or github.com/google/uuid.UUID.
+func isUUIDType(rt reflect.Type) bool {
+	return rt.Kind() == reflect.Array && rt.Len() == 16 && rt.Elem().Kind() == reflect.Uint8 && rt.Name() == "UUID"
+}
+
+// newMarshalCodec creates the mapping of the Go type.
+func newMarshalCodec(rt reflect.Type, seen map[reflect.Type]struct{}) (*marshalCodec, error) {
+	// 1. The well-known struct and integer types are mapped first.
+	switch rt {
+	case _timeType:
+		return &marshalCodec{
+			t: bsttype.Timestamp(),
+			write: func(c *Composer, v reflect.Value) error {
+				return c.WriteTimestamp(v.Interface().(time.Time))
+			},
+			read: func(x *Extractor, v reflect.Value) error {
+				tv, err := x.ReadTimestamp()
+				v.Set(reflect.ValueOf(tv))
+				return err
+			},
+		}, nil
+	case _durationType:
+		return &marshalCodec{
+			t: bsttype.Duration(),
+			write: func(c *Composer, v reflect.Value) error {
+				return c.WriteDuration(time.Duration(v.Int()))
+			},
+			read: func(x *Extractor, v reflect.Value) error {
+				d, err := x.ReadDuration()
+				v.SetInt(int64(d))
+				return err
+			},
+		}, nil
+	case _decimalType:
+		return &marshalCodec{
+			t: bsttype.Decimal(),
+			write: func(c *Composer, v reflect.Value) error {
+				return c.WriteDecimal(v.Interface().(bstio.Decimal))
+			},
+			read: func(x *Extractor, v reflect.Value) error {
+				d, err := x.ReadDecimal()
+				v.Set(reflect.ValueOf(d))
+				return err
+			},
+		}, nil
+	}
+	if isUUIDType(rt) {
+		return &marshalCodec{
+			t: bsttype.UUID(),
+			write: func(c *Composer, v reflect.Value) error {
+				return c.WriteUUID(v.Convert(_uuidType).Interface().(bstio.UUID))
+			},
+			read: func(x *Extractor, v reflect.Value) error {
+				u, err := x.ReadUUID()
+				v.Set(reflect.ValueOf(u).Convert(v.Type()))
+				return err
+			},
+		}, nil
+	}
+
+	// 2. Map the type by its kind.
+	switch rt.Kind() {
+	case reflect.Bool:
+		return &marshalCodec{
+			t: bsttype.Boolean(),
+			write: func(c *Composer, v reflect.Value) error {
+				return c.WriteBoolean(v.Bool())
+			},
+			read: func(x *Extractor, v reflect.Value) error {
+				b, err := x.ReadBoolean()
+				v.SetBool(b)
+				return err
+			},
+		}, nil
+	case reflect.Int8:
+		return intMarshalCodec(bsttype.Int8(), func(c *Composer, i int64) error { return c.WriteInt8(int8(i)) },
+			func(x *Extractor) (int64, error) { i, err := x.ReadInt8(); return int64(i), err }), nil
+	case reflect.Int16:
+		return intMarshalCodec(bsttype.Int16(), func(c *Composer, i int64) error { return c.WriteInt16(int16(i)) },
+			func(x *Extractor) (int64, error) { i, err := x.ReadInt16(); return int64(i), err }), nil
+	case reflect.Int32:
+		return intMarshalCodec(bsttype.Int32(), func(c *Composer, i int64) error { return c.WriteInt32(int32(i)) },
+			func(x *Extractor) (int64, error) { i, err := x.ReadInt32(); return int64(i), err }), nil
+	case reflect.Int64:
+		return intMarshalCodec(bsttype.Int64(), func(c *Composer, i int64) error { return c.WriteInt64(i) },
+			func(x *Extractor) (int64, error) { return x.ReadInt64() }), nil
+	case reflect.Int:
+		return intMarshalCodec(bsttype.Int(), func(c *Composer, i int64) error { return c.WriteInt(int(i)) },
+			func(x *Extractor) (int64, error) { i, err := x.ReadInt(); return int64(i), err }), nil
+	case reflect.Uint8:
+		return uintMarshalCodec(bsttype.Uint8(), func(c *Composer, u uint64) error { return c.WriteUint8(uint8(u)) },
+			func(x *Extractor) (uint64, error) { u, err := x.ReadUint8(); return uint64(u), err }), nil
+	case reflect.Uint16:
+		return uintMarshalCodec(bsttype.Uint16(), func(c *Composer, u uint64) error { return c.WriteUint16(uint16(u)) },
+			func(x *Extractor) (uint64, error) { u, err := x.ReadUint16(); return uint64(u), err }), nil
+	case reflect.Uint32:
+		return uintMarshalCodec(bsttype.Uint32(), func(c *Composer, u uint64) error { return c.WriteUint32(uint32(u)) },
+			func(x *Extractor) (uint64, error) { u, err := x.ReadUint32(); return uint64(u), err }), nil
+	case reflect.Uint64:
+		return uintMarshalCodec(bsttype.Uint64(), func(c *Composer, u uint64) error { return c.WriteUint64(u) },
+			func(x *Extractor) (uint64, error) { return x.ReadUint64() }), nil
+	case reflect.Uint:
+		return uintMarshalCodec(bsttype.Uint(), func(c *Composer, u uint64) error { return c.WriteUint(uint(u)) },
+			func(x *Extractor) (uint64, error) { u, err := x.ReadUint(); return uint64(u), err }), nil
+	case reflect.Float32:
+		return &marshalCodec{
+			t: bsttype.Float32(),
+			write: func(c *Composer, v reflect.Value) error {
+				return c.WriteFloat32(float32(v.Float()))
+			},
+			read: func(x *Extractor, v reflect.Value) error {
+				f, err := x.ReadFloat32()
+				v.SetFloat(float64(f))
+				return err
+			},
+		}, nil
+	case reflect.Float64:
+		return &marshalCodec{
+			t: bsttype.Float64(),
+			write: func(c *Composer, v reflect.Value) error {
+				return c.WriteFloat64(v.Float())
+			},
+			read: func(x *Extractor, v reflect.Value) error {
+				f, err := x.ReadFloat64()
+				v.SetFloat(f)
+				return err
+			},
+		}, nil
+	case reflect.String:
+		return &marshalCodec{
+			t: bsttype.String(),
+			write: func(c *Composer, v reflect.Value) error {
+				return c.WriteString(v.String())
+			},
+			read: func(x *Extractor, v reflect.Value) error {
+				s, err := x.ReadString()
+				v.SetString(s)
+				return err
+			},
+		}, nil
+	case reflect.Slice:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			return bytesMarshalCodec(), nil
+		}
+		return arrayMarshalCodec(rt, seen)
+	case reflect.Array:
+		return arrayMarshalCodec(rt, seen)
+	case reflect.Map:
+		return mapMarshalCodec(rt, seen)
+	case reflect.Pointer:
+		return nullableMarshalCodec(rt, seen)
+	case reflect.Struct:
+		ms, err := newMarshalStruct(rt, seen)
+		if err != nil {
+			return nil, err
+		}
+		return &marshalCodec{
+			t: ms.t,
+			write: func(c *Composer, v reflect.Value) error {
+				return c.WriteStruct(func(sc *Composer) error {
+					return ms.writeFields(sc, v)
+				})
+			},
+			read: func(x *Extractor, v reflect.Value) error {
+				return x.ReadStruct(func(sx *Extractor) error {
+					return ms.readFields(sx, v)
+				})
+			},
+		}, nil
+	}
+	return nil, bsterr.Err(bsterr.CodeTypeNotMapped, "go type could not be marshaled").WithDetail("type", rt)
+}
+
+// intMarshalCodec creates the mapping of the signed integer kind.
+func intMarshalCodec(t bsttype.Type, write func(c *Composer, i int64) error, read func(x *Extractor) (int64, error)) *marshalCodec {
+	return &marshalCodec{
+		t: t,
+		write: func(c *Composer, v reflect.Value) error {
+			return write(c, v.Int())
+		},
+		read: func(x *Extractor, v reflect.Value) error {
+			i, err := read(x)
+			v.SetInt(i)
+			return err
+		},
+	}
+}
+
+// uintMarshalCodec creates the mapping of the unsigned integer kind.
+func uintMarshalCodec(t bsttype.Type, write func(c *Composer, u uint64) error, read func(x *Extractor) (uint64, error)) *marshalCodec {
+	return &marshalCodec{
+		t: t,
+		write: func(c *Composer, v reflect.Value) error {
+			return write(c, v.Uint())
+		},
+		read: func(x *Extractor, v reflect.Value) error {
+			u, err := read(x)
+			v.SetUint(u)
+			return err
+		},
+	}
+}
+
+// bytesMarshalCodec creates the mapping of the byte slice.
+func bytesMarshalCodec() *marshalCodec {
+	return &marshalCodec{
+		t: &bsttype.Bytes{},
+		write: func(c *Composer, v reflect.Value) error {
+			return c.WriteBytes(v.Bytes())
+		},
+		read: func(x *Extractor, v reflect.Value) error {
+			b, err := x.ReadBytes()
+			if err != nil {
+				return err
+			}
+			v.SetBytes(append([]byte(nil), b...))
+			return nil
+		},
+	}
+}
+
+// arrayMarshalCodec creates the mapping of the slice or the array. The Go array is mapped to the fixed size Array.
+func arrayMarshalCodec(rt reflect.Type, seen map[reflect.Type]struct{}) (*marshalCodec, error) {
+	elem, err := newMarshalCodec(rt.Elem(), seen)
+	if err != nil {
+		return nil, err
+	}
+	fixed := rt.Kind() == reflect.Array
+
+	mc := &marshalCodec{
+		t: bsttype.ArrayOf(elem.t),
+		write: func(c *Composer, v reflect.Value) error {
+			return c.WriteArray(func(ac *Composer) error {
+				for i := 0; i < v.Len(); i++ {
+					if err := elem.write(ac, v.Index(i)); err != nil {
+						return err
+					}
+				}
+				return nil
+			}, v.Len())
+		},
+		read: func(x *Extractor, v reflect.Value) error {
+			return x.ReadArray(func(ax *Extractor) error {
+				// 1. The slice is replaced with a new one, of the extracted length.
+				if !fixed {
+					v.Set(reflect.MakeSlice(rt, ax.Length(), ax.Length()))
+				}
+				for ax.Next() {
+					if ax.Index() >= v.Len() {
+						return bsterr.Err(bsterr.CodeOutOfBounds, "array index is out of bounds").
+							WithDetails(bsterr.D("index", ax.Index()), bsterr.D("length", v.Len()))
+					}
+					if err := elem.read(ax, v.Index(ax.Index())); err != nil {
+						return err
+					}
+				}
+				return ax.Err()
+			})
+		},
+	}
+	if fixed {
+		mc.t = bsttype.FixedSizeArrayOf(elem.t, uint(rt.Len()))
+	}
+	return mc, nil
+}
+
+// mapMarshalCodec creates the mapping of the map. The entries are written in the order of their keys,
+// so that the binary of the map is deterministic.
+func mapMarshalCodec(rt reflect.Type, seen map[reflect.Type]struct{}) (*marshalCodec, error) {
+	key, err := newMarshalCodec(rt.Key(), seen)
+	if err != nil {
+		return nil, err
+	}
+	value, err := newMarshalCodec(rt.Elem(), seen)
+	if err != nil {
+		return nil, err
+	}
+	mt, err := bsttype.NewMapType(key.t, value.t, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &marshalCodec{
+		t: mt,
+		write: func(c *Composer, v reflect.Value) error {
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return compareMapKeys(keys[i], keys[j]) < 0
+			})
+			return c.WriteMap(func(mc *Composer) error {
+				for _, k := range keys {
+					if err := key.write(mc, k); err != nil {
+						return err
+					}
+					if err := value.write(mc, v.MapIndex(k)); err != nil {
+						return err
+					}
+				}
+				return nil
+			}, len(keys))
+		},
+		read: func(x *Extractor, v reflect.Value) error {
+			return x.ReadMap(func(mx *Extractor) error {
+				v.Set(reflect.MakeMapWithSize(rt, mx.Length()))
+				for mx.Next() {
+					k := reflect.New(rt.Key()).Elem()
+					if err := key.read(mx, k); err != nil {
+						return err
+					}
+					if !mx.Next() {
+						break
+					}
+					e := reflect.New(rt.Elem()).Elem()
+					if err := value.read(mx, e); err != nil {
+						return err
+					}
+					v.SetMapIndex(k, e)
+				}
+				return mx.Err()
+			})
+		},
+	}, nil
+}
+
+// compareMapKeys compares the map keys of the same type. The key types are limited by the bsttype.CanBeMapKey.
+func compareMapKeys(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case b.Bool():
+			return -1
+		}
+		return 1
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch ai, bi := a.Int(), b.Int(); {
+		case ai < bi:
+			return -1
+		case ai > bi:
+			return 1
+		}
+		return 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch au, bu := a.Uint(), b.Uint(); {
+		case au < bu:
+			return -1
+		case au > bu:
+			return 1
+		}
+		return 0
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Pointer:
+		switch {
+		case a.IsNil() && b.IsNil():
+			return 0
+		case a.IsNil():
+			return -1
+		case b.IsNil():
+			return 1
+		}
+		return compareMapKeys(a.Elem(), b.Elem())
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if c := compareMapKeys(a.Index(i), b.Index(i)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if c := compareMapKeys(a.Field(i), b.Field(i)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+	return 0
+}
+
+// nullableMarshalCodec creates the mapping of the pointer, where the nil pointer is the null value.
+func nullableMarshalCodec(rt reflect.Type, seen map[reflect.Type]struct{}) (*marshalCodec, error) {
+	if rt.Elem().Kind() == reflect.Pointer {
+		return nil, bsterr.Err(bsterr.CodeTypeNotMapped, "pointer to pointer could not be marshaled").
+			WithDetail("type", rt)
+	}
+	elem, err := newMarshalCodec(rt.Elem(), seen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &marshalCodec{
+		t: bsttype.NullableOf(elem.t),
+		write: func(c *Composer, v reflect.Value) error {
+			if v.IsNil() {
+				return c.WriteNull()
+			}
+			if err := c.WriteNotNull(); err != nil {
+				return err
+			}
+			return elem.write(c, v.Elem())
+		},
+		read: func(x *Extractor, v reflect.Value) error {
+			isNull, err := x.IsNull()
+			if err != nil {
+				return err
+			}
+			if isNull {
+				v.SetZero()
+				return nil
+			}
+			if v.IsNil() {
+				v.Set(reflect.New(rt.Elem()))
+			}
+			return elem.read(x, v.Elem())
+		},
+	}, nil
+}
@@ -0,0 +1,154 @@
+package bst
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+type marshalAddress struct {
+	City string `bst:"city"`
+	Zip  uint32 `bst:"zip,index=5"`
+}
+
+type marshalUser struct {
+	ID       uint64            `bst:"id,index=1"`
+	Name     string            `bst:"name,index=2"`
+	Score    int32             `bst:",index=4,desc"`
+	Active   bool              `bst:"active,index=3"`
+	Tags     []string          `bst:"tags"`
+	Data     []byte            `bst:"data"`
+	Address  marshalAddress    `bst:"address"`
+	Previous *marshalAddress   `bst:"previous"`
+	Labels   map[string]int64  `bst:"labels"`
+	Created  time.Time         `bst:"created"`
+	TTL      time.Duration     `bst:"ttl"`
+	Ratio    float64           `bst:"ratio"`
+	Pair     [2]int16          `bst:"pair"`
+	Nested   map[uint8][]uint8 `bst:"nested"`
+	Ignored  string            `bst:"-"`
+	internal string
+}
+
+func TestMarshal(t *testing.T) {
+	in := marshalUser{
+		ID:       42,
+		Name:     "john",
+		Score:    -7,
+		Active:   true,
+		Tags:     []string{"a", "b"},
+		Data:     []byte{1, 2, 3},
+		Address:  marshalAddress{City: "Warsaw", Zip: 12345},
+		Previous: &marshalAddress{City: "Cracow", Zip: 30001},
+		Labels:   map[string]int64{"x": 1, "y": -2, "z": 3},
+		Created:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		TTL:      time.Minute,
+		Ratio:    0.25,
+		Pair:     [2]int16{-1, 1},
+		Nested:   map[uint8][]uint8{2: {3}, 1: nil},
+		Ignored:  "ignored",
+		internal: "internal",
+	}
+
+	st, err := StructTypeOf(&in)
+	if err != nil {
+		t.Fatalf("deriving struct type failed: %v", err)
+	}
+	if len(st.Fields) != 14 {
+		t.Fatalf("unexpected number of struct fields: %d", len(st.Fields))
+	}
+	if f := st.Fields[3]; f.Name != "Score" || f.Index != 4 || !f.Descending {
+		t.Fatalf("unexpected tagged field: %+v", f)
+	}
+	if f := st.Fields[4]; f.Name != "tags" || f.Index != 5 {
+		t.Fatalf("unexpected auto indexed field: %+v", f)
+	}
+	if _, ok := st.Fields[7].Type.(*bsttype.Nullable); !ok {
+		t.Fatalf("unexpected pointer field type: %v", st.Fields[7].Type)
+	}
+
+	options := [][]Option{
+		nil,
+		{ForRowStorage()},
+		{ForRPC()},
+		{ForIndexKey(), WithDescending()},
+	}
+	for _, opts := range options {
+		data, err := Marshal(&in, opts...)
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		again, err := Marshal(in, opts...)
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		if !bytes.Equal(data, again) {
+			t.Fatal("marshaled binary is not deterministic")
+		}
+
+		out := marshalUser{Ignored: "kept"}
+		if err = Unmarshal(data, &out, opts...); err != nil {
+			t.Fatalf("unmarshal failed: %v", err)
+		}
+		// The timestamp is extracted in the local time zone.
+		if !out.Created.Equal(in.Created) {
+			t.Fatalf("unexpected unmarshaled timestamp: %v", out.Created)
+		}
+		out.Created = in.Created
+
+		expected := in
+		expected.Ignored, expected.internal = "kept", ""
+		if !reflect.DeepEqual(out, expected) {
+			t.Fatalf("unexpected unmarshaled value:\n%+v\nexpected:\n%+v", out, expected)
+		}
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		for _, opts := range options {
+			data, err := Marshal(marshalUser{}, opts...)
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+			out := marshalUser{Previous: &marshalAddress{City: "Cracow"}}
+			if err = Unmarshal(data, &out, opts...); err != nil {
+				t.Fatalf("unmarshal failed: %v", err)
+			}
+			if out.Previous != nil || len(out.Tags) != 0 || len(out.Labels) != 0 {
+				t.Fatalf("unexpected unmarshaled empty value: %+v", out)
+			}
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		type duplicated struct {
+			A int `bst:"a,index=1"`
+			B int `bst:"b,index=1"`
+		}
+		type unknownOption struct {
+			A int `bst:"a,omitempty"`
+		}
+		type unsupported struct {
+			C chan int
+		}
+		type floatKey struct {
+			M map[float64]int
+		}
+		type recursive struct {
+			Next *recursive
+		}
+		for _, v := range []any{duplicated{}, unknownOption{}, unsupported{}, floatKey{}, recursive{}} {
+			if _, err := Marshal(v); err == nil {
+				t.Fatalf("expected marshal of %T to fail", v)
+			}
+		}
+		if _, err := Marshal(1); err == nil {
+			t.Fatal("expected marshal of non-struct value to fail")
+		}
+		if err := Unmarshal(nil, marshalUser{}); err == nil {
+			t.Fatal("expected unmarshal into non-pointer value to fail")
+		}
+	})
+}
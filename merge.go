@@ -0,0 +1,460 @@
+package bst
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bstskip"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// MergeStrategy defines how the values of a single struct field are combined by the Merge function.
+type MergeStrategy int
+
+const (
+	// MergeLastWriterWins takes the field value of the more recent row.
+	// The recency of a row is determined by the MergeRules.TimestampField.
+	// This is the default strategy for all the fields.
+	MergeLastWriterWins MergeStrategy = iota
+	// MergeMax takes the greater of two numeric values.
+	MergeMax
+	// MergeMin takes the lesser of two numeric values.
+	MergeMin
+	// MergeSum adds two numeric values, which is suitable for the counter fields.
+	// Integer overflows wrap around.
+	MergeSum
+	// MergeUnion combines the elements of two variable length arrays (sets) or two maps.
+	// Duplicated array elements are written once, and the map entries with the same key take
+	// the value of the more recent row.
+	MergeUnion
+)
+
+// String returns a human-readable name of the strategy.
+func (s MergeStrategy) String() string {
+	switch s {
+	case MergeLastWriterWins:
+		return "LastWriterWins"
+	case MergeMax:
+		return "Max"
+	case MergeMin:
+		return "Min"
+	case MergeSum:
+		return "Sum"
+	case MergeUnion:
+		return "Union"
+	default:
+		return "Unknown"
+	}
+}
+
+// MergeRules are the rules used by the Merge function.
+type MergeRules struct {
+	// TimestampField is the name of the field which determines the more recent row.
+	// It needs to be a numeric, timestamp, duration or date time field.
+	// If not defined, the right-hand side row (b) is always treated as the more recent one.
+	TimestampField string
+	// Fields maps the struct field names to their merge strategies.
+	// The fields not defined in here are merged using MergeLastWriterWins strategy.
+	Fields map[string]MergeStrategy
+	// Options are the binary options both input values were encoded with.
+	Options bstio.ValueOptions
+}
+
+// Merge combines two headless struct values a and b of the type t according to given rules.
+// Both values need to be encoded with the same rules.Options, and the result is encoded with them as well.
+// The fields merged with MergeLastWriterWins strategy are copied without being decoded,
+// and only the fields with other strategies are decoded.
+// If the rules.Options.CompatibilityMode is set, the fields missing in one of the rows are taken from the other one.
+func Merge(a, b []byte, t bsttype.Type, rules MergeRules) ([]byte, error) {
+	// 1. Dereference the named type and verify that it is a struct.
+	st, ok := derefNamedType(t).(*bsttype.Struct)
+	if !ok {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "merge is supported only for struct types").
+			WithDetail("type", t)
+	}
+
+	// 2. Validate the rules against the struct type.
+	if err := validateMergeRules(st, rules); err != nil {
+		return nil, err
+	}
+
+	// 3. Split both rows into the field segments.
+	sa, err := splitStructSegmentsOf(a, st, rules.Options)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to split left merge value")
+	}
+	sb, err := splitStructSegmentsOf(b, st, rules.Options)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to split right merge value")
+	}
+
+	// 4. Determine which of the rows is the most recent one.
+	bWins, err := mergeRightWins(st, sa, sb, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. Merge the segments and write the result.
+	var buf bytes.Buffer
+	if rules.Options.CompatibilityMode {
+		err = mergeCompatibilityFields(&buf, st, sa, sb, bWins, rules)
+	} else {
+		err = mergeFields(&buf, st, sa, sb, bWins, rules)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func validateMergeRules(st *bsttype.Struct, rules MergeRules) error {
+	// 1. Check if the timestamp field exists.
+	if rules.TimestampField != "" {
+		if _, _, found := st.FieldByName(rules.TimestampField); !found {
+			return bsterr.Err(bsterr.CodeInvalidValue, "merge timestamp field not found").
+				WithDetail("field", rules.TimestampField)
+		}
+	}
+
+	// 2. Check if all the fields with strategies exist.
+	for name, s := range rules.Fields {
+		f, _, found := st.FieldByName(name)
+		if !found {
+			return bsterr.Err(bsterr.CodeInvalidValue, "merge rule field not found").
+				WithDetail("field", name)
+		}
+		if s != MergeLastWriterWins && f.Type.Kind() == bsttype.KindBoolean {
+			return bsterr.Err(bsterr.CodeInvalidType, "boolean fields support only last writer wins merge strategy").
+				WithDetails(bsterr.D("field", name), bsterr.D("strategy", s))
+		}
+	}
+	return nil
+}
+
+func mergeRightWins(st *bsttype.Struct, sa, sb []structSegment, rules MergeRules) (bool, error) {
+	// 1. Without the timestamp field the right-hand side value always wins.
+	if rules.TimestampField == "" {
+		return true, nil
+	}
+
+	// 2. Find the timestamp field segments in both values.
+	f, pos, _ := st.FieldByName(rules.TimestampField)
+	da, okA := findStructSegment(sa, structSegmentIndex(f, pos, rules.Options))
+	db, okB := findStructSegment(sb, structSegmentIndex(f, pos, rules.Options))
+	switch {
+	case !okA:
+		return true, nil
+	case !okB:
+		return false, nil
+	}
+
+	// 3. Decode and compare the timestamps.
+	o := structFieldOptions(f, rules.Options)
+	ta, err := decodeNumericValue(f.Type, da.data, o)
+	if err != nil {
+		return false, err
+	}
+	tb, err := decodeNumericValue(f.Type, db.data, o)
+	if err != nil {
+		return false, err
+	}
+	return tb.compare(ta) >= 0, nil
+}
+
+func mergeFields(w *bytes.Buffer, st *bsttype.Struct, sa, sb []structSegment, bWins bool, rules MergeRules) error {
+	for i := range sa {
+		sg := sa[i]
+		if bWins {
+			sg = sb[i]
+		}
+
+		// 1. Packed boolean fields and fields without a strategy are taken from the winner.
+		f := st.Fields[sg.index]
+		s := rules.Fields[f.Name]
+		if sg.count > 1 || s == MergeLastWriterWins {
+			w.Write(sg.data)
+			continue
+		}
+
+		// 2. Merge the field value with defined strategy.
+		data, err := mergeValue(f.Type, sa[i].data, sb[i].data, s, bWins, structFieldOptions(f, rules.Options))
+		if err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to merge struct field").
+				WithDetail("field", f.Name)
+		}
+		w.Write(data)
+	}
+	return nil
+}
+
+func mergeCompatibilityFields(w *bytes.Buffer, st *bsttype.Struct, sa, sb []structSegment, bWins bool, rules MergeRules) error {
+	// 1. Collect all the field indices present in any of the values.
+	var indices []uint
+	for _, sg := range sa {
+		indices = append(indices, sg.index)
+	}
+	for _, sg := range sb {
+		if _, ok := findStructSegment(sa, sg.index); !ok {
+			indices = append(indices, sg.index)
+		}
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+
+	// 2. Write the struct header, which is the max index of the fields.
+	if _, err := bstio.WriteStructHeader(w, len(indices)); err != nil {
+		return err
+	}
+
+	// 3. Merge and write each field with its header.
+	for _, index := range indices {
+		ga, okA := findStructSegment(sa, index)
+		gb, okB := findStructSegment(sb, index)
+
+		var data []byte
+		switch {
+		case !okA:
+			data = gb.data
+		case !okB:
+			data = ga.data
+		default:
+			data = ga.data
+			if bWins {
+				data = gb.data
+			}
+			f, _, found := st.FieldByIndex(index)
+			if !found {
+				break
+			}
+			s := rules.Fields[f.Name]
+			if s == MergeLastWriterWins {
+				break
+			}
+			var err error
+			data, err = mergeValue(f.Type, ga.data, gb.data, s, bWins, structFieldOptions(f, rules.Options))
+			if err != nil {
+				return bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to merge struct field").
+					WithDetail("field", f.Name)
+			}
+		}
+
+		if _, err := bstio.WriteUint(w, index, false); err != nil {
+			return err
+		}
+		if _, err := bstio.WriteUint(w, uint(len(data)), false); err != nil {
+			return err
+		}
+		w.Write(data)
+	}
+	return nil
+}
+
+func mergeValue(t bsttype.Type, a, b []byte, s MergeStrategy, bWins bool, o bstio.ValueOptions) ([]byte, error) {
+	t = derefNamedType(t)
+
+	// 1. Nullable values are merged only if both are not null.
+	if nt, ok := t.(*bsttype.Nullable); ok {
+		if len(a) == 0 || len(b) == 0 {
+			return nil, bsterr.Err(bsterr.CodeMalformedBinary, "empty nullable value binary")
+		}
+		notNull := bstio.NullableIsNotNull
+		if o.Descending {
+			notNull = bstio.NullableIsNotNullDesc
+		}
+		switch {
+		case a[0] != notNull:
+			return b, nil
+		case b[0] != notNull:
+			return a, nil
+		}
+		data, err := mergeValue(nt.Type, a[1:], b[1:], s, bWins, o)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{notNull}, data...), nil
+	}
+
+	switch s {
+	case MergeMax, MergeMin:
+		return mergeMinMax(t, a, b, s, o)
+	case MergeSum:
+		return mergeSum(t, a, b, o)
+	case MergeUnion:
+		return mergeUnion(t, a, b, bWins, o)
+	default:
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "unknown merge strategy").WithDetail("strategy", s)
+	}
+}
+
+func mergeMinMax(t bsttype.Type, a, b []byte, s MergeStrategy, o bstio.ValueOptions) ([]byte, error) {
+	na, err := decodeNumericValue(t, a, o)
+	if err != nil {
+		return nil, err
+	}
+	nb, err := decodeNumericValue(t, b, o)
+	if err != nil {
+		return nil, err
+	}
+
+	// The values are compared after decoding, but the winner binary is copied as is.
+	cmp := na.compare(nb)
+	if (s == MergeMax && cmp >= 0) || (s == MergeMin && cmp <= 0) {
+		return a, nil
+	}
+	return b, nil
+}
+
+func mergeSum(t bsttype.Type, a, b []byte, o bstio.ValueOptions) ([]byte, error) {
+	// 1. Summing time points makes no sense.
+	switch t.Kind() {
+	case bsttype.KindTimestamp, bsttype.KindDateTime:
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "sum merge strategy is not supported for time values").
+			WithDetail("kind", t.Kind())
+	}
+
+	// 2. Decode both values.
+	na, err := decodeNumericValue(t, a, o)
+	if err != nil {
+		return nil, err
+	}
+	nb, err := decodeNumericValue(t, b, o)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Add and encode the result.
+	na.i += nb.i
+	na.u += nb.u
+	na.f += nb.f
+
+	var buf bytes.Buffer
+	if err = encodeNumericValue(&buf, t, na, o); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func mergeUnion(t bsttype.Type, a, b []byte, bWins bool, o bstio.ValueOptions) ([]byte, error) {
+	// 1. Union is supported only for the non-comparable binary, as its elements could be
+	//    referenced without decoding.
+	if o.Comparable {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "union merge strategy is not supported for comparable values")
+	}
+
+	switch tt := t.(type) {
+	case *bsttype.Array:
+		if tt.HasFixedSize() || tt.Elem().Kind() == bsttype.KindBoolean {
+			return nil, bsterr.Err(bsterr.CodeInvalidType, "union merge strategy requires variable length array of non boolean elements").
+				WithDetail("type", tt)
+		}
+		return mergeUnionArray(tt, a, b, o)
+	case *bsttype.Map:
+		return mergeUnionMap(tt, a, b, bWins, o)
+	default:
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "union merge strategy is supported only for arrays and maps").
+			WithDetail("kind", t.Kind())
+	}
+}
+
+func mergeUnionArray(at *bsttype.Array, a, b []byte, o bstio.ValueOptions) ([]byte, error) {
+	skip := bstskip.SkipFuncOf(at.Elem())
+
+	// 1. Collect the unique elements of both arrays, preserving their order.
+	var (
+		elems [][]byte
+		seen  = map[string]struct{}{}
+	)
+	for _, in := range [][]byte{a, b} {
+		err := splitMergeElements(in, o, func(r *bytes.Reader, start int) error {
+			n, err := skip(r, o)
+			if err != nil {
+				return err
+			}
+			elem := in[start : start+int(n)]
+			if _, ok := seen[string(elem)]; ok {
+				return nil
+			}
+			seen[string(elem)] = struct{}{}
+			elems = append(elems, elem)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. Write the length and the elements.
+	var buf bytes.Buffer
+	if _, err := bstio.WriteUint(&buf, uint(len(elems)), o.Descending); err != nil {
+		return nil, err
+	}
+	for _, elem := range elems {
+		buf.Write(elem)
+	}
+	return buf.Bytes(), nil
+}
+
+func mergeUnionMap(mt *bsttype.Map, a, b []byte, bWins bool, o bstio.ValueOptions) ([]byte, error) {
+	sk, sv := bstskip.SkipFuncOf(mt.Key.Type), bstskip.SkipFuncOf(mt.Value.Type)
+	ko, vo := o, o
+	ko.Descending = o.Descending != mt.Key.Descending
+	vo.Descending = o.Descending != mt.Value.Descending
+
+	// 1. Collect the entries of both maps, the first value wins unless the right map is the more recent one.
+	var (
+		keys    [][]byte
+		entries = map[string][]byte{}
+	)
+	for i, in := range [][]byte{a, b} {
+		err := splitMergeElements(in, o, func(r *bytes.Reader, start int) error {
+			n, err := sk(r, ko)
+			if err != nil {
+				return err
+			}
+			key := in[start : start+int(n)]
+			m, err := sv(r, vo)
+			if err != nil {
+				return err
+			}
+			value := in[start+int(n) : start+int(n+m)]
+
+			if _, ok := entries[string(key)]; !ok {
+				keys = append(keys, key)
+			} else if i == 0 || !bWins {
+				return nil
+			}
+			entries[string(key)] = value
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. Write the length and the entries.
+	var buf bytes.Buffer
+	if _, err := bstio.WriteUint(&buf, uint(len(keys)), o.Descending); err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		buf.Write(key)
+		buf.Write(entries[string(key)])
+	}
+	return buf.Bytes(), nil
+}
+
+// splitMergeElements reads the length of variable length collection and calls fn for each of its elements.
+func splitMergeElements(in []byte, o bstio.ValueOptions, fn func(r *bytes.Reader, start int) error) error {
+	r := bytes.NewReader(in)
+	length, _, err := bstio.ReadUint(r, o.Descending)
+	if err != nil {
+		return err
+	}
+	for i := uint(0); i < length; i++ {
+		if err = fn(r, len(in)-r.Len()); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeSkippingBinaryValue, "failed to skip collection element")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package bst
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestMerge(t *testing.T) {
+	st := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "Updated", Type: bsttype.Timestamp()},
+			{Index: 2, Name: "Count", Type: bsttype.Int64()},
+			{Index: 3, Name: "Peak", Type: bsttype.Float64()},
+			{Index: 4, Name: "Tags", Type: bsttype.ArrayOf(bsttype.String())},
+			{Index: 5, Name: "Name", Type: bsttype.String()},
+		},
+	}
+
+	row := func(t *testing.T, updated int64, count int64, peak float64, name string, tags ...string) []byte {
+		tv := bstvalue.EmptyArrayValue(st.Fields[3].Type.(*bsttype.Array))
+		for _, tag := range tags {
+			if err := tv.Append(bstvalue.NewStringValue(tag)); err != nil {
+				t.Fatalf("appending tag failed: %v", err)
+			}
+		}
+		sv := bstvalue.MustNewStructValue(st, []bstvalue.Value{
+			bstvalue.NewTimestampValue(time.Unix(0, updated)),
+			bstvalue.NewInt64Value(count),
+			bstvalue.NewFloat64Value(peak),
+			tv,
+			bstvalue.NewStringValue(name),
+		})
+		data, err := sv.MarshalValue(bstio.ValueOptions{})
+		if err != nil {
+			t.Fatalf("marshaling row failed: %v", err)
+		}
+		return data
+	}
+
+	rules := MergeRules{
+		TimestampField: "Updated",
+		Fields: map[string]MergeStrategy{
+			"Count": MergeSum,
+			"Peak":  MergeMax,
+			"Tags":  MergeUnion,
+		},
+	}
+
+	t.Run("RightNewer", func(t *testing.T) {
+		a := row(t, 10, 3, 5.5, "old", "a", "b")
+		b := row(t, 20, 4, 1.5, "new", "b", "c")
+
+		got, err := Merge(a, b, st, rules)
+		if err != nil {
+			t.Fatalf("merge failed: %v", err)
+		}
+
+		expected := row(t, 20, 7, 5.5, "new", "a", "b", "c")
+		if !bytes.Equal(got, expected) {
+			t.Fatalf("unexpected merge result:\n%v\nexpected:\n%v", got, expected)
+		}
+	})
+
+	t.Run("LeftNewer", func(t *testing.T) {
+		a := row(t, 30, 1, 0.5, "newest")
+		b := row(t, 20, 2, 2.5, "new", "x")
+
+		got, err := Merge(a, b, st, rules)
+		if err != nil {
+			t.Fatalf("merge failed: %v", err)
+		}
+
+		expected := row(t, 30, 3, 2.5, "newest", "x")
+		if !bytes.Equal(got, expected) {
+			t.Fatalf("unexpected merge result:\n%v\nexpected:\n%v", got, expected)
+		}
+	})
+
+	t.Run("UnknownField", func(t *testing.T) {
+		a := row(t, 10, 3, 5.5, "a")
+		_, err := Merge(a, a, st, MergeRules{Fields: map[string]MergeStrategy{"Unknown": MergeMax}})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("InvalidStrategyType", func(t *testing.T) {
+		a := row(t, 10, 3, 5.5, "a")
+		_, err := Merge(a, a, st, MergeRules{Fields: map[string]MergeStrategy{"Name": MergeSum}})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("Compatibility", func(t *testing.T) {
+		ct := &bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "Version", Type: bsttype.Uint8()},
+				{Index: 2, Name: "Hits", Type: bsttype.Uint8()},
+				{Index: 3, Name: "Label", Type: bsttype.Uint8()},
+			},
+		}
+		// The rows missing a field are composed with the older revisions of the type.
+		a := composeCompatibilityRow(t, &bsttype.Struct{Fields: ct.Fields[:2]}, 5, 3)
+		b := composeCompatibilityRow(t, &bsttype.Struct{Fields: []bsttype.StructField{ct.Fields[0], ct.Fields[2]}}, 6, 9)
+		rules := MergeRules{
+			TimestampField: "Version",
+			Fields:         map[string]MergeStrategy{"Hits": MergeSum},
+			Options:        bstio.ValueOptions{CompatibilityMode: true},
+		}
+
+		got, err := Merge(a, b, ct, rules)
+		if err != nil {
+			t.Fatalf("merge failed: %v", err)
+		}
+
+		// Hits are missing in b, and Label in a.
+		expected := composeCompatibilityRow(t, ct, 6, 3, 9)
+		if !bytes.Equal(got, expected) {
+			t.Fatalf("unexpected merge result:\n%v\nexpected:\n%v", got, expected)
+		}
+		if fields := extractCompatibilityRow(t, ct, got); len(fields) != 3 || fields["Label"] != 9 {
+			t.Fatalf("unexpected merged fields: %v", fields)
+		}
+
+		// The merge of the full rows keeps all their fields.
+		full := composeCompatibilityRow(t, ct, 1, 2, 3)
+		got, err = Merge(full, full, ct, rules)
+		if err != nil {
+			t.Fatalf("merge failed: %v", err)
+		}
+		expected = composeCompatibilityRow(t, ct, 1, 4, 3)
+		if !bytes.Equal(got, expected) {
+			t.Fatalf("unexpected merge result:\n%v\nexpected:\n%v", got, expected)
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package bst
 
 import (
+	"time"
+
 	"github.com/devmodules/bst/bsterr"
 	"github.com/devmodules/bst/bstio"
 	"github.com/devmodules/bst/bsttype"
@@ -22,7 +24,16 @@ func (x *Composer) WriteNull() error {
 			)
 	}
 
-	// 3. If the base is a struct, check if the field header needs to be written.
+	// 3. The null flags of the null bitmap array elements are written on close.
+	if x.nullBitmapArray() {
+		for len(x.nullBitmap) <= x.index>>3 {
+			x.nullBitmap = append(x.nullBitmap, 0)
+		}
+		x.nullBitmap[x.index>>3] |= 1 << (x.index & 7)
+		return x.finishElem()
+	}
+
+	// 4. If the base is a struct, check if the field header needs to be written.
 	if x.needWriteFieldHeader() {
 		n, err := x.writeFieldHeader(x.w, x.fieldIndex(), 1)
 		if err != nil {
@@ -32,19 +43,19 @@ func (x *Composer) WriteNull() error {
 		x.bytesWritten += n
 	}
 
-	// 4. Prepare the null value.
+	// 5. Prepare the null value.
 	bt := bstio.NullableIsNull
 	if x.elemDesc {
 		bt = bstio.NullableIsNullDesc
 	}
 
-	// 4. Write the null value.
+	// 5. Write the null value.
 	if err := bstio.WriteByte(x.w, bt); err != nil {
 		return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write null")
 	}
 	x.bytesWritten++
 
-	// 5. Mark the element as written.
+	// 6. Mark the element as written.
 	if err := x.finishElem(); err != nil {
 		return err
 	}
@@ -68,26 +79,32 @@ func (x *Composer) WriteNotNull() error {
 			)
 	}
 
-	// 3. If the base is a struct, check if the field header needs to be written.
+	// 3. The null flags of the null bitmap array elements are written on close.
+	if x.nullBitmapArray() {
+		x.elemType = derefNamedType(nt.Elem())
+		return nil
+	}
+
+	// 4. If the base is a struct, check if the field header needs to be written.
 	if x.needWriteFieldHeader() {
 		x.setFieldBuffer()
 	}
 
-	// 4. Prepare the binary value for the not null header.
+	// 5. Prepare the binary value for the not null header.
 	bt := bstio.NullableIsNotNull
 	if x.elemDesc {
 		bt = bstio.NullableIsNotNullDesc
 	}
 
-	// 5. Write the not null value.
+	// 6. Write the not null value.
 	if err := bstio.WriteByte(x.w, bt); err != nil {
 		return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write not null")
 	}
 
 	x.bytesWritten++
 
-	// 6. Dereference the nullable type.
-	x.elemType = nt.Elem()
+	// 7. Dereference the nullable type.
+	x.elemType = derefNamedType(nt.Elem())
 	return nil
 }
 
@@ -113,15 +130,26 @@ func (x *Extractor) IsNull() (bool, error) {
 			)
 	}
 
-	// 3. Read the null value.
-	v, err := bstio.ReadNullableFlag(x.r, x.opts.Descending)
-	if err != nil {
-		return false, err
-	}
+	// 3. Read the null value. The null flags of the null bitmap array elements were read upfront.
+	v := bstio.NullableIsNotNull
+	if x.nullBitmapArray() {
+		if x.nullBitmapIsNull(x.index) {
+			v = bstio.NullableIsNull
+		}
+	} else {
+		var err error
+		v, err = bstio.ReadNullableFlag(x.r, x.elemDesc)
+		if err != nil {
+			return false, err
+		}
 
-	x.bytesRead += 1
+		x.bytesRead += 1
+	}
 
 	// 4. Finish nullable type.
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpNullFlag, v == bstio.NullableIsNull)
+	}
 	switch v {
 	case bstio.NullableIsNotNull:
 		// 4.1. A 1-bit indicates that the value is not-null.
@@ -133,20 +161,21 @@ func (x *Extractor) IsNull() (bool, error) {
 			}
 			x.embed.elemType = x.elemType
 		} else {
-			var nx *bsttype.Nullable
-			nx, ok = x.opts.ExpectedType.(*bsttype.Nullable)
+			// 4.2. The element type is the expected one, whereas the embedded element type is dereferenced separately.
+			var ne *bsttype.Nullable
+			ne, ok = x.embed.elemType.(*bsttype.Nullable)
 			if !ok {
-				return false, bsterr.Err(bsterr.CodeInvalidType, "invalid type expected element type").
+				return false, bsterr.Err(bsterr.CodeInvalidType, "invalid type embedded element type").
 					WithDetails(
 						bsterr.D("expected", bsttype.KindNullable),
-						bsterr.D("actual", x.opts.ExpectedType.Kind()),
+						bsterr.D("actual", x.embed.elemType),
 					)
 			}
-			x.elemType, x.err = x.derefType(nx.Type)
+			x.elemType, x.err = x.derefType(nt.Type)
 			if x.err != nil {
 				return false, x.err
 			}
-			x.embed.elemType, x.err = x.derefType(nt.Type)
+			x.embed.elemType, x.err = x.derefType(ne.Type)
 			if x.err != nil {
 				return false, x.err
 			}
@@ -160,3 +189,124 @@ func (x *Extractor) IsNull() (bool, error) {
 		return false, bsterr.Err(bsterr.CodeInvalidValue, "invalid nullable flag value")
 	}
 }
+
+// ReadNullable reads the nullable element value in a single call, with the read function of its element type,
+// i.e. the (*Extractor).ReadString. The ok flag is false and the value is zero if the element is null.
+func ReadNullable[T any](x *Extractor, read func(x *Extractor) (T, error)) (T, bool, error) {
+	var zero T
+	isNull, err := x.IsNull()
+	if err != nil || isNull {
+		return zero, false, err
+	}
+	v, err := read(x)
+	if err != nil {
+		return zero, false, err
+	}
+	return v, true, nil
+}
+
+// WriteNullable writes the nullable element value in a single call, with the write function of its element type,
+// i.e. the (*Composer).WriteString. The nil v is written as null.
+func WriteNullable[T any](c *Composer, v *T, write func(c *Composer, v T) error) error {
+	if v == nil {
+		return c.WriteNull()
+	}
+	if err := c.WriteNotNull(); err != nil {
+		return err
+	}
+	return write(c, *v)
+}
+
+// ReadNullableBoolean reads the nullable boolean value. The ok flag is false if the value is null.
+func (x *Extractor) ReadNullableBoolean() (bool, bool, error) {
+	return ReadNullable(x, (*Extractor).ReadBoolean)
+}
+
+// ReadNullableInt32 reads the nullable int32 value. The ok flag is false if the value is null.
+func (x *Extractor) ReadNullableInt32() (int32, bool, error) {
+	return ReadNullable(x, (*Extractor).ReadInt32)
+}
+
+// ReadNullableInt64 reads the nullable int64 value. The ok flag is false if the value is null.
+func (x *Extractor) ReadNullableInt64() (int64, bool, error) {
+	return ReadNullable(x, (*Extractor).ReadInt64)
+}
+
+// ReadNullableUint32 reads the nullable uint32 value. The ok flag is false if the value is null.
+func (x *Extractor) ReadNullableUint32() (uint32, bool, error) {
+	return ReadNullable(x, (*Extractor).ReadUint32)
+}
+
+// ReadNullableUint64 reads the nullable uint64 value. The ok flag is false if the value is null.
+func (x *Extractor) ReadNullableUint64() (uint64, bool, error) {
+	return ReadNullable(x, (*Extractor).ReadUint64)
+}
+
+// ReadNullableFloat64 reads the nullable float64 value. The ok flag is false if the value is null.
+func (x *Extractor) ReadNullableFloat64() (float64, bool, error) {
+	return ReadNullable(x, (*Extractor).ReadFloat64)
+}
+
+// ReadNullableString reads the nullable string value. The ok flag is false if the value is null.
+func (x *Extractor) ReadNullableString() (string, bool, error) {
+	return ReadNullable(x, (*Extractor).ReadString)
+}
+
+// ReadNullableBytes reads the nullable bytes value. The ok flag is false if the value is null.
+func (x *Extractor) ReadNullableBytes() ([]byte, bool, error) {
+	return ReadNullable(x, (*Extractor).ReadBytes)
+}
+
+// ReadNullableTimestamp reads the nullable timestamp value. The ok flag is false if the value is null.
+func (x *Extractor) ReadNullableTimestamp() (time.Time, bool, error) {
+	return ReadNullable(x, (*Extractor).ReadTimestamp)
+}
+
+// WriteNullableBoolean writes the nullable boolean value. The nil v is written as null.
+func (x *Composer) WriteNullableBoolean(v *bool) error {
+	return WriteNullable(x, v, (*Composer).WriteBoolean)
+}
+
+// WriteNullableInt32 writes the nullable int32 value. The nil v is written as null.
+func (x *Composer) WriteNullableInt32(v *int32) error {
+	return WriteNullable(x, v, (*Composer).WriteInt32)
+}
+
+// WriteNullableInt64 writes the nullable int64 value. The nil v is written as null.
+func (x *Composer) WriteNullableInt64(v *int64) error {
+	return WriteNullable(x, v, (*Composer).WriteInt64)
+}
+
+// WriteNullableUint32 writes the nullable uint32 value. The nil v is written as null.
+func (x *Composer) WriteNullableUint32(v *uint32) error {
+	return WriteNullable(x, v, (*Composer).WriteUint32)
+}
+
+// WriteNullableUint64 writes the nullable uint64 value. The nil v is written as null.
+func (x *Composer) WriteNullableUint64(v *uint64) error {
+	return WriteNullable(x, v, (*Composer).WriteUint64)
+}
+
+// WriteNullableFloat64 writes the nullable float64 value. The nil v is written as null.
+func (x *Composer) WriteNullableFloat64(v *float64) error {
+	return WriteNullable(x, v, (*Composer).WriteFloat64)
+}
+
+// WriteNullableString writes the nullable string value. The nil v is written as null.
+func (x *Composer) WriteNullableString(v *string) error {
+	return WriteNullable(x, v, (*Composer).WriteString)
+}
+
+// WriteNullableBytes writes the nullable bytes value. The nil v is written as null,
+// whereas the empty not nil slice is the empty bytes value.
+func (x *Composer) WriteNullableBytes(v []byte) error {
+	if v == nil {
+		return x.WriteNull()
+	}
+	return WriteNullable(x, &v, (*Composer).WriteBytes)
+}
+
+// WriteNullableTimestamp writes the nullable timestamp value. The nil v is written as null.
+func (x *Composer) WriteNullableTimestamp(v *time.Time) error {
+	return WriteNullable(x, v, (*Composer).WriteTimestamp)
+}
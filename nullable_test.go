@@ -0,0 +1,126 @@
+package bst
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestNullableHelpers(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "Name", Type: bsttype.NullableOf(bsttype.String())},
+		{Index: 2, Name: "Age", Type: bsttype.NullableOf(bsttype.Int64())},
+		{Index: 3, Name: "Data", Type: bsttype.NullableOf(&bsttype.Bytes{})},
+		{Index: 4, Name: "Seen", Type: bsttype.NullableOf(bsttype.Timestamp())},
+	}}
+	name, age, seen := "ann", int64(-42), time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	type row struct {
+		name *string
+		age  *int64
+		data []byte
+		seen *time.Time
+	}
+	for _, r := range []row{{}, {name: &name, age: &age, data: []byte{}, seen: &seen}, {age: &age, data: []byte{1, 2}}} {
+		for _, opts := range []ComposerOptions{{}, {Descending: true}, {Comparable: true}} {
+			// 1. The helpers compose the same binary as the null flag and the value written separately.
+			var helperBuf, manualBuf bytes.Buffer
+			c, err := NewComposer(&helperBuf, st, opts)
+			if err != nil {
+				t.Fatalf("creating composer failed: %v", err)
+			}
+			if err = c.WriteNullableString(r.name); err != nil {
+				t.Fatalf("writing nullable string failed: %v", err)
+			}
+			if err = c.WriteNullableInt64(r.age); err != nil {
+				t.Fatalf("writing nullable int64 failed: %v", err)
+			}
+			if err = c.WriteNullableBytes(r.data); err != nil {
+				t.Fatalf("writing nullable bytes failed: %v", err)
+			}
+			if err = c.WriteNullableTimestamp(r.seen); err != nil {
+				t.Fatalf("writing nullable timestamp failed: %v", err)
+			}
+			if err = c.Close(); err != nil {
+				t.Fatalf("closing composer failed: %v", err)
+			}
+
+			c, err = NewComposer(&manualBuf, st, opts)
+			if err != nil {
+				t.Fatalf("creating composer failed: %v", err)
+			}
+			writeManual := func(isNull bool, write func() error) {
+				if isNull {
+					err = c.WriteNull()
+				} else if err = c.WriteNotNull(); err == nil {
+					err = write()
+				}
+				if err != nil {
+					t.Fatalf("writing nullable failed: %v", err)
+				}
+			}
+			writeManual(r.name == nil, func() error { return c.WriteString(*r.name) })
+			writeManual(r.age == nil, func() error { return c.WriteInt64(*r.age) })
+			writeManual(r.data == nil, func() error { return c.WriteBytes(r.data) })
+			writeManual(r.seen == nil, func() error { return c.WriteTimestamp(*r.seen) })
+			if err = c.Close(); err != nil {
+				t.Fatalf("closing composer failed: %v", err)
+			}
+			if !bytes.Equal(helperBuf.Bytes(), manualBuf.Bytes()) {
+				t.Fatalf("binaries differ (options: %+v): %x != %x", opts, helperBuf.Bytes(), manualBuf.Bytes())
+			}
+
+			// 2. The helpers read the values back in a single call.
+			x, err := NewExtractor(bytes.NewReader(helperBuf.Bytes()), ExtractorOptions{
+				ExpectedType: st,
+				Descending:   opts.Descending,
+				Comparable:   opts.Comparable,
+			})
+			if err != nil {
+				t.Fatalf("creating extractor failed: %v", err)
+			}
+			if !x.Next() {
+				t.Fatal("expected name field")
+			}
+			gotName, ok, err := x.ReadNullableString()
+			if err != nil || ok != (r.name != nil) || (ok && gotName != *r.name) {
+				t.Fatalf("unexpected name: %q, %v, %v", gotName, ok, err)
+			}
+			if !x.Next() {
+				t.Fatal("expected age field")
+			}
+			gotAge, ok, err := x.ReadNullableInt64()
+			if err != nil || ok != (r.age != nil) || (ok && gotAge != *r.age) {
+				t.Fatalf("unexpected age: %d, %v, %v", gotAge, ok, err)
+			}
+			if !x.Next() {
+				t.Fatal("expected data field")
+			}
+			gotData, ok, err := x.ReadNullableBytes()
+			if err != nil || ok != (r.data != nil) || !bytes.Equal(gotData, r.data) {
+				t.Fatalf("unexpected data: %v, %v, %v", gotData, ok, err)
+			}
+			if !x.Next() {
+				t.Fatal("expected seen field")
+			}
+			gotSeen, ok, err := ReadNullable(x, (*Extractor).ReadTimestamp)
+			if err != nil || ok != (r.seen != nil) || (ok && !gotSeen.Equal(*r.seen)) || (!ok && !gotSeen.IsZero()) {
+				t.Fatalf("unexpected seen: %v, %v, %v", gotSeen, ok, err)
+			}
+			if x.Next() {
+				t.Fatal("unexpected field")
+			}
+		}
+	}
+
+	// 3. The helper of the not nullable element fails.
+	c, err := NewComposer(&bytes.Buffer{}, bsttype.String(), ComposerOptions{})
+	if err != nil {
+		t.Fatalf("creating composer failed: %v", err)
+	}
+	if err = c.WriteNullableString(&name); err == nil {
+		t.Fatal("expected error writing nullable string to the string type")
+	}
+}
@@ -104,8 +104,12 @@ func (x *Composer) writeOneOfIndex(index uint, elem bsttype.Type, indexBytes uin
 	return nil
 }
 
+// reset the composer to the initial state of the nested composite value.
+// The nested value inherits the effective order of the element it is written as.
 func (x *Composer) reset() {
-	*x = Composer{w: x.w, opts: x.opts, modules: x.modules}
+	opts := x.opts
+	opts.Descending = x.elemDesc
+	*x = Composer{w: x.w, opts: opts, modules: x.modules, guard: x.guard, limit: x.limit}
 }
 
 // OneOfHeader is the header of the OneOf Value.
@@ -158,5 +162,8 @@ func (x *Extractor) ReadOneOfHeader() (OneOfHeader, error) {
 		return OneOfHeader{}, x.err
 	}
 	x.elemType = t
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpHeader, idx)
+	}
 	return OneOfHeader{Index: idx, Type: t}, nil
 }
@@ -0,0 +1,334 @@
+package bst
+
+import (
+	"github.com/devmodules/bst/bstcol"
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// EncodingOptions is a validated set of the value encoding options, shared by the composer and the extractor.
+// It is created by the NewOptions out of the presets (ForIndexKey, ForRowStorage, ForRPC) and the functional options.
+type EncodingOptions struct {
+	Descending        bool
+	Comparable        bool
+	CompatibilityMode bool
+	EmbedType         bool
+	Modules           *bsttype.Modules
+	// LengthPrefixedCollections prefixes the variable size array and map elements of the arrays and maps
+	// with their binary size. It requires the compatibility mode.
+	LengthPrefixedCollections bool
+	// BlobStore offloads and fetches the ExternalBytes values.
+	BlobStore BlobStore
+	// InlineThreshold is the length from which on the External encoded field values are offloaded to the BlobStore.
+	InlineThreshold int
+	// Encryption defines the encrypted struct fields.
+	Encryption *FieldEncryption
+	// Tokenizers define the tokenized struct fields, by the field names.
+	Tokenizers map[string]Tokenizer
+	// Budget limits the bytes read and the elements extracted by the extractor.
+	Budget *DecodeBudget
+	// Signature signs the composed values with the current key, and verifies the extracted ones.
+	Signature *SignatureKeys
+	// CanonicalMaps sorts the composed map entries by their key binaries, and verifies the order of the extracted ones.
+	CanonicalMaps bool
+	// EmbedDocs embeds the doc strings of the struct fields and the module definitions along with the embedded type.
+	EmbedDocs bool
+	// Checksum appends the checksum trailer of given kind to the composed values, and requires it on their verification.
+	Checksum bstio.ChecksumKind
+	// Compression compresses the body of the composed values with the codec of given kind.
+	Compression bstio.CompressionKind
+	// CompressEmbeddedType compresses the embedded type or schema ID of the composed values along with their body.
+	CompressEmbeddedType bool
+	// FloatColumn defines the transforms of the composed float column arrays.
+	FloatColumn *bstcol.ArrayEncodingOptions
+	// OrderedFloats encodes the float values in the ordered format, whose binaries compare just like the values.
+	OrderedFloats bool
+}
+
+// Option is a functional option which modifies the EncodingOptions.
+type Option func(o *EncodingOptions)
+
+// NewOptions applies the options in given order on the zero EncodingOptions and validates their combination.
+func NewOptions(opts ...Option) (EncodingOptions, error) {
+	var o EncodingOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.Validate(); err != nil {
+		return EncodingOptions{}, err
+	}
+	return o, nil
+}
+
+// ForIndexKey is the preset for the index keys, whose binaries are compared directly.
+// The values are encoded in the comparable format, without the embedded type, and the floats in the ordered format,
+// so that the negative floats are ordered as well. The keys are extracted with its ExtractorOptions.
+func ForIndexKey() Option {
+	return func(o *EncodingOptions) {
+		o.Comparable = true
+		o.OrderedFloats = true
+		o.CompatibilityMode = false
+		o.LengthPrefixedCollections = false
+		o.EmbedType = false
+	}
+}
+
+// ForRowStorage is the preset for the stored rows, whose type might evolve over time.
+// The values are encoded in the compatibility mode, without the embedded type - it is known by the storage.
+func ForRowStorage() Option {
+	return func(o *EncodingOptions) {
+		o.Comparable = false
+		o.Descending = false
+		o.CompatibilityMode = true
+		o.EmbedType = false
+	}
+}
+
+// ForRPC is the preset for the messages exchanged between the services.
+// The values are self-describing - they embed their type, thus the receiver could extract them without the type.
+func ForRPC() Option {
+	return func(o *EncodingOptions) {
+		o.Comparable = false
+		o.Descending = false
+		o.CompatibilityMode = false
+		o.LengthPrefixedCollections = false
+		o.EmbedType = true
+	}
+}
+
+// WithDescending sets the descending order of the values.
+func WithDescending() Option {
+	return func(o *EncodingOptions) {
+		o.Descending = true
+	}
+}
+
+// WithComparable sets the comparable format of the values.
+func WithComparable() Option {
+	return func(o *EncodingOptions) {
+		o.Comparable = true
+	}
+}
+
+// WithCompatibilityMode sets the compatibility mode of the struct values.
+func WithCompatibilityMode() Option {
+	return func(o *EncodingOptions) {
+		o.CompatibilityMode = true
+	}
+}
+
+// WithLengthPrefixedCollections prefixes the nested collections with their binary size, so that they could be
+// skipped without parsing. It requires the compatibility mode.
+func WithLengthPrefixedCollections() Option {
+	return func(o *EncodingOptions) {
+		o.LengthPrefixedCollections = true
+	}
+}
+
+// WithEmbedType embeds the type of the value, along with given modules (optional) into the value binary.
+func WithEmbedType(modules *bsttype.Modules) Option {
+	return func(o *EncodingOptions) {
+		o.EmbedType = true
+		o.Modules = modules
+	}
+}
+
+// WithModules sets the modules used to resolve the named types.
+func WithModules(modules *bsttype.Modules) Option {
+	return func(o *EncodingOptions) {
+		o.Modules = modules
+	}
+}
+
+// WithBlobStore sets the blob store of the ExternalBytes values.
+func WithBlobStore(store BlobStore) Option {
+	return func(o *EncodingOptions) {
+		o.BlobStore = store
+	}
+}
+
+// WithInlineThreshold sets the length from which on the External encoded field values are offloaded to the blob store.
+func WithInlineThreshold(n int) Option {
+	return func(o *EncodingOptions) {
+		o.InlineThreshold = n
+	}
+}
+
+// WithFieldEncryption sets the encrypted struct fields along with their key provider.
+func WithFieldEncryption(encryption *FieldEncryption) Option {
+	return func(o *EncodingOptions) {
+		o.Encryption = encryption
+	}
+}
+
+// WithTokenizers sets the tokenizers of the struct fields, by the field names.
+func WithTokenizers(tokenizers map[string]Tokenizer) Option {
+	return func(o *EncodingOptions) {
+		o.Tokenizers = tokenizers
+	}
+}
+
+// WithDecodeBudget sets the decode budget of the extracted values.
+func WithDecodeBudget(budget *DecodeBudget) Option {
+	return func(o *EncodingOptions) {
+		o.Budget = budget
+	}
+}
+
+// WithSignature signs the values with the trailer of the current key, and verifies the signatures
+// of the extracted values with any of the keys.
+func WithSignature(keys *SignatureKeys) Option {
+	return func(o *EncodingOptions) {
+		o.Signature = keys
+	}
+}
+
+// WithCanonicalMaps writes the map entries sorted by their key binaries, so that the maps of the same entries
+// have equal binaries, and verifies the order of the entries of the extracted maps.
+func WithCanonicalMaps() Option {
+	return func(o *EncodingOptions) {
+		o.CanonicalMaps = true
+	}
+}
+
+// WithEmbedDocs embeds the doc strings of the struct fields and the module definitions along with the embedded type.
+func WithEmbedDocs() Option {
+	return func(o *EncodingOptions) {
+		o.EmbedDocs = true
+	}
+}
+
+// WithChecksum appends the checksum trailer of given kind to the composed values, and requires the trailer
+// of the extracted values verified by the Extractor.VerifyAndClose.
+func WithChecksum(kind bstio.ChecksumKind) Option {
+	return func(o *EncodingOptions) {
+		o.Checksum = kind
+	}
+}
+
+// WithCompression compresses the body of the composed values with the codec of given kind.
+// The extracted values are decompressed regardless of the option.
+func WithCompression(kind bstio.CompressionKind) Option {
+	return func(o *EncodingOptions) {
+		o.Compression = kind
+	}
+}
+
+// WithCompressedEmbeddedType compresses the embedded type or schema ID of the composed values along with their body.
+// It requires the WithCompression.
+func WithCompressedEmbeddedType() Option {
+	return func(o *EncodingOptions) {
+		o.CompressEmbeddedType = true
+	}
+}
+
+// WithFloatColumn sets the transforms of the composed bsttype.ArrayEncodingFloatColumn arrays.
+// The extracted arrays are decoded regardless of the option, as their transforms are stored in their binary.
+func WithFloatColumn(opts bstcol.ArrayEncodingOptions) Option {
+	return func(o *EncodingOptions) {
+		o.FloatColumn = &opts
+	}
+}
+
+// WithOrderedFloats encodes the float values in the ordered format, whose binaries of the negative values compare
+// on the bytes level just like the values. The values need to be extracted with the same option, as the negative
+// values of the default format have different binaries.
+func WithOrderedFloats() Option {
+	return func(o *EncodingOptions) {
+		o.OrderedFloats = true
+	}
+}
+
+// Validate checks if the combination of the options is valid:
+//   - the comparable format could not be used in the compatibility mode, as the struct field headers break the order,
+//   - the comparable format could not embed the type, as its binary is not a part of the value order,
+//   - the length prefixed collections require the compatibility mode,
+//   - the comparable format could not be signed, as the signature trailer is not a part of the value order,
+//   - the comparable format could not have the checksum trailer for the same reason, and its kind needs to be known,
+//   - the comparable format could not be compressed, as the compressed binaries are not ordered, and the compression
+//     kind needs to be known,
+//   - the compressed embedded type requires the compression,
+//   - the inline threshold could not be negative.
+func (x EncodingOptions) Validate() error {
+	switch {
+	case x.Comparable && x.CompatibilityMode:
+		return bsterr.Err(bsterr.CodeInvalidValue, "comparable format could not be used in the compatibility mode")
+	case x.Comparable && x.EmbedType:
+		return bsterr.Err(bsterr.CodeInvalidValue, "comparable format could not embed the type")
+	case x.LengthPrefixedCollections && !x.CompatibilityMode:
+		return bsterr.Err(bsterr.CodeInvalidValue, "length prefixed collections require the compatibility mode")
+	case x.Comparable && x.Signature != nil:
+		return bsterr.Err(bsterr.CodeInvalidValue, "comparable format could not be signed")
+	case x.Comparable && x.Checksum != bstio.ChecksumNone:
+		return bsterr.Err(bsterr.CodeInvalidValue, "comparable format could not have the checksum trailer")
+	case x.Checksum != bstio.ChecksumNone && x.Checksum.Size() == 0:
+		return bsterr.Err(bsterr.CodeInvalidValue, "unknown checksum kind").WithDetail("kind", uint8(x.Checksum))
+	case x.Comparable && x.Compression != bstio.CompressionNone:
+		return bsterr.Err(bsterr.CodeInvalidValue, "comparable format could not be compressed")
+	case !x.Compression.Known():
+		return bsterr.Err(bsterr.CodeInvalidValue, "unknown compression kind").WithDetail("kind", uint8(x.Compression))
+	case x.CompressEmbeddedType && x.Compression == bstio.CompressionNone:
+		return bsterr.Err(bsterr.CodeInvalidValue, "compressed embedded type requires the compression")
+	case x.InlineThreshold < 0:
+		return bsterr.Err(bsterr.CodeInvalidValue, "inline threshold could not be negative").
+			WithDetail("threshold", x.InlineThreshold)
+	}
+	return nil
+}
+
+// ComposerOptions returns the composer options matching the encoding options.
+func (x EncodingOptions) ComposerOptions() ComposerOptions {
+	return ComposerOptions{
+		Descending:                x.Descending,
+		Comparable:                x.Comparable,
+		CompatibilityMode:         x.CompatibilityMode,
+		EmbedType:                 x.EmbedType,
+		Modules:                   x.Modules,
+		LengthPrefixedCollections: x.LengthPrefixedCollections,
+		BlobStore:                 x.BlobStore,
+		InlineThreshold:           x.InlineThreshold,
+		Encryption:                x.Encryption,
+		Tokenizers:                x.Tokenizers,
+		Signature:                 x.Signature,
+		CanonicalMaps:             x.CanonicalMaps,
+		EmbedDocs:                 x.EmbedDocs,
+		Checksum:                  x.Checksum,
+		Compression:               x.Compression,
+		CompressEmbeddedType:      x.CompressEmbeddedType,
+		FloatColumn:               x.FloatColumn,
+		OrderedFloats:             x.OrderedFloats,
+	}
+}
+
+// ExtractorOptions returns the extractor options matching the encoding options, for given expected type.
+// The expected type might be nil for the values with embedded type.
+func (x EncodingOptions) ExtractorOptions(expected bsttype.Type) ExtractorOptions {
+	return ExtractorOptions{
+		Descending:                x.Descending,
+		Comparable:                x.Comparable,
+		CompatibilityMode:         x.CompatibilityMode,
+		ExpectedType:              expected,
+		Modules:                   x.Modules,
+		LengthPrefixedCollections: x.LengthPrefixedCollections,
+		BlobStore:                 x.BlobStore,
+		Encryption:                x.Encryption,
+		Tokenizers:                x.Tokenizers,
+		Budget:                    x.Budget,
+		Signature:                 x.Signature,
+		CanonicalMaps:             x.CanonicalMaps,
+		RequireChecksum:           x.Checksum != bstio.ChecksumNone,
+		OrderedFloats:             x.OrderedFloats,
+	}
+}
+
+// ValueOptions returns the bstio value options matching the encoding options.
+func (x EncodingOptions) ValueOptions() bstio.ValueOptions {
+	return bstio.ValueOptions{
+		Descending:        x.Descending,
+		Comparable:        x.Comparable,
+		CompatibilityMode: x.CompatibilityMode,
+		LengthPrefixed:    x.LengthPrefixedCollections,
+		OrderedFloats:     x.OrderedFloats,
+	}
+}
@@ -0,0 +1,139 @@
+package bst
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestNewOptions(t *testing.T) {
+	t.Run("Presets", func(t *testing.T) {
+		st := &bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "ID", Type: bsttype.Uint()},
+				{Index: 2, Name: "Score", Type: bsttype.Int64()},
+			},
+		}
+		presets := map[string][]Option{
+			"IndexKey":           {ForIndexKey()},
+			"IndexKeyDesc":       {ForIndexKey(), WithDescending()},
+			"RowStorage":         {ForRowStorage()},
+			"RowStoragePrefixed": {ForRowStorage(), WithLengthPrefixedCollections()},
+			"RPC":                {ForRPC()},
+			"RowStorageDesc":     {ForRowStorage(), WithDescending()},
+			"RPCDesc":            {WithDescending(), ForRPC(), WithDescending()},
+		}
+		for name, opts := range presets {
+			t.Run(name, func(t *testing.T) {
+				o, err := NewOptions(opts...)
+				if err != nil {
+					t.Fatalf("invalid preset: %v", err)
+				}
+
+				var buf bytes.Buffer
+				c, err := NewComposer(&buf, st, o.ComposerOptions())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err = c.WriteUint(42); err != nil {
+					t.Fatal(err)
+				}
+				if err = c.WriteInt64(-7); err != nil {
+					t.Fatal(err)
+				}
+				if err = c.Close(); err != nil {
+					t.Fatal(err)
+				}
+
+				// The values with embedded type are extracted without the expected type.
+				var expected bsttype.Type = st
+				if o.EmbedType {
+					expected = nil
+				}
+				x, err := NewExtractor(bytes.NewReader(buf.Bytes()), o.ExtractorOptions(expected))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer x.Close()
+				if !x.Next() {
+					t.Fatalf("no field: %v", x.Err())
+				}
+				id, err := x.ReadUint()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !x.Next() {
+					t.Fatalf("no field: %v", x.Err())
+				}
+				score, err := x.ReadInt64()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if id != 42 || score != -7 {
+					t.Fatalf("unexpected values: %d, %d", id, score)
+				}
+			})
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		invalid := map[string][]Option{
+			"ComparableCompatibility": {ForIndexKey(), WithCompatibilityMode()},
+			"ComparableEmbedType":     {ForIndexKey(), WithEmbedType(nil)},
+			"LengthPrefixedRPC":       {ForRPC(), WithLengthPrefixedCollections()},
+		}
+		for name, opts := range invalid {
+			if _, err := NewOptions(opts...); err == nil {
+				t.Errorf("%s: expected invalid options error", name)
+			}
+		}
+	})
+
+	t.Run("PresetOverrides", func(t *testing.T) {
+		o, err := NewOptions(WithCompatibilityMode(), WithEmbedType(nil), ForIndexKey())
+		if err != nil {
+			t.Fatalf("preset should override previous options: %v", err)
+		}
+		if !o.Comparable || o.CompatibilityMode || o.EmbedType {
+			t.Fatalf("unexpected options: %+v", o)
+		}
+	})
+}
+
+func TestForIndexKeyFloats(t *testing.T) {
+	type key struct {
+		V float64 `bst:"v"`
+	}
+	values := []float64{1, -1, 0, -2.5}
+	keys := make([][]byte, len(values))
+	for i, v := range values {
+		data, err := Marshal(key{V: v}, ForIndexKey())
+		if err != nil {
+			t.Fatalf("marshaling key of %v failed: %v", v, err)
+		}
+		keys[i] = data
+	}
+
+	// The keys of the preset sort just like their values, including the negative ones.
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	for i, expected := range []float64{-2.5, -1, 0, 1} {
+		var k key
+		if err := Unmarshal(keys[i], &k, ForIndexKey()); err != nil {
+			t.Fatalf("unmarshaling key %d failed: %v", i, err)
+		}
+		if k.V != expected {
+			t.Fatalf("unexpected key %d: %v, expected: %v", i, k.V, expected)
+		}
+	}
+
+	// The extractor options of the preset read the ordered floats.
+	o, err := NewOptions(ForIndexKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !o.ComposerOptions().OrderedFloats || !o.ExtractorOptions(nil).OrderedFloats || !o.ValueOptions().OrderedFloats {
+		t.Fatalf("ordered floats not set by the preset: %+v", o)
+	}
+}
@@ -0,0 +1,130 @@
+package bst
+
+import (
+	"errors"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+var _ io.WriterTo = (*Extractor)(nil)
+
+// WriteTo writes the binary of the current element to the writer, just as it is encoded, and finishes the element
+// as the Skip does. The element boundary is detected by skipping it, while the skipped bytes are copied to the writer,
+// thus the element is streamed without being buffered, i.e. by the copy-through proxies.
+// The booleans packed into the shared bytes, and the elements whose binaries depend on the other ones, i.e. the delta
+// encoded and the null bitmap array elements, could not be copied on their own.
+// Implements io.WriterTo interface.
+func (x *Extractor) WriteTo(w io.Writer) (int64, error) {
+	// 1. Check if the element could be copied.
+	if x.err != nil {
+		return 0, x.err
+	}
+	if x.elemDone {
+		return 0, bsterr.Err(bsterr.CodeAlreadyRead, "data element was already read")
+	}
+	if x.index > x.maxIndex {
+		return 0, bsterr.Err(bsterr.CodeOutOfBounds, "buffIndex out of bounds")
+	}
+	if x.delta || x.nullBitmapArray() || derefNamedType(x.elemType).Kind() == bsttype.KindBoolean {
+		return 0, bsterr.Err(bsterr.CodeInvalidType, "element binary could not be copied on its own").
+			WithDetail("type", x.elemType)
+	}
+
+	// 2. Skip the element, with the bytes read copied to the writer.
+	rc := rawCopier{rs: x.r, w: w}
+	skipFunc, opts := x.elemSkipFunc(x.elemType)
+	n, err := skipFunc(&rc, opts)
+	if err == nil {
+		err = rc.flush()
+	}
+	if err != nil {
+		return rc.written, err
+	}
+
+	// 3. Finish the element, just as it was skipped.
+	x.bytesRead += int(n)
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpSkip, nil)
+	}
+	x.finishElem()
+	return rc.written, nil
+}
+
+// rawCopier is the read seeker copying the bytes read out of the underlying one to the writer. The seeks forward
+// are replaced with the copies. The bytes of the last read are held back until the next read or seek, as the comparable
+// values are scanned past their terminator, and then seeked back to it.
+type rawCopier struct {
+	rs      io.ReadSeeker
+	w       io.Writer
+	pending []byte
+	written int64
+}
+
+// Read reads from the underlying reader, once the bytes of the previous read are copied.
+// Implements io.Reader interface.
+func (x *rawCopier) Read(p []byte) (int, error) {
+	if err := x.flush(); err != nil {
+		return 0, err
+	}
+	n, err := x.rs.Read(p)
+	x.pending = append(x.pending[:0], p[:n]...)
+	return n, err
+}
+
+// Seek copies the bytes up to the offset, or drops the bytes of the last read which are seeked back over.
+// Implements io.Seeker interface.
+func (x *rawCopier) Seek(offset int64, whence int) (int64, error) {
+	// 1. Find the offset relative to the current position.
+	rel := offset
+	switch whence {
+	case io.SeekCurrent:
+	case io.SeekStart:
+		cur, err := x.rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+		rel = offset - cur
+	default:
+		return 0, bsterr.Err(bsterr.CodeReadingFailed, "invalid raw copy seek whence").WithDetail("whence", whence)
+	}
+
+	// 2. The bytes seeked back over are read once again, thus they are not copied yet.
+	if rel <= 0 {
+		if -rel > int64(len(x.pending)) {
+			return 0, bsterr.Err(bsterr.CodeReadingFailed, "raw copy could not seek back beyond the last read").
+				WithDetail("offset", rel)
+		}
+		x.pending = x.pending[:len(x.pending)+int(rel)]
+		return x.rs.Seek(rel, io.SeekCurrent)
+	}
+
+	// 3. Copy the bytes seeked over.
+	if err := x.flush(); err != nil {
+		return 0, err
+	}
+	n, err := io.CopyN(x.w, x.rs, rel)
+	x.written += n
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, io.EOF
+		}
+		return 0, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to copy raw element binary")
+	}
+	return x.rs.Seek(0, io.SeekCurrent)
+}
+
+// flush copies the held back bytes of the last read.
+func (x *rawCopier) flush() error {
+	if len(x.pending) == 0 {
+		return nil
+	}
+	n, err := x.w.Write(x.pending)
+	x.written += int64(n)
+	x.pending = x.pending[:0]
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to copy raw element binary")
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package bst
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestExtractorWriteTo(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "name", Type: bsttype.String()},
+		{Index: 2, Name: "blob", Type: &bsttype.Bytes{}},
+		{Index: 3, Name: "tags", Type: bsttype.ArrayOf(bsttype.String())},
+		{Index: 4, Name: "count", Type: bsttype.Uint32()},
+		{Index: 5, Name: "trailer", Type: bsttype.String()},
+	}}
+	// record composes the struct value.
+	record := func(t *testing.T, opts ComposerOptions) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteString("name\x00\xff"); err != nil {
+			t.Fatalf("writing name failed: %v", err)
+		}
+		if err = c.WriteBytes(bytes.Repeat([]byte{0x00, 0xff}, 1<<12)); err != nil {
+			t.Fatalf("writing blob failed: %v", err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, tag := range []string{"a", "bc", "def"} {
+				if err := ac.WriteString(tag); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, 0)
+		if err != nil {
+			t.Fatalf("writing tags failed: %v", err)
+		}
+		if err = c.WriteUint32(42); err != nil {
+			t.Fatalf("writing count failed: %v", err)
+		}
+		if err = c.WriteString("end"); err != nil {
+			t.Fatalf("writing trailer failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	testCases := []struct {
+		name      string
+		opts      EncodingOptions
+		streaming bool
+	}{
+		{name: "Plain"},
+		{name: "Descending", opts: EncodingOptions{Descending: true}},
+		{name: "CompatibilityMode", opts: EncodingOptions{CompatibilityMode: true}},
+		{name: "Comparable", opts: EncodingOptions{Comparable: true}},
+		{name: "ComparableDescending", opts: EncodingOptions{Comparable: true, Descending: true}},
+		{name: "ComparableStreaming", opts: EncodingOptions{Comparable: true}, streaming: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := record(t, tc.opts.ComposerOptions())
+			opts := tc.opts.ExtractorOptions(st)
+			opts.Streaming = tc.streaming
+			x, err := NewExtractor(&plainReader{r: bytes.NewReader(data)}, opts)
+			if err != nil {
+				t.Fatalf("creating extractor failed: %v", err)
+			}
+			defer x.Close()
+
+			// Each copied field binary is the one between the offsets of the field value.
+			for x.Next() {
+				start := x.Offset()
+				var buf bytes.Buffer
+				n, err := x.WriteTo(&buf)
+				if err != nil {
+					t.Fatalf("copying field %d failed: %v", x.Index(), err)
+				}
+				if end := x.Offset(); !bytes.Equal(buf.Bytes(), data[start:end]) || int(n) != end-start {
+					t.Fatalf("copied field %d binary differs: %x != %x", x.Index(), buf.Bytes(), data[start:end])
+				}
+			}
+			if err = x.Err(); err != nil {
+				t.Fatalf("extracting failed: %v", err)
+			}
+		})
+	}
+
+	t.Run("Boolean", func(t *testing.T) {
+		bt := &bsttype.Struct{Fields: []bsttype.StructField{{Index: 1, Name: "flag", Type: bsttype.Boolean()}}}
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, bt, ComposerOptions{})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteBoolean(true); err != nil {
+			t.Fatalf("writing boolean failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		x, err := NewExtractor(bytes.NewReader(buf.Bytes()), ExtractorOptions{ExpectedType: bt})
+		if err != nil {
+			t.Fatalf("creating extractor failed: %v", err)
+		}
+		defer x.Close()
+		x.Next()
+		if _, err = x.WriteTo(&bytes.Buffer{}); err == nil {
+			t.Fatal("expected packed boolean error")
+		}
+	})
+}
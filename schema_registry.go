@@ -0,0 +1,16 @@
+package bst
+
+import (
+	"github.com/devmodules/bst/bsttype"
+)
+
+// SchemaRegistry is the runtime registry of the types, which lets the composed values carry only the compact
+// schema ID in their header, instead of the embedded type and modules. The extractor of such value needs
+// the registry with the same schema registered. See the bstreg.Registry.
+type SchemaRegistry interface {
+	// SchemaID returns the schema ID of the registered type, or false if the type is not registered.
+	SchemaID(t bsttype.Type) (uint64, bool)
+	// Schema returns the registered type and its modules by the schema ID. The modules are nil
+	// if the type has no named dependencies. The returned type and modules are not changed by the extractor.
+	Schema(id uint64) (bsttype.Type, *bsttype.Modules, error)
+}
@@ -0,0 +1,172 @@
+package bst
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bstskip"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+// WriteSet writes the set value to the composer, see the bsttype.SetOf. The input function writes the set elements
+// to the sub-composer in any order, and they are sorted once written, so that the sets of the same elements
+// have equal binaries. Writing a duplicate element fails.
+func (x *Composer) WriteSet(fn func(c *Composer) error) error {
+	// 1. Check if the element was already written.
+	if x.done {
+		return bsterr.Err(bsterr.CodeAlreadyWritten, "element already written")
+	}
+
+	// 2. Verify if current element is a set.
+	if at, ok := x.elemType.(*bsttype.Array); !ok || !at.IsSet() {
+		return bsterr.Err(bsterr.CodeInvalidType, "invalid type to write").
+			WithDetails(
+				bsterr.D("expected", "Set"),
+				bsterr.D("actual", x.elemType),
+			)
+	}
+
+	// 3. Write the elements as the ones of the array of undefined length.
+	return x.WriteArray(fn, 0)
+}
+
+// recordSetElem records the end of the set element in the buffer, once it is written.
+func (x *Composer) recordSetElem() error {
+	if at, ok := x.baseType.(*bsttype.Array); !ok || !at.IsSet() {
+		return nil
+	}
+	sb, ok := x.w.(*iopool.SharedBuffer)
+	if !ok {
+		return bsterr.Err(bsterr.CodeWritingFailed, "set elements are not buffered")
+	}
+	x.setBounds = append(x.setBounds, sb.Len())
+	return nil
+}
+
+// sortSetElems sorts the buffered elements of the set by their binaries, and verifies that they are unique.
+func (x *Composer) sortSetElems() error {
+	// 1. Split the buffered binary into the elements.
+	sb, ok := x.w.(*iopool.SharedBuffer)
+	if !ok {
+		return bsterr.Err(bsterr.CodeWritingFailed, "set elements are not buffered")
+	}
+	elems := make([][]byte, len(x.setBounds))
+	var start int
+	for i, end := range x.setBounds {
+		elems[i] = sb.Bytes[start:end]
+		start = end
+	}
+
+	// 2. Sort the elements, and replace the buffered binary with the sorted one.
+	if err := bstio.SortSetElements(elems); err != nil {
+		return err
+	}
+	sorted := make([]byte, 0, len(sb.Bytes))
+	for _, elem := range elems {
+		sorted = append(sorted, elem...)
+	}
+	copy(sb.Bytes, sorted)
+	return nil
+}
+
+// closeFixedSizeSet writes the sorted elements of the fixed size set, which has no length.
+func (x *Composer) closeFixedSizeSet() error {
+	// 1. Verify that all the elements of the set were written.
+	if x.index <= x.maxIndex {
+		return bsterr.Err(bsterr.CodeWritingFailed, "not all set elements were written").
+			WithDetails(bsterr.D("written", x.index), bsterr.D("expected", x.maxIndex+1))
+	}
+
+	// 2. Write the buffered elements, which bytes were already counted.
+	sb := x.w.(*iopool.SharedBuffer)
+	root := sb.Root
+	if _, err := sb.WriteTo(root); err != nil {
+		return x.flushFailed(sb, err)
+	}
+
+	// 3. Reset and release the buffer, and mark the set composer as done.
+	x.w = root
+	iopool.ReleaseBuffer(sb)
+	x.done = true
+	return nil
+}
+
+// ReadSet extracts and reads the set value, see the bsttype.SetOf. The elements are verified to be unique and sorted
+// before the input function reads them, thus the set of duplicate elements fails to be read.
+func (x *Extractor) ReadSet(fn func(x *Extractor) error) error {
+	// 1. Check if any previous reading had failed.
+	if x.err != nil {
+		return x.err
+	}
+
+	// 2. Ensure that the element is a set.
+	if at, ok := x.elemType.(*bsttype.Array); !ok || !at.IsSet() {
+		x.err = bsterr.Err(bsterr.CodeInvalidType, "invalid type to read").
+			WithDetails(
+				bsterr.D("expected", "Set"),
+				bsterr.D("actual", x.elemType),
+			)
+		return x.err
+	}
+
+	// 3. Read the set as an array, with its elements verified first.
+	return x.ReadArray(func(ax *Extractor) error {
+		if err := ax.verifySetElems(); err != nil {
+			return err
+		}
+		return fn(ax)
+	})
+}
+
+// verifySetElems verifies that the elements of the set extractor are unique and sorted by their binaries.
+// The reader is positioned back at the first element.
+func (x *Extractor) verifySetElems() error {
+	// 1. Prepare the skipper of the set elements.
+	at := x.embedType.(*bsttype.Array)
+	opts := bstio.ValueOptions{
+		Descending:        x.opts.Descending,
+		Comparable:        x.opts.Comparable,
+		CompatibilityMode: x.opts.CompatibilityMode,
+		LengthPrefixed:    x.opts.LengthPrefixedCollections,
+		OrderedFloats:     x.opts.OrderedFloats,
+	}
+	skip := bstskip.ElemSkipFuncOf(at.Type, opts)
+	start, err := x.r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	// 2. Compare the binary of each element with the previous one.
+	var prev []byte
+	pos := start
+	for i := 0; i <= x.maxIndex; i++ {
+		n, err := skip(x.r, opts)
+		if err != nil {
+			return err
+		}
+		if _, err = x.r.Seek(pos, io.SeekStart); err != nil {
+			return err
+		}
+		elem := make([]byte, n)
+		if _, err = io.ReadFull(x.r, elem); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read set element")
+		}
+		pos += n
+		if i > 0 {
+			switch c := bytes.Compare(prev, elem); {
+			case c == 0:
+				return bsterr.Err(bsterr.CodeInvalidValue, "duplicate set element").WithDetail("index", i)
+			case c > 0:
+				return bsterr.Err(bsterr.CodeInvalidValue, "set elements are not sorted").WithDetail("index", i)
+			}
+		}
+		prev = elem
+	}
+
+	// 3. Seek back to the first element.
+	_, err = x.r.Seek(start, io.SeekStart)
+	return err
+}
@@ -0,0 +1,192 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestSet(t *testing.T) {
+	set := bsttype.SetOf(bsttype.String())
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "tags", Type: set},
+		{Index: 2, Name: "trailer", Type: bsttype.String()},
+	}}
+	// record composes the struct of the set of given elements, written in their order.
+	record := func(t *testing.T, opts ComposerOptions, elems ...string) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		err = c.WriteSet(func(sc *Composer) error {
+			for _, e := range elems {
+				if err := sc.WriteString(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("writing set failed: %v", err)
+		}
+		if err = c.WriteString("end"); err != nil {
+			t.Fatalf("writing trailer failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+	// read extracts the elements of the set field.
+	read := func(data []byte, opts ExtractorOptions) ([]string, error) {
+		opts.ExpectedType = st
+		x, err := NewExtractor(bytes.NewReader(data), opts)
+		if err != nil {
+			return nil, err
+		}
+		defer x.Close()
+		var elems []string
+		x.Next()
+		err = x.ReadSet(func(sx *Extractor) error {
+			for sx.Next() {
+				e, err := sx.ReadString()
+				if err != nil {
+					return err
+				}
+				elems = append(elems, e)
+			}
+			return sx.Err()
+		})
+		if err != nil {
+			return nil, err
+		}
+		x.Next()
+		if trailer, err := x.ReadString(); err != nil || trailer != "end" {
+			return nil, errors.New("unexpected trailer: " + trailer)
+		}
+		return elems, nil
+	}
+	isInvalidValue := func(err error) bool {
+		for ; err != nil; err = errors.Unwrap(err) {
+			if be, ok := err.(*bsterr.Error); ok && be.Code == bsterr.CodeInvalidValue {
+				return true
+			}
+		}
+		return false
+	}
+
+	testCases := []struct {
+		name string
+		opts EncodingOptions
+	}{
+		{name: "Plain"},
+		{name: "Descending", opts: EncodingOptions{Descending: true}},
+		{name: "CompatibilityMode", opts: EncodingOptions{CompatibilityMode: true}},
+		{name: "Comparable", opts: EncodingOptions{Comparable: true}},
+		{name: "ComparableDescending", opts: EncodingOptions{Comparable: true, Descending: true}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The sets of the same elements have equal binaries, regardless of the order the elements are written in.
+			first := record(t, tc.opts.ComposerOptions(), "b", "c", "a")
+			second := record(t, tc.opts.ComposerOptions(), "c", "a", "b")
+			if !bytes.Equal(first, second) {
+				t.Fatalf("set binaries differ: %x != %x", first, second)
+			}
+			elems, err := read(first, tc.opts.ExtractorOptions(st))
+			if err != nil {
+				t.Fatalf("reading set failed: %v", err)
+			}
+			// The elements are sorted in the order of the value.
+			expected := []string{"a", "b", "c"}
+			if tc.opts.Descending {
+				expected = []string{"c", "b", "a"}
+			}
+			if !reflect.DeepEqual(elems, expected) {
+				t.Fatalf("unexpected elements: %v", elems)
+			}
+		})
+	}
+
+	t.Run("Duplicate", func(t *testing.T) {
+		// The duplicate elements fail to be written.
+		c, err := NewComposer(&bytes.Buffer{}, st, ComposerOptions{})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		err = c.WriteSet(func(sc *Composer) error {
+			for _, e := range []string{"a", "b", "a"} {
+				if err := sc.WriteString(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if !isInvalidValue(err) {
+			t.Fatalf("expected duplicate element error, got: %v", err)
+		}
+
+		// The binary of the duplicate elements, i.e. composed as the plain array, fails to be read as well.
+		plain := &bsttype.Struct{Fields: []bsttype.StructField{
+			{Index: 1, Name: "tags", Type: bsttype.ArrayOf(bsttype.String())},
+			{Index: 2, Name: "trailer", Type: bsttype.String()},
+		}}
+		var buf bytes.Buffer
+		if c, err = NewComposer(&buf, plain, ComposerOptions{}); err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, e := range []string{"a", "a"} {
+				if err := ac.WriteString(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, 2)
+		if err != nil {
+			t.Fatalf("writing array failed: %v", err)
+		}
+		if err = c.WriteString("end"); err != nil {
+			t.Fatalf("writing trailer failed: %v", err)
+		}
+		if _, err = read(buf.Bytes(), ExtractorOptions{}); !isInvalidValue(err) {
+			t.Fatalf("expected duplicate element error, got: %v", err)
+		}
+	})
+
+	t.Run("ArrayValue", func(t *testing.T) {
+		// The set value is encoded the same way by the composer and the array value.
+		av, err := bstvalue.ArrayValueOf(set, []bstvalue.Value{
+			bstvalue.NewStringValue("c"), bstvalue.NewStringValue("a"), bstvalue.NewStringValue("b"),
+		})
+		if err != nil {
+			t.Fatalf("creating array value failed: %v", err)
+		}
+		ab, err := av.MarshalValue(bstio.ValueOptions{})
+		if err != nil {
+			t.Fatalf("marshaling array value failed: %v", err)
+		}
+		if !bytes.Contains(record(t, ComposerOptions{}, "b", "a", "c"), ab) {
+			t.Fatalf("array value binary differs from the composed one")
+		}
+		av.Values = append(av.Values, bstvalue.NewStringValue("a"))
+		av.InvalidateMarshalCache()
+		if _, err = av.MarshalValue(bstio.ValueOptions{}); !isInvalidValue(err) {
+			t.Fatalf("expected duplicate element error, got: %v", err)
+		}
+	})
+
+	t.Run("Booleans", func(t *testing.T) {
+		if _, err := NewComposer(&bytes.Buffer{}, bsttype.SetOf(bsttype.Boolean()), ComposerOptions{}); err == nil {
+			t.Fatal("expected boolean set error")
+		}
+	})
+}
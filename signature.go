@@ -0,0 +1,242 @@
+package bst
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"hash"
+	"io"
+	"math"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+// SignatureAlgorithm is the algorithm of the value signature.
+type SignatureAlgorithm uint8
+
+// Enumerated signature algorithms. The values are a part of the signature trailer and never change.
+const (
+	// SignHMACSHA256 is the HMAC-SHA256 of the value, keyed by the secret shared by the signer and the verifier.
+	SignHMACSHA256 SignatureAlgorithm = 1
+	// SignEd25519 is the Ed25519ph signature of the SHA-512 of the value. The value is signed by the private key,
+	// and verified by the public one.
+	SignEd25519 SignatureAlgorithm = 2
+)
+
+// signatureTrailerSize is the size of the signature trailer, without the signature itself.
+const signatureTrailerSize = 4 + 1 + 1
+
+// SigningKey is the key of the value signatures.
+type SigningKey struct {
+	// Algorithm is the signature algorithm of the key.
+	Algorithm SignatureAlgorithm
+	// Key is the HMAC secret, or the ed25519.PrivateKey of the signer and the ed25519.PublicKey of the verifier.
+	Key []byte
+}
+
+// SignatureKeys are the keys of the value signatures, identified by the IDs recorded in each signature trailer,
+// so that the keys could be rotated while the values signed by the previous ones are still verified.
+//
+// The signed value binary is followed by its trailer, read from its end:
+//   - Signature (N bytes).
+//   - Key ID (uint32).
+//   - Algorithm (1 byte).
+//   - Signature size N (1 byte).
+//
+// The signature covers the whole value binary, including its header and embedded type, along with the key ID and
+// the algorithm of the trailer.
+type SignatureKeys struct {
+	// CurrentID is the ID of the key used to sign the values.
+	CurrentID uint32
+	// Keys are the keys by their IDs. All of them are active for the verification.
+	Keys map[uint32]SigningKey
+}
+
+// key returns the key of given ID.
+func (x *SignatureKeys) key(keyID uint32) (SigningKey, error) {
+	k, ok := x.Keys[keyID]
+	if !ok {
+		return SigningKey{}, bsterr.Err(bsterr.CodeInvalidSignature, "signature key not found").WithDetail("keyID", keyID)
+	}
+	return k, nil
+}
+
+// newSignatureHash creates the hash of the signed binary, for given key.
+func newSignatureHash(k SigningKey) (hash.Hash, error) {
+	switch k.Algorithm {
+	case SignHMACSHA256:
+		if len(k.Key) == 0 {
+			return nil, bsterr.Err(bsterr.CodeInvalidValue, "empty HMAC signature key")
+		}
+		return hmac.New(sha256.New, k.Key), nil
+	case SignEd25519:
+		if len(k.Key) != ed25519.PrivateKeySize && len(k.Key) != ed25519.PublicKeySize {
+			return nil, bsterr.Err(bsterr.CodeInvalidValue, "invalid Ed25519 signature key size").
+				WithDetail("size", len(k.Key))
+		}
+		return sha512.New(), nil
+	default:
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "unknown signature algorithm").WithDetail("algorithm", k.Algorithm)
+	}
+}
+
+// appendSignedTrailer appends the key ID and the algorithm, which are signed along with the value.
+func appendSignedTrailer(dst []byte, keyID uint32, alg SignatureAlgorithm) []byte {
+	return append(binary.BigEndian.AppendUint32(dst, keyID), byte(alg))
+}
+
+// valueSigner is the root writer of the composer with the Signature option, which hashes the written binary.
+// The signature trailer is written on the composer close.
+type valueSigner struct {
+	w     io.Writer
+	keyID uint32
+	key   SigningKey
+	h     hash.Hash
+}
+
+// Write writes the p to the underlying writer, and hashes the written part.
+// Implements io.Writer interface.
+func (x *valueSigner) Write(p []byte) (int, error) {
+	n, err := x.w.Write(p)
+	x.h.Write(p[:n])
+	return n, err
+}
+
+// reset prepares the signer of the current key, writing to w.
+func (x *valueSigner) reset(w io.Writer, keys *SignatureKeys) error {
+	k, err := keys.key(keys.CurrentID)
+	if err != nil {
+		return err
+	}
+	h, err := newSignatureHash(k)
+	if err != nil {
+		return err
+	}
+	if k.Algorithm == SignEd25519 && len(k.Key) != ed25519.PrivateKeySize {
+		return bsterr.Err(bsterr.CodeInvalidValue, "Ed25519 private key is required to sign values")
+	}
+	*x = valueSigner{w: w, keyID: keys.CurrentID, key: k, h: h}
+	return nil
+}
+
+// writeTrailer writes the signature trailer of the hashed binary.
+func (x *valueSigner) writeTrailer() (int, error) {
+	// 1. Sign the hashed binary along with the key ID and algorithm.
+	signed := appendSignedTrailer(nil, x.keyID, x.key.Algorithm)
+	x.h.Write(signed)
+	digest := x.h.Sum(nil)
+	sig := digest
+	if x.key.Algorithm == SignEd25519 {
+		var err error
+		sig, err = ed25519.PrivateKey(x.key.Key).Sign(nil, digest, &ed25519.Options{Hash: crypto.SHA512})
+		if err != nil {
+			return 0, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to sign value")
+		}
+	}
+
+	// 2. Write the trailer.
+	trailer := append(append(sig, signed...), byte(len(sig)))
+	n, err := x.w.Write(trailer)
+	if err != nil {
+		return n, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write signature trailer")
+	}
+	return n, nil
+}
+
+// verifySignature reads the whole signed value out of the extractor reader, and verifies its signature trailer.
+// The value is then extracted out of its verified binary, without the trailer.
+func (x *Extractor) verifySignature() error {
+	// 1. Read the signed value, up to the frame end and within the remaining decode budget. The budget reader
+	//    is bypassed, so that the value exceeding it is told apart, and the bytes read are used at once.
+	r, limit := x.r, int64(math.MaxInt64)
+	if br, ok := r.(*budgetReader); ok {
+		r = br.ReadSeeker
+	}
+	if x.opts.FrameSize > 0 {
+		limit = int64(x.opts.FrameSize)
+	}
+	var remaining int64 = -1
+	if x.opts.Budget != nil && x.opts.Budget.maxBytes > 0 {
+		used, _ := x.opts.Budget.Used()
+		remaining = max(x.opts.Budget.maxBytes-used, 0)
+		limit = min(limit, remaining+1)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit))
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read signed value")
+	}
+	if remaining >= 0 && int64(len(data)) > remaining {
+		return bsterr.Err(bsterr.CodeValueTooLarge, "signed value exceeds the remaining decode budget").
+			WithDetail("remaining", remaining)
+	}
+	if x.opts.Budget != nil {
+		if err = x.opts.Budget.useBytes(len(data)); err != nil {
+			return err
+		}
+	}
+
+	// 2. Release the shared reader wrapping the value.
+	if x.clearReader {
+		iopool.ReleaseReadSeeker(rootReader(x.r).(*iopool.SharedReadSeeker))
+		x.clearReader = false
+	}
+
+	// 3. Split the trailer, read from the end.
+	invalid := func(msg string) error {
+		return bsterr.Err(bsterr.CodeInvalidSignature, msg).WithDetail("size", len(data))
+	}
+	if len(data) < signatureTrailerSize {
+		return invalid("signature trailer not found")
+	}
+	sigSize := int(data[len(data)-1])
+	if len(data) < signatureTrailerSize+sigSize {
+		return invalid("signature trailer not found")
+	}
+	signedEnd := len(data) - 1
+	valueEnd := len(data) - signatureTrailerSize - sigSize
+	sig := data[valueEnd : valueEnd+sigSize]
+	keyID := binary.BigEndian.Uint32(data[valueEnd+sigSize:])
+	alg := SignatureAlgorithm(data[signedEnd-1])
+
+	// 4. Verify the signature by the key of its ID, which needs to match the algorithm.
+	k, err := x.opts.Signature.key(keyID)
+	if err != nil {
+		return err
+	}
+	if k.Algorithm != alg {
+		return bsterr.Err(bsterr.CodeInvalidSignature, "signature algorithm doesn't match its key").
+			WithDetails(bsterr.D("keyID", keyID), bsterr.D("algorithm", alg))
+	}
+	h, err := newSignatureHash(k)
+	if err != nil {
+		return err
+	}
+	h.Write(data[:valueEnd])
+	h.Write(data[valueEnd+sigSize : signedEnd])
+	digest := h.Sum(nil)
+
+	var valid bool
+	switch alg {
+	case SignHMACSHA256:
+		valid = hmac.Equal(sig, digest)
+	case SignEd25519:
+		pub := ed25519.PublicKey(k.Key)
+		if len(k.Key) == ed25519.PrivateKeySize {
+			pub = ed25519.PrivateKey(k.Key).Public().(ed25519.PublicKey)
+		}
+		valid = ed25519.VerifyWithOptions(pub, digest, sig, &ed25519.Options{Hash: crypto.SHA512}) == nil
+	}
+	if !valid {
+		return bsterr.Err(bsterr.CodeInvalidSignature, "invalid value signature").WithDetail("keyID", keyID)
+	}
+
+	// 5. Extract the value out of its verified binary. Its bytes were already used from the decode budget.
+	x.r = bytes.NewReader(data[:valueEnd])
+	x.startOffset = 0
+	return nil
+}
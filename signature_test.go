@@ -0,0 +1,222 @@
+package bst
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestSignature(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "name", Type: bsttype.String()},
+		{Index: 2, Name: "count", Type: bsttype.Uint32()},
+	}}
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key failed: %v", err)
+	}
+	hmacKeys := &SignatureKeys{CurrentID: 1, Keys: map[uint32]SigningKey{
+		1: {Algorithm: SignHMACSHA256, Key: []byte("first secret")},
+	}}
+	// sign composes the signed value of the struct type.
+	sign := func(t *testing.T, keys *SignatureKeys, embedType bool) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, ComposerOptions{Signature: keys, EmbedType: embedType})
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteString("signed"); err != nil {
+			t.Fatalf("writing string failed: %v", err)
+		}
+		if err = c.WriteUint32(42); err != nil {
+			t.Fatalf("writing uint32 failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+	// extract extracts the signed value out of r with given options, and checks its fields.
+	extract := func(t *testing.T, r io.ReadSeeker, opts ExtractorOptions) error {
+		t.Helper()
+		x, err := NewExtractor(r, opts)
+		if err != nil {
+			return err
+		}
+		defer x.Close()
+		var (
+			name  string
+			count uint32
+		)
+		for x.Next() {
+			switch x.Index() {
+			case 0:
+				name, err = x.ReadString()
+			case 1:
+				count, err = x.ReadUint32()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err = x.Err(); err != nil {
+			return err
+		}
+		if name != "signed" || count != 42 {
+			t.Fatalf("unexpected value: %q, %d", name, count)
+		}
+		return nil
+	}
+	// verify extracts the signed value, and checks its fields.
+	verify := func(t *testing.T, data []byte, keys *SignatureKeys, embedType bool) error {
+		t.Helper()
+		opts := ExtractorOptions{Signature: keys, ExpectedType: st}
+		if embedType {
+			opts.ExpectedType = nil
+		}
+		return extract(t, bytes.NewReader(data), opts)
+	}
+	hasCode := func(err error, code bsterr.ErrCode) bool {
+		var be *bsterr.Error
+		return errors.As(err, &be) && be.Code == code
+	}
+	isInvalidSignature := func(err error) bool {
+		for ; err != nil; err = errors.Unwrap(err) {
+			if be, ok := err.(*bsterr.Error); ok && be.Code == bsterr.CodeInvalidSignature {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("HMAC", func(t *testing.T) {
+		if err := verify(t, sign(t, hmacKeys, false), hmacKeys, false); err != nil {
+			t.Fatalf("verifying value failed: %v", err)
+		}
+	})
+
+	t.Run("Ed25519", func(t *testing.T) {
+		// The value is signed by the private key, and verified by the public one.
+		signer := &SignatureKeys{CurrentID: 7, Keys: map[uint32]SigningKey{7: {Algorithm: SignEd25519, Key: priv}}}
+		verifier := &SignatureKeys{Keys: map[uint32]SigningKey{7: {Algorithm: SignEd25519, Key: pub}}}
+		if err := verify(t, sign(t, signer, false), verifier, false); err != nil {
+			t.Fatalf("verifying value failed: %v", err)
+		}
+
+		// The public key could not sign the values.
+		if _, err := NewComposer(&bytes.Buffer{}, st, ComposerOptions{Signature: verifier}); err == nil {
+			t.Fatal("expected public key signing error")
+		}
+	})
+
+	t.Run("EmbeddedType", func(t *testing.T) {
+		// The embedded type is covered by the signature as well.
+		data := sign(t, hmacKeys, true)
+		if err := verify(t, data, hmacKeys, true); err != nil {
+			t.Fatalf("verifying value failed: %v", err)
+		}
+		tampered := append([]byte{}, data...)
+		tampered[1] ^= 0xff
+		if err := verify(t, tampered, hmacKeys, true); !isInvalidSignature(err) {
+			t.Fatalf("expected invalid signature error, got: %v", err)
+		}
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		// The values signed by the previous key are verified, while the new ones are signed by the current key.
+		rotated := &SignatureKeys{CurrentID: 2, Keys: map[uint32]SigningKey{
+			1: hmacKeys.Keys[1],
+			2: {Algorithm: SignEd25519, Key: priv},
+		}}
+		if err := verify(t, sign(t, hmacKeys, false), rotated, false); err != nil {
+			t.Fatalf("verifying previous key value failed: %v", err)
+		}
+		data := sign(t, rotated, false)
+		if err := verify(t, data, rotated, false); err != nil {
+			t.Fatalf("verifying current key value failed: %v", err)
+		}
+
+		// The key removed from the active ones doesn't verify its values anymore.
+		if err := verify(t, sign(t, hmacKeys, false), &SignatureKeys{Keys: map[uint32]SigningKey{
+			2: rotated.Keys[2],
+		}}, false); !isInvalidSignature(err) {
+			t.Fatalf("expected invalid signature error, got: %v", err)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		data := sign(t, hmacKeys, false)
+		for i := range data {
+			tampered := append([]byte{}, data...)
+			tampered[i] ^= 0x01
+			if err := verify(t, tampered, hmacKeys, false); err == nil {
+				t.Fatalf("expected error of tampered byte %d", i)
+			}
+		}
+		if err := verify(t, data[:len(data)-1], hmacKeys, false); !isInvalidSignature(err) {
+			t.Fatalf("expected invalid signature error of truncated value, got: %v", err)
+		}
+		other := &SignatureKeys{Keys: map[uint32]SigningKey{1: {Algorithm: SignHMACSHA256, Key: []byte("other")}}}
+		if err := verify(t, data, other, false); !isInvalidSignature(err) {
+			t.Fatalf("expected invalid signature error of other key, got: %v", err)
+		}
+	})
+
+	t.Run("FrameSize", func(t *testing.T) {
+		// The signed record is followed by the next one, thus the value is read up to its frame end.
+		data, next := sign(t, hmacKeys, false), sign(t, hmacKeys, true)
+		r := bytes.NewReader(append(bytes.Clone(data), next...))
+		opts := ExtractorOptions{Signature: hmacKeys, ExpectedType: st, FrameSize: len(data)}
+		if err := extract(t, r, opts); err != nil {
+			t.Fatalf("verifying framed value failed: %v", err)
+		}
+		if r.Len() != len(next) {
+			t.Fatalf("unexpected remaining bytes: %d", r.Len())
+		}
+		if err := extract(t, r, ExtractorOptions{Signature: hmacKeys, FrameSize: r.Len()}); err != nil {
+			t.Fatalf("verifying next framed value failed: %v", err)
+		}
+
+		// The frame cutting the trailer fails the verification.
+		opts.FrameSize = len(data) - 1
+		if err := extract(t, bytes.NewReader(data), opts); !isInvalidSignature(err) {
+			t.Fatalf("expected invalid signature error, got: %v", err)
+		}
+	})
+
+	t.Run("Budget", func(t *testing.T) {
+		// The signed value is read within the budget, and uses its bytes.
+		data := sign(t, hmacKeys, false)
+		budget := NewDecodeBudget(int64(len(data)), 0)
+		if err := extract(t, bytes.NewReader(data), ExtractorOptions{Signature: hmacKeys, ExpectedType: st, Budget: budget}); err != nil {
+			t.Fatalf("verifying value within budget failed: %v", err)
+		}
+		if used, _ := budget.Used(); used != int64(len(data)) {
+			t.Fatalf("unexpected budget bytes used: %d, expected: %d", used, len(data))
+		}
+
+		// The input exceeding the budget is not read as a whole.
+		budget = NewDecodeBudget(16, 0)
+		r := bytes.NewReader(append(bytes.Clone(data), make([]byte, 1<<20)...))
+		err := extract(t, r, ExtractorOptions{Signature: hmacKeys, ExpectedType: st, Budget: budget})
+		if !hasCode(err, bsterr.CodeValueTooLarge) {
+			t.Fatalf("expected value too large error, got: %v", err)
+		}
+		if read := r.Size() - int64(r.Len()); read != 17 {
+			t.Fatalf("unexpected bytes read: %d", read)
+		}
+	})
+
+	t.Run("Comparable", func(t *testing.T) {
+		opts := EncodingOptions{Comparable: true, Signature: hmacKeys}
+		if err := opts.Validate(); err == nil {
+			t.Fatal("expected comparable signature error")
+		}
+	})
+}
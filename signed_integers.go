@@ -32,7 +32,10 @@ func (x *Composer) WriteInt8(v int8) error {
 		x.bytesWritten += n
 	}
 
-	// 4. Write the value.
+	// 4. Write the value, as the difference from the previous element of the delta encoded array.
+	if x.delta {
+		v = deltaEncode(&x.deltaPrev, v)
+	}
 	n, err := bstio.WriteInt8(x.w, v, x.elemDesc)
 	if err != nil {
 		return err
@@ -73,7 +76,10 @@ func (x *Composer) WriteInt16(v int16) error {
 		x.bytesWritten += n
 	}
 
-	// 4. Write the value.
+	// 4. Write the value, as the difference from the previous element of the delta encoded array.
+	if x.delta {
+		v = deltaEncode(&x.deltaPrev, v)
+	}
 	n, err := bstio.WriteInt16(x.w, v, x.elemDesc)
 	if err != nil {
 		return err
@@ -114,7 +120,10 @@ func (x *Composer) WriteInt32(v int32) error {
 		x.bytesWritten += n
 	}
 
-	// 4. Write the value.
+	// 4. Write the value, as the difference from the previous element of the delta encoded array.
+	if x.delta {
+		v = deltaEncode(&x.deltaPrev, v)
+	}
 	n, err := bstio.WriteInt32(x.w, v, x.elemDesc)
 	if err != nil {
 		return err
@@ -155,7 +164,10 @@ func (x *Composer) WriteInt64(v int64) error {
 		x.bytesWritten += n
 	}
 
-	// 4. Write the value.
+	// 4. Write the value, as the difference from the previous element of the delta encoded array.
+	if x.delta {
+		v = deltaEncode(&x.deltaPrev, v)
+	}
 	n, err := bstio.WriteInt64(x.w, v, x.elemDesc)
 	if err != nil {
 		return err
@@ -201,7 +213,10 @@ func (x *Composer) WriteInt(v int) error {
 		x.bytesWritten += n
 	}
 
-	// 4. Write the value.
+	// 4. Write the value, as the difference from the previous element of the delta encoded array.
+	if x.delta {
+		v = deltaEncode(&x.deltaPrev, v)
+	}
 	n, err := bstio.WriteInt(x.w, v, x.elemDesc, x.opts.Comparable)
 	if err != nil {
 		return err
@@ -251,7 +266,13 @@ func (x *Extractor) ReadInt8() (int8, error) {
 	}
 
 	x.bytesRead += n
+	if x.delta {
+		v = deltaDecode(&x.deltaPrev, v)
+	}
 
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
@@ -272,6 +293,12 @@ func (x *Extractor) ReadInt16() (int16, error) {
 	}
 
 	// 3. Verify if current element matches the expected type.
+	//    The narrower integer of the compatibility mode is read, widened losslessly.
+	if x.coercible(bsttype.KindInt16) {
+		x.elemType = x.embeddedElemType()
+		v, err := x.Int()
+		return int16(v), err
+	}
 	if x.elemType.Kind() != bsttype.KindInt16 {
 		return 0, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
 			WithDetails(
@@ -287,7 +314,13 @@ func (x *Extractor) ReadInt16() (int16, error) {
 	}
 
 	x.bytesRead += n
+	if x.delta {
+		v = deltaDecode(&x.deltaPrev, v)
+	}
 
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
@@ -308,6 +341,12 @@ func (x *Extractor) ReadInt32() (int32, error) {
 	}
 
 	// 3. Verify if current element matches the expected type.
+	//    The narrower integer of the compatibility mode is read, widened losslessly.
+	if x.coercible(bsttype.KindInt32) {
+		x.elemType = x.embeddedElemType()
+		v, err := x.Int()
+		return int32(v), err
+	}
 	if x.elemType.Kind() != bsttype.KindInt32 {
 		return 0, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
 			WithDetails(
@@ -323,6 +362,12 @@ func (x *Extractor) ReadInt32() (int32, error) {
 	}
 
 	x.bytesRead += n
+	if x.delta {
+		v = deltaDecode(&x.deltaPrev, v)
+	}
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
@@ -343,6 +388,12 @@ func (x *Extractor) ReadInt64() (int64, error) {
 	}
 
 	// 3. Verify if current element matches the expected type.
+	//    The narrower integer of the compatibility mode is read, widened losslessly.
+	if x.coercible(bsttype.KindInt64) {
+		x.elemType = x.embeddedElemType()
+		v, err := x.Int()
+		return int64(v), err
+	}
 	if x.elemType.Kind() != bsttype.KindInt64 {
 		return 0, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
 			WithDetails(
@@ -358,7 +409,13 @@ func (x *Extractor) ReadInt64() (int64, error) {
 	}
 
 	x.bytesRead += n
+	if x.delta {
+		v = deltaDecode(&x.deltaPrev, v)
+	}
 
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
@@ -379,6 +436,12 @@ func (x *Extractor) ReadInt() (int, error) {
 	}
 
 	// 3. Verify if current element matches the expected type.
+	//    The narrower integer of the compatibility mode is read, widened losslessly.
+	if x.coercible(bsttype.KindInt) {
+		x.elemType = x.embeddedElemType()
+		v, err := x.Int()
+		return int(v), err
+	}
 	if x.elemType.Kind() != bsttype.KindInt {
 		return 0, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
 			WithDetails(
@@ -394,8 +457,14 @@ func (x *Extractor) ReadInt() (int, error) {
 	}
 
 	x.bytesRead += n
-	x.finishElem()
+	if x.delta {
+		v = deltaDecode(&x.deltaPrev, v)
+	}
 
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
+	x.finishElem()
 	return v, nil
 }
 
@@ -423,6 +492,9 @@ func (x *Extractor) Int() (int64, error) {
 			return 0, err
 		}
 		x.bytesRead += n
+		if x.delta {
+			v = deltaDecode(&x.deltaPrev, v)
+		}
 		res = int64(v)
 	case bsttype.KindInt16:
 		v, n, err := bstio.ReadInt16(x.r, x.elemDesc)
@@ -430,6 +502,9 @@ func (x *Extractor) Int() (int64, error) {
 			return 0, err
 		}
 		x.bytesRead += n
+		if x.delta {
+			v = deltaDecode(&x.deltaPrev, v)
+		}
 		res = int64(v)
 	case bsttype.KindInt32:
 		v, n, err := bstio.ReadInt32(x.r, x.elemDesc)
@@ -437,6 +512,9 @@ func (x *Extractor) Int() (int64, error) {
 			return 0, err
 		}
 		x.bytesRead += n
+		if x.delta {
+			v = deltaDecode(&x.deltaPrev, v)
+		}
 		res = int64(v)
 	case bsttype.KindInt64:
 		v, n, err := bstio.ReadInt64(x.r, x.elemDesc)
@@ -444,6 +522,9 @@ func (x *Extractor) Int() (int64, error) {
 			return 0, err
 		}
 		x.bytesRead += n
+		if x.delta {
+			v = deltaDecode(&x.deltaPrev, v)
+		}
 		res = v
 	case bsttype.KindInt:
 		v, n, err := bstio.ReadInt(x.r, x.elemDesc, x.opts.Comparable)
@@ -451,6 +532,9 @@ func (x *Extractor) Int() (int64, error) {
 			return 0, err
 		}
 		x.bytesRead += n
+		if x.delta {
+			v = deltaDecode(&x.deltaPrev, v)
+		}
 		res = int64(v)
 	default:
 		return 0, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
@@ -460,6 +544,9 @@ func (x *Extractor) Int() (int64, error) {
 			)
 	}
 
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, res)
+	}
 	x.finishElem()
 	return res, nil
 }
@@ -0,0 +1,66 @@
+package bst
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// experimentalTag is the build tag which constrains every file of the experimental packages.
+const experimentalTag = "bstexperimental"
+
+func TestStabilityTiers(t *testing.T) {
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			return err
+		}
+
+		// 1. Every file of the experimental packages requires the experimental build tag.
+		if strings.HasPrefix(filepath.ToSlash(path), "x/") {
+			if !requiresExperimentalTag(f.Comments) {
+				t.Errorf("experimental file %s is not constrained with the %q build tag", path, experimentalTag)
+			}
+			return nil
+		}
+
+		// 2. The stable packages never import the experimental ones.
+		for _, is := range f.Imports {
+			ip, _ := strconv.Unquote(is.Path.Value)
+			if ip == "github.com/devmodules/bst/x" || strings.HasPrefix(ip, "github.com/devmodules/bst/x/") {
+				t.Errorf("stable file %s imports experimental package %s", path, ip)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walking module files failed: %v", err)
+	}
+}
+
+// requiresExperimentalTag checks if the file build constraint is not satisfied without the experimental tag.
+func requiresExperimentalTag(comments []*ast.CommentGroup) bool {
+	for _, cg := range comments {
+		for _, c := range cg.List {
+			if !constraint.IsGoBuild(c.Text) {
+				continue
+			}
+			expr, err := constraint.Parse(c.Text)
+			if err != nil {
+				return false
+			}
+			return !expr.Eval(func(tag string) bool { return tag != experimentalTag })
+		}
+	}
+	return false
+}
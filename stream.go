@@ -0,0 +1,687 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// The stream is composed of the header byte, with the stream version and flags, followed by the rows.
+// Each row is prefixed with its record length (bstio uint).
+//
+// In the segmented stream, the rows are grouped into the segments, each composed of:
+//   - Number of the segment rows (bstio uint).
+//   - Length of the segment rows binary (bstio uint).
+//   - Optional CRC32C checksum of the segment rows binary (uint32).
+//   - Segment rows.
+//
+// In the back references mode, the row record is composed of:
+//   - Number of the row segments (bstio uint).
+//   - Bitmap of the segments which are the same as in the previous row, one bit per segment.
+//   - Each segment, where in the compatibility mode it is prefixed with its field index (bstio uint),
+//     and the segments other than the same as previous are followed by their length (bstio uint) and binary.
+//
+// The segment is the binary of a single struct field, or the packed booleans in the regular mode.
+// In the regular mode the previous segment is the one at the same position, and in the compatibility mode
+// the one with the same field index. The back references are reset at the start of each segment,
+// so that every segment could be decoded on its own.
+const (
+	// streamVersion is the version of the stream layout.
+	streamVersion = 1
+	// streamVersionMask is the mask of the version within the stream header.
+	streamVersionMask = 0x0f
+	// streamBackReferences is the flag of the streams with the back references.
+	streamBackReferences = 0x80
+	// streamCompatibility is the flag of the back references streams with the compatibility mode rows.
+	streamCompatibility = 0x40
+	// streamSegmented is the flag of the segmented streams.
+	streamSegmented = 0x20
+	// streamChecksums is the flag of the segmented streams with the segment checksums.
+	streamChecksums = 0x10
+)
+
+var _streamCastagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// StreamOptions are the options of the stream composer.
+type StreamOptions struct {
+	// Options are the binary options the row values are written with.
+	Options bstio.ValueOptions
+	// BackReferences enables the encoding where each struct field which binary is the same as in the previous row,
+	// is replaced with the "same as previous" marker. It drastically shrinks the streams of slowly-changing rows,
+	// at the cost of splitting each row into the field segments. It requires the stream type to be a struct.
+	BackReferences bool
+
+	// SegmentMaxBytes finalizes the stream segment once its rows binary reaches given size.
+	// Setting any of the segment options makes the stream segmented. Zero disables the limit.
+	SegmentMaxBytes int
+	// SegmentMaxRows finalizes the stream segment once it contains given number of rows. Zero disables the limit.
+	SegmentMaxRows int
+	// SegmentChecksums adds the CRC32C checksum of the segment rows to each segment header,
+	// which is verified by the StreamExtractor.
+	SegmentChecksums bool
+	// OnSegment is called with each completed segment, after it is written to the composer writer if it is defined.
+	// It allows i.e. uploading the segments as the parts of an object-store multipart upload.
+	OnSegment func(segment StreamSegment) error
+	// AsyncFlush writes the completed segments and calls the OnSegment in a background goroutine, so that writing
+	// the rows doesn't wait for them. The flush error is returned by the next call of the composer.
+	// The segments are flushed in order, and the Close waits for all of them.
+	AsyncFlush bool
+}
+
+// segmented returns true if any of the segment options is set.
+func (x StreamOptions) segmented() bool {
+	return x.SegmentMaxBytes > 0 || x.SegmentMaxRows > 0 || x.SegmentChecksums || x.OnSegment != nil || x.AsyncFlush
+}
+
+// StreamSegment is the completed segment of the segmented stream.
+type StreamSegment struct {
+	// Seq is the sequence number of the segment, starting from zero.
+	Seq int
+	// Rows is the number of rows in the segment.
+	Rows int
+	// Data is the segment binary, owned by the receiver. The data of the first segment starts with the stream header,
+	// so that the concatenation of all the segments data is the whole stream.
+	Data []byte
+}
+
+// StreamComposer writes the stream of the headless row values of a single type.
+// The stream is read by the StreamExtractor, which reconstructs the full rows.
+type StreamComposer struct {
+	w     io.Writer
+	st    *bsttype.Struct
+	opts  StreamOptions
+	rows  int
+	buf   bytes.Buffer
+	frame []byte
+	value bytes.Buffer
+
+	// Back references state: the copy of the previous row and its segments, and the spare row buffer.
+	prev         []byte
+	prevSegments []structSegment
+	spare        []byte
+
+	// Segmentation state: the pending stream header, the current segment rows and the async flush worker.
+	segmented   bool
+	header      []byte
+	segment     bytes.Buffer
+	segmentRows int
+	segmentSeq  int
+	closed      bool
+	flushes     chan StreamSegment
+	flushed     chan struct{}
+	flushMu     sync.Mutex
+	flushErr    error
+}
+
+// NewStreamComposer creates a new stream composer of the rows of type t, and writes the stream header to w.
+// In the segmented stream, the header is written with the first segment, and the w could be nil if the segments
+// are consumed only by the OnSegment callback. The segmented stream composer needs to be closed.
+func NewStreamComposer(w io.Writer, t bsttype.Type, options StreamOptions) (*StreamComposer, error) {
+	x := &StreamComposer{w: w, opts: options}
+
+	// 1. The back references are defined over the struct fields.
+	header := byte(streamVersion)
+	if options.BackReferences {
+		st, ok := derefNamedType(t).(*bsttype.Struct)
+		if !ok {
+			return nil, bsterr.Err(bsterr.CodeInvalidType, "stream back references are supported only for struct types").
+				WithDetail("type", t)
+		}
+		x.st = st
+		header |= streamBackReferences
+		if options.Options.CompatibilityMode {
+			header |= streamCompatibility
+		}
+	}
+
+	// 2. The segmented stream defers the header until the first segment, and starts the async flush worker.
+	if options.segmented() {
+		header |= streamSegmented
+		if options.SegmentChecksums {
+			header |= streamChecksums
+		}
+		x.segmented = true
+		x.header = []byte{header}
+		if options.AsyncFlush {
+			x.flushes = make(chan StreamSegment, 1)
+			x.flushed = make(chan struct{})
+			go x.flushWorker()
+		}
+		return x, nil
+	}
+
+	// 3. Write the stream header.
+	if w == nil {
+		return nil, bsterr.Err(bsterr.CodeInvalidValue, "stream writer is not defined")
+	}
+	if _, err := w.Write([]byte{header}); err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write stream header")
+	}
+	return x, nil
+}
+
+// Rows returns the number of the rows written so far.
+func (x *StreamComposer) Rows() int {
+	return x.rows
+}
+
+// WriteValue writes the row with the binary written by the value writer, with the stream value options.
+func (x *StreamComposer) WriteValue(vw ValueWriter) error {
+	x.value.Reset()
+	if _, err := vw.WriteValue(&x.value, x.opts.Options); err != nil {
+		return err
+	}
+	return x.WriteRow(x.value.Bytes())
+}
+
+// WriteRow writes the headless row binary, encoded with the stream value options.
+// The row is written with a single Write call, or added to the current segment of the segmented stream.
+func (x *StreamComposer) WriteRow(row []byte) error {
+	if x.closed {
+		return bsterr.Err(bsterr.CodeAlreadyWritten, "stream composer is already closed")
+	}
+	if err := x.asyncErr(); err != nil {
+		return err
+	}
+
+	// 1. Encode the row record.
+	record := row
+	if x.st != nil {
+		var err error
+		if record, err = x.backReferenceRecord(row); err != nil {
+			return err
+		}
+	}
+
+	// 2. Frame the record.
+	x.frame = bstio.AppendUint(x.frame[:0], uint(len(record)), false)
+	x.frame = append(x.frame, record...)
+
+	// 3. Add the record to the segment, and finalize it once it reaches the limits.
+	if x.segmented {
+		x.segment.Write(x.frame)
+		x.segmentRows++
+		x.rows++
+		if (x.opts.SegmentMaxBytes > 0 && x.segment.Len() >= x.opts.SegmentMaxBytes) ||
+			(x.opts.SegmentMaxRows > 0 && x.segmentRows >= x.opts.SegmentMaxRows) {
+			return x.finalizeSegment()
+		}
+		return nil
+	}
+
+	// 4. Write the record.
+	if _, err := x.w.Write(x.frame); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write stream row").
+			WithDetail("row", x.rows)
+	}
+	x.rows++
+	return nil
+}
+
+// Flush finalizes the current segment of the segmented stream, even if it doesn't reach the limits.
+// With the AsyncFlush it waits for all the segments to be flushed. It is a no-op for other streams.
+func (x *StreamComposer) Flush() error {
+	if !x.segmented || x.closed {
+		return nil
+	}
+	if err := x.finalizeSegment(); err != nil {
+		return err
+	}
+	if x.flushes == nil {
+		return nil
+	}
+
+	// The worker is restarted after all the pending segments are flushed.
+	close(x.flushes)
+	<-x.flushed
+	x.flushes = make(chan StreamSegment, 1)
+	x.flushed = make(chan struct{})
+	go x.flushWorker()
+	return x.asyncErr()
+}
+
+// Close finalizes the last segment of the segmented stream, and waits for all the segments to be flushed.
+// No more rows could be written after the Close.
+func (x *StreamComposer) Close() error {
+	if x.closed {
+		return nil
+	}
+	var err error
+	if x.segmented {
+		err = x.finalizeSegment()
+	}
+	x.closed = true
+	if x.flushes != nil {
+		close(x.flushes)
+		<-x.flushed
+		if err == nil {
+			err = x.asyncErr()
+		}
+	}
+	return err
+}
+
+// finalizeSegment completes the current segment and flushes it. The back references are reset,
+// so that the next segment doesn't depend on this one.
+func (x *StreamComposer) finalizeSegment() error {
+	if x.segmentRows == 0 {
+		return nil
+	}
+
+	// 1. Compose the segment binary, the first one starts with the stream header.
+	payload := x.segment.Bytes()
+	data := make([]byte, 0, len(x.header)+len(payload)+22)
+	data = append(data, x.header...)
+	data = bstio.AppendUint(data, uint(x.segmentRows), false)
+	data = bstio.AppendUint(data, uint(len(payload)), false)
+	if x.opts.SegmentChecksums {
+		data = bstio.AppendUint32(data, crc32.Checksum(payload, _streamCastagnoli), false)
+	}
+	data = append(data, payload...)
+	segment := StreamSegment{Seq: x.segmentSeq, Rows: x.segmentRows, Data: data}
+
+	// 2. Reset the segment state.
+	x.header = nil
+	x.segment.Reset()
+	x.segmentRows = 0
+	x.segmentSeq++
+	x.prevSegments = nil
+
+	// 3. Flush the segment.
+	if x.flushes != nil {
+		x.flushes <- segment
+		return nil
+	}
+	return x.flushSegment(segment)
+}
+
+// flushSegment writes the segment to the composer writer, and passes it to the OnSegment callback.
+func (x *StreamComposer) flushSegment(segment StreamSegment) error {
+	if x.w != nil {
+		if _, err := x.w.Write(segment.Data); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write stream segment").
+				WithDetail("segment", segment.Seq)
+		}
+	}
+	if x.opts.OnSegment != nil {
+		if err := x.opts.OnSegment(segment); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "stream segment callback failed").
+				WithDetail("segment", segment.Seq)
+		}
+	}
+	return nil
+}
+
+// flushWorker flushes the segments in the background. After the first failure the remaining segments are dropped.
+func (x *StreamComposer) flushWorker() {
+	defer close(x.flushed)
+	for segment := range x.flushes {
+		if x.asyncErr() != nil {
+			continue
+		}
+		if err := x.flushSegment(segment); err != nil {
+			x.flushMu.Lock()
+			x.flushErr = err
+			x.flushMu.Unlock()
+		}
+	}
+}
+
+// asyncErr returns the error of the background segments flush.
+func (x *StreamComposer) asyncErr() error {
+	x.flushMu.Lock()
+	defer x.flushMu.Unlock()
+	return x.flushErr
+}
+
+// backReferenceRecord encodes the row record, with the segments same as in the previous row replaced by the markers.
+func (x *StreamComposer) backReferenceRecord(row []byte) ([]byte, error) {
+	// 1. Split the copy of the row, which becomes the previous row for the next one.
+	cur := append(x.spare[:0], row...)
+	segments, err := splitStructSegmentsOf(cur, x.st, x.opts.Options)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to split stream row").
+			WithDetail("row", x.rows)
+	}
+	compat := x.opts.Options.CompatibilityMode
+
+	// 2. Mark the segments which binary is the same as in the previous row.
+	bitmap := make([]byte, (len(segments)+7)>>3)
+	for i, sg := range segments {
+		if prev, ok := previousSegment(x.prevSegments, i, sg.index, compat); ok && bytes.Equal(prev.data, sg.data) {
+			bitmap[i>>3] |= 1 << (i & 7)
+		}
+	}
+
+	// 3. Write the record.
+	x.buf.Reset()
+	_, _ = bstio.WriteUint(&x.buf, uint(len(segments)), false)
+	x.buf.Write(bitmap)
+	for i, sg := range segments {
+		if compat {
+			_, _ = bstio.WriteUint(&x.buf, sg.index, false)
+		}
+		if bitmap[i>>3]&(1<<(i&7)) != 0 {
+			continue
+		}
+		_, _ = bstio.WriteUint(&x.buf, uint(len(sg.data)), false)
+		x.buf.Write(sg.data)
+	}
+
+	// 4. Keep the row as the previous one.
+	x.spare, x.prev = x.prev, cur
+	x.prevSegments = segments
+	return x.buf.Bytes(), nil
+}
+
+// previousSegment returns the segment of the previous row matching the segment at position i with given index.
+func previousSegment(prev []structSegment, i int, index uint, compat bool) (structSegment, bool) {
+	if !compat {
+		if i < len(prev) {
+			return prev[i], true
+		}
+		return structSegment{}, false
+	}
+	for _, sg := range prev {
+		if sg.index == index {
+			return sg, true
+		}
+	}
+	return structSegment{}, false
+}
+
+// StreamExtractor reads the rows of the stream written by the StreamComposer.
+// The rows written with the back references are reconstructed transparently.
+type StreamExtractor struct {
+	// SkipRows is the number of rows skipped after each read row, which allows sampling every (SkipRows+1)th row
+	// i.e. for the quick data profiling of huge streams. The skipped rows are never reconstructed,
+	// and without the back references their binary is discarded without being decoded.
+	SkipRows int
+	// MaxRowSize is the maximum binary size of the row accepted by the extractor.
+	// The larger row lengths are reported as the bsterr.CodeMalformedBinary error, instead of being allocated.
+	MaxRowSize uint
+	// MaxSegmentSize is the maximum binary size of the segment rows accepted by the extractor of the segmented stream.
+	// The larger segment lengths are reported as the bsterr.CodeMalformedBinary error, instead of being allocated.
+	MaxSegmentSize uint
+
+	r         io.Reader
+	backRefs  bool
+	compat    bool
+	row       []byte
+	rows      int
+	segments  []structSegment
+	err       error
+	exhausted bool
+	started   bool
+
+	// Segmented stream state: the current segment rows binary, and the number of its rows read and expected.
+	segmented   bool
+	checksums   bool
+	segment     *bytes.Reader
+	segmentRows uint
+}
+
+// DefaultStreamMaxRowSize is the default maximum binary size of the row accepted by the StreamExtractor.
+const DefaultStreamMaxRowSize = 64 << 20
+
+// DefaultStreamMaxSegmentSize is the default maximum binary size of the segment rows accepted by the StreamExtractor.
+const DefaultStreamMaxSegmentSize = 64 << 20
+
+// NewStreamExtractor creates a new stream extractor, reading the stream header from r.
+func NewStreamExtractor(r io.Reader) (*StreamExtractor, error) {
+	// 1. Read the stream header.
+	var header [1]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read stream header")
+	}
+
+	// 2. Verify the stream version.
+	if v := header[0] & streamVersionMask; v != streamVersion {
+		return nil, bsterr.Err(bsterr.CodeMalformedBinary, "unsupported stream version").
+			WithDetail("version", v)
+	}
+	return &StreamExtractor{
+		MaxRowSize:     DefaultStreamMaxRowSize,
+		MaxSegmentSize: DefaultStreamMaxSegmentSize,
+		r:              r,
+		backRefs:       header[0]&streamBackReferences != 0,
+		compat:         header[0]&streamCompatibility != 0,
+		segmented:      header[0]&streamSegmented != 0,
+		checksums:      header[0]&streamChecksums != 0,
+	}, nil
+}
+
+// Next reads the next row, after skipping the SkipRows rows following the previous one.
+// It returns false at the end of the stream or on an error.
+func (x *StreamExtractor) Next() bool {
+	// 1. Skip the rows between the sampled ones.
+	if x.started {
+		for i := 0; i < x.SkipRows; i++ {
+			if !x.readRow(false) {
+				return false
+			}
+		}
+	}
+	x.started = true
+
+	// 2. Read the row.
+	return x.readRow(true)
+}
+
+// Index returns the position of the last read row within the stream, including the skipped rows.
+func (x *StreamExtractor) Index() int {
+	return x.rows - 1
+}
+
+// readRow reads the next row record. If the row is not decoded, its binary is only skipped,
+// or in the back references stream its segments are resolved without joining them into the row.
+func (x *StreamExtractor) readRow(decode bool) bool {
+	if x.err != nil || x.exhausted {
+		return false
+	}
+
+	// 1. In the segmented stream the rows are read from the current segment, which is followed by the next one.
+	r := x.r
+	if x.segmented {
+		for x.segment == nil || x.segment.Len() == 0 {
+			if x.segment != nil && x.segmentRows != 0 {
+				return x.fail(bsterr.Err(bsterr.CodeMalformedBinary, "stream segment rows are missing").
+					WithDetail("missing", x.segmentRows))
+			}
+			ok, err := x.readSegment()
+			if err != nil {
+				return x.fail(err)
+			}
+			if !ok {
+				x.exhausted = true
+				return false
+			}
+		}
+		if x.segmentRows == 0 {
+			return x.fail(bsterr.Err(bsterr.CodeMalformedBinary, "stream segment exceeds its rows"))
+		}
+		x.segmentRows--
+		r = x.segment
+	}
+
+	// 2. Read the record length. A clean end of the stream occurs only before the row.
+	length, n, err := bstio.ReadUint(r, false)
+	if err != nil {
+		if n == 0 && errors.Is(err, io.EOF) {
+			x.exhausted = true
+			return false
+		}
+		return x.fail(bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read stream row length"))
+	}
+
+	// 3. Read the record, whose length is verified before it is allocated.
+	if length > x.MaxRowSize {
+		return x.fail(bsterr.Err(bsterr.CodeMalformedBinary, "stream row length exceeds the maximum row size").
+			WithDetails(bsterr.D("row", x.rows), bsterr.D("length", length), bsterr.D("maxRowSize", x.MaxRowSize)))
+	}
+	if x.segmented && length > uint(x.segment.Len()) {
+		return x.fail(bsterr.Err(bsterr.CodeMalformedBinary, "stream row length exceeds the segment").
+			WithDetails(bsterr.D("row", x.rows), bsterr.D("length", length)))
+	}
+	if !decode && !x.backRefs {
+		if _, err = io.CopyN(io.Discard, r, int64(length)); err != nil {
+			return x.fail(bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to skip stream row"))
+		}
+		x.rows++
+		return true
+	}
+	if err = bstio.CheckLength(r, length, 1); err != nil {
+		return x.fail(err)
+	}
+	record := make([]byte, length)
+	if _, err = io.ReadFull(r, record); err != nil {
+		return x.fail(bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read stream row"))
+	}
+
+	// 4. Reconstruct the row.
+	if !x.backRefs {
+		x.row = record
+	} else if err = x.resolveBackReferences(record, decode); err != nil {
+		return x.fail(err)
+	}
+	x.rows++
+	return true
+}
+
+// Row returns the headless binary of the last read row.
+func (x *StreamExtractor) Row() []byte {
+	return x.row
+}
+
+// Err returns the error which stopped the extractor.
+func (x *StreamExtractor) Err() error {
+	return x.err
+}
+
+// resolveBackReferences reconstructs the row from the record, with the markers replaced by the previous row segments.
+// If the row is not joined, only its segments are kept for the next row.
+func (x *StreamExtractor) resolveBackReferences(record []byte, join bool) error {
+	r := bytes.NewReader(record)
+
+	// 1. Read the number of segments and the bitmap of the same as previous segments.
+	count, _, err := bstio.ReadUint(r, false)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read stream row segments count")
+	}
+	if (count+7)>>3 > uint(r.Len()) {
+		return bsterr.Err(bsterr.CodeMalformedBinary, "number of stream row segments exceeds the record").
+			WithDetails(bsterr.D("row", x.rows), bsterr.D("count", count))
+	}
+	bitmap := make([]byte, (count+7)>>3)
+	if _, err = io.ReadFull(r, bitmap); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeMalformedBinary, "failed to read stream row bitmap")
+	}
+
+	// 2. Read the segments, and resolve the references to the previous row.
+	segments := make([]structSegment, 0, count)
+	for i := 0; i < int(count); i++ {
+		var index uint
+		if x.compat {
+			if index, _, err = bstio.ReadUint(r, false); err != nil {
+				return bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read stream row segment index")
+			}
+		}
+
+		if bitmap[i>>3]&(1<<(i&7)) != 0 {
+			prev, ok := previousSegment(x.segments, i, index, x.compat)
+			if !ok {
+				return bsterr.Err(bsterr.CodeMalformedBinary, "stream row references undefined previous segment").
+					WithDetails(bsterr.D("row", x.rows), bsterr.D("segment", i))
+			}
+			segments = append(segments, structSegment{index: index, count: 1, data: prev.data})
+			continue
+		}
+
+		size, _, err := bstio.ReadUint(r, false)
+		if err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to read stream row segment length")
+		}
+		if uint(r.Len()) < size {
+			return bsterr.Err(bsterr.CodeMalformedBinary, "stream row segment length exceeds the record").
+				WithDetails(bsterr.D("row", x.rows), bsterr.D("length", size))
+		}
+		start := len(record) - r.Len()
+		segments = append(segments, structSegment{index: index, count: 1, data: record[start : start+int(size)]})
+		_, _ = r.Seek(int64(size), io.SeekCurrent)
+	}
+
+	// 3. Join the segments into the row binary.
+	x.segments = segments
+	if !join {
+		return nil
+	}
+	var buf bytes.Buffer
+	if x.compat {
+		if err = writeStructSegments(&buf, segments); err != nil {
+			return err
+		}
+	} else {
+		for _, sg := range segments {
+			buf.Write(sg.data)
+		}
+	}
+	x.row = buf.Bytes()
+	return nil
+}
+
+// readSegment reads the next segment of the segmented stream, and verifies its checksum.
+// It returns false at the clean end of the stream. The back references are reset at the start of the segment.
+func (x *StreamExtractor) readSegment() (bool, error) {
+	// 1. Read the segment header.
+	rows, n, err := bstio.ReadUint(x.r, false)
+	if err != nil {
+		if n == 0 && errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read stream segment header")
+	}
+	length, _, err := bstio.ReadUint(x.r, false)
+	if err != nil {
+		return false, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read stream segment length")
+	}
+	if length > x.MaxSegmentSize {
+		return false, bsterr.Err(bsterr.CodeMalformedBinary, "stream segment length exceeds the maximum segment size").
+			WithDetails(bsterr.D("row", x.rows), bsterr.D("length", length), bsterr.D("maxSegmentSize", x.MaxSegmentSize))
+	}
+	var crc uint32
+	if x.checksums {
+		if crc, _, err = bstio.ReadUint32(x.r, false); err != nil {
+			return false, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read stream segment checksum")
+		}
+	}
+
+	// 2. Read the segment rows binary, and verify its checksum.
+	if err = bstio.CheckLength(x.r, length, 1); err != nil {
+		return false, err
+	}
+	payload := make([]byte, length)
+	if _, err = io.ReadFull(x.r, payload); err != nil {
+		return false, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read stream segment")
+	}
+	if x.checksums && crc32.Checksum(payload, _streamCastagnoli) != crc {
+		return false, bsterr.Err(bsterr.CodeMalformedBinary, "stream segment checksum mismatch").
+			WithDetail("row", x.rows)
+	}
+
+	// 3. Set up the segment.
+	x.segment = bytes.NewReader(payload)
+	x.segmentRows = rows
+	x.segments = nil
+	return true, nil
+}
+
+func (x *StreamExtractor) fail(err error) bool {
+	x.err = err
+	return false
+}
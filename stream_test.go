@@ -0,0 +1,316 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestStream(t *testing.T) {
+	st := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "ID", Type: bsttype.Uint64()},
+			{Index: 2, Name: "Name", Type: bsttype.String()},
+			{Index: 3, Name: "Country", Type: bsttype.String()},
+			{Index: 4, Name: "Active", Type: bsttype.Boolean()},
+			{Index: 5, Name: "Admin", Type: bsttype.Boolean(), Descending: true},
+			{Index: 6, Name: "Score", Type: bsttype.Int64()},
+		},
+	}
+
+	// The slowly-changing rows, where only the ID changes with each row.
+	rows := func(t *testing.T, o bstio.ValueOptions) [][]byte {
+		var out [][]byte
+		for i := 0; i < 100; i++ {
+			sv := bstvalue.MustNewStructValue(st, []bstvalue.Value{
+				bstvalue.NewUint64Value(uint64(i)),
+				bstvalue.NewStringValue("customer with a long name"),
+				bstvalue.NewStringValue("Poland"),
+				bstvalue.NewBoolValue(i < 50),
+				bstvalue.NewBoolValue(false),
+				bstvalue.NewInt64Value(int64(i / 10)),
+			})
+			data, err := sv.MarshalValue(o)
+			if err != nil {
+				t.Fatalf("marshaling row failed: %v", err)
+			}
+			out = append(out, data)
+		}
+		return out
+	}
+
+	compose := func(t *testing.T, in [][]byte, options StreamOptions) []byte {
+		var buf bytes.Buffer
+		sc, err := NewStreamComposer(&buf, st, options)
+		if err != nil {
+			t.Fatalf("creating stream composer failed: %v", err)
+		}
+		for _, row := range in {
+			if err = sc.WriteRow(row); err != nil {
+				t.Fatalf("writing stream row failed: %v", err)
+			}
+		}
+		if sc.Rows() != len(in) {
+			t.Fatalf("unexpected number of rows: %d", sc.Rows())
+		}
+		return buf.Bytes()
+	}
+
+	extract := func(t *testing.T, stream []byte) [][]byte {
+		se, err := NewStreamExtractor(bytes.NewReader(stream))
+		if err != nil {
+			t.Fatalf("creating stream extractor failed: %v", err)
+		}
+		var out [][]byte
+		for se.Next() {
+			out = append(out, se.Row())
+		}
+		if err = se.Err(); err != nil {
+			t.Fatalf("extracting stream failed: %v", err)
+		}
+		return out
+	}
+
+	for _, o := range []bstio.ValueOptions{{}, {Descending: true}} {
+		in := rows(t, o)
+		plain := compose(t, in, StreamOptions{Options: o})
+		backRefs := compose(t, in, StreamOptions{Options: o, BackReferences: true})
+		if 2*len(backRefs) > len(plain) {
+			t.Fatalf("back references stream is not shrunk (options: %+v): %d bytes, plain: %d bytes", o, len(backRefs), len(plain))
+		}
+
+		for _, stream := range [][]byte{plain, backRefs} {
+			out := extract(t, stream)
+			if len(out) != len(in) {
+				t.Fatalf("unexpected number of extracted rows: %d", len(out))
+			}
+			for i := range in {
+				if !bytes.Equal(out[i], in[i]) {
+					t.Fatalf("unexpected row %d (options: %+v):\n%v\nexpected:\n%v", i, o, out[i], in[i])
+				}
+			}
+		}
+	}
+
+	t.Run("Compatibility", func(t *testing.T) {
+		ct := &bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "A", Type: bsttype.Uint8()},
+				{Index: 2, Name: "B", Type: bsttype.Uint8()},
+				{Index: 3, Name: "C", Type: bsttype.Uint8()},
+			},
+		}
+		// The rows missing a field are composed with the older revisions of the type.
+		in := [][]byte{
+			composeCompatibilityRow(t, &bsttype.Struct{Fields: []bsttype.StructField{ct.Fields[0], ct.Fields[2]}}, 5, 7),
+			composeCompatibilityRow(t, ct, 5, 3, 7),
+			composeCompatibilityRow(t, &bsttype.Struct{Fields: ct.Fields[1:]}, 3, 8),
+		}
+
+		var buf bytes.Buffer
+		sc, err := NewStreamComposer(&buf, ct, StreamOptions{
+			Options:        bstio.ValueOptions{CompatibilityMode: true},
+			BackReferences: true,
+		})
+		if err != nil {
+			t.Fatalf("creating stream composer failed: %v", err)
+		}
+		for _, row := range in {
+			if err = sc.WriteRow(row); err != nil {
+				t.Fatalf("writing stream row failed: %v", err)
+			}
+		}
+
+		out := extract(t, buf.Bytes())
+		if len(out) != len(in) {
+			t.Fatalf("unexpected number of extracted rows: %d", len(out))
+		}
+		for i := range in {
+			if !bytes.Equal(out[i], in[i]) {
+				t.Fatalf("unexpected row %d:\n%v\nexpected:\n%v", i, out[i], in[i])
+			}
+		}
+	})
+
+	t.Run("Segmented", func(t *testing.T) {
+		in := rows(t, bstio.ValueOptions{})
+		for _, async := range []bool{false, true} {
+			var buf bytes.Buffer
+			var segments []StreamSegment
+			sc, err := NewStreamComposer(&buf, st, StreamOptions{
+				BackReferences:   true,
+				SegmentMaxRows:   30,
+				SegmentChecksums: true,
+				AsyncFlush:       async,
+				OnSegment: func(segment StreamSegment) error {
+					segments = append(segments, segment)
+					return nil
+				},
+			})
+			if err != nil {
+				t.Fatalf("creating stream composer failed: %v", err)
+			}
+			for _, row := range in {
+				if err = sc.WriteRow(row); err != nil {
+					t.Fatalf("writing stream row failed: %v", err)
+				}
+			}
+			if err = sc.Close(); err != nil {
+				t.Fatalf("closing stream composer failed: %v", err)
+			}
+
+			// The rows are split into the segments of 30, 30, 30 and 10 rows, which concatenation is the stream.
+			var joined []byte
+			for i, segment := range segments {
+				if segment.Seq != i || segment.Rows != []int{30, 30, 30, 10}[i] {
+					t.Fatalf("unexpected segment %d: %d rows", segment.Seq, segment.Rows)
+				}
+				joined = append(joined, segment.Data...)
+			}
+			if len(segments) != 4 || !bytes.Equal(joined, buf.Bytes()) {
+				t.Fatalf("segments don't compose the stream (async: %v)", async)
+			}
+
+			out := extract(t, buf.Bytes())
+			if len(out) != len(in) {
+				t.Fatalf("unexpected number of extracted rows: %d", len(out))
+			}
+			for i := range in {
+				if !bytes.Equal(out[i], in[i]) {
+					t.Fatalf("unexpected row %d:\n%v\nexpected:\n%v", i, out[i], in[i])
+				}
+			}
+
+			// The corrupted segment fails the checksum verification.
+			corrupted := append([]byte(nil), buf.Bytes()...)
+			corrupted[len(corrupted)-1] ^= 0xff
+			se, err := NewStreamExtractor(bytes.NewReader(corrupted))
+			if err != nil {
+				t.Fatalf("creating stream extractor failed: %v", err)
+			}
+			for se.Next() {
+			}
+			if se.Err() == nil {
+				t.Fatal("expected corrupted segment to fail")
+			}
+		}
+	})
+
+	t.Run("SegmentCallbackError", func(t *testing.T) {
+		sc, err := NewStreamComposer(nil, st, StreamOptions{
+			SegmentMaxBytes: 1,
+			AsyncFlush:      true,
+			OnSegment: func(StreamSegment) error {
+				return io.ErrShortWrite
+			},
+		})
+		if err != nil {
+			t.Fatalf("creating stream composer failed: %v", err)
+		}
+		in := rows(t, bstio.ValueOptions{})
+		if err = sc.WriteRow(in[0]); err != nil {
+			t.Fatalf("writing stream row failed: %v", err)
+		}
+		if err = sc.Close(); !errors.Is(err, io.ErrShortWrite) {
+			t.Fatalf("expected the callback error, got: %v", err)
+		}
+		if err = sc.WriteRow(in[1]); err == nil {
+			t.Fatal("expected writing to the closed stream composer to fail")
+		}
+	})
+
+	t.Run("Sampling", func(t *testing.T) {
+		in := rows(t, bstio.ValueOptions{})
+		for _, options := range []StreamOptions{{}, {BackReferences: true}, {BackReferences: true, SegmentMaxRows: 7}} {
+			var buf bytes.Buffer
+			sc, err := NewStreamComposer(&buf, st, options)
+			if err != nil {
+				t.Fatalf("creating stream composer failed: %v", err)
+			}
+			for _, row := range in {
+				if err = sc.WriteRow(row); err != nil {
+					t.Fatalf("writing stream row failed: %v", err)
+				}
+			}
+			if err = sc.Close(); err != nil {
+				t.Fatalf("closing stream composer failed: %v", err)
+			}
+
+			se, err := NewStreamExtractor(&buf)
+			if err != nil {
+				t.Fatalf("creating stream extractor failed: %v", err)
+			}
+			se.SkipRows = 9
+			var sampled int
+			for ; se.Next(); sampled++ {
+				if se.Index() != sampled*10 || !bytes.Equal(se.Row(), in[se.Index()]) {
+					t.Fatalf("unexpected sampled row %d (options: %+v)", se.Index(), options)
+				}
+			}
+			if err = se.Err(); err != nil || sampled != 10 {
+				t.Fatalf("unexpected sampling result: %d rows, err: %v", sampled, err)
+			}
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := NewStreamComposer(&bytes.Buffer{}, bsttype.String(), StreamOptions{BackReferences: true}); err == nil {
+			t.Fatal("expected back references stream of non-struct type to fail")
+		}
+		if _, err := NewStreamExtractor(bytes.NewReader([]byte{0x02})); err == nil {
+			t.Fatal("expected unsupported stream version to fail")
+		}
+
+		// The first row could not reference the previous one.
+		se, err := NewStreamExtractor(bytes.NewReader([]byte{streamVersion | streamBackReferences, 0x03, 0x01, 0x01, 0x01}))
+		if err != nil {
+			t.Fatalf("creating stream extractor failed: %v", err)
+		}
+		if se.Next() || se.Err() == nil {
+			t.Fatal("expected undefined back reference to fail")
+		}
+	})
+
+	t.Run("HugeRow", func(t *testing.T) {
+		// The row length above the maximum row size fails without being allocated.
+		stream := append([]byte{streamVersion}, bstio.MarshalUint(math.MaxInt64, false)...)
+		se, err := NewStreamExtractor(bytes.NewReader(stream))
+		if err != nil {
+			t.Fatalf("creating stream extractor failed: %v", err)
+		}
+		var be *bsterr.Error
+		if se.Next() || !errors.As(se.Err(), &be) || be.Code != bsterr.CodeMalformedBinary {
+			t.Fatalf("expected huge row to fail as malformed, got: %v", se.Err())
+		}
+
+		// The row length within the maximum, but above the remaining input, fails as truncated.
+		stream = append([]byte{streamVersion}, bstio.MarshalUint(1<<20, false)...)
+		if se, err = NewStreamExtractor(bytes.NewReader(stream)); err != nil {
+			t.Fatalf("creating stream extractor failed: %v", err)
+		}
+		if se.Next() || !errors.As(se.Err(), &be) || be.Code != bsterr.CodeTruncatedBinary {
+			t.Fatalf("expected truncated row to fail, got: %v", se.Err())
+		}
+	})
+
+	t.Run("HugeSegment", func(t *testing.T) {
+		// The segment length above the maximum segment size fails without being allocated.
+		stream := append([]byte{streamVersion | streamSegmented}, bstio.MarshalUint(1, false)...)
+		stream = append(stream, bstio.MarshalUint(math.MaxInt64, false)...)
+		se, err := NewStreamExtractor(bytes.NewReader(stream))
+		if err != nil {
+			t.Fatalf("creating stream extractor failed: %v", err)
+		}
+		var be *bsterr.Error
+		if se.Next() || !errors.As(se.Err(), &be) || be.Code != bsterr.CodeMalformedBinary {
+			t.Fatalf("expected huge segment to fail as malformed, got: %v", se.Err())
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package bst
+
+import (
+	"errors"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+// streamingReader is the root reader of the extractor with the Streaming option. It reads the plain io.Reader
+// without buffering it, and seeks forward by discarding the bytes in bounded chunks, so that the skipped values
+// are never held in memory. Seeking back is possible only within the bytes of the last read, i.e. once the
+// comparable value is scanned past its terminator, thus the reads which go further back fail.
+type streamingReader struct {
+	r      io.Reader
+	pos    int64
+	window []byte
+	unread int
+}
+
+// Read reads the bytes seeked back over at first, and then the ones of the underlying reader.
+// Implements io.Reader interface.
+func (x *streamingReader) Read(p []byte) (int, error) {
+	// 1. Read once again the bytes of the last read, which were seeked back over.
+	if x.unread > 0 {
+		n := copy(p, x.window[len(x.window)-x.unread:])
+		x.unread -= n
+		x.pos += int64(n)
+		return n, nil
+	}
+
+	// 2. Read the underlying reader, and keep the read bytes so that they could be seeked back over.
+	n, err := x.r.Read(p)
+	x.window = append(x.window[:0], p[:n]...)
+	x.pos += int64(n)
+	return n, err
+}
+
+// Seek sets the offset for the next read. Seeking forward discards the bytes of the underlying reader,
+// while seeking back is limited to the bytes of the last read. The io.SeekEnd is not supported.
+// Implements io.Seeker interface.
+func (x *streamingReader) Seek(offset int64, whence int) (int64, error) {
+	// 1. Find the target position, relative to the start of the stream.
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = x.pos + offset
+	default:
+		return x.pos, bsterr.Err(bsterr.CodeReadingFailed, "invalid streaming reader seek whence").WithDetail("whence", whence)
+	}
+
+	// 2. Seek back over the bytes of the last read.
+	if pos < x.pos {
+		back := x.pos - pos
+		if back > int64(len(x.window)-x.unread) {
+			return x.pos, bsterr.Err(bsterr.CodeReadingFailed, "streaming reader could not seek back beyond the last read").
+				WithDetails(bsterr.D("offset", pos), bsterr.D("position", x.pos))
+		}
+		x.unread += int(back)
+		x.pos = pos
+		return x.pos, nil
+	}
+
+	// 3. Seek forward over the bytes seeked back over at first, and discard the remaining ones.
+	skip := pos - x.pos
+	if skip <= int64(x.unread) {
+		x.unread -= int(skip)
+		x.pos = pos
+		return x.pos, nil
+	}
+	skip -= int64(x.unread)
+	x.pos += int64(x.unread)
+	x.unread = 0
+	x.window = x.window[:0]
+	n, err := io.CopyN(io.Discard, x.r, skip)
+	x.pos += n
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return x.pos, io.EOF
+		}
+		return x.pos, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to discard streamed bytes")
+	}
+	return x.pos, nil
+}
+
+// RemainingSize returns false, as the size of the stream is unknown until it is read.
+// Implements bstio.RemainingSizer interface.
+func (x *streamingReader) RemainingSize() (int64, bool) {
+	return 0, false
+}
+
+// extractorReader returns the read seeker the extractor reads the input with, and whether it is the shared one
+// wrapping the reader, which needs to be released once the extractor is closed.
+func extractorReader(r io.Reader, opts ExtractorOptions) (io.ReadSeeker, bool) {
+	if opts.Streaming {
+		return &streamingReader{r: r}, false
+	}
+	if rs, ok := r.(io.ReadSeeker); ok {
+		return rs, false
+	}
+	return iopool.WrapReader(r), true
+}
@@ -0,0 +1,180 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// plainReader hides all the methods of the reader other than the Read, and records the largest read.
+type plainReader struct {
+	r       io.Reader
+	maxRead int
+}
+
+// Read implements io.Reader interface.
+func (x *plainReader) Read(p []byte) (int, error) {
+	n, err := x.r.Read(p)
+	x.maxRead = max(x.maxRead, n)
+	return n, err
+}
+
+func TestExtractorStreaming(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "name", Type: bsttype.String()},
+		{Index: 2, Name: "blob", Type: &bsttype.Bytes{}},
+		{Index: 3, Name: "tags", Type: bsttype.ArrayOf(bsttype.String())},
+		{Index: 4, Name: "counts", Type: &bsttype.Map{Key: bsttype.MapElement{Type: bsttype.String()}, Value: bsttype.MapElement{Type: bsttype.Uint32()}}},
+		{Index: 5, Name: "trailer", Type: bsttype.String()},
+	}}
+	blob := bytes.Repeat([]byte{0xab}, 1<<16)
+	tags := []string{"a", "b", "c"}
+	// record composes the struct value, followed by the next value which must not be consumed.
+	record := func(t *testing.T, opts ComposerOptions) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteString("name"); err != nil {
+			t.Fatalf("writing name failed: %v", err)
+		}
+		if err = c.WriteBytes(blob); err != nil {
+			t.Fatalf("writing blob failed: %v", err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, tag := range tags {
+				if err := ac.WriteString(tag); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, 0)
+		if err != nil {
+			t.Fatalf("writing tags failed: %v", err)
+		}
+		err = c.WriteMap(func(mc *Composer) error {
+			for i := 0; i < 100; i++ {
+				if err := mc.WriteString(fmt.Sprintf("key-%03d", i)); err != nil {
+					return err
+				}
+				if err := mc.WriteUint32(uint32(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, 0)
+		if err != nil {
+			t.Fatalf("writing counts failed: %v", err)
+		}
+		if err = c.WriteString("end"); err != nil {
+			t.Fatalf("writing trailer failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return append(buf.Bytes(), "next"...)
+	}
+
+	testCases := []struct {
+		name string
+		opts EncodingOptions
+	}{
+		{name: "Plain"},
+		{name: "Descending", opts: EncodingOptions{Descending: true}},
+		{name: "CompatibilityMode", opts: EncodingOptions{CompatibilityMode: true}},
+		{name: "Comparable", opts: EncodingOptions{Comparable: true}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &plainReader{r: bytes.NewReader(record(t, tc.opts.ComposerOptions()))}
+			opts := tc.opts.ExtractorOptions(st)
+			opts.Streaming = true
+			x, err := NewExtractor(r, opts)
+			if err != nil {
+				t.Fatalf("creating extractor failed: %v", err)
+			}
+			defer x.Close()
+
+			// 1. Read the name, and skip the blob which is discarded rather than buffered.
+			x.Next()
+			if name, err := x.ReadString(); err != nil || name != "name" {
+				t.Fatalf("unexpected name: %q, %v", name, err)
+			}
+			x.Next()
+			if _, err = x.Skip(); err != nil {
+				t.Fatalf("skipping blob failed: %v", err)
+			}
+
+			// 2. Read the tags, and skip the counts.
+			x.Next()
+			var read []string
+			err = x.ReadArray(func(ax *Extractor) error {
+				for ax.Next() {
+					tag, err := ax.ReadString()
+					if err != nil {
+						return err
+					}
+					read = append(read, tag)
+				}
+				return ax.Err()
+			})
+			if err != nil || !reflect.DeepEqual(read, tags) {
+				t.Fatalf("unexpected tags: %v, %v", read, err)
+			}
+			x.Next()
+			if _, err = x.Skip(); err != nil {
+				t.Fatalf("skipping counts failed: %v", err)
+			}
+
+			// 3. Read the trailer, and verify the input of the next value is not consumed,
+			//    unless it is read ahead by the scan of the comparable trailer.
+			x.Next()
+			if trailer, err := x.ReadString(); err != nil || trailer != "end" {
+				t.Fatalf("unexpected trailer: %q, %v", trailer, err)
+			}
+			if x.Next() {
+				t.Fatal("unexpected field after the trailer")
+			}
+			if err = x.Err(); err != nil {
+				t.Fatalf("extracting failed: %v", err)
+			}
+			if next, err := io.ReadAll(r); err != nil || (!tc.opts.Comparable && string(next) != "next") {
+				t.Fatalf("unexpected remaining input: %q, %v", next, err)
+			}
+			if r.maxRead >= len(blob) {
+				t.Fatalf("the input was buffered by the read of %d bytes", r.maxRead)
+			}
+		})
+	}
+
+	t.Run("SeekBack", func(t *testing.T) {
+		// The map key lookup, which goes back in the input, fails on the streamed input.
+		data := record(t, ComposerOptions{})
+		x, err := NewExtractor(bytes.NewReader(data), ExtractorOptions{ExpectedType: st, Streaming: true})
+		if err != nil {
+			t.Fatalf("creating extractor failed: %v", err)
+		}
+		defer x.Close()
+		for i := 0; i < 4; i++ {
+			x.Next()
+			if i < 3 {
+				if _, err = x.Skip(); err != nil {
+					t.Fatalf("skipping field failed: %v", err)
+				}
+			}
+		}
+		_, err = x.FindMapEntry(func(c *Composer) error { return c.WriteString("key-042") }, func(*Extractor) error { return nil })
+		var be *bsterr.Error
+		if !errors.As(err, &be) || be.Code != bsterr.CodeReadingFailed {
+			t.Fatalf("expected reading failed error, got: %v", err)
+		}
+	})
+}
@@ -22,7 +22,48 @@ func (x *Composer) WriteString(v string) error {
 			)
 	}
 
-	// 3. If the base is a struct, check if the field header needs to be written.
+	// 3. Replace the value of the tokenized field with its token.
+	if len(x.opts.Tokenizers) > 0 {
+		tv, ok, err := x.tokenizeField([]byte(v))
+		if err != nil {
+			return err
+		}
+		if ok {
+			v = string(tv)
+		}
+	}
+
+	// 3.1. Transform and bound the comparable string field, if defined.
+	v = x.boundString(x.transformKeyString(v))
+
+	// 3.2. Encrypt the value of the encrypted field.
+	if x.opts.Encryption != nil {
+		ev, ok, err := x.encryptField([]byte(v))
+		if err != nil {
+			return err
+		}
+		if ok {
+			v = string(ev)
+		}
+	}
+
+	// 4. Compress the value of the deflate encoded field.
+	if x.fieldEncoding() == bsttype.FieldEncodingDeflate {
+		cv, err := bstio.Deflate([]byte(v))
+		if err != nil {
+			return err
+		}
+		v = string(cv)
+	}
+
+	// 5. Inline or offload the value of the external encoded field.
+	if x.fieldEncoding() == bsttype.FieldEncodingExternal {
+		return x.writeExternalField(bstio.UnsafeStringToBytes(v), bstio.StringBinarySize(v, x.opts.Comparable), func() (int, error) {
+			return bstio.WriteStringAlloc(x.w, v, x.elemDesc, x.opts.Comparable, x.opts.Allocator)
+		})
+	}
+
+	// 6. If the base is a struct, check if the field header needs to be written.
 	if x.needWriteFieldHeader() {
 		n, err := x.writeFieldHeader(x.w, x.fieldIndex(), bstio.StringBinarySize(v, x.opts.Comparable))
 		if err != nil {
@@ -32,21 +73,64 @@ func (x *Composer) WriteString(v string) error {
 		x.bytesWritten += n
 	}
 
-	// 4. Write the value.
-	n, err := bstio.WriteString(x.w, v, x.elemDesc, x.opts.Comparable)
+	// 7. Write the value.
+	n, err := bstio.WriteStringAlloc(x.w, v, x.elemDesc, x.opts.Comparable, x.opts.Allocator)
 	if err != nil {
 		return err
 	}
 
 	x.bytesWritten += n
+	x.countValueOverhead(n, len(v))
 
-	// 5. Mark the element as written.
+	// 8. Mark the element as written.
 	if err = x.finishElem(); err != nil {
 		return err
 	}
 	return nil
 }
 
+// boundString bounds the string written as the struct field, whose bound is defined in the StringBounds option.
+func (x *Composer) boundString(v string) string {
+	if !x.opts.Comparable || len(x.opts.StringBounds) == 0 {
+		return v
+	}
+	name, ok := x.structFieldName()
+	if !ok {
+		return v
+	}
+	bound, ok := x.opts.StringBounds[name]
+	if !ok {
+		return v
+	}
+	return bstio.BoundString(v, bound)
+}
+
+// transformKeyString transforms the string written as the struct field, whose transform is defined
+// in the KeyTransforms option.
+func (x *Composer) transformKeyString(v string) string {
+	if !x.opts.Comparable || len(x.opts.KeyTransforms) == 0 {
+		return v
+	}
+	name, ok := x.structFieldName()
+	if !ok {
+		return v
+	}
+	fn, ok := x.opts.KeyTransforms[name]
+	if !ok || fn == nil {
+		return v
+	}
+	return fn(v)
+}
+
+// structFieldName returns the name of the current struct field.
+func (x *Composer) structFieldName() (string, bool) {
+	st, ok := x.baseType.(*bsttype.Struct)
+	if !ok || x.index >= len(st.Fields) {
+		return "", false
+	}
+	return st.Fields[x.index].Name, true
+}
+
 // ReadString reads the string value from the extractor.
 func (x *Extractor) ReadString() (string, error) {
 	if x.err != nil {
@@ -71,7 +155,29 @@ func (x *Extractor) ReadString() (string, error) {
 			)
 	}
 
-	// 4. Read the string value.
+	// 4. Read the tag of the external encoded field, and return its value if it was offloaded.
+	if x.fieldEncoding() == bsttype.FieldEncodingExternal {
+		ev, offloaded, err := x.readExternalField()
+		if err != nil {
+			return "", err
+		}
+		if offloaded {
+			if ev, err = x.decryptField(ev); err != nil {
+				return "", err
+			}
+			if ev, err = x.detokenizeField(ev); err != nil {
+				return "", err
+			}
+			v := string(ev)
+			if x.opts.Trace != nil {
+				x.traceElem(TraceOpRead, v)
+			}
+			x.finishElem()
+			return v, nil
+		}
+	}
+
+	// 5. Read the string value.
 	v, n, err := bstio.ReadString(x.r, x.elemDesc, x.opts.Comparable)
 	if err != nil {
 		return "", err
@@ -79,6 +185,53 @@ func (x *Extractor) ReadString() (string, error) {
 
 	x.bytesRead += n
 
+	// 6. Decompress the value of the deflate encoded field.
+	if x.fieldEncoding() == bsttype.FieldEncodingDeflate {
+		dv, err := x.inflateField([]byte(v))
+		if err != nil {
+			return "", err
+		}
+		v = string(dv)
+	}
+
+	// 7. Decrypt the value of the encrypted field.
+	if x.opts.Encryption != nil {
+		dv, err := x.decryptField([]byte(v))
+		if err != nil {
+			return "", err
+		}
+		v = string(dv)
+	}
+
+	// 8. Replace the token of the tokenized field with its value.
+	if len(x.opts.Tokenizers) > 0 {
+		dv, err := x.detokenizeField([]byte(v))
+		if err != nil {
+			return "", err
+		}
+		v = string(dv)
+	}
+
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
+
+// ReadStringZeroCopy reads the 'string' elem value from the extractor as the bytes, just like the ReadBytesView does.
+// The bytes reference the in-memory input, thus they must not be modified, and they are valid only until
+// the extractor is closed, or as long as the bstio.BytesReader input is.
+func (x *Extractor) ReadStringZeroCopy() ([]byte, error) {
+	if x.err != nil {
+		return nil, x.err
+	}
+	if x.elemType.Kind() != bsttype.KindString || x.elemDone || !x.viewable() {
+		v, err := x.ReadString()
+		if err != nil {
+			return nil, err
+		}
+		return []byte(v), nil
+	}
+	return x.readView(0)
+}
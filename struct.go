@@ -17,6 +17,7 @@ func (x *Composer) WriteStruct(fn func(c *Composer) error) error {
 		return bsterr.Err(bsterr.CodeAlreadyWritten, "element already written")
 	}
 
+	w, bufWrites := x.w, x.bufWrites
 	if x.needWriteFieldHeader() {
 		x.setFieldBuffer()
 	}
@@ -27,11 +28,11 @@ func (x *Composer) WriteStruct(fn func(c *Composer) error) error {
 	// 3. Verify if current element matches expected type.
 	st, ok := x.elemType.(*bsttype.Struct)
 	if !ok {
-		return bsterr.Err(bsterr.CodeInvalidType, "invalid type to write").
+		return x.abortComposite(&sp, w, bufWrites, bsterr.Err(bsterr.CodeInvalidType, "invalid type to write").
 			WithDetails(
 				bsterr.D("expected", bsttype.KindStruct),
 				bsterr.D("actual", x.elemType.Kind()),
-			)
+			))
 	}
 
 	// 4. Reset current composer state.
@@ -39,27 +40,31 @@ func (x *Composer) WriteStruct(fn func(c *Composer) error) error {
 
 	// 5. Initialize the sub-composer.
 	if err := x.initializeStructComposer(st, false); err != nil {
-		return err
+		return x.abortComposite(&sp, w, bufWrites, err)
 	}
 
 	// 6. Call input function.
 	if err := fn(x); err != nil {
-		return err
+		return x.abortComposite(&sp, w, bufWrites, err)
 	}
 
-	// 7. Verify if writing was completed
+	// 7. Verify if writing was completed, after the omitted trailing fields are written as nulls.
+	if err := x.writeTrailingNulls(st); err != nil {
+		return x.abortComposite(&sp, w, bufWrites, err)
+	}
 	if x.index <= x.maxIndex {
-		return bsterr.Err(bsterr.CodeWritingFailed, "sub-composer didn't write all elements")
+		return x.abortComposite(&sp, w, bufWrites, bsterr.Err(bsterr.CodeWritingFailed, "sub-composer didn't write all elements"))
 	}
 
-	// 8. Store number of bytes written to the struct composer.
-	bw := x.bytesWritten
+	// 8. Store number of bytes written to the struct composer, along with its write stats.
+	bw, stats := x.bytesWritten, x.stats
 
 	// 9. Restore the savepoint.
 	*x = sp
 
-	// 10. Increase the number of bytes written by the struct composer.
+	// 10. Increase the number of bytes written and the write stats by the struct composer.
 	x.bytesWritten += bw
+	x.stats.add(stats)
 
 	// 11. Finish the element.
 	if err := x.finishElem(); err != nil {
@@ -68,6 +73,27 @@ func (x *Composer) WriteStruct(fn func(c *Composer) error) error {
 	return nil
 }
 
+// writeTrailingNulls writes the remaining fields of the struct composer as nulls, if the ImplicitTrailingNulls
+// option is set. It fails on the first remaining field which is not Nullable.
+func (x *Composer) writeTrailingNulls(st *bsttype.Struct) error {
+	if !x.opts.ImplicitTrailingNulls {
+		return nil
+	}
+	for !x.done && x.index <= x.maxIndex {
+		if x.elemType.Kind() != bsttype.KindNullable {
+			return bsterr.Err(bsterr.CodeWritingFailed, "omitted trailing struct field is not nullable").
+				WithDetails(
+					bsterr.D("field", st.Fields[x.index].Name),
+					bsterr.D("type", x.elemType),
+				)
+		}
+		if err := x.WriteNull(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ReadStruct reads the struct value from the extractor.
 func (x *Extractor) ReadStruct(fn func(sx *Extractor) error) error {
 	if x.err != nil {
@@ -78,10 +104,7 @@ func (x *Extractor) ReadStruct(fn func(sx *Extractor) error) error {
 		return bsterr.Err(bsterr.CodeAlreadyRead, "elem already done")
 	}
 
-	// 2. Create a snapshot of current element.
-	sp := *x
-
-	// 3. Ensure that the element is a structure.
+	// 2. Ensure that the element is a structure.
 	if x.elemType.Kind() != bsttype.KindStruct {
 		return bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
 			WithDetails(
@@ -90,12 +113,18 @@ func (x *Extractor) ReadStruct(fn func(sx *Extractor) error) error {
 			)
 	}
 
+	// 2.1. Record the struct in the trace, preceding the events of its elements.
+	var traceIndex int
+	if x.opts.Trace != nil {
+		traceIndex = x.traceEvent(TraceOpComposite, nil)
+	}
+
 	// 4. Keep embedded and expected struct types.
 	xt := x.elemType
 	et := x.embed.elemType
 
-	// 5. Reset the extractor.
-	x.reset()
+	// 5. Push the current frame and reset the extractor.
+	x.enterNested()
 
 	// 6. Set up base types for the struct.
 	x.opts.ExpectedType = xt
@@ -103,27 +132,30 @@ func (x *Extractor) ReadStruct(fn func(sx *Extractor) error) error {
 
 	// 5. Initialize the base of the structure.
 	if err := x.initStructBase(); err != nil {
-		return err
+		return x.abortNested(err)
 	}
 
 	// 6. Execute the extractor.
 	if err := fn(x); err != nil {
-		return err
+		return x.abortNested(err)
 	}
 
 	// 7. Finish embedded element.
 	if err := x.finishStruct(); err != nil {
-		return err
+		return x.abortNested(err)
 	}
 
 	// 8. Keep the number of bytes read.
 	br := x.bytesRead
 
-	// 9. Restore the extractor.
-	*x = sp
+	// 9. Restore the frame of the parent composite.
+	x.leaveNested()
 
 	// 10. Update the number of bytes read.
 	x.bytesRead += br
+	if x.opts.Trace != nil {
+		x.traceComposite(traceIndex)
+	}
 
 	// 11. Finish this function element.
 	x.finishElem()
@@ -195,8 +227,10 @@ func (x *Extractor) initStructBase() error {
 
 	// 4. If the expected type is the same as the embedded one, we base everything on the embedded one.
 	if xt.CompareType(et) {
-		// 4.1. Set the max index to the number of embedded fields.
+		// 4.1. Set the max index to the number of embedded fields. The types might be different instances,
+		//      matched field by field, thus the embedded max index is set as well.
 		x.maxIndex = len(et.Fields) - 1
+		x.embed.maxIndex = x.maxIndex
 		return nil
 	}
 
@@ -209,13 +243,18 @@ func (x *Extractor) initStructBase() error {
 
 // This function advances an index of the struct field and checks whether a new expected field is possible to extract.
 func (x *Extractor) nextStructElem() bool {
-	// 1. Check if the extractor is in compatibility mode.
+	// 1. Check if the extractor is in compatibility mode. The expected field missing in the binary
+	//    is read from its default value, if it has one.
+	x.leaveFieldDefault()
 	if x.opts.CompatibilityMode {
 		hasNext, err := x.nextCompatibilityStructElem()
 		if err != nil {
 			x.err = err
 			return false
 		}
+		if !hasNext && !x.baseDone {
+			return x.enterFieldDefault()
+		}
 		return hasNext
 	}
 
@@ -281,6 +320,7 @@ func (x *Extractor) nextStructElemCompatibilityNoExpected() (bool, error) {
 	}
 
 	x.embed.elemType = x.elemType
+	x.embed.encoding = et.Fields[x.index].Encoding
 	x.elemDesc = et.Fields[x.index].Descending
 	if x.opts.Descending {
 		x.elemDesc = !x.elemDesc
@@ -346,6 +386,7 @@ func (x *Extractor) nextStructElemCompatibilityNoEmbed() (bool, error) {
 			x.elemDesc = !x.elemDesc
 		}
 		x.embed.elemType = x.elemType
+		x.embed.encoding = exField.Encoding
 		x.embed.used = true
 		return true, nil
 	}
@@ -392,6 +433,7 @@ func (x *Extractor) nextStructElemCompatibilityNoEmbed() (bool, error) {
 				x.elemDesc = !x.elemDesc
 			}
 			x.embed.elemType = x.elemType
+			x.embed.encoding = exField.Encoding
 			x.embed.used = true
 			return true, nil
 		}
@@ -418,6 +460,7 @@ func (x *Extractor) nextStructElemCompatibilityNoEmbed() (bool, error) {
 
 func (x *Extractor) nextStructElemCompatibilityEmbedNotExpected() (bool, error) {
 	// In this scenario an embedded type was defined in the binary, as well as the expected one, but they are not the same.
+	// The embed index is the position of the last field header read, which is used once its value is read or skipped.
 	et := x.embedType.(*bsttype.Struct)
 	xt := x.opts.ExpectedType.(*bsttype.Struct)
 
@@ -426,152 +469,92 @@ func (x *Extractor) nextStructElemCompatibilityEmbedNotExpected() (bool, error)
 	x.elemDone = false
 
 	// 2. Check if no more expected fields are available.
-	var err error
 	if x.index > x.maxIndex {
-		if x.embed.used {
-			x.embed.index++
-			x.embed.used = false
-		}
-		// 2.1. If the is no more fields to expect, we are done.
-		//        However, embedded type index could still have more fields, thus we may need to skip till the end of the struct.
-		for x.embed.index <= x.embed.maxIndex {
-			// 2.1.2. If the embed field was not used we need to skip it.
-			if !x.embed.used {
-				_, err = x.r.Seek(int64(x.fieldHeader.length), io.SeekCurrent)
-				if err != nil {
+		// 2.1. The embedded type could still have more fields, thus we need to skip till the end of the struct.
+		for {
+			if x.embed.index >= 0 && !x.embed.used {
+				if _, err := x.r.Seek(int64(x.fieldHeader.length), io.SeekCurrent); err != nil {
 					return false, err
 				}
 				x.bytesRead += x.fieldHeader.length
 				x.embed.used = true
 			}
-
-			// 2.1.3. Read the next field header.
-			x.fieldHeader, err = x.readCompatibleField()
+			if x.embed.index > x.embed.maxIndex {
+				break
+			}
+			fh, err := x.readCompatibleField()
 			if err != nil {
 				return false, err
 			}
-			x.embed.used = false
+			x.fieldHeader = fh
 			x.embed.index++
+			x.embed.used = false
 		}
 		// 2.2. Now, we have read all the fields in the binary, and the expected type does not have more fields.
 		//        We are done.
 		x.baseDone = true
 		return false, nil
 	}
+	xField := xt.Fields[x.index]
 
-	// 3. If the embed field was already used, we need to increase the index of the embed field.
-	if x.embed.used || x.embed.index == -1 {
-		x.embed.index++
-
-		// 3.1. Check if the embed field index reached the maximum.
-		if x.embed.index > x.embed.maxIndex {
-			// 3.1.1. We're not setting the extractor as done, because we still have more fields to read in expected type.
-			return false, nil
-		}
-
-		// 3.2. Read the next field header.
-		x.fieldHeader, err = x.readCompatibleField()
-		if err != nil {
-			return false, err
-		}
-		x.embed.used = false
-	}
-
-	xField := xt.Fields[x.embed.index]
-	etField := et.Fields[x.index]
-
-	// 4. If the expected field is before the embedded field, then this field cannot be reached.
-	//    This occurs when the embedded type definition is less specific than the expected type.
-	//    In case when there are index gaps in the embedded type fields, which occurs in expected type
-	//    a field could be defined in expected type, but not in the embedded type.
-	//    In this case, we're not reading the field, but quickly returning false.
-	if xField.Index < etField.Index {
-		return false, nil
-	}
+	// 3. Find the embedded field of the expected field index. The fields in the binary are sorted by their indexes.
+	for {
+		// 3.1. The field header which was read, but not used yet, could be the expected one.
+		if x.embed.index >= 0 && !x.embed.used {
+			// 3.1.1. If the field header index is greater than the expected one, the expected field is not in the binary.
+			if x.fieldHeader.index > int(xField.Index) {
+				return false, nil
+			}
 
-	// 5. If the indexes are the same, then we can set up the next extractor element to be the expected one.
-	if xField.Index == etField.Index {
-		// 5.1. Set current struct element to the expected one.
-		x.elemType, x.err = x.derefType(xField.Type)
-		if x.err != nil {
-			return false, x.err
-		}
-		// 5.2. Set the embed element type.
-		x.embed.elemType, x.err = x.derefType(etField.Type)
-		if x.err != nil {
-			return false, x.err
-		}
-		x.embed.used = true
+			// 3.1.2. If the indexes are the same, set up the next extractor element to be the expected one.
+			if x.fieldHeader.index == int(xField.Index) {
+				etField, _, found := et.FieldByIndex(xField.Index)
+				if !found {
+					return false, bsterr.Err(bsterr.CodeMalformedBinary, "field header index is not defined by the embedded type").
+						WithDetail("index", x.fieldHeader.index)
+				}
+				x.elemType, x.err = x.derefType(xField.Type)
+				if x.err != nil {
+					return false, x.err
+				}
+				x.embed.elemType, x.err = x.derefType(etField.Type)
+				if x.err != nil {
+					return false, x.err
+				}
+				x.embed.encoding = etField.Encoding
+				x.embed.used = true
 
-		// 5.3. If the expected field is descending, we need to invert the element descending flag.
-		x.elemDesc = xField.Descending
-		if x.opts.Descending {
-			x.elemDesc = !x.elemDesc
-		}
-		return true, nil
-	}
+				// 3.1.3. If the expected field is descending, we need to invert the element descending flag.
+				x.elemDesc = xField.Descending
+				if x.opts.Descending {
+					x.elemDesc = !x.elemDesc
+				}
+				return true, nil
+			}
 
-	// 6. The expected index is after the embedded index, so we need to skip the bytes of the embedded field.
-	//    expectedField.Index > embeddedField.Index
-	//
-	//    Check if expected field in the embedded binary.
-	for x.embed.index <= x.embed.maxIndex {
-		if !x.embed.used {
-			_, err = x.r.Seek(int64(x.fieldHeader.length), io.SeekCurrent)
-			if err != nil {
+			// 3.1.4. The embedded field is before the expected one, thus its bytes are skipped.
+			if _, err := x.r.Seek(int64(x.fieldHeader.length), io.SeekCurrent); err != nil {
 				return false, err
 			}
-
 			x.bytesRead += x.fieldHeader.length
 			x.embed.used = true
 		}
 
-		// 6.1. Read the field header.
-		x.fieldHeader, err = x.readCompatibleField()
+		// 3.2. If all the fields of the binary were read, the expected field is not in the binary.
+		//      We're not setting the extractor as done, because we still have more fields to read in expected type.
+		if x.embed.index > x.embed.maxIndex {
+			return false, nil
+		}
+
+		// 3.3. Read the next field header.
+		fh, err := x.readCompatibleField()
 		if err != nil {
 			return false, err
 		}
-
-		// 6.2. Advance the index of embedded type.
+		x.fieldHeader = fh
 		x.embed.index++
 		x.embed.used = false
-
-		if xField.Index == uint(x.fieldHeader.index) {
-			// 6.3. The expected field matches the embedded field, so we can set up the next extractor element to be the expected one.
-			x.elemType, x.err = x.derefType(xField.Type)
-			if x.err != nil {
-				return false, x.err
-			}
-
-			// 6.4. Set up embed elem.
-			x.embed.elemType, x.err = x.derefType(et.Fields[x.embed.index].Type)
-			if x.err != nil {
-				return false, x.err
-			}
-			x.embed.used = true
-
-			// 6.5. Determine if the field value is descending.
-			x.elemDesc = xField.Descending
-			if x.opts.Descending {
-				x.elemDesc = !x.elemDesc
-			}
-			return true, nil
-		}
-
-		// 6.6. If the index of the expected field is smaller than the index of the embedded field, then the
-		//        expected field is not in the binary, and we return false.
-		if xField.Index < uint(x.fieldHeader.index) {
-			return false, nil
-		}
-
-		// 6.7. If the index of the embedded field is still less than the expected index, then we can try reaching
-		//      the next embed field.
-		//      xField.Index > x.fieldHeader.index
 	}
-	// 6.8. We have read all the fields in the embedded binary, and the expected field is not in the binary.
-	//        We are done, however, we still have more fields to read in the expected type,
-	//        and we're not marking the extractor as done.
-	return false, nil
 }
 
 func (x *Extractor) nextEmbedStructElem() bool {
@@ -600,6 +583,7 @@ func (x *Extractor) nextEmbedStructElem() bool {
 	if x.err != nil {
 		return false
 	}
+	x.embed.encoding = eField.Encoding
 	x.embed.used = true
 	x.elemDesc = eField.Descending
 
@@ -635,13 +619,15 @@ func (x *Extractor) nextExpectedStructElem() (bool, error) {
 				Descending:        x.opts.Descending,
 				Comparable:        x.opts.Comparable,
 				CompatibilityMode: x.opts.CompatibilityMode,
+				LengthPrefixed:    x.opts.LengthPrefixedCollections,
+				OrderedFloats:     x.opts.OrderedFloats,
 			}
 			if eField.Descending {
 				opts.Descending = !opts.Descending
 			}
 
 			// 2.1.2. Create a skipper for the field type, and skip the bytes.
-			n, err := bstskip.SkipFuncOf(eField.Type)(x.r, opts)
+			n, err := bstskip.FieldSkipFuncOf(eField, opts)(x.r, opts)
 			if err != nil {
 				return false, err
 			}
@@ -675,6 +661,7 @@ func (x *Extractor) nextExpectedStructElem() (bool, error) {
 			// 4.2.1. Now, the expected field is in the embedded type, so we can set up the next extractor element to be the expected one.
 			x.elemType = xField.Type
 			x.embed.elemType = eField.Type
+			x.embed.encoding = eField.Encoding
 			x.embed.used = true
 
 			x.elemDesc = xField.Descending
@@ -694,6 +681,8 @@ func (x *Extractor) nextExpectedStructElem() (bool, error) {
 			Descending:        x.opts.Descending,
 			Comparable:        x.opts.Comparable,
 			CompatibilityMode: x.opts.CompatibilityMode,
+			LengthPrefixed:    x.opts.LengthPrefixedCollections,
+			OrderedFloats:     x.opts.OrderedFloats,
 		}
 		if eField.Descending {
 			opts.Descending = !opts.Descending
@@ -701,7 +690,7 @@ func (x *Extractor) nextExpectedStructElem() (bool, error) {
 
 		// 4.3. The expected field is after the embedded field, so we need to skip the bytes of the embedded field.
 		//      expectedField.Index > embeddedField.Index
-		n, err := bstskip.SkipFuncOf(eField.Type)(x.r, opts)
+		n, err := bstskip.FieldSkipFuncOf(eField, opts)(x.r, opts)
 		if err != nil {
 			return false, err
 		}
@@ -719,6 +708,7 @@ func (x *Extractor) nextExpectedStructElem() (bool, error) {
 }
 
 func (x *Extractor) finishStruct() error {
+	x.leaveFieldDefault()
 	if x.elemDone || x.baseDone {
 		return nil
 	}
@@ -770,11 +760,13 @@ func (x *Extractor) finishStruct() error {
 				Descending:        x.opts.Descending,
 				Comparable:        x.opts.Comparable,
 				CompatibilityMode: x.opts.CompatibilityMode,
+				LengthPrefixed:    x.opts.LengthPrefixedCollections,
+				OrderedFloats:     x.opts.OrderedFloats,
 			}
 			if eField.Descending {
 				opts.Descending = !opts.Descending
 			}
-			n, err := bstskip.SkipFuncOf(eField.Type)(x.r, opts)
+			n, err := bstskip.FieldSkipFuncOf(eField, opts)(x.r, opts)
 			if err != nil {
 				return err
 			}
@@ -795,11 +787,13 @@ func (x *Extractor) finishStruct() error {
 				Descending:        x.opts.Descending,
 				Comparable:        x.opts.Comparable,
 				CompatibilityMode: x.opts.CompatibilityMode,
+				LengthPrefixed:    x.opts.LengthPrefixedCollections,
+				OrderedFloats:     x.opts.OrderedFloats,
 			}
 			if etField.Descending {
 				opts.Descending = !opts.Descending
 			}
-			n, err := bstskip.SkipFuncOf(etField.Type)(x.r, opts)
+			n, err := bstskip.FieldSkipFuncOf(etField, opts)(x.r, opts)
 			if err != nil {
 				return err
 			}
@@ -827,7 +821,11 @@ func (x *Extractor) previewPrevStructElem() (bsttype.Type, bool) {
 	if x.index == 0 {
 		return nil, false
 	}
+	// The index of the expected struct might be out of the embedded fields.
 	et := x.embedType.(*bsttype.Struct)
+	if x.index > len(et.Fields) {
+		return nil, false
+	}
 	return et.Fields[x.index-1].Type, true
 }
 
@@ -844,7 +842,7 @@ func (x *Extractor) readCompatibleField() (fieldHeader, error) {
 
 	x.bytesRead += n
 
-	length, n, err := bstio.ReadUint(x.r, false)
+	length, n, err := bstio.ReadLength(x.r, false, 1)
 	if err != nil {
 		return fieldHeader{}, bsterr.ErrWrap(err, bsterr.CodeReadingFailed, "failed to read field length")
 	}
@@ -859,11 +857,11 @@ type compatibilityStructHeader struct {
 }
 
 func (x *Extractor) readCompatibilityStructHeader() (compatibilityStructHeader, error) {
-	maxIndex, n, err := bstio.ReadUint(x.r, false)
+	count, n, err := bstio.ReadStructHeader(x.r)
 	if err != nil {
 		return compatibilityStructHeader{}, err
 	}
 	x.bytesRead += n
 
-	return compatibilityStructHeader{maxIndex: int(maxIndex)}, nil
+	return compatibilityStructHeader{maxIndex: count - 1}, nil
 }
@@ -0,0 +1,168 @@
+package bst
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bstskip"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// structSegment is a raw binary segment of the struct field(s).
+// In the regular mode a segment may contain multiple boolean fields packed together.
+type structSegment struct {
+	index uint   // index of the struct field (StructField.Index) or position of the first field in the regular mode.
+	count int    // number of fields in the segment.
+	data  []byte // raw field binary.
+}
+
+func derefNamedType(t bsttype.Type) bsttype.Type {
+	for {
+		nt, ok := t.(*bsttype.Named)
+		if !ok {
+			return t
+		}
+		t = nt.Type
+	}
+}
+
+func splitStructSegments(in []byte, st *bsttype.Struct, o bstio.ValueOptions) ([]structSegment, error) {
+	r := bytes.NewReader(in)
+	segments := make([]structSegment, 0, len(st.Fields))
+	for i := 0; i < len(st.Fields); i++ {
+		start := len(in) - r.Len()
+
+		// 1. Consecutive boolean fields are packed into a single byte each eight fields.
+		if st.Fields[i].Type.Kind() == bsttype.KindBoolean {
+			count := 1
+			for i+count < len(st.Fields) && st.Fields[i+count].Type.Kind() == bsttype.KindBoolean {
+				count++
+			}
+			size := (count + 7) >> 3
+			if r.Len() < size {
+				return nil, bsterr.Err(bsterr.CodeMalformedBinary, "not enough bytes for boolean fields")
+			}
+			segments = append(segments, structSegment{index: uint(i), count: count, data: in[start : start+size]})
+			_, _ = r.Seek(int64(size), io.SeekCurrent)
+			i += count - 1
+			continue
+		}
+
+		// 2. Any other field is skipped to find out its length.
+		fo := structFieldOptions(st.Fields[i], o)
+		n, err := bstskip.FieldSkipFuncOf(st.Fields[i], fo)(r, fo)
+		if err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeSkippingBinaryValue, "failed to skip struct field").
+				WithDetail("field", st.Fields[i].Name)
+		}
+		segments = append(segments, structSegment{index: uint(i), count: 1, data: in[start : start+int(n)]})
+	}
+	return segments, nil
+}
+
+// splitStructSegmentsOf splits the struct value binary into the segments, respecting the compatibility mode.
+func splitStructSegmentsOf(in []byte, st *bsttype.Struct, o bstio.ValueOptions) ([]structSegment, error) {
+	if o.CompatibilityMode {
+		return splitCompatibilityStructSegments(in, st, o)
+	}
+	return splitStructSegments(in, st, o)
+}
+
+func splitCompatibilityStructSegments(in []byte, _ *bsttype.Struct, _ bstio.ValueOptions) ([]structSegment, error) {
+	segments, _, err := readStructSegments(in)
+	return segments, err
+}
+
+// readStructSegments reads the compatibility mode struct binary as the list of segments, where each segment is prefixed
+// with its index and binary length. It returns the segments and the number of bytes read.
+func readStructSegments(in []byte) ([]structSegment, int, error) {
+	r := bytes.NewReader(in)
+
+	// 1. Read the struct header, which is the max index of the segments.
+	count, _, err := bstio.ReadStructHeader(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 2. Read the segments following the header.
+	segments, err := readSegmentList(in, r, count)
+	if err != nil {
+		return nil, 0, err
+	}
+	return segments, len(in) - r.Len(), nil
+}
+
+// readSegmentList reads count segments of the in binary, from the current offset of its reader r.
+func readSegmentList(in []byte, r *bytes.Reader, count int) ([]structSegment, error) {
+	segments := make([]structSegment, 0, count)
+	for i := 0; i < count; i++ {
+		index, _, err := bstio.ReadUint(r, false)
+		if err != nil {
+			return nil, err
+		}
+		size, _, err := bstio.ReadUint(r, false)
+		if err != nil {
+			return nil, err
+		}
+		if uint(r.Len()) < size {
+			return nil, bsterr.Err(bsterr.CodeMalformedBinary, "field length exceeds the value binary").
+				WithDetails(bsterr.D("index", index), bsterr.D("length", size))
+		}
+		start := len(in) - r.Len()
+		segments = append(segments, structSegment{index: index, count: 1, data: in[start : start+int(size)]})
+		_, _ = r.Seek(int64(size), io.SeekCurrent)
+	}
+	return segments, nil
+}
+
+// writeStructSegments writes the segments as the compatibility mode struct binary, prefixed with the struct header.
+func writeStructSegments(w io.Writer, segments []structSegment) error {
+	if _, err := bstio.WriteStructHeader(w, len(segments)); err != nil {
+		return err
+	}
+	return writeSegmentList(w, segments)
+}
+
+// writeSegmentList writes the segments, each prefixed with its index and binary length.
+func writeSegmentList(w io.Writer, segments []structSegment) error {
+	for _, sg := range segments {
+		if _, err := bstio.WriteUint(w, sg.index, false); err != nil {
+			return err
+		}
+		if _, err := bstio.WriteUint(w, uint(len(sg.data)), false); err != nil {
+			return err
+		}
+		if _, err := w.Write(sg.data); err != nil {
+			return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write field binary")
+		}
+	}
+	return nil
+}
+
+func structFieldOptions(f bsttype.StructField, o bstio.ValueOptions) bstio.ValueOptions {
+	fo := bstio.ValueOptions{Descending: o.Descending, Comparable: o.Comparable}
+	if f.Descending {
+		fo.Descending = !fo.Descending
+	}
+	return fo
+}
+
+// structSegmentIndex returns the segment index of the field, which is the field index in the compatibility mode,
+// and the field position otherwise.
+func structSegmentIndex(f bsttype.StructField, pos int, o bstio.ValueOptions) uint {
+	if o.CompatibilityMode {
+		return f.Index
+	}
+	return uint(pos)
+}
+
+func findStructSegment(segments []structSegment, index uint) (structSegment, bool) {
+	for _, sg := range segments {
+		if sg.index == index {
+			return sg, true
+		}
+	}
+	return structSegment{}, false
+}
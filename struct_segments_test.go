@@ -0,0 +1,100 @@
+package bst
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+// composeCompatibilityRow composes the headless compatibility mode row of the uint8 fields of the struct type.
+func composeCompatibilityRow(t *testing.T, st *bsttype.Struct, values ...uint8) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	c, err := NewComposer(&buf, st, ComposerOptions{CompatibilityMode: true})
+	if err != nil {
+		t.Fatalf("creating composer failed: %v", err)
+	}
+	for _, v := range values {
+		if err = c.WriteUint8(v); err != nil {
+			t.Fatalf("writing uint8 failed: %v", err)
+		}
+	}
+	if err = c.Close(); err != nil {
+		t.Fatalf("closing composer failed: %v", err)
+	}
+
+	// The composed value is prefixed with the single byte of its header.
+	return buf.Bytes()[1:]
+}
+
+// extractCompatibilityRow extracts the uint8 fields of the headless compatibility mode row, by the field names.
+func extractCompatibilityRow(t *testing.T, st *bsttype.Struct, row []byte) map[string]uint8 {
+	t.Helper()
+	x, err := NewExtractor(bytes.NewReader(row), ExtractorOptions{
+		ExpectedType:      st,
+		Headless:          true,
+		CompatibilityMode: true,
+	})
+	if err != nil {
+		t.Fatalf("creating extractor failed: %v", err)
+	}
+	defer x.Close()
+	fields := map[string]uint8{}
+	for x.Next() {
+		v, err := x.ReadUint8()
+		if err != nil {
+			t.Fatalf("reading field %d failed: %v", x.Index(), err)
+		}
+		fields[st.Fields[x.Index()].Name] = v
+	}
+	if err = x.Err(); err != nil {
+		t.Fatalf("extracting row failed: %v", err)
+	}
+	return fields
+}
+
+func TestReadStructSegments(t *testing.T) {
+	st := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "A", Type: bsttype.Uint8()},
+			{Index: 2, Name: "B", Type: bsttype.Uint8()},
+			{Index: 5, Name: "C", Type: bsttype.Uint8()},
+		},
+	}
+	row := composeCompatibilityRow(t, st, 1, 2, 3)
+
+	// 1. All the fields written by the composer are read, including the last one.
+	segments, n, err := readStructSegments(row)
+	if err != nil {
+		t.Fatalf("reading segments failed: %v", err)
+	}
+	if n != len(row) || len(segments) != 3 {
+		t.Fatalf("unexpected segments: %d of %d bytes, row of %d bytes", len(segments), n, len(row))
+	}
+	for i, sg := range segments {
+		if sg.index != st.Fields[i].Index || !bytes.Equal(sg.data, []byte{uint8(i + 1)}) {
+			t.Fatalf("unexpected segment %d: %d %v", i, sg.index, sg.data)
+		}
+	}
+
+	// 2. The written segments match the composed binary.
+	var buf bytes.Buffer
+	if err = writeStructSegments(&buf, segments); err != nil {
+		t.Fatalf("writing segments failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), row) {
+		t.Fatalf("unexpected segments binary:\n%v\nexpected:\n%v", buf.Bytes(), row)
+	}
+
+	// 3. The empty struct has no segments.
+	empty := composeCompatibilityRow(t, &bsttype.Struct{})
+	if segments, _, err = readStructSegments(empty); err != nil || len(segments) != 0 {
+		t.Fatalf("unexpected empty struct segments: %v, %v", segments, err)
+	}
+
+	// 4. The header exceeding the fields is rejected.
+	if _, _, err = readStructSegments(row[:len(row)-4]); err == nil {
+		t.Fatalf("expected truncated segments error")
+	}
+}
@@ -0,0 +1,267 @@
+package bst
+
+import (
+	"bytes"
+	"math"
+	"math/bits"
+	"time"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+// TimeSeriesChunk is a chunk of the time series points, sorted by their timestamps.
+// The timestamps and the values are encoded in separate runs of their own codecs, which makes it much smaller
+// than the generic array of point structs:
+//   - the timestamps are encoded as the differences of their consecutive deltas, which are zero for the regular intervals,
+//   - the floating point values are encoded as the bit-reversed xor of the previous ones, which is zero for the repeated values
+//     and short for the values differing only in the exponent and the highest mantissa bits,
+//   - the integer values are encoded as the differences from the previous ones, as they are usually counters
+//     or gauges, which change by small amounts.
+//
+// The differences are zigzag encoded varying size unsigned integers, so that the small negative ones are short as well.
+type TimeSeriesChunk struct {
+	kind   bsttype.Kind
+	times  []int64
+	values []uint64
+}
+
+// TimeSeriesPoint is a point of the time series chunk.
+type TimeSeriesPoint struct {
+	Time time.Time
+	bits uint64
+}
+
+// Float64 returns the value of the point of the KindFloat64 chunk.
+func (x TimeSeriesPoint) Float64() float64 {
+	return math.Float64frombits(x.bits)
+}
+
+// Int64 returns the value of the point of the KindInt64 chunk.
+func (x TimeSeriesPoint) Int64() int64 {
+	return int64(x.bits)
+}
+
+// Uint64 returns the value of the point of the KindUint64 chunk.
+func (x TimeSeriesPoint) Uint64() uint64 {
+	return x.bits
+}
+
+var _timeSeriesChunkType = &bsttype.Struct{
+	Fields: []bsttype.StructField{
+		{Index: 1, Name: "Times", Type: bsttype.ArrayOf(bsttype.Uint())},
+		{Index: 2, Name: "Values", Type: bsttype.ArrayOf(bsttype.Uint())},
+	},
+}
+
+// TimeSeriesChunkType returns the struct type used to serialize the TimeSeriesChunk runs.
+// The returned type must not be modified.
+func TimeSeriesChunkType() *bsttype.Struct {
+	return _timeSeriesChunkType
+}
+
+// NewTimeSeriesChunk creates an empty time series chunk, with the values of given kind.
+// The supported kinds are: KindFloat64, KindInt64 and KindUint64.
+func NewTimeSeriesChunk(kind bsttype.Kind) (*TimeSeriesChunk, error) {
+	switch kind {
+	case bsttype.KindFloat64, bsttype.KindInt64, bsttype.KindUint64:
+		return &TimeSeriesChunk{kind: kind}, nil
+	default:
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "unsupported time series value kind").WithDetail("kind", kind)
+	}
+}
+
+// Kind returns the kind of the chunk values.
+func (x *TimeSeriesChunk) Kind() bsttype.Kind {
+	return x.kind
+}
+
+// Len returns the number of the chunk points.
+func (x *TimeSeriesChunk) Len() int {
+	return len(x.times)
+}
+
+// Point returns the i-th point of the chunk.
+func (x *TimeSeriesChunk) Point(i int) TimeSeriesPoint {
+	return TimeSeriesPoint{Time: time.Unix(0, x.times[i]), bits: x.values[i]}
+}
+
+// Range calls the fn for the chunk points in the time order, until it returns false.
+func (x *TimeSeriesChunk) Range(fn func(p TimeSeriesPoint) bool) {
+	for i := range x.times {
+		if !fn(x.Point(i)) {
+			return
+		}
+	}
+}
+
+// AppendFloat64 appends the point to the KindFloat64 chunk.
+func (x *TimeSeriesChunk) AppendFloat64(t time.Time, v float64) error {
+	return x.append(bsttype.KindFloat64, t, math.Float64bits(v))
+}
+
+// AppendInt64 appends the point to the KindInt64 chunk.
+func (x *TimeSeriesChunk) AppendInt64(t time.Time, v int64) error {
+	return x.append(bsttype.KindInt64, t, uint64(v))
+}
+
+// AppendUint64 appends the point to the KindUint64 chunk.
+func (x *TimeSeriesChunk) AppendUint64(t time.Time, v uint64) error {
+	return x.append(bsttype.KindUint64, t, v)
+}
+
+func (x *TimeSeriesChunk) append(kind bsttype.Kind, t time.Time, bits uint64) error {
+	// 1. Verify the value kind.
+	if kind != x.kind {
+		return bsterr.Err(bsterr.CodeInvalidType, "invalid time series value kind").
+			WithDetails(bsterr.D("expected", x.kind), bsterr.D("actual", kind))
+	}
+
+	// 2. Verify that the points are appended in the time order.
+	ts := t.UnixNano()
+	if n := len(x.times); n > 0 && ts < x.times[n-1] {
+		return bsterr.Err(bsterr.CodeInvalidValue, "time series point is older than the last one").
+			WithDetails(bsterr.D("time", t), bsterr.D("last", time.Unix(0, x.times[n-1])))
+	}
+
+	// 3. Append the point.
+	x.times = append(x.times, ts)
+	x.values = append(x.values, bits)
+	return nil
+}
+
+// MarshalTimeSeriesChunk encodes the chunk as the value kind header, followed by the headless value
+// of its TimeSeriesChunkType.
+func MarshalTimeSeriesChunk(x *TimeSeriesChunk) ([]byte, error) {
+	// 1. Write the kind header.
+	buf := bytes.NewBuffer([]byte{byte(x.kind)})
+
+	// 2. Compose the timestamps and values runs.
+	c, err := NewComposer(buf, _timeSeriesChunkType, ComposerOptions{})
+	if err != nil {
+		return nil, err
+	}
+	err = c.WriteArray(func(ac *Composer) error {
+		var prevTime, prevDelta uint64
+		for _, ts := range x.times {
+			dd := deltaEncode(&prevDelta, deltaEncode(&prevTime, ts))
+			if err := ac.WriteUint(uint(zigzagEncode(dd))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, len(x.times))
+	if err != nil {
+		return nil, err
+	}
+	err = c.WriteArray(func(ac *Composer) error {
+		var prev uint64
+		for _, v := range x.values {
+			if err := ac.WriteUint(uint(encodeTimeSeriesValue(x.kind, &prev, v))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, len(x.values))
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalTimeSeriesChunk decodes the chunk encoded by the MarshalTimeSeriesChunk.
+func UnmarshalTimeSeriesChunk(data []byte) (*TimeSeriesChunk, error) {
+	// 1. Read the kind header.
+	if len(data) == 0 {
+		return nil, bsterr.Err(bsterr.CodeMalformedBinary, "empty time series chunk binary")
+	}
+	x, err := NewTimeSeriesChunk(bsttype.Kind(data[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Extract the timestamps and values runs.
+	r := iopool.GetReadSeeker(data[1:])
+	defer iopool.ReleaseReadSeeker(r)
+
+	ex, err := NewExtractor(r, ExtractorOptions{ExpectedType: _timeSeriesChunkType})
+	if err != nil {
+		return nil, err
+	}
+	defer ex.Close()
+
+	for ex.Next() {
+		switch ex.Index() {
+		case 0:
+			err = ex.ReadArray(func(ax *Extractor) error {
+				var prevTime, prevDelta uint64
+				for ax.Next() {
+					dd, err := ax.ReadUint()
+					if err != nil {
+						return err
+					}
+					ts := deltaDecode(&prevTime, deltaDecode(&prevDelta, zigzagDecode(uint64(dd))))
+					x.times = append(x.times, ts)
+				}
+				return ax.Err()
+			})
+		case 1:
+			err = ex.ReadArray(func(ax *Extractor) error {
+				var prev uint64
+				for ax.Next() {
+					v, err := ax.ReadUint()
+					if err != nil {
+						return err
+					}
+					x.values = append(x.values, decodeTimeSeriesValue(x.kind, &prev, uint64(v)))
+				}
+				return ax.Err()
+			})
+		default:
+			_, err = ex.Skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = ex.Err(); err != nil {
+		return nil, err
+	}
+
+	// 3. Verify that each timestamp has its value.
+	if len(x.times) != len(x.values) {
+		return nil, bsterr.Err(bsterr.CodeMalformedBinary, "time series chunk timestamps and values count mismatch").
+			WithDetails(bsterr.D("times", len(x.times)), bsterr.D("values", len(x.values)))
+	}
+	return x, nil
+}
+
+// encodeTimeSeriesValue encodes the value bits relative to the previous value, with the codec of the value kind.
+func encodeTimeSeriesValue(kind bsttype.Kind, prev *uint64, v uint64) uint64 {
+	if kind == bsttype.KindFloat64 {
+		xv := v ^ *prev
+		*prev = v
+		return bits.Reverse64(xv)
+	}
+	return zigzagEncode(deltaEncode(prev, int64(v)))
+}
+
+// decodeTimeSeriesValue reverts the encodeTimeSeriesValue.
+func decodeTimeSeriesValue(kind bsttype.Kind, prev *uint64, u uint64) uint64 {
+	if kind == bsttype.KindFloat64 {
+		*prev ^= bits.Reverse64(u)
+		return *prev
+	}
+	return uint64(deltaDecode(prev, zigzagDecode(u)))
+}
+
+// zigzagEncode maps the signed difference to the unsigned one, so that the differences of small magnitude are small.
+func zigzagEncode(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// zigzagDecode reverts the zigzagEncode.
+func zigzagDecode(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
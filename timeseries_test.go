@@ -0,0 +1,109 @@
+package bst
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestTimeSeriesChunk(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		kind   bsttype.Kind
+		append func(x *TimeSeriesChunk, t time.Time, i int) error
+		value  func(p TimeSeriesPoint) float64
+	}{
+		{
+			kind:   bsttype.KindFloat64,
+			append: func(x *TimeSeriesChunk, t time.Time, i int) error { return x.AppendFloat64(t, float64(i)*0.5) },
+			value:  func(p TimeSeriesPoint) float64 { return p.Float64() },
+		},
+		{
+			kind:   bsttype.KindInt64,
+			append: func(x *TimeSeriesChunk, t time.Time, i int) error { return x.AppendInt64(t, int64(50-i)) },
+			value:  func(p TimeSeriesPoint) float64 { return float64(p.Int64()) },
+		},
+		{
+			kind:   bsttype.KindUint64,
+			append: func(x *TimeSeriesChunk, t time.Time, i int) error { return x.AppendUint64(t, uint64(1000+i)) },
+			value:  func(p TimeSeriesPoint) float64 { return float64(p.Uint64()) },
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.kind.String(), func(t *testing.T) {
+			x, err := NewTimeSeriesChunk(tc.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 100; i++ {
+				if err = tc.append(x, start.Add(time.Duration(i)*10*time.Second), i); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			data, err := MarshalTimeSeriesChunk(x)
+			if err != nil {
+				t.Fatalf("marshaling chunk failed: %v", err)
+			}
+			got, err := UnmarshalTimeSeriesChunk(data)
+			if err != nil {
+				t.Fatalf("unmarshaling chunk failed: %v", err)
+			}
+			if got.Kind() != tc.kind || got.Len() != x.Len() {
+				t.Fatalf("unexpected chunk: %v with %d points", got.Kind(), got.Len())
+			}
+			for i := 0; i < x.Len(); i++ {
+				p, e := got.Point(i), x.Point(i)
+				if !p.Time.Equal(e.Time) || tc.value(p) != tc.value(e) {
+					t.Fatalf("unexpected point %d: %v=%v, expected: %v=%v", i, p.Time, tc.value(p), e.Time, tc.value(e))
+				}
+			}
+
+			// The chunk is much smaller than the array of the (int64, 8 byte value) structs.
+			if generic := x.Len() * 16; len(data)*3 > generic {
+				t.Fatalf("chunk binary of %d bytes is not much smaller than the generic %d bytes", len(data), generic)
+			}
+		})
+	}
+
+	t.Run("Range", func(t *testing.T) {
+		x, _ := NewTimeSeriesChunk(bsttype.KindFloat64)
+		for i := 0; i < 5; i++ {
+			if err := x.AppendFloat64(start.Add(time.Duration(i)*time.Second), math.Pi); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var seen int
+		x.Range(func(p TimeSeriesPoint) bool {
+			seen++
+			return seen < 3
+		})
+		if seen != 3 {
+			t.Fatalf("expected range to stop after 3 points, got: %d", seen)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := NewTimeSeriesChunk(bsttype.KindString); err == nil {
+			t.Fatal("expected unsupported kind error")
+		}
+		x, _ := NewTimeSeriesChunk(bsttype.KindInt64)
+		if err := x.AppendFloat64(start, 1); err == nil {
+			t.Fatal("expected value kind error")
+		}
+		if err := x.AppendInt64(start, 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := x.AppendInt64(start.Add(-time.Second), 1); err == nil {
+			t.Fatal("expected unordered point error")
+		}
+		for _, data := range [][]byte{nil, {byte(bsttype.KindString)}, bytes.Repeat([]byte{byte(bsttype.KindInt64)}, 2)} {
+			if _, err := UnmarshalTimeSeriesChunk(data); err == nil {
+				t.Fatalf("expected unmarshaling %v to fail", data)
+			}
+		}
+	})
+}
@@ -80,6 +80,10 @@ func (x *Extractor) ReadTimestamp() (time.Time, error) {
 		return time.Time{}, err
 	}
 
+	t := time.Unix(0, v).UTC()
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, t)
+	}
 	x.finishElem()
-	return time.Unix(0, v).UTC(), nil
+	return t, nil
 }
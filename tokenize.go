@@ -0,0 +1,201 @@
+package bst
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// Tokenizer replaces the values of the personally identifiable information (PII) fields with their tokens,
+// so that the pseudonymized datasets could be produced. The tokens are reversed only by the privileged callers,
+// whose extractor is given the tokenizer.
+type Tokenizer interface {
+	// Tokenize returns the token replacing the value of the struct field with given name.
+	Tokenize(field string, v []byte) ([]byte, error)
+	// Detokenize returns the value replaced by the token of the struct field with given name.
+	Detokenize(field string, token []byte) ([]byte, error)
+}
+
+// Compile-time check to ensure that TokenVault implements the Tokenizer interface.
+var _ Tokenizer = (*TokenVault)(nil)
+
+// TokenVaultTokenSize is the length of the tokens of the TokenVault.
+const TokenVaultTokenSize = 32
+
+// TokenVault is the in-memory Tokenizer with the deterministic tokens, thus the equal values of the same field
+// have equal tokens, and the pseudonymized datasets could still be joined by them. The token is the hex encoded
+// prefix of the HMAC-SHA256 of the field name and the value. The tokenized values are kept in the vault, so that
+// these could be detokenized. It is safe for concurrent use.
+type TokenVault struct {
+	key    []byte
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+// NewTokenVault creates a new token vault, whose tokens are derived with given secret key.
+func NewTokenVault(key []byte) *TokenVault {
+	return &TokenVault{key: bytes.Clone(key), values: make(map[string][]byte)}
+}
+
+// Tokenize returns the token of the field value, and stores the value in the vault.
+// Implements the Tokenizer interface.
+func (x *TokenVault) Tokenize(field string, v []byte) ([]byte, error) {
+	// 1. Derive the token from the field name and its value.
+	mac := hmac.New(sha256.New, x.key)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write(v)
+	token := make([]byte, TokenVaultTokenSize)
+	hex.Encode(token, mac.Sum(nil)[:TokenVaultTokenSize/2])
+
+	// 2. Store the value of the token, unless already stored.
+	key := field + "\x00" + string(token)
+	x.mu.RLock()
+	_, ok := x.values[key]
+	x.mu.RUnlock()
+	if !ok {
+		x.mu.Lock()
+		x.values[key] = bytes.Clone(v)
+		x.mu.Unlock()
+	}
+	return token, nil
+}
+
+// Detokenize returns the value of the token stored in the vault.
+// Implements the Tokenizer interface.
+func (x *TokenVault) Detokenize(field string, token []byte) ([]byte, error) {
+	x.mu.RLock()
+	v, ok := x.values[field+"\x00"+string(token)]
+	x.mu.RUnlock()
+	if !ok {
+		return nil, bsterr.Err(bsterr.CodeUndefinedValue, "token not found in the vault").
+			WithDetail("field", field)
+	}
+	return bytes.Clone(v), nil
+}
+
+// TokenizeField returns the FieldTransform which replaces the String or Bytes field value with its token.
+// The field name is the one passed to the tokenizer. Used with the Transcode, it pseudonymizes the stored values
+// in a single pass, without decoding the rest of their fields.
+func TokenizeField(tk Tokenizer, field string) FieldTransform {
+	return func(t bsttype.Type, data []byte, options bstio.ValueOptions) ([]byte, error) {
+		return transformTokenField(t, data, options, func(v []byte) ([]byte, error) {
+			return tk.Tokenize(field, v)
+		})
+	}
+}
+
+// DetokenizeField returns the FieldTransform which replaces the String or Bytes field token with its value.
+// It reverses the TokenizeField transform.
+func DetokenizeField(tk Tokenizer, field string) FieldTransform {
+	return func(t bsttype.Type, data []byte, options bstio.ValueOptions) ([]byte, error) {
+		return transformTokenField(t, data, options, func(v []byte) ([]byte, error) {
+			return tk.Detokenize(field, v)
+		})
+	}
+}
+
+// transformTokenField decodes the String or Bytes field binary, replaces its value with the result of fn,
+// and encodes it back with the same options.
+func transformTokenField(t bsttype.Type, data []byte, options bstio.ValueOptions, fn func(v []byte) ([]byte, error)) ([]byte, error) {
+	// 1. Decode the value of the field.
+	var (
+		v   []byte
+		err error
+	)
+	bt, isBytes := t.(*bsttype.Bytes)
+	switch {
+	case t.Kind() == bsttype.KindString:
+		var s string
+		if s, _, err = bstio.ReadString(bytes.NewReader(data), options.Descending, options.Comparable); err == nil {
+			v = []byte(s)
+		}
+	case isBytes:
+		if bt.HasFixedSize() {
+			return nil, bsterr.Err(bsterr.CodeInvalidType, "fixed size bytes field could not be tokenized")
+		}
+		v, _, err = bstio.ReadBytes(bytes.NewReader(data), 0, options.Descending, options.Comparable)
+	default:
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "tokenized field needs to be a string or bytes").
+			WithDetail("type", t)
+	}
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to decode field binary")
+	}
+
+	// 2. Replace the value.
+	if v, err = fn(v); err != nil {
+		return nil, err
+	}
+
+	// 3. Encode it back with the same options.
+	var buf bytes.Buffer
+	if isBytes {
+		_, err = bstio.WriteBytes(&buf, 0, v, options.Descending, options.Comparable)
+	} else {
+		_, err = bstio.WriteString(&buf, string(v), options.Descending, options.Comparable)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// tokenizeField replaces the value of the struct field, whose tokenizer is defined in the Tokenizers option,
+// with its token. It returns false if the field is not tokenized.
+func (x *Composer) tokenizeField(v []byte) ([]byte, bool, error) {
+	// 1. Check if the current struct field is tokenized.
+	if len(x.opts.Tokenizers) == 0 {
+		return nil, false, nil
+	}
+	name, ok := x.structFieldName()
+	if !ok {
+		return nil, false, nil
+	}
+	tk, ok := x.opts.Tokenizers[name]
+	if !ok || tk == nil {
+		return nil, false, nil
+	}
+
+	// 2. The fixed size bytes could not hold the token of arbitrary length.
+	if bt, ok := x.elemType.(*bsttype.Bytes); ok && bt.HasFixedSize() {
+		return nil, false, bsterr.Err(bsterr.CodeInvalidType, "fixed size bytes field could not be tokenized").
+			WithDetail("field", name)
+	}
+
+	// 3. Replace the value with its token.
+	token, err := tk.Tokenize(name, v)
+	if err != nil {
+		return nil, false, bsterr.ErrWrap(err, bsterr.CodeEncodingBinaryValue, "failed to tokenize field value").
+			WithDetail("field", name)
+	}
+	return token, true, nil
+}
+
+// detokenizeField replaces the token of the struct field, whose tokenizer is defined in the Tokenizers option,
+// with its value. Without the tokenizer, the token is returned as is.
+func (x *Extractor) detokenizeField(v []byte) ([]byte, error) {
+	if len(x.opts.Tokenizers) == 0 {
+		return v, nil
+	}
+	name, ok := x.structFieldName()
+	if !ok {
+		return v, nil
+	}
+	tk, ok := x.opts.Tokenizers[name]
+	if !ok || tk == nil {
+		return v, nil
+	}
+	dv, err := tk.Detokenize(name, v)
+	if err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to detokenize field value").
+			WithDetail("field", name)
+	}
+	return dv, nil
+}
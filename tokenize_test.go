@@ -0,0 +1,145 @@
+package bst
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/devmodules/bst/bstio"
+)
+
+type tokenPerson struct {
+	ID    uint64 `bst:"id"`
+	Email string `bst:"email"`
+	Phone []byte `bst:"phone"`
+	Name  string `bst:"name"`
+}
+
+func TestTokenizer(t *testing.T) {
+	vault := NewTokenVault([]byte("secret"))
+	tokenizers := map[string]Tokenizer{"email": vault, "phone": vault}
+	in := tokenPerson{ID: 7, Email: "john@example.com", Phone: []byte("+48 123 456 789"), Name: "john"}
+
+	data, err := Marshal(in, WithTokenizers(tokenizers))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if bytes.Contains(data, []byte(in.Email)) || bytes.Contains(data, in.Phone) {
+		t.Fatal("tokenized field values are written in plain")
+	}
+
+	// 1. The unprivileged reader gets the deterministic tokens.
+	var pseudo tokenPerson
+	if err = Unmarshal(data, &pseudo); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if pseudo.ID != in.ID || pseudo.Name != in.Name || len(pseudo.Email) != TokenVaultTokenSize || len(pseudo.Phone) != TokenVaultTokenSize {
+		t.Fatalf("unexpected pseudonymized value: %+v", pseudo)
+	}
+	again, err := Marshal(in, WithTokenizers(tokenizers))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Fatal("equal values produced different tokens")
+	}
+
+	// 2. The privileged reader gets the original values.
+	var out tokenPerson
+	if err = Unmarshal(data, &out, WithTokenizers(tokenizers)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if out.Email != in.Email || !bytes.Equal(out.Phone, in.Phone) || out.Name != in.Name {
+		t.Fatalf("unexpected detokenized value: %+v", out)
+	}
+
+	t.Run("Encrypted", func(t *testing.T) {
+		encryption := &FieldEncryption{
+			Fields: map[string]EncryptionMode{"email": EncryptDeterministic},
+			Keys:   StaticKeyProvider{CurrentID: 1, Keys: map[uint32][]byte{1: bytes.Repeat([]byte{0x02}, FieldEncryptionKeySize)}},
+		}
+		data, err := Marshal(in, WithTokenizers(tokenizers), WithFieldEncryption(encryption))
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		var out tokenPerson
+		if err = Unmarshal(data, &out, WithTokenizers(tokenizers), WithFieldEncryption(encryption)); err != nil {
+			t.Fatalf("unmarshal failed: %v", err)
+		}
+		if out.Email != in.Email {
+			t.Fatalf("unexpected decrypted and detokenized value: %+v", out)
+		}
+	})
+
+	t.Run("Transcode", func(t *testing.T) {
+		st, err := StructTypeOf(in)
+		if err != nil {
+			t.Fatalf("struct type failed: %v", err)
+		}
+		cases := []struct {
+			opts []Option
+			o    bstio.ValueOptions
+		}{
+			{o: bstio.ValueOptions{}},
+			{opts: []Option{WithComparable()}, o: bstio.ValueOptions{Comparable: true}},
+			{opts: []Option{WithComparable(), WithDescending()}, o: bstio.ValueOptions{Comparable: true, Descending: true}},
+		}
+		for _, tc := range cases {
+			opts, o := tc.opts, tc.o
+			plain, err := Marshal(in, opts...)
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+			tokenized, err := Marshal(in, append(opts[:len(opts):len(opts)], WithTokenizers(tokenizers))...)
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+
+			// The single transcode pass produces the binary of the tokenizing composer.
+			got, err := Transcode(plain[1:], st, TranscodeRules{
+				Fields: map[FieldPath]FieldTransform{
+					"email": TokenizeField(vault, "email"),
+					"phone": TokenizeField(vault, "phone"),
+				},
+				Options: o,
+			})
+			if err != nil {
+				t.Fatalf("transcode failed: %v", err)
+			}
+			if !bytes.Equal(got, tokenized[1:]) {
+				t.Fatalf("unexpected transcoded binary (%+v):\n%v\nexpected:\n%v", o, got, tokenized[1:])
+			}
+
+			// The reverse pass restores the original binary.
+			restored, err := Transcode(got, st, TranscodeRules{
+				Fields: map[FieldPath]FieldTransform{
+					"email": DetokenizeField(vault, "email"),
+					"phone": DetokenizeField(vault, "phone"),
+				},
+				Options: o,
+			})
+			if err != nil {
+				t.Fatalf("transcode failed: %v", err)
+			}
+			if !bytes.Equal(restored, plain[1:]) {
+				t.Fatalf("unexpected restored binary (%+v):\n%v\nexpected:\n%v", o, restored, plain[1:])
+			}
+		}
+
+		if _, err = Transcode(data[1:], st, TranscodeRules{
+			Fields: map[FieldPath]FieldTransform{"id": TokenizeField(vault, "id")},
+		}); err == nil {
+			t.Fatal("expected tokenizing integer field to fail")
+		}
+	})
+
+	t.Run("UnknownToken", func(t *testing.T) {
+		data, err := Marshal(in, WithTokenizers(map[string]Tokenizer{"email": NewTokenVault([]byte("other"))}))
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		var out tokenPerson
+		if err = Unmarshal(data, &out, WithTokenizers(tokenizers)); err == nil {
+			t.Fatal("expected detokenizing unknown token to fail")
+		}
+	})
+}
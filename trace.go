@@ -0,0 +1,136 @@
+package bst
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// TraceOp is the kind of the traced extractor operation.
+type TraceOp string
+
+// Enumerated trace operations.
+const (
+	// TraceOpRead is the read of the basic value.
+	TraceOpRead TraceOp = "read"
+	// TraceOpSkip is the skip of the value.
+	TraceOpSkip TraceOp = "skip"
+	// TraceOpNullFlag is the check of the nullable value flag.
+	TraceOpNullFlag TraceOp = "null"
+	// TraceOpHeader is the read of the header of the Any or OneOf value, which dereferences the element type.
+	TraceOpHeader TraceOp = "header"
+	// TraceOpComposite is the read of the composite (struct, array, map) value.
+	// The composite event precedes the events of its elements.
+	TraceOpComposite TraceOp = "composite"
+)
+
+// maxTraceValueSize is the maximum length of the traced value summary.
+const maxTraceValueSize = 64
+
+// TraceEvent is a single traced operation of the extractor.
+type TraceEvent struct {
+	// Offset is the offset of the operation binary, counted from the start of the extractor input.
+	Offset int `json:"offset"`
+	// Size is the number of bytes read by the operation.
+	Size int `json:"size"`
+	// Op is the kind of the operation.
+	Op TraceOp `json:"op"`
+	// Path is the path of the element, i.e. '$.items[2].name'.
+	Path string `json:"path"`
+	// Kind is the name of the element type kind.
+	Kind string `json:"kind"`
+	// Value is the summary of the decoded value, truncated if too long.
+	Value string `json:"value,omitempty"`
+	// Descending is the effective order of the element.
+	Descending bool `json:"desc,omitempty"`
+	// Comparable is the comparable format flag of the binary.
+	Comparable bool `json:"comparable,omitempty"`
+	// CompatibilityMode is the compatibility mode flag of the binary.
+	CompatibilityMode bool `json:"compat,omitempty"`
+}
+
+// Trace is a machine-readable record of the extractor operations.
+// It is enabled by setting it in the ExtractorOptions, and used to diff the decoding of the same binary
+// by different implementations or versions.
+// Tracing is meant for debugging, it slows the extraction down considerably.
+type Trace struct {
+	Events []TraceEvent `json:"events"`
+}
+
+// Reset clears the trace events, so that the trace could be reused.
+func (t *Trace) Reset() {
+	t.Events = t.Events[:0]
+}
+
+// WriteJSON writes the trace as a JSON document.
+func (t *Trace) WriteJSON(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeWritingFailed, "failed to write trace")
+	}
+	return nil
+}
+
+// traceElem records the operation on the current element, which started at the x.elemStart offset.
+func (x *Extractor) traceElem(op TraceOp, v interface{}) {
+	x.traceEvent(op, v)
+	x.elemStart = x.bytesRead
+}
+
+// traceEvent records the operation on the current element and returns its index in the trace.
+func (x *Extractor) traceEvent(op TraceOp, v interface{}) int {
+	e := TraceEvent{
+		Offset:            x.traceOffset + x.elemStart,
+		Size:              x.bytesRead - x.elemStart,
+		Op:                op,
+		Path:              x.Path(),
+		Descending:        x.elemDesc,
+		Comparable:        x.opts.Comparable,
+		CompatibilityMode: x.opts.CompatibilityMode,
+	}
+	if x.elemType != nil {
+		e.Kind = x.elemType.Kind().String()
+	}
+	if v != nil {
+		e.Value = traceValueSummary(v)
+	}
+	x.opts.Trace.Events = append(x.opts.Trace.Events, e)
+	return len(x.opts.Trace.Events) - 1
+}
+
+// traceComposite completes the composite event, after its elements were read.
+func (x *Extractor) traceComposite(i int) {
+	x.opts.Trace.Events[i].Size = x.bytesRead - x.elemStart
+	x.elemStart = x.bytesRead
+}
+
+func traceValueSummary(v interface{}) string {
+	var s string
+	switch tv := v.(type) {
+	case string:
+		if len(tv) > maxTraceValueSize {
+			tv = tv[:maxTraceValueSize] + "..."
+		}
+		return strconv.Quote(tv)
+	case []byte:
+		if len(tv) > maxTraceValueSize/2 {
+			return hex.EncodeToString(tv[:maxTraceValueSize/2]) + "..."
+		}
+		return hex.EncodeToString(tv)
+	case time.Time:
+		s = tv.Format(time.RFC3339Nano)
+	case bsttype.Type:
+		s = tv.Kind().String()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if len(s) > maxTraceValueSize {
+		s = s[:maxTraceValueSize] + "..."
+	}
+	return s
+}
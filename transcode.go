@@ -0,0 +1,71 @@
+package bst
+
+import (
+	"bytes"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+// FieldTransform transforms the binary of a struct field of type t during the Transcode.
+// The options are the options the field binary was written with, with the field order already applied.
+type FieldTransform func(t bsttype.Type, data []byte, options bstio.ValueOptions) ([]byte, error)
+
+// FlipOrder is the FieldTransform which inverts the order of the field binary. It repairs the fields written with
+// the inverted Descending flag, where the value is transcoded with the type it was written with.
+// The binary is decoded with given options and encoded back with the inverted order.
+func FlipOrder(t bsttype.Type, data []byte, options bstio.ValueOptions) ([]byte, error) {
+	// 1. Decode the value with the order it was written with.
+	v := bstvalue.EmptyValueOf(t)
+	if v == nil {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "no value is defined for the field type").WithDetail("type", t)
+	}
+	if err := v.UnmarshalValue(data, options); err != nil {
+		return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to decode field binary")
+	}
+
+	// 2. Encode it back with the inverted order.
+	inverted := options
+	inverted.Descending = !inverted.Descending
+	return v.MarshalValue(inverted)
+}
+
+// TranscodeRules are the rules used by the Transcode function.
+type TranscodeRules struct {
+	// Fields maps the paths of the struct fields to their transforms. The nested struct fields are addressed
+	// by their dotted path, i.e. "Address.City".
+	Fields map[FieldPath]FieldTransform
+	// Options are the binary options the input value was encoded with. The result is encoded with them as well.
+	Options bstio.ValueOptions
+}
+
+// Transcode rewrites the headless struct value of the type t, by applying the transforms of the rules to their fields.
+// The t needs to be the type the value was written with, as it is used to find the field binaries.
+// All other fields are copied verbatim, without being decoded, which makes it suitable for the in-place repair jobs,
+// i.e. fixing the fields written with the inverted Descending flag with the FlipOrder transform.
+// In the compatibility mode, the transforms of the fields missing in the value are not called.
+func Transcode(in []byte, t bsttype.Type, rules TranscodeRules) ([]byte, error) {
+	// 1. Turn the transforms into the field rewrites, which keep the missing fields absent.
+	rewrites := make(map[FieldPath]fieldRewrite, len(rules.Fields))
+	for path, fn := range rules.Fields {
+		if fn == nil {
+			return nil, bsterr.Err(bsterr.CodeInvalidValue, "transcode field transform is not defined").
+				WithDetail("path", path)
+		}
+		rewrites[path] = func(t bsttype.Type, data []byte, fo bstio.ValueOptions) ([]byte, error) {
+			if data == nil {
+				return nil, nil
+			}
+			return fn(t, data, fo)
+		}
+	}
+
+	// 2. Rewrite the struct binary.
+	var buf bytes.Buffer
+	if err := rewriteStruct(&buf, in, t, rewrites, rules.Options); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
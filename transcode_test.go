@@ -0,0 +1,107 @@
+package bst
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestTranscode(t *testing.T) {
+	structOf := func(legacy bool) *bsttype.Struct {
+		at := &bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "City", Type: bsttype.String(), Descending: legacy},
+				{Index: 2, Name: "Zip", Type: bsttype.Uint32()},
+			},
+		}
+		return &bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "Name", Type: bsttype.String()},
+				{Index: 2, Name: "Active", Type: bsttype.Boolean(), Descending: legacy},
+				{Index: 3, Name: "Admin", Type: bsttype.Boolean()},
+				{Index: 4, Name: "Address", Type: at},
+				{Index: 5, Name: "Score", Type: bsttype.Int64(), Descending: !legacy},
+			},
+		}
+	}
+
+	row := func(t *testing.T, st *bsttype.Struct, o bstio.ValueOptions) []byte {
+		sv := bstvalue.MustNewStructValue(st, []bstvalue.Value{
+			bstvalue.NewStringValue("john"),
+			bstvalue.NewBoolValue(true),
+			bstvalue.NewBoolValue(false),
+			bstvalue.MustNewStructValue(st.Fields[3].Type.(*bsttype.Struct), []bstvalue.Value{
+				bstvalue.NewStringValue("Warsaw"),
+				bstvalue.NewUint32Value(12345),
+			}),
+			bstvalue.NewInt64Value(-10),
+		})
+		data, err := sv.MarshalValue(o)
+		if err != nil {
+			t.Fatalf("marshaling row failed: %v", err)
+		}
+		return data
+	}
+
+	for _, o := range []bstio.ValueOptions{{}, {Descending: true}, {Comparable: true}} {
+		legacy, expected := row(t, structOf(true), o), row(t, structOf(false), o)
+		if bytes.Equal(legacy, expected) {
+			t.Fatal("legacy row doesn't differ from the expected one")
+		}
+
+		got, err := Transcode(legacy, structOf(true), TranscodeRules{
+			Fields: map[FieldPath]FieldTransform{
+				"Active":       FlipOrder,
+				"Address.City": FlipOrder,
+				"Score":        FlipOrder,
+			},
+			Options: o,
+		})
+		if err != nil {
+			t.Fatalf("transcode failed: %v", err)
+		}
+		if !bytes.Equal(got, expected) {
+			t.Fatalf("unexpected transcoded value (options: %+v):\n%v\nexpected:\n%v", o, got, expected)
+		}
+	}
+
+	t.Run("Compatibility", func(t *testing.T) {
+		ct := &bsttype.Struct{
+			Fields: []bsttype.StructField{
+				{Index: 1, Name: "A", Type: bsttype.Uint8()},
+				{Index: 2, Name: "B", Type: bsttype.Uint8()},
+			},
+		}
+		// The row missing the field B is composed with the older revision of the type.
+		in := composeCompatibilityRow(t, &bsttype.Struct{Fields: ct.Fields[:1]}, 5)
+		got, err := Transcode(in, ct, TranscodeRules{
+			Fields:  map[FieldPath]FieldTransform{"A": FlipOrder, "B": FlipOrder},
+			Options: bstio.ValueOptions{CompatibilityMode: true},
+		})
+		if err != nil {
+			t.Fatalf("transcode failed: %v", err)
+		}
+
+		// The missing field B is kept absent, and A: 5 is flipped to descending.
+		expected := composeCompatibilityRow(t, &bsttype.Struct{Fields: ct.Fields[:1]}, ^uint8(5))
+		if !bytes.Equal(got, expected) {
+			t.Fatalf("unexpected transcoded value:\n%v\nexpected:\n%v", got, expected)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		st := structOf(false)
+		in := row(t, st, bstio.ValueOptions{})
+		for _, fields := range []map[FieldPath]FieldTransform{
+			{"Unknown": FlipOrder},
+			{"Name": nil},
+		} {
+			if _, err := Transcode(in, st, TranscodeRules{Fields: fields}); err == nil {
+				t.Fatalf("expected transcode to fail for %v", fields)
+			}
+		}
+	})
+}
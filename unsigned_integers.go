@@ -32,7 +32,10 @@ func (x *Composer) WriteUint8(v uint8) error {
 		x.bytesWritten += n
 	}
 
-	// 4. Write the value.
+	// 4. Write the value, as the difference from the previous element of the delta encoded array.
+	if x.delta {
+		v = deltaEncode(&x.deltaPrev, v)
+	}
 	n, err := bstio.WriteUint8(x.w, v, x.elemDesc)
 	if err != nil {
 		return err
@@ -73,7 +76,10 @@ func (x *Composer) WriteUint16(v uint16) error {
 		x.bytesWritten += n
 	}
 
-	// 4. Write the value.
+	// 4. Write the value, as the difference from the previous element of the delta encoded array.
+	if x.delta {
+		v = deltaEncode(&x.deltaPrev, v)
+	}
 	n, err := bstio.WriteUint16(x.w, v, x.elemDesc)
 	if err != nil {
 		return err
@@ -115,7 +121,10 @@ func (x *Composer) WriteUint32(v uint32) error {
 		x.bytesWritten += n
 	}
 
-	// 4. Write the value.
+	// 4. Write the value, as the difference from the previous element of the delta encoded array.
+	if x.delta {
+		v = deltaEncode(&x.deltaPrev, v)
+	}
 	n, err := bstio.WriteUint32(x.w, v, x.elemDesc)
 	if err != nil {
 		return err
@@ -156,7 +165,10 @@ func (x *Composer) WriteUint64(v uint64) error {
 		x.bytesWritten += n
 	}
 
-	// 4. Write the value.
+	// 4. Write the value, as the difference from the previous element of the delta encoded array.
+	if x.delta {
+		v = deltaEncode(&x.deltaPrev, v)
+	}
 	n, err := bstio.WriteUint64(x.w, v, x.elemDesc)
 	if err != nil {
 		return err
@@ -197,7 +209,10 @@ func (x *Composer) WriteUint(v uint) error {
 		x.bytesWritten += n
 	}
 
-	// 4. Write the value.
+	// 4. Write the value, as the difference from the previous element of the delta encoded array.
+	if x.delta {
+		v = deltaEncode(&x.deltaPrev, v)
+	}
 	n, err := bstio.WriteUint(x.w, v, x.elemDesc)
 	if err != nil {
 		return err
@@ -247,6 +262,12 @@ func (x *Extractor) ReadUint8() (uint8, error) {
 	}
 
 	x.bytesRead += n
+	if x.delta {
+		v = deltaDecode(&x.deltaPrev, v)
+	}
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
@@ -267,6 +288,12 @@ func (x *Extractor) ReadUint16() (uint16, error) {
 	}
 
 	// 3. Verify if current element matches the expected type.
+	//    The narrower integer of the compatibility mode is read, widened losslessly.
+	if x.coercible(bsttype.KindUint16) {
+		x.elemType = x.embeddedElemType()
+		v, err := x.Uint()
+		return uint16(v), err
+	}
 	if x.elemType.Kind() != bsttype.KindUint16 {
 		return 0, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
 			WithDetails(
@@ -282,6 +309,12 @@ func (x *Extractor) ReadUint16() (uint16, error) {
 	}
 
 	x.bytesRead += n
+	if x.delta {
+		v = deltaDecode(&x.deltaPrev, v)
+	}
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
@@ -302,6 +335,12 @@ func (x *Extractor) ReadUint32() (uint32, error) {
 	}
 
 	// 3. Verify if current element matches the expected type.
+	//    The narrower integer of the compatibility mode is read, widened losslessly.
+	if x.coercible(bsttype.KindUint32) {
+		x.elemType = x.embeddedElemType()
+		v, err := x.Uint()
+		return uint32(v), err
+	}
 	if x.elemType.Kind() != bsttype.KindUint32 {
 		return 0, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
 			WithDetails(
@@ -317,7 +356,13 @@ func (x *Extractor) ReadUint32() (uint32, error) {
 	}
 
 	x.bytesRead += n
+	if x.delta {
+		v = deltaDecode(&x.deltaPrev, v)
+	}
 
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
@@ -338,6 +383,12 @@ func (x *Extractor) ReadUint64() (uint64, error) {
 	}
 
 	// 3. Verify if current element matches the expected type.
+	//    The narrower integer of the compatibility mode is read, widened losslessly.
+	if x.coercible(bsttype.KindUint64) {
+		x.elemType = x.embeddedElemType()
+		v, err := x.Uint()
+		return uint64(v), err
+	}
 	if x.elemType.Kind() != bsttype.KindUint64 {
 		return 0, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
 			WithDetails(
@@ -352,7 +403,13 @@ func (x *Extractor) ReadUint64() (uint64, error) {
 		return 0, err
 	}
 	x.bytesRead += n
+	if x.delta {
+		v = deltaDecode(&x.deltaPrev, v)
+	}
 
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
@@ -373,6 +430,12 @@ func (x *Extractor) ReadUint() (uint, error) {
 	}
 
 	// 3. Verify if current element matches the expected type.
+	//    The narrower integer of the compatibility mode is read, widened losslessly.
+	if x.coercible(bsttype.KindUint) {
+		x.elemType = x.embeddedElemType()
+		v, err := x.Uint()
+		return uint(v), err
+	}
 	if x.elemType.Kind() != bsttype.KindUint {
 		return 0, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
 			WithDetails(
@@ -388,7 +451,13 @@ func (x *Extractor) ReadUint() (uint, error) {
 	}
 
 	x.bytesRead += n
+	if x.delta {
+		v = deltaDecode(&x.deltaPrev, v)
+	}
 
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
 	x.finishElem()
 	return v, nil
 }
@@ -417,6 +486,9 @@ func (x *Extractor) Uint() (uint64, error) {
 			return 0, err
 		}
 		x.bytesRead += n
+		if x.delta {
+			v = deltaDecode(&x.deltaPrev, v)
+		}
 		res = uint64(v)
 	case bsttype.KindUint16:
 		v, n, err := bstio.ReadUint16(x.r, x.elemDesc)
@@ -424,6 +496,9 @@ func (x *Extractor) Uint() (uint64, error) {
 			return 0, err
 		}
 		x.bytesRead += n
+		if x.delta {
+			v = deltaDecode(&x.deltaPrev, v)
+		}
 		res = uint64(v)
 	case bsttype.KindUint32:
 		v, n, err := bstio.ReadUint32(x.r, x.elemDesc)
@@ -431,6 +506,9 @@ func (x *Extractor) Uint() (uint64, error) {
 			return 0, err
 		}
 		x.bytesRead += n
+		if x.delta {
+			v = deltaDecode(&x.deltaPrev, v)
+		}
 		res = uint64(v)
 	case bsttype.KindUint64:
 		v, n, err := bstio.ReadUint64(x.r, x.elemDesc)
@@ -438,6 +516,9 @@ func (x *Extractor) Uint() (uint64, error) {
 			return 0, err
 		}
 		x.bytesRead += n
+		if x.delta {
+			v = deltaDecode(&x.deltaPrev, v)
+		}
 		res = v
 	case bsttype.KindUint:
 		v, n, err := bstio.ReadUint(x.r, x.elemDesc)
@@ -445,6 +526,9 @@ func (x *Extractor) Uint() (uint64, error) {
 			return 0, err
 		}
 		x.bytesRead += n
+		if x.delta {
+			v = deltaDecode(&x.deltaPrev, v)
+		}
 		res = uint64(v)
 	default:
 		return 0, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
@@ -454,6 +538,9 @@ func (x *Extractor) Uint() (uint64, error) {
 			)
 	}
 
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, res)
+	}
 	x.finishElem()
 	return res, nil
 }
@@ -0,0 +1,88 @@
+package bst
+
+import (
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// WriteUUID writes the UUID value to the composer. It accepts any of the [16]byte based types,
+// i.e. the bstio.UUID or the github.com/google/uuid.UUID.
+func (x *Composer) WriteUUID(v [16]byte) error {
+	// 1. Check if the element was already written.
+	if x.done {
+		return bsterr.Err(bsterr.CodeAlreadyWritten, "element already written")
+	}
+
+	// 2. Verify if current element matches expected type.
+	if x.elemType.Kind() != bsttype.KindUUID {
+		return bsterr.Err(bsterr.CodeInvalidType, "invalid type to write").
+			WithDetails(
+				bsterr.D("expected", bsttype.KindUUID),
+				bsterr.D("actual", x.elemType.Kind()),
+			)
+	}
+
+	// 3. If the base is a struct, check if the field header needs to be written.
+	if x.needWriteFieldHeader() {
+		n, err := x.writeFieldHeader(x.w, x.fieldIndex(), bstio.UUIDBinarySize)
+		if err != nil {
+			return err
+		}
+
+		x.bytesWritten += n
+	}
+
+	// 4. Write the value.
+	n, err := bstio.WriteUUID(x.w, v, x.elemDesc)
+	if err != nil {
+		return err
+	}
+
+	x.bytesWritten += n
+
+	// 5. Mark the element as written.
+	if err = x.finishElem(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadUUID reads the UUID value from the extractor. The result is assignable to any of the [16]byte based types,
+// i.e. the bstio.UUID or the github.com/google/uuid.UUID.
+func (x *Extractor) ReadUUID() ([16]byte, error) {
+	if x.err != nil {
+		return [16]byte{}, x.err
+	}
+	// 1. Check if reading element value is already finished.
+	if x.elemDone {
+		return [16]byte{}, bsterr.Err(bsterr.CodeAlreadyRead, "elem already done")
+	}
+
+	// 2. Check if current element is still in range.
+	if x.index > x.maxIndex {
+		return [16]byte{}, bsterr.Err(bsterr.CodeOutOfBounds, "buffIndex out of bounds")
+	}
+
+	// 3. Verify if current element matches the expected type.
+	if x.elemType.Kind() != bsttype.KindUUID {
+		return [16]byte{}, bsterr.Err(bsterr.CodeInvalidType, "invalid type element type").
+			WithDetails(
+				bsterr.D("expected", bsttype.KindUUID),
+				bsterr.D("actual", x.elemType.Kind()),
+			)
+	}
+
+	// 4. Read the UUID.
+	v, n, err := bstio.ReadUUID(x.r, x.elemDesc)
+	x.bytesRead += n
+	if err != nil {
+		return [16]byte{}, err
+	}
+
+	if x.opts.Trace != nil {
+		x.traceElem(TraceOpRead, v)
+	}
+	x.finishElem()
+	return v, nil
+}
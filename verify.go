@@ -0,0 +1,206 @@
+package bst
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bsttype"
+)
+
+// DefaultVerifyMaxDepth is the maximum depth of the nested structs, arrays and maps of the verified value,
+// used if the VerifyOptions doesn't define it.
+const DefaultVerifyMaxDepth = 64
+
+// VerifyOptions are the options of the Verify.
+type VerifyOptions struct {
+	// Extractor are the options of the extractor walking the value, i.e. its encoding format and decode budget.
+	// The expected type is the verified type.
+	Extractor ExtractorOptions
+	// MaxDepth is the maximum depth of the nested structs, arrays and maps. The zero value means
+	// the DefaultVerifyMaxDepth.
+	MaxDepth int
+	// AllowTrailing doesn't report the bytes following the value, i.e. of the values stored one after another.
+	AllowTrailing bool
+}
+
+// VerifyProblem is the problem found in the verified value binary.
+type VerifyProblem struct {
+	// Offset is the absolute offset of the element binary in the verified reader.
+	Offset int
+	// Path is the path from the root value to the element, see the Extractor.Path.
+	Path string
+	// Err describes the problem.
+	Err error
+}
+
+// String returns the problem along with its offset and path.
+func (x VerifyProblem) String() string {
+	return fmt.Sprintf("%s at offset %d: %v", x.Path, x.Offset, x.Err)
+}
+
+// errVerifyAborted stops the walk of the value, whose binary could not be followed beyond the reported problem.
+var errVerifyAborted = errors.New("verify aborted")
+
+// Verify checks the structural integrity of the value of given type, read from r, without materializing it.
+// The value is walked by the extractor: the lengths, escapes and terminators of its elements are checked by skipping
+// them, the enum indices, oneof and any headers are checked against their types, and the nesting depth is limited.
+// Once the binary could not be followed any further, the walk stops. The checksum trailer of the value, if any,
+// is verified as well. It returns the problems in the order they were found, thus no problems mean a valid value.
+// It is meant for the storage scrubbers detecting the corrupted values proactively.
+func Verify(r io.Reader, t bsttype.Type, opts VerifyOptions) []VerifyProblem {
+	v := verifier{maxDepth: opts.MaxDepth}
+	if v.maxDepth <= 0 {
+		v.maxDepth = DefaultVerifyMaxDepth
+	}
+
+	// 1. Read the header of the value.
+	xOpts := opts.Extractor
+	xOpts.ExpectedType = t
+	start := 0
+	if s, ok := r.(io.Seeker); ok {
+		if pos, err := s.Seek(0, io.SeekCurrent); err == nil {
+			start = int(pos)
+		}
+	}
+	x, err := NewExtractor(r, xOpts)
+	if err != nil {
+		return []VerifyProblem{{Offset: start, Path: "$", Err: err}}
+	}
+
+	// 2. Walk the elements of the root value, along with the checksum trailer.
+	if err = v.walk(x, 0); err == nil {
+		if err = x.VerifyAndClose(); err != nil {
+			v.problems = append(v.problems, VerifyProblem{Offset: x.Offset(), Path: "$", Err: err})
+		}
+	} else {
+		x.Close()
+	}
+	if err != nil {
+		return v.problems
+	}
+
+	// 3. Check if the value overruns the input, as the skipped values are seeked over, or if it is followed by any bytes.
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return v.problems
+	}
+	pos, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return v.problems
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return v.problems
+	}
+	if _, err = s.Seek(pos, io.SeekStart); err != nil {
+		return v.problems
+	}
+	switch {
+	case pos > end:
+		v.problems = append(v.problems, VerifyProblem{
+			Offset: int(end),
+			Path:   "$",
+			Err:    bsterr.Err(bsterr.CodeTruncatedBinary, "value overruns the input").WithDetail("size", pos-end),
+		})
+	case pos < end && !opts.AllowTrailing:
+		v.problems = append(v.problems, VerifyProblem{
+			Offset: int(pos),
+			Path:   "$",
+			Err:    bsterr.Err(bsterr.CodeMalformedBinary, "trailing bytes follow the value").WithDetail("size", end-pos),
+		})
+	}
+	return v.problems
+}
+
+// verifier walks the value binary, and collects its problems.
+type verifier struct {
+	maxDepth int
+	problems []VerifyProblem
+}
+
+// report reports the problem of the current element of the extractor.
+func (v *verifier) report(x *Extractor, err error) {
+	v.problems = append(v.problems, VerifyProblem{Offset: x.StartOffset(), Path: x.Path(), Err: err})
+}
+
+// abort reports the problem of the current element, beyond which the binary could not be followed.
+func (v *verifier) abort(x *Extractor, err error) error {
+	if errors.Is(err, errVerifyAborted) {
+		return err
+	}
+	v.report(x, err)
+	return errVerifyAborted
+}
+
+// walk verifies all the elements of the extractor.
+func (v *verifier) walk(x *Extractor, depth int) error {
+	for x.Next() {
+		if err := v.verifyElem(x, depth); err != nil {
+			return err
+		}
+	}
+	if err := x.Err(); err != nil {
+		return v.abort(x, err)
+	}
+	return nil
+}
+
+// verifyElem verifies the current element of the extractor.
+func (v *verifier) verifyElem(x *Extractor, depth int) error {
+	// 1. The nested composites are walked, up to the maximum depth.
+	t, err := x.derefType(x.elemType)
+	if err != nil {
+		return v.abort(x, err)
+	}
+	switch t.Kind() {
+	case bsttype.KindStruct, bsttype.KindArray, bsttype.KindMap:
+		if depth+1 > v.maxDepth {
+			return v.abort(x, bsterr.Err(bsterr.CodeMalformedBinary, "value nested too deep").
+				WithDetail("maxDepth", v.maxDepth))
+		}
+	}
+	walk := func(sx *Extractor) error {
+		return v.walk(sx, depth+1)
+	}
+	switch tt := t.(type) {
+	case *bsttype.Struct:
+		err = x.ReadStruct(walk)
+	case *bsttype.Array:
+		err = x.ReadArray(walk)
+	case *bsttype.Map:
+		err = x.ReadMap(walk)
+	case *bsttype.Nullable:
+		var isNull bool
+		if isNull, err = x.IsNull(); err == nil && !isNull {
+			return v.verifyElem(x, depth)
+		}
+	case *bsttype.Enum:
+		// 2. The enum index is read, and the unknown one is reported, as the binary could be followed anyway.
+		var index uint
+		if index, err = x.ReadEnumIndex(); err == nil {
+			if _, ok := tt.IndexString(index); !ok {
+				v.report(x, bsterr.Err(bsterr.CodeInvalidValue, "enum element not found").WithDetail("index", index))
+			}
+		}
+	case *bsttype.OneOf:
+		// 3. The oneof and any headers define the type of the following value.
+		if _, err = x.ReadOneOfHeader(); err == nil {
+			return v.verifyElem(x, depth)
+		}
+	default:
+		if t.Kind() == bsttype.KindAny {
+			if _, err = x.ReadAnyType(); err == nil {
+				return v.verifyElem(x, depth)
+			}
+			break
+		}
+		// 4. The other values are skipped, which checks their lengths, escapes and terminators.
+		_, err = x.Skip()
+	}
+	if err != nil {
+		return v.abort(x, err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package bst
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestVerify(t *testing.T) {
+	color := &bsttype.Enum{ValueBytes: 1, Elements: []bsttype.EnumElement{{String: "red", Index: 1}, {String: "blue", Index: 2}}}
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "name", Type: bsttype.String()},
+		{Index: 2, Name: "color", Type: color},
+		{Index: 3, Name: "labels", Type: &bsttype.Map{
+			Key:   bsttype.MapElement{Type: bsttype.String()},
+			Value: bsttype.MapElement{Type: &bsttype.Nullable{Type: bsttype.Uint32()}},
+		}},
+		{Index: 4, Name: "count", Type: bsttype.Uint32()},
+	}}
+	// compose writes the struct value with given options.
+	compose := func(t *testing.T, opts ComposerOptions) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteString("verified"); err != nil {
+			t.Fatalf("writing string failed: %v", err)
+		}
+		if err = c.WriteEnum("blue"); err != nil {
+			t.Fatalf("writing enum failed: %v", err)
+		}
+		err = c.WriteMap(func(mc *Composer) error {
+			if err := mc.WriteString("a"); err != nil {
+				return err
+			}
+			if err := mc.WriteNull(); err != nil {
+				return err
+			}
+			if err := mc.WriteString("b"); err != nil {
+				return err
+			}
+			v := uint32(2)
+			return mc.WriteNullableUint32(&v)
+		}, 2)
+		if err != nil {
+			t.Fatalf("writing map failed: %v", err)
+		}
+		if err = c.WriteUint32(42); err != nil {
+			t.Fatalf("writing uint32 failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+	hasCode := func(err error, code bsterr.ErrCode) bool {
+		var be *bsterr.Error
+		return errors.As(err, &be) && be.Code == code
+	}
+
+	testCases := []struct {
+		name string
+		opts ComposerOptions
+	}{
+		{name: "Plain"},
+		{name: "Comparable", opts: ComposerOptions{Comparable: true}},
+		{name: "CompatibilityMode", opts: ComposerOptions{CompatibilityMode: true}},
+		{name: "EmbedType", opts: ComposerOptions{EmbedType: true}},
+		{name: "Checksum", opts: ComposerOptions{Checksum: bstio.ChecksumCRC32C}},
+		{name: "Compression", opts: ComposerOptions{Compression: bstio.CompressionDeflate}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := compose(t, tc.opts)
+			opts := VerifyOptions{Extractor: ExtractorOptions{
+				Comparable:        tc.opts.Comparable,
+				CompatibilityMode: tc.opts.CompatibilityMode,
+			}}
+
+			// 1. The valid value has no problems.
+			if problems := Verify(bytes.NewReader(data), st, opts); len(problems) != 0 {
+				t.Fatalf("unexpected problems: %v", problems)
+			}
+
+			// 2. The truncated value could not be followed.
+			problems := Verify(bytes.NewReader(data[:len(data)-3]), st, opts)
+			if len(problems) != 1 {
+				t.Fatalf("expected single problem of truncated value, got: %v", problems)
+			}
+
+			// 3. The bytes following the value are reported, unless allowed.
+			trailing := append(bytes.Clone(data), 0)
+			problems = Verify(bytes.NewReader(trailing), st, opts)
+			if len(problems) != 1 || !hasCode(problems[0].Err, bsterr.CodeMalformedBinary) || problems[0].Offset != len(data) {
+				t.Fatalf("expected trailing bytes problem, got: %v", problems)
+			}
+			opts.AllowTrailing = true
+			if problems = Verify(bytes.NewReader(trailing), st, opts); len(problems) != 0 {
+				t.Fatalf("unexpected problems: %v", problems)
+			}
+		})
+	}
+
+	t.Run("UnknownEnum", func(t *testing.T) {
+		data := compose(t, ComposerOptions{})
+		i := bytes.Index(data, []byte("verified")) + len("verified")
+		data[i] = 9
+
+		// The walk continues after the unknown enum index, as its binary is followed anyway.
+		data = append(data, 0)
+		problems := Verify(bytes.NewReader(data), st, VerifyOptions{})
+		if len(problems) != 2 {
+			t.Fatalf("expected two problems, got: %v", problems)
+		}
+		if p := problems[0]; p.Path != "$.color" || p.Offset != i || !hasCode(p.Err, bsterr.CodeInvalidValue) {
+			t.Fatalf("unexpected enum problem: %v", p)
+		}
+	})
+
+	t.Run("MaxDepth", func(t *testing.T) {
+		data := compose(t, ComposerOptions{})
+		problems := Verify(bytes.NewReader(data), st, VerifyOptions{MaxDepth: 1})
+		if len(problems) != 0 {
+			t.Fatalf("unexpected problems: %v", problems)
+		}
+		nested := &bsttype.Struct{Fields: []bsttype.StructField{{Index: 1, Name: "inner", Type: st}}}
+		problems = Verify(bytes.NewReader(data), nested, VerifyOptions{MaxDepth: 1})
+		if len(problems) != 1 || problems[0].Path != "$.inner.labels" || !hasCode(problems[0].Err, bsterr.CodeMalformedBinary) {
+			t.Fatalf("expected depth problem, got: %v", problems)
+		}
+	})
+
+	t.Run("ChecksumMismatch", func(t *testing.T) {
+		data := compose(t, ComposerOptions{Checksum: bstio.ChecksumXXHash64})
+		data[len(data)-1] ^= 0xff
+		problems := Verify(bytes.NewReader(data), st, VerifyOptions{})
+		if len(problems) != 1 || !hasCode(problems[0].Err, bsterr.CodeChecksumMismatch) {
+			t.Fatalf("expected checksum problem, got: %v", problems)
+		}
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		problems := Verify(bytes.NewReader(nil), st, VerifyOptions{})
+		if len(problems) != 1 || problems[0].Offset != 0 || problems[0].Path != "$" {
+			t.Fatalf("expected header problem, got: %v", problems)
+		}
+	})
+}
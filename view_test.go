@@ -0,0 +1,129 @@
+package bst
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+)
+
+func TestExtractorZeroCopy(t *testing.T) {
+	st := &bsttype.Struct{Fields: []bsttype.StructField{
+		{Index: 1, Name: "name", Type: bsttype.String()},
+		{Index: 2, Name: "data", Type: &bsttype.Bytes{}},
+		{Index: 3, Name: "code", Type: &bsttype.Bytes{FixedSize: 4}},
+		{Index: 4, Name: "tags", Type: bsttype.ArrayOf(bsttype.String())},
+	}}
+	tags := []string{"a", "bc"}
+	// record composes the struct value.
+	record := func(t *testing.T, opts ComposerOptions) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		c, err := NewComposer(&buf, st, opts)
+		if err != nil {
+			t.Fatalf("creating composer failed: %v", err)
+		}
+		if err = c.WriteString("name"); err != nil {
+			t.Fatalf("writing name failed: %v", err)
+		}
+		if err = c.WriteBytes([]byte{0x00, 0x01, 0xff}); err != nil {
+			t.Fatalf("writing data failed: %v", err)
+		}
+		if err = c.WriteBytes([]byte("code")); err != nil {
+			t.Fatalf("writing code failed: %v", err)
+		}
+		err = c.WriteArray(func(ac *Composer) error {
+			for _, tag := range tags {
+				if err := ac.WriteString(tag); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, 0)
+		if err != nil {
+			t.Fatalf("writing tags failed: %v", err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatalf("closing composer failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+	// within checks if the value references the input.
+	within := func(v, data []byte) bool {
+		p := uintptr(unsafe.Pointer(unsafe.SliceData(v)))
+		start := uintptr(unsafe.Pointer(unsafe.SliceData(data)))
+		return p >= start && p+uintptr(len(v)) <= start+uintptr(len(data))
+	}
+
+	testCases := []struct {
+		name string
+		opts EncodingOptions
+		view bool
+	}{
+		{name: "Plain", view: true},
+		{name: "CompatibilityMode", opts: EncodingOptions{CompatibilityMode: true}, view: true},
+		{name: "Descending", opts: EncodingOptions{Descending: true}},
+		{name: "Comparable", opts: EncodingOptions{Comparable: true}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := record(t, tc.opts.ComposerOptions())
+			x, err := NewExtractor(bstio.NewBytesReader(data), tc.opts.ExtractorOptions(st))
+			if err != nil {
+				t.Fatalf("creating extractor failed: %v", err)
+			}
+			defer x.Close()
+
+			// check verifies the read value, which is the view of the input, unless it needs to be decoded.
+			check := func(v []byte, err error, expected string) {
+				t.Helper()
+				if err != nil {
+					t.Fatalf("reading %q failed: %v", expected, err)
+				}
+				if string(v) != expected {
+					t.Fatalf("unexpected value: %q != %q", v, expected)
+				}
+				if within(v, data) != tc.view {
+					t.Fatalf("value %q view of the input: %v", expected, !tc.view)
+				}
+				if tc.view && cap(v) != len(v) {
+					t.Fatalf("value %q view is not capped", expected)
+				}
+			}
+			x.Next()
+			v, err := x.ReadStringZeroCopy()
+			check(v, err, "name")
+			x.Next()
+			v, err = x.ReadBytesView()
+			check(v, err, "\x00\x01\xff")
+			x.Next()
+			v, err = x.ReadBytesView()
+			check(v, err, "code")
+			x.Next()
+			err = x.ReadArray(func(ax *Extractor) error {
+				for i := 0; ax.Next(); i++ {
+					v, err := ax.ReadStringZeroCopy()
+					check(v, err, tags[i])
+				}
+				return ax.Err()
+			})
+			if err != nil {
+				t.Fatalf("reading tags failed: %v", err)
+			}
+		})
+	}
+
+	t.Run("InvalidType", func(t *testing.T) {
+		x, err := NewExtractor(bstio.NewBytesReader(record(t, ComposerOptions{})), ExtractorOptions{ExpectedType: st})
+		if err != nil {
+			t.Fatalf("creating extractor failed: %v", err)
+		}
+		defer x.Close()
+		x.Next()
+		if _, err = x.ReadBytesView(); err == nil {
+			t.Fatal("expected invalid type error")
+		}
+	})
+}
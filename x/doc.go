@@ -0,0 +1,20 @@
+//go:build bstexperimental
+
+// Package x is the root of the experimental packages of the BST, i.e. the new streaming, migration or interop
+// subsystems, which are still iterated on.
+//
+// The packages of the module are released in two stability tiers:
+//   - Stable - all the packages outside the x directory. Their exported API is changed only in the backward
+//     compatible way within the major version, and their binary formats are readable by all the later versions.
+//   - Experimental - the packages within the x directory. Their API and binary formats might change, or be removed,
+//     in any release. Once stabilized, the package is moved out of the x directory, and the experimental one is
+//     kept as a deprecated alias for at least one release.
+//
+// Every file of the experimental packages is constrained with the 'bstexperimental' build tag, thus using them
+// requires an explicit opt-in, i.e.:
+//
+//	go build -tags bstexperimental ./...
+//
+// Without the tag, the experimental packages are excluded from the build, and importing them fails.
+// The stable packages never import the experimental ones.
+package x
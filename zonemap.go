@@ -0,0 +1,368 @@
+package bst
+
+import (
+	"bytes"
+
+	"github.com/devmodules/bst/bsterr"
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+	"github.com/devmodules/bst/internal/iopool"
+)
+
+// ZoneMap is a summary of the minimum and maximum values of each ordered field over a batch of struct values.
+// It allows skipping the whole batch (block) in the scans, whose predicates cannot match any of its values.
+type ZoneMap struct {
+	// Rows is the number of summarized values.
+	Rows uint
+	// Fields are the summaries of the ordered kind fields.
+	Fields []ZoneMapField
+}
+
+// ZoneMapField is a minimum and maximum summary of a single struct field.
+// The Min and Max are the raw field binaries, encoded with the options of the summarized values
+// and the field descending flag. They are nil if all the values of the field were null.
+type ZoneMapField struct {
+	Index    uint
+	Name     string
+	Nulls    uint
+	Min, Max []byte
+}
+
+var _zoneMapType = &bsttype.Struct{
+	Fields: []bsttype.StructField{
+		{Index: 1, Name: "Rows", Type: bsttype.Uint()},
+		{
+			Index: 2,
+			Name:  "Fields",
+			Type: bsttype.ArrayOf(&bsttype.Struct{
+				Fields: []bsttype.StructField{
+					{Index: 1, Name: "Index", Type: bsttype.Uint()},
+					{Index: 2, Name: "Name", Type: bsttype.String()},
+					{Index: 3, Name: "Nulls", Type: bsttype.Uint()},
+					{Index: 4, Name: "Min", Type: bsttype.NullableOf(&bsttype.Bytes{})},
+					{Index: 5, Name: "Max", Type: bsttype.NullableOf(&bsttype.Bytes{})},
+				},
+			}),
+		},
+	},
+}
+
+// ZoneMapType returns the struct type used to serialize the ZoneMap.
+// The returned type must not be modified.
+func ZoneMapType() *bsttype.Struct {
+	return _zoneMapType
+}
+
+// BuildZoneMap computes the zone map of the headless struct values of type t, encoded with given options.
+// Only the fields of the ordered kinds - numbers, strings, bytes, enums and time values (optionally nullable) are summarized.
+func BuildZoneMap(rows [][]byte, t bsttype.Type, options bstio.ValueOptions) (*ZoneMap, error) {
+	// 1. Dereference the named type and verify that it is a struct.
+	st, ok := derefNamedType(t).(*bsttype.Struct)
+	if !ok {
+		return nil, bsterr.Err(bsterr.CodeInvalidType, "zone map is supported only for struct types").
+			WithDetail("type", t)
+	}
+
+	// 2. Prepare the summaries of the ordered fields.
+	zm := &ZoneMap{Rows: uint(len(rows))}
+	for _, f := range st.Fields {
+		if isOrderedKind(zoneMapElemType(f.Type).Kind()) {
+			zm.Fields = append(zm.Fields, ZoneMapField{Index: f.Index, Name: f.Name})
+		}
+	}
+
+	// 3. Update the summaries with each row.
+	for ri, row := range rows {
+		segments, err := splitStructSegmentsOf(row, st, options)
+		if err != nil {
+			return nil, bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to split zone map row").
+				WithDetail("row", ri)
+		}
+		for i := range zm.Fields {
+			if err = zm.update(st, &zm.Fields[i], segments, options); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return zm, nil
+}
+
+// Field returns the summary of the field with given name.
+func (x *ZoneMap) Field(name string) (*ZoneMapField, bool) {
+	for i := range x.Fields {
+		if x.Fields[i].Name == name {
+			return &x.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+func (x *ZoneMap) update(st *bsttype.Struct, zf *ZoneMapField, segments []structSegment, o bstio.ValueOptions) error {
+	// 1. Find the field segment, a field missing in the compatibility mode is treated as null.
+	f, pos, _ := st.FieldByName(zf.Name)
+	sg, ok := findStructSegment(segments, structSegmentIndex(f, pos, o))
+	if !ok {
+		zf.Nulls++
+		return nil
+	}
+
+	// 2. Strip the nullable flag.
+	data := sg.data
+	fo := structFieldOptions(f, o)
+	if _, isNullable := derefNamedType(f.Type).(*bsttype.Nullable); isNullable {
+		if len(data) == 0 {
+			return bsterr.Err(bsterr.CodeMalformedBinary, "empty nullable field binary").WithDetail("field", f.Name)
+		}
+		nf := data[0]
+		if fo.Descending {
+			nf = ^nf
+		}
+		if nf == bstio.NullableIsNull {
+			zf.Nulls++
+			return nil
+		}
+		data = data[1:]
+	}
+
+	// 3. Update the minimum and maximum.
+	et := zoneMapElemType(f.Type)
+	if zf.Min == nil {
+		zf.Min, zf.Max = data, data
+		return nil
+	}
+	cmp, err := compareValueBinary(et, data, zf.Min, fo)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to compare zone map field").WithDetail("field", f.Name)
+	}
+	if cmp < 0 {
+		zf.Min = data
+	}
+	cmp, err = compareValueBinary(et, data, zf.Max, fo)
+	if err != nil {
+		return bsterr.ErrWrap(err, bsterr.CodeDecodingBinaryValue, "failed to compare zone map field").WithDetail("field", f.Name)
+	}
+	if cmp > 0 {
+		zf.Max = data
+	}
+	return nil
+}
+
+// zoneMapElemType dereferences the named and nullable types.
+func zoneMapElemType(t bsttype.Type) bsttype.Type {
+	t = derefNamedType(t)
+	if nt, ok := t.(*bsttype.Nullable); ok {
+		return derefNamedType(nt.Type)
+	}
+	return t
+}
+
+// MarshalZoneMap encodes the zone map as the ZoneMapType value.
+func MarshalZoneMap(zm *ZoneMap) ([]byte, error) {
+	var buf bytes.Buffer
+	c, err := NewComposer(&buf, _zoneMapType, ComposerOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if err = c.WriteUint(zm.Rows); err != nil {
+		return nil, err
+	}
+	err = c.WriteArray(func(ac *Composer) error {
+		for i := range zm.Fields {
+			zf := &zm.Fields[i]
+			err := ac.WriteStruct(func(sc *Composer) error {
+				if err := sc.WriteUint(zf.Index); err != nil {
+					return err
+				}
+				if err := sc.WriteString(zf.Name); err != nil {
+					return err
+				}
+				if err := sc.WriteUint(zf.Nulls); err != nil {
+					return err
+				}
+				for _, v := range [][]byte{zf.Min, zf.Max} {
+					if v == nil {
+						if err := sc.WriteNull(); err != nil {
+							return err
+						}
+						continue
+					}
+					if err := sc.WriteNotNull(); err != nil {
+						return err
+					}
+					if err := sc.WriteBytes(v); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, len(zm.Fields))
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalZoneMap decodes the zone map encoded by the MarshalZoneMap.
+func UnmarshalZoneMap(data []byte) (*ZoneMap, error) {
+	r := iopool.GetReadSeeker(data)
+	defer iopool.ReleaseReadSeeker(r)
+
+	x, err := NewExtractor(r, ExtractorOptions{ExpectedType: _zoneMapType})
+	if err != nil {
+		return nil, err
+	}
+	defer x.Close()
+
+	var zm ZoneMap
+	for x.Next() {
+		switch x.Index() {
+		case 0:
+			zm.Rows, err = x.ReadUint()
+		case 1:
+			err = x.ReadArray(func(ax *Extractor) error {
+				for ax.Next() {
+					var zf ZoneMapField
+					if err := ax.ReadStruct(func(sx *Extractor) error {
+						return readZoneMapField(sx, &zf)
+					}); err != nil {
+						return err
+					}
+					zm.Fields = append(zm.Fields, zf)
+				}
+				return ax.Err()
+			})
+		default:
+			_, err = x.Skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = x.Err(); err != nil {
+		return nil, err
+	}
+	return &zm, nil
+}
+
+func readZoneMapField(x *Extractor, zf *ZoneMapField) error {
+	for x.Next() {
+		var err error
+		switch x.Index() {
+		case 0:
+			zf.Index, err = x.ReadUint()
+		case 1:
+			zf.Name, err = x.ReadString()
+		case 2:
+			zf.Nulls, err = x.ReadUint()
+		case 3, 4:
+			var isNull bool
+			isNull, err = x.IsNull()
+			if err != nil || isNull {
+				break
+			}
+			var v []byte
+			v, err = x.ReadBytes()
+			if x.Index() == 3 {
+				zf.Min = v
+			} else {
+				zf.Max = v
+			}
+		default:
+			_, err = x.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return x.Err()
+}
+
+// ZoneOp is the comparison operator of the ZonePredicate.
+type ZoneOp int
+
+// Enumerated zone predicate operators.
+const (
+	ZoneOpEqual ZoneOp = iota
+	ZoneOpLess
+	ZoneOpLessOrEqual
+	ZoneOpGreater
+	ZoneOpGreaterOrEqual
+)
+
+// ZonePredicate is a comparison of the struct field value with a constant, i.e.: Age >= 18.
+type ZonePredicate struct {
+	Field string
+	Op    ZoneOp
+	Value bstvalue.Value
+}
+
+// MayMatch checks if any of the summarized values may satisfy all given predicates.
+// It returns false only if the zone map proves that no value matches.
+// The type t and options need to be the same as the ones used to build the zone map.
+func (x *ZoneMap) MayMatch(t bsttype.Type, options bstio.ValueOptions, predicates ...ZonePredicate) (bool, error) {
+	st, ok := derefNamedType(t).(*bsttype.Struct)
+	if !ok {
+		return false, bsterr.Err(bsterr.CodeInvalidType, "zone map is supported only for struct types").
+			WithDetail("type", t)
+	}
+
+	for _, p := range predicates {
+		// 1. Find the field summary, not summarized fields could not exclude the zone.
+		zf, found := x.Field(p.Field)
+		if !found {
+			continue
+		}
+		f, _, found := st.FieldByName(p.Field)
+		if !found {
+			return false, bsterr.Err(bsterr.CodeInvalidValue, "zone predicate field not found").
+				WithDetail("field", p.Field)
+		}
+
+		// 2. A field with only null values never matches a comparison.
+		if zf.Min == nil {
+			return false, nil
+		}
+
+		// 3. Encode the predicate value and compare it with the summary bounds.
+		fo := structFieldOptions(f, options)
+		v, err := p.Value.MarshalValue(fo)
+		if err != nil {
+			return false, err
+		}
+		et := zoneMapElemType(f.Type)
+		cmpMin, err := compareValueBinary(et, v, zf.Min, fo)
+		if err != nil {
+			return false, err
+		}
+		cmpMax, err := compareValueBinary(et, v, zf.Max, fo)
+		if err != nil {
+			return false, err
+		}
+
+		var match bool
+		switch p.Op {
+		case ZoneOpEqual:
+			match = cmpMin >= 0 && cmpMax <= 0
+		case ZoneOpLess:
+			match = cmpMin > 0
+		case ZoneOpLessOrEqual:
+			match = cmpMin >= 0
+		case ZoneOpGreater:
+			match = cmpMax < 0
+		case ZoneOpGreaterOrEqual:
+			match = cmpMax <= 0
+		default:
+			return false, bsterr.Err(bsterr.CodeInvalidValue, "unknown zone predicate operator").WithDetail("op", p.Op)
+		}
+		if !match {
+			return false, nil
+		}
+	}
+	return true, nil
+}
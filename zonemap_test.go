@@ -0,0 +1,131 @@
+package bst
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/devmodules/bst/bstio"
+	"github.com/devmodules/bst/bsttype"
+	"github.com/devmodules/bst/bstvalue"
+)
+
+func TestZoneMap(t *testing.T) {
+	st := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "Age", Type: bsttype.Int32()},
+			{Index: 2, Name: "Name", Type: bsttype.String()},
+			{Index: 3, Name: "Score", Type: bsttype.NullableOf(bsttype.Float64())},
+			{Index: 4, Name: "Active", Type: bsttype.Boolean()},
+		},
+	}
+	o := bstio.ValueOptions{}
+
+	var rows [][]byte
+	for i, name := range []string{"carol", "alice", "bob"} {
+		score := bstvalue.NullValueOf(st.Fields[2].Type.(*bsttype.Nullable))
+		if i > 0 {
+			score = bstvalue.MustNullableValue(bstvalue.NewFloat64Value(float64(i)*1.5), false)
+		}
+		sv := bstvalue.MustNewStructValue(st, []bstvalue.Value{
+			bstvalue.NewInt32Value(int32(20 + i*10)),
+			bstvalue.NewStringValue(name),
+			score,
+			bstvalue.NewBoolValue(true),
+		})
+		data, err := sv.MarshalValue(o)
+		if err != nil {
+			t.Fatalf("marshaling row failed: %v", err)
+		}
+		rows = append(rows, data)
+	}
+
+	zm, err := BuildZoneMap(rows, st, o)
+	if err != nil {
+		t.Fatalf("building zone map failed: %v", err)
+	}
+
+	if zm.Rows != 3 || len(zm.Fields) != 3 {
+		t.Fatalf("unexpected zone map: %+v", zm)
+	}
+	if sf, _ := zm.Field("Score"); sf.Nulls != 1 {
+		t.Fatalf("unexpected number of nulls: %d", sf.Nulls)
+	}
+
+	data, err := MarshalZoneMap(zm)
+	if err != nil {
+		t.Fatalf("marshaling zone map failed: %v", err)
+	}
+	zm, err = UnmarshalZoneMap(data)
+	if err != nil {
+		t.Fatalf("unmarshaling zone map failed: %v", err)
+	}
+
+	testCases := []struct {
+		Name      string
+		Predicate ZonePredicate
+		Expected  bool
+	}{
+		{Name: "AgeEqualInside", Predicate: ZonePredicate{Field: "Age", Op: ZoneOpEqual, Value: bstvalue.NewInt32Value(30)}, Expected: true},
+		{Name: "AgeEqualOutside", Predicate: ZonePredicate{Field: "Age", Op: ZoneOpEqual, Value: bstvalue.NewInt32Value(41)}, Expected: false},
+		{Name: "AgeGreater", Predicate: ZonePredicate{Field: "Age", Op: ZoneOpGreater, Value: bstvalue.NewInt32Value(40)}, Expected: false},
+		{Name: "AgeGreaterOrEqual", Predicate: ZonePredicate{Field: "Age", Op: ZoneOpGreaterOrEqual, Value: bstvalue.NewInt32Value(40)}, Expected: true},
+		{Name: "AgeLess", Predicate: ZonePredicate{Field: "Age", Op: ZoneOpLess, Value: bstvalue.NewInt32Value(20)}, Expected: false},
+		{Name: "NameLess", Predicate: ZonePredicate{Field: "Name", Op: ZoneOpLess, Value: bstvalue.NewStringValue("b")}, Expected: true},
+		{Name: "NameGreater", Predicate: ZonePredicate{Field: "Name", Op: ZoneOpGreater, Value: bstvalue.NewStringValue("dave")}, Expected: false},
+		{Name: "ScoreLess", Predicate: ZonePredicate{Field: "Score", Op: ZoneOpLess, Value: bstvalue.NewFloat64Value(1)}, Expected: false},
+		{Name: "NotSummarized", Predicate: ZonePredicate{Field: "Active", Op: ZoneOpEqual, Value: bstvalue.NewBoolValue(false)}, Expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			match, err := zm.MayMatch(st, o, tc.Predicate)
+			if err != nil {
+				t.Fatalf("evaluating predicate failed: %v", err)
+			}
+			if match != tc.Expected {
+				t.Fatalf("unexpected match result: %v", match)
+			}
+		})
+	}
+}
+
+func TestZoneMapCompatibility(t *testing.T) {
+	st := &bsttype.Struct{
+		Fields: []bsttype.StructField{
+			{Index: 1, Name: "A", Type: bsttype.Uint8()},
+			{Index: 2, Name: "B", Type: bsttype.Uint8()},
+			{Index: 5, Name: "C", Type: bsttype.Uint8()},
+		},
+	}
+	o := bstio.ValueOptions{CompatibilityMode: true}
+
+	// The last field of the composed rows is summarized as well.
+	rows := [][]byte{
+		composeCompatibilityRow(t, st, 1, 2, 3),
+		composeCompatibilityRow(t, st, 4, 5, 9),
+		composeCompatibilityRow(t, &bsttype.Struct{Fields: st.Fields[:2]}, 2, 1),
+	}
+	zm, err := BuildZoneMap(rows, st, o)
+	if err != nil {
+		t.Fatalf("building zone map failed: %v", err)
+	}
+	if cf, _ := zm.Field("C"); cf.Nulls != 1 || !bytes.Equal(cf.Min, []byte{3}) || !bytes.Equal(cf.Max, []byte{9}) {
+		t.Fatalf("unexpected summary of the last field: %+v", cf)
+	}
+	for _, tc := range []struct {
+		Predicate ZonePredicate
+		Expected  bool
+	}{
+		{Predicate: ZonePredicate{Field: "C", Op: ZoneOpEqual, Value: bstvalue.NewUint8Value(9)}, Expected: true},
+		{Predicate: ZonePredicate{Field: "C", Op: ZoneOpGreater, Value: bstvalue.NewUint8Value(9)}, Expected: false},
+		{Predicate: ZonePredicate{Field: "A", Op: ZoneOpLess, Value: bstvalue.NewUint8Value(1)}, Expected: false},
+	} {
+		match, err := zm.MayMatch(st, o, tc.Predicate)
+		if err != nil {
+			t.Fatalf("evaluating predicate failed: %v", err)
+		}
+		if match != tc.Expected {
+			t.Fatalf("unexpected match result of %+v: %v", tc.Predicate, match)
+		}
+	}
+}